@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+//APIKeyRequest - a new api key, what it's called and the scopes it gets
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+//APIKeyResponse - an api key.  Key is the key itself, it's only returned when the key is made.
+type APIKeyResponse struct {
+	KeyData *goparent.APIKey `json:"keyData"`
+	Key     string           `json:"key,omitempty"`
+}
+
+//APIKeysResponse - the user's api keys
+type APIKeysResponse struct {
+	Keys []*goparent.APIKey `json:"keys"`
+}
+
+//apiKeyRouteScopes - the scope an api key needs for each route it can be used on, by
+// route name.  keys can't be used on routes that aren't in here.
+var apiKeyRouteScopes = map[string]string{
+	"ChildrenGet":    goparent.ScopeChildrenRead,
+	"ChildView":      goparent.ScopeChildrenRead,
+	"ChildSummary":   goparent.ScopeChildrenRead,
+	"V2ChildrenList": goparent.ScopeChildrenRead,
+	"V2ChildGet":     goparent.ScopeChildrenRead,
+	"V2ChildSummary": goparent.ScopeChildrenRead,
+
+	"FamilyGet":  goparent.ScopeFamilyRead,
+	"FamilyList": goparent.ScopeFamilyRead,
+	"FamilyView": goparent.ScopeFamilyRead,
+
+	"FeedingGet":       goparent.ScopeFeedingRead,
+	"FeedingView":      goparent.ScopeFeedingRead,
+	"FeedingGraphData": goparent.ScopeFeedingRead,
+	"V2FeedingsList":   goparent.ScopeFeedingRead,
+	"V2FeedingsGraph":  goparent.ScopeFeedingRead,
+	"FeedingNew":       goparent.ScopeFeedingWrite,
+	"FeedingEdit":      goparent.ScopeFeedingWrite,
+	"FeedingDelete":    goparent.ScopeFeedingWrite,
+	"V2FeedingCreate":  goparent.ScopeFeedingWrite,
+
+	"SleepGet":       goparent.ScopeSleepRead,
+	"SleepView":      goparent.ScopeSleepRead,
+	"SleepGraphData": goparent.ScopeSleepRead,
+	"SleepStatus":    goparent.ScopeSleepRead,
+	"V2SleepsList":   goparent.ScopeSleepRead,
+	"V2SleepsGraph":  goparent.ScopeSleepRead,
+	"V2SleepActive":  goparent.ScopeSleepRead,
+	"SleepNew":       goparent.ScopeSleepWrite,
+	"SleepEdit":      goparent.ScopeSleepWrite,
+	"SleepDelete":    goparent.ScopeSleepWrite,
+	"V2SleepCreate":  goparent.ScopeSleepWrite,
+	"SleepStart":     goparent.ScopeSleepToggle,
+	"SleepEnd":       goparent.ScopeSleepToggle,
+	"V2SleepStart":   goparent.ScopeSleepToggle,
+	"V2SleepEnd":     goparent.ScopeSleepToggle,
+
+	"WasteGet":       goparent.ScopeWasteRead,
+	"WasteView":      goparent.ScopeWasteRead,
+	"WasteGraphData": goparent.ScopeWasteRead,
+	"V2WastesList":   goparent.ScopeWasteRead,
+	"V2WastesGraph":  goparent.ScopeWasteRead,
+	"WasteNew":       goparent.ScopeWasteWrite,
+	"WasteEdit":      goparent.ScopeWasteWrite,
+	"WasteDelete":    goparent.ScopeWasteWrite,
+	"V2WasteCreate":  goparent.ScopeWasteWrite,
+
+	"Events": goparent.ScopeEventsRead,
+}
+
+var errAPIKeyNotAllowed = forbidden("api keys can't be used for this, log in instead")
+
+//apiKeyAuth - authenticate with an api key.  the key has to have the scope for the route,
+// its last use is recorded at most once every APIKeyUseInterval.
+func (sh *Handler) apiKeyAuth(w http.ResponseWriter, r *http.Request, next http.Handler, secret string) {
+	ctx := sh.Env.DB.GetContext(r)
+	if sh.APIKeyService == nil {
+		writeError(w, r, errUnauthorized)
+		return
+	}
+	key, err := sh.APIKeyService.APIKeyByHash(ctx, goparent.HashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, goparent.ErrNoAPIKeyFound) {
+			err = errUnauthorized
+		}
+		writeError(w, r, err)
+		return
+	}
+
+	var name string
+	if route := mux.CurrentRoute(r); route != nil {
+		name = route.GetName()
+	}
+	scope, ok := apiKeyRouteScopes[name]
+	if !ok {
+		writeError(w, r, errAPIKeyNotAllowed)
+		return
+	}
+	if !key.Allows(scope) {
+		writeError(w, r, forbidden("the api key doesn't have the "+scope+" scope"))
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(key.LastUsed) >= goparent.APIKeyUseInterval {
+		err = sh.APIKeyService.Used(ctx, key, now)
+		if err != nil {
+			log.Printf("request %s: api key %s: %s", RequestIDFromContext(r.Context()), key.ID, err)
+		}
+	}
+	sh.serveUser(ctx, w, r, next, key.UserID)
+}
+
+//ownAPIKey - the api key in the path, keys belonging to other users are not found
+func (h *Handler) ownAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) (*goparent.APIKey, bool) {
+	user, err := UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, r, errUnauthorized)
+		return nil, false
+	}
+	key, err := h.APIKeyService.APIKey(ctx, mux.Vars(r)["id"])
+	if err == nil && key.UserID != user.ID {
+		err = goparent.ErrNoAPIKeyFound
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return key, true
+}
+
+func (h *Handler) apiKeysListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		keys, err := h.APIKeyService.APIKeys(h.Env.DB.GetContext(r), user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if keys == nil {
+			keys = []*goparent.APIKey{}
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(APIKeysResponse{Keys: keys})
+	})
+}
+
+func (h *Handler) apiKeyCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		var keyRequest APIKeyRequest
+		err = json.NewDecoder(r.Body).Decode(&keyRequest)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		defer r.Body.Close()
+
+		now := time.Now()
+		key, secret, err := goparent.NewAPIKey(user, keyRequest.Name, keyRequest.Scopes, now)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = key.Validate(now)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.APIKeyService.Save(h.Env.DB.GetContext(r), key)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(APIKeyResponse{KeyData: key, Key: secret})
+	})
+}
+
+func (h *Handler) apiKeyRevokeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		key, ok := h.ownAPIKey(ctx, w, r)
+		if !ok {
+			return
+		}
+		err := h.APIKeyService.Delete(ctx, key)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
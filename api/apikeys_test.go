@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	secret := goparent.APIKeyPrefix + "0123456789abcdef"
+	testCases := []struct {
+		desc         string
+		route        string
+		token        string
+		lastUsed     time.Time
+		responseCode int
+		used         int
+	}{
+		{
+			desc:         "key with the scope",
+			route:        "WasteNew",
+			token:        secret,
+			responseCode: http.StatusOK,
+			used:         1,
+		},
+		{
+			desc:         "recently used key isn't written again",
+			route:        "WasteNew",
+			token:        secret,
+			lastUsed:     time.Now().Add(-time.Second),
+			responseCode: http.StatusOK,
+		},
+		{
+			desc:         "key without the scope",
+			route:        "FeedingNew",
+			token:        secret,
+			responseCode: http.StatusForbidden,
+		},
+		{
+			desc:         "route keys can't use",
+			route:        "UserAPIKeyCreate",
+			token:        secret,
+			responseCode: http.StatusForbidden,
+		},
+		{
+			desc:         "unknown key",
+			route:        "WasteNew",
+			token:        goparent.APIKeyPrefix + "nope",
+			responseCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			keyService := &mock.APIKeyService{
+				GetKey: &goparent.APIKey{ID: "key-1", UserID: "1", Hash: goparent.HashAPIKey(secret), Scopes: []string{goparent.ScopeWasteWrite}, LastUsed: tC.lastUsed},
+			}
+			mockHandler := Handler{
+				Env:           &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+				UserService:   &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+				APIKeyService: keyService,
+			}
+			var served *goparent.User
+			routes := mux.NewRouter()
+			routes.Handle("/test", mockHandler.AuthRequired(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served, _ = UserFromContext(r.Context())
+			}))).Name(tC.route)
+
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tC.token)
+			rr := httptest.NewRecorder()
+			routes.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			assert.Len(t, keyService.UsedAt, tC.used)
+			if tC.responseCode == http.StatusOK && assert.NotNil(t, served) {
+				assert.Equal(t, "1", served.ID)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRouteScopes(t *testing.T) {
+	routes := BuildAPIRouting(&Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}})
+	for name, scope := range apiKeyRouteScopes {
+		assert.NotNil(t, routes.Get(name), "%s isn't a route", name)
+		assert.Contains(t, goparent.APIKeyScopes, scope)
+	}
+}
+
+func TestAPIKeyCreateHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		request      string
+		responseCode int
+	}{
+		{
+			desc:         "makes a key",
+			request:      `{"name": "diaper button", "scopes": ["waste:write"]}`,
+			responseCode: http.StatusCreated,
+		},
+		{
+			desc:         "unknown scope",
+			request:      `{"name": "diaper button", "scopes": ["everything"]}`,
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "no name",
+			request:      `{"scopes": ["waste:write"]}`,
+			responseCode: http.StatusBadRequest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			keyService := &mock.APIKeyService{KeyID: "key-1"}
+			mockHandler := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}, APIKeyService: keyService}
+			req, _ := http.NewRequest("POST", "/user/apikeys", bytes.NewBufferString(tC.request))
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+			rr := httptest.NewRecorder()
+			mockHandler.apiKeyCreateHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.responseCode != http.StatusCreated {
+				assert.Empty(t, keyService.Saved)
+				return
+			}
+
+			var resp APIKeyResponse
+			json.NewDecoder(rr.Body).Decode(&resp)
+			assert.True(t, goparent.IsAPIKey(resp.Key))
+			assert.Equal(t, "key-1", resp.KeyData.ID)
+			if assert.Len(t, keyService.Saved, 1) {
+				assert.Equal(t, "1", keyService.Saved[0].UserID)
+				assert.Equal(t, goparent.HashAPIKey(resp.Key), keyService.Saved[0].Hash)
+			}
+			assert.NotContains(t, rr.Body.String(), goparent.HashAPIKey(resp.Key), "the hash isn't returned")
+		})
+	}
+}
+
+func TestAPIKeyRevokeHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		key          *goparent.APIKey
+		responseCode int
+	}{
+		{
+			desc:         "revokes the key",
+			key:          &goparent.APIKey{ID: "key-1", UserID: "1"},
+			responseCode: http.StatusNoContent,
+		},
+		{
+			desc:         "someone else's key",
+			key:          &goparent.APIKey{ID: "key-1", UserID: "2"},
+			responseCode: http.StatusNotFound,
+		},
+		{
+			desc:         "no key",
+			responseCode: http.StatusNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			keyService := &mock.APIKeyService{GetKey: tC.key}
+			mockHandler := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}, APIKeyService: keyService}
+			req, _ := http.NewRequest("DELETE", "/user/apikeys/key-1", nil)
+			req = mux.SetURLVars(req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"})), map[string]string{"id": "key-1"})
+			rr := httptest.NewRecorder()
+			mockHandler.apiKeyRevokeHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.responseCode == http.StatusNoContent {
+				assert.Len(t, keyService.Deleted, 1)
+			} else {
+				assert.Empty(t, keyService.Deleted)
+			}
+		})
+	}
+}
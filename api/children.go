@@ -2,7 +2,9 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sasimpson/goparent"
@@ -29,11 +31,58 @@ type ChildSummaryResponse struct {
 	Stats     Summary        `json:"stats"`
 }
 
-//Summary - return structure of all summary data
+//Summary - return structure of all summary data.  Households is only filled in for
+// children shared between families.
 type Summary struct {
-	Feeding goparent.FeedingSummary `json:"feeding"`
-	Sleep   goparent.SleepSummary   `json:"sleep"`
-	Waste   goparent.WasteSummary   `json:"waste"`
+	Feeding    goparent.FeedingSummary `json:"feeding"`
+	Sleep      goparent.SleepSummary   `json:"sleep"`
+	Waste      goparent.WasteSummary   `json:"waste"`
+	Households []HouseholdSummary      `json:"households,omitempty"`
+}
+
+//HouseholdSummary - how many of the records in a shared child's summary each family logged
+type HouseholdSummary struct {
+	FamilyID string `json:"familyID"`
+	Owner    bool   `json:"owner"`
+	Feedings int    `json:"feedings"`
+	Sleeps   int    `json:"sleeps"`
+	Wastes   int    `json:"wastes"`
+}
+
+//ChildSharesResponse - the families a child is shared with
+type ChildSharesResponse struct {
+	Shares []goparent.ChildShare `json:"shares"`
+}
+
+//households - who logged what in a shared child's summary, the child's own family first
+func households(child *goparent.Child, summary *Summary) []HouseholdSummary {
+	if len(child.Shares) == 0 {
+		return nil
+	}
+	result := []HouseholdSummary{{FamilyID: child.FamilyID, Owner: true}}
+	for _, familyID := range child.Families()[1:] {
+		result = append(result, HouseholdSummary{FamilyID: familyID})
+	}
+	household := func(familyID string) *HouseholdSummary {
+		for i := range result {
+			if result[i].FamilyID == familyID {
+				return &result[i]
+			}
+		}
+		//a family the child was shared with before
+		result = append(result, HouseholdSummary{FamilyID: familyID})
+		return &result[len(result)-1]
+	}
+	for _, feeding := range summary.Feeding.Data {
+		household(feeding.FamilyID).Feedings++
+	}
+	for _, sleep := range summary.Sleep.Data {
+		household(sleep.FamilyID).Sleeps++
+	}
+	for _, waste := range summary.Waste.Data {
+		household(waste.FamilyID).Wastes++
+	}
+	return result
 }
 
 func (h *Handler) initChildrenHandlers(r *mux.Router) {
@@ -44,6 +93,10 @@ func (h *Handler) initChildrenHandlers(r *mux.Router) {
 	c.Handle("/{id}", h.AuthRequired(h.childEditHandler())).Methods("PUT").Name("ChildEdit")
 	c.Handle("/{id}", h.AuthRequired(h.childDeleteHandler())).Methods("DELETE").Name("ChildDelete")
 	c.Handle("/{id}/summary", h.AuthRequired(h.childSummary())).Methods("GET").Name("ChildSummary")
+	c.Handle("/{id}/shares", h.AuthRequired(h.childSharesHandler())).Methods("GET").Name("ChildShares")
+	c.Handle("/{id}/shares/{sharedFamilyID}", h.AuthRequired(h.childShareHandler())).Methods("PUT").Name("ChildShare")
+	c.Handle("/{id}/shares/{sharedFamilyID}", h.AuthRequired(h.childUnshareHandler())).Methods("DELETE").Name("ChildUnshare")
+	c.Handle("/{id}/shares/{sharedFamilyID}/accept", h.AuthRequired(h.childShareAcceptHandler())).Methods("PUT").Name("ChildShareAccept")
 
 }
 
@@ -54,49 +107,68 @@ func (h *Handler) childSummary() http.Handler {
 		childID := mux.Vars(r)["id"]
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		var summary ChildSummaryResponse
 		child, err := h.ChildService.Child(ctx, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		summary.ChildData = *child
-		if child.FamilyID != family.ID {
-			http.Error(w, "not found", http.StatusNotFound)
+		if !child.CanView(family.ID) {
+			writeError(w, r, errNotFound)
+			return
+		}
+
+		loc, err := requestLocation(r, family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		query, err := getDataQuery(r, goparent.NewStatsQuery(time.Now(), loc))
+		if err != nil {
+			writeError(w, r, badRequest(err))
 			return
 		}
 
-		feedingSummary, err := h.FeedingService.Stats(ctx, child)
+		units, err := displayUnits(r, family)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
-		summary.Stats.Feeding = *feedingSummary
 
-		sleeps, err := h.SleepService.Stats(ctx, child)
+		feedingSummary, err := h.FeedingService.Stats(ctx, child, query)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
+			return
+		}
+		summary.Stats.Feeding = *feedingSummary.In(units)
+
+		sleeps, err := h.SleepService.Stats(ctx, child, query)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 		summary.Stats.Sleep = *sleeps
 
-		wastes, err := h.WasteService.Stats(ctx, child)
+		wastes, err := h.WasteService.Stats(ctx, child, query)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 		summary.Stats.Waste = *wastes
+		summary.Stats.Households = households(child, &summary.Stats)
 
 		w.Header().Set("Content-Type", jsonContentType)
 		json.NewEncoder(w).Encode(summary)
@@ -109,19 +181,19 @@ func (h *Handler) childrenGetHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		allChildren, err := h.FamilyService.Children(ctx, family)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -138,13 +210,13 @@ func (h *Handler) childNewHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -152,17 +224,24 @@ func (h *Handler) childNewHandler() http.Handler {
 		var childRequest ChildRequest
 		err = decoder.Decode(&childRequest)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
 
 		defer r.Body.Close()
+
+		err = childRequest.ChildData.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
 		w.Header().Set("Content-Type", jsonContentType)
 		childRequest.ChildData.ParentID = user.ID
 		childRequest.ChildData.FamilyID = family.ID
 		err = h.ChildService.Save(ctx, &childRequest.ChildData)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusConflict)
+			writeError(w, r, err)
 			return
 		}
 		json.NewEncoder(w).Encode(childRequest.ChildData)
@@ -177,25 +256,25 @@ func (h *Handler) childViewHandler() http.Handler {
 		childID := mux.Vars(r)["id"]
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		child, err := h.ChildService.Child(ctx, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		//child needs to belong to the user's family.
-		if child.FamilyID != family.ID {
-			http.Error(w, "not found", http.StatusNotFound)
+		//child needs to belong to or be shared with the user's family.
+		if !child.CanView(family.ID) {
+			writeError(w, r, errNotFound)
 			return
 		}
 
@@ -211,13 +290,13 @@ func (h *Handler) childEditHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -225,22 +304,30 @@ func (h *Handler) childEditHandler() http.Handler {
 		var childRequest ChildRequest
 		err = decoder.Decode(&childRequest)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
 
 		id := mux.Vars(r)["id"]
-		child, err := h.ChildService.Child(ctx, id)
+		child, err := h.ownChild(ctx, family, id)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeError(w, r, err)
 			return
 		}
 
 		//verify both the child we requested to edit, and that the parent is the user id.
 		if (child.ID != childRequest.ChildData.ID) || (childRequest.ChildData.FamilyID != family.ID) {
-			http.Error(w, "invalid relationship", http.StatusBadRequest)
+			writeError(w, r, badRequest(errors.New("invalid relationship")))
 			return
 		}
+
+		err = childRequest.ChildData.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		//shares are changed through /shares, not by editing the child
+		childRequest.ChildData.Shares = child.Shares
 		h.ChildService.Save(ctx, &childRequest.ChildData)
 		err = json.NewEncoder(w).Encode(childRequest.ChildData)
 		return
@@ -255,25 +342,26 @@ func (h *Handler) childDeleteHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
-		id := mux.Vars(r)["id"]
-		child, err := h.ChildService.Child(ctx, id)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		if child.FamilyID != family.ID {
-			http.Error(w, "not found", http.StatusNotFound)
+		id := mux.Vars(r)["id"]
+		child, err := h.ownChild(ctx, family, id)
+		if err != nil {
+			writeError(w, r, err)
+			return
 		}
 
 		deleted, err := h.ChildService.Delete(ctx, child)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -286,3 +374,146 @@ func (h *Handler) childDeleteHandler() http.Handler {
 		json.NewEncoder(w).Encode(deletedResponse)
 	})
 }
+
+//childSharesHandler - GET /{id}/shares - the families the child is shared with
+func (h *Handler) childSharesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+
+		child, err := h.ownChild(ctx, family, mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		shares := child.Shares
+		if shares == nil {
+			shares = []goparent.ChildShare{}
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(ChildSharesResponse{Shares: shares})
+	})
+}
+
+//childShareHandler - PUT /{id}/shares/{sharedFamilyID} - link the child into another
+// family with view or log permission, sharing again changes the permission.  only the
+// admin of the child's family can share it, and the share is pending until the other
+// family accepts it.
+func (h *Handler) childShareHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, family, ok := h.adminFamily(w, r, "share a child")
+		if !ok {
+			return
+		}
+
+		child, err := h.ownChild(ctx, family, mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		sharedFamily, err := h.FamilyService.Family(ctx, mux.Vars(r)["sharedFamilyID"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		//the other family's admin has to accept the share, unless whoever shares it is in
+		// both families
+		err = child.Share(goparent.ChildShare{
+			FamilyID:   sharedFamily.ID,
+			Permission: r.FormValue("permission"),
+			SharedBy:   user.ID,
+			SharedAt:   time.Now(),
+			Pending:    !sharedFamily.IsMember(user.ID),
+		})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.ChildService.Save(ctx, child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(child)
+	})
+}
+
+//childShareAcceptHandler - PUT /{id}/shares/{sharedFamilyID}/accept - the admin of the
+// family a child was shared into takes the share, until then the family can't see the child.
+func (h *Handler) childShareAcceptHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, family, ok := h.adminFamily(w, r, "accept a shared child")
+		if !ok {
+			return
+		}
+		if mux.Vars(r)["sharedFamilyID"] != family.ID {
+			writeError(w, r, forbidden("only the family a child is shared with can accept it"))
+			return
+		}
+
+		child, err := h.sharedChild(ctx, family, mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = child.Accept(family.ID, user.ID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.ChildService.Save(ctx, child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(child)
+	})
+}
+
+//childUnshareHandler - DELETE /{id}/shares/{sharedFamilyID} - take the child back out of
+// another family.  the admin of either family can do this, the other family turns down a
+// share it hasn't accepted this way.
+func (h *Handler) childUnshareHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		_, family, ok := h.adminFamily(w, r, "unshare a child")
+		if !ok {
+			return
+		}
+
+		sharedFamilyID := mux.Vars(r)["sharedFamilyID"]
+		child, err := h.sharedChild(ctx, family, mux.Vars(r)["id"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if child.FamilyID != family.ID && sharedFamilyID != family.ID {
+			writeError(w, r, goparent.ErrNotChildOwner)
+			return
+		}
+
+		err = child.Unshare(sharedFamilyID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.ChildService.Save(ctx, child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -7,6 +7,9 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,6 +62,30 @@ func Test(t *testing.T) {
 			path:    "/children/{id}/summary",
 			methods: []string{"GET"},
 		},
+		{
+			desc:    "child shares",
+			name:    "ChildShares",
+			path:    "/children/{id}/shares",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "child share",
+			name:    "ChildShare",
+			path:    "/children/{id}/shares/{sharedFamilyID}",
+			methods: []string{"PUT"},
+		},
+		{
+			desc:    "child unshare",
+			name:    "ChildUnshare",
+			path:    "/children/{id}/shares/{sharedFamilyID}",
+			methods: []string{"DELETE"},
+		},
+		{
+			desc:    "child share accept",
+			name:    "ChildShareAccept",
+			path:    "/children/{id}/shares/{sharedFamilyID}/accept",
+			methods: []string{"PUT"},
+		},
 	}
 
 	var testEnv *goparent.Env
@@ -89,6 +116,7 @@ func TestChildSummary(t *testing.T) {
 		wasteService   goparent.WasteService
 		contextUser    *goparent.User
 		contextError   bool
+		queryString    string
 		responseCode   int
 		resultLength   int
 	}{
@@ -307,6 +335,32 @@ func TestChildSummary(t *testing.T) {
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			responseCode: http.StatusInternalServerError,
 		},
+		{
+			desc: "get child summary, invalid query",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{
+					ID:          "1",
+					Admin:       "1",
+					Members:     []string{"1"},
+					CreatedAt:   time.Now(),
+					LastUpdated: time.Now(),
+				},
+			},
+			childService: &mock.ChildService{
+				Kid: &goparent.Child{
+					Name:     "test child",
+					ID:       "1",
+					FamilyID: "1",
+					Birthday: time.Now()},
+			},
+			feedingService: &mock.FeedingService{},
+			sleepService:   &mock.SleepService{},
+			wasteService:   &mock.WasteService{},
+			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			queryString:    "?granularity=fortnight",
+			responseCode:   http.StatusBadRequest,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -319,7 +373,7 @@ func TestChildSummary(t *testing.T) {
 				WasteService:   tC.wasteService,
 			}
 
-			req, err := http.NewRequest("GET", "/children/1/summary", nil)
+			req, err := http.NewRequest("GET", "/children/1/summary"+tC.queryString, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -535,7 +589,7 @@ func TestChildrenNewHandler(t *testing.T) {
 			},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError: false,
-			responseCode: http.StatusConflict,
+			responseCode: http.StatusInternalServerError,
 		},
 		{
 			desc: "returns auth error",
@@ -569,7 +623,7 @@ func TestChildrenNewHandler(t *testing.T) {
 			childService:  &mock.ChildService{},
 			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:  false,
-			responseCode:  http.StatusInternalServerError,
+			responseCode:  http.StatusBadRequest,
 		},
 	}
 	for _, tC := range testCases {
@@ -585,7 +639,7 @@ func TestChildrenNewHandler(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			if (tC.childRequest == ChildRequest{}) {
+			if reflect.DeepEqual(tC.childRequest, ChildRequest{}) {
 				js = []byte("this is a test")
 			}
 			req, err := http.NewRequest("POST", "/children", bytes.NewReader(js))
@@ -804,7 +858,7 @@ func TestChildEditHandler(t *testing.T) {
 					Birthday: time.Now()},
 			},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
-			responseCode: http.StatusBadRequest,
+			responseCode: http.StatusNotFound,
 		},
 		{
 			desc: "returns no family error",
@@ -848,7 +902,7 @@ func TestChildEditHandler(t *testing.T) {
 			},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError: false,
-			responseCode: http.StatusNotFound,
+			responseCode: http.StatusInternalServerError,
 		},
 		{
 			desc: "returns auth error",
@@ -882,7 +936,7 @@ func TestChildEditHandler(t *testing.T) {
 			childService:  &mock.ChildService{},
 			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:  false,
-			responseCode:  http.StatusInternalServerError,
+			responseCode:  http.StatusBadRequest,
 		},
 	}
 	for _, tC := range testCases {
@@ -898,7 +952,7 @@ func TestChildEditHandler(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			if (tC.childRequest == ChildRequest{}) {
+			if reflect.DeepEqual(tC.childRequest, ChildRequest{}) {
 				js = []byte("this is a test")
 			}
 			req, err := http.NewRequest("PUT", "/children/1", bytes.NewReader(js))
@@ -1100,3 +1154,313 @@ func TestChildDeleteHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestChildAccess(t *testing.T) {
+	child := &goparent.Child{ID: "1", FamilyID: "1", Shares: []goparent.ChildShare{
+		{FamilyID: "2", Permission: goparent.ShareLog},
+		{FamilyID: "3", Permission: goparent.ShareView},
+	}}
+	testCases := []struct {
+		desc     string
+		familyID string
+		viewErr  error
+		logErr   error
+		ownErr   error
+	}{
+		{desc: "own family", familyID: "1"},
+		{desc: "shared to log", familyID: "2", ownErr: goparent.ErrNotChildOwner},
+		{desc: "shared to view", familyID: "3", logErr: goparent.ErrChildViewOnly, ownErr: goparent.ErrNotChildOwner},
+		{desc: "not shared", familyID: "4", viewErr: goparent.ErrNoChildFound, logErr: goparent.ErrNoChildFound, ownErr: goparent.ErrNoChildFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			h := Handler{ChildService: &mock.ChildService{Kid: child}}
+			family := &goparent.Family{ID: tC.familyID}
+			_, err := h.familyChild(context.Background(), family, "1")
+			assert.Equal(t, tC.viewErr, err)
+			_, err = h.loggableChild(context.Background(), family, "1")
+			assert.Equal(t, tC.logErr, err)
+			_, err = h.ownChild(context.Background(), family, "1")
+			assert.Equal(t, tC.ownErr, err)
+		})
+	}
+}
+
+func TestChildShareHandler(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		family        *goparent.Family
+		child         *goparent.Child
+		permission    string
+		familyService *mock.FamilyService
+		responseCode  int
+		errorCode     string
+		pending       bool
+	}{
+		{
+			desc:          "share to log",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy"},
+			permission:    goparent.ShareLog,
+			familyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "2", Admin: "2"}},
+			responseCode:  http.StatusOK,
+			pending:       true,
+		},
+		{
+			desc:          "sharer is in both families",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy"},
+			permission:    goparent.ShareLog,
+			familyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "2", Admin: "2", Members: []string{"2", "1"}}},
+			responseCode:  http.StatusOK,
+		},
+		{
+			desc:          "accepted share changes permission",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy", Shares: []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareView, AcceptedBy: "2"}}},
+			permission:    goparent.ShareLog,
+			familyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "2", Admin: "2"}},
+			responseCode:  http.StatusOK,
+		},
+		{
+			desc:          "bad permission",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy"},
+			permission:    "edit",
+			familyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "2", Admin: "2"}},
+			responseCode:  http.StatusBadRequest,
+			errorCode:     CodeValidation,
+		},
+		{
+			desc:          "not the admin",
+			family:        &goparent.Family{ID: "1", Admin: "2", Members: []string{"2", "1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy"},
+			permission:    goparent.ShareLog,
+			familyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "2", Admin: "2"}},
+			responseCode:  http.StatusForbidden,
+			errorCode:     CodeForbidden,
+		},
+		{
+			desc:          "shared with the family",
+			family:        &goparent.Family{ID: "2", Admin: "1", Members: []string{"1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy", Shares: []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareLog}}},
+			permission:    goparent.ShareLog,
+			familyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "3", Admin: "3"}},
+			responseCode:  http.StatusForbidden,
+			errorCode:     CodeNotChildOwner,
+		},
+		{
+			desc:          "no such family",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			child:         &goparent.Child{ID: "1", FamilyID: "1", Name: "Billy"},
+			permission:    goparent.ShareLog,
+			familyService: &mock.FamilyService{GetFamilyErr: errors.New("test error")},
+			responseCode:  http.StatusInternalServerError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			childService := &mock.ChildService{Kid: tC.child}
+			mockHandler := Handler{
+				Env:           &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:   &mock.UserService{Family: tC.family},
+				FamilyService: tC.familyService,
+				ChildService:  childService,
+			}
+
+			form := url.Values{"permission": {tC.permission}}
+			req, _ := http.NewRequest("PUT", "/children/1/shares/2", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req = mux.SetURLVars(req, map[string]string{"id": "1", "sharedFamilyID": "2"})
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+			rr := httptest.NewRecorder()
+			mockHandler.childShareHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				json.NewDecoder(rr.Body).Decode(&errBody)
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			}
+			if rr.Code == http.StatusOK {
+				var child goparent.Child
+				err := json.NewDecoder(rr.Body).Decode(&child)
+				assert.Nil(t, err)
+				if assert.Len(t, child.Shares, 1) {
+					assert.Equal(t, "2", child.Shares[0].FamilyID)
+					assert.Equal(t, "1", child.Shares[0].SharedBy)
+					assert.Equal(t, tC.pending, child.Shares[0].Pending)
+				}
+				assert.Len(t, childService.Saved, 1)
+			} else {
+				assert.Empty(t, childService.Saved)
+			}
+		})
+	}
+}
+
+func TestChildUnshareHandler(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		family         *goparent.Family
+		sharedFamilyID string
+		responseCode   int
+	}{
+		{
+			desc:           "child's own family",
+			family:         &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "2",
+			responseCode:   http.StatusNoContent,
+		},
+		{
+			desc:           "family it's shared with",
+			family:         &goparent.Family{ID: "2", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "2",
+			responseCode:   http.StatusNoContent,
+		},
+		{
+			desc:           "another shared family",
+			family:         &goparent.Family{ID: "3", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "2",
+			responseCode:   http.StatusForbidden,
+		},
+		{
+			desc:           "turned down before it's accepted",
+			family:         &goparent.Family{ID: "4", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "4",
+			responseCode:   http.StatusNoContent,
+		},
+		{
+			desc:           "not shared",
+			family:         &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "5",
+			responseCode:   http.StatusNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			child := &goparent.Child{ID: "1", FamilyID: "1", Shares: []goparent.ChildShare{
+				{FamilyID: "2", Permission: goparent.ShareLog},
+				{FamilyID: "3", Permission: goparent.ShareView},
+				{FamilyID: "4", Permission: goparent.ShareView, Pending: true},
+			}}
+			mockHandler := Handler{
+				Env:          &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:  &mock.UserService{Family: tC.family},
+				ChildService: &mock.ChildService{Kid: child},
+			}
+
+			req, _ := http.NewRequest("DELETE", "/children/1/shares/"+tC.sharedFamilyID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1", "sharedFamilyID": tC.sharedFamilyID})
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+			rr := httptest.NewRecorder()
+			mockHandler.childUnshareHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusNoContent {
+				assert.Nil(t, child.SharedWith(tC.sharedFamilyID))
+			} else {
+				assert.Len(t, child.Shares, 3)
+			}
+		})
+	}
+}
+
+func TestChildShareAcceptHandler(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		family         *goparent.Family
+		sharedFamilyID string
+		shares         []goparent.ChildShare
+		responseCode   int
+		errorCode      string
+	}{
+		{
+			desc:           "accepted",
+			family:         &goparent.Family{ID: "2", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "2",
+			shares:         []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareLog, Pending: true}},
+			responseCode:   http.StatusOK,
+		},
+		{
+			desc:           "already accepted",
+			family:         &goparent.Family{ID: "2", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "2",
+			shares:         []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareLog}},
+			responseCode:   http.StatusConflict,
+			errorCode:      CodeShareNotPending,
+		},
+		{
+			desc:           "the child's own family",
+			family:         &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "2",
+			shares:         []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareLog, Pending: true}},
+			responseCode:   http.StatusForbidden,
+			errorCode:      CodeForbidden,
+		},
+		{
+			desc:           "not the admin",
+			family:         &goparent.Family{ID: "2", Admin: "2", Members: []string{"2", "1"}},
+			sharedFamilyID: "2",
+			shares:         []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareLog, Pending: true}},
+			responseCode:   http.StatusForbidden,
+			errorCode:      CodeForbidden,
+		},
+		{
+			desc:           "not shared",
+			family:         &goparent.Family{ID: "3", Admin: "1", Members: []string{"1"}},
+			sharedFamilyID: "3",
+			shares:         []goparent.ChildShare{{FamilyID: "2", Permission: goparent.ShareLog, Pending: true}},
+			responseCode:   http.StatusNotFound,
+			errorCode:      CodeChildNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			childService := &mock.ChildService{Kid: &goparent.Child{ID: "1", FamilyID: "1", Shares: tC.shares}}
+			mockHandler := Handler{
+				Env:          &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:  &mock.UserService{Family: tC.family},
+				ChildService: childService,
+			}
+
+			req, _ := http.NewRequest("PUT", "/children/1/shares/"+tC.sharedFamilyID+"/accept", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1", "sharedFamilyID": tC.sharedFamilyID})
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+			rr := httptest.NewRecorder()
+			mockHandler.childShareAcceptHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				json.NewDecoder(rr.Body).Decode(&errBody)
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			}
+			if rr.Code == http.StatusOK {
+				var child goparent.Child
+				assert.Nil(t, json.NewDecoder(rr.Body).Decode(&child))
+				assert.True(t, child.CanLog("2"))
+				assert.Equal(t, "1", child.SharedWith("2").AcceptedBy)
+				assert.Len(t, childService.Saved, 1)
+			} else {
+				assert.Empty(t, childService.Saved)
+			}
+		})
+	}
+}
+
+func TestHouseholds(t *testing.T) {
+	assert.Nil(t, households(&goparent.Child{ID: "1", FamilyID: "1"}, &Summary{}))
+
+	child := &goparent.Child{ID: "1", FamilyID: "1", Shares: []goparent.ChildShare{
+		{FamilyID: "2", Permission: goparent.ShareLog},
+		{FamilyID: "4", Permission: goparent.ShareLog, Pending: true},
+	}}
+	summary := &Summary{
+		Feeding: goparent.FeedingSummary{Data: []goparent.Feeding{{FamilyID: "1"}, {FamilyID: "2"}, {FamilyID: "2"}}},
+		Sleep:   goparent.SleepSummary{Data: []goparent.Sleep{{FamilyID: "2"}}},
+		Waste:   goparent.WasteSummary{Data: []goparent.Waste{{FamilyID: "1"}, {FamilyID: "3"}}},
+	}
+	assert.Equal(t, []HouseholdSummary{
+		{FamilyID: "1", Owner: true, Feedings: 1, Wastes: 1},
+		{FamilyID: "2", Feedings: 2, Sleeps: 1},
+		{FamilyID: "3", Wastes: 1},
+	}, households(child, summary))
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+const (
+	problemContentType string     = "application/problem+json"
+	requestIDHeader    string     = "X-Request-ID"
+	requestIDKey       contextKey = "requestID"
+)
+
+//Error codes returned in the code field of every error response.  these are stable,
+// clients should switch on the code rather than the message.
+const (
+	CodeBadRequest               = "bad_request"
+	CodeUnauthorized             = "unauthorized"
+	CodeForbidden                = "forbidden"
+	CodeNotFound                 = "not_found"
+	CodeConflict                 = "conflict"
+	CodeInternal                 = "internal_error"
+	CodeNotImplemented           = "not_implemented"
+	CodeInvalidLogin             = "invalid_login"
+	CodeUserNotFound             = "user_not_found"
+	CodeUserExists               = "user_exists"
+	CodeInvalidResetCode         = "invalid_reset_code"
+	CodeInvalidToken             = "invalid_token"
+	CodeInvalidTwoFactorCode     = "invalid_2fa_code"
+	CodeEmailNotVerified         = "email_not_verified"
+	CodeTooManyRequests          = "too_many_requests"
+	CodeAccountLocked            = "account_locked"
+	CodeInvitationExists         = "invitation_exists"
+	CodeInvitationNotFound       = "invitation_not_found"
+	CodeInvitationExpired        = "invitation_expired"
+	CodeInvitationAnswered       = "invitation_answered"
+	CodeSleepAlreadyActive       = "sleep_already_started"
+	CodeNoSleepSession           = "no_sleep_session"
+	CodeInvalidGranularity       = "invalid_granularity"
+	CodeInvalidDateRange         = "invalid_date_range"
+	CodeInvalidUnit              = "invalid_unit"
+	CodeInvalidUnitSystem        = "invalid_unit_system"
+	CodeValidation               = "validation_failed"
+	CodeChildNotFound            = "child_not_found"
+	CodeChildViewOnly            = "child_view_only"
+	CodeNotChildOwner            = "not_child_owner"
+	CodeShareNotFound            = "share_not_found"
+	CodeShareNotPending          = "share_not_pending"
+	CodeFamilyAdmin              = "family_admin"
+	CodeNotFamilyMember          = "not_family_member"
+	CodeConfirmationRequired     = "confirmation_required"
+	CodeReauthRequired           = "reauthentication_required"
+	CodeUnavailable              = "unavailable"
+	CodeWebhookNotFound          = "webhook_not_found"
+	CodeAPIKeyNotFound           = "api_key_not_found"
+	CodeReminderNotFound         = "reminder_not_found"
+	CodeNotificationNotFound     = "notification_not_found"
+	CodeNotificationAcknowledged = "notification_acknowledged"
+	CodeIdentityNotFound         = "identity_not_found"
+	CodeIdentityLinked           = "identity_linked"
+	CodeIdentityProvider         = "identity_provider_error"
+)
+
+//APIError - an error with the http status and code it should be returned to the client with.
+type APIError struct {
+	Status     int
+	Code       string
+	Message    string
+	Fields     []goparent.FieldError
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+//Unwrap - the underlying error, if there is one
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+//NewAPIError - create an error with a status, code and client safe message
+func NewAPIError(status int, code string, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+//domainErrors - errors from the services that mean something to the client, in the
+// order they are checked.  anything not in here is an internal error.
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{goparent.ErrInvalidLogin, http.StatusUnauthorized, CodeInvalidLogin},
+	{goparent.ErrNoUserFound, http.StatusNotFound, CodeUserNotFound},
+	{goparent.ErrNoChildFound, http.StatusNotFound, CodeChildNotFound},
+	{goparent.ErrChildViewOnly, http.StatusForbidden, CodeChildViewOnly},
+	{goparent.ErrNotChildOwner, http.StatusForbidden, CodeNotChildOwner},
+	{goparent.ErrNoShareFound, http.StatusNotFound, CodeShareNotFound},
+	{goparent.ErrShareNotPending, http.StatusConflict, CodeShareNotPending},
+	{goparent.ErrNotFamilyMember, http.StatusNotFound, CodeNotFamilyMember},
+	{goparent.ErrFamilyAdmin, http.StatusConflict, CodeFamilyAdmin},
+	{goparent.ErrNoWebhookFound, http.StatusNotFound, CodeWebhookNotFound},
+	{goparent.ErrNoAPIKeyFound, http.StatusNotFound, CodeAPIKeyNotFound},
+	{goparent.ErrNoIdentityFound, http.StatusNotFound, CodeIdentityNotFound},
+	{goparent.ErrIdentityLinked, http.StatusConflict, CodeIdentityLinked},
+	{goparent.ErrInvalidIDToken, http.StatusUnauthorized, CodeInvalidToken},
+	{goparent.ErrNoReminderFound, http.StatusNotFound, CodeReminderNotFound},
+	{goparent.ErrNoNotificationFound, http.StatusNotFound, CodeNotificationNotFound},
+	{goparent.ErrExistingUser, http.StatusConflict, CodeUserExists},
+	{goparent.ErrEmailNotVerified, http.StatusForbidden, CodeEmailNotVerified},
+	{goparent.ErrInvalidResetCode, http.StatusBadRequest, CodeInvalidResetCode},
+	{goparent.ErrExistingInvitation, http.StatusConflict, CodeInvitationExists},
+	{goparent.ErrNoInvitationFound, http.StatusNotFound, CodeInvitationNotFound},
+	{goparent.ErrInvitationExpired, http.StatusGone, CodeInvitationExpired},
+	{goparent.ErrInvitationAnswered, http.StatusConflict, CodeInvitationAnswered},
+	{goparent.ErrExistingStart, http.StatusConflict, CodeSleepAlreadyActive},
+	{goparent.ErrNoExistingSession, http.StatusNotFound, CodeNoSleepSession},
+	{goparent.ErrInvalidGranularity, http.StatusBadRequest, CodeInvalidGranularity},
+	{goparent.ErrInvalidDateRange, http.StatusBadRequest, CodeInvalidDateRange},
+	{goparent.ErrDateRangeTooLong, http.StatusBadRequest, CodeInvalidDateRange},
+	{goparent.ErrInvalidUnit, http.StatusBadRequest, CodeInvalidUnit},
+	{goparent.ErrIncompatibleUnits, http.StatusBadRequest, CodeInvalidUnit},
+	{goparent.ErrInvalidUnitSystem, http.StatusBadRequest, CodeInvalidUnitSystem},
+}
+
+var (
+	errUnauthorized   = NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
+	errNotFound       = NewAPIError(http.StatusNotFound, CodeNotFound, "not found")
+	errNotImplemented = NewAPIError(http.StatusNotImplemented, CodeNotImplemented, "not implemented")
+	errInternal       = NewAPIError(http.StatusInternalServerError, CodeInternal, "internal server error")
+)
+
+//toAPIError - find the status and code for an error.  unknown errors are internal
+// and their message is not passed on to the client.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var validationErr *goparent.ValidationError
+	if errors.As(err, &validationErr) {
+		return &APIError{Status: http.StatusBadRequest, Code: CodeValidation, Message: "validation failed", Fields: validationErr.Fields, Err: err}
+	}
+	for _, d := range domainErrors {
+		if errors.Is(err, d.err) {
+			return &APIError{Status: d.status, Code: d.code, Message: d.err.Error(), Err: err}
+		}
+	}
+	return &APIError{Status: errInternal.Status, Code: errInternal.Code, Message: errInternal.Message, Err: err}
+}
+
+//badRequest - the client sent something we can't use.  domain errors keep their own code.
+func badRequest(err error) error {
+	apiErr := toAPIError(err)
+	if apiErr.Status != http.StatusInternalServerError {
+		return apiErr
+	}
+	return &APIError{Status: http.StatusBadRequest, Code: CodeBadRequest, Message: err.Error(), Err: err}
+}
+
+//forbidden - the user is known but isn't allowed to do this
+func forbidden(message string) error {
+	return NewAPIError(http.StatusForbidden, CodeForbidden, message)
+}
+
+//tooManyRequests - the client has to wait before trying again, the wait is sent in Retry-After
+func tooManyRequests(wait time.Duration) error {
+	return &APIError{Status: http.StatusTooManyRequests, Code: CodeTooManyRequests, Message: "too many requests, try again later", RetryAfter: wait}
+}
+
+//ErrorBody - the details of an error response
+type ErrorBody struct {
+	Code      string                `json:"code"`
+	Status    int                   `json:"status"`
+	Message   string                `json:"message"`
+	Fields    []goparent.FieldError `json:"fields,omitempty"`
+	RequestID string                `json:"requestId,omitempty"`
+}
+
+//ErrService - error envelope returned by every failed service call
+type ErrService struct {
+	ErrMessage ErrorBody `json:"error"`
+}
+
+//ProblemDetails - RFC 7807 error response, returned when the client accepts application/problem+json
+type ProblemDetails struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	Errors    []goparent.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"requestId,omitempty"`
+}
+
+//writeError - write the error to the client as json, or as problem+json if the client asks
+// for it.  internal errors are logged with the request id and the client gets a generic message.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	requestID := RequestIDFromContext(r.Context())
+	if apiErr.Status >= http.StatusInternalServerError && apiErr.Err != nil {
+		log.Printf("request %s: %s %s: %s", requestID, r.Method, r.URL.Path, apiErr.Err)
+	}
+	if apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), problemContentType) {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(apiErr.Status)
+		json.NewEncoder(w).Encode(ProblemDetails{
+			Type:      "about:blank",
+			Title:     http.StatusText(apiErr.Status),
+			Status:    apiErr.Status,
+			Detail:    apiErr.Message,
+			Instance:  r.URL.Path,
+			Code:      apiErr.Code,
+			Errors:    apiErr.Fields,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	var errMsg ErrService
+	errMsg.ErrMessage = ErrorBody{
+		Code:      apiErr.Code,
+		Status:    apiErr.Status,
+		Message:   apiErr.Message,
+		Fields:    apiErr.Fields,
+		RequestID: requestID,
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(errMsg)
+}
+
+//RequestIDMiddleware - give every request an id, using the client's X-Request-ID if it sent
+// one.  the id is echoed back in the response header and included in error responses.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+//RequestIDFromContext - the request id set by RequestIDMiddleware, blank if there isn't one
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
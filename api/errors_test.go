@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToAPIError(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{
+			desc:    "domain error",
+			err:     goparent.ErrExistingStart,
+			status:  http.StatusConflict,
+			code:    CodeSleepAlreadyActive,
+			message: goparent.ErrExistingStart.Error(),
+		},
+		{
+			desc:    "wrapped domain error",
+			err:     fmt.Errorf("datastore.UserService.User: %w", goparent.ErrNoUserFound),
+			status:  http.StatusNotFound,
+			code:    CodeUserNotFound,
+			message: goparent.ErrNoUserFound.Error(),
+		},
+		{
+			desc:    "api error",
+			err:     forbidden("nope"),
+			status:  http.StatusForbidden,
+			code:    CodeForbidden,
+			message: "nope",
+		},
+		{
+			desc:    "internal error is not leaked",
+			err:     errors.New("connection refused to db01.internal:28015"),
+			status:  http.StatusInternalServerError,
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+		{
+			desc:    "bad request",
+			err:     badRequest(errors.New("unexpected EOF")),
+			status:  http.StatusBadRequest,
+			code:    CodeBadRequest,
+			message: "unexpected EOF",
+		},
+		{
+			desc:    "bad request keeps domain code",
+			err:     badRequest(goparent.ErrInvalidGranularity),
+			status:  http.StatusBadRequest,
+			code:    CodeInvalidGranularity,
+			message: goparent.ErrInvalidGranularity.Error(),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			apiErr := toAPIError(tC.err)
+			assert.Equal(t, tC.status, apiErr.Status)
+			assert.Equal(t, tC.code, apiErr.Code)
+			assert.Equal(t, tC.message, apiErr.Message)
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		accept      string
+		contentType string
+	}{
+		{desc: "json envelope", contentType: jsonContentType},
+		{desc: "problem json", accept: "application/problem+json, application/json", contentType: problemContentType},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/feeding", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(requestIDHeader, "abc123")
+			if tC.accept != "" {
+				req.Header.Set("Accept", tC.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, errors.New("secret internal detail"))
+			}))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusInternalServerError, rr.Code)
+			assert.Equal(t, tC.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, "abc123", rr.Header().Get(requestIDHeader))
+			assert.NotContains(t, rr.Body.String(), "secret")
+
+			if tC.contentType == problemContentType {
+				var result ProblemDetails
+				err = json.NewDecoder(rr.Body).Decode(&result)
+				assert.Nil(t, err)
+				assert.Equal(t, http.StatusInternalServerError, result.Status)
+				assert.Equal(t, CodeInternal, result.Code)
+				assert.Equal(t, "abc123", result.RequestID)
+				assert.Equal(t, "/api/feeding", result.Instance)
+			} else {
+				var result ErrService
+				err = json.NewDecoder(rr.Body).Decode(&result)
+				assert.Nil(t, err)
+				assert.Equal(t, http.StatusInternalServerError, result.ErrMessage.Status)
+				assert.Equal(t, CodeInternal, result.ErrMessage.Code)
+				assert.Equal(t, "abc123", result.ErrMessage.RequestID)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	req, err := http.NewRequest("GET", "/api/info", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, rr.Header().Get(requestIDHeader))
+
+	req.Header.Set(requestIDHeader, "from-client")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "from-client", seen)
+	assert.Equal(t, "from-client", rr.Header().Get(requestIDHeader))
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/request"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sasimpson/goparent"
+)
+
+const eventStreamContentType string = "text/event-stream"
+
+//heartbeatInterval - how often an idle stream gets a comment or ping so proxies don't close it
+var heartbeatInterval = 30 * time.Second
+
+//streamTokenExtractor - browsers can't set headers on EventSource or WebSocket requests,
+// so streams also take the token as an access_token query parameter.
+var streamTokenExtractor = request.MultiExtractor{
+	request.AuthorizationHeaderExtractor,
+	request.ArgumentExtractor{"access_token"},
+}
+
+//the token is checked before the upgrade, so any origin can connect
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+var errEventsUnavailable = NewAPIError(http.StatusServiceUnavailable, CodeUnavailable, "event stream unavailable")
+
+func (h *Handler) initEventsHandlers(r *mux.Router) {
+	r.Handle("/events", h.authRequired(h.eventsHandler(), streamTokenExtractor)).Methods("GET").Name("Events")
+}
+
+//eventsHandler - stream the events in the user's family, over a websocket if the client
+// asks for an upgrade and as server sent events otherwise.
+func (h *Handler) eventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if h.Env.Events == nil {
+			writeError(w, r, errEventsUnavailable)
+			return
+		}
+
+		family, err := h.requestFamily(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if websocket.IsWebSocketUpgrade(r) {
+			h.streamWebSocket(w, r, user, family)
+			return
+		}
+		h.streamSSE(w, r, user, family)
+	})
+}
+
+//revokes - whether the event takes the user out of the family, the event is still sent
+// so the client knows why the stream ended.
+func revokes(event goparent.Event, user *goparent.User) bool {
+	if event.Resource != goparent.ResourceFamily {
+		return false
+	}
+	if event.Type == goparent.EventDeleted {
+		return true
+	}
+	family, ok := event.Data.(*goparent.Family)
+	return ok && !family.IsMember(user.ID)
+}
+
+func (h *Handler) streamSSE(w http.ResponseWriter, r *http.Request, user *goparent.User, family *goparent.Family) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errEventsUnavailable)
+		return
+	}
+
+	events, cancel := h.Env.Events.Subscribe(family.ID)
+	defer cancel()
+
+	//streams outlive the server's write timeout, the heartbeat notices dead clients instead
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Name(), data)
+			if err != nil {
+				return
+			}
+			if revokes(event, user) {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (h *Handler) streamWebSocket(w http.ResponseWriter, r *http.Request, user *goparent.User, family *goparent.Family) {
+	//subscribe first so nothing published while the client finishes connecting is missed
+	events, cancel := h.Env.Events.Subscribe(family.ID)
+	defer cancel()
+
+	//the upgrader writes its own error response
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	//nothing is read from the client, but reading is how a close is noticed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeatInterval))
+			if err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			err := conn.WriteJSON(event)
+			if err != nil {
+				return
+			}
+			if revokes(event, user) {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "no longer a member of the family"), time.Now().Add(time.Second))
+				return
+			}
+		}
+	}
+}
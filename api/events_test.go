@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/metrics"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEventsServer(bus goparent.EventBus, m *metrics.Metrics) (*httptest.Server, string) {
+	user := &goparent.User{ID: "1", Name: "test user", Username: "testuser"}
+	env := &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("testkey")}, Events: bus}
+	h := &Handler{
+		Env:         env,
+		UserService: &mock.UserService{ReturnedUser: user, Family: &goparent.Family{ID: "1"}},
+		Metrics:     m,
+	}
+	return httptest.NewServer(BuildAPIRouting(h)), makeTestToken(user, env.Auth.SigningKey)
+}
+
+func TestEventsHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		bus          goparent.EventBus
+		userService  *mock.UserService
+		responseCode int
+		errorCode    string
+	}{
+		{
+			desc:         "no event bus",
+			userService:  &mock.UserService{Family: &goparent.Family{ID: "1"}},
+			responseCode: http.StatusServiceUnavailable,
+			errorCode:    CodeUnavailable,
+		},
+		{
+			desc:         "family error",
+			bus:          goparent.NewEventBus(),
+			userService:  &mock.UserService{FamilyErr: goparent.ErrNoUserFound},
+			responseCode: http.StatusNotFound,
+			errorCode:    CodeUserNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"})
+			req = req.WithContext(ctx)
+
+			mockHandler := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}, Events: tC.bus}, UserService: tC.userService}
+			rr := httptest.NewRecorder()
+			mockHandler.eventsHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			var errBody ErrService
+			err = json.NewDecoder(rr.Body).Decode(&errBody)
+			assert.Nil(t, err)
+			assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+		})
+	}
+}
+
+func TestEventsSSE(t *testing.T) {
+	defer func(interval time.Duration) { heartbeatInterval = interval }(heartbeatInterval)
+	heartbeatInterval = 10 * time.Millisecond
+
+	bus := goparent.NewEventBus()
+	server, token := newEventsServer(bus, nil)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/events?access_token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, eventStreamContentType, resp.Header.Get("Content-Type"))
+
+	//an idle stream gets heartbeats
+	scanner := bufio.NewScanner(resp.Body)
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, ": heartbeat", scanner.Text())
+
+	//other families' events are left out
+	bus.Publish(goparent.Event{Type: goparent.EventCreated, Resource: goparent.ResourceFeeding, FamilyID: "2"})
+	bus.Publish(goparent.Event{Type: goparent.EventStarted, Resource: goparent.ResourceSleep, FamilyID: "1", ChildID: "1"})
+
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line == ": heartbeat" {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if assert.Len(t, lines, 3) {
+		assert.True(t, strings.HasPrefix(lines[0], "id: "))
+		assert.Equal(t, "event: sleep.started", lines[1])
+		var event goparent.Event
+		err = json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &event)
+		assert.Nil(t, err)
+		assert.Equal(t, "1", event.ChildID)
+		assert.Equal(t, goparent.EventStarted, event.Type)
+	}
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		metrics *metrics.Metrics
+	}{
+		{desc: "without metrics"},
+		{desc: "through the metrics middleware", metrics: metrics.New(&mock.DBEnv{})},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			bus := goparent.NewEventBus()
+			server, token := newEventsServer(bus, tC.metrics)
+			defer server.Close()
+			url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/events"
+
+			_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+			assert.Equal(t, websocket.ErrBadHandshake, err)
+			if assert.NotNil(t, resp) {
+				assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			}
+
+			conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			bus.Publish(goparent.Event{Type: goparent.EventDeleted, Resource: goparent.ResourceChild, FamilyID: "2"})
+			bus.Publish(goparent.Event{Type: goparent.EventCreated, Resource: goparent.ResourceWaste, FamilyID: "1", ChildID: "1"})
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var event goparent.Event
+			err = conn.ReadJSON(&event)
+			assert.Nil(t, err)
+			assert.Equal(t, "waste.created", event.Name())
+			assert.NotEmpty(t, event.ID)
+		})
+	}
+}
+
+func TestRevokes(t *testing.T) {
+	user := &goparent.User{ID: "1"}
+	testCases := []struct {
+		desc    string
+		event   goparent.Event
+		revokes bool
+	}{
+		{
+			desc:  "other resource",
+			event: goparent.Event{Type: goparent.EventDeleted, Resource: goparent.ResourceChild, FamilyID: "1"},
+		},
+		{
+			desc:  "still a member",
+			event: goparent.Event{Type: goparent.EventUpdated, Resource: goparent.ResourceFamily, FamilyID: "1", Data: &goparent.Family{ID: "1", Admin: "2", Members: []string{"2", "1"}}},
+		},
+		{
+			desc:    "removed from the family",
+			event:   goparent.Event{Type: goparent.EventUpdated, Resource: goparent.ResourceFamily, FamilyID: "1", Data: &goparent.Family{ID: "1", Admin: "2", Members: []string{"2"}}},
+			revokes: true,
+		},
+		{
+			desc:    "family deleted",
+			event:   goparent.Event{Type: goparent.EventDeleted, Resource: goparent.ResourceFamily, FamilyID: "1"},
+			revokes: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.revokes, revokes(tC.event, user))
+		})
+	}
+}
+
+func TestEventsSSERevoked(t *testing.T) {
+	bus := goparent.NewEventBus()
+	server, token := newEventsServer(bus, nil)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/events?access_token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	//the user is told why and the stream ends
+	bus.Publish(goparent.Event{Type: goparent.EventDeleted, Resource: goparent.ResourceFamily, FamilyID: "1"})
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "event: family.deleted")
+}
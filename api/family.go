@@ -0,0 +1,322 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+//familyDeleteWindow - how long a family delete confirmation can be used for
+const familyDeleteWindow = 10 * time.Minute
+
+var errDeleteUnconfirmed = NewAPIError(http.StatusBadRequest, CodeConfirmationRequired, "deleting a family needs a current confirmation from POST /api/family/delete")
+
+//FamilyRequest - incoming request structure for family settings
+type FamilyRequest struct {
+	FamilyData FamilySettings `json:"familyData"`
+}
+
+//FamilySettings - the settings to change, the ones left out are kept
+type FamilySettings struct {
+	Timezone *string              `json:"timezone,omitempty"`
+	Units    *goparent.UnitSystem `json:"units,omitempty"`
+}
+
+//FamilyResponse - response structure for a family
+type FamilyResponse struct {
+	FamilyData *goparent.Family `json:"familyData"`
+}
+
+//FamilyDeleteResponse - what deleting the family will remove, and the confirmation to
+// send with the delete
+type FamilyDeleteResponse struct {
+	Confirm   string    `json:"confirm"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Members   int       `json:"members"`
+	Children  int       `json:"children"`
+}
+
+//FamiliesResponse - the families a user belongs to and which one is current
+type FamiliesResponse struct {
+	FamilyData    []*goparent.Family `json:"familyData"`
+	CurrentFamily string             `json:"currentFamily"`
+}
+
+func (h *Handler) initFamilyHandlers(r *mux.Router) {
+	f := r.PathPrefix("/family").Subrouter()
+	f.Handle("", h.AuthRequired(h.familyGetHandler())).Methods("GET").Name("FamilyGet")
+	f.Handle("", h.AuthRequired(h.familyEditHandler())).Methods("PUT").Name("FamilyEdit")
+	f.Handle("", h.AuthRequired(h.familyDeleteHandler())).Methods("DELETE").Name("FamilyDelete")
+	f.Handle("/delete", h.AuthRequired(h.familyDeleteConfirmHandler())).Methods("POST").Name("FamilyDeleteConfirm")
+	f.Handle("/admin", h.AuthRequired(h.familyTransferHandler())).Methods("PUT").Name("FamilyTransfer")
+	f.Handle("/leave", h.AuthRequired(h.familyLeaveHandler())).Methods("POST").Name("FamilyLeave")
+	f.Handle("/members/{userID}", h.AuthRequired(h.familyRemoveMemberHandler())).Methods("DELETE").Name("FamilyRemoveMember")
+
+	fs := r.PathPrefix("/families").Subrouter()
+	fs.Handle("", h.AuthRequired(h.familyListHandler())).Methods("GET").Name("FamilyList")
+	fs.Handle("/{familyID}", h.AuthRequired(h.familyGetHandler())).Methods("GET").Name("FamilyView")
+}
+
+//familyListHandler - GET /families - every family the user is a member of
+func (h *Handler) familyListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		families, err := h.UserService.GetAllFamily(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if families == nil {
+			families = []*goparent.Family{}
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(FamiliesResponse{FamilyData: families, CurrentFamily: user.CurrentFamily})
+	})
+}
+
+//familyGetHandler - GET / - get the user's current family
+func (h *Handler) familyGetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		family, err := h.requestFamily(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(FamilyResponse{FamilyData: family})
+	})
+}
+
+//familyEditHandler - PUT / - update the settings for the user's current family.
+// only the family admin can change settings, and membership can't be changed here.
+func (h *Handler) familyEditHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		family, err := h.requestFamily(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if family.Admin != user.ID {
+			writeError(w, r, forbidden("only the family admin can change family settings"))
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		var familyRequest FamilyRequest
+		err = decoder.Decode(&familyRequest)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		defer r.Body.Close()
+
+		settings := familyRequest.FamilyData
+		if settings.Timezone != nil {
+			family.Timezone = *settings.Timezone
+			_, err = family.Location()
+			if err != nil {
+				writeError(w, r, badRequest(errors.New("invalid timezone")))
+				return
+			}
+		}
+
+		if settings.Units != nil {
+			family.Units = *settings.Units
+			if !family.Units.Valid() {
+				writeError(w, r, goparent.ErrInvalidUnitSystem)
+				return
+			}
+		}
+
+		err = h.FamilyService.Save(ctx, family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(FamilyResponse{FamilyData: family})
+	})
+}
+
+//adminFamily - the user and family from the request, if the user is the family's admin
+func (h *Handler) adminFamily(w http.ResponseWriter, r *http.Request, action string) (*goparent.User, *goparent.Family, bool) {
+	user, family, ok := h.userFamily(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	if family.Admin != user.ID {
+		writeError(w, r, forbidden("only the family admin can "+action))
+		return nil, nil, false
+	}
+	return user, family, true
+}
+
+//familyRemoveMemberHandler - DELETE /members/{userID} - the admin takes someone out of the family
+func (h *Handler) familyRemoveMemberHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		_, family, ok := h.adminFamily(w, r, "remove members")
+		if !ok {
+			return
+		}
+
+		userID := mux.Vars(r)["userID"]
+		if !family.IsMember(userID) {
+			writeError(w, r, goparent.ErrNotFamilyMember)
+			return
+		}
+		member, err := h.UserService.User(ctx, userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.FamilyService.RemoveMember(ctx, family, member)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//familyLeaveHandler - POST /leave - a member leaves the family, the admin has to transfer
+// ownership first
+func (h *Handler) familyLeaveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+
+		err := h.FamilyService.RemoveMember(ctx, family, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//familyTransferHandler - PUT /admin - the admin hands the family to another member
+func (h *Handler) familyTransferHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		_, family, ok := h.adminFamily(w, r, "transfer ownership")
+		if !ok {
+			return
+		}
+
+		userID := r.FormValue("userID")
+		if userID == "" {
+			writeError(w, r, badRequest(errors.New("no userID submitted")))
+			return
+		}
+		err := family.TransferAdmin(userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.FamilyService.Save(ctx, family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(FamilyResponse{FamilyData: family})
+	})
+}
+
+//familyDeleteConfirmHandler - POST /delete - what deleting the family will remove, with
+// the confirmation the delete needs
+func (h *Handler) familyDeleteConfirmHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, family, ok := h.adminFamily(w, r, "delete the family")
+		if !ok {
+			return
+		}
+
+		children, err := h.FamilyService.Children(ctx, family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		expires := time.Now().Add(familyDeleteWindow)
+		confirm, err := h.familyDeleteToken(family, user, expires)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(FamilyDeleteResponse{
+			Confirm:   confirm,
+			ExpiresAt: expires,
+			Members:   len(family.Members),
+			Children:  len(children),
+		})
+	})
+}
+
+//familyDeleteHandler - DELETE /?confirm= - delete the family and everything in it
+func (h *Handler) familyDeleteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, family, ok := h.adminFamily(w, r, "delete the family")
+		if !ok {
+			return
+		}
+		if !h.validFamilyDeleteToken(r.FormValue("confirm"), family, user) {
+			writeError(w, r, errDeleteUnconfirmed)
+			return
+		}
+
+		err := h.FamilyService.Delete(ctx, family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//familyDeleteToken - a short lived token that says the admin asked to delete the family
+func (h *Handler) familyDeleteToken(family *goparent.Family, user *goparent.User, expires time.Time) (string, error) {
+	return h.purposeToken("family.delete", jwt.MapClaims{"family": family.ID, "user": user.ID}, expires)
+}
+
+func (h *Handler) validFamilyDeleteToken(confirm string, family *goparent.Family, user *goparent.User) bool {
+	claims, ok := h.purposeClaims(confirm, "family.delete")
+	return ok && claims["family"] == family.ID && claims["user"] == user.ID
+}
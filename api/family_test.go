@@ -0,0 +1,621 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFamilyGetHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		env          *goparent.Env
+		userService  goparent.UserService
+		contextUser  *goparent.User
+		contextError bool
+		responseCode int
+	}{
+		{
+			desc: "get family",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}, Timezone: "America/Los_Angeles"},
+			},
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode: http.StatusOK,
+		},
+		{
+			desc: "get family error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			userService: &mock.UserService{
+				FamilyErr: errors.New("user has no current family"),
+			},
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "auth error",
+			env:          &goparent.Env{DB: &mock.DBEnv{}},
+			userService:  &mock.UserService{},
+			contextUser:  &goparent.User{},
+			contextError: true,
+			responseCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         tC.env,
+				UserService: tC.userService,
+			}
+
+			req, err := http.NewRequest("GET", "/family", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := mockHandler.familyGetHandler()
+			rr := httptest.NewRecorder()
+
+			ctx := req.Context()
+			if tC.contextError == true {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			}
+
+			req = req.WithContext(ctx)
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.responseCode == http.StatusOK {
+				var result FamilyResponse
+				err := json.NewDecoder(rr.Body).Decode(&result)
+				assert.Nil(t, err)
+				assert.Equal(t, "America/Los_Angeles", result.FamilyData.Timezone)
+			}
+		})
+	}
+}
+
+func TestFamilyEditHandler(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		env           *goparent.Env
+		body          string
+		userService   goparent.UserService
+		familyService goparent.FamilyService
+		contextUser   *goparent.User
+		contextError  bool
+		responseCode  int
+		timezone      string
+		units         goparent.UnitSystem
+	}{
+		{
+			desc: "set timezone keeps units",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"timezone":"America/Los_Angeles"}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}, Units: goparent.UnitSystemImperial, CreatedAt: time.Now(), LastUpdated: time.Now()},
+			},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusOK,
+			timezone:      "America/Los_Angeles",
+			units:         goparent.UnitSystemImperial,
+		},
+		{
+			desc: "set both back to the defaults",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"timezone":"","units":""}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}, Timezone: "Europe/London", Units: goparent.UnitSystemImperial},
+			},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusOK,
+		},
+		{
+			desc: "invalid timezone",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"timezone":"Mars/Olympus_Mons"}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusBadRequest,
+		},
+		{
+			desc: "set units keeps timezone",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"units":"metric"}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}, Timezone: "Europe/London"},
+			},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusOK,
+			timezone:      "Europe/London",
+			units:         goparent.UnitSystemMetric,
+		},
+		{
+			desc: "invalid units",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"units":"cubits"}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusBadRequest,
+		},
+		{
+			desc: "not the admin",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"timezone":"America/Los_Angeles"}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "2", Members: []string{"1", "2"}},
+			},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusForbidden,
+		},
+		{
+			desc: "save error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			body: `{"familyData":{"timezone":"UTC"}}`,
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService: &mock.FamilyService{SaveErr: errors.New("test error")},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusInternalServerError,
+		},
+		{
+			desc:          "auth error",
+			env:           &goparent.Env{DB: &mock.DBEnv{}},
+			userService:   &mock.UserService{},
+			familyService: &mock.FamilyService{},
+			contextUser:   &goparent.User{},
+			contextError:  true,
+			responseCode:  http.StatusUnauthorized,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:           tC.env,
+				UserService:   tC.userService,
+				FamilyService: tC.familyService,
+			}
+
+			req, err := http.NewRequest("PUT", "/family", strings.NewReader(tC.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := mockHandler.familyEditHandler()
+			rr := httptest.NewRecorder()
+
+			ctx := req.Context()
+			if tC.contextError == true {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			}
+
+			req = req.WithContext(ctx)
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.responseCode == http.StatusOK {
+				var result FamilyResponse
+				err := json.NewDecoder(rr.Body).Decode(&result)
+				assert.Nil(t, err)
+				assert.Equal(t, tC.timezone, result.FamilyData.Timezone)
+				assert.Equal(t, tC.units, result.FamilyData.Units)
+			}
+		})
+	}
+}
+
+func TestFamilyListHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		userService  goparent.UserService
+		contextUser  *goparent.User
+		responseCode int
+		resultLength int
+	}{
+		{
+			desc:         "auth error",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "families error",
+			userService:  &mock.UserService{FamilyErr: errors.New("test error")},
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "1"},
+			responseCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "no families",
+			userService:  &mock.UserService{},
+			contextUser:  &goparent.User{ID: "1"},
+			responseCode: http.StatusOK,
+		},
+		{
+			desc: "two families",
+			userService: &mock.UserService{Families: []*goparent.Family{
+				{ID: "1", Admin: "1", Members: []string{"1"}},
+				{ID: "2", Admin: "2", Members: []string{"2", "1"}, Roles: []goparent.MemberRole{{UserID: "1", Role: goparent.RoleCaregiver}}},
+			}},
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "2"},
+			responseCode: http.StatusOK,
+			resultLength: 2,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}},
+				UserService: tC.userService,
+			}
+
+			req, _ := http.NewRequest("GET", "/families", nil)
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+			mockHandler.familyListHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var result FamiliesResponse
+				err := json.NewDecoder(rr.Body).Decode(&result)
+				assert.Nil(t, err)
+				assert.Len(t, result.FamilyData, tC.resultLength)
+				assert.Equal(t, tC.contextUser.CurrentFamily, result.CurrentFamily)
+			}
+		})
+	}
+}
+
+func TestFamilyRemoveMemberHandler(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		family        *goparent.Family
+		member        *goparent.User
+		userID        string
+		familyService *mock.FamilyService
+		responseCode  int
+		errorCode     string
+	}{
+		{
+			desc:          "remove a member",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}},
+			member:        &goparent.User{ID: "2", CurrentFamily: "1"},
+			userID:        "2",
+			familyService: &mock.FamilyService{},
+			responseCode:  http.StatusNoContent,
+		},
+		{
+			desc:          "not the admin",
+			family:        &goparent.Family{ID: "1", Admin: "2", Members: []string{"2", "1", "3"}},
+			member:        &goparent.User{ID: "3"},
+			userID:        "3",
+			familyService: &mock.FamilyService{},
+			responseCode:  http.StatusForbidden,
+			errorCode:     CodeForbidden,
+		},
+		{
+			desc:          "not a member",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			userID:        "2",
+			familyService: &mock.FamilyService{},
+			responseCode:  http.StatusNotFound,
+			errorCode:     CodeNotFamilyMember,
+		},
+		{
+			desc:          "the admin removing themselves",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}},
+			member:        &goparent.User{ID: "1"},
+			userID:        "1",
+			familyService: &mock.FamilyService{},
+			responseCode:  http.StatusConflict,
+			errorCode:     CodeFamilyAdmin,
+		},
+		{
+			desc:          "remove error",
+			family:        &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}},
+			member:        &goparent.User{ID: "2"},
+			userID:        "2",
+			familyService: &mock.FamilyService{RemoveErr: errors.New("test error")},
+			responseCode:  http.StatusInternalServerError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:           &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:   &mock.UserService{Family: tC.family, ReturnedUser: tC.member},
+				FamilyService: tC.familyService,
+			}
+
+			req, _ := http.NewRequest("DELETE", "/family/members/"+tC.userID, nil)
+			req = mux.SetURLVars(req, map[string]string{"userID": tC.userID})
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+			rr := httptest.NewRecorder()
+			mockHandler.familyRemoveMemberHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				json.NewDecoder(rr.Body).Decode(&errBody)
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			}
+			if rr.Code == http.StatusNoContent {
+				assert.Equal(t, []*goparent.User{tC.member}, tC.familyService.Removed)
+				assert.False(t, tC.family.IsMember(tC.userID))
+			}
+		})
+	}
+}
+
+func TestFamilyLeaveHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		family       *goparent.Family
+		responseCode int
+		errorCode    string
+	}{
+		{
+			desc:         "member leaves",
+			family:       &goparent.Family{ID: "1", Admin: "2", Members: []string{"2", "1"}},
+			responseCode: http.StatusNoContent,
+		},
+		{
+			desc:         "admin can't leave",
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}},
+			responseCode: http.StatusConflict,
+			errorCode:    CodeFamilyAdmin,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			user := &goparent.User{ID: "1", CurrentFamily: "3"}
+			familyService := &mock.FamilyService{}
+			mockHandler := Handler{
+				Env:           &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:   &mock.UserService{Family: tC.family},
+				FamilyService: familyService,
+			}
+
+			req, _ := http.NewRequest("POST", "/family/leave", nil)
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+			rr := httptest.NewRecorder()
+			mockHandler.familyLeaveHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				json.NewDecoder(rr.Body).Decode(&errBody)
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			} else {
+				assert.Equal(t, []*goparent.User{user}, familyService.Removed)
+			}
+		})
+	}
+}
+
+func TestFamilyTransferHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		family       *goparent.Family
+		userID       string
+		saveErr      error
+		responseCode int
+	}{
+		{
+			desc:         "transfer",
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}, Roles: []goparent.MemberRole{{UserID: "2", Role: goparent.RoleCaregiver}}},
+			userID:       "2",
+			responseCode: http.StatusOK,
+		},
+		{
+			desc:         "no user",
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "not a member",
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			userID:       "2",
+			responseCode: http.StatusNotFound,
+		},
+		{
+			desc:         "not the admin",
+			family:       &goparent.Family{ID: "1", Admin: "2", Members: []string{"1", "2"}},
+			userID:       "1",
+			responseCode: http.StatusForbidden,
+		},
+		{
+			desc:         "save error",
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}},
+			userID:       "2",
+			saveErr:      errors.New("test error"),
+			responseCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:           &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:   &mock.UserService{Family: tC.family},
+				FamilyService: &mock.FamilyService{SaveErr: tC.saveErr},
+			}
+
+			form := url.Values{"userID": {tC.userID}}
+			req, _ := http.NewRequest("PUT", "/family/admin", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+			rr := httptest.NewRecorder()
+			mockHandler.familyTransferHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var result FamilyResponse
+				err := json.NewDecoder(rr.Body).Decode(&result)
+				assert.Nil(t, err)
+				assert.Equal(t, "2", result.FamilyData.Admin)
+				assert.Equal(t, goparent.RoleAdmin, result.FamilyData.Role("2"))
+				assert.Equal(t, goparent.RoleMember, result.FamilyData.Role("1"))
+			}
+		})
+	}
+}
+
+func TestFamilyDeleteHandler(t *testing.T) {
+	env := &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("testkey")}}
+	admin := &goparent.User{ID: "1"}
+	family := &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}}
+	familyService := &mock.FamilyService{Kids: []*goparent.Child{{ID: "1"}}}
+	mockHandler := Handler{
+		Env:           env,
+		UserService:   &mock.UserService{Family: family},
+		FamilyService: familyService,
+	}
+	serve := func(handler http.Handler, method string, target string, user *goparent.User) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	//only the admin can ask
+	rr := serve(mockHandler.familyDeleteConfirmHandler(), "POST", "/family/delete", &goparent.User{ID: "2"})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = serve(mockHandler.familyDeleteConfirmHandler(), "POST", "/family/delete", admin)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var confirm FamilyDeleteResponse
+	err := json.NewDecoder(rr.Body).Decode(&confirm)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, confirm.Confirm)
+	assert.Equal(t, 2, confirm.Members)
+	assert.Equal(t, 1, confirm.Children)
+	assert.WithinDuration(t, time.Now().Add(familyDeleteWindow), confirm.ExpiresAt, time.Minute)
+
+	//the delete needs the confirmation, for this family and this admin
+	rr = serve(mockHandler.familyDeleteHandler(), "DELETE", "/family", admin)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = serve(mockHandler.familyDeleteHandler(), "DELETE", "/family?confirm=garbage", admin)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	other, _ := mockHandler.familyDeleteToken(&goparent.Family{ID: "2"}, admin, time.Now().Add(time.Minute))
+	rr = serve(mockHandler.familyDeleteHandler(), "DELETE", "/family?confirm="+other, admin)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	expired, _ := mockHandler.familyDeleteToken(family, admin, time.Now().Add(-time.Minute))
+	rr = serve(mockHandler.familyDeleteHandler(), "DELETE", "/family?confirm="+expired, admin)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, familyService.Deleted)
+
+	familyService.DeleteErr = errors.New("test error")
+	rr = serve(mockHandler.familyDeleteHandler(), "DELETE", "/family?confirm="+confirm.Confirm, admin)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	familyService.DeleteErr = nil
+	rr = serve(mockHandler.familyDeleteHandler(), "DELETE", "/family?confirm="+confirm.Confirm, admin)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, []*goparent.Family{family}, familyService.Deleted)
+}
+
+func TestInitFamilyHandlers(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		name    string
+		path    string
+		methods []string
+	}{
+		{
+			desc:    "family get",
+			name:    "FamilyGet",
+			path:    "/family",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "family edit",
+			name:    "FamilyEdit",
+			path:    "/family",
+			methods: []string{"PUT"},
+		},
+		{
+			desc:    "family delete",
+			name:    "FamilyDelete",
+			path:    "/family",
+			methods: []string{"DELETE"},
+		},
+		{
+			desc:    "family delete confirm",
+			name:    "FamilyDeleteConfirm",
+			path:    "/family/delete",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "family transfer",
+			name:    "FamilyTransfer",
+			path:    "/family/admin",
+			methods: []string{"PUT"},
+		},
+		{
+			desc:    "family leave",
+			name:    "FamilyLeave",
+			path:    "/family/leave",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "family remove member",
+			name:    "FamilyRemoveMember",
+			path:    "/family/members/{userID}",
+			methods: []string{"DELETE"},
+		},
+		{
+			desc:    "family list",
+			name:    "FamilyList",
+			path:    "/families",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "family view",
+			name:    "FamilyView",
+			path:    "/families/{familyID}",
+			methods: []string{"GET"},
+		},
+	}
+
+	var testEnv *goparent.Env
+	h := Handler{Env: testEnv}
+	routes := mux.NewRouter()
+	h.initFamilyHandlers(routes)
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			route := routes.Get(tC.name)
+			path, _ := route.GetPathTemplate()
+			methods, _ := route.GetMethods()
+			assert.Equal(t, tC.name, route.GetName())
+			assert.Equal(t, tC.path, path)
+			assert.Equal(t, tC.methods, methods)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sasimpson/goparent"
@@ -34,24 +35,35 @@ func (h *Handler) feedingGetHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		pagination := getPagination(r)
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
+			return
+		}
+
+		units, err := displayUnits(r, family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
 			return
 		}
 
 		feedingData, err := h.FeedingService.Feeding(ctx, family, pagination.Days)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
+		for i, feeding := range feedingData {
+			converted := feeding.In(units)
+			feedingData[i] = &converted
+		}
+
 		feedingResponse := FeedingResponse{FeedingData: feedingData}
 		// log.Printf("feedingResponse %#v", feedingResponse)
 		w.Header().Set("Content-Type", jsonContentType)
@@ -63,11 +75,11 @@ func (h *Handler) feedingViewHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -75,11 +87,11 @@ func (h *Handler) feedingEditHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -89,13 +101,13 @@ func (h *Handler) feedingNewHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -103,16 +115,28 @@ func (h *Handler) feedingNewHandler() http.Handler {
 		var feedingRequest FeedingRequest
 		err = decoder.Decode(&feedingRequest)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
+			return
 		}
 		defer r.Body.Close()
 
+		//amounts without a unit are in the type's default unit
+		if feedingRequest.FeedingData.Unit == "" {
+			feedingRequest.FeedingData.Unit = goparent.DefaultFeedingUnit(feedingRequest.FeedingData.Type)
+		}
+		err = h.validateChildEntity(ctx, family, feedingRequest.FeedingData, "childID", feedingRequest.FeedingData.ChildID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
 		w.Header().Set("Content-Type", jsonContentType)
 		feedingRequest.FeedingData.UserID = user.ID
 		feedingRequest.FeedingData.FamilyID = family.ID
 		err = h.FeedingService.Save(ctx, &feedingRequest.FeedingData)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusConflict)
+			writeError(w, r, err)
+			return
 		}
 		json.NewEncoder(w).Encode(feedingRequest)
 	})
@@ -122,10 +146,10 @@ func (h *Handler) feedingDeleteHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -135,37 +159,55 @@ func (h *Handler) feedingGraphDataHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		childID := mux.Vars(r)["id"]
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		child, err := h.ChildService.Child(ctx, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		//child needs to belong to the user's family.
-		if child.FamilyID != family.ID {
-			http.Error(w, "not found", http.StatusNotFound)
+		//child needs to belong to or be shared with the user's family.
+		if !child.CanView(family.ID) {
+			writeError(w, r, errNotFound)
+			return
+		}
+
+		loc, err := requestLocation(r, family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		query, err := getDataQuery(r, goparent.NewGraphQuery(time.Now(), loc))
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		units, err := displayUnits(r, family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
 			return
 		}
 
-		feedingGraphData, err := h.FeedingService.GraphData(ctx, child)
+		feedingGraphData, err := h.FeedingService.GraphData(ctx, child, query)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", jsonContentType)
-		json.NewEncoder(w).Encode(feedingGraphData)
+		json.NewEncoder(w).Encode(feedingGraphData.In(units))
 	})
 }
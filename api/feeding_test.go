@@ -25,8 +25,11 @@ func TestFeedingGetHandler(t *testing.T) {
 		feedingService goparent.FeedingService
 		contextUser    *goparent.User
 		contextError   bool
+		queryString    string
 		responseCode   int
 		resultLength   int
+		resultAmounts  []float32
+		resultUnits    []goparent.Unit
 	}{
 		{
 			desc: "returns no feedings",
@@ -73,6 +76,56 @@ func TestFeedingGetHandler(t *testing.T) {
 			responseCode: http.StatusOK,
 			resultLength: 2,
 		},
+		{
+			desc: "returns feedings in family units",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}, Units: goparent.UnitSystemMetric},
+			},
+			familyService: &mock.FamilyService{},
+			feedingService: &mock.FeedingService{
+				Feedings: []*goparent.Feeding{
+					&goparent.Feeding{ID: "1", Type: "bottle", Amount: 4, Unit: goparent.UnitFluidOunce, UserID: "1", FamilyID: "1", TimeStamp: time.Now(), ChildID: "1"},
+					&goparent.Feeding{ID: "2", Type: "breast", Amount: 15, UserID: "1", FamilyID: "1", TimeStamp: time.Now().Add(time.Hour), ChildID: "1"},
+				},
+			},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusOK,
+			resultLength:  2,
+			resultAmounts: []float32{118.29412, 15},
+			resultUnits:   []goparent.Unit{goparent.UnitMilliliter, goparent.UnitMinute},
+		},
+		{
+			desc: "returns feedings in requested units",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}, Units: goparent.UnitSystemMetric},
+			},
+			familyService: &mock.FamilyService{},
+			feedingService: &mock.FeedingService{
+				Feedings: []*goparent.Feeding{
+					&goparent.Feeding{ID: "1", Type: "bottle", Amount: 120, Unit: goparent.UnitMilliliter, UserID: "1", FamilyID: "1", TimeStamp: time.Now(), ChildID: "1"},
+				},
+			},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			queryString:   "?units=imperial",
+			responseCode:  http.StatusOK,
+			resultLength:  1,
+			resultAmounts: []float32{4.057682},
+			resultUnits:   []goparent.Unit{goparent.UnitFluidOunce},
+		},
+		{
+			desc: "returns invalid units error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService:  &mock.FamilyService{},
+			feedingService: &mock.FeedingService{},
+			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			queryString:    "?units=cubits",
+			responseCode:   http.StatusBadRequest,
+		},
 		{
 			desc: "returns no family error",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
@@ -117,7 +170,7 @@ func TestFeedingGetHandler(t *testing.T) {
 				FeedingService: tC.feedingService,
 			}
 
-			req, err := http.NewRequest("GET", "/feeding", nil)
+			req, err := http.NewRequest("GET", "/feeding"+tC.queryString, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -141,6 +194,10 @@ func TestFeedingGetHandler(t *testing.T) {
 				err := decoder.Decode(&result)
 				assert.Nil(t, err)
 				assert.Equal(t, tC.resultLength, len(result.FeedingData))
+				for i := range tC.resultAmounts {
+					assert.InDelta(t, tC.resultAmounts[i], result.FeedingData[i].Amount, 0.001)
+					assert.Equal(t, tC.resultUnits[i], result.FeedingData[i].Unit)
+				}
 			}
 		})
 	}
@@ -155,6 +212,7 @@ func TestFeedingNewHandler(t *testing.T) {
 		userService    goparent.UserService
 		familyService  goparent.FamilyService
 		feedingService goparent.FeedingService
+		childService   goparent.ChildService
 		contextUser    *goparent.User
 		contextError   bool
 		responseCode   int
@@ -182,12 +240,70 @@ func TestFeedingNewHandler(t *testing.T) {
 				},
 			},
 			familyService:  &mock.FamilyService{},
+			childService:   &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
 			feedingService: &mock.FeedingService{},
 			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:   false,
 			responseCode:   http.StatusOK,
 			resultLength:   0,
 		},
+		{
+			desc: "returns invalid unit error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			feedingRequest: FeedingRequest{
+				FeedingData: goparent.Feeding{
+					Type:      "bottle",
+					Amount:    3.5,
+					Unit:      "cups",
+					UserID:    "1",
+					ChildID:   "1",
+					FamilyID:  "1",
+					TimeStamp: timestamp}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService:  &mock.FamilyService{},
+			childService:   &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
+			feedingService: &mock.FeedingService{},
+			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:   http.StatusBadRequest,
+		},
+		{
+			desc: "returns validation error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			feedingRequest: FeedingRequest{
+				FeedingData: goparent.Feeding{
+					Type:      "soda",
+					Amount:    -3.5,
+					ChildID:   "1",
+					TimeStamp: timestamp.Add(24 * time.Hour)}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService:  &mock.FamilyService{},
+			childService:   &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
+			feedingService: &mock.FeedingService{},
+			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:   http.StatusBadRequest,
+		},
+		{
+			desc: "returns child in another family error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			feedingRequest: FeedingRequest{
+				FeedingData: goparent.Feeding{
+					Type:      "bottle",
+					Amount:    3.5,
+					ChildID:   "2",
+					TimeStamp: timestamp}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService:  &mock.FamilyService{},
+			childService:   &mock.ChildService{Kid: &goparent.Child{ID: "2", Name: "Sally", FamilyID: "2"}},
+			feedingService: &mock.FeedingService{},
+			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:   http.StatusBadRequest,
+		},
 		{
 			desc: "returns no family error",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
@@ -231,12 +347,13 @@ func TestFeedingNewHandler(t *testing.T) {
 				},
 			},
 			familyService: &mock.FamilyService{},
+			childService:  &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
 			feedingService: &mock.FeedingService{
 				GetErr: errors.New("unknown feeding error"),
 			},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError: false,
-			responseCode: http.StatusConflict,
+			responseCode: http.StatusInternalServerError,
 		},
 		{
 			desc: "returns auth error",
@@ -273,7 +390,7 @@ func TestFeedingNewHandler(t *testing.T) {
 			feedingService: &mock.FeedingService{},
 			contextUser:    &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:   false,
-			responseCode:   http.StatusInternalServerError,
+			responseCode:   http.StatusBadRequest,
 		},
 	}
 	for _, tC := range testCases {
@@ -283,6 +400,7 @@ func TestFeedingNewHandler(t *testing.T) {
 				UserService:    tC.userService,
 				FamilyService:  tC.familyService,
 				FeedingService: tC.feedingService,
+				ChildService:   tC.childService,
 			}
 
 			js, err := json.Marshal(&tC.feedingRequest)
@@ -308,9 +426,13 @@ func TestFeedingNewHandler(t *testing.T) {
 			req = req.WithContext(ctx)
 			handler.ServeHTTP(rr, req)
 			assert.Equal(t, tC.responseCode, rr.Code)
-			// if tC.responseCode == http.StatusOK {
-
-			// }
+			if tC.responseCode != http.StatusOK {
+				//the error is the whole body, nothing gets encoded after it
+				dec := json.NewDecoder(rr.Body)
+				var body map[string]interface{}
+				assert.Nil(t, dec.Decode(&body))
+				assert.False(t, dec.More())
+			}
 		})
 	}
 }
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+)
+
+//backendCheckTimeout - how long the backend has to answer before it's reported down
+const backendCheckTimeout = 2 * time.Second
+
+//HealthCheck - the state of something the service depends on
+type HealthCheck struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"durationMs"`
+}
+
+//HealthResponse - the state of the service, degraded if any of its checks fail
+type HealthResponse struct {
+	Status   string        `json:"status"`
+	Version  string        `json:"version"`
+	Commit   string        `json:"commit"`
+	Hostname string        `json:"hostname"`
+	Checks   []HealthCheck `json:"checks"`
+}
+
+//checkHealth - check the backend and build the response
+func (h *Handler) checkHealth() HealthResponse {
+	name, _ := os.Hostname()
+	health := HealthResponse{
+		Status:   healthOK,
+		Version:  goparent.Version,
+		Commit:   goparent.Commit,
+		Hostname: name,
+	}
+
+	health.Checks = append(health.Checks, checkBackend(h.Env.DB))
+	if h.EventSource != nil {
+		health.Checks = append(health.Checks, checkEvents(h.EventSource))
+	}
+	for _, check := range health.Checks {
+		if check.Status != healthOK {
+			health.Status = healthDegraded
+		}
+	}
+	return health
+}
+
+//checkEvents - whether events are being published, without them event streams and webhooks
+// go quiet
+func checkEvents(source goparent.EventSource) HealthCheck {
+	check := HealthCheck{Name: "events", Status: healthOK}
+	err := source.Err()
+	if err != nil {
+		check.Status = healthDegraded
+		check.Error = err.Error()
+	}
+	return check
+}
+
+//checkBackend - whether there's a connection to the backend
+func checkBackend(db goparent.Datastore) HealthCheck {
+	check := HealthCheck{Name: "backend", Status: healthOK}
+	start := time.Now()
+	err := goparent.CheckConnection(db, backendCheckTimeout)
+	check.Duration = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		check.Status = healthDegraded
+		check.Error = err.Error()
+	}
+	return check
+}
+
+//healthHandler - liveness.  the service is alive even when the backend is down, restarting it
+// won't bring the backend back, so this is always a 200 with the details in the body.
+func (h *Handler) healthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(h.checkHealth())
+	})
+}
+
+//readyHandler - readiness.  503 while the backend can't be reached so traffic goes to
+// instances that can serve it.
+func (h *Handler) readyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := h.checkHealth()
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		if health.Status != healthOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/metrics"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+//eventSource - an EventSource that's down when err is set
+type eventSource struct {
+	err error
+}
+
+func (s eventSource) Err() error {
+	return s.err
+}
+
+func TestHealthHandlers(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		path         string
+		connErr      error
+		events       goparent.EventSource
+		responseCode int
+		status       string
+	}{
+		{desc: "healthy", path: "/healthz", responseCode: http.StatusOK, status: healthOK},
+		{desc: "alive without the backend", path: "/healthz", connErr: errors.New("connection refused"), responseCode: http.StatusOK, status: healthDegraded},
+		{desc: "ready", path: "/readyz", responseCode: http.StatusOK, status: healthOK},
+		{desc: "not ready without the backend", path: "/readyz", connErr: errors.New("connection refused"), responseCode: http.StatusServiceUnavailable, status: healthDegraded},
+		{desc: "ready with events", path: "/readyz", events: eventSource{}, responseCode: http.StatusOK, status: healthOK},
+		{desc: "not ready without events", path: "/readyz", events: eventSource{err: errors.New("the change feed ended")}, responseCode: http.StatusServiceUnavailable, status: healthDegraded},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			h := &Handler{Env: &goparent.Env{DB: &mock.DBEnv{ConnectionErr: tC.connErr}}, EventSource: tC.events}
+			req, _ := http.NewRequest("GET", tC.path, nil)
+			rr := httptest.NewRecorder()
+			BuildAPIRouting(h).ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+
+			var health HealthResponse
+			assert.Nil(t, json.NewDecoder(rr.Body).Decode(&health))
+			assert.Equal(t, tC.status, health.Status)
+			assert.Equal(t, goparent.Version, health.Version)
+			checks := 1
+			if tC.events != nil {
+				checks = 2
+			}
+			if assert.Len(t, health.Checks, checks) {
+				assert.Equal(t, "backend", health.Checks[0].Name)
+				if tC.connErr != nil {
+					assert.Equal(t, tC.connErr.Error(), health.Checks[0].Error)
+				}
+			}
+			if tC.events != nil && len(health.Checks) == 2 {
+				assert.Equal(t, "events", health.Checks[1].Name)
+				if err := tC.events.Err(); err != nil {
+					assert.Equal(t, err.Error(), health.Checks[1].Error)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsRoute(t *testing.T) {
+	h := &Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}}
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	BuildAPIRouting(h).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "there are no metrics unless they're set up")
+
+	h.Metrics = metrics.New(h.Env.DB)
+	router := BuildAPIRouting(h)
+	req, _ = http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `goparent_http_requests_total{code="200",method="GET",route="Ready"} 1`)
+}
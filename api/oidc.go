@@ -0,0 +1,420 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+//oidcCookie - the cookie that ties the provider's redirect back to the browser that started
+// the login.  it holds the state, nonce and pkce verifier, signed, so none of them are put
+// in the urls the provider sees.
+const oidcCookie = "goparent_oidc"
+
+//oidcLoginWindow - how long the user has to log in with the provider
+const oidcLoginWindow = 10 * time.Minute
+
+//reauthWindow - how recently a user has to have logged in with the provider to confirm it's
+// them, and how long the reauth token they get for it lasts
+const reauthWindow = 5 * time.Minute
+
+var (
+	errInvalidOIDCState  = NewAPIError(http.StatusBadRequest, CodeInvalidToken, "the login with the identity provider is invalid or has expired, start again")
+	errOIDCNoEmail       = NewAPIError(http.StatusBadRequest, CodeBadRequest, "the identity provider didn't share an email address")
+	errOIDCEmailInUse    = NewAPIError(http.StatusConflict, CodeUserExists, "an account already uses that email, log in with your password and link the identity provider from your account")
+	errOIDCProvider      = NewAPIError(http.StatusBadGateway, CodeIdentityProvider, "logging in with the identity provider failed")
+	errLastLoginIdentity = NewAPIError(http.StatusConflict, CodeConflict, "set a password before unlinking your only way to log in")
+	errReauthFailed      = NewAPIError(http.StatusUnauthorized, CodeReauthRequired, "the identity provider didn't confirm it's you, log in with it again")
+)
+
+var oidcCallbackLimits = []rateLimit{
+	{name: "oidc.ip", limit: goparent.RateLimit{Limit: 30, Window: time.Minute}, key: byIP},
+}
+
+//OIDCProvidersResponse - the identity providers users can log in with
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+//OIDCLinkResponse - where to send the user to link an identity provider to their account,
+// or to log in with it again to confirm it's them
+type OIDCLinkResponse struct {
+	URL string `json:"url"`
+}
+
+//ReauthResponse - a token that stands in for the password on requests that change the
+// account, for a few minutes
+type ReauthResponse struct {
+	ReauthToken string    `json:"reauthToken"`
+	Expires     time.Time `json:"expires"`
+}
+
+//IdentityResponse - an identity linked to the user
+type IdentityResponse struct {
+	IdentityData *goparent.ExternalIdentity `json:"identityData"`
+}
+
+//IdentitiesResponse - the identities linked to the user
+type IdentitiesResponse struct {
+	Identities []*goparent.ExternalIdentity `json:"identities"`
+}
+
+func (h *Handler) oidcProvidersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		providers := []string{}
+		for name := range h.Env.OIDC {
+			providers = append(providers, name)
+		}
+		sort.Strings(providers)
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(OIDCProvidersResponse{Providers: providers})
+	})
+}
+
+//oidcProvider - the provider in the path
+func (h *Handler) oidcProvider(w http.ResponseWriter, r *http.Request) (*goparent.OIDCProvider, bool) {
+	provider, ok := h.Env.OIDC[mux.Vars(r)["provider"]]
+	if !ok || h.IdentityService == nil {
+		writeError(w, r, errNotFound)
+		return nil, false
+	}
+	return provider, true
+}
+
+//startOIDC - set the cookie for a login with the provider and return where to send the
+// user.  linkUser is the user the identity is being linked to and reauthUser the user
+// confirming it's them, if they're already logged in.
+func (h *Handler) startOIDC(ctx context.Context, w http.ResponseWriter, r *http.Request, provider *goparent.OIDCProvider, linkUser string, reauthUser string) (string, error) {
+	state, nonce, verifier, err := goparent.NewOIDCLogin()
+	if err != nil {
+		return "", err
+	}
+	authURL := provider.AuthURL
+	if reauthUser != "" {
+		authURL = provider.ReauthURL
+	}
+	redirect, err := authURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("request %s: %s", RequestIDFromContext(r.Context()), err)
+		return "", errOIDCProvider
+	}
+	expires := time.Now().Add(oidcLoginWindow)
+	cookie, err := h.purposeToken("oidc.login", jwt.MapClaims{
+		"provider": provider.Name,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"link":     linkUser,
+		"reauth":   reauthUser,
+	}, expires)
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    cookie,
+		Path:     "/api/user/oidc",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		//lax so the cookie comes back on the provider's redirect
+		SameSite: http.SameSiteLaxMode,
+	})
+	return redirect, nil
+}
+
+//oidcLoginHandler - send the browser to the provider to log in
+func (h *Handler) oidcLoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := h.oidcProvider(w, r)
+		if !ok {
+			return
+		}
+		authURL, err := h.startOIDC(h.Env.DB.GetContext(r), w, r, provider, "", "")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+}
+
+//oidcLinkHandler - start linking the provider to the logged in user, the browser has to be
+// sent to the returned url
+func (h *Handler) oidcLinkHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		provider, ok := h.oidcProvider(w, r)
+		if !ok {
+			return
+		}
+		authURL, err := h.startOIDC(h.Env.DB.GetContext(r), w, r, provider, user.ID, "")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(OIDCLinkResponse{URL: authURL})
+	})
+}
+
+//oidcReauthHandler - start a fresh login with a provider linked to the logged in user, for
+// users without a password to confirm it's them.  the callback returns a reauth token.
+func (h *Handler) oidcReauthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		provider, ok := h.oidcProvider(w, r)
+		if !ok {
+			return
+		}
+		authURL, err := h.startOIDC(h.Env.DB.GetContext(r), w, r, provider, "", user.ID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(OIDCLinkResponse{URL: authURL})
+	})
+}
+
+//reauthToken - the token for a user who just logged in with an identity linked to them
+func (h *Handler) reauthToken(ctx context.Context, claims *goparent.OIDCClaims, userID string) (*ReauthResponse, error) {
+	linked, err := h.IdentityService.IdentityBySubject(ctx, claims.Issuer, claims.Subject)
+	if errors.Is(err, goparent.ErrNoIdentityFound) || (err == nil && linked.UserID != userID) {
+		return nil, errReauthFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	//the provider was asked to log them in again, a login from before then isn't good enough
+	now := time.Now()
+	if !claims.AuthTime.IsZero() && now.Sub(claims.AuthTime) > reauthWindow {
+		return nil, errReauthFailed
+	}
+
+	expires := now.Add(reauthWindow)
+	token, err := h.purposeToken("reauth", jwt.MapClaims{"user": userID}, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &ReauthResponse{ReauthToken: token, Expires: expires}, nil
+}
+
+//oidcCallbackHandler - where the provider sends the browser back to.  the code is traded for
+// the user's identity, which logs in the user it's linked to.  identities that aren't linked
+// yet get a new user and family, unless an account already has the email.
+func (h *Handler) oidcCallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		provider, ok := h.oidcProvider(w, r)
+		if !ok {
+			return
+		}
+
+		var login jwt.MapClaims
+		if cookie, err := r.Cookie(oidcCookie); err == nil {
+			login, ok = h.purposeClaims(cookie.Value, "oidc.login")
+		}
+		http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: "/api/user/oidc", MaxAge: -1, HttpOnly: true})
+		state, _ := login["state"].(string)
+		if !ok || login["provider"] != provider.Name || state == "" || state != r.FormValue("state") {
+			writeError(w, r, errInvalidOIDCState)
+			return
+		}
+		if reason := r.FormValue("error"); reason != "" {
+			writeError(w, r, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "the identity provider didn't log you in: "+reason))
+			return
+		}
+
+		nonce, _ := login["nonce"].(string)
+		verifier, _ := login["verifier"].(string)
+		claims, err := provider.Exchange(ctx, r.FormValue("code"), verifier, nonce)
+		if err != nil {
+			if !errors.Is(err, goparent.ErrInvalidIDToken) {
+				log.Printf("request %s: %s", RequestIDFromContext(r.Context()), err)
+				err = errOIDCProvider
+			}
+			writeError(w, r, err)
+			return
+		}
+
+		now := time.Now()
+		identity := &goparent.ExternalIdentity{
+			Provider:  provider.Name,
+			Issuer:    claims.Issuer,
+			Subject:   claims.Subject,
+			Email:     claims.Email,
+			CreatedAt: now,
+			LastLogin: now,
+		}
+		if reauthUser, _ := login["reauth"].(string); reauthUser != "" {
+			reauth, err := h.reauthToken(ctx, claims, reauthUser)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", jsonContentType)
+			json.NewEncoder(w).Encode(reauth)
+			return
+		}
+		if linkUser, _ := login["link"].(string); linkUser != "" {
+			identity.UserID = linkUser
+			err = h.IdentityService.Save(ctx, identity)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", jsonContentType)
+			json.NewEncoder(w).Encode(IdentityResponse{IdentityData: identity})
+			return
+		}
+
+		user, err := h.oidcUser(ctx, claims, identity)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		h.loginOrChallenge(w, r, user)
+	})
+}
+
+//oidcUser - the user linked to the identity, or a new one for it
+func (h *Handler) oidcUser(ctx context.Context, claims *goparent.OIDCClaims, identity *goparent.ExternalIdentity) (*goparent.User, error) {
+	linked, err := h.IdentityService.IdentityBySubject(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		user, err := h.UserService.User(ctx, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		linked.Email = identity.Email
+		linked.LastLogin = identity.LastLogin
+		err = h.IdentityService.Save(ctx, linked)
+		if err != nil {
+			log.Printf("identity %s: %s", linked.ID, err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, goparent.ErrNoIdentityFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, errOIDCNoEmail
+	}
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	//the provider's word is taken for the email, if it says it's verified
+	user := &goparent.User{
+		Name:       name,
+		Email:      claims.Email,
+		Unverified: !claims.EmailVerified,
+	}
+	if user.Unverified {
+		user.VerificationSentAt = identity.CreatedAt
+	}
+	err = h.UserService.Save(ctx, user)
+	if errors.Is(err, goparent.ErrExistingUser) {
+		return nil, errOIDCEmailInUse
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Unverified {
+		err = h.mailVerification(ctx, user)
+		if err != nil {
+			log.Printf("error sending mail: %s", err)
+		}
+	}
+
+	identity.UserID = user.ID
+	err = h.IdentityService.Save(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (h *Handler) identitiesListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		identities := []*goparent.ExternalIdentity{}
+		if h.IdentityService != nil {
+			identities, err = h.IdentityService.Identities(h.Env.DB.GetContext(r), user)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+		if identities == nil {
+			identities = []*goparent.ExternalIdentity{}
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(IdentitiesResponse{Identities: identities})
+	})
+}
+
+//identityUnlinkHandler - unlink an identity.  users without a password can't unlink the last
+// one, they'd have no way to log in.
+func (h *Handler) identityUnlinkHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if h.IdentityService == nil {
+			writeError(w, r, goparent.ErrNoIdentityFound)
+			return
+		}
+		identity, err := h.IdentityService.Identity(ctx, mux.Vars(r)["id"])
+		if err == nil && identity.UserID != user.ID {
+			err = goparent.ErrNoIdentityFound
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if user.Password == "" {
+			identities, err := h.IdentityService.Identities(ctx, user)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if len(identities) <= 1 {
+				writeError(w, r, errLastLoginIdentity)
+				return
+			}
+		}
+
+		err = h.IdentityService.Delete(ctx, identity)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
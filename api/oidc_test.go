@@ -0,0 +1,317 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+//oidcTest - the api and a stub provider it logs in with, and a browser with cookies
+type oidcTest struct {
+	stub       *mock.OIDCServer
+	server     *httptest.Server
+	users      *mock.UserService
+	identities *mock.IdentityService
+	browser    *http.Client
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	stub := mock.NewOIDCServer()
+	users := &mock.UserService{UserID: "1", Token: "this-is-a-token"}
+	identities := &mock.IdentityService{}
+	h := &Handler{
+		Env:             &goparent.Env{DB: &mock.DBEnv{}, Mailer: &mock.Mailer{}, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+		UserService:     users,
+		IdentityService: identities,
+	}
+	server := httptest.NewServer(BuildAPIRouting(h))
+	h.Env.OIDC = map[string]*goparent.OIDCProvider{"stub": stub.Provider("stub", server.URL+"/api/user/oidc/stub/callback")}
+	jar, _ := cookiejar.New(nil)
+	t.Cleanup(func() {
+		server.Close()
+		stub.Close()
+	})
+	return &oidcTest{stub: stub, server: server, users: users, identities: identities, browser: &http.Client{Jar: jar}}
+}
+
+//login - log in with the stub provider from the start, following the redirects like a browser
+func (o *oidcTest) login(t *testing.T) (*http.Response, UserAuthResponse) {
+	resp, err := o.browser.Get(o.server.URL + "/api/user/oidc/stub/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var auth UserAuthResponse
+	json.NewDecoder(resp.Body).Decode(&auth)
+	return resp, auth
+}
+
+func TestOIDCLogin(t *testing.T) {
+	o := newOIDCTest(t)
+
+	resp, auth := o.login(t)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "this-is-a-token", auth.Token)
+	if assert.Len(t, o.users.Saved, 1, "the first login signs up") {
+		assert.Equal(t, "stub@test.com", o.users.Saved[0].Email)
+		assert.Equal(t, "Stub User", o.users.Saved[0].Name)
+		assert.False(t, o.users.Saved[0].Unverified, "the provider verified the email")
+	}
+	identity, err := o.identities.IdentityBySubject(context.Background(), o.stub.URL, "stub-user")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1", identity.UserID)
+		assert.Equal(t, "stub", identity.Provider)
+	}
+
+	o.users.ReturnedUser = o.users.Saved[0]
+	resp, auth = o.login(t)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "this-is-a-token", auth.Token)
+	assert.Len(t, o.users.Saved, 1, "later logins find the linked user")
+
+	o.users.ReturnedUser.TOTPEnabled = true
+	_, auth = o.login(t)
+	assert.True(t, auth.TwoFactorRequired, "two factor still applies")
+	assert.Empty(t, auth.Token)
+}
+
+func TestOIDCSignup(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		claims       map[string]interface{}
+		saveErr      error
+		responseCode int
+		code         string
+		unverified   bool
+	}{
+		{
+			desc:         "unverified email",
+			claims:       map[string]interface{}{"sub": "s1", "email": "new@test.com", "email_verified": false},
+			responseCode: http.StatusOK,
+			unverified:   true,
+		},
+		{
+			desc:         "email already has an account",
+			claims:       map[string]interface{}{"sub": "s1", "email": "taken@test.com", "email_verified": true},
+			saveErr:      goparent.ErrExistingUser,
+			responseCode: http.StatusConflict,
+			code:         CodeUserExists,
+		},
+		{
+			desc:         "no email",
+			claims:       map[string]interface{}{"sub": "s1"},
+			responseCode: http.StatusBadRequest,
+			code:         CodeBadRequest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			o := newOIDCTest(t)
+			o.stub.Claims = tC.claims
+			o.users.SaveErr = tC.saveErr
+
+			resp, _ := o.login(t)
+			assert.Equal(t, tC.responseCode, resp.StatusCode)
+			if tC.responseCode != http.StatusOK {
+				assert.Empty(t, o.identities.Linked)
+				return
+			}
+			if assert.Len(t, o.users.Saved, 1) {
+				assert.Equal(t, tC.unverified, o.users.Saved[0].Unverified)
+				assert.Equal(t, "new@test.com", o.users.Saved[0].Name, "the email is the name if there isn't one")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	o := newOIDCTest(t)
+	noRedirects := &http.Client{Jar: o.browser.Jar, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := noRedirects.Get(o.server.URL + "/api/user/oidc/stub/login")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		location := resp.Header.Get("Location")
+		assert.Contains(t, location, o.stub.URL+"/authorize?")
+		assert.NotContains(t, location, "code_verifier")
+	}
+
+	resp, err = noRedirects.Get(o.server.URL + "/api/user/oidc/stub/callback?code=abc&state=forged")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "the state has to match the browser's cookie")
+	}
+	resp, err = http.Get(o.server.URL + "/api/user/oidc/stub/callback?code=abc&state=forged")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "there has to be a cookie")
+	}
+	resp, err = http.Get(o.server.URL + "/api/user/oidc/nope/login")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+	assert.Empty(t, o.users.Saved)
+}
+
+func TestOIDCLink(t *testing.T) {
+	o := newOIDCTest(t)
+	o.users.ReturnedUser = &goparent.User{ID: "2", Email: "parent@test.com", Password: "secret"}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"ID": "2", "exp": time.Now().Add(time.Minute).Unix()}).SignedString([]byte("test"))
+
+	link := func() (*http.Response, IdentityResponse) {
+		req, _ := http.NewRequest("POST", o.server.URL+"/api/user/oidc/stub/link", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := o.browser.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var linkResp OIDCLinkResponse
+		json.NewDecoder(resp.Body).Decode(&linkResp)
+		resp.Body.Close()
+
+		resp, err = o.browser.Get(linkResp.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var identity IdentityResponse
+		json.NewDecoder(resp.Body).Decode(&identity)
+		return resp, identity
+	}
+
+	resp, identity := link()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if assert.NotNil(t, identity.IdentityData) {
+		assert.Equal(t, "2", identity.IdentityData.UserID)
+	}
+	assert.Empty(t, o.users.Saved, "linking doesn't sign anyone up")
+
+	for _, linked := range o.identities.Linked {
+		linked.UserID = "3"
+	}
+	resp, _ = link()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "the identity is someone else's")
+}
+
+func TestOIDCReauth(t *testing.T) {
+	o := newOIDCTest(t)
+	o.users.ReturnedUser = &goparent.User{ID: "2", Name: "Parent", Email: "parent@test.com"}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"ID": "2", "exp": time.Now().Add(time.Minute).Unix()}).SignedString([]byte("test"))
+
+	reauth := func() (*http.Response, ReauthResponse) {
+		req, _ := http.NewRequest("POST", o.server.URL+"/api/user/oidc/stub/reauth", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := o.browser.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var start OIDCLinkResponse
+		json.NewDecoder(resp.Body).Decode(&start)
+		resp.Body.Close()
+		assert.Contains(t, start.URL, "max_age=0", "the provider is asked to log them in again")
+
+		resp, err = o.browser.Get(start.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var reauth ReauthResponse
+		json.NewDecoder(resp.Body).Decode(&reauth)
+		return resp, reauth
+	}
+	changeEmail := func(form url.Values) int {
+		req, _ := http.NewRequest("POST", o.server.URL+"/api/user/email", strings.NewReader(form.Encode()))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	resp, _ := reauth()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "the identity isn't linked to the user")
+
+	o.identities.Save(context.Background(), &goparent.ExternalIdentity{Provider: "stub", Issuer: o.stub.URL, Subject: "stub-user", UserID: "2"})
+	assert.Equal(t, http.StatusUnauthorized, changeEmail(url.Values{"email": {"new@test.com"}, "password": {""}}), "there's no password to match")
+	resp, confirmed := reauth()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, confirmed.ReauthToken)
+	assert.Equal(t, http.StatusAccepted, changeEmail(url.Values{"email": {"new@test.com"}, "reauthToken": {confirmed.ReauthToken}}))
+
+	o.stub.Claims["auth_time"] = time.Now().Add(-time.Hour).Unix()
+	resp, _ = reauth()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "the provider didn't log them in again")
+	assert.Empty(t, o.users.Saved)
+}
+
+func TestIdentityUnlinkHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		user         *goparent.User
+		linked       []*goparent.ExternalIdentity
+		responseCode int
+	}{
+		{
+			desc:         "unlinks",
+			user:         &goparent.User{ID: "1", Password: "secret"},
+			linked:       []*goparent.ExternalIdentity{{UserID: "1", Issuer: "https://idp.test", Subject: "a"}},
+			responseCode: http.StatusNoContent,
+		},
+		{
+			desc:         "last way to log in",
+			user:         &goparent.User{ID: "1"},
+			linked:       []*goparent.ExternalIdentity{{UserID: "1", Issuer: "https://idp.test", Subject: "a"}},
+			responseCode: http.StatusConflict,
+		},
+		{
+			desc: "another identity to log in with",
+			user: &goparent.User{ID: "1"},
+			linked: []*goparent.ExternalIdentity{
+				{UserID: "1", Issuer: "https://idp.test", Subject: "a"},
+				{UserID: "1", Issuer: "https://other.test", Subject: "b"},
+			},
+			responseCode: http.StatusNoContent,
+		},
+		{
+			desc:         "someone else's",
+			user:         &goparent.User{ID: "1", Password: "secret"},
+			linked:       []*goparent.ExternalIdentity{{UserID: "2", Issuer: "https://idp.test", Subject: "a"}},
+			responseCode: http.StatusNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			identities := &mock.IdentityService{}
+			for _, identity := range tC.linked {
+				identities.Save(context.Background(), identity)
+			}
+			mockHandler := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}, IdentityService: identities}
+			id := goparent.IdentityID("https://idp.test", "a")
+			req, _ := http.NewRequest("DELETE", "/user/identities/"+id, nil)
+			req = mux.SetURLVars(req.WithContext(context.WithValue(req.Context(), userContextKey, tC.user)), map[string]string{"id": id})
+			rr := httptest.NewRecorder()
+			mockHandler.identityUnlinkHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.responseCode == http.StatusNoContent {
+				assert.Len(t, identities.Deleted, 1)
+			} else {
+				assert.Empty(t, identities.Deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+const openAPIVersion = "3.0.3"
+
+//docsPage - the interactive docs served at /api/, it renders openapi.json in the browser
+//
+//go:embed docs.html
+var docsPage []byte
+
+//OpenAPI - the parts of an OpenAPI 3 document that we generate
+type OpenAPI struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       OpenAPIInfo         `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components OpenAPIComponents   `json:"components"`
+	Tags       []OpenAPITag        `json:"tags,omitempty"`
+}
+
+//OpenAPIInfo - title and version of the api
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+//OpenAPITag - a group of operations, one per resource
+type OpenAPITag struct {
+	Name string `json:"name"`
+}
+
+//PathItem - the operations on a path keyed by lower case http method
+type PathItem map[string]*Operation
+
+//Operation - a single route
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+//Parameter - a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+//RequestBody - the body of a request by content type
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+//Response - a response by content type
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+//MediaType - the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+//Schema - a json schema, only the parts we use
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+//OpenAPIComponents - shared schemas and the auth scheme
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+//SecurityScheme - how to authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+//operation - what the router can't tell us about a route.  every named route in
+// BuildAPIRouting needs one of these, GenerateOpenAPI fails if they drift apart.
+type operation struct {
+	summary   string
+	tag       string
+	public    bool
+	query     []Parameter
+	form      []string
+	request   interface{}
+	response  interface{}
+	status    int
+	text      bool
+	enveloped bool
+	stream    bool
+}
+
+var (
+	oidcCallbackParams = []Parameter{
+		{Name: "code", In: "query", Description: "the code from the identity provider", Schema: &Schema{Type: "string"}},
+		{Name: "state", In: "query", Description: "the state sent to the identity provider", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "error", In: "query", Description: "why the identity provider didn't log the user in", Schema: &Schema{Type: "string"}},
+	}
+	daysParam = Parameter{Name: "days", In: "query", Description: "how many days back to return, defaults to 7", Schema: &Schema{Type: "integer"}}
+	unitParam = Parameter{Name: "units", In: "query", Description: "unit system to show amounts in, defaults to the family setting", Schema: &Schema{Type: "string", Enum: []string{string(goparent.UnitSystemMetric), string(goparent.UnitSystemImperial)}}}
+	tzParam   = Parameter{Name: timezoneHeader, In: "header", Description: "IANA time zone for day boundaries, defaults to the family setting", Schema: &Schema{Type: "string"}}
+
+	confirmParam = Parameter{Name: "confirm", In: "query", Required: true, Description: "confirmation from POST /api/family/delete", Schema: &Schema{Type: "string"}}
+	familyParam  = Parameter{Name: familyHeader, In: "header", Description: "id of the family the request is for, defaults to the user's current family", Schema: &Schema{Type: "string"}}
+
+	dataQueryParams = []Parameter{
+		{Name: "start", In: "query", Description: "start of the window, RFC3339 or " + queryDateFormat, Schema: &Schema{Type: "string"}},
+		{Name: "end", In: "query", Description: "end of the window, RFC3339 or " + queryDateFormat + ", the default window ends here if there's no start or days", Schema: &Schema{Type: "string"}},
+		{Name: "days", In: "query", Description: "length of the window in days, ending at end", Schema: &Schema{Type: "integer"}},
+		{Name: "granularity", In: "query", Description: "bucket size for graphs, windows can be up to " + strconv.Itoa(goparent.MaxDataQueryBuckets) + " buckets long", Schema: &Schema{Type: "string", Enum: []string{string(goparent.GranularityHour), string(goparent.GranularityDay), string(goparent.GranularityWeek), string(goparent.GranularityMonth)}}},
+		tzParam,
+	}
+	graphParams = append(append([]Parameter{}, dataQueryParams...), unitParam)
+
+	allParam         = Parameter{Name: "all", In: "query", Description: "true to include acknowledged notifications", Schema: &Schema{Type: "boolean"}}
+	accessTokenParam = Parameter{Name: "access_token", In: "query", Description: "token for clients that can't set the Authorization header", Schema: &Schema{Type: "string"}}
+)
+
+var operations = map[string]operation{
+	"APIDocs":                  {summary: "Interactive api documentation", public: true, text: true},
+	"ServiceInfo":              {summary: "Service version and host", public: true, response: ServiceInfo{}},
+	"Health":                   {summary: "Liveness, with the state of the backend", tag: "service", public: true, response: HealthResponse{}},
+	"Ready":                    {summary: "Readiness, 503 while the backend can't be reached", tag: "service", public: true, response: HealthResponse{}},
+	"Metrics":                  {summary: "Prometheus metrics", tag: "service", public: true, text: true},
+	"OpenAPISpec":              {summary: "This document", public: true, response: map[string]interface{}{}},
+	"UserNew":                  {summary: "Sign up a new user", public: true, request: NewUserRequest{}, response: goparent.User{}},
+	"UserGetData":              {summary: "The current user and their family", response: UserResponse{}},
+	"UserUpdate":               {summary: "Change the user's name and username", request: UserRequest{}, response: UserResponse{}},
+	"UserDelete":               {summary: "Delete the account, families it runs go to another member or are deleted if it's the only one", form: []string{"password", "reauthToken"}, status: http.StatusNoContent},
+	"UserChangeEmail":          {summary: "Mail a token to a new address that confirms the change to it", form: []string{"email", "password", "reauthToken"}, status: http.StatusAccepted},
+	"UserVerifyEmail":          {summary: "Verify a new user's email with the token mailed to it", public: true, form: []string{"token"}, response: UserResponse{}},
+	"UserResendVerification":   {summary: "Mail another verification token, at most once every five minutes", status: http.StatusAccepted},
+	"UserConfirmEmail":         {summary: "Change the user's email with a token mailed to the new address", public: true, form: []string{"token"}, response: UserResponse{}},
+	"UserLogin":                {summary: "Log in and get a token, users with two factor turned on get a challenge for POST /api/user/login/2fa instead.  repeated failures lock the account for a while", public: true, form: []string{"username", "password"}, response: UserAuthResponse{}},
+	"UserLoginTwoFactor":       {summary: "Exchange a login challenge and an authenticator or recovery code for a token", public: true, form: []string{"challenge", "code"}, response: UserAuthResponse{}},
+	"UserTwoFactorEnroll":      {summary: "Start turning on two factor, returns the authenticator secret and provisioning uri", form: []string{"password", "reauthToken"}, response: TwoFactorEnrollResponse{}},
+	"UserTwoFactorConfirm":     {summary: "Turn on two factor with a code from the authenticator, returns recovery codes", form: []string{"code"}, response: RecoveryCodesResponse{}},
+	"UserTwoFactorDisable":     {summary: "Turn off two factor", form: []string{"password", "reauthToken", "code"}, status: http.StatusNoContent},
+	"UserTwoFactorRecovery":    {summary: "Replace the recovery codes, the old ones stop working", form: []string{"code"}, response: RecoveryCodesResponse{}},
+	"UserAPIKeys":              {summary: "The user's api keys, without the keys themselves", response: APIKeysResponse{}},
+	"UserAPIKeyCreate":         {summary: "Make an api key for scripts and devices, the key is only returned here.  api keys can't manage the account or other keys", request: APIKeyRequest{}, response: APIKeyResponse{}, status: http.StatusCreated},
+	"UserAPIKeyRevoke":         {summary: "Revoke an api key", status: http.StatusNoContent},
+	"UserOIDCProviders":        {summary: "The identity providers users can log in with", public: true, response: OIDCProvidersResponse{}},
+	"UserOIDCLogin":            {summary: "Redirect to the identity provider to log in, it redirects back to the callback", public: true, status: http.StatusFound},
+	"UserOIDCCallback":         {summary: "Where the identity provider redirects back to.  logs in the linked user, or signs up a new user and family if the identity isn't linked.  reauth logins get a ReauthResponse instead", public: true, query: oidcCallbackParams, response: UserAuthResponse{}},
+	"UserOIDCLink":             {summary: "Start linking an identity provider to the account, send the browser to the returned url", response: OIDCLinkResponse{}},
+	"UserOIDCReauth":           {summary: "Start logging in with a linked identity provider again, the callback returns a reauth token that stands in for the password for a few minutes", response: OIDCLinkResponse{}},
+	"UserIdentities":           {summary: "The identity providers linked to the account", response: IdentitiesResponse{}},
+	"UserIdentityUnlink":       {summary: "Unlink an identity provider, users without a password can't unlink their last one", status: http.StatusNoContent},
+	"UserRefreshToken":         {summary: "Get a new long lived token", response: UserAuthResponse{}},
+	"UserGetSentInvites":       {summary: "Invites sent by and pending for the user", response: InvitesResponse{}},
+	"UserNewInvite":            {summary: "Invite another parent to the family, role is member or caregiver", form: []string{"email", "role"}, response: InviteResponse{}, status: http.StatusCreated},
+	"UserDeleteInvite":         {summary: "Delete an invite the user sent", status: http.StatusNoContent},
+	"UserResendInvite":         {summary: "Mail an invite again with a new token and expiry", response: InviteResponse{}},
+	"UserAcceptInvite":         {summary: "Accept an invite and join the family", status: http.StatusNoContent},
+	"UserDeclineInvite":        {summary: "Decline an invite", status: http.StatusNoContent},
+	"UserInvitePreview":        {summary: "Who an invite token is from and whether it can still be answered", public: true, response: InvitePreviewResponse{}},
+	"UserSignupInvite":         {summary: "Sign up with an invite token and join the inviting family", public: true, request: InviteSignupRequest{}, response: UserAuthResponse{}, status: http.StatusCreated},
+	"UserRequestResetPassword": {summary: "Email a password reset code, it works once within an hour.  accepted whether or not the email has an account", public: true, form: []string{"email"}, status: http.StatusAccepted},
+	"UserResetPassword":        {summary: "Reset a password with a reset code", public: true, form: []string{"password"}, status: http.StatusAccepted},
+	"UserSwitchFamily":         {summary: "Make another of the user's families their current family", form: []string{"familyID"}, response: UserResponse{}},
+	"FamilyGet":                {summary: "The user's current family", response: FamilyResponse{}},
+	"ChildShares":              {summary: "The families a child is shared with, for the child's own family", response: ChildSharesResponse{}},
+	"ChildShare":               {summary: "Share a child with another family, permission is view or log, admin only.  the share is pending until the other family accepts it", form: []string{"permission"}, response: goparent.Child{}},
+	"ChildShareAccept":         {summary: "Accept a child shared with your family, admin only", response: goparent.Child{}},
+	"ChildUnshare":             {summary: "Stop sharing a child with a family, the admin of either family can do this", status: http.StatusNoContent},
+	"FamilyDelete":             {summary: "Delete the family and everything in it, admin only", query: []Parameter{confirmParam}, status: http.StatusNoContent},
+	"FamilyDeleteConfirm":      {summary: "What deleting the family removes, and the confirmation the delete needs", response: FamilyDeleteResponse{}},
+	"FamilyTransfer":           {summary: "Make another member the family admin, admin only", form: []string{"userID"}, response: FamilyResponse{}},
+	"FamilyLeave":              {summary: "Leave the family, the admin has to transfer ownership first", status: http.StatusNoContent},
+	"FamilyRemoveMember":       {summary: "Remove a member from the family, admin only", status: http.StatusNoContent},
+	"FamilyList":               {summary: "Families the user is a member of", response: FamiliesResponse{}},
+	"FamilyView":               {summary: "One of the user's families", response: FamilyResponse{}},
+	"FamilyEdit":               {summary: "Change family settings, admin only", request: FamilyRequest{}, response: FamilyResponse{}},
+	"ChildrenGet":              {summary: "Children in the family", response: ChildrenResponse{}},
+	"ChildNew":                 {summary: "Add a child", request: ChildRequest{}, response: goparent.Child{}},
+	"ChildView":                {summary: "A child", response: goparent.Child{}},
+	"ChildEdit":                {summary: "Change a child", request: ChildRequest{}, response: goparent.Child{}},
+	"ChildDelete":              {summary: "Delete a child", response: ChildDeletedResponse{}, status: http.StatusAccepted},
+	"ChildSummary":             {summary: "Feeding, sleep and waste totals for a child", query: graphParams, response: ChildSummaryResponse{}},
+	"FeedingGet":               {summary: "Recent feedings for the family", query: []Parameter{daysParam, unitParam}, response: FeedingResponse{}},
+	"FeedingNew":               {summary: "Record a feeding", request: FeedingRequest{}, response: FeedingRequest{}},
+	"FeedingGraphData":         {summary: "Feeding totals bucketed for graphs", query: graphParams, response: goparent.FeedingChartData{}},
+	"FeedingView":              {summary: "A feeding, not implemented"},
+	"FeedingEdit":              {summary: "Change a feeding, not implemented"},
+	"FeedingDelete":            {summary: "Delete a feeding, not implemented"},
+	"SleepGet":                 {summary: "Recent sleeps for the family", response: SleepResponse{}},
+	"SleepNew":                 {summary: "Record a sleep", request: SleepRequest{}, response: SleepRequest{}},
+	"SleepView":                {summary: "A sleep, not implemented"},
+	"SleepEdit":                {summary: "Change a sleep, not implemented"},
+	"SleepDelete":              {summary: "Delete a sleep, not implemented"},
+	"SleepStatus":              {summary: "Whether the child has a sleep going, 404 if not", text: true},
+	"SleepStart":               {summary: "Start a sleep for the child", text: true},
+	"SleepEnd":                 {summary: "End the child's current sleep", text: true},
+	"SleepGraphData":           {summary: "Sleep durations bucketed for graphs", query: dataQueryParams, response: goparent.SleepChartData{}},
+	"WasteGet":                 {summary: "Recent wastes for the family", query: []Parameter{daysParam}, response: WasteResponse{}},
+	"WasteNew":                 {summary: "Record a waste", request: WasteRequest{}, response: WasteRequest{}},
+	"WasteGraphData":           {summary: "Waste counts bucketed for graphs", query: dataQueryParams, response: goparent.WasteChartData{}},
+	"WasteView":                {summary: "A waste, not implemented"},
+	"WasteEdit":                {summary: "Change a waste, not implemented"},
+	"WasteDelete":              {summary: "Delete a waste, not implemented"},
+
+	"V2ChildrenList":  {summary: "Children in the family", response: []*goparent.Child{}, enveloped: true},
+	"V2ChildCreate":   {summary: "Add a child", request: goparent.Child{}, response: goparent.Child{}, status: http.StatusCreated, enveloped: true},
+	"V2ChildGet":      {summary: "A child", response: goparent.Child{}, enveloped: true},
+	"V2ChildUpdate":   {summary: "Change a child's name and birthday", request: goparent.Child{}, response: goparent.Child{}, enveloped: true},
+	"V2ChildDelete":   {summary: "Delete a child", status: http.StatusNoContent},
+	"V2ChildSummary":  {summary: "Feeding, sleep and waste totals for a child", query: graphParams, response: Summary{}, enveloped: true},
+	"V2FeedingsList":  {summary: "Recent feedings for a child", tag: "v2 feedings", query: []Parameter{daysParam, unitParam}, response: []*goparent.Feeding{}, enveloped: true},
+	"V2FeedingCreate": {summary: "Record a feeding", tag: "v2 feedings", request: goparent.Feeding{}, response: goparent.Feeding{}, status: http.StatusCreated, enveloped: true},
+	"V2FeedingsGraph": {summary: "Feeding totals bucketed for graphs", tag: "v2 feedings", query: graphParams, response: goparent.FeedingChartData{}, enveloped: true},
+	"V2SleepsList":    {summary: "Recent sleeps for a child", tag: "v2 sleeps", query: []Parameter{daysParam}, response: []*goparent.Sleep{}, enveloped: true},
+	"V2SleepCreate":   {summary: "Record a finished sleep", tag: "v2 sleeps", request: goparent.Sleep{}, response: goparent.Sleep{}, status: http.StatusCreated, enveloped: true},
+	"V2SleepsGraph":   {summary: "Sleep durations bucketed for graphs", tag: "v2 sleeps", query: dataQueryParams, response: goparent.SleepChartData{}, enveloped: true},
+	"V2SleepActive":   {summary: "Whether the child has a sleep going", tag: "v2 sleeps", response: SleepStatus{}, enveloped: true},
+	"V2SleepStart":    {summary: "Start a sleep now", tag: "v2 sleeps", response: SleepStatus{}, status: http.StatusCreated, enveloped: true},
+	"V2SleepEnd":      {summary: "End the current sleep now", tag: "v2 sleeps", response: SleepStatus{}, enveloped: true},
+	"V2WastesList":    {summary: "Recent wastes for a child", tag: "v2 wastes", query: []Parameter{daysParam}, response: []*goparent.Waste{}, enveloped: true},
+	"V2WasteCreate":   {summary: "Record a waste", tag: "v2 wastes", request: goparent.Waste{}, response: goparent.Waste{}, status: http.StatusCreated, enveloped: true},
+	"V2WastesGraph":   {summary: "Waste counts bucketed for graphs", tag: "v2 wastes", query: dataQueryParams, response: goparent.WasteChartData{}, enveloped: true},
+
+	"WebhooksList":      {summary: "The family's webhooks, without their secrets", response: WebhooksResponse{}},
+	"WebhookCreate":     {summary: "Register a webhook, admin only.  the signing secret is only returned here", request: WebhookRequest{}, response: WebhookResponse{}, status: http.StatusCreated},
+	"WebhookTest":       {summary: "Send a webhook.ping event once, admin only", response: WebhookDeliveryResponse{}},
+	"WebhookDisable":    {summary: "Stop sending events to a webhook, admin only", response: WebhookResponse{}},
+	"WebhookDeliveries": {summary: "Recent delivery attempts for a webhook, newest first", response: WebhookDeliveriesResponse{}},
+
+	"RemindersList":      {summary: "The family's reminder rules", response: RemindersResponse{}},
+	"ReminderCreate":     {summary: "Add a reminder rule for a child", request: ReminderRequest{}, response: ReminderResponse{}, status: http.StatusCreated},
+	"ReminderUpdate":     {summary: "Replace a reminder rule", request: ReminderRequest{}, response: ReminderResponse{}},
+	"ReminderDelete":     {summary: "Delete a reminder rule, its notifications stay in the inbox", status: http.StatusNoContent},
+	"NotificationsList":  {summary: "The family's notification inbox, newest first", query: []Parameter{allParam}, response: NotificationsResponse{}},
+	"NotificationSnooze": {summary: "Deliver a notification again later, 15 minutes unless minutes is sent", request: SnoozeRequest{}, response: NotificationResponse{}},
+	"NotificationAck":    {summary: "Acknowledge a notification so it isn't delivered again", response: NotificationResponse{}},
+
+	"Events": {summary: "Live family events as server sent events, or over a websocket if the request asks to upgrade", query: []Parameter{accessTokenParam}, response: goparent.Event{}, stream: true},
+}
+
+var pathVarRegexp = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+//GenerateOpenAPI - build the api document by walking the router and looking up each
+// route by name in the operation registry.  it fails if a route isn't named, isn't in the
+// registry, or the registry has an operation that isn't routed.
+func GenerateOpenAPI(router *mux.Router) (*OpenAPI, error) {
+	doc := &OpenAPI{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       "goparent",
+			Description: "Track feedings, sleeps and diapers for your children.",
+			Version:     goparent.Version,
+		},
+		Paths: make(map[string]PathItem),
+		Components: OpenAPIComponents{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT or " + goparent.APIKeyPrefix + " api key"},
+			},
+		},
+	}
+	schemas := newSchemaBuilder(doc.Components.Schemas)
+	seen := make(map[string]bool)
+	tags := make(map[string]bool)
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			//subrouter prefixes
+			return nil
+		}
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return fmt.Errorf("route %s has no methods", tpl)
+		}
+		name := route.GetName()
+		if name == "" {
+			return fmt.Errorf("route %s %s has no name", strings.Join(methods, ","), tpl)
+		}
+		op, ok := operations[name]
+		if !ok {
+			return fmt.Errorf("route %s (%s %s) is not documented", name, strings.Join(methods, ","), tpl)
+		}
+		seen[name] = true
+
+		openAPIPath := pathVarRegexp.ReplaceAllString(tpl, "{$1}")
+		if doc.Paths[openAPIPath] == nil {
+			doc.Paths[openAPIPath] = make(PathItem)
+		}
+		tag := op.tag
+		if tag == "" {
+			tag = routeTag(tpl)
+		}
+		tags[tag] = true
+		for _, method := range methods {
+			doc.Paths[openAPIPath][strings.ToLower(method)] = buildOperation(name, tag, tpl, op, schemas)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for name := range operations {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("documented operations are not routed: %s", strings.Join(missing, ", "))
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, OpenAPITag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc, nil
+}
+
+//routeTag - group operations by the first path segment after /api
+func routeTag(tpl string) string {
+	parts := strings.Split(strings.TrimPrefix(tpl, "/api"), "/")
+	if len(parts) < 2 || parts[1] == "" || strings.Contains(parts[1], ".") {
+		return "service"
+	}
+	if parts[1] == "v2" && len(parts) > 2 {
+		return "v2 " + parts[2]
+	}
+	return parts[1]
+}
+
+func buildOperation(name string, tag string, tpl string, op operation, schemas *schemaBuilder) *Operation {
+	o := &Operation{
+		OperationID: name,
+		Summary:     op.summary,
+		Tags:        []string{tag},
+		Responses:   make(map[string]*Response),
+	}
+	for _, match := range pathVarRegexp.FindAllStringSubmatch(tpl, -1) {
+		o.Parameters = append(o.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	o.Parameters = append(o.Parameters, op.query...)
+	if !op.public && !strings.Contains(tpl, "{familyID}") {
+		o.Parameters = append(o.Parameters, familyParam)
+	}
+
+	if op.request != nil {
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{jsonContentType: {Schema: schemas.body(op.request, op.enveloped)}},
+		}
+	} else if len(op.form) > 0 {
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema), Required: op.form}
+		for _, field := range op.form {
+			form.Properties[field] = &Schema{Type: "string"}
+		}
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/x-www-form-urlencoded": {Schema: form}},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case op.stream:
+		success.Content = map[string]MediaType{eventStreamContentType: {Schema: schemas.schema(reflect.TypeOf(op.response))}}
+	case op.response != nil:
+		success.Content = map[string]MediaType{jsonContentType: {Schema: schemas.body(op.response, op.enveloped)}}
+	case op.text:
+		success.Content = map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}}
+	}
+	o.Responses[fmt.Sprintf("%d", status)] = success
+
+	errorContent := map[string]MediaType{
+		jsonContentType:    {Schema: schemas.schema(reflect.TypeOf(ErrService{}))},
+		problemContentType: {Schema: schemas.schema(reflect.TypeOf(ProblemDetails{}))},
+	}
+	o.Responses["default"] = &Response{Description: "error", Content: errorContent}
+
+	if !op.public {
+		o.Security = []map[string][]string{{"bearerAuth": {}}}
+		o.Description = "api keys can't be used for this"
+		if scope, ok := apiKeyRouteScopes[name]; ok {
+			o.Description = "api keys need the " + scope + " scope"
+		}
+	}
+	return o
+}
+
+//schemaBuilder - turns go types into schemas, named structs are added to the
+// components and referenced.
+type schemaBuilder struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type
+}
+
+func newSchemaBuilder(schemas map[string]*Schema) *schemaBuilder {
+	return &schemaBuilder{schemas: schemas, types: make(map[string]reflect.Type)}
+}
+
+//body - the schema of a request or response body, v2 bodies are wrapped in an Envelope
+// and lists have its meta.
+func (b *schemaBuilder) body(v interface{}, enveloped bool) *Schema {
+	t := reflect.TypeOf(v)
+	if !enveloped {
+		return b.schema(t)
+	}
+	s := &Schema{Type: "object", Properties: map[string]*Schema{"data": b.schema(t)}, Required: []string{"data"}}
+	if t.Kind() == reflect.Slice {
+		s.Properties["meta"] = b.schema(reflect.TypeOf(Meta{}))
+	}
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := b.name(t)
+		if _, ok := b.schemas[name]; !ok {
+			//add a placeholder first so recursive types terminate
+			b.schemas[name] = &Schema{}
+			*b.schemas[name] = *b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+//name - the component name of a type, types from different packages with the same
+// name are prefixed with their package.
+func (b *schemaBuilder) name(t reflect.Type) string {
+	name := t.Name()
+	if existing, ok := b.types[name]; ok && existing != t {
+		name = path.Base(t.PkgPath()) + name
+	}
+	b.types[name] = t
+	return name
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := b.structSchema(indirect(field.Type))
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = b.schema(field.Type)
+	}
+	return s
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+//openAPIHandler - serve the document for the router, it's generated the first time it's asked for
+func openAPIHandler(router *mux.Router) http.Handler {
+	var once sync.Once
+	var spec []byte
+	var specErr error
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var doc *OpenAPI
+			doc, specErr = GenerateOpenAPI(router)
+			if specErr == nil {
+				spec, specErr = json.MarshalIndent(doc, "", "  ")
+			}
+		})
+		if specErr != nil {
+			writeError(w, r, specErr)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Write(spec)
+	})
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+//TestOpenAPIMatchesRoutes - fails when a route is added without documenting it in
+// operations, or an operation is left behind after its route is removed.
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	router := BuildAPIRouting(&Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}})
+	doc, err := GenerateOpenAPI(router)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var routes int
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		tpl, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			routes++
+			item, ok := doc.Paths[pathVarRegexp.ReplaceAllString(tpl, "{$1}")]
+			if assert.True(t, ok, tpl) && assert.NotNil(t, item[strings.ToLower(method)], method+" "+tpl) {
+				assert.Equal(t, route.GetName(), item[strings.ToLower(method)].OperationID)
+			}
+		}
+		return nil
+	})
+
+	var operationCount int
+	for _, item := range doc.Paths {
+		operationCount += len(item)
+	}
+	assert.Equal(t, routes, operationCount)
+	assert.Equal(t, len(operations), operationCount)
+}
+
+func TestGenerateOpenAPIDrift(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		routes func(r *mux.Router)
+		err    string
+	}{
+		{
+			desc: "undocumented route",
+			routes: func(r *mux.Router) {
+				r.HandleFunc("/api/bogus", apiHandler).Methods("GET").Name("Bogus")
+			},
+			err: "route Bogus (GET /api/bogus) is not documented",
+		},
+		{
+			desc: "unnamed route",
+			routes: func(r *mux.Router) {
+				r.HandleFunc("/api/bogus", apiHandler).Methods("GET")
+			},
+			err: "route GET /api/bogus has no name",
+		},
+		{
+			desc:   "documented operation with no route",
+			routes: func(r *mux.Router) {},
+			err:    "documented operations are not routed: APIDocs",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := mux.NewRouter()
+			tC.routes(r)
+			_, err := GenerateOpenAPI(r)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), tC.err)
+			}
+		})
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	router := BuildAPIRouting(&Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}})
+	req, err := http.NewRequest("GET", "/api/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, jsonContentType, rr.Header().Get("Content-Type"))
+
+	var doc OpenAPI
+	err = json.NewDecoder(rr.Body).Decode(&doc)
+	assert.Nil(t, err)
+	assert.Equal(t, openAPIVersion, doc.OpenAPI)
+
+	feedingNew := doc.Paths["/api/feeding"]["post"]
+	if assert.NotNil(t, feedingNew) {
+		assert.Equal(t, "FeedingNew", feedingNew.OperationID)
+		assert.Equal(t, []string{"feeding"}, feedingNew.Tags)
+		assert.Equal(t, "#/components/schemas/FeedingRequest", feedingNew.RequestBody.Content[jsonContentType].Schema.Ref)
+		assert.NotEmpty(t, feedingNew.Security)
+	}
+	login := doc.Paths["/api/user/login"]["post"]
+	if assert.NotNil(t, login) {
+		assert.Empty(t, login.Security)
+		assert.Contains(t, login.RequestBody.Content, "application/x-www-form-urlencoded")
+	}
+	childView := doc.Paths["/api/children/{id}"]["get"]
+	if assert.NotNil(t, childView) && assert.Len(t, childView.Parameters, 2) {
+		assert.Equal(t, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}, childView.Parameters[0])
+		assert.Equal(t, familyParam, childView.Parameters[1])
+	}
+	//the family is in the path, there's no header for it
+	familyView := doc.Paths["/api/families/{familyID}"]["get"]
+	if assert.NotNil(t, familyView) && assert.Len(t, familyView.Parameters, 1) {
+		assert.Equal(t, "familyID", familyView.Parameters[0].Name)
+	}
+
+	feeding := doc.Components.Schemas["Feeding"]
+	if assert.NotNil(t, feeding) {
+		assert.Equal(t, &Schema{Type: "number", Format: "float"}, feeding.Properties["feedingAmount"])
+		assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, feeding.Properties["timestamp"])
+	}
+	user := doc.Components.Schemas["User"]
+	if assert.NotNil(t, user) {
+		assert.NotContains(t, user.Properties, "password")
+	}
+}
+
+func TestAPIDocsHandler(t *testing.T) {
+	router := BuildAPIRouting(&Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}})
+	req, err := http.NewRequest("GET", "/api/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rr.Body.String(), `fetch("openapi.json")`)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+//rateLimit - a limit on how often requests sharing a key can be made.  key gives "" for
+// requests the limit doesn't apply to.
+type rateLimit struct {
+	name  string
+	limit goparent.RateLimit
+	key   func(*http.Request) string
+}
+
+//limits for the endpoints that can be used to guess passwords, reset codes or whether
+// an account exists
+var (
+	loginLimits = []rateLimit{
+		{name: "login.ip", limit: goparent.RateLimit{Limit: 30, Window: time.Minute}, key: byIP},
+		{name: "login.account", limit: goparent.RateLimit{Limit: 10, Window: time.Minute}, key: byForm("username")},
+	}
+	loginTwoFactorLimits = []rateLimit{
+		{name: "login2fa.ip", limit: goparent.RateLimit{Limit: 30, Window: time.Minute}, key: byIP},
+	}
+	resetRequestLimits = []rateLimit{
+		{name: "reset.ip", limit: goparent.RateLimit{Limit: 10, Window: time.Hour}, key: byIP},
+		{name: "reset.account", limit: goparent.RateLimit{Limit: 3, Window: time.Hour}, key: byForm("email")},
+	}
+	resetLimits = []rateLimit{
+		{name: "resetcode.ip", limit: goparent.RateLimit{Limit: 10, Window: 15 * time.Minute}, key: byIP},
+	}
+)
+
+//lockout - accounts are locked after 5 failed logins or two factor codes in a day, for a
+// minute, doubling with every failure after that up to an hour.  a success clears it.
+var lockout = goparent.Lockout{Threshold: 5, Base: time.Minute, Max: time.Hour, Window: 24 * time.Hour}
+
+//accountLocked - the account has had too many failures, the wait is sent in Retry-After
+func accountLocked(wait time.Duration) error {
+	return &APIError{Status: http.StatusTooManyRequests, Code: CodeAccountLocked, Message: "too many failed attempts, the account is locked for now", RetryAfter: wait}
+}
+
+//byIP - key requests by the address they came from
+func byIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+//byForm - key requests by a form value, like the account they're for
+func byForm(field string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return strings.ToLower(strings.TrimSpace(r.FormValue(field)))
+	}
+}
+
+//rateLimited - count the request against each of the limits, sending 429 if it's over any.
+// if the counts can't be kept the request is let through rather than taking logins down.
+func (h *Handler) rateLimited(next http.Handler, limits ...rateLimit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.RateLimits != nil {
+			ctx := h.Env.DB.GetContext(r)
+			now := time.Now()
+			for _, l := range limits {
+				key := l.key(r)
+				if key == "" {
+					continue
+				}
+				count, err := h.RateLimits.Hit(ctx, l.name+":"+key, now, l.limit.Window)
+				if err != nil {
+					log.Printf("request %s: rate limit %s: %s", RequestIDFromContext(r.Context()), l.name, err)
+					continue
+				}
+				if wait := l.limit.Wait(count, now); wait > 0 {
+					writeError(w, r, tooManyRequests(wait))
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//lockedOut - whether the key is locked out after too many failures, writing the error if it is
+func (h *Handler) lockedOut(w http.ResponseWriter, r *http.Request, key string) bool {
+	if h.RateLimits == nil {
+		return false
+	}
+	now := time.Now()
+	count, err := h.RateLimits.Count(h.Env.DB.GetContext(r), "lockout."+key, now, lockout.Window)
+	if err != nil {
+		log.Printf("request %s: lockout: %s", RequestIDFromContext(r.Context()), err)
+		return false
+	}
+	if wait := lockout.Wait(count, now); wait > 0 {
+		writeError(w, r, accountLocked(wait))
+		return true
+	}
+	return false
+}
+
+//failedAttempt - count a failed login or code against the key
+func (h *Handler) failedAttempt(ctx context.Context, key string) {
+	if h.RateLimits == nil {
+		return
+	}
+	_, err := h.RateLimits.Hit(ctx, "lockout."+key, time.Now(), lockout.Window)
+	if err != nil {
+		log.Printf("lockout %s: %s", key, err)
+	}
+}
+
+//clearFailures - forget the key's failures after a success
+func (h *Handler) clearFailures(ctx context.Context, key string) {
+	if h.RateLimits == nil {
+		return
+	}
+	err := h.RateLimits.Reset(ctx, "lockout."+key)
+	if err != nil {
+		log.Printf("lockout %s: %s", key, err)
+	}
+}
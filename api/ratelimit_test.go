@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimited(t *testing.T) {
+	mockHandler := Handler{
+		Env:        &goparent.Env{DB: &mock.DBEnv{}},
+		RateLimits: goparent.NewMemoryRateLimitStore(),
+	}
+	limits := []rateLimit{
+		{name: "test.ip", limit: goparent.RateLimit{Limit: 3, Window: time.Minute}, key: byIP},
+		{name: "test.account", limit: goparent.RateLimit{Limit: 2, Window: time.Minute}, key: byForm("email")},
+	}
+	handler := mockHandler.rateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}), limits...)
+	send := func(addr string, email string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/user/resetpassword", strings.NewReader(url.Values{"email": {email}}.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusAccepted, send("10.0.0.1:1234", "one@test.com").Code)
+	assert.Equal(t, http.StatusAccepted, send("10.0.0.1:1235", "One@Test.com").Code)
+	rr := send("10.0.0.2:1234", "one@test.com")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "the account is over its limit from any address")
+	retryAfter, _ := strconv.Atoi(rr.Header().Get("Retry-After"))
+	assert.True(t, retryAfter > 0 && retryAfter <= 60)
+	var errMsg ErrService
+	json.NewDecoder(rr.Body).Decode(&errMsg)
+	assert.Equal(t, CodeTooManyRequests, errMsg.ErrMessage.Code)
+
+	assert.Equal(t, http.StatusAccepted, send("10.0.0.1:1236", "two@test.com").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.1:1237", "three@test.com").Code, "the address is over its limit for any account")
+	assert.Equal(t, http.StatusAccepted, send("10.0.0.3:1234", "two@test.com").Code)
+
+	unlimited := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}}
+	handler = unlimited.rateLimited(http.NotFoundHandler(), limits...)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusNotFound, send("10.0.0.1:1234", "one@test.com").Code)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	userService := &mock.UserService{
+		ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com"},
+		Token:        "this-is-a-token",
+		AuthErr:      goparent.ErrInvalidLogin,
+	}
+	mockHandler := Handler{
+		Env:         &goparent.Env{DB: &mock.DBEnv{}},
+		UserService: userService,
+		RateLimits:  goparent.NewMemoryRateLimitStore(),
+	}
+	login := func(username string) *httptest.ResponseRecorder {
+		params := url.Values{"username": {username}, "password": {"testpassword"}}
+		req, _ := http.NewRequest("POST", "/user/login", strings.NewReader(params.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		mockHandler.loginHandler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < lockout.Threshold; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login("testuser@test.com").Code)
+	}
+	rr := login("TestUser@test.com")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	var errMsg ErrService
+	json.NewDecoder(rr.Body).Decode(&errMsg)
+	assert.Equal(t, CodeAccountLocked, errMsg.ErrMessage.Code)
+
+	userService.AuthErr = nil
+	assert.Equal(t, http.StatusTooManyRequests, login("testuser@test.com").Code, "the right password doesn't get past the lockout")
+	assert.Equal(t, http.StatusOK, login("other@test.com").Code)
+
+	//a success clears the failures
+	mockHandler.RateLimits.Reset(context.Background(), "lockout.login:testuser@test.com")
+	userService.AuthErr = goparent.ErrInvalidLogin
+	login("testuser@test.com")
+	userService.AuthErr = nil
+	assert.Equal(t, http.StatusOK, login("testuser@test.com").Code)
+	count, _ := mockHandler.RateLimits.Count(context.Background(), "lockout.login:testuser@test.com", time.Now(), lockout.Window)
+	assert.Equal(t, 0, count.Count)
+
+	//errors that aren't the user's fault don't count
+	userService.AuthErr = errors.New("db down")
+	for i := 0; i < lockout.Threshold+1; i++ {
+		assert.Equal(t, http.StatusInternalServerError, login("testuser@test.com").Code)
+	}
+}
+
+func TestLoginTwoFactorLockout(t *testing.T) {
+	mockHandler := Handler{
+		Env: &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+		UserService: &mock.UserService{
+			ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			Token:        "this-is-a-token",
+		},
+		RateLimits: goparent.NewMemoryRateLimitStore(),
+	}
+	challenge, _ := mockHandler.purposeToken("login.2fa", map[string]interface{}{"user": "1"}, time.Now().Add(time.Minute))
+	send := func(code string) int {
+		params := url.Values{"challenge": {challenge}, "code": {code}}
+		req, _ := http.NewRequest("POST", "/user/login/2fa", strings.NewReader(params.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		mockHandler.loginTwoFactorHandler().ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for i := 0; i < lockout.Threshold; i++ {
+		assert.Equal(t, http.StatusUnauthorized, send("000000"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send(currentCode(t)))
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+//defaultSnooze - how long a notification is snoozed for when the request doesn't say
+const defaultSnooze = 15 * time.Minute
+
+//ReminderRequest - incoming request structure for creating or updating a reminder rule
+type ReminderRequest struct {
+	ReminderData goparent.ReminderRule `json:"reminderData"`
+}
+
+//ReminderResponse - response structure for a reminder rule
+type ReminderResponse struct {
+	ReminderData *goparent.ReminderRule `json:"reminderData"`
+}
+
+//RemindersResponse - response structure for a family's reminder rules
+type RemindersResponse struct {
+	Reminders []*goparent.ReminderRule `json:"reminders"`
+}
+
+//SnoozeRequest - how many minutes to snooze a notification for, 15 if it's left out
+type SnoozeRequest struct {
+	Minutes int `json:"minutes"`
+}
+
+//NotificationResponse - response structure for a notification
+type NotificationResponse struct {
+	NotificationData *goparent.Notification `json:"notificationData"`
+}
+
+//NotificationsResponse - the family's inbox, newest first
+type NotificationsResponse struct {
+	Notifications []*goparent.Notification `json:"notifications"`
+}
+
+func (h *Handler) initReminderHandlers(r *mux.Router) {
+	rr := r.PathPrefix("/reminders").Subrouter()
+	rr.Handle("", h.AuthRequired(h.remindersListHandler())).Methods("GET").Name("RemindersList")
+	rr.Handle("", h.AuthRequired(h.reminderCreateHandler())).Methods("POST").Name("ReminderCreate")
+	rr.Handle("/{id}", h.AuthRequired(h.reminderUpdateHandler())).Methods("PUT").Name("ReminderUpdate")
+	rr.Handle("/{id}", h.AuthRequired(h.reminderDeleteHandler())).Methods("DELETE").Name("ReminderDelete")
+
+	nr := r.PathPrefix("/notifications").Subrouter()
+	nr.Handle("", h.AuthRequired(h.notificationsListHandler())).Methods("GET").Name("NotificationsList")
+	nr.Handle("/{id}/snooze", h.AuthRequired(h.notificationSnoozeHandler())).Methods("POST").Name("NotificationSnooze")
+	nr.Handle("/{id}/ack", h.AuthRequired(h.notificationAckHandler())).Methods("POST").Name("NotificationAck")
+}
+
+//familyReminder - the reminder rule in the path, rules in other families are not found
+func (h *Handler) familyReminder(w http.ResponseWriter, r *http.Request, family *goparent.Family) (*goparent.ReminderRule, bool) {
+	rule, err := h.ReminderService.Rule(h.Env.DB.GetContext(r), mux.Vars(r)["id"])
+	if err == nil && rule.FamilyID != family.ID {
+		err = goparent.ErrNoReminderFound
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return rule, true
+}
+
+//familyNotification - the notification in the path, notifications in other families are not found
+func (h *Handler) familyNotification(w http.ResponseWriter, r *http.Request, family *goparent.Family) (*goparent.Notification, bool) {
+	n, err := h.ReminderService.Notification(h.Env.DB.GetContext(r), mux.Vars(r)["id"])
+	if err == nil && n.FamilyID != family.ID {
+		err = goparent.ErrNoNotificationFound
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return n, true
+}
+
+//decodeReminder - read and validate a reminder rule, the child has to be in the family
+func (h *Handler) decodeReminder(w http.ResponseWriter, r *http.Request, family *goparent.Family) (*goparent.ReminderRule, bool) {
+	var reminderRequest ReminderRequest
+	err := json.NewDecoder(r.Body).Decode(&reminderRequest)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	rule := &reminderRequest.ReminderData
+	err = h.validateChildEntity(h.Env.DB.GetContext(r), family, rule, "childID", rule.ChildID)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	rule.FamilyID = family.ID
+	return rule, true
+}
+
+//remindersListHandler - GET / - the family's reminder rules
+func (h *Handler) remindersListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+
+		rules, err := h.ReminderService.Rules(h.Env.DB.GetContext(r), family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(RemindersResponse{Reminders: rules})
+	})
+}
+
+//reminderCreateHandler - POST / - add a reminder rule for one of the family's children
+func (h *Handler) reminderCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+		rule, ok := h.decodeReminder(w, r, family)
+		if !ok {
+			return
+		}
+
+		rule.ID = ""
+		err := h.ReminderService.SaveRule(h.Env.DB.GetContext(r), rule)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ReminderResponse{ReminderData: rule})
+	})
+}
+
+//reminderUpdateHandler - PUT /{id} - replace a reminder rule, e.g. to change its interval or disable it
+func (h *Handler) reminderUpdateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+		existing, ok := h.familyReminder(w, r, family)
+		if !ok {
+			return
+		}
+		rule, ok := h.decodeReminder(w, r, family)
+		if !ok {
+			return
+		}
+
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+		err := h.ReminderService.SaveRule(h.Env.DB.GetContext(r), rule)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(ReminderResponse{ReminderData: rule})
+	})
+}
+
+//reminderDeleteHandler - DELETE /{id} - delete a reminder rule, its notifications stay in the inbox
+func (h *Handler) reminderDeleteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+		rule, ok := h.familyReminder(w, r, family)
+		if !ok {
+			return
+		}
+
+		err := h.ReminderService.DeleteRule(h.Env.DB.GetContext(r), rule)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//notificationsListHandler - GET / - the family's inbox, ?all=true includes acknowledged notifications
+func (h *Handler) notificationsListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+
+		all := r.URL.Query().Get("all") == "true"
+		notifications, err := h.ReminderService.Notifications(h.Env.DB.GetContext(r), family, all)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(NotificationsResponse{Notifications: notifications})
+	})
+}
+
+//notificationSnoozeHandler - POST /{id}/snooze - deliver the notification again later
+func (h *Handler) notificationSnoozeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+
+		var snoozeRequest SnoozeRequest
+		err := json.NewDecoder(r.Body).Decode(&snoozeRequest)
+		if err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		defer r.Body.Close()
+		snooze := defaultSnooze
+		if snoozeRequest.Minutes != 0 {
+			if snoozeRequest.Minutes < 0 || snoozeRequest.Minutes > 24*60 {
+				writeError(w, r, goparent.NewValidationError("minutes", "must be between 1 and 1440"))
+				return
+			}
+			snooze = time.Duration(snoozeRequest.Minutes) * time.Minute
+		}
+
+		n, ok := h.familyNotification(w, r, family)
+		if !ok {
+			return
+		}
+		if n.Status == goparent.NotificationAcknowledged {
+			writeError(w, r, NewAPIError(http.StatusConflict, CodeNotificationAcknowledged, "notification has already been acknowledged"))
+			return
+		}
+
+		n.Snooze(time.Now(), snooze)
+		err = h.ReminderService.SaveNotification(h.Env.DB.GetContext(r), n)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(NotificationResponse{NotificationData: n})
+	})
+}
+
+//notificationAckHandler - POST /{id}/ack - the notification has been dealt with
+func (h *Handler) notificationAckHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, family, ok := h.userFamily(w, r)
+		if !ok {
+			return
+		}
+		n, ok := h.familyNotification(w, r, family)
+		if !ok {
+			return
+		}
+
+		if n.Status != goparent.NotificationAcknowledged {
+			n.Acknowledge(time.Now(), user)
+			err := h.ReminderService.SaveNotification(h.Env.DB.GetContext(r), n)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(NotificationResponse{NotificationData: n})
+	})
+}
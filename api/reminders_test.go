@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderHandlers(t *testing.T) {
+	user := &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"}
+	family := &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}}
+	kid := &goparent.Child{ID: "1", FamilyID: "1", Name: "test kid"}
+	rule := func() *goparent.ReminderRule {
+		return &goparent.ReminderRule{ID: "1", FamilyID: "1", ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 180, CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	notification := func() *goparent.Notification {
+		return &goparent.Notification{ID: "1", FamilyID: "1", ChildID: "1", RuleID: "1", Status: goparent.NotificationDelivered}
+	}
+	testCases := []struct {
+		desc            string
+		method          string
+		path            string
+		vars            map[string]string
+		body            interface{}
+		handler         func(h *Handler) http.Handler
+		child           *goparent.Child
+		reminderService *mock.ReminderService
+		responseCode    int
+		errorCode       string
+		check           func(t *testing.T, body []byte, rs *mock.ReminderService)
+	}{
+		{
+			desc:            "list reminders",
+			method:          "GET",
+			path:            "/reminders",
+			handler:         (*Handler).remindersListHandler,
+			reminderService: &mock.ReminderService{GetRules: []*goparent.ReminderRule{rule()}},
+			responseCode:    http.StatusOK,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				var resp RemindersResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Len(t, resp.Reminders, 1)
+			},
+		},
+		{
+			desc:            "create reminder",
+			method:          "POST",
+			path:            "/reminders",
+			body:            ReminderRequest{ReminderData: goparent.ReminderRule{ChildID: "1", FamilyID: "2", Kind: goparent.ReminderNoWetDiaper, Interval: 240, Channels: []string{goparent.ChannelWebhook}}},
+			handler:         (*Handler).reminderCreateHandler,
+			child:           kid,
+			reminderService: &mock.ReminderService{RuleID: "5"},
+			responseCode:    http.StatusCreated,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				var resp ReminderResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Equal(t, "5", resp.ReminderData.ID)
+				assert.Equal(t, "1", resp.ReminderData.FamilyID)
+			},
+		},
+		{
+			desc:            "create reminder invalid",
+			method:          "POST",
+			path:            "/reminders",
+			body:            ReminderRequest{ReminderData: goparent.ReminderRule{ChildID: "1", Kind: "bath_time", Channels: []string{"sms"}}},
+			handler:         (*Handler).reminderCreateHandler,
+			child:           kid,
+			reminderService: &mock.ReminderService{},
+			responseCode:    http.StatusBadRequest,
+			errorCode:       CodeValidation,
+		},
+		{
+			desc:            "create reminder for other family's child",
+			method:          "POST",
+			path:            "/reminders",
+			body:            ReminderRequest{ReminderData: goparent.ReminderRule{ChildID: "2", Kind: goparent.ReminderFeedingDue, Interval: 180}},
+			handler:         (*Handler).reminderCreateHandler,
+			child:           &goparent.Child{ID: "2", FamilyID: "2"},
+			reminderService: &mock.ReminderService{},
+			responseCode:    http.StatusBadRequest,
+			errorCode:       CodeValidation,
+		},
+		{
+			desc:            "update reminder",
+			method:          "PUT",
+			path:            "/reminders/1",
+			vars:            map[string]string{"id": "1"},
+			body:            ReminderRequest{ReminderData: goparent.ReminderRule{ID: "9", ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 120, Disabled: true}},
+			handler:         (*Handler).reminderUpdateHandler,
+			child:           kid,
+			reminderService: &mock.ReminderService{GetRule: rule()},
+			responseCode:    http.StatusOK,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				if assert.Len(t, rs.SavedRules, 1) {
+					assert.Equal(t, "1", rs.SavedRules[0].ID)
+					assert.Equal(t, 120, rs.SavedRules[0].Interval)
+					assert.True(t, rs.SavedRules[0].Disabled)
+					assert.Equal(t, 2026, rs.SavedRules[0].CreatedAt.Year())
+				}
+			},
+		},
+		{
+			desc:            "update other family's reminder",
+			method:          "PUT",
+			path:            "/reminders/1",
+			vars:            map[string]string{"id": "1"},
+			body:            ReminderRequest{ReminderData: goparent.ReminderRule{ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 120}},
+			handler:         (*Handler).reminderUpdateHandler,
+			child:           kid,
+			reminderService: &mock.ReminderService{GetRule: &goparent.ReminderRule{ID: "1", FamilyID: "2"}},
+			responseCode:    http.StatusNotFound,
+			errorCode:       CodeReminderNotFound,
+		},
+		{
+			desc:            "delete reminder",
+			method:          "DELETE",
+			path:            "/reminders/1",
+			vars:            map[string]string{"id": "1"},
+			handler:         (*Handler).reminderDeleteHandler,
+			reminderService: &mock.ReminderService{GetRule: rule()},
+			responseCode:    http.StatusNoContent,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				assert.Len(t, rs.Deleted, 1)
+			},
+		},
+		{
+			desc:            "delete missing reminder",
+			method:          "DELETE",
+			path:            "/reminders/1",
+			vars:            map[string]string{"id": "1"},
+			handler:         (*Handler).reminderDeleteHandler,
+			reminderService: &mock.ReminderService{},
+			responseCode:    http.StatusNotFound,
+			errorCode:       CodeReminderNotFound,
+		},
+		{
+			desc:            "inbox",
+			method:          "GET",
+			path:            "/notifications",
+			handler:         (*Handler).notificationsListHandler,
+			reminderService: &mock.ReminderService{GetNotifications: []*goparent.Notification{notification()}},
+			responseCode:    http.StatusOK,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				var resp NotificationsResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Len(t, resp.Notifications, 1)
+			},
+		},
+		{
+			desc:            "snooze with default",
+			method:          "POST",
+			path:            "/notifications/1/snooze",
+			vars:            map[string]string{"id": "1"},
+			handler:         (*Handler).notificationSnoozeHandler,
+			reminderService: &mock.ReminderService{GetNotification: notification()},
+			responseCode:    http.StatusOK,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				var resp NotificationResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Equal(t, goparent.NotificationSnoozed, resp.NotificationData.Status)
+				assert.WithinDuration(t, time.Now().Add(defaultSnooze), resp.NotificationData.SnoozedUntil, time.Minute)
+			},
+		},
+		{
+			desc:            "snooze for an hour",
+			method:          "POST",
+			path:            "/notifications/1/snooze",
+			vars:            map[string]string{"id": "1"},
+			body:            SnoozeRequest{Minutes: 60},
+			handler:         (*Handler).notificationSnoozeHandler,
+			reminderService: &mock.ReminderService{GetNotification: notification()},
+			responseCode:    http.StatusOK,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				if assert.Len(t, rs.SavedNotifications, 1) {
+					assert.WithinDuration(t, time.Now().Add(time.Hour), rs.SavedNotifications[0].SnoozedUntil, time.Minute)
+				}
+			},
+		},
+		{
+			desc:            "snooze too long",
+			method:          "POST",
+			path:            "/notifications/1/snooze",
+			vars:            map[string]string{"id": "1"},
+			body:            SnoozeRequest{Minutes: 5000},
+			handler:         (*Handler).notificationSnoozeHandler,
+			reminderService: &mock.ReminderService{GetNotification: notification()},
+			responseCode:    http.StatusBadRequest,
+			errorCode:       CodeValidation,
+		},
+		{
+			desc:            "snooze acknowledged",
+			method:          "POST",
+			path:            "/notifications/1/snooze",
+			vars:            map[string]string{"id": "1"},
+			handler:         (*Handler).notificationSnoozeHandler,
+			reminderService: &mock.ReminderService{GetNotification: &goparent.Notification{ID: "1", FamilyID: "1", Status: goparent.NotificationAcknowledged}},
+			responseCode:    http.StatusConflict,
+			errorCode:       CodeNotificationAcknowledged,
+		},
+		{
+			desc:            "acknowledge",
+			method:          "POST",
+			path:            "/notifications/1/ack",
+			vars:            map[string]string{"id": "1"},
+			handler:         (*Handler).notificationAckHandler,
+			reminderService: &mock.ReminderService{GetNotification: notification()},
+			responseCode:    http.StatusOK,
+			check: func(t *testing.T, body []byte, rs *mock.ReminderService) {
+				var resp NotificationResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Equal(t, goparent.NotificationAcknowledged, resp.NotificationData.Status)
+				assert.Equal(t, "1", resp.NotificationData.AcknowledgedBy)
+			},
+		},
+		{
+			desc:            "acknowledge other family's notification",
+			method:          "POST",
+			path:            "/notifications/1/ack",
+			vars:            map[string]string{"id": "1"},
+			handler:         (*Handler).notificationAckHandler,
+			reminderService: &mock.ReminderService{GetNotification: &goparent.Notification{ID: "1", FamilyID: "2"}},
+			responseCode:    http.StatusNotFound,
+			errorCode:       CodeNotificationNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := &Handler{
+				Env:             &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:     &mock.UserService{Family: family},
+				ChildService:    &mock.ChildService{Kid: tC.child},
+				ReminderService: tC.reminderService,
+			}
+
+			var body bytes.Buffer
+			if tC.body != nil {
+				json.NewEncoder(&body).Encode(tC.body)
+			}
+			req, err := http.NewRequest(tC.method, tC.path, &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = mux.SetURLVars(req, tC.vars)
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+
+			rr := httptest.NewRecorder()
+			tC.handler(mockHandler).ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				assert.Nil(t, json.NewDecoder(rr.Body).Decode(&errBody))
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			}
+			if tC.check != nil {
+				tC.check(t, rr.Body.Bytes(), tC.reminderService)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"encoding/json"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/dgrijalva/jwt-go/request"
 	"github.com/gorilla/mux"
 	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/metrics"
 )
 
 type contextKey string
@@ -23,8 +25,14 @@ func (c contextKey) String() string {
 }
 
 const (
-	jsonContentType string     = "application/json"
-	userContextKey  contextKey = "user"
+	jsonContentType  string     = "application/json"
+	queryDateFormat  string     = "2006-01-02"
+	timezoneHeader   string     = "X-Timezone"
+	familyHeader     string     = "X-Family-ID"
+	userContextKey   contextKey = "user"
+	familyContextKey contextKey = "family"
+	//familyErrContextKey - why AuthRequired couldn't find the user's current family
+	familyErrContextKey contextKey = "familyErr"
 )
 
 //ServiceHandler -
@@ -43,12 +51,26 @@ type Handler struct {
 	FeedingService        goparent.FeedingService
 	SleepService          goparent.SleepService
 	WasteService          goparent.WasteService
-	Env                   *goparent.Env
+	WebhookService        goparent.WebhookService
+	WebhookDispatcher     *goparent.WebhookDispatcher
+	ReminderService       goparent.ReminderService
+	APIKeyService         goparent.APIKeyService
+	IdentityService       goparent.ExternalIdentityService
+	//RateLimits - where rate limit and lockout counts are kept, BuildAPIRouting keeps them
+	// in memory if it isn't set
+	RateLimits goparent.RateLimitStore
+	//Metrics - counts requests and serves /metrics, there are no metrics if it isn't set
+	Metrics *metrics.Metrics
+	//EventSource - what publishes the events streams and webhooks get, the health checks
+	// report it when it's set
+	EventSource goparent.EventSource
+	Env         *goparent.Env
 }
 
 //ServiceInfo - return data about the service
 type ServiceInfo struct {
 	Version  string `json:"version"`
+	Commit   string `json:"commit"`
 	Hostname string `json:"hostname"`
 }
 
@@ -60,73 +82,189 @@ type Pagination struct {
 	Days  uint64
 }
 
-//ErrService - error message format for service calls
-type ErrService struct {
-	ErrMessage struct {
-		Body string `json:"body"`
-		Code int    `json:"code"`
-	} `json:"error"`
-}
-
 //BuildAPIRouting - common api routing here if passed a handler
 func BuildAPIRouting(serviceHandler *Handler) *mux.Router {
+	if serviceHandler.RateLimits == nil {
+		serviceHandler.RateLimits = goparent.NewMemoryRateLimitStore()
+	}
 	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware)
+	if serviceHandler.Metrics != nil {
+		r.Use(serviceHandler.Metrics.Middleware)
+	}
+	r.Handle("/healthz", serviceHandler.healthHandler()).Methods("GET").Name("Health")
+	r.Handle("/readyz", serviceHandler.readyHandler()).Methods("GET").Name("Ready")
+	r.Handle("/metrics", serviceHandler.metricsHandler()).Methods("GET").Name("Metrics")
 	a := r.PathPrefix("/api").Subrouter()
-	a.HandleFunc("/", apiHandler)
-	a.HandleFunc("/info", infoHandler)
+	a.HandleFunc("/", apiHandler).Methods("GET").Name("APIDocs")
+	a.HandleFunc("/info", infoHandler).Methods("GET").Name("ServiceInfo")
+	a.Handle("/openapi.json", openAPIHandler(r)).Methods("GET").Name("OpenAPISpec")
 
 	serviceHandler.initUsersHandlers(a)
+	serviceHandler.initFamilyHandlers(a)
 	serviceHandler.initChildrenHandlers(a)
 	serviceHandler.initFeedingHandlers(a)
 	serviceHandler.initSleepHandlers(a)
 	serviceHandler.initWasteHandlers(a)
+	serviceHandler.initEventsHandlers(a)
+	serviceHandler.initWebhookHandlers(a)
+	serviceHandler.initReminderHandlers(a)
+
+	v2 := r.PathPrefix("/api/v2").Subrouter()
+	serviceHandler.initV2Handlers(v2)
 
 	return r
 }
 
+//apiHandler - interactive docs for the api, built from /api/openapi.json
 func apiHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "please check docs at: https://github.com/sasimpson/goparent")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+//metricsHandler - the prometheus metrics, not found if they're off
+func (sh *Handler) metricsHandler() http.Handler {
+	if sh.Metrics == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, r, errNotFound)
+		})
+	}
+	return sh.Metrics.Handler()
 }
 
 func infoHandler(w http.ResponseWriter, r *http.Request) {
 	name, _ := os.Hostname()
 
-	si := ServiceInfo{Version: "v0.1", Hostname: name}
+	si := ServiceInfo{Version: goparent.Version, Commit: goparent.Commit, Hostname: name}
 	json.NewEncoder(w).Encode(si)
 	return
 }
 
 //AuthRequired - handler to handle authentication of users tokens.
 func (sh *Handler) AuthRequired(h http.Handler) http.Handler {
+	return sh.authRequired(h, request.AuthorizationHeaderExtractor)
+}
+
+//authRequired - authenticate with the token the extractor finds in the request
+func (sh *Handler) authRequired(h http.Handler, extractor request.Extractor) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := sh.Env.DB.GetContext(r)
-		token, err := request.ParseFromRequestWithClaims(r, request.AuthorizationHeaderExtractor, &goparent.UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if secret, err := extractor.ExtractToken(r); err == nil && goparent.IsAPIKey(secret) {
+			sh.apiKeyAuth(w, r, h, secret)
+			return
+		}
+		token, err := request.ParseFromRequestWithClaims(r, extractor, &goparent.UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 			}
 			return sh.Env.Auth.SigningKey, nil
 		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		if claims, ok := token.Claims.(*goparent.UserClaims); ok && token.Valid {
-			user, err := sh.UserService.User(ctx, claims.ID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			ctx := context.WithValue(r.Context(), userContextKey, user)
-			h.ServeHTTP(w, r.WithContext(ctx))
+			sh.serveUser(ctx, w, r, h, claims.ID)
 			return
 		}
-		http.Error(w, "failed", http.StatusInternalServerError)
+		writeError(w, r, errUnauthorized)
 		return
 	})
 }
 
+//serveUser - serve the request as the authenticated user, with their family in the context
+func (sh *Handler) serveUser(ctx context.Context, w http.ResponseWriter, r *http.Request, h http.Handler, userID string) {
+	user, err := sh.UserService.User(ctx, userID)
+	if err != nil {
+		//a token for a user that no longer exists is no good
+		if errors.Is(err, goparent.ErrNoUserFound) {
+			err = errUnauthorized
+		}
+		writeError(w, r, err)
+		return
+	}
+	family, lookupErr, err := sh.resolveFamily(ctx, r, user)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	reqCtx := context.WithValue(r.Context(), userContextKey, user)
+	if family != nil {
+		reqCtx = context.WithValue(reqCtx, familyContextKey, family)
+	} else {
+		reqCtx = context.WithValue(reqCtx, familyErrContextKey, lookupErr)
+	}
+	h.ServeHTTP(w, r.WithContext(reqCtx))
+}
+
+//resolveFamily - the family the request is for, from the familyID path variable, the
+// X-Family-ID header or the user's current family in that order.  the user is left as
+// stored, so handlers that save them don't save the family the request was scoped to.
+// a family the user doesn't belong to is forbidden.  if the current family can't be
+// found, lookupErr is why, handlers that need a family report it from requestFamily.
+func (sh *Handler) resolveFamily(ctx context.Context, r *http.Request, user *goparent.User) (family *goparent.Family, lookupErr error, err error) {
+	id := mux.Vars(r)["familyID"]
+	if id == "" {
+		id = r.Header.Get(familyHeader)
+	}
+	explicit := id != ""
+	if !explicit {
+		id = user.CurrentFamily
+	}
+	//the backends know there's no family without a lookup, requestFamily asks them why
+	if id == "" {
+		return nil, nil, nil
+	}
+
+	scoped := *user
+	scoped.CurrentFamily = id
+	family, err = sh.UserService.GetFamily(ctx, &scoped)
+	if err != nil {
+		if explicit {
+			return nil, nil, forbidden("not a member of that family")
+		}
+		return nil, err, nil
+	}
+	if family == nil {
+		return nil, nil, nil
+	}
+	if !family.IsMember(user.ID) {
+		return nil, nil, forbidden("not a member of that family")
+	}
+	return family, nil, nil
+}
+
+//requestFamily - the family AuthRequired resolved for the request, or why it couldn't.
+// it's only looked up here for handlers that are served without AuthRequired.
+func (h *Handler) requestFamily(ctx context.Context, user *goparent.User) (*goparent.Family, error) {
+	family, err := FamilyFromContext(ctx)
+	if err == nil {
+		return family, nil
+	}
+	//AuthRequired already looked and couldn't find it
+	if err, ok := ctx.Value(familyErrContextKey).(error); ok {
+		return nil, err
+	}
+	return h.UserService.GetFamily(ctx, user)
+}
+
+//userFamily - the user from the request and their family
+func (h *Handler) userFamily(w http.ResponseWriter, r *http.Request) (*goparent.User, *goparent.Family, bool) {
+	user, err := UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, r, errUnauthorized)
+		return nil, nil, false
+	}
+
+	family, err := h.requestFamily(h.Env.DB.GetContext(r), user)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+	return user, family, true
+}
+
 //UserFromContext - helper to get the user from the request context
 func UserFromContext(ctx context.Context) (*goparent.User, error) {
 	user, ok := ctx.Value(userContextKey).(*goparent.User)
@@ -136,6 +274,43 @@ func UserFromContext(ctx context.Context) (*goparent.User, error) {
 	return user, nil
 }
 
+//FamilyFromContext - the family AuthRequired resolved for the request
+func FamilyFromContext(ctx context.Context) (*goparent.Family, error) {
+	family, ok := ctx.Value(familyContextKey).(*goparent.Family)
+	if !ok {
+		return nil, errors.New("no family found in context")
+	}
+	return family, nil
+}
+
+//purposeToken - sign a token that can only be used for one thing, like confirming a delete
+// or an email address.  they're signed with the same key as auth tokens, the purpose keeps
+// one from being used as another.
+func (h *Handler) purposeToken(purpose string, claims jwt.MapClaims, expires time.Time) (string, error) {
+	claims["purpose"] = purpose
+	claims["exp"] = expires.Unix()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.Env.Auth.SigningKey)
+}
+
+//purposeClaims - the claims of a token from purposeToken, if it was signed for the purpose
+// and hasn't expired
+func (h *Handler) purposeClaims(tokenString string, purpose string) (jwt.MapClaims, bool) {
+	if tokenString == "" {
+		return nil, false
+	}
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return h.Env.Auth.SigningKey, nil
+	})
+	if err != nil || !token.Valid || claims["purpose"] != purpose {
+		return nil, false
+	}
+	return claims, true
+}
+
 func getPagination(r *http.Request) *Pagination {
 	q := r.URL.Query()
 
@@ -145,3 +320,92 @@ func getPagination(r *http.Request) *Pagination {
 	}
 	return &Pagination{Days: days}
 }
+
+//requestLocation - the time zone to use for day boundaries on a request.  the
+// X-Timezone header overrides the family's time zone setting.
+func requestLocation(r *http.Request, family *goparent.Family) (*time.Location, error) {
+	if tz := r.Header.Get(timezoneHeader); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %s", timezoneHeader, err)
+		}
+		return loc, nil
+	}
+	if family == nil {
+		return time.UTC, nil
+	}
+	return family.Location()
+}
+
+//displayUnits - the unit system to show quantities in on a request.  the units
+// query parameter overrides the family's unit setting.
+func displayUnits(r *http.Request, family *goparent.Family) (goparent.UnitSystem, error) {
+	system := goparent.UnitSystemRecorded
+	if family != nil {
+		system = family.Units
+	}
+	if units := r.URL.Query().Get("units"); units != "" {
+		system = goparent.UnitSystem(units)
+	}
+	if !system.Valid() {
+		return "", goparent.ErrInvalidUnitSystem
+	}
+	return system, nil
+}
+
+//getDataQuery - override the default query window with the start, end, days and
+// granularity query parameters.  times can be RFC3339 or just a date, dates are
+// midnight in the default query's location.  end on its own moves the default window
+// back so it ends there.
+func getDataQuery(r *http.Request, defaultQuery *goparent.DataQuery) (*goparent.DataQuery, error) {
+	q := r.URL.Query()
+	query := *defaultQuery
+
+	if end := q.Get("end"); end != "" {
+		t, err := parseQueryTime(end, query.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %s", err)
+		}
+		//the default window ends at end, unless start or days move the start
+		defaultDays := int(defaultQuery.End.Sub(defaultQuery.Start).Round(24*time.Hour) / (24 * time.Hour))
+		query.End = t
+		query.Start = t.AddDate(0, 0, -defaultDays)
+	}
+
+	if days := q.Get("days"); days != "" {
+		d, err := strconv.ParseUint(days, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid days: %s", err)
+		}
+		query.Start = query.End.AddDate(0, 0, -int(d))
+	}
+
+	if start := q.Get("start"); start != "" {
+		t, err := parseQueryTime(start, query.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start: %s", err)
+		}
+		query.Start = t
+	}
+
+	if granularity := q.Get("granularity"); granularity != "" {
+		query.Granularity = goparent.Granularity(granularity)
+	}
+
+	err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &query, nil
+}
+
+func parseQueryTime(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.ParseInLocation(queryDateFormat, value, loc)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +10,10 @@ import (
 	"time"
 
 	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -137,3 +141,228 @@ func TestAuthRequiredMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDataQuery(t *testing.T) {
+	now := time.Date(2018, 6, 15, 12, 0, 0, 0, time.UTC)
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		desc        string
+		queryString string
+		loc         *time.Location
+		start       time.Time
+		end         time.Time
+		granularity goparent.Granularity
+		err         bool
+	}{
+		{
+			desc:        "defaults",
+			queryString: "",
+			start:       time.Date(2018, 6, 9, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2018, 6, 16, 0, 0, 0, 0, time.UTC),
+			granularity: goparent.GranularityDay,
+		},
+		{
+			desc:        "defaults in family time zone",
+			queryString: "",
+			loc:         pacific,
+			start:       time.Date(2018, 6, 9, 0, 0, 0, 0, pacific),
+			end:         time.Date(2018, 6, 16, 0, 0, 0, 0, pacific),
+			granularity: goparent.GranularityDay,
+		},
+		{
+			desc:        "dates are local midnight",
+			queryString: "?start=2018-03-10&end=2018-03-12",
+			loc:         pacific,
+			start:       time.Date(2018, 3, 10, 8, 0, 0, 0, time.UTC),
+			end:         time.Date(2018, 3, 12, 7, 0, 0, 0, time.UTC),
+			granularity: goparent.GranularityDay,
+		},
+		{
+			desc:        "start, end and granularity",
+			queryString: "?start=2018-05-01&end=2018-06-01T00:00:00Z&granularity=week",
+			start:       time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC),
+			granularity: goparent.GranularityWeek,
+		},
+		{
+			desc:        "days back from end",
+			queryString: "?days=30&granularity=month",
+			start:       time.Date(2018, 5, 17, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2018, 6, 16, 0, 0, 0, 0, time.UTC),
+			granularity: goparent.GranularityMonth,
+		},
+		{
+			desc:        "week ending a date months ago",
+			queryString: "?end=2018-01-08",
+			start:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC),
+			granularity: goparent.GranularityDay,
+		},
+		{
+			desc:        "invalid granularity",
+			queryString: "?granularity=fortnight",
+			err:         true,
+		},
+		{
+			desc:        "too many hours",
+			queryString: "?days=90&granularity=hour",
+			err:         true,
+		},
+		{
+			desc:        "invalid start",
+			queryString: "?start=yesterday",
+			err:         true,
+		},
+		{
+			desc:        "end before start",
+			queryString: "?start=2018-06-01&end=2018-05-01",
+			err:         true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/graph"+tC.queryString, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loc := tC.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			query, err := getDataQuery(req, goparent.NewGraphQuery(now, loc))
+			if tC.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.True(t, tC.start.Equal(query.Start))
+			assert.True(t, tC.end.Equal(query.End))
+			assert.Equal(t, tC.granularity, query.Granularity)
+		})
+	}
+}
+
+func TestRequestLocation(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		header   string
+		family   *goparent.Family
+		location string
+		err      bool
+	}{
+		{desc: "no family", location: "UTC"},
+		{desc: "family without time zone", family: &goparent.Family{}, location: "UTC"},
+		{desc: "family time zone", family: &goparent.Family{Timezone: "America/Los_Angeles"}, location: "America/Los_Angeles"},
+		{desc: "header overrides family", header: "Europe/London", family: &goparent.Family{Timezone: "America/Los_Angeles"}, location: "Europe/London"},
+		{desc: "invalid header", header: "Mars/Olympus_Mons", family: &goparent.Family{}, err: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tC.header != "" {
+				req.Header.Set(timezoneHeader, tC.header)
+			}
+			loc, err := requestLocation(req, tC.family)
+			if tC.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tC.location, loc.String())
+		})
+	}
+}
+
+//familiesUserService - looks families up by the user's current family
+type familiesUserService struct {
+	*mock.UserService
+	families map[string]*goparent.Family
+	lookups  int
+}
+
+func (s *familiesUserService) GetFamily(ctx context.Context, user *goparent.User) (*goparent.Family, error) {
+	s.lookups++
+	family, ok := s.families[user.CurrentFamily]
+	if !ok {
+		return nil, errors.New("no family")
+	}
+	return family, nil
+}
+
+func TestAuthRequiredFamily(t *testing.T) {
+	key := []byte("testkey")
+	families := map[string]*goparent.Family{
+		"1": {ID: "1", Admin: "1", Members: []string{"1"}},
+		"2": {ID: "2", Admin: "2", Members: []string{"2", "1"}},
+		"3": {ID: "3", Admin: "3", Members: []string{"3"}},
+	}
+	testCases := []struct {
+		desc          string
+		path          string
+		header        string
+		currentFamily string
+		responseCode  int
+		family        string
+	}{
+		{desc: "current family", path: "/feeding", currentFamily: "1", responseCode: http.StatusOK, family: "1"},
+		{desc: "no current family", path: "/feeding", responseCode: http.StatusOK},
+		{desc: "missing current family", path: "/feeding", currentFamily: "9", responseCode: http.StatusOK},
+		{desc: "removed from current family", path: "/feeding", currentFamily: "3", responseCode: http.StatusForbidden},
+		{desc: "header", path: "/feeding", header: "2", currentFamily: "1", responseCode: http.StatusOK, family: "2"},
+		{desc: "header for another family", path: "/feeding", header: "3", currentFamily: "1", responseCode: http.StatusForbidden},
+		{desc: "header for missing family", path: "/feeding", header: "9", currentFamily: "1", responseCode: http.StatusForbidden},
+		{desc: "path", path: "/families/2", header: "1", currentFamily: "1", responseCode: http.StatusOK, family: "2"},
+		{desc: "path for another family", path: "/families/3", currentFamily: "1", responseCode: http.StatusForbidden},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			user := &goparent.User{ID: "1", CurrentFamily: tC.currentFamily}
+			users := &familiesUserService{UserService: &mock.UserService{ReturnedUser: user}, families: families}
+			h := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: key}}, UserService: users}
+
+			var family *goparent.Family
+			var requestUser *goparent.User
+			var familyErr error
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				family, _ = FamilyFromContext(r.Context())
+				requestUser, _ = UserFromContext(r.Context())
+				_, familyErr = h.requestFamily(r.Context(), requestUser)
+			})
+			router := mux.NewRouter()
+			router.Handle("/feeding", h.AuthRequired(next))
+			router.Handle("/families/{familyID}", h.AuthRequired(next))
+
+			req, _ := http.NewRequest("GET", tC.path, nil)
+			req.Header.Set("Authorization", "Bearer "+makeTestToken(user, key))
+			if tC.header != "" {
+				req.Header.Set(familyHeader, tC.header)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.family != "" {
+				if assert.NotNil(t, family) {
+					assert.Equal(t, tC.family, family.ID)
+				}
+				//handlers that save the user mustn't save the family the request was for
+				assert.Equal(t, tC.currentFamily, requestUser.CurrentFamily)
+				assert.Equal(t, 1, users.lookups, "handlers use the family AuthRequired found")
+				assert.Nil(t, familyErr)
+			} else {
+				assert.Nil(t, family)
+			}
+			if tC.currentFamily == "9" {
+				assert.Equal(t, 1, users.lookups, "handlers don't look again for a family AuthRequired couldn't find")
+				assert.Error(t, familyErr)
+			}
+			//the stored user isn't changed by scoping a request
+			assert.Equal(t, tC.currentFamily, user.CurrentFamily)
+		})
+	}
+}
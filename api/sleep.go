@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"encoding/json"
 
@@ -40,19 +42,19 @@ func (h *Handler) sleepGetHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		sleepData, err := h.SleepService.Sleep(ctx, family, 7)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -66,11 +68,11 @@ func (h *Handler) sleepViewHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -78,11 +80,11 @@ func (h *Handler) sleepEditHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -93,13 +95,13 @@ func (h *Handler) sleepNewHandler() http.Handler {
 		//how time should be passed "2017-03-09T18:09:31.409Z"
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -107,17 +109,23 @@ func (h *Handler) sleepNewHandler() http.Handler {
 		var sleepRequest SleepRequest
 		err = decoder.Decode(&sleepRequest)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
 		defer r.Body.Close()
 
 		w.Header().Set("Content-Type", jsonContentType)
+		err = h.validateChildEntity(ctx, family, sleepRequest.SleepData, "childID", sleepRequest.SleepData.ChildID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
 		sleepRequest.SleepData.UserID = user.ID
 		sleepRequest.SleepData.FamilyID = family.ID
 		err = h.SleepService.Save(ctx, &sleepRequest.SleepData)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusConflict)
+			writeError(w, r, err)
 			return
 		}
 
@@ -129,11 +137,11 @@ func (h *Handler) sleepDeleteHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -142,35 +150,31 @@ func (h *Handler) sleepStartHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		childID := mux.Vars(r)["childID"]
 		if childID == "" {
-			http.Error(w, "invalid Child ID", http.StatusBadRequest)
+			writeError(w, r, badRequest(errors.New("invalid Child ID")))
 			return
 		}
 
-		family, err := h.FamilyService.Family(ctx, user.CurrentFamily)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		child, err := h.ChildService.Child(ctx, childID)
+		child, err := h.loggableChild(ctx, family, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		err = h.SleepService.Start(ctx, family, child)
 		if err != nil {
-			if err == goparent.ErrExistingStart {
-				http.Error(w, err.Error(), http.StatusConflict)
-				return
-			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -184,35 +188,31 @@ func (h *Handler) sleepEndHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		childID := mux.Vars(r)["childID"]
 		if childID == "" {
-			http.Error(w, "invalid Child ID", http.StatusBadRequest)
+			writeError(w, r, badRequest(errors.New("invalid Child ID")))
 			return
 		}
 
-		family, err := h.FamilyService.Family(ctx, user.CurrentFamily)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		child, err := h.ChildService.Child(ctx, childID)
+		child, err := h.loggableChild(ctx, family, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		err = h.SleepService.End(ctx, family, child)
 		if err != nil {
-			if err == goparent.ErrNoExistingSession {
-				http.Error(w, err.Error(), http.StatusNotFound)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			writeError(w, r, err)
 			return
 		}
 
@@ -226,31 +226,31 @@ func (h *Handler) sleepToggleStatus() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		childID := mux.Vars(r)["childID"]
 		if childID == "" {
-			http.Error(w, "invalid Child ID", http.StatusBadRequest)
+			writeError(w, r, badRequest(errors.New("invalid Child ID")))
 			return
 		}
 
-		family, err := h.FamilyService.Family(ctx, user.CurrentFamily)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		child, err := h.ChildService.Child(ctx, childID)
+		child, err := h.familyChild(ctx, family, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		_, ok, err := h.SleepService.Status(ctx, family, child)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -258,7 +258,7 @@ func (h *Handler) sleepToggleStatus() http.Handler {
 			fmt.Fprintf(w, "sleep session active")
 			return
 		}
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, r, errNotFound)
 		return
 	})
 }
@@ -269,33 +269,45 @@ func (h *Handler) sleepGraphDataHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		childID := mux.Vars(r)["childID"]
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		child, err := h.ChildService.Child(ctx, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		//child needs to belong to the user's family.
-		if child.FamilyID != family.ID {
-			http.Error(w, "not found", http.StatusNotFound)
+		//child needs to belong to or be shared with the user's family.
+		if !child.CanView(family.ID) {
+			writeError(w, r, errNotFound)
+			return
+		}
+
+		loc, err := requestLocation(r, family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		query, err := getDataQuery(r, goparent.NewGraphQuery(time.Now(), loc))
+		if err != nil {
+			writeError(w, r, badRequest(err))
 			return
 		}
 
-		sleepGraphData, err := h.SleepService.GraphData(ctx, child)
+		sleepGraphData, err := h.SleepService.GraphData(ctx, child, query)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -149,7 +149,7 @@ func TestSleepGetHandler(t *testing.T) {
 }
 
 func TestSleepNewHandler(t *testing.T) {
-	timestamp := time.Now()
+	timestamp := time.Now().Add(-2 * time.Hour)
 	testCases := []struct {
 		desc          string
 		env           *goparent.Env
@@ -157,6 +157,7 @@ func TestSleepNewHandler(t *testing.T) {
 		userService   goparent.UserService
 		familyService goparent.FamilyService
 		sleepService  goparent.SleepService
+		childService  goparent.ChildService
 		contextUser   *goparent.User
 		contextError  bool
 		responseCode  int
@@ -182,6 +183,7 @@ func TestSleepNewHandler(t *testing.T) {
 				},
 			},
 			familyService: &mock.FamilyService{},
+			childService:  &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
 			sleepService:  &mock.SleepService{},
 			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:  false,
@@ -227,12 +229,13 @@ func TestSleepNewHandler(t *testing.T) {
 				},
 			},
 			familyService: &mock.FamilyService{},
+			childService:  &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
 			sleepService: &mock.SleepService{
 				GetErr: errors.New("unknown sleep error"),
 			},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError: false,
-			responseCode: http.StatusConflict,
+			responseCode: http.StatusInternalServerError,
 		},
 		{
 			desc: "returns auth error",
@@ -267,7 +270,7 @@ func TestSleepNewHandler(t *testing.T) {
 			sleepService:  &mock.SleepService{},
 			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:  false,
-			responseCode:  http.StatusInternalServerError,
+			responseCode:  http.StatusBadRequest,
 		},
 	}
 	for _, tC := range testCases {
@@ -277,6 +280,7 @@ func TestSleepNewHandler(t *testing.T) {
 				UserService:   tC.userService,
 				FamilyService: tC.familyService,
 				SleepService:  tC.sleepService,
+				ChildService:  tC.childService,
 			}
 
 			js, err := json.Marshal(&tC.sleepRequest)
@@ -467,16 +471,16 @@ func TestSleepDeleteHandler(t *testing.T) {
 
 func TestSleepStartHandler(t *testing.T) {
 	testCases := []struct {
-		desc          string
-		env           *goparent.Env
-		route         string
-		method        string
-		childID       string
-		familyService goparent.FamilyService
-		childService  goparent.ChildService
-		sleepService  goparent.SleepService
-		responseCode  int
-		contextUser   *goparent.User
+		desc         string
+		env          *goparent.Env
+		route        string
+		method       string
+		childID      string
+		family       *goparent.Family
+		childService goparent.ChildService
+		sleepService goparent.SleepService
+		responseCode int
+		contextUser  *goparent.User
 	}{
 		{
 			desc:         "sleepStartHandler unauthorized",
@@ -499,11 +503,9 @@ func TestSleepStartHandler(t *testing.T) {
 			env:     &goparent.Env{DB: &mock.DBEnv{}},
 			route:   "/sleep/start/1",
 			childID: "1",
-			familyService: &mock.FamilyService{
-				GetFamily: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
-			},
+			family:  &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
 			childService: &mock.ChildService{
-				Kid: &goparent.Child{ID: "1"},
+				Kid: &goparent.Child{ID: "1", FamilyID: "1"},
 			},
 			sleepService: &mock.SleepService{
 				GetStatus: false,
@@ -518,11 +520,9 @@ func TestSleepStartHandler(t *testing.T) {
 			env:     &goparent.Env{DB: &mock.DBEnv{}},
 			route:   "/sleep/start/1",
 			childID: "1",
-			familyService: &mock.FamilyService{
-				GetFamily: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
-			},
+			family:  &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
 			childService: &mock.ChildService{
-				Kid: &goparent.Child{ID: "1"},
+				Kid: &goparent.Child{ID: "1", FamilyID: "1"},
 			},
 			sleepService: &mock.SleepService{
 				StartErr:  goparent.ErrExistingStart,
@@ -533,14 +533,27 @@ func TestSleepStartHandler(t *testing.T) {
 			responseCode: http.StatusConflict,
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 		},
+		{
+			desc:    "sleepStartHandler child in another family",
+			env:     &goparent.Env{DB: &mock.DBEnv{}},
+			route:   "/sleep/start/1",
+			childID: "1",
+			family:  &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			childService: &mock.ChildService{
+				Kid: &goparent.Child{ID: "1", FamilyID: "2"},
+			},
+			sleepService: &mock.SleepService{},
+			method:       "POST",
+			responseCode: http.StatusNotFound,
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			mockHandler := Handler{
-				Env:           tC.env,
-				FamilyService: tC.familyService,
-				ChildService:  tC.childService,
-				SleepService:  tC.sleepService,
+				Env:          tC.env,
+				ChildService: tC.childService,
+				SleepService: tC.sleepService,
 			}
 			req, _ := http.NewRequest(tC.method, tC.route, nil)
 			req = mux.SetURLVars(req, map[string]string{"childID": tC.childID})
@@ -553,6 +566,9 @@ func TestSleepStartHandler(t *testing.T) {
 			} else {
 				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
 			}
+			if tC.family != nil {
+				ctx = context.WithValue(ctx, familyContextKey, tC.family)
+			}
 			req = req.WithContext(ctx)
 			handler.ServeHTTP(rr, req)
 			assert.Equal(t, tC.responseCode, rr.Code)
@@ -563,16 +579,16 @@ func TestSleepStartHandler(t *testing.T) {
 
 func TestSleepEndHandler(t *testing.T) {
 	testCases := []struct {
-		desc          string
-		env           *goparent.Env
-		route         string
-		method        string
-		childID       string
-		familyService goparent.FamilyService
-		childService  goparent.ChildService
-		sleepService  goparent.SleepService
-		responseCode  int
-		contextUser   *goparent.User
+		desc         string
+		env          *goparent.Env
+		route        string
+		method       string
+		childID      string
+		family       *goparent.Family
+		childService goparent.ChildService
+		sleepService goparent.SleepService
+		responseCode int
+		contextUser  *goparent.User
 	}{
 		{
 			desc:         "sleepStartHandler unauthorized",
@@ -596,11 +612,9 @@ func TestSleepEndHandler(t *testing.T) {
 			route:   "/sleep/end",
 			method:  "POST",
 			childID: "1",
-			familyService: &mock.FamilyService{
-				GetFamily: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
-			},
+			family:  &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
 			childService: &mock.ChildService{
-				Kid: &goparent.Child{ID: "1"},
+				Kid: &goparent.Child{ID: "1", FamilyID: "1"},
 			},
 			sleepService: &mock.SleepService{
 				GetStatus: true,
@@ -615,11 +629,9 @@ func TestSleepEndHandler(t *testing.T) {
 			route:   "/sleep/end",
 			method:  "POST",
 			childID: "1",
-			familyService: &mock.FamilyService{
-				GetFamily: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
-			},
+			family:  &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
 			childService: &mock.ChildService{
-				Kid: &goparent.Child{ID: "1"},
+				Kid: &goparent.Child{ID: "1", FamilyID: "1"},
 			},
 			sleepService: &mock.SleepService{
 				EndErr:    goparent.ErrNoExistingSession,
@@ -633,10 +645,9 @@ func TestSleepEndHandler(t *testing.T) {
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			mockHandler := Handler{
-				Env:           tC.env,
-				FamilyService: tC.familyService,
-				ChildService:  tC.childService,
-				SleepService:  tC.sleepService,
+				Env:          tC.env,
+				ChildService: tC.childService,
+				SleepService: tC.sleepService,
 			}
 			req, _ := http.NewRequest(tC.method, tC.route, nil)
 			req = mux.SetURLVars(req, map[string]string{"childID": tC.childID})
@@ -649,6 +660,9 @@ func TestSleepEndHandler(t *testing.T) {
 			} else {
 				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
 			}
+			if tC.family != nil {
+				ctx = context.WithValue(ctx, familyContextKey, tC.family)
+			}
 			req = req.WithContext(ctx)
 			handler.ServeHTTP(rr, req)
 			assert.Equal(t, tC.responseCode, rr.Code)
@@ -659,16 +673,16 @@ func TestSleepEndHandler(t *testing.T) {
 
 func TestSleepToggleStatusHandler(t *testing.T) {
 	testCases := []struct {
-		desc          string
-		env           *goparent.Env
-		route         string
-		method        string
-		responseCode  int
-		childID       string
-		contextUser   *goparent.User
-		familyService goparent.FamilyService
-		childService  goparent.ChildService
-		sleepService  goparent.SleepService
+		desc         string
+		env          *goparent.Env
+		route        string
+		method       string
+		responseCode int
+		childID      string
+		contextUser  *goparent.User
+		family       *goparent.Family
+		childService goparent.ChildService
+		sleepService goparent.SleepService
 	}{
 		{
 			desc:         "sleepToggleStatusHandler unauthorized",
@@ -694,11 +708,9 @@ func TestSleepToggleStatusHandler(t *testing.T) {
 			childID:      "1",
 			responseCode: http.StatusOK,
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser", CurrentFamily: "1"},
-			familyService: &mock.FamilyService{
-				GetFamily: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
-			},
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
 			childService: &mock.ChildService{
-				Kid: &goparent.Child{ID: "1"},
+				Kid: &goparent.Child{ID: "1", FamilyID: "1"},
 			},
 			sleepService: &mock.SleepService{
 				GetStatus: true,
@@ -713,11 +725,9 @@ func TestSleepToggleStatusHandler(t *testing.T) {
 			childID:      "1",
 			responseCode: http.StatusNotFound,
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser", CurrentFamily: "1"},
-			familyService: &mock.FamilyService{
-				GetFamily: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
-			},
+			family:       &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
 			childService: &mock.ChildService{
-				Kid: &goparent.Child{ID: "1"},
+				Kid: &goparent.Child{ID: "1", FamilyID: "1"},
 			},
 			sleepService: &mock.SleepService{
 				GetStatus: false,
@@ -728,10 +738,9 @@ func TestSleepToggleStatusHandler(t *testing.T) {
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			mockHandler := Handler{
-				Env:           tC.env,
-				FamilyService: tC.familyService,
-				ChildService:  tC.childService,
-				SleepService:  tC.sleepService,
+				Env:          tC.env,
+				ChildService: tC.childService,
+				SleepService: tC.sleepService,
 			}
 			req, _ := http.NewRequest(tC.method, tC.route, nil)
 			req = mux.SetURLVars(req, map[string]string{"childID": tC.childID})
@@ -744,6 +753,9 @@ func TestSleepToggleStatusHandler(t *testing.T) {
 			} else {
 				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
 			}
+			if tC.family != nil {
+				ctx = context.WithValue(ctx, familyContextKey, tC.family)
+			}
 			req = req.WithContext(ctx)
 			handler.ServeHTTP(rr, req)
 			assert.Equal(t, tC.responseCode, rr.Code)
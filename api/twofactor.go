@@ -0,0 +1,257 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sasimpson/goparent"
+)
+
+//twoFactorChallengeWindow - how long after the password the two factor code has to be sent
+const twoFactorChallengeWindow = 5 * time.Minute
+
+var (
+	errInvalidChallenge      = NewAPIError(http.StatusUnauthorized, CodeInvalidToken, "the login challenge is invalid or has expired, log in again")
+	errInvalidTwoFactorCode  = NewAPIError(http.StatusUnauthorized, CodeInvalidTwoFactorCode, "the two factor code is wrong")
+	errTwoFactorEnabled      = NewAPIError(http.StatusConflict, CodeConflict, "two factor authentication is already turned on")
+	errTwoFactorNotEnabled   = NewAPIError(http.StatusConflict, CodeConflict, "two factor authentication isn't turned on")
+	errTwoFactorNotEnrolling = NewAPIError(http.StatusConflict, CodeConflict, "start two factor enrollment with POST /api/user/2fa first")
+)
+
+//TwoFactorEnrollResponse - the secret for the user's authenticator app, as is and as a
+// provisioning uri to show as a qr code
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+//RecoveryCodesResponse - recovery codes, they're only ever shown this once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+//loginTwoFactorHandler - the second step of logging in for users with two factor turned
+// on, the challenge from the login and a code from their authenticator or a recovery code
+// get them a token
+func (h *Handler) loginTwoFactorHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		claims, ok := h.purposeClaims(r.FormValue("challenge"), "login.2fa")
+		userID, _ := claims["user"].(string)
+		if !ok || userID == "" {
+			writeError(w, r, errInvalidChallenge)
+			return
+		}
+		user, err := h.UserService.User(ctx, userID)
+		if err != nil || !user.TOTPEnabled {
+			writeError(w, r, errInvalidChallenge)
+			return
+		}
+		if h.lockedOut(w, r, "2fa:"+user.ID) {
+			return
+		}
+
+		valid, recovery := user.CheckTwoFactor(r.FormValue("code"), time.Now())
+		if !valid {
+			h.failedAttempt(ctx, "2fa:"+user.ID)
+			writeError(w, r, errInvalidTwoFactorCode)
+			return
+		}
+		h.clearFailures(ctx, "2fa:"+user.ID)
+		//the code is used up either way
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if recovery {
+			log.Printf("user %s logged in with a recovery code, %d left", user.ID, len(user.RecoveryCodes))
+		}
+		h.writeLogin(w, r, user)
+	})
+}
+
+//twoFactorEnrollHandler - start turning on two factor.  the user gets a new secret for
+// their authenticator, it isn't needed to log in until it's confirmed with a code.
+func (h *Handler) twoFactorEnrollHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if user.TOTPEnabled {
+			writeError(w, r, errTwoFactorEnabled)
+			return
+		}
+		if !h.reauthenticate(w, r, user) {
+			return
+		}
+
+		secret, err := goparent.NewTOTPSecret()
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		user.TOTPSecret = secret
+		user.TOTPLastStep = 0
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(TwoFactorEnrollResponse{Secret: secret, URI: goparent.TOTPURI(secret, user.Email)})
+	})
+}
+
+//twoFactorConfirmHandler - turn two factor on with a code from the authenticator the user
+// just set up, they get their recovery codes back
+func (h *Handler) twoFactorConfirmHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if user.TOTPEnabled {
+			writeError(w, r, errTwoFactorEnabled)
+			return
+		}
+		if user.TOTPSecret == "" {
+			writeError(w, r, errTwoFactorNotEnrolling)
+			return
+		}
+		if !user.UseTOTP(r.FormValue("code"), time.Now()) {
+			writeError(w, r, errInvalidTwoFactorCode)
+			return
+		}
+
+		codes, hashes, err := goparent.NewRecoveryCodes()
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		user.TOTPEnabled = true
+		user.RecoveryCodes = hashes
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+//twoFactorDisableHandler - turn two factor off, it needs the password and a current code
+func (h *Handler) twoFactorDisableHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, ok := h.twoFactorUser(w, r)
+		if !ok {
+			return
+		}
+		if !h.reauthenticate(w, r, user) {
+			return
+		}
+
+		user.TOTPEnabled = false
+		user.TOTPSecret = ""
+		user.RecoveryCodes = nil
+		err := h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//twoFactorRecoveryHandler - replace the user's recovery codes, the old ones stop working
+func (h *Handler) twoFactorRecoveryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, ok := h.twoFactorUser(w, r)
+		if !ok {
+			return
+		}
+
+		codes, hashes, err := goparent.NewRecoveryCodes()
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		user.RecoveryCodes = hashes
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+//twoFactorUser - the user, for changes to two factor that need it turned on and a code
+// from the authenticator or a recovery code, writing the error if it isn't or the code is wrong.
+// the code is used up, handlers save the user after it.
+func (h *Handler) twoFactorUser(w http.ResponseWriter, r *http.Request) (*goparent.User, bool) {
+	ctx := h.Env.DB.GetContext(r)
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeError(w, r, errUnauthorized)
+		return nil, false
+	}
+	if !user.TOTPEnabled {
+		writeError(w, r, errTwoFactorNotEnabled)
+		return nil, false
+	}
+	if h.lockedOut(w, r, "2fa:"+user.ID) {
+		return nil, false
+	}
+	valid, _ := user.CheckTwoFactor(r.FormValue("code"), time.Now())
+	if !valid {
+		h.failedAttempt(ctx, "2fa:"+user.ID)
+		writeError(w, r, errInvalidTwoFactorCode)
+		return nil, false
+	}
+	h.clearFailures(ctx, "2fa:"+user.ID)
+	return user, true
+}
+
+//loginOrChallenge - log the user in, or send the two factor challenge if they have it on
+func (h *Handler) loginOrChallenge(w http.ResponseWriter, r *http.Request, user *goparent.User) {
+	if user.TOTPEnabled {
+		challenge, err := h.purposeToken("login.2fa", jwt.MapClaims{"user": user.ID}, time.Now().Add(twoFactorChallengeWindow))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(UserAuthResponse{TwoFactorRequired: true, Challenge: challenge})
+		return
+	}
+	h.writeLogin(w, r, user)
+}
+
+//writeLogin - send a logged in user their token
+func (h *Handler) writeLogin(w http.ResponseWriter, r *http.Request, user *goparent.User) {
+	token, err := h.UserService.GetToken(user, time.Minute*5)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var userResp UserAuthResponse
+	userResp.UserData = user
+	userResp.Token = token
+	w.Header().Set("Content-Type", jsonContentType)
+	w.Header().Set("x-auth-token", token)
+	json.NewEncoder(w).Encode(userResp)
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+//testTOTPSecret - the RFC 6238 sha1 test secret
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func currentCode(t *testing.T) string {
+	code, err := goparent.TOTPCode(testTOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+//currentStep - the time step currentCode is for
+func currentStep() int64 {
+	return time.Now().Unix() / int64(goparent.TOTPPeriod.Seconds())
+}
+
+func TestLoginTwoFactorChallenge(t *testing.T) {
+	mockHandler := Handler{
+		Env: &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+		UserService: &mock.UserService{
+			ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			Token:        "this-is-a-token",
+		},
+	}
+	params := url.Values{"username": {"testuser@test.com"}, "password": {"testpassword"}}
+	req, _ := http.NewRequest("POST", "/user/login", strings.NewReader(params.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	mockHandler.loginHandler().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("x-auth-token"))
+
+	var resp UserAuthResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.Nil(t, err)
+	assert.True(t, resp.TwoFactorRequired)
+	assert.Empty(t, resp.Token)
+	assert.Nil(t, resp.UserData)
+	_, ok := mockHandler.purposeClaims(resp.Challenge, "login.2fa")
+	assert.True(t, ok)
+}
+
+func TestLoginTwoFactorHandler(t *testing.T) {
+	h := Handler{Env: &goparent.Env{Auth: goparent.Authentication{SigningKey: []byte("test")}}}
+	challenge, _ := h.purposeToken("login.2fa", jwt.MapClaims{"user": "1"}, time.Now().Add(time.Minute))
+	expired, _ := h.purposeToken("login.2fa", jwt.MapClaims{"user": "1"}, time.Now().Add(-time.Minute))
+	verifyToken, _ := h.purposeToken("email.verify", jwt.MapClaims{"user": "1", "email": "testuser@test.com"}, time.Now().Add(time.Minute))
+	codes, hashes, _ := goparent.NewRecoveryCodes()
+
+	testCases := []struct {
+		desc         string
+		challenge    string
+		code         string
+		user         *goparent.User
+		responseCode int
+		saved        int
+	}{
+		{
+			desc:         "no challenge",
+			code:         currentCode(t),
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "expired challenge",
+			challenge:    expired,
+			code:         currentCode(t),
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "token for something else",
+			challenge:    verifyToken,
+			code:         currentCode(t),
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "wrong code",
+			challenge:    challenge,
+			code:         "000000",
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "authenticator code",
+			challenge:    challenge,
+			code:         currentCode(t),
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret},
+			responseCode: http.StatusOK,
+			saved:        1,
+		},
+		{
+			desc:         "authenticator code already used",
+			challenge:    challenge,
+			code:         currentCode(t),
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret, TOTPLastStep: currentStep()},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "recovery code",
+			challenge:    challenge,
+			code:         codes[0],
+			user:         &goparent.User{ID: "1", TOTPEnabled: true, TOTPSecret: testTOTPSecret, RecoveryCodes: hashes},
+			responseCode: http.StatusOK,
+			saved:        1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			userService := &mock.UserService{ReturnedUser: tC.user, Token: "this-is-a-token"}
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+				UserService: userService,
+			}
+			form := url.Values{"challenge": {tC.challenge}, "code": {tC.code}}
+			req, _ := http.NewRequest("POST", "/user/login/2fa", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			mockHandler.loginTwoFactorHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				assert.Equal(t, "this-is-a-token", rr.Header().Get("x-auth-token"))
+			}
+			if assert.Len(t, userService.Saved, tC.saved) && tC.saved > 0 {
+				//whichever code was used, it can't be used again
+				valid, _ := userService.Saved[0].CheckTwoFactor(tC.code, time.Now())
+				assert.False(t, valid)
+			}
+		})
+	}
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	user := &goparent.User{ID: "1", Email: "testuser@test.com"}
+	userService := &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}}
+	mockHandler := Handler{
+		Env:         &goparent.Env{DB: &mock.DBEnv{}},
+		UserService: userService,
+	}
+	serve := func(handler http.Handler, form url.Values) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/user/2fa", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	//confirming before enrolling
+	rr := serve(mockHandler.twoFactorConfirmHandler(), url.Values{"code": {"000000"}})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = serve(mockHandler.twoFactorEnrollHandler(), url.Values{"password": {"testing"}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var enroll TwoFactorEnrollResponse
+	err := json.NewDecoder(rr.Body).Decode(&enroll)
+	assert.Nil(t, err)
+	assert.Equal(t, user.TOTPSecret, enroll.Secret)
+	assert.Equal(t, goparent.TOTPURI(enroll.Secret, "testuser@test.com"), enroll.URI)
+	assert.False(t, user.TOTPEnabled)
+
+	rr = serve(mockHandler.twoFactorConfirmHandler(), url.Values{"code": {"000000"}})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	code, _ := goparent.TOTPCode(enroll.Secret, time.Now())
+	rr = serve(mockHandler.twoFactorConfirmHandler(), url.Values{"code": {code}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var recovery RecoveryCodesResponse
+	err = json.NewDecoder(rr.Body).Decode(&recovery)
+	assert.Nil(t, err)
+	assert.Len(t, recovery.RecoveryCodes, goparent.RecoveryCodeCount)
+	assert.True(t, user.TOTPEnabled)
+	assert.NotContains(t, user.RecoveryCodes, recovery.RecoveryCodes[0])
+
+	//enrolling again once it's on
+	rr = serve(mockHandler.twoFactorEnrollHandler(), url.Values{"password": {"testing"}})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = serve(mockHandler.twoFactorRecoveryHandler(), url.Values{"code": {recovery.RecoveryCodes[0]}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var replaced RecoveryCodesResponse
+	err = json.NewDecoder(rr.Body).Decode(&replaced)
+	assert.Nil(t, err)
+	valid, _ := user.CheckTwoFactor(recovery.RecoveryCodes[1], time.Now())
+	assert.False(t, valid, "the old codes stop working")
+
+	rr = serve(mockHandler.twoFactorDisableHandler(), url.Values{"password": {"testing"}, "code": {"000000"}})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	rr = serve(mockHandler.twoFactorDisableHandler(), url.Values{"password": {"testing"}, "code": {code}})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "the code that turned it on can't be used again")
+	rr = serve(mockHandler.twoFactorDisableHandler(), url.Values{"password": {"testing"}, "code": {replaced.RecoveryCodes[0]}})
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.False(t, user.TOTPEnabled)
+	assert.Empty(t, user.TOTPSecret)
+	assert.Empty(t, user.RecoveryCodes)
+
+	rr = serve(mockHandler.twoFactorDisableHandler(), url.Values{"password": {"testing"}, "code": {code}})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 	"github.com/sasimpson/goparent"
 )
@@ -23,19 +28,20 @@ type UserResponse struct {
 //NewUserRequest - this is for submitting password in new user request
 type NewUserRequest struct {
 	UserData struct {
-		ID            string `json:"id,omitempty"`
-		Name          string `json:"name"`
-		Email         string `json:"email"`
-		Username      string `json:"username"`
-		Password      string `json:"password"`
-		CurrentFamily string `json:"currentFamily"`
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Username string `json:"username"`
+		Password string `json:"password"`
 	} `json:"userData"`
 }
 
-//UserAuthResponse - auth response structure
+//UserAuthResponse - auth response structure.  logins for users with two factor turned on
+// only get a Challenge, which is exchanged for the token with a code.
 type UserAuthResponse struct {
-	UserData *goparent.User `json:"userData"`
-	Token    string         `json:"token"`
+	UserData          *goparent.User `json:"userData"`
+	Token             string         `json:"token"`
+	TwoFactorRequired bool           `json:"twoFactorRequired,omitempty"`
+	Challenge         string         `json:"challenge,omitempty"`
 }
 
 //InvitesResponse - response structure for invites
@@ -44,18 +50,84 @@ type InvitesResponse struct {
 	PendingInviteData []*goparent.UserInvitation `json:"pendingInviteData"`
 }
 
+//InviteResponse - response structure for a sent invite
+type InviteResponse struct {
+	InviteData *goparent.UserInvitation `json:"inviteData"`
+}
+
+//InvitePreviewResponse - what someone holding an invite token is shown before they sign
+// up or log in to answer it
+type InvitePreviewResponse struct {
+	From      string                    `json:"from"`
+	Email     string                    `json:"email"`
+	Role      string                    `json:"role"`
+	Status    goparent.InvitationStatus `json:"status"`
+	ExpiresAt time.Time                 `json:"expiresAt"`
+}
+
+//emailChangeWindow - how long the token mailed to a new address can confirm it for
+const emailChangeWindow = 24 * time.Hour
+
+var errInvalidEmailToken = NewAPIError(http.StatusBadRequest, CodeInvalidToken, "the email change token is invalid or has expired")
+
+//verificationWindow - how long the token mailed to a new user can verify their email for
+const verificationWindow = 72 * time.Hour
+
+//verificationResendInterval - how long a user has to wait before another verification mail
+const verificationResendInterval = 5 * time.Minute
+
+var (
+	errInvalidVerifyToken = NewAPIError(http.StatusBadRequest, CodeInvalidToken, "the verification token is invalid or has expired")
+	errAlreadyVerified    = NewAPIError(http.StatusConflict, CodeConflict, "the email is already verified")
+	errReauthRequired     = NewAPIError(http.StatusUnauthorized, CodeReauthRequired, "confirm it's you by logging in with your identity provider again, POST /api/user/oidc/{provider}/reauth")
+)
+
+//InviteSignupRequest - sign up with the email an invite was sent to and join its family
+type InviteSignupRequest struct {
+	Token    string `json:"token"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 func (h *Handler) initUsersHandlers(r *mux.Router) {
 	u := r.PathPrefix("/user").Subrouter()
 	u.Handle("/", h.userNewHandler()).Methods("POST").Name("UserNew")
 	u.Handle("/", h.AuthRequired(h.userGetHandler())).Methods("GET").Name("UserGetData")
-	u.Handle("/login", h.loginHandler()).Methods("POST").Name("UserLogin")
+	u.Handle("/", h.AuthRequired(h.userUpdateHandler())).Methods("PUT").Name("UserUpdate")
+	u.Handle("/delete", h.AuthRequired(h.userDeleteHandler())).Methods("POST").Name("UserDelete")
+	u.Handle("/email", h.AuthRequired(h.userChangeEmailHandler())).Methods("POST").Name("UserChangeEmail")
+	u.Handle("/email/confirm", h.userConfirmEmailHandler()).Methods("POST").Name("UserConfirmEmail")
+	u.Handle("/verify", h.userVerifyEmailHandler()).Methods("POST").Name("UserVerifyEmail")
+	u.Handle("/verify/resend", h.AuthRequired(h.userResendVerificationHandler())).Methods("POST").Name("UserResendVerification")
+	u.Handle("/login", h.rateLimited(h.loginHandler(), loginLimits...)).Methods("POST").Name("UserLogin")
+	u.Handle("/login/2fa", h.rateLimited(h.loginTwoFactorHandler(), loginTwoFactorLimits...)).Methods("POST").Name("UserLoginTwoFactor")
+	u.Handle("/2fa", h.AuthRequired(h.twoFactorEnrollHandler())).Methods("POST").Name("UserTwoFactorEnroll")
+	u.Handle("/2fa/confirm", h.AuthRequired(h.twoFactorConfirmHandler())).Methods("POST").Name("UserTwoFactorConfirm")
+	u.Handle("/2fa/disable", h.AuthRequired(h.twoFactorDisableHandler())).Methods("POST").Name("UserTwoFactorDisable")
+	u.Handle("/2fa/recovery", h.AuthRequired(h.twoFactorRecoveryHandler())).Methods("POST").Name("UserTwoFactorRecovery")
+	u.Handle("/apikeys", h.AuthRequired(h.apiKeysListHandler())).Methods("GET").Name("UserAPIKeys")
+	u.Handle("/apikeys", h.AuthRequired(h.apiKeyCreateHandler())).Methods("POST").Name("UserAPIKeyCreate")
+	u.Handle("/apikeys/{id}", h.AuthRequired(h.apiKeyRevokeHandler())).Methods("DELETE").Name("UserAPIKeyRevoke")
+	u.Handle("/oidc", h.oidcProvidersHandler()).Methods("GET").Name("UserOIDCProviders")
+	u.Handle("/oidc/{provider}/login", h.oidcLoginHandler()).Methods("GET").Name("UserOIDCLogin")
+	u.Handle("/oidc/{provider}/callback", h.rateLimited(h.oidcCallbackHandler(), oidcCallbackLimits...)).Methods("GET").Name("UserOIDCCallback")
+	u.Handle("/oidc/{provider}/link", h.AuthRequired(h.oidcLinkHandler())).Methods("POST").Name("UserOIDCLink")
+	u.Handle("/oidc/{provider}/reauth", h.AuthRequired(h.oidcReauthHandler())).Methods("POST").Name("UserOIDCReauth")
+	u.Handle("/identities", h.AuthRequired(h.identitiesListHandler())).Methods("GET").Name("UserIdentities")
+	u.Handle("/identities/{id}", h.AuthRequired(h.identityUnlinkHandler())).Methods("DELETE").Name("UserIdentityUnlink")
 	u.Handle("/refresh", h.AuthRequired(h.userRefreshTokenHandler())).Methods("POST").Name("UserRefreshToken")
+	u.Handle("/family", h.AuthRequired(h.userSwitchFamilyHandler())).Methods("PUT").Name("UserSwitchFamily")
 	u.Handle("/invite", h.AuthRequired(h.userListInviteHandler())).Methods("GET").Name("UserGetSentInvites")
 	u.Handle("/invite", h.AuthRequired(h.userNewInviteHandler())).Methods("POST").Name("UserNewInvite")
 	u.Handle("/invite/{id}", h.AuthRequired(h.userDeleteInviteHandler())).Methods("DELETE").Name("UserDeleteInvite")
+	u.Handle("/invite/{id}/resend", h.AuthRequired(h.userResendInviteHandler())).Methods("POST").Name("UserResendInvite")
 	u.Handle("/invite/accept/{id}", h.AuthRequired(h.userAcceptInviteHandler())).Methods("POST").Name("UserAcceptInvite")
-	u.Handle("/resetpassword", h.userRequestResetPasswordHandler()).Methods("POST").Name("UserRequestResetPassword")
-	u.Handle("/resetpassword/{code}", h.userResetPasswordHandler()).Methods("POST").Name("UserResetPassword")
+	u.Handle("/invite/decline/{id}", h.AuthRequired(h.userDeclineInviteHandler())).Methods("POST").Name("UserDeclineInvite")
+	u.Handle("/invite/token/{token}", h.userInvitePreviewHandler()).Methods("GET").Name("UserInvitePreview")
+	u.Handle("/signup/invite", h.userInviteSignupHandler()).Methods("POST").Name("UserSignupInvite")
+	u.Handle("/resetpassword", h.rateLimited(h.userRequestResetPasswordHandler(), resetRequestLimits...)).Methods("POST").Name("UserRequestResetPassword")
+	u.Handle("/resetpassword/{code}", h.rateLimited(h.userResetPasswordHandler(), resetLimits...)).Methods("POST").Name("UserResetPassword")
 }
 
 func (h *Handler) userResetPasswordHandler() http.Handler {
@@ -65,7 +137,7 @@ func (h *Handler) userResetPasswordHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		err := h.UserService.ResetPassword(ctx, vars["code"], password)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -79,8 +151,10 @@ func (h *Handler) userRequestResetPasswordHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 
 		err := h.UserService.RequestResetPassword(ctx, email, r.RemoteAddr)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		//an email without an account that can be reset gets the same answer as one with,
+		// otherwise this would tell anyone which emails have accounts
+		if err != nil && !errors.Is(err, goparent.ErrNoUserFound) && !errors.Is(err, goparent.ErrEmailNotVerified) {
+			writeError(w, r, err)
 			return
 		}
 
@@ -94,24 +168,29 @@ func (h *Handler) loginHandler() http.Handler {
 		password := r.FormValue("password")
 		ctx := h.Env.DB.GetContext(r)
 
-		user, err := h.UserService.UserByLogin(ctx, username, password)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		//unknown usernames are locked out the same as real ones so it doesn't show which exist
+		lockoutKey := "login:" + strings.ToLower(strings.TrimSpace(username))
+		if h.lockedOut(w, r, lockoutKey) {
 			return
 		}
-
-		token, err := h.UserService.GetToken(user, time.Minute*5)
+		//users who signed up with an identity provider don't have a password to log in with
+		if password == "" {
+			writeError(w, r, goparent.ErrInvalidLogin)
+			return
+		}
+		user, err := h.UserService.UserByLogin(ctx, username, password)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			if errors.Is(err, goparent.ErrNoUserFound) {
+				err = goparent.ErrInvalidLogin
+			}
+			if errors.Is(err, goparent.ErrInvalidLogin) {
+				h.failedAttempt(ctx, lockoutKey)
+			}
+			writeError(w, r, err)
 			return
 		}
-
-		var userResp UserAuthResponse
-		userResp.UserData = user
-		userResp.Token = token
-		w.Header().Set("Content-Type", jsonContentType)
-		w.Header().Set("x-auth-token", token)
-		json.NewEncoder(w).Encode(userResp)
+		h.clearFailures(ctx, lockoutKey)
+		h.loginOrChallenge(w, r, user)
 	})
 }
 
@@ -120,13 +199,13 @@ func (h *Handler) userRefreshTokenHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		token, err := h.UserService.GetToken(user, time.Hour*24*14)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -144,13 +223,13 @@ func (h *Handler) userGetHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -163,29 +242,72 @@ func (h *Handler) userGetHandler() http.Handler {
 	})
 }
 
+//userSwitchFamilyHandler - make another of the user's families their current family,
+// requests without a family header or path are for that family afterwards
+func (h *Handler) userSwitchFamilyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		familyID := r.FormValue("familyID")
+		if familyID == "" {
+			writeError(w, r, badRequest(errors.New("no familyID submitted")))
+			return
+		}
+		user.CurrentFamily = familyID
+		family, err := h.UserService.GetFamily(ctx, user)
+		if err != nil || !family.IsMember(user.ID) {
+			writeError(w, r, forbidden("not a member of that family"))
+			return
+		}
+
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(UserResponse{UserData: user, FamilyData: family})
+	})
+}
+
 func (h *Handler) userNewHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := h.Env.DB.GetContext(r)
 		decoder := json.NewDecoder(r.Body)
 		var newUserRequest NewUserRequest
 		err := decoder.Decode(&newUserRequest)
-		userData := goparent.User(newUserRequest.UserData)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
 		defer r.Body.Close()
 
+		//new users have to prove they own the email before it's trusted.  the id and family
+		// are left to Save, a client supplied id would replace whoever already has it.
+		userData := goparent.User{
+			Name:               newUserRequest.UserData.Name,
+			Email:              newUserRequest.UserData.Email,
+			Username:           newUserRequest.UserData.Username,
+			Password:           newUserRequest.UserData.Password,
+			Unverified:         true,
+			VerificationSentAt: time.Now(),
+		}
 		w.Header().Set("Content-Type", jsonContentType)
 		err = h.UserService.Save(ctx, &userData)
 		if err != nil {
-			var errMsg ErrService
-			errMsg.ErrMessage.Body = err.Error()
-			errMsg.ErrMessage.Code = http.StatusConflict
-			js, _ := json.Marshal(errMsg)
-			http.Error(w, string(js), http.StatusConflict)
+			writeError(w, r, err)
 			return
 		}
+		err = h.mailVerification(ctx, &userData)
+		if err != nil {
+			log.Printf("error sending mail: %s", err)
+		}
 		json.NewEncoder(w).Encode(userData)
 	})
 }
@@ -195,31 +317,48 @@ func (h *Handler) userNewInviteHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if !verified(w, r, user) {
 			return
 		}
 		err = r.ParseForm()
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
 
 		invitedUserEmail := r.PostFormValue("email")
 		if invitedUserEmail == "" || len(invitedUserEmail) <= 0 {
-			http.Error(w, "no invite email submitted", http.StatusBadRequest)
+			writeError(w, r, badRequest(errors.New("no invite email submitted")))
 			return
 		}
 
-		err = h.UserInvitationService.InviteParent(ctx, user, invitedUserEmail, time.Now())
+		invite := &goparent.UserInvitation{
+			InviteEmail: invitedUserEmail,
+			Role:        r.PostFormValue("role"),
+			Timestamp:   time.Now(),
+		}
+		err = invite.Validate(invite.Timestamp)
 		if err != nil {
-			if err == goparent.ErrExistingInvitation {
-				http.Error(w, err.Error(), http.StatusConflict)
-				return
-			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
+
+		//the invite is to the family the request is for, which can be other than the current one
+		inviter := *user
+		if family, err := FamilyFromContext(ctx); err == nil {
+			inviter.CurrentFamily = family.ID
+		}
+		err = h.UserInvitationService.InviteParent(ctx, &inviter, invite)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
 		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(InviteResponse{InviteData: invite})
 	})
 }
 
@@ -228,22 +367,36 @@ func (h *Handler) userListInviteHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		sentInvites, err := h.UserInvitationService.SentInvites(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		pendingInvites, err := h.UserInvitationService.Invites(ctx, user)
+		invites, err := h.UserInvitationService.Invites(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
+		//sent invites show whether they've expired, only invites the user can still
+		// answer are pending for them, and only once they've shown the email is theirs
+		now := time.Now()
+		for _, invite := range sentInvites {
+			invite.Status = invite.CurrentStatus(now)
+		}
+		pendingInvites := []*goparent.UserInvitation{}
+		for _, invite := range invites {
+			if !user.Unverified && invite.CurrentStatus(now) == goparent.InvitationPending {
+				invite.Status = goparent.InvitationPending
+				pendingInvites = append(pendingInvites, invite)
+			}
+		}
+
 		invitesResponse := InvitesResponse{SentInviteData: sentInvites, PendingInviteData: pendingInvites}
 		w.Header().Set("Content-Type", jsonContentType)
 		json.NewEncoder(w).Encode(invitesResponse)
@@ -255,14 +408,17 @@ func (h *Handler) userAcceptInviteHandler() http.Handler {
 		ctx := h.Env.DB.GetContext(r)
 		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
+		if !verified(w, r, user) {
+			return
+		}
 		id := mux.Vars(r)["id"]
 		err = h.UserInvitationService.Accept(ctx, user, id)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -272,27 +428,453 @@ func (h *Handler) userAcceptInviteHandler() http.Handler {
 	})
 }
 
-func (h *Handler) userDeleteInviteHandler() http.Handler {
+func (h *Handler) userDeclineInviteHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := h.Env.DB.GetContext(r)
-		_, err := UserFromContext(ctx)
+		user, err := UserFromContext(ctx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
-		id := mux.Vars(r)["id"]
-		invite, err := h.UserInvitationService.Invite(ctx, id)
+
+		err = h.UserInvitationService.Decline(ctx, user, mux.Vars(r)["id"])
 		if err != nil {
-			http.Error(w, "not found", http.StatusNotFound)
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//sentInvite - the invite with the id in the path, if the user sent it
+func (h *Handler) sentInvite(w http.ResponseWriter, r *http.Request, user *goparent.User) (*goparent.UserInvitation, bool) {
+	ctx := h.Env.DB.GetContext(r)
+	invite, err := h.UserInvitationService.Invite(ctx, mux.Vars(r)["id"])
+	if err == nil && invite.UserID != user.ID {
+		err = goparent.ErrNoInvitationFound
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return invite, true
+}
+
+func (h *Handler) userResendInviteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if !verified(w, r, user) {
+			return
+		}
+		invite, ok := h.sentInvite(w, r, user)
+		if !ok {
+			return
+		}
+
+		err = h.UserInvitationService.Resend(ctx, user, invite)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(InviteResponse{InviteData: invite})
+	})
+}
+
+func (h *Handler) userInvitePreviewHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		invite, err := h.UserInvitationService.InviteByToken(ctx, mux.Vars(r)["token"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		preview := InvitePreviewResponse{
+			Email:     invite.InviteEmail,
+			Role:      invite.Role,
+			Status:    invite.CurrentStatus(time.Now()),
+			ExpiresAt: invite.ExpiresAt,
+		}
+		from, err := h.UserService.User(ctx, invite.UserID)
+		if err == nil {
+			preview.From = from.Name
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(preview)
+	})
+}
+
+//userInviteSignupHandler - create an account for the invited email and accept the invite
+// with it, so the new user starts out in the inviting family
+func (h *Handler) userInviteSignupHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		var signup InviteSignupRequest
+		err := json.NewDecoder(r.Body).Decode(&signup)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		defer r.Body.Close()
+		if signup.Token == "" || signup.Username == "" || signup.Password == "" {
+			writeError(w, r, badRequest(errors.New("token, username and password are required")))
+			return
+		}
+
+		invite, err := h.UserInvitationService.InviteByToken(ctx, signup.Token)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		switch invite.CurrentStatus(time.Now()) {
+		case goparent.InvitationPending:
+		case goparent.InvitationExpired:
+			writeError(w, r, goparent.ErrInvitationExpired)
+			return
+		default:
+			writeError(w, r, goparent.ErrInvitationAnswered)
+			return
+		}
+
+		user := &goparent.User{
+			Name:     signup.Name,
+			Email:    invite.InviteEmail,
+			Username: signup.Username,
+			Password: signup.Password,
+		}
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.UserInvitationService.Accept(ctx, user, invite.ID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		token, err := h.UserService.GetToken(user, time.Hour*24*14)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Header().Set("x-auth-token", token)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(UserAuthResponse{UserData: user, Token: token})
+	})
+}
+
+func (h *Handler) userDeleteInviteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		invite, ok := h.sentInvite(w, r, user)
+		if !ok {
 			return
 		}
 		err = h.UserInvitationService.Delete(ctx, invite)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//userUpdateHandler - update the user's name and username.  the email can only be changed
+// through a confirmed email change.
+func (h *Handler) userUpdateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		var userRequest UserRequest
+		err = json.NewDecoder(r.Body).Decode(&userRequest)
+		if err != nil || userRequest.UserData == nil {
+			writeError(w, r, badRequest(errors.New("no user data submitted")))
+			return
+		}
+		defer r.Body.Close()
+		if userRequest.UserData.Email != "" && userRequest.UserData.Email != user.Email {
+			writeError(w, r, goparent.NewValidationError("email", "can only be changed with POST /api/user/email"))
+			return
+		}
+
+		updated := *user
+		updated.Name = userRequest.UserData.Name
+		updated.Username = userRequest.UserData.Username
+		err = updated.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		user = &updated
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		family, err := h.requestFamily(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(UserResponse{UserData: user, FamilyData: family})
+	})
+}
+
+//userChangeEmailHandler - start an email change.  the user has to give their password,
+// and the change only happens once the token mailed to the new address is confirmed.
+func (h *Handler) userChangeEmailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if !h.reauthenticate(w, r, user) {
+			return
+		}
+
+		email := r.FormValue("email")
+		err = goparent.User{Name: user.Name, Email: email}.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if email == user.Email {
+			writeError(w, r, goparent.NewValidationError("email", "is already the account's email"))
+			return
+		}
+
+		expires := time.Now().Add(emailChangeWindow)
+		token, err := h.emailChangeToken(user, email, expires)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.Env.MailEmailChange(ctx, user, email, token, expires)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+//userConfirmEmailHandler - finish an email change with the token mailed to the new address,
+// the old address is told about the change
+func (h *Handler) userConfirmEmailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		claims, ok := h.validEmailChangeToken(r.FormValue("token"))
+		if !ok {
+			writeError(w, r, errInvalidEmailToken)
+			return
+		}
+		user, err := h.UserService.User(ctx, claims["user"].(string))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		//a token for an address the user has already moved away from is spent
+		oldEmail := user.Email
+		if oldEmail != claims["from"] {
+			writeError(w, r, errInvalidEmailToken)
 			return
 		}
 
+		err = h.UserService.ChangeEmail(ctx, user, claims["email"].(string))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.Env.MailEmailChanged(ctx, user, oldEmail)
+		if err != nil {
+			log.Printf("error sending mail: %s", err)
+		}
 		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(UserResponse{UserData: user})
+	})
+}
+
+//userDeleteHandler - delete the user's account, they have to give their password
+func (h *Handler) userDeleteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if !h.reauthenticate(w, r, user) {
+			return
+		}
+
+		err = h.UserService.Delete(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
 		w.WriteHeader(http.StatusNoContent)
 	})
 }
+
+//reauthenticate - check the password submitted with a request that changes the account,
+// writing the error if it's wrong.  users without a password send a reauth token from
+// logging in with their identity provider again instead.
+func (h *Handler) reauthenticate(w http.ResponseWriter, r *http.Request, user *goparent.User) bool {
+	ctx := h.Env.DB.GetContext(r)
+	if token := r.FormValue("reauthToken"); token != "" {
+		claims, ok := h.purposeClaims(token, "reauth")
+		if !ok || claims["user"] != user.ID {
+			writeError(w, r, errReauthFailed)
+			return false
+		}
+		return true
+	}
+	//users who signed up with an identity provider don't have a password to match
+	password := r.FormValue("password")
+	if password == "" {
+		if user.Password == "" {
+			writeError(w, r, errReauthRequired)
+		} else {
+			writeError(w, r, goparent.ErrInvalidLogin)
+		}
+		return false
+	}
+	login, err := h.UserService.UserByLogin(ctx, user.Email, password)
+	if err != nil || login == nil || login.ID != user.ID {
+		writeError(w, r, goparent.ErrInvalidLogin)
+		return false
+	}
+	return true
+}
+
+//emailChangeToken - a token that moves the user from their current email to the new one
+func (h *Handler) emailChangeToken(user *goparent.User, email string, expires time.Time) (string, error) {
+	return h.purposeToken("email.change", jwt.MapClaims{"user": user.ID, "from": user.Email, "email": email}, expires)
+}
+
+//validEmailChangeToken - the claims of an email change token, if it's one of ours and
+// hasn't expired
+func (h *Handler) validEmailChangeToken(tokenString string) (jwt.MapClaims, bool) {
+	claims, ok := h.purposeClaims(tokenString, "email.change")
+	if !ok {
+		return nil, false
+	}
+	for _, claim := range []string{"user", "from", "email"} {
+		if _, ok := claims[claim].(string); !ok {
+			return nil, false
+		}
+	}
+	return claims, true
+}
+
+//userVerifyEmailHandler - verify a new user's email with the token mailed to it
+func (h *Handler) userVerifyEmailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		claims, ok := h.purposeClaims(r.FormValue("token"), "email.verify")
+		userID, _ := claims["user"].(string)
+		if !ok || userID == "" {
+			writeError(w, r, errInvalidVerifyToken)
+			return
+		}
+		user, err := h.UserService.User(ctx, userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		//a token for an address the user has since moved away from doesn't verify the new one
+		if claims["email"] != user.Email {
+			writeError(w, r, errInvalidVerifyToken)
+			return
+		}
+
+		if user.Unverified {
+			user.Unverified = false
+			err = h.UserService.Save(ctx, user)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(UserResponse{UserData: user})
+	})
+}
+
+//userResendVerificationHandler - mail the user another verification token, at most once
+// every verificationResendInterval
+func (h *Handler) userResendVerificationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := h.Env.DB.GetContext(r)
+		user, err := UserFromContext(ctx)
+		if err != nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+		if !user.Unverified {
+			writeError(w, r, errAlreadyVerified)
+			return
+		}
+		wait := user.VerificationSentAt.Add(verificationResendInterval).Sub(time.Now())
+		if wait > 0 {
+			writeError(w, r, tooManyRequests(wait))
+			return
+		}
+
+		user.VerificationSentAt = time.Now()
+		err = h.UserService.Save(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		err = h.mailVerification(ctx, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+//mailVerification - mail the user a token that verifies their current email
+func (h *Handler) mailVerification(ctx context.Context, user *goparent.User) error {
+	expires := time.Now().Add(verificationWindow)
+	token, err := h.purposeToken("email.verify", jwt.MapClaims{"user": user.ID, "email": user.Email}, expires)
+	if err != nil {
+		return err
+	}
+	return h.Env.MailVerification(ctx, user, token, expires)
+}
+
+//verified - check the user has verified their email, writing the error if they haven't
+func verified(w http.ResponseWriter, r *http.Request, user *goparent.User) bool {
+	if user.Unverified {
+		writeError(w, r, goparent.ErrEmailNotVerified)
+		return false
+	}
+	return true
+}
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 	"github.com/sasimpson/goparent"
 	"github.com/sasimpson/goparent/mock"
@@ -55,7 +56,7 @@ func TestLoginHandler(t *testing.T) {
 					Email:    "testuser@test.com",
 					Username: "testuser",
 				},
-				AuthErr: errors.New("invalid login"),
+				AuthErr: goparent.ErrInvalidLogin,
 			},
 			responseCode: http.StatusUnauthorized,
 		},
@@ -75,6 +76,16 @@ func TestLoginHandler(t *testing.T) {
 			},
 			responseCode: http.StatusInternalServerError,
 		},
+		{
+			desc:  "no password",
+			env:   &goparent.Env{DB: &mock.DBEnv{}},
+			email: "testuser@test.com",
+			userService: &mock.UserService{
+				ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com"},
+				Token:        "this-is-a-token",
+			},
+			responseCode: http.StatusUnauthorized,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -194,13 +205,13 @@ func TestUserNewHandler(t *testing.T) {
 			desc:         "invalid json",
 			env:          &goparent.Env{DB: &mock.DBEnv{}},
 			userService:  &mock.UserService{},
-			responseCode: http.StatusInternalServerError,
+			responseCode: http.StatusBadRequest,
 		},
 		{
 			desc: "invalid user save",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
 			userService: &mock.UserService{
-				SaveErr: errors.New("user exists"),
+				SaveErr: goparent.ErrExistingUser,
 			},
 			userRequest: &UserRequest{
 				UserData: &goparent.User{
@@ -256,19 +267,44 @@ func TestUserNewHandler(t *testing.T) {
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var user goparent.User
+				err := json.NewDecoder(rr.Body).Decode(&user)
+				assert.Nil(t, err)
+				assert.True(t, user.Unverified)
+			}
 		})
 	}
 }
 
+func TestUserNewHandlerIgnoresID(t *testing.T) {
+	users := &mock.UserService{}
+	mockHandler := Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}, UserService: users}
+
+	js := `{"userData":{"id":"someone-else","currentFamily":"their-family","name":"test user","email":"testuser@test.com","username":"testuser","password":"testpassword"}}`
+	req, _ := http.NewRequest("POST", "/user", strings.NewReader(js))
+	rr := httptest.NewRecorder()
+	mockHandler.userNewHandler().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if assert.Len(t, users.Saved, 1) {
+		assert.Empty(t, users.Saved[0].ID, "the user can't pick an id that replaces someone else")
+		assert.Empty(t, users.Saved[0].CurrentFamily, "or join a family by naming it")
+		assert.Equal(t, "testuser@test.com", users.Saved[0].Email)
+	}
+}
+
 func TestUserNewInviteHandler(t *testing.T) {
 	testCases := []struct {
 		desc              string
 		env               *goparent.Env
 		userInviteService goparent.UserInvitationService
 		inviteUser        string
+		inviteRole        string
 		contextUser       *goparent.User
+		family            *goparent.Family
 		formErr           bool
 		responseCode      int
+		familyID          string
 	}{
 		{
 			desc:         "invite fails auth",
@@ -280,7 +316,7 @@ func TestUserNewInviteHandler(t *testing.T) {
 			env:          &goparent.Env{DB: &mock.DBEnv{}},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			formErr:      true,
-			responseCode: http.StatusInternalServerError,
+			responseCode: http.StatusBadRequest,
 		},
 		{
 			desc:         "invite no email",
@@ -288,6 +324,28 @@ func TestUserNewInviteHandler(t *testing.T) {
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			responseCode: http.StatusBadRequest,
 		},
+		{
+			desc:         "invite from unverified user",
+			env:          &goparent.Env{DB: &mock.DBEnv{}},
+			inviteUser:   "invited@test.com",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser", Unverified: true},
+			responseCode: http.StatusForbidden,
+		},
+		{
+			desc:         "invite bad email",
+			env:          &goparent.Env{DB: &mock.DBEnv{}},
+			inviteUser:   "not an email",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "invite bad role",
+			env:          &goparent.Env{DB: &mock.DBEnv{}},
+			inviteUser:   "invitedUser@test.com",
+			inviteRole:   "admin",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode: http.StatusBadRequest,
+		},
 		{
 			desc: "existing invite",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
@@ -313,19 +371,33 @@ func TestUserNewInviteHandler(t *testing.T) {
 			env:               &goparent.Env{DB: &mock.DBEnv{}},
 			userInviteService: &mock.UserInvitationService{},
 			inviteUser:        "invitedUser@test.com",
-			contextUser:       &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			inviteRole:        "caregiver",
+			contextUser:       &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser", CurrentFamily: "1"},
+			responseCode:      http.StatusCreated,
+			familyID:          "1",
+		},
+		{
+			desc:              "invite to the family the request is for",
+			env:               &goparent.Env{DB: &mock.DBEnv{}},
+			userInviteService: &mock.UserInvitationService{},
+			inviteUser:        "invitedUser@test.com",
+			inviteRole:        "caregiver",
+			contextUser:       &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser", CurrentFamily: "1"},
+			family:            &goparent.Family{ID: "2", Admin: "1", Members: []string{"1"}},
 			responseCode:      http.StatusCreated,
+			familyID:          "2",
 		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			mockHandler := Handler{
-				Env: tC.env,
+				Env:                   tC.env,
 				UserInvitationService: tC.userInviteService,
 			}
 
 			form := url.Values{}
 			form.Add("email", tC.inviteUser)
+			form.Add("role", tC.inviteRole)
 			var req *http.Request
 			if tC.formErr != true {
 				req, _ = http.NewRequest("POST", "/user/invite", strings.NewReader(form.Encode()))
@@ -343,11 +415,24 @@ func TestUserNewInviteHandler(t *testing.T) {
 			} else {
 				ctx = context.WithValue(ctx, userContextKey, "")
 			}
+			if tC.family != nil {
+				ctx = context.WithValue(ctx, familyContextKey, tC.family)
+			}
 			req = req.WithContext(ctx)
 
 			handler.ServeHTTP(rr, req)
 			assert.Equal(t, tC.responseCode, rr.Code)
-
+			if rr.Code == http.StatusCreated {
+				var resp InviteResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.Equal(t, tC.familyID, resp.InviteData.FamilyID)
+				assert.Equal(t, "1", tC.contextUser.CurrentFamily, "the user's current family isn't changed")
+				assert.Equal(t, "invitedUser@test.com", resp.InviteData.InviteEmail)
+				assert.Equal(t, tC.inviteRole, resp.InviteData.Role)
+				assert.Equal(t, goparent.InvitationPending, resp.InviteData.Status)
+				assert.NotContains(t, rr.Body.String(), "tokenHash")
+			}
 		})
 	}
 }
@@ -471,7 +556,7 @@ func TestAcceptInviteHandler(t *testing.T) {
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			mockHandler := Handler{
-				Env: tC.env,
+				Env:                   tC.env,
 				UserInvitationService: tC.userInvitationService,
 			}
 
@@ -510,7 +595,21 @@ func TestDeleteInviteHandler(t *testing.T) {
 			env:         &goparent.Env{DB: &mock.DBEnv{}},
 			contextUser: &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			userInvitationService: &mock.UserInvitationService{
-				InviteErr: errors.New("not found"),
+				InviteErr: goparent.ErrNoInvitationFound,
+			},
+			responseCode: http.StatusNotFound,
+		},
+		{
+			desc:        "invite sent by someone else",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
+			contextUser: &goparent.User{ID: "2", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			userInvitationService: &mock.UserInvitationService{
+				GetInvite: &goparent.UserInvitation{
+					ID:          "1",
+					UserID:      "1",
+					InviteEmail: "testuser@test.com",
+					Timestamp:   time.Now(),
+				},
 			},
 			responseCode: http.StatusNotFound,
 		},
@@ -547,7 +646,7 @@ func TestDeleteInviteHandler(t *testing.T) {
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			mockHandler := Handler{
-				Env: tC.env,
+				Env:                   tC.env,
 				UserInvitationService: tC.userInvitationService,
 			}
 
@@ -568,24 +667,1026 @@ func TestDeleteInviteHandler(t *testing.T) {
 	}
 }
 
-func TestInitUsersHandlers(t *testing.T) {
-	//TODO: update with new handler routes
+func TestDeclineInviteHandler(t *testing.T) {
 	testCases := []struct {
-		desc    string
-		name    string
-		path    string
-		methods []string
+		desc                  string
+		contextUser           *goparent.User
+		userInvitationService goparent.UserInvitationService
+		responseCode          int
 	}{
 		{
-			desc:    "user new",
-			name:    "UserNew",
-			path:    "/user/",
-			methods: []string{"POST"},
+			desc:         "bad auth",
+			responseCode: http.StatusUnauthorized,
 		},
 		{
-			desc:    "user login",
-			name:    "UserLogin",
-			path:    "/user/login",
+			desc:        "decline answered invite",
+			contextUser: &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			userInvitationService: &mock.UserInvitationService{
+				DeclineErr: goparent.ErrInvitationAnswered,
+			},
+			responseCode: http.StatusConflict,
+		},
+		{
+			desc:        "decline expired invite",
+			contextUser: &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			userInvitationService: &mock.UserInvitationService{
+				DeclineErr: goparent.ErrInvitationExpired,
+			},
+			responseCode: http.StatusGone,
+		},
+		{
+			desc:                  "decline success",
+			contextUser:           &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			userInvitationService: &mock.UserInvitationService{},
+			responseCode:          http.StatusNoContent,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:                   &goparent.Env{DB: &mock.DBEnv{}},
+				UserInvitationService: tC.userInvitationService,
+			}
+
+			handler := mockHandler.userDeclineInviteHandler()
+			req, _ := http.NewRequest("POST", "/user/invite/decline/1", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+		})
+	}
+}
+
+func TestResendInviteHandler(t *testing.T) {
+	sent := &goparent.UserInvitation{ID: "1", UserID: "1", InviteEmail: "invited@test.com", Timestamp: time.Now()}
+	testCases := []struct {
+		desc                  string
+		contextUser           *goparent.User
+		userInvitationService goparent.UserInvitationService
+		responseCode          int
+	}{
+		{
+			desc:         "bad auth",
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:                  "invite not found",
+			contextUser:           &goparent.User{ID: "1"},
+			userInvitationService: &mock.UserInvitationService{},
+			responseCode:          http.StatusNotFound,
+		},
+		{
+			desc:                  "invite sent by someone else",
+			contextUser:           &goparent.User{ID: "2"},
+			userInvitationService: &mock.UserInvitationService{GetInvite: sent},
+			responseCode:          http.StatusNotFound,
+		},
+		{
+			desc:        "invite already answered",
+			contextUser: &goparent.User{ID: "1"},
+			userInvitationService: &mock.UserInvitationService{
+				GetInvite: sent,
+				ResendErr: goparent.ErrInvitationAnswered,
+			},
+			responseCode: http.StatusConflict,
+		},
+		{
+			desc:                  "resend success",
+			contextUser:           &goparent.User{ID: "1"},
+			userInvitationService: &mock.UserInvitationService{GetInvite: sent},
+			responseCode:          http.StatusOK,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:                   &goparent.Env{DB: &mock.DBEnv{}},
+				UserInvitationService: tC.userInvitationService,
+			}
+
+			handler := mockHandler.userResendInviteHandler()
+			req, _ := http.NewRequest("POST", "/user/invite/1/resend", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+		})
+	}
+}
+
+func TestInvitePreviewHandler(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		desc                  string
+		userInvitationService goparent.UserInvitationService
+		responseCode          int
+		status                goparent.InvitationStatus
+	}{
+		{
+			desc:                  "unknown token",
+			userInvitationService: &mock.UserInvitationService{},
+			responseCode:          http.StatusNotFound,
+		},
+		{
+			desc: "pending invite",
+			userInvitationService: &mock.UserInvitationService{
+				GetInvite: &goparent.UserInvitation{ID: "1", UserID: "1", InviteEmail: "invited@test.com", Role: goparent.RoleCaregiver, Status: goparent.InvitationPending, Timestamp: now, ExpiresAt: now.Add(time.Hour)},
+			},
+			responseCode: http.StatusOK,
+			status:       goparent.InvitationPending,
+		},
+		{
+			desc: "expired invite",
+			userInvitationService: &mock.UserInvitationService{
+				GetInvite: &goparent.UserInvitation{ID: "1", UserID: "1", InviteEmail: "invited@test.com", Status: goparent.InvitationPending, Timestamp: now, ExpiresAt: now.Add(-time.Hour)},
+			},
+			responseCode: http.StatusOK,
+			status:       goparent.InvitationExpired,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:                   &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:           &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Name: "Pat"}},
+				UserInvitationService: tC.userInvitationService,
+			}
+
+			handler := mockHandler.userInvitePreviewHandler()
+			req, _ := http.NewRequest("GET", "/user/invite/token/abc", nil)
+			req = mux.SetURLVars(req, map[string]string{"token": "abc"})
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var resp InvitePreviewResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.Equal(t, "Pat", resp.From)
+				assert.Equal(t, "invited@test.com", resp.Email)
+				assert.Equal(t, tC.status, resp.Status)
+			}
+		})
+	}
+}
+
+func TestInviteSignupHandler(t *testing.T) {
+	now := time.Now()
+	pending := &goparent.UserInvitation{ID: "1", UserID: "1", InviteEmail: "invited@test.com", Status: goparent.InvitationPending, Timestamp: now, ExpiresAt: now.Add(time.Hour)}
+	testCases := []struct {
+		desc                  string
+		body                  string
+		userService           *mock.UserService
+		userInvitationService goparent.UserInvitationService
+		responseCode          int
+	}{
+		{
+			desc:                  "bad body",
+			body:                  "{",
+			userService:           &mock.UserService{},
+			userInvitationService: &mock.UserInvitationService{},
+			responseCode:          http.StatusBadRequest,
+		},
+		{
+			desc:                  "missing password",
+			body:                  `{"token":"abc","username":"sam"}`,
+			userService:           &mock.UserService{},
+			userInvitationService: &mock.UserInvitationService{},
+			responseCode:          http.StatusBadRequest,
+		},
+		{
+			desc:                  "unknown token",
+			body:                  `{"token":"abc","username":"sam","password":"pw"}`,
+			userService:           &mock.UserService{},
+			userInvitationService: &mock.UserInvitationService{},
+			responseCode:          http.StatusNotFound,
+		},
+		{
+			desc:        "expired invite",
+			body:        `{"token":"abc","username":"sam","password":"pw"}`,
+			userService: &mock.UserService{},
+			userInvitationService: &mock.UserInvitationService{
+				GetInvite: &goparent.UserInvitation{ID: "1", InviteEmail: "invited@test.com", Status: goparent.InvitationPending, Timestamp: now, ExpiresAt: now.Add(-time.Hour)},
+			},
+			responseCode: http.StatusGone,
+		},
+		{
+			desc:        "answered invite",
+			body:        `{"token":"abc","username":"sam","password":"pw"}`,
+			userService: &mock.UserService{},
+			userInvitationService: &mock.UserInvitationService{
+				GetInvite: &goparent.UserInvitation{ID: "1", InviteEmail: "invited@test.com", Status: goparent.InvitationDeclined, Timestamp: now, ExpiresAt: now.Add(time.Hour)},
+			},
+			responseCode: http.StatusConflict,
+		},
+		{
+			desc:                  "existing user",
+			body:                  `{"token":"abc","username":"sam","password":"pw"}`,
+			userService:           &mock.UserService{SaveErr: goparent.ErrExistingUser},
+			userInvitationService: &mock.UserInvitationService{GetInvite: pending},
+			responseCode:          http.StatusConflict,
+		},
+		{
+			desc:                  "accept error",
+			body:                  `{"token":"abc","username":"sam","password":"pw"}`,
+			userService:           &mock.UserService{UserID: "2"},
+			userInvitationService: &mock.UserInvitationService{GetInvite: pending, AcceptErr: errors.New("test error")},
+			responseCode:          http.StatusInternalServerError,
+		},
+		{
+			desc:                  "signup success",
+			body:                  `{"token":"abc","name":"Sam","username":"sam","password":"pw"}`,
+			userService:           &mock.UserService{UserID: "2", Token: "token"},
+			userInvitationService: &mock.UserInvitationService{GetInvite: pending},
+			responseCode:          http.StatusCreated,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:                   &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:           tC.userService,
+				UserInvitationService: tC.userInvitationService,
+			}
+
+			handler := mockHandler.userInviteSignupHandler()
+			req, _ := http.NewRequest("POST", "/user/signup/invite", strings.NewReader(tC.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusCreated {
+				var resp UserAuthResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.Equal(t, "token", resp.Token)
+				assert.Equal(t, "2", resp.UserData.ID)
+				assert.Equal(t, "invited@test.com", resp.UserData.Email)
+			}
+		})
+	}
+}
+
+func TestUserSwitchFamilyHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		contextUser  *goparent.User
+		familyID     string
+		userService  *mock.UserService
+		responseCode int
+	}{
+		{
+			desc:         "bad auth",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "no family",
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "1"},
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "unknown family",
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "1"},
+			familyID:     "2",
+			userService:  &mock.UserService{FamilyErr: errors.New("not found")},
+			responseCode: http.StatusForbidden,
+		},
+		{
+			desc:         "not a member",
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "1"},
+			familyID:     "2",
+			userService:  &mock.UserService{Family: &goparent.Family{ID: "2", Admin: "2", Members: []string{"2"}}},
+			responseCode: http.StatusForbidden,
+		},
+		{
+			desc:         "save error",
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "1"},
+			familyID:     "2",
+			userService:  &mock.UserService{Family: &goparent.Family{ID: "2", Admin: "2", Members: []string{"2", "1"}}, SaveErr: errors.New("test error")},
+			responseCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "switched",
+			contextUser:  &goparent.User{ID: "1", CurrentFamily: "1"},
+			familyID:     "2",
+			userService:  &mock.UserService{Family: &goparent.Family{ID: "2", Admin: "2", Members: []string{"2", "1"}}},
+			responseCode: http.StatusOK,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}},
+				UserService: tC.userService,
+			}
+
+			form := url.Values{"familyID": {tC.familyID}}
+			req, _ := http.NewRequest("PUT", "/user/family", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+			mockHandler.userSwitchFamilyHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var resp UserResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.Equal(t, "2", resp.UserData.CurrentFamily)
+				assert.Equal(t, "2", resp.FamilyData.ID)
+				if assert.Len(t, tC.userService.Saved, 1) {
+					assert.Equal(t, "2", tC.userService.Saved[0].CurrentFamily)
+				}
+			}
+		})
+	}
+}
+
+func TestUserUpdateHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		contextUser  *goparent.User
+		body         string
+		userService  *mock.UserService
+		responseCode int
+	}{
+		{
+			desc:         "bad auth",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "no user data",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			body:         `{}`,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "email change",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			body:         `{"userData": {"name": "new name", "email": "other@test.com"}}`,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "no name",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			body:         `{"userData": {"name": "", "username": "tester"}}`,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "save error",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			body:         `{"userData": {"name": "new name", "username": "tester"}}`,
+			userService:  &mock.UserService{SaveErr: errors.New("test error")},
+			responseCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "updated",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", CurrentFamily: "1"},
+			body:         `{"userData": {"name": "new name", "username": "tester", "email": "testuser@test.com"}}`,
+			userService:  &mock.UserService{Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}}},
+			responseCode: http.StatusOK,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}},
+				UserService: tC.userService,
+			}
+
+			req, _ := http.NewRequest("PUT", "/user/", strings.NewReader(tC.body))
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+			mockHandler.userUpdateHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var resp UserResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.Equal(t, "new name", resp.UserData.Name)
+				assert.Equal(t, "tester", resp.UserData.Username)
+				assert.Equal(t, "testuser@test.com", resp.UserData.Email)
+				assert.Len(t, tC.userService.Saved, 1)
+			}
+		})
+	}
+}
+
+func TestUserChangeEmailHandler(t *testing.T) {
+	h := Handler{Env: &goparent.Env{Auth: goparent.Authentication{SigningKey: []byte("test")}}}
+	reauth, _ := h.purposeToken("reauth", jwt.MapClaims{"user": "1"}, time.Now().Add(time.Minute))
+	otherReauth, _ := h.purposeToken("reauth", jwt.MapClaims{"user": "2"}, time.Now().Add(time.Minute))
+	expiredReauth, _ := h.purposeToken("reauth", jwt.MapClaims{"user": "1"}, time.Now().Add(-time.Minute))
+
+	testCases := []struct {
+		desc         string
+		contextUser  *goparent.User
+		form         url.Values
+		userService  *mock.UserService
+		responseCode int
+		errorCode    string
+		mailed       int
+	}{
+		{
+			desc:         "bad auth",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "wrong password",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"new@test.com"}, "password": {"wrong"}},
+			userService:  &mock.UserService{AuthErr: goparent.ErrInvalidLogin},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "no password",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Password: "testing"},
+			form:         url.Values{"email": {"new@test.com"}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "user without a password",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"new@test.com"}, "password": {""}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusUnauthorized,
+			errorCode:    CodeReauthRequired,
+		},
+		{
+			desc:         "reauth token",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"new@test.com"}, "reauthToken": {reauth}},
+			userService:  &mock.UserService{AuthErr: goparent.ErrInvalidLogin},
+			responseCode: http.StatusAccepted,
+			mailed:       1,
+		},
+		{
+			desc:         "someone else's reauth token",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"new@test.com"}, "reauthToken": {otherReauth}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusUnauthorized,
+			errorCode:    CodeReauthRequired,
+		},
+		{
+			desc:         "expired reauth token",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"new@test.com"}, "reauthToken": {expiredReauth}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusUnauthorized,
+			errorCode:    CodeReauthRequired,
+		},
+		{
+			desc:         "invalid email",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"not an email"}, "password": {"testing"}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "same email",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"testuser@test.com"}, "password": {"testing"}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "mails the new address",
+			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"},
+			form:         url.Values{"email": {"new@test.com"}, "password": {"testing"}},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusAccepted,
+			mailed:       1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mailer := &mock.Mailer{}
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}, Mailer: mailer, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+				UserService: tC.userService,
+			}
+
+			req, _ := http.NewRequest("POST", "/user/email", strings.NewReader(tC.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+			mockHandler.userChangeEmailHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				json.NewDecoder(rr.Body).Decode(&errBody)
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			}
+			if assert.Len(t, mailer.Sent, tC.mailed) && tC.mailed > 0 {
+				assert.Equal(t, []string{"new@test.com"}, mailer.Sent[0].To)
+				assert.Equal(t, "testuser@test.com", tC.contextUser.Email)
+			}
+		})
+	}
+}
+
+func TestUserConfirmEmailHandler(t *testing.T) {
+	h := Handler{Env: &goparent.Env{Auth: goparent.Authentication{SigningKey: []byte("test")}}}
+	user := &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com"}
+	token, _ := h.emailChangeToken(user, "new@test.com", time.Now().Add(time.Hour))
+	expired, _ := h.emailChangeToken(user, "new@test.com", time.Now().Add(-time.Hour))
+	deleteToken, _ := h.familyDeleteToken(&goparent.Family{ID: "1"}, user, time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		desc         string
+		token        string
+		userService  *mock.UserService
+		responseCode int
+		mailed       int
+	}{
+		{
+			desc:         "no token",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "expired",
+			token:        expired,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "token for something else",
+			token:        deleteToken,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "email already changed",
+			token:        token,
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Email: "other@test.com"}},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "email taken",
+			token:        token,
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com"}, EmailErr: goparent.ErrExistingUser},
+			responseCode: http.StatusConflict,
+		},
+		{
+			desc:         "changed",
+			token:        token,
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com"}},
+			responseCode: http.StatusOK,
+			mailed:       1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mailer := &mock.Mailer{}
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}, Mailer: mailer, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+				UserService: tC.userService,
+			}
+
+			form := url.Values{"token": {tC.token}}
+			req, _ := http.NewRequest("POST", "/user/email/confirm", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			mockHandler.userConfirmEmailHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var resp UserResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.Equal(t, "new@test.com", resp.UserData.Email)
+			}
+			if assert.Len(t, mailer.Sent, tC.mailed) && tC.mailed > 0 {
+				assert.Equal(t, []string{"testuser@test.com"}, mailer.Sent[0].To)
+			}
+		})
+	}
+}
+
+func TestUserDeleteHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		contextUser  *goparent.User
+		userService  *mock.UserService
+		responseCode int
+		deleted      int
+	}{
+		{
+			desc:         "bad auth",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "wrong password",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com"},
+			userService:  &mock.UserService{AuthErr: goparent.ErrInvalidLogin},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "someone else's password",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com"},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "2"}},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "delete error",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com"},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}, DeleteErr: errors.New("test error")},
+			responseCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "deleted",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com"},
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1"}},
+			responseCode: http.StatusNoContent,
+			deleted:      1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}},
+				UserService: tC.userService,
+			}
+
+			form := url.Values{"password": {"testing"}}
+			req, _ := http.NewRequest("POST", "/user/delete", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+			mockHandler.userDeleteHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			assert.Len(t, tC.userService.Deleted, tC.deleted)
+		})
+	}
+}
+
+func TestUserVerifyEmailHandler(t *testing.T) {
+	h := Handler{Env: &goparent.Env{Auth: goparent.Authentication{SigningKey: []byte("test")}}}
+	token, _ := h.purposeToken("email.verify", jwt.MapClaims{"user": "1", "email": "testuser@test.com"}, time.Now().Add(time.Hour))
+	expired, _ := h.purposeToken("email.verify", jwt.MapClaims{"user": "1", "email": "testuser@test.com"}, time.Now().Add(-time.Hour))
+	changeToken, _ := h.emailChangeToken(&goparent.User{ID: "1", Email: "old@test.com"}, "testuser@test.com", time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		desc         string
+		token        string
+		userService  *mock.UserService
+		responseCode int
+		saved        int
+	}{
+		{
+			desc:         "no token",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "expired",
+			token:        expired,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "token for something else",
+			token:        changeToken,
+			userService:  &mock.UserService{},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "email changed since",
+			token:        token,
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Email: "other@test.com", Unverified: true}},
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "verified",
+			token:        token,
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com", Unverified: true}},
+			responseCode: http.StatusOK,
+			saved:        1,
+		},
+		{
+			desc:         "already verified",
+			token:        token,
+			userService:  &mock.UserService{ReturnedUser: &goparent.User{ID: "1", Email: "testuser@test.com"}},
+			responseCode: http.StatusOK,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+				UserService: tC.userService,
+			}
+
+			form := url.Values{"token": {tC.token}}
+			req, _ := http.NewRequest("POST", "/user/verify", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			mockHandler.userVerifyEmailHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var resp UserResponse
+				err := json.NewDecoder(rr.Body).Decode(&resp)
+				assert.Nil(t, err)
+				assert.False(t, resp.UserData.Unverified)
+			}
+			assert.Len(t, tC.userService.Saved, tC.saved)
+		})
+	}
+}
+
+func TestUserResendVerificationHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		contextUser  *goparent.User
+		userService  *mock.UserService
+		responseCode int
+		retryAfter   string
+		mailed       int
+	}{
+		{
+			desc:         "bad auth",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "already verified",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com"},
+			userService:  &mock.UserService{},
+			responseCode: http.StatusConflict,
+		},
+		{
+			desc:         "sent too recently",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com", Unverified: true, VerificationSentAt: time.Now().Add(-time.Minute)},
+			userService:  &mock.UserService{},
+			responseCode: http.StatusTooManyRequests,
+			retryAfter:   "240",
+		},
+		{
+			desc:         "save error",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com", Unverified: true},
+			userService:  &mock.UserService{SaveErr: errors.New("test error")},
+			responseCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "resent",
+			contextUser:  &goparent.User{ID: "1", Email: "testuser@test.com", Unverified: true, VerificationSentAt: time.Now().Add(-time.Hour)},
+			userService:  &mock.UserService{},
+			responseCode: http.StatusAccepted,
+			mailed:       1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mailer := &mock.Mailer{}
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}, Mailer: mailer, Auth: goparent.Authentication{SigningKey: []byte("test")}},
+				UserService: tC.userService,
+			}
+
+			req, _ := http.NewRequest("POST", "/user/verify/resend", nil)
+			ctx := req.Context()
+			if tC.contextUser != nil {
+				ctx = context.WithValue(ctx, userContextKey, tC.contextUser)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+			mockHandler.userResendVerificationHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.retryAfter != "" {
+				assert.Equal(t, tC.retryAfter, rr.Header().Get("Retry-After"))
+			}
+			if assert.Len(t, mailer.Sent, tC.mailed) && tC.mailed > 0 {
+				assert.Equal(t, []string{"testuser@test.com"}, mailer.Sent[0].To)
+				assert.WithinDuration(t, time.Now(), tC.contextUser.VerificationSentAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestUserRequestResetPasswordHandler(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		userService  *mock.UserService
+		responseCode int
+	}{
+		{
+			desc:         "reset requested",
+			userService:  &mock.UserService{},
+			responseCode: http.StatusAccepted,
+		},
+		{
+			desc:         "unknown email",
+			userService:  &mock.UserService{ResetErr: goparent.ErrNoUserFound},
+			responseCode: http.StatusAccepted,
+		},
+		{
+			desc:         "unverified email",
+			userService:  &mock.UserService{ResetErr: goparent.ErrEmailNotVerified},
+			responseCode: http.StatusAccepted,
+		},
+		{
+			desc:         "service error",
+			userService:  &mock.UserService{ResetErr: errors.New("test error")},
+			responseCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := Handler{
+				Env:         &goparent.Env{DB: &mock.DBEnv{}},
+				UserService: tC.userService,
+			}
+			form := url.Values{"email": {"testuser@test.com"}}
+			req, _ := http.NewRequest("POST", "/user/resetpassword", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			mockHandler.userRequestResetPasswordHandler().ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+		})
+	}
+}
+
+func TestInitUsersHandlers(t *testing.T) {
+	//TODO: update with new handler routes
+	testCases := []struct {
+		desc    string
+		name    string
+		path    string
+		methods []string
+	}{
+		{
+			desc:    "user new",
+			name:    "UserNew",
+			path:    "/user/",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user login",
+			name:    "UserLogin",
+			path:    "/user/login",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user decline invite",
+			name:    "UserDeclineInvite",
+			path:    "/user/invite/decline/{id}",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user signup with invite",
+			name:    "UserSignupInvite",
+			path:    "/user/signup/invite",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user update",
+			name:    "UserUpdate",
+			path:    "/user/",
+			methods: []string{"PUT"},
+		},
+		{
+			desc:    "user delete",
+			name:    "UserDelete",
+			path:    "/user/delete",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user change email",
+			name:    "UserChangeEmail",
+			path:    "/user/email",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user api keys",
+			name:    "UserAPIKeys",
+			path:    "/user/apikeys",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "user api key revoke",
+			name:    "UserAPIKeyRevoke",
+			path:    "/user/apikeys/{id}",
+			methods: []string{"DELETE"},
+		},
+		{
+			desc:    "user oidc providers",
+			name:    "UserOIDCProviders",
+			path:    "/user/oidc",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "user oidc login",
+			name:    "UserOIDCLogin",
+			path:    "/user/oidc/{provider}/login",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "user oidc callback",
+			name:    "UserOIDCCallback",
+			path:    "/user/oidc/{provider}/callback",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "user oidc link",
+			name:    "UserOIDCLink",
+			path:    "/user/oidc/{provider}/link",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user identities",
+			name:    "UserIdentities",
+			path:    "/user/identities",
+			methods: []string{"GET"},
+		},
+		{
+			desc:    "user identity unlink",
+			name:    "UserIdentityUnlink",
+			path:    "/user/identities/{id}",
+			methods: []string{"DELETE"},
+		},
+		{
+			desc:    "user login two factor",
+			name:    "UserLoginTwoFactor",
+			path:    "/user/login/2fa",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user two factor enroll",
+			name:    "UserTwoFactorEnroll",
+			path:    "/user/2fa",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user two factor disable",
+			name:    "UserTwoFactorDisable",
+			path:    "/user/2fa/disable",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user verify email",
+			name:    "UserVerifyEmail",
+			path:    "/user/verify",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user resend verification",
+			name:    "UserResendVerification",
+			path:    "/user/verify/resend",
+			methods: []string{"POST"},
+		},
+		{
+			desc:    "user confirm email",
+			name:    "UserConfirmEmail",
+			path:    "/user/email/confirm",
 			methods: []string{"POST"},
 		},
 	}
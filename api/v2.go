@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+//Envelope - the body of every successful v2 response and every v2 request.  failed v2
+// responses use the same error body as v1.
+type Envelope struct {
+	Data interface{} `json:"data"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+//Meta - details about the data in a v2 list response
+type Meta struct {
+	Count int                 `json:"count"`
+	Days  uint64              `json:"days,omitempty"`
+	Units goparent.UnitSystem `json:"units,omitempty"`
+}
+
+//SleepStatus - whether a child has a sleep going, and the sleep if they do
+type SleepStatus struct {
+	Active bool            `json:"active"`
+	Sleep  *goparent.Sleep `json:"sleep,omitempty"`
+}
+
+//initV2Handlers - the v2 api nests everything about a child under /children/{childID}.
+// it uses the same services as v1 so both can be served side by side.
+func (h *Handler) initV2Handlers(r *mux.Router) {
+	c := r.PathPrefix("/children").Subrouter()
+	c.Handle("", h.AuthRequired(h.v2ChildrenListHandler())).Methods("GET").Name("V2ChildrenList")
+	c.Handle("", h.AuthRequired(h.v2ChildCreateHandler())).Methods("POST").Name("V2ChildCreate")
+	c.Handle("/{childID}", h.AuthRequired(h.v2ChildGetHandler())).Methods("GET").Name("V2ChildGet")
+	c.Handle("/{childID}", h.AuthRequired(h.v2ChildUpdateHandler())).Methods("PUT").Name("V2ChildUpdate")
+	c.Handle("/{childID}", h.AuthRequired(h.v2ChildDeleteHandler())).Methods("DELETE").Name("V2ChildDelete")
+	c.Handle("/{childID}/summary", h.AuthRequired(h.v2ChildSummaryHandler())).Methods("GET").Name("V2ChildSummary")
+
+	c.Handle("/{childID}/feedings", h.AuthRequired(h.v2FeedingsListHandler())).Methods("GET").Name("V2FeedingsList")
+	c.Handle("/{childID}/feedings", h.AuthRequired(h.v2FeedingCreateHandler())).Methods("POST").Name("V2FeedingCreate")
+	c.Handle("/{childID}/feedings/graph", h.AuthRequired(h.v2FeedingsGraphHandler())).Methods("GET").Name("V2FeedingsGraph")
+
+	c.Handle("/{childID}/sleeps", h.AuthRequired(h.v2SleepsListHandler())).Methods("GET").Name("V2SleepsList")
+	c.Handle("/{childID}/sleeps", h.AuthRequired(h.v2SleepCreateHandler())).Methods("POST").Name("V2SleepCreate")
+	c.Handle("/{childID}/sleeps/graph", h.AuthRequired(h.v2SleepsGraphHandler())).Methods("GET").Name("V2SleepsGraph")
+	c.Handle("/{childID}/sleeps/active", h.AuthRequired(h.v2SleepActiveHandler())).Methods("GET").Name("V2SleepActive")
+	c.Handle("/{childID}/sleeps/active", h.AuthRequired(h.v2SleepStartHandler())).Methods("POST").Name("V2SleepStart")
+	c.Handle("/{childID}/sleeps/active", h.AuthRequired(h.v2SleepEndHandler())).Methods("DELETE").Name("V2SleepEnd")
+
+	c.Handle("/{childID}/wastes", h.AuthRequired(h.v2WastesListHandler())).Methods("GET").Name("V2WastesList")
+	c.Handle("/{childID}/wastes", h.AuthRequired(h.v2WasteCreateHandler())).Methods("POST").Name("V2WasteCreate")
+	c.Handle("/{childID}/wastes/graph", h.AuthRequired(h.v2WastesGraphHandler())).Methods("GET").Name("V2WastesGraph")
+}
+
+//writeData - write a v2 response
+func writeData(w http.ResponseWriter, status int, data interface{}, meta *Meta) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Envelope{Data: data, Meta: meta})
+}
+
+//readData - decode the data of a v2 request into v
+func readData(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&Envelope{Data: v})
+	if err != nil {
+		return badRequest(err)
+	}
+	return nil
+}
+
+//v2Scope - who a v2 request is from, and the child it's about on /children/{childID} routes
+type v2Scope struct {
+	ctx    context.Context
+	user   *goparent.User
+	family *goparent.Family
+	child  *goparent.Child
+}
+
+//scope - look up the user, family and child for a v2 request.  if anything is wrong the
+// error is written to the client and ok is false.
+func (h *Handler) scope(w http.ResponseWriter, r *http.Request) (*v2Scope, bool) {
+	ctx := h.Env.DB.GetContext(r)
+
+	user, err := UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, r, errUnauthorized)
+		return nil, false
+	}
+
+	family, err := h.requestFamily(ctx, user)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+
+	s := &v2Scope{ctx: ctx, user: user, family: family}
+	if childID, ok := mux.Vars(r)["childID"]; ok {
+		s.child, err = h.familyChild(ctx, family, childID)
+		if err != nil {
+			writeError(w, r, err)
+			return nil, false
+		}
+	}
+	return s, true
+}
+
+//canLog - make sure the family can log records for the scope's child.  if it can't the
+// error is written to the client and false is returned.
+func (s *v2Scope) canLog(w http.ResponseWriter, r *http.Request) bool {
+	if !s.child.CanLog(s.family.ID) {
+		writeError(w, r, goparent.ErrChildViewOnly)
+		return false
+	}
+	return true
+}
+
+//owns - make sure the scope's child belongs to the family rather than being shared with it
+func (s *v2Scope) owns(w http.ResponseWriter, r *http.Request) bool {
+	if s.child.FamilyID != s.family.ID {
+		writeError(w, r, goparent.ErrNotChildOwner)
+		return false
+	}
+	return true
+}
+
+//dataQuery - the window for stats and graphs on a v2 request, in the family's time zone
+func dataQuery(r *http.Request, family *goparent.Family, defaultQuery func(time.Time, *time.Location) *goparent.DataQuery) (*goparent.DataQuery, error) {
+	loc, err := requestLocation(r, family)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	query, err := getDataQuery(r, defaultQuery(time.Now(), loc))
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	return query, nil
+}
+
+//v2ChildrenListHandler - GET /children - the children in the user's family
+func (h *Handler) v2ChildrenListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		children, err := h.FamilyService.Children(s.ctx, s.family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if children == nil {
+			children = []*goparent.Child{}
+		}
+		writeData(w, http.StatusOK, children, &Meta{Count: len(children)})
+	})
+}
+
+//v2ChildCreateHandler - POST /children - add a child to the user's family
+func (h *Handler) v2ChildCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		var child goparent.Child
+		err := readData(r, &child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		err = child.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		child.ID = ""
+		child.ParentID = s.user.ID
+		child.FamilyID = s.family.ID
+		err = h.ChildService.Save(s.ctx, &child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusCreated, child, nil)
+	})
+}
+
+//v2ChildGetHandler - GET /children/{childID}
+func (h *Handler) v2ChildGetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+		writeData(w, http.StatusOK, s.child, nil)
+	})
+}
+
+//v2ChildUpdateHandler - PUT /children/{childID} - change a child's name and birthday.
+// the id and family come from the stored child, not the request.
+func (h *Handler) v2ChildUpdateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.owns(w, r) {
+			return
+		}
+
+		var update goparent.Child
+		err := readData(r, &update)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		child := *s.child
+		child.Name = update.Name
+		child.Birthday = update.Birthday
+		err = child.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		err = h.ChildService.Save(s.ctx, &child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusOK, child, nil)
+	})
+}
+
+//v2ChildDeleteHandler - DELETE /children/{childID}
+func (h *Handler) v2ChildDeleteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.owns(w, r) {
+			return
+		}
+
+		_, err := h.ChildService.Delete(s.ctx, s.child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+//v2ChildSummaryHandler - GET /children/{childID}/summary - feeding, sleep and waste stats
+func (h *Handler) v2ChildSummaryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := dataQuery(r, s.family, goparent.NewStatsQuery)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		units, err := displayUnits(r, s.family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		var summary Summary
+		feedings, err := h.FeedingService.Stats(s.ctx, s.child, query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		summary.Feeding = *feedings.In(units)
+
+		sleeps, err := h.SleepService.Stats(s.ctx, s.child, query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		summary.Sleep = *sleeps
+
+		wastes, err := h.WasteService.Stats(s.ctx, s.child, query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		summary.Waste = *wastes
+		summary.Households = households(s.child, &summary)
+
+		writeData(w, http.StatusOK, summary, nil)
+	})
+}
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+//childMismatch - an event posted to one child's route that names a different child
+func childMismatch(field string) error {
+	return goparent.NewValidationError(field, "does not match the child in the path")
+}
+
+//v2FeedingsListHandler - GET /children/{childID}/feedings - recent feedings in display units
+func (h *Handler) v2FeedingsListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		units, err := displayUnits(r, s.family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		pagination := getPagination(r)
+		feedings, err := h.FeedingService.Feeding(s.ctx, s.family, pagination.Days)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		data := []*goparent.Feeding{}
+		for _, feeding := range feedings {
+			if feeding.ChildID == s.child.ID {
+				converted := feeding.In(units)
+				data = append(data, &converted)
+			}
+		}
+		writeData(w, http.StatusOK, data, &Meta{Count: len(data), Days: pagination.Days, Units: units})
+	})
+}
+
+//v2FeedingCreateHandler - POST /children/{childID}/feedings
+func (h *Handler) v2FeedingCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.canLog(w, r) {
+			return
+		}
+
+		var feeding goparent.Feeding
+		err := readData(r, &feeding)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if feeding.ChildID != "" && feeding.ChildID != s.child.ID {
+			writeError(w, r, childMismatch("childID"))
+			return
+		}
+		feeding.ChildID = s.child.ID
+		//amounts without a unit are in the type's default unit
+		if feeding.Unit == "" {
+			feeding.Unit = goparent.DefaultFeedingUnit(feeding.Type)
+		}
+		err = feeding.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		feeding.ID = ""
+		feeding.UserID = s.user.ID
+		feeding.FamilyID = s.family.ID
+		err = h.FeedingService.Save(s.ctx, &feeding)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusCreated, feeding, nil)
+	})
+}
+
+//v2FeedingsGraphHandler - GET /children/{childID}/feedings/graph
+func (h *Handler) v2FeedingsGraphHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := dataQuery(r, s.family, goparent.NewGraphQuery)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		units, err := displayUnits(r, s.family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		graph, err := h.FeedingService.GraphData(s.ctx, s.child, query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusOK, graph.In(units), nil)
+	})
+}
+
+//v2SleepsListHandler - GET /children/{childID}/sleeps
+func (h *Handler) v2SleepsListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		pagination := getPagination(r)
+		sleeps, err := h.SleepService.Sleep(s.ctx, s.family, pagination.Days)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		data := []*goparent.Sleep{}
+		for _, sleep := range sleeps {
+			if sleep.ChildID == s.child.ID {
+				data = append(data, sleep)
+			}
+		}
+		writeData(w, http.StatusOK, data, &Meta{Count: len(data), Days: pagination.Days})
+	})
+}
+
+//v2SleepCreateHandler - POST /children/{childID}/sleeps - record a finished sleep, use
+// sleeps/active to track one that's still going.
+func (h *Handler) v2SleepCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.canLog(w, r) {
+			return
+		}
+
+		var sleep goparent.Sleep
+		err := readData(r, &sleep)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if sleep.ChildID != "" && sleep.ChildID != s.child.ID {
+			writeError(w, r, childMismatch("childID"))
+			return
+		}
+		sleep.ChildID = s.child.ID
+		err = sleep.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		sleep.ID = ""
+		sleep.UserID = s.user.ID
+		sleep.FamilyID = s.family.ID
+		err = h.SleepService.Save(s.ctx, &sleep)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusCreated, sleep, nil)
+	})
+}
+
+//v2SleepsGraphHandler - GET /children/{childID}/sleeps/graph
+func (h *Handler) v2SleepsGraphHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := dataQuery(r, s.family, goparent.NewGraphQuery)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		graph, err := h.SleepService.GraphData(s.ctx, s.child, query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusOK, graph, nil)
+	})
+}
+
+//sleepStatus - the child's current sleep, if they have one
+func (h *Handler) sleepStatus(s *v2Scope) (*SleepStatus, error) {
+	sleep, active, err := h.SleepService.Status(s.ctx, s.family, s.child)
+	if err != nil {
+		return nil, err
+	}
+	status := &SleepStatus{Active: active}
+	if active {
+		status.Sleep = sleep
+	}
+	return status, nil
+}
+
+//v2SleepActiveHandler - GET /children/{childID}/sleeps/active - whether the child is asleep
+func (h *Handler) v2SleepActiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		status, err := h.sleepStatus(s)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusOK, status, nil)
+	})
+}
+
+//v2SleepStartHandler - POST /children/{childID}/sleeps/active - start a sleep now
+func (h *Handler) v2SleepStartHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.canLog(w, r) {
+			return
+		}
+
+		err := h.SleepService.Start(s.ctx, s.family, s.child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		status, err := h.sleepStatus(s)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusCreated, status, nil)
+	})
+}
+
+//v2SleepEndHandler - DELETE /children/{childID}/sleeps/active - end the current sleep now,
+// the sleep is kept and shows up in the sleeps list.
+func (h *Handler) v2SleepEndHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.canLog(w, r) {
+			return
+		}
+
+		err := h.SleepService.End(s.ctx, s.family, s.child)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusOK, SleepStatus{Active: false}, nil)
+	})
+}
+
+//v2WastesListHandler - GET /children/{childID}/wastes
+func (h *Handler) v2WastesListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		pagination := getPagination(r)
+		wastes, err := h.WasteService.Waste(s.ctx, s.family, pagination.Days)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		data := []*goparent.Waste{}
+		for _, waste := range wastes {
+			if waste.ChildID == s.child.ID {
+				data = append(data, waste)
+			}
+		}
+		writeData(w, http.StatusOK, data, &Meta{Count: len(data), Days: pagination.Days})
+	})
+}
+
+//v2WasteCreateHandler - POST /children/{childID}/wastes
+func (h *Handler) v2WasteCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok || !s.canLog(w, r) {
+			return
+		}
+
+		var waste goparent.Waste
+		err := readData(r, &waste)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if waste.ChildID != "" && waste.ChildID != s.child.ID {
+			writeError(w, r, childMismatch("childid"))
+			return
+		}
+		waste.ChildID = s.child.ID
+		err = waste.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		waste.ID = ""
+		waste.UserID = s.user.ID
+		waste.FamilyID = s.family.ID
+		err = h.WasteService.Save(s.ctx, &waste)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusCreated, waste, nil)
+	})
+}
+
+//v2WastesGraphHandler - GET /children/{childID}/wastes/graph
+func (h *Handler) v2WastesGraphHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.scope(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := dataQuery(r, s.family, goparent.NewGraphQuery)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		graph, err := h.WasteService.GraphData(s.ctx, s.child, query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, http.StatusOK, graph, nil)
+	})
+}
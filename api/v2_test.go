@@ -0,0 +1,343 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+//v2Result - a decoded v2 response, either data and meta or an error
+type v2Result struct {
+	Data  json.RawMessage `json:"data"`
+	Meta  *Meta           `json:"meta"`
+	Error *ErrorBody      `json:"error"`
+}
+
+func TestV2Handlers(t *testing.T) {
+	now := time.Now()
+	family := &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}}
+	child := &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1", ParentID: "1"}
+	user := &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"}
+	timestamp, _ := json.Marshal(now.Add(-time.Hour))
+
+	testCases := []struct {
+		desc         string
+		handler      func(h *Handler) http.Handler
+		method       string
+		childID      string
+		body         string
+		handlerSetup Handler
+		contextUser  *goparent.User
+		responseCode int
+		count        int
+		errorCode    string
+		data         map[string]interface{}
+	}{
+		{
+			desc:    "list children",
+			handler: (*Handler).v2ChildrenListHandler,
+			handlerSetup: Handler{
+				FamilyService: &mock.FamilyService{Kids: []*goparent.Child{child, {ID: "3", Name: "Sally", FamilyID: "1"}}},
+			},
+			responseCode: http.StatusOK,
+			count:        2,
+		},
+		{
+			desc:         "create child",
+			handler:      (*Handler).v2ChildCreateHandler,
+			method:       "POST",
+			body:         `{"data": {"name": "Sally", "familyID": "2"}}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{}},
+			responseCode: http.StatusCreated,
+			data:         map[string]interface{}{"name": "Sally", "familyID": "1", "parentID": "1"},
+		},
+		{
+			desc:         "create child without a name",
+			handler:      (*Handler).v2ChildCreateHandler,
+			method:       "POST",
+			body:         `{"data": {"name": ""}}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{}},
+			responseCode: http.StatusBadRequest,
+			errorCode:    CodeValidation,
+		},
+		{
+			desc:         "create child with a bare body",
+			handler:      (*Handler).v2ChildCreateHandler,
+			method:       "POST",
+			body:         `{"name": "Sally"}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{}},
+			responseCode: http.StatusBadRequest,
+			errorCode:    CodeValidation,
+		},
+		{
+			desc:         "get child",
+			handler:      (*Handler).v2ChildGetHandler,
+			childID:      "1",
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: child}},
+			responseCode: http.StatusOK,
+			data:         map[string]interface{}{"id": "1", "name": "Billy"},
+		},
+		{
+			desc:         "get child in another family",
+			handler:      (*Handler).v2ChildGetHandler,
+			childID:      "2",
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: &goparent.Child{ID: "2", FamilyID: "2"}}},
+			responseCode: http.StatusNotFound,
+			errorCode:    CodeChildNotFound,
+		},
+		{
+			desc:         "update child keeps its family",
+			handler:      (*Handler).v2ChildUpdateHandler,
+			method:       "PUT",
+			childID:      "1",
+			body:         `{"data": {"id": "5", "name": "William", "familyID": "2"}}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: child}},
+			responseCode: http.StatusOK,
+			data:         map[string]interface{}{"id": "1", "name": "William", "familyID": "1"},
+		},
+		{
+			desc:         "delete child",
+			handler:      (*Handler).v2ChildDeleteHandler,
+			method:       "DELETE",
+			childID:      "1",
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: child, Deleted: 1}},
+			responseCode: http.StatusNoContent,
+		},
+		{
+			desc:    "child summary",
+			handler: (*Handler).v2ChildSummaryHandler,
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService:   &mock.ChildService{Kid: child},
+				FeedingService: &mock.FeedingService{Stat: &goparent.FeedingSummary{}},
+				SleepService:   &mock.SleepService{Stat: &goparent.SleepSummary{Total: 3600}},
+				WasteService:   &mock.WasteService{Stat: &goparent.WasteSummary{}},
+			},
+			responseCode: http.StatusOK,
+		},
+		{
+			desc:    "list feedings for one child",
+			handler: (*Handler).v2FeedingsListHandler,
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				FeedingService: &mock.FeedingService{Feedings: []*goparent.Feeding{
+					{ID: "1", ChildID: "1", Type: "bottle", Amount: 4, Unit: goparent.UnitFluidOunce},
+					{ID: "2", ChildID: "3", Type: "bottle", Amount: 4, Unit: goparent.UnitFluidOunce},
+				}},
+			},
+			responseCode: http.StatusOK,
+			count:        1,
+		},
+		{
+			desc:         "create feeding for the child in the path",
+			handler:      (*Handler).v2FeedingCreateHandler,
+			method:       "POST",
+			childID:      "1",
+			body:         `{"data": {"feedingType": "bottle", "feedingAmount": 4, "timestamp": ` + string(timestamp) + `}}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: child}, FeedingService: &mock.FeedingService{}},
+			responseCode: http.StatusCreated,
+			data:         map[string]interface{}{"childID": "1", "feedingUnit": "floz", "userid": "1", "familyid": "1"},
+		},
+		{
+			desc:         "create feeding for a different child",
+			handler:      (*Handler).v2FeedingCreateHandler,
+			method:       "POST",
+			childID:      "1",
+			body:         `{"data": {"childID": "3", "feedingType": "bottle", "feedingAmount": 4, "timestamp": ` + string(timestamp) + `}}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: child}, FeedingService: &mock.FeedingService{}},
+			responseCode: http.StatusBadRequest,
+			errorCode:    CodeValidation,
+		},
+		{
+			desc:         "create waste",
+			handler:      (*Handler).v2WasteCreateHandler,
+			method:       "POST",
+			childID:      "1",
+			body:         `{"data": {"wasteType": 1, "timestamp": ` + string(timestamp) + `}}`,
+			handlerSetup: Handler{ChildService: &mock.ChildService{Kid: child}, WasteService: &mock.WasteService{}},
+			responseCode: http.StatusCreated,
+			data:         map[string]interface{}{"childid": "1"},
+		},
+		{
+			desc:    "list sleeps",
+			handler: (*Handler).v2SleepsListHandler,
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				SleepService: &mock.SleepService{Sleeps: []*goparent.Sleep{{ID: "1", ChildID: "1"}}},
+			},
+			responseCode: http.StatusOK,
+			count:        1,
+		},
+		{
+			desc:    "active sleep",
+			handler: (*Handler).v2SleepActiveHandler,
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				SleepService: &mock.SleepService{GetStatus: true, GetSleep: &goparent.Sleep{ID: "1", ChildID: "1", Start: now}},
+			},
+			responseCode: http.StatusOK,
+			data:         map[string]interface{}{"active": true},
+		},
+		{
+			desc:    "no active sleep",
+			handler: (*Handler).v2SleepActiveHandler,
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				SleepService: &mock.SleepService{},
+			},
+			responseCode: http.StatusOK,
+			data:         map[string]interface{}{"active": false},
+		},
+		{
+			desc:    "start sleep when one is going",
+			handler: (*Handler).v2SleepStartHandler,
+			method:  "POST",
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				SleepService: &mock.SleepService{StartErr: goparent.ErrExistingStart},
+			},
+			responseCode: http.StatusConflict,
+			errorCode:    CodeSleepAlreadyActive,
+		},
+		{
+			desc:    "end sleep",
+			handler: (*Handler).v2SleepEndHandler,
+			method:  "DELETE",
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				SleepService: &mock.SleepService{},
+			},
+			responseCode: http.StatusOK,
+			data:         map[string]interface{}{"active": false},
+		},
+		{
+			desc:    "wastes graph",
+			handler: (*Handler).v2WastesGraphHandler,
+			childID: "1",
+			handlerSetup: Handler{
+				ChildService: &mock.ChildService{Kid: child},
+				WasteService: &mock.WasteService{Graph: &goparent.WasteChartData{Granularity: goparent.GranularityDay}},
+			},
+			responseCode: http.StatusOK,
+			data:         map[string]interface{}{"granularity": "day"},
+		},
+		{
+			desc:         "no user",
+			handler:      (*Handler).v2ChildrenListHandler,
+			contextUser:  &goparent.User{},
+			responseCode: http.StatusUnauthorized,
+			errorCode:    CodeUnauthorized,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := tC.handlerSetup
+			mockHandler.Env = &goparent.Env{DB: &mock.DBEnv{}}
+			mockHandler.UserService = &mock.UserService{Family: family}
+
+			method := tC.method
+			if method == "" {
+				method = "GET"
+			}
+			req, err := http.NewRequest(method, "/api/v2/children", bytes.NewBufferString(tC.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tC.childID != "" {
+				req = mux.SetURLVars(req, map[string]string{"childID": tC.childID})
+			}
+
+			ctx := req.Context()
+			if tC.contextUser == nil {
+				ctx = context.WithValue(ctx, userContextKey, user)
+			} else {
+				ctx = context.WithValue(ctx, userContextKey, "")
+			}
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			tC.handler(&mockHandler).ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.responseCode == http.StatusNoContent {
+				assert.Empty(t, rr.Body.String())
+				return
+			}
+
+			var result v2Result
+			err = json.NewDecoder(rr.Body).Decode(&result)
+			assert.Nil(t, err)
+			if tC.errorCode != "" {
+				if assert.NotNil(t, result.Error) {
+					assert.Equal(t, tC.errorCode, result.Error.Code)
+				}
+				return
+			}
+			assert.Nil(t, result.Error)
+			if tC.count > 0 && assert.NotNil(t, result.Meta) {
+				assert.Equal(t, tC.count, result.Meta.Count)
+			}
+			if tC.data != nil {
+				var data map[string]interface{}
+				err = json.Unmarshal(result.Data, &data)
+				assert.Nil(t, err)
+				for k, v := range tC.data {
+					assert.Equal(t, v, data[k], k)
+				}
+			}
+		})
+	}
+}
+
+func TestV2Routing(t *testing.T) {
+	router := BuildAPIRouting(&Handler{Env: &goparent.Env{DB: &mock.DBEnv{}}})
+	testCases := []struct {
+		desc   string
+		method string
+		path   string
+		route  string
+	}{
+		{desc: "v2 children", method: "GET", path: "/api/v2/children", route: "V2ChildrenList"},
+		{desc: "v2 feedings", method: "POST", path: "/api/v2/children/1/feedings", route: "V2FeedingCreate"},
+		{desc: "v2 sleep end", method: "DELETE", path: "/api/v2/children/1/sleeps/active", route: "V2SleepEnd"},
+		{desc: "v1 children still routed", method: "GET", path: "/api/children", route: "ChildrenGet"},
+		{desc: "v1 sleep status still routed", method: "GET", path: "/api/sleep/status/1", route: "SleepStatus"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req, err := http.NewRequest(tC.method, tC.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var match mux.RouteMatch
+			if assert.True(t, router.Match(req, &match)) && assert.NotNil(t, match.Route) {
+				assert.Equal(t, tC.route, match.Route.GetName())
+			}
+
+			//without a token every route is unauthorized, with the same error body
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			var result v2Result
+			err = json.NewDecoder(rr.Body).Decode(&result)
+			assert.Nil(t, err)
+			if assert.NotNil(t, result.Error) {
+				assert.Equal(t, CodeUnauthorized, result.Error.Code)
+			}
+		})
+	}
+}
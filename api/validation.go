@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+//familyChild - get a child and make sure the family can see it, either because it's the
+// family's own or because it's shared with the family.  children from other families are
+// reported as not found so ids from other families can't be probed.
+func (h *Handler) familyChild(ctx context.Context, family *goparent.Family, childID string) (*goparent.Child, error) {
+	child, err := h.ChildService.Child(ctx, childID)
+	if err != nil {
+		return nil, err
+	}
+	if !child.CanView(family.ID) {
+		return nil, goparent.ErrNoChildFound
+	}
+	return child, nil
+}
+
+//sharedChild - get a child the family can see or has been asked to take a share of
+func (h *Handler) sharedChild(ctx context.Context, family *goparent.Family, childID string) (*goparent.Child, error) {
+	child, err := h.ChildService.Child(ctx, childID)
+	if err != nil {
+		return nil, err
+	}
+	if !child.CanView(family.ID) && child.SharedWith(family.ID) == nil {
+		return nil, goparent.ErrNoChildFound
+	}
+	return child, nil
+}
+
+//loggableChild - get a child the family can log feedings, sleeps and wastes for
+func (h *Handler) loggableChild(ctx context.Context, family *goparent.Family, childID string) (*goparent.Child, error) {
+	child, err := h.familyChild(ctx, family, childID)
+	if err != nil {
+		return nil, err
+	}
+	if !child.CanLog(family.ID) {
+		return nil, goparent.ErrChildViewOnly
+	}
+	return child, nil
+}
+
+//ownChild - get a child that belongs to the family.  only the child's own family can
+// change, delete or share it.
+func (h *Handler) ownChild(ctx context.Context, family *goparent.Family, childID string) (*goparent.Child, error) {
+	child, err := h.familyChild(ctx, family, childID)
+	if err != nil {
+		return nil, err
+	}
+	if child.FamilyID != family.ID {
+		return nil, goparent.ErrNotChildOwner
+	}
+	return child, nil
+}
+
+//validateChildEntity - validate a feeding, sleep or waste and make sure the child it
+// is for can be logged by the family.  field is the json name of the entity's child id.
+func (h *Handler) validateChildEntity(ctx context.Context, family *goparent.Family, entity goparent.Validator, field string, childID string) error {
+	err := entity.Validate(time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = h.loggableChild(ctx, family, childID)
+	if errors.Is(err, goparent.ErrNoChildFound) {
+		return goparent.NewValidationError(field, "child not found")
+	}
+	return err
+}
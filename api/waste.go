@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sasimpson/goparent"
@@ -35,21 +36,21 @@ func (h *Handler) wasteGetHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		pagination := getPagination(r)
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		wasteData, err := h.WasteService.Waste(ctx, family, pagination.Days)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 		}
 
 		wasteResponse := WasteResponse{WasteData: wasteData, Pagination: *pagination}
@@ -62,11 +63,11 @@ func (h *Handler) wasteViewHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 
 	})
 }
@@ -75,11 +76,11 @@ func (h *Handler) wasteEditHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -89,13 +90,13 @@ func (h *Handler) wasteNewHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -103,17 +104,23 @@ func (h *Handler) wasteNewHandler() http.Handler {
 		var wasteRequest WasteRequest
 		err = decoder.Decode(&wasteRequest)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, badRequest(err))
 			return
 		}
 		defer r.Body.Close()
 
+		err = h.validateChildEntity(ctx, family, wasteRequest.WasteData, "childid", wasteRequest.WasteData.ChildID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
 		w.Header().Set("Content-Type", jsonContentType)
 		wasteRequest.WasteData.UserID = user.ID
 		wasteRequest.WasteData.FamilyID = family.ID
 		err = h.WasteService.Save(ctx, &wasteRequest.WasteData)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusConflict)
+			writeError(w, r, err)
 			return
 		}
 
@@ -125,11 +132,11 @@ func (h *Handler) wasteDeleteHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
-		http.Error(w, "not implemented", http.StatusNotImplemented)
+		writeError(w, r, errNotImplemented)
 	})
 }
 
@@ -139,33 +146,45 @@ func (h *Handler) wasteGraphDataHandler() http.Handler {
 
 		user, err := UserFromContext(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, r, errUnauthorized)
 			return
 		}
 
 		childID := mux.Vars(r)["id"]
 
-		family, err := h.UserService.GetFamily(ctx, user)
+		family, err := h.requestFamily(ctx, user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		child, err := h.ChildService.Child(ctx, childID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
+			return
+		}
+
+		//child needs to belong to or be shared with the user's family.
+		if !child.CanView(family.ID) {
+			writeError(w, r, errNotFound)
+			return
+		}
+
+		loc, err := requestLocation(r, family)
+		if err != nil {
+			writeError(w, r, badRequest(err))
 			return
 		}
 
-		//child needs to belong to the user's family.
-		if child.FamilyID != family.ID {
-			http.Error(w, "not found", http.StatusNotFound)
+		query, err := getDataQuery(r, goparent.NewGraphQuery(time.Now(), loc))
+		if err != nil {
+			writeError(w, r, badRequest(err))
 			return
 		}
 
-		wasteGraphData, err := h.WasteService.GraphData(ctx, child)
+		wasteGraphData, err := h.WasteService.GraphData(ctx, child, query)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
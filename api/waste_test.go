@@ -312,6 +312,7 @@ func TestWasteNewHandler(t *testing.T) {
 		userService   goparent.UserService
 		familyService goparent.FamilyService
 		wasteService  goparent.WasteService
+		childService  goparent.ChildService
 		contextUser   *goparent.User
 		contextError  bool
 		responseCode  int
@@ -338,12 +339,30 @@ func TestWasteNewHandler(t *testing.T) {
 				},
 			},
 			familyService: &mock.FamilyService{},
+			childService:  &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
 			wasteService:  &mock.WasteService{},
 			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:  false,
 			responseCode:  http.StatusOK,
 			resultLength:  0,
 		},
+		{
+			desc: "returns validation error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			wasteRequest: WasteRequest{
+				WasteData: goparent.Waste{
+					Type:      7,
+					ChildID:   "1",
+					TimeStamp: timestamp}},
+			userService: &mock.UserService{
+				Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}},
+			},
+			familyService: &mock.FamilyService{},
+			childService:  &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
+			wasteService:  &mock.WasteService{},
+			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+			responseCode:  http.StatusBadRequest,
+		},
 		{
 			desc: "returns no family error",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
@@ -385,12 +404,13 @@ func TestWasteNewHandler(t *testing.T) {
 				},
 			},
 			familyService: &mock.FamilyService{},
+			childService:  &mock.ChildService{Kid: &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}},
 			wasteService: &mock.WasteService{
 				GetErr: errors.New("unknown waste error"),
 			},
 			contextUser:  &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError: false,
-			responseCode: http.StatusConflict,
+			responseCode: http.StatusInternalServerError,
 		},
 		{
 			desc: "returns auth error",
@@ -426,7 +446,7 @@ func TestWasteNewHandler(t *testing.T) {
 			wasteService:  &mock.WasteService{},
 			contextUser:   &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
 			contextError:  false,
-			responseCode:  http.StatusInternalServerError,
+			responseCode:  http.StatusBadRequest,
 		},
 	}
 	for _, tC := range testCases {
@@ -436,6 +456,7 @@ func TestWasteNewHandler(t *testing.T) {
 				UserService:   tC.userService,
 				FamilyService: tC.familyService,
 				WasteService:  tC.wasteService,
+				ChildService:  tC.childService,
 			}
 
 			js, err := json.Marshal(&tC.wasteRequest)
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+)
+
+//webhookDeliveryLimit - how many deliveries the delivery log returns
+const webhookDeliveryLimit = 50
+
+//WebhookRequest - incoming request structure for a new webhook, a secret is generated
+// if one isn't sent.
+type WebhookRequest struct {
+	WebhookData goparent.Webhook `json:"webhookData"`
+}
+
+//WebhookResponse - response structure for a webhook.  the secret is only returned when
+// the webhook is created.
+type WebhookResponse struct {
+	WebhookData *goparent.Webhook `json:"webhookData"`
+}
+
+//WebhooksResponse - response structure for a family's webhooks
+type WebhooksResponse struct {
+	Webhooks []*goparent.Webhook `json:"webhooks"`
+}
+
+//WebhookDeliveryResponse - the result of testing a webhook
+type WebhookDeliveryResponse struct {
+	Delivery *goparent.WebhookDelivery `json:"delivery"`
+}
+
+//WebhookDeliveriesResponse - the recent deliveries for a webhook, newest first
+type WebhookDeliveriesResponse struct {
+	Deliveries []*goparent.WebhookDelivery `json:"deliveries"`
+}
+
+func (h *Handler) initWebhookHandlers(r *mux.Router) {
+	wh := r.PathPrefix("/webhooks").Subrouter()
+	wh.Handle("", h.AuthRequired(h.webhooksListHandler())).Methods("GET").Name("WebhooksList")
+	wh.Handle("", h.AuthRequired(h.webhookCreateHandler())).Methods("POST").Name("WebhookCreate")
+	wh.Handle("/{id}/test", h.AuthRequired(h.webhookTestHandler())).Methods("POST").Name("WebhookTest")
+	wh.Handle("/{id}/disable", h.AuthRequired(h.webhookDisableHandler())).Methods("POST").Name("WebhookDisable")
+	wh.Handle("/{id}/deliveries", h.AuthRequired(h.webhookDeliveriesHandler())).Methods("GET").Name("WebhookDeliveries")
+}
+
+//webhookScope - the user's family, and the webhook in the path if there is one.  webhooks
+// in other families are not found.
+func (h *Handler) webhookScope(w http.ResponseWriter, r *http.Request, adminOnly bool) (*goparent.Family, *goparent.Webhook, bool) {
+	ctx := h.Env.DB.GetContext(r)
+	user, err := UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, r, errUnauthorized)
+		return nil, nil, false
+	}
+
+	family, err := h.requestFamily(ctx, user)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+	if adminOnly && family.Admin != user.ID {
+		writeError(w, r, forbidden("only the family admin can manage webhooks"))
+		return nil, nil, false
+	}
+
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return family, nil, true
+	}
+	webhook, err := h.WebhookService.Webhook(ctx, id)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+	if webhook.FamilyID != family.ID {
+		writeError(w, r, goparent.ErrNoWebhookFound)
+		return nil, nil, false
+	}
+	return family, webhook, true
+}
+
+//withoutSecret - a copy of the webhook that is safe to list
+func withoutSecret(webhook *goparent.Webhook) *goparent.Webhook {
+	hidden := *webhook
+	hidden.Secret = ""
+	return &hidden
+}
+
+//webhooksListHandler - GET / - the family's webhooks
+func (h *Handler) webhooksListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		family, _, ok := h.webhookScope(w, r, false)
+		if !ok {
+			return
+		}
+
+		webhooks, err := h.WebhookService.Webhooks(h.Env.DB.GetContext(r), family)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		resp := WebhooksResponse{Webhooks: make([]*goparent.Webhook, len(webhooks))}
+		for i, webhook := range webhooks {
+			resp.Webhooks[i] = withoutSecret(webhook)
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+//webhookCreateHandler - POST / - register a webhook for the family, admin only
+func (h *Handler) webhookCreateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		family, _, ok := h.webhookScope(w, r, true)
+		if !ok {
+			return
+		}
+
+		var webhookRequest WebhookRequest
+		err := json.NewDecoder(r.Body).Decode(&webhookRequest)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		defer r.Body.Close()
+
+		webhook := &webhookRequest.WebhookData
+		err = webhook.Validate(time.Now())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		webhook.ID = ""
+		webhook.FamilyID = family.ID
+		webhook.Disabled = false
+		if webhook.Secret == "" {
+			webhook.Secret, err = goparent.NewWebhookSecret()
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		err = h.WebhookService.Save(h.Env.DB.GetContext(r), webhook)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(WebhookResponse{WebhookData: webhook})
+	})
+}
+
+//webhookTestHandler - POST /{id}/test - send a webhook.ping event once and return how it went
+func (h *Handler) webhookTestHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, webhook, ok := h.webhookScope(w, r, true)
+		if !ok {
+			return
+		}
+
+		dispatcher := h.WebhookDispatcher
+		if dispatcher == nil {
+			dispatcher = &goparent.WebhookDispatcher{Service: h.WebhookService}
+		}
+		delivery := dispatcher.Send(h.Env.DB.GetContext(r), webhook, goparent.WebhookPing(webhook))
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(WebhookDeliveryResponse{Delivery: delivery})
+	})
+}
+
+//webhookDisableHandler - POST /{id}/disable - stop sending events to a webhook, admin only
+func (h *Handler) webhookDisableHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, webhook, ok := h.webhookScope(w, r, true)
+		if !ok {
+			return
+		}
+
+		webhook.Disabled = true
+		err := h.WebhookService.Save(h.Env.DB.GetContext(r), webhook)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(WebhookResponse{WebhookData: withoutSecret(webhook)})
+	})
+}
+
+//webhookDeliveriesHandler - GET /{id}/deliveries - the webhook's recent delivery attempts
+func (h *Handler) webhookDeliveriesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, webhook, ok := h.webhookScope(w, r, false)
+		if !ok {
+			return
+		}
+
+		deliveries, err := h.WebhookService.Deliveries(h.Env.DB.GetContext(r), webhook, webhookDeliveryLimit)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		json.NewEncoder(w).Encode(WebhookDeliveriesResponse{Deliveries: deliveries})
+	})
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookHandlers(t *testing.T) {
+	admin := &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"}
+	member := &goparent.User{ID: "2", Name: "other parent", Email: "other@test.com", Username: "other"}
+	family := &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}}
+	webhook := func() *goparent.Webhook {
+		return &goparent.Webhook{ID: "1", FamilyID: "1", URL: "http://localhost/hook", Secret: "secret", Events: []string{"sleep.*"}}
+	}
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		vars           map[string]string
+		body           interface{}
+		handler        func(h *Handler) http.Handler
+		user           *goparent.User
+		webhookService *mock.WebhookService
+		responseCode   int
+		errorCode      string
+		check          func(t *testing.T, body []byte)
+	}{
+		{
+			desc:           "list webhooks hides secrets",
+			method:         "GET",
+			path:           "/webhooks",
+			handler:        (*Handler).webhooksListHandler,
+			user:           member,
+			webhookService: &mock.WebhookService{GetWebhooks: []*goparent.Webhook{webhook()}},
+			responseCode:   http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp WebhooksResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				if assert.Len(t, resp.Webhooks, 1) {
+					assert.Empty(t, resp.Webhooks[0].Secret)
+					assert.Equal(t, "http://localhost/hook", resp.Webhooks[0].URL)
+				}
+			},
+		},
+		{
+			desc:           "create webhook",
+			method:         "POST",
+			path:           "/webhooks",
+			body:           WebhookRequest{WebhookData: goparent.Webhook{URL: "https://example.com/hook", Events: []string{"sleep.started"}, FamilyID: "2"}},
+			handler:        (*Handler).webhookCreateHandler,
+			user:           admin,
+			webhookService: &mock.WebhookService{WebhookID: "5"},
+			responseCode:   http.StatusCreated,
+			check: func(t *testing.T, body []byte) {
+				var resp WebhookResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Equal(t, "5", resp.WebhookData.ID)
+				assert.Equal(t, "1", resp.WebhookData.FamilyID)
+				assert.Len(t, resp.WebhookData.Secret, 64)
+			},
+		},
+		{
+			desc:           "create webhook not admin",
+			method:         "POST",
+			path:           "/webhooks",
+			body:           WebhookRequest{WebhookData: goparent.Webhook{URL: "https://example.com/hook"}},
+			handler:        (*Handler).webhookCreateHandler,
+			user:           member,
+			webhookService: &mock.WebhookService{},
+			responseCode:   http.StatusForbidden,
+			errorCode:      CodeForbidden,
+		},
+		{
+			desc:           "create webhook invalid",
+			method:         "POST",
+			path:           "/webhooks",
+			body:           WebhookRequest{WebhookData: goparent.Webhook{URL: "example.com", Events: []string{"nap.started"}}},
+			handler:        (*Handler).webhookCreateHandler,
+			user:           admin,
+			webhookService: &mock.WebhookService{},
+			responseCode:   http.StatusBadRequest,
+			errorCode:      CodeValidation,
+		},
+		{
+			desc:           "disable webhook",
+			method:         "POST",
+			path:           "/webhooks/1/disable",
+			vars:           map[string]string{"id": "1"},
+			handler:        (*Handler).webhookDisableHandler,
+			user:           admin,
+			webhookService: &mock.WebhookService{GetWebhook: webhook()},
+			responseCode:   http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp WebhookResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.True(t, resp.WebhookData.Disabled)
+				assert.Empty(t, resp.WebhookData.Secret)
+			},
+		},
+		{
+			desc:           "disable other family's webhook",
+			method:         "POST",
+			path:           "/webhooks/1/disable",
+			vars:           map[string]string{"id": "1"},
+			handler:        (*Handler).webhookDisableHandler,
+			user:           admin,
+			webhookService: &mock.WebhookService{GetWebhook: &goparent.Webhook{ID: "1", FamilyID: "2"}},
+			responseCode:   http.StatusNotFound,
+			errorCode:      CodeWebhookNotFound,
+		},
+		{
+			desc:           "disable missing webhook",
+			method:         "POST",
+			path:           "/webhooks/1/disable",
+			vars:           map[string]string{"id": "1"},
+			handler:        (*Handler).webhookDisableHandler,
+			user:           admin,
+			webhookService: &mock.WebhookService{},
+			responseCode:   http.StatusNotFound,
+			errorCode:      CodeWebhookNotFound,
+		},
+		{
+			desc:    "delivery log",
+			method:  "GET",
+			path:    "/webhooks/1/deliveries",
+			vars:    map[string]string{"id": "1"},
+			handler: (*Handler).webhookDeliveriesHandler,
+			user:    member,
+			webhookService: &mock.WebhookService{
+				GetWebhook:    webhook(),
+				GetDeliveries: []*goparent.WebhookDelivery{{ID: "2", WebhookID: "1", Attempt: 2, StatusCode: 200}, {ID: "1", WebhookID: "1", Attempt: 1, StatusCode: 503}},
+			},
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp WebhookDeliveriesResponse
+				assert.Nil(t, json.Unmarshal(body, &resp))
+				assert.Len(t, resp.Deliveries, 2)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mockHandler := &Handler{
+				Env:            &goparent.Env{DB: &mock.DBEnv{}},
+				UserService:    &mock.UserService{Family: family},
+				WebhookService: tC.webhookService,
+			}
+
+			var body bytes.Buffer
+			if tC.body != nil {
+				json.NewEncoder(&body).Encode(tC.body)
+			}
+			req, err := http.NewRequest(tC.method, tC.path, &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = mux.SetURLVars(req, tC.vars)
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, tC.user))
+
+			rr := httptest.NewRecorder()
+			tC.handler(mockHandler).ServeHTTP(rr, req)
+			assert.Equal(t, tC.responseCode, rr.Code)
+			if tC.errorCode != "" {
+				var errBody ErrService
+				assert.Nil(t, json.NewDecoder(rr.Body).Decode(&errBody))
+				assert.Equal(t, tC.errorCode, errBody.ErrMessage.Code)
+			}
+			if tC.check != nil {
+				tC.check(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestWebhookTestHandler(t *testing.T) {
+	var received []byte
+	var signature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(goparent.WebhookSignatureHeader)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	webhookService := &mock.WebhookService{GetWebhook: &goparent.Webhook{ID: "1", FamilyID: "1", URL: receiver.URL, Secret: "secret"}}
+	mockHandler := &Handler{
+		Env:            &goparent.Env{DB: &mock.DBEnv{}},
+		UserService:    &mock.UserService{Family: &goparent.Family{ID: "1", Admin: "1"}},
+		WebhookService: webhookService,
+	}
+	req, err := http.NewRequest("POST", "/webhooks/1/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	req = req.WithContext(context.WithValue(req.Context(), userContextKey, &goparent.User{ID: "1"}))
+
+	rr := httptest.NewRecorder()
+	mockHandler.webhookTestHandler().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp WebhookDeliveryResponse
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, http.StatusAccepted, resp.Delivery.StatusCode)
+	assert.Equal(t, "webhook.ping", resp.Delivery.Event)
+	assert.True(t, goparent.VerifyWebhook("secret", received, signature))
+	assert.Len(t, webhookService.Saved, 1)
+}
@@ -0,0 +1,131 @@
+package goparent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//APIKeyPrefix - every api key starts with this, it's how they're told apart from login tokens
+const APIKeyPrefix = "gpk_"
+
+//API key scopes, what a key is allowed to do.  keys can only do the things in their scopes
+// and nothing that manages the account.
+const (
+	ScopeChildrenRead = "children:read"
+	ScopeFamilyRead   = "family:read"
+	ScopeFeedingRead  = "feeding:read"
+	ScopeFeedingWrite = "feeding:write"
+	ScopeSleepRead    = "sleep:read"
+	ScopeSleepWrite   = "sleep:write"
+	//ScopeSleepToggle - starting and ending sleeps, without being able to edit them
+	ScopeSleepToggle = "sleep:toggle"
+	ScopeWasteRead   = "waste:read"
+	ScopeWasteWrite  = "waste:write"
+	ScopeEventsRead  = "events:read"
+)
+
+//APIKeyScopes - all of the scopes a key can have
+var APIKeyScopes = []string{
+	ScopeChildrenRead, ScopeFamilyRead,
+	ScopeFeedingRead, ScopeFeedingWrite,
+	ScopeSleepRead, ScopeSleepWrite, ScopeSleepToggle,
+	ScopeWasteRead, ScopeWasteWrite,
+	ScopeEventsRead,
+}
+
+//APIKeyUseInterval - how often a key's LastUsed is updated, so every use isn't a write
+const APIKeyUseInterval = time.Minute
+
+//ErrNoAPIKeyFound - there is no api key with that id
+var ErrNoAPIKeyFound = errors.New("no api key found with that id")
+
+//APIKey - a named key a user makes for scripts and devices, used in place of a login token.
+// only a hash of the key is kept, Prefix is the start of it so the user can tell keys apart.
+type APIKey struct {
+	ID        string    `json:"id" gorethink:"id,omitempty"`
+	UserID    string    `json:"userID" gorethink:"userID"`
+	Name      string    `json:"name" gorethink:"name"`
+	Prefix    string    `json:"prefix" gorethink:"prefix"`
+	Hash      string    `json:"-" gorethink:"hash"`
+	Scopes    []string  `json:"scopes" gorethink:"scopes"`
+	CreatedAt time.Time `json:"createdAt" gorethink:"createdAt"`
+	LastUsed  time.Time `json:"lastUsed" gorethink:"lastUsed"`
+}
+
+//APIKeyService - storage for api keys.  Used only updates LastUsed, it doesn't bring back a
+// key that's been deleted.
+type APIKeyService interface {
+	Save(context.Context, *APIKey) error
+	APIKey(context.Context, string) (*APIKey, error)
+	APIKeyByHash(context.Context, string) (*APIKey, error)
+	APIKeys(context.Context, *User) ([]*APIKey, error)
+	Used(context.Context, *APIKey, time.Time) error
+	Delete(context.Context, *APIKey) error
+}
+
+//NewAPIKey - a key for the user, returned with the secret that's shown to them once
+func NewAPIKey(user *User, name string, scopes []string, now time.Time) (*APIKey, string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := APIKeyPrefix + hex.EncodeToString(b)
+	return &APIKey{
+		UserID:    user.ID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    secret[:len(APIKeyPrefix)+8],
+		Hash:      HashAPIKey(secret),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}, secret, nil
+}
+
+//HashAPIKey - the hash a key is stored and looked up by
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+//IsAPIKey - whether a bearer token is an api key rather than a login token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+//Allows - whether the key has the scope
+func (key *APIKey) Allows(scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//Validate - check a key before it's saved
+func (key APIKey) Validate(now time.Time) error {
+	return validate(
+		required("name", key.Name),
+		apiKeyScopes("scopes", key.Scopes),
+	)
+}
+
+func apiKeyScopes(field string, scopes []string) rule {
+	return func() *FieldError {
+		if len(scopes) == 0 {
+			return &FieldError{field, "is required"}
+		}
+		for _, scope := range scopes {
+			if fieldErr := oneOf(field, scope, APIKeyScopes)(); fieldErr != nil {
+				return &FieldError{field, fmt.Sprintf("%s isn't a scope, %s", scope, fieldErr.Message)}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package goparent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	key, secret, err := NewAPIKey(&User{ID: "1"}, " diaper button ", []string{ScopeWasteWrite}, now)
+	assert.Nil(t, err)
+	assert.True(t, IsAPIKey(secret))
+	assert.False(t, IsAPIKey("eyJhbGciOiJIUzI1NiJ9.e30.sig"))
+	assert.Equal(t, "1", key.UserID)
+	assert.Equal(t, "diaper button", key.Name)
+	assert.Equal(t, secret[:len(key.Prefix)], key.Prefix)
+	assert.Equal(t, HashAPIKey(secret), key.Hash)
+	assert.NotContains(t, key.Hash, secret)
+	assert.Equal(t, now, key.CreatedAt)
+	assert.True(t, key.Allows(ScopeWasteWrite))
+	assert.False(t, key.Allows(ScopeWasteRead))
+
+	_, other, _ := NewAPIKey(&User{ID: "1"}, "other", []string{ScopeWasteWrite}, now)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestAPIKeyValidate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		key    APIKey
+		fields []string
+	}{
+		{
+			desc: "valid",
+			key:  APIKey{Name: "tablet", Scopes: []string{ScopeChildrenRead, ScopeSleepToggle}},
+		},
+		{
+			desc:   "no name or scopes",
+			key:    APIKey{},
+			fields: []string{"name", "scopes"},
+		},
+		{
+			desc:   "unknown scope",
+			key:    APIKey{Name: "tablet", Scopes: []string{ScopeChildrenRead, "admin"}},
+			fields: []string{"scopes"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.key.Validate(time.Now())
+			if tC.fields == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var fields []string
+			if verr, ok := err.(*ValidationError); assert.True(t, ok) {
+				for _, f := range verr.Fields {
+					fields = append(fields, f.Field)
+				}
+			}
+			assert.Equal(t, tC.fields, fields)
+		})
+	}
+}
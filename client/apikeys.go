@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+//APIKeys - the user's api keys, the keys themselves aren't included
+func (c *Client) APIKeys(ctx context.Context) ([]*goparent.APIKey, error) {
+	var keys api.APIKeysResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/apikeys"}, &keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys.Keys, nil
+}
+
+//CreateAPIKey - make an api key with the scopes.  the returned key is only shown once, use
+// it with WithAPIKey.
+func (c *Client) CreateAPIKey(ctx context.Context, name string, scopes ...string) (*goparent.APIKey, string, error) {
+	var created api.APIKeyResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/apikeys", body: api.APIKeyRequest{Name: name, Scopes: scopes}}, &created)
+	if err != nil {
+		return nil, "", err
+	}
+	return created.KeyData, created.Key, nil
+}
+
+//RevokeAPIKey - delete an api key, it stops working straight away
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/user/apikeys/" + url.PathEscape(id)}, nil)
+}
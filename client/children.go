@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+//children and their events use the v2 api, everything about a child is under its path
+func childPath(childID string, parts ...string) string {
+	path := "/v2/children/" + url.PathEscape(childID)
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+//data - decode the data of a v2 response into v
+func data(v interface{}) *api.Envelope {
+	return &api.Envelope{Data: v}
+}
+
+//Children - the children in the user's family
+func (c *Client) Children(ctx context.Context) ([]*goparent.Child, error) {
+	var children []*goparent.Child
+	err := c.do(ctx, request{method: http.MethodGet, path: "/v2/children"}, data(&children))
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+//Child - a child in the user's family
+func (c *Client) Child(ctx context.Context, id string) (*goparent.Child, error) {
+	var child goparent.Child
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(id)}, data(&child))
+	if err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+//CreateChild - add a child to the user's family
+func (c *Client) CreateChild(ctx context.Context, child *goparent.Child) (*goparent.Child, error) {
+	var created goparent.Child
+	err := c.do(ctx, request{method: http.MethodPost, path: "/v2/children", body: data(child)}, data(&created))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+//UpdateChild - change a child's name and birthday
+func (c *Client) UpdateChild(ctx context.Context, child *goparent.Child) (*goparent.Child, error) {
+	var updated goparent.Child
+	err := c.do(ctx, request{method: http.MethodPut, path: childPath(child.ID), body: data(child)}, data(&updated))
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+//DeleteChild - delete a child
+func (c *Client) DeleteChild(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: childPath(id)}, nil)
+}
+
+//sharePath - a child's share with another family, shares are managed through the v1 api
+func sharePath(childID string, familyID string) string {
+	return "/children/" + url.PathEscape(childID) + "/shares/" + url.PathEscape(familyID)
+}
+
+//ChildShares - the families a child in the user's family is shared with
+func (c *Client) ChildShares(ctx context.Context, childID string) ([]goparent.ChildShare, error) {
+	var shares api.ChildSharesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/children/" + url.PathEscape(childID) + "/shares"}, &shares)
+	if err != nil {
+		return nil, err
+	}
+	return shares.Shares, nil
+}
+
+//ShareChild - link a child into another family, permission is goparent.ShareView or
+// goparent.ShareLog
+func (c *Client) ShareChild(ctx context.Context, childID string, familyID string, permission string) (*goparent.Child, error) {
+	form := url.Values{"permission": {permission}}
+	var child goparent.Child
+	err := c.do(ctx, request{method: http.MethodPut, path: sharePath(childID, familyID), form: form}, &child)
+	if err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+//AcceptShare - take a child another family shared with the user's family, familyID is
+// the user's family
+func (c *Client) AcceptShare(ctx context.Context, childID string, familyID string) (*goparent.Child, error) {
+	var child goparent.Child
+	err := c.do(ctx, request{method: http.MethodPut, path: sharePath(childID, familyID) + "/accept"}, &child)
+	if err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+//UnshareChild - stop sharing a child with a family, or turn down a share the user's family
+// hasn't accepted
+func (c *Client) UnshareChild(ctx context.Context, childID string, familyID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: sharePath(childID, familyID)}, nil)
+}
+
+//ChildSummary - feeding, sleep and waste stats for a child
+func (c *Client) ChildSummary(ctx context.Context, childID string, q *Query) (*api.Summary, error) {
+	var summary api.Summary
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "summary"), query: q.values(), header: q.header()}, data(&summary))
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+//Feedings - a child's recent feedings, q.Days and q.Units are used
+func (c *Client) Feedings(ctx context.Context, childID string, q *Query) ([]*goparent.Feeding, error) {
+	var feedings []*goparent.Feeding
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "feedings"), query: q.values()}, data(&feedings))
+	if err != nil {
+		return nil, err
+	}
+	return feedings, nil
+}
+
+//CreateFeeding - record a feeding for feeding.ChildID
+func (c *Client) CreateFeeding(ctx context.Context, feeding *goparent.Feeding) (*goparent.Feeding, error) {
+	var created goparent.Feeding
+	err := c.do(ctx, request{method: http.MethodPost, path: childPath(feeding.ChildID, "feedings"), body: data(feeding)}, data(&created))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+//FeedingGraph - a child's feedings bucketed for graphs
+func (c *Client) FeedingGraph(ctx context.Context, childID string, q *Query) (*goparent.FeedingChartData, error) {
+	var graph goparent.FeedingChartData
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "feedings", "graph"), query: q.values(), header: q.header()}, data(&graph))
+	if err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+//Sleeps - a child's recent sleeps, q.Days is used
+func (c *Client) Sleeps(ctx context.Context, childID string, q *Query) ([]*goparent.Sleep, error) {
+	var sleeps []*goparent.Sleep
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "sleeps"), query: q.values()}, data(&sleeps))
+	if err != nil {
+		return nil, err
+	}
+	return sleeps, nil
+}
+
+//CreateSleep - record a finished sleep for sleep.ChildID
+func (c *Client) CreateSleep(ctx context.Context, sleep *goparent.Sleep) (*goparent.Sleep, error) {
+	var created goparent.Sleep
+	err := c.do(ctx, request{method: http.MethodPost, path: childPath(sleep.ChildID, "sleeps"), body: data(sleep)}, data(&created))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+//SleepGraph - a child's sleeps bucketed for graphs
+func (c *Client) SleepGraph(ctx context.Context, childID string, q *Query) (*goparent.SleepChartData, error) {
+	var graph goparent.SleepChartData
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "sleeps", "graph"), query: q.values(), header: q.header()}, data(&graph))
+	if err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+//SleepStatus - whether a child has a sleep going
+func (c *Client) SleepStatus(ctx context.Context, childID string) (*api.SleepStatus, error) {
+	return c.sleepActive(ctx, http.MethodGet, childID)
+}
+
+//StartSleep - start a sleep for a child now, an api.CodeSleepAlreadyActive error means
+// one is already going.
+func (c *Client) StartSleep(ctx context.Context, childID string) (*api.SleepStatus, error) {
+	return c.sleepActive(ctx, http.MethodPost, childID)
+}
+
+//EndSleep - end a child's sleep now, an api.CodeNoSleepSession error means there wasn't one
+func (c *Client) EndSleep(ctx context.Context, childID string) (*api.SleepStatus, error) {
+	return c.sleepActive(ctx, http.MethodDelete, childID)
+}
+
+func (c *Client) sleepActive(ctx context.Context, method string, childID string) (*api.SleepStatus, error) {
+	var status api.SleepStatus
+	err := c.do(ctx, request{method: method, path: childPath(childID, "sleeps", "active")}, data(&status))
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+//Wastes - a child's recent wastes, q.Days is used
+func (c *Client) Wastes(ctx context.Context, childID string, q *Query) ([]*goparent.Waste, error) {
+	var wastes []*goparent.Waste
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "wastes"), query: q.values()}, data(&wastes))
+	if err != nil {
+		return nil, err
+	}
+	return wastes, nil
+}
+
+//CreateWaste - record a waste for waste.ChildID
+func (c *Client) CreateWaste(ctx context.Context, waste *goparent.Waste) (*goparent.Waste, error) {
+	var created goparent.Waste
+	err := c.do(ctx, request{method: http.MethodPost, path: childPath(waste.ChildID, "wastes"), body: data(waste)}, data(&created))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+//WasteGraph - a child's wastes bucketed for graphs
+func (c *Client) WasteGraph(ctx context.Context, childID string, q *Query) (*goparent.WasteChartData, error) {
+	var graph goparent.WasteChartData
+	err := c.do(ctx, request{method: http.MethodGet, path: childPath(childID, "wastes", "graph"), query: q.values(), header: q.header()}, data(&graph))
+	if err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
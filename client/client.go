@@ -0,0 +1,334 @@
+//Package client is a Go client for the goparent api.  it logs in, keeps its token fresh
+// and turns error responses into *Error values carrying the service's error codes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+//RefreshWindow - tokens this close to expiring are swapped for new ones before they're used
+const RefreshWindow = time.Minute
+
+//ErrNoCredentials - the client has no token and no username and password to get one
+var ErrNoCredentials = errors.New("no token or credentials to log in with")
+
+//ErrTwoFactorRequired - the user has two factor turned on and the client has no way to get a code
+var ErrTwoFactorRequired = errors.New("login needs a two factor code, use WithTwoFactor")
+
+//Client - a client for a goparent service
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Username   string
+	Password   string
+	//FamilyID - the family requests are for, the user's current family if it's blank
+	FamilyID string
+	//TwoFactor - gets an authenticator or recovery code when the user has two factor turned on
+	TwoFactor func(context.Context) (string, error)
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	user    *goparent.User
+}
+
+//Option - configures a Client
+type Option func(*Client)
+
+//WithCredentials - log in as this user whenever a token is needed
+func WithCredentials(username string, password string) Option {
+	return func(c *Client) {
+		c.Username = username
+		c.Password = password
+	}
+}
+
+//WithTwoFactor - ask code for an authenticator or recovery code whenever a login needs one
+func WithTwoFactor(code func(context.Context) (string, error)) Option {
+	return func(c *Client) {
+		c.TwoFactor = code
+	}
+}
+
+//WithToken - start with an existing token instead of logging in
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.setToken(token, nil)
+	}
+}
+
+//WithAPIKey - authenticate with an api key instead of logging in.  keys don't expire, so
+// they're never refreshed, and they can only make the calls their scopes allow.
+func WithAPIKey(key string) Option {
+	return WithToken(key)
+}
+
+//WithFamily - make requests for one of the user's families instead of their current one
+func WithFamily(familyID string) Option {
+	return func(c *Client) {
+		c.FamilyID = familyID
+	}
+}
+
+//WithHTTPClient - send requests with hc instead of http.DefaultClient
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.HTTPClient = hc
+	}
+}
+
+//New - a client for the service at baseURL, e.g. http://localhost:8000
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+//User - the user the client is logged in as, nil until the first login or refresh
+func (c *Client) User() *goparent.User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.user
+}
+
+//Token - a token for the next request.  it logs in if there isn't one, and refreshes it
+// if it's about to expire.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	//tokens we can't read the expiry of are used until the service turns them down
+	if c.token != "" && (c.expires.IsZero() || time.Until(c.expires) > RefreshWindow) {
+		return c.token, nil
+	}
+	if c.token != "" && time.Now().Before(c.expires) {
+		err := c.refreshLocked(ctx)
+		if err == nil {
+			return c.token, nil
+		}
+	}
+	if c.Username == "" {
+		if c.token != "" {
+			return c.token, nil
+		}
+		return "", ErrNoCredentials
+	}
+	err := c.loginLocked(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.token, nil
+}
+
+//setToken - keep the token and when it expires.  the signature isn't checked, only the
+// service can do that.
+func (c *Client) setToken(token string, user *goparent.User) {
+	c.token = token
+	c.expires = time.Time{}
+	if user != nil {
+		c.user = user
+	}
+	claims := jwt.MapClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	if err != nil {
+		return
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		c.expires = time.Unix(int64(exp), 0)
+	}
+}
+
+func (c *Client) loginLocked(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("username", c.Username)
+	form.Set("password", c.Password)
+	var auth api.UserAuthResponse
+	err := c.send(ctx, request{method: http.MethodPost, path: "/user/login", form: form}, "", &auth)
+	if err != nil {
+		return err
+	}
+	if auth.TwoFactorRequired {
+		if c.TwoFactor == nil {
+			return ErrTwoFactorRequired
+		}
+		code, err := c.TwoFactor(ctx)
+		if err != nil {
+			return err
+		}
+		form = url.Values{"challenge": {auth.Challenge}, "code": {code}}
+		err = c.send(ctx, request{method: http.MethodPost, path: "/user/login/2fa", form: form}, "", &auth)
+		if err != nil {
+			return err
+		}
+	}
+	c.setToken(auth.Token, auth.UserData)
+	return nil
+}
+
+func (c *Client) refreshLocked(ctx context.Context) error {
+	var auth api.UserAuthResponse
+	err := c.send(ctx, request{method: http.MethodPost, path: "/user/refresh"}, c.token, &auth)
+	if err != nil {
+		return err
+	}
+	c.setToken(auth.Token, auth.UserData)
+	return nil
+}
+
+//request - a call to the api, path is relative to /api
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	form   url.Values
+	body   interface{}
+	public bool
+}
+
+//do - make an api call and decode the response into out.  if the service turns down the
+// token the client logs in again and retries once.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	if req.public {
+		return c.send(ctx, req, "", out)
+	}
+
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.send(ctx, req, token, out)
+	if !IsCode(err, api.CodeUnauthorized) || c.Username == "" {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.token == token {
+		c.token = ""
+	}
+	c.mu.Unlock()
+	token, err = c.Token(ctx)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, req, token, out)
+}
+
+func (c *Client) send(ctx context.Context, req request, token string, out interface{}) error {
+	u := c.BaseURL + "/api" + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch {
+	case req.form != nil:
+		body = strings.NewReader(req.form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case req.body != nil:
+		js, err := json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(js)
+		contentType = "application/json"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+		if c.FamilyID != "" && httpReq.Header.Get("X-Family-ID") == "" {
+			httpReq.Header.Set("X-Family-ID", c.FamilyID)
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return newError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+//Query - the window and units for lists, summaries and graphs.  zero fields are left to
+// the service defaults.
+type Query struct {
+	Start       time.Time
+	End         time.Time
+	Days        int
+	Granularity goparent.Granularity
+	Units       goparent.UnitSystem
+	Timezone    string
+}
+
+func (q *Query) values() url.Values {
+	v := url.Values{}
+	if q == nil {
+		return v
+	}
+	if !q.Start.IsZero() {
+		v.Set("start", q.Start.Format(time.RFC3339))
+	}
+	if !q.End.IsZero() {
+		v.Set("end", q.End.Format(time.RFC3339))
+	}
+	if q.Days > 0 {
+		v.Set("days", fmt.Sprint(q.Days))
+	}
+	if q.Granularity != "" {
+		v.Set("granularity", string(q.Granularity))
+	}
+	if q.Units != "" {
+		v.Set("units", string(q.Units))
+	}
+	return v
+}
+
+func (q *Query) header() http.Header {
+	h := http.Header{}
+	if q != nil && q.Timezone != "" {
+		h.Set("X-Timezone", q.Timezone)
+	}
+	return h
+}
@@ -0,0 +1,668 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("client test key")
+
+//testUserService - signs real tokens so the api's auth middleware can check them
+type testUserService struct {
+	*mock.UserService
+	user      *goparent.User
+	logins    int
+	refreshes int
+}
+
+func (s *testUserService) User(ctx context.Context, id string) (*goparent.User, error) {
+	if id != s.user.ID {
+		return nil, goparent.ErrNoUserFound
+	}
+	return s.user, nil
+}
+
+func (s *testUserService) UserByLogin(ctx context.Context, username string, password string) (*goparent.User, error) {
+	if (username != s.user.Username && username != s.user.Email) || password != "secret" {
+		return nil, goparent.ErrNoUserFound
+	}
+	s.logins++
+	return s.user, nil
+}
+
+func (s *testUserService) GetToken(user *goparent.User, duration time.Duration) (string, error) {
+	if duration > time.Hour {
+		s.refreshes++
+	}
+	return signToken(user, duration)
+}
+
+func signToken(user *goparent.User, duration time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"ID":  user.ID,
+		"exp": time.Now().Add(duration).Unix(),
+	})
+	return token.SignedString(testKey)
+}
+
+func newTestServer(h *api.Handler) (*httptest.Server, *testUserService) {
+	users := &testUserService{
+		UserService: &mock.UserService{Family: &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}}},
+		user:        &goparent.User{ID: "1", Name: "test user", Email: "testuser@test.com", Username: "testuser"},
+	}
+	if h.Env == nil {
+		h.Env = &goparent.Env{}
+	}
+	h.Env.DB = &mock.DBEnv{}
+	h.Env.Auth = goparent.Authentication{SigningKey: testKey}
+	h.UserService = users
+	return httptest.NewServer(api.BuildAPIRouting(h)), users
+}
+
+func TestAuthentication(t *testing.T) {
+	user := &goparent.User{ID: "1"}
+	expiring, _ := signToken(user, 30*time.Second)
+	expired, _ := signToken(user, -time.Minute)
+	fresh, _ := signToken(user, time.Hour)
+
+	testCases := []struct {
+		desc      string
+		options   []Option
+		err       error
+		errCode   string
+		logins    int
+		refreshes int
+	}{
+		{
+			desc:    "logs in on first call",
+			options: []Option{WithCredentials("testuser", "secret")},
+			logins:  1,
+		},
+		{
+			desc:    "uses a token it's given",
+			options: []Option{WithToken(fresh)},
+		},
+		{
+			desc:      "refreshes a token that's about to expire",
+			options:   []Option{WithToken(expiring)},
+			refreshes: 1,
+		},
+		{
+			desc:    "logs in again when the token has expired",
+			options: []Option{WithToken(expired), WithCredentials("testuser", "secret")},
+			logins:  1,
+		},
+		{
+			desc:    "logs in again when the service turns the token down",
+			options: []Option{WithToken("not a token"), WithCredentials("testuser", "secret")},
+			logins:  1,
+		},
+		{
+			desc:    "expired token and no credentials",
+			options: []Option{WithToken(expired)},
+			errCode: api.CodeUnauthorized,
+		},
+		{
+			desc:    "wrong password",
+			options: []Option{WithCredentials("testuser", "wrong")},
+			errCode: api.CodeInvalidLogin,
+		},
+		{
+			desc: "no credentials",
+			err:  ErrNoCredentials,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			server, users := newTestServer(&api.Handler{FamilyService: &mock.FamilyService{Kids: []*goparent.Child{{ID: "1", Name: "Billy"}}}})
+			defer server.Close()
+
+			c := New(server.URL, tC.options...)
+			children, err := c.Children(context.Background())
+			switch {
+			case tC.err != nil:
+				assert.Equal(t, tC.err, err)
+			case tC.errCode != "":
+				assert.True(t, IsCode(err, tC.errCode), "%v", err)
+			default:
+				assert.Nil(t, err)
+				assert.Len(t, children, 1)
+			}
+			assert.Equal(t, tC.logins, users.logins)
+			assert.Equal(t, tC.refreshes, users.refreshes)
+			if tC.logins > 0 || tC.refreshes > 0 {
+				assert.Equal(t, "1", c.User().ID)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	child := &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}
+	server, _ := newTestServer(&api.Handler{
+		ChildService:   &mock.ChildService{Kid: child},
+		FeedingService: &mock.FeedingService{},
+		SleepService:   &mock.SleepService{StartErr: goparent.ErrExistingStart},
+	})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	_, err := c.StartSleep(ctx, "1")
+	assert.True(t, IsCode(err, api.CodeSleepAlreadyActive))
+	if apiErr, ok := err.(*Error); assert.True(t, ok) {
+		assert.Equal(t, 409, apiErr.StatusCode)
+		assert.NotEmpty(t, apiErr.RequestID)
+	}
+
+	_, err = c.CreateFeeding(ctx, &goparent.Feeding{ChildID: "1", Type: "soda", TimeStamp: time.Now()})
+	assert.True(t, IsCode(err, api.CodeValidation))
+	if apiErr, ok := err.(*Error); assert.True(t, ok) {
+		assert.Equal(t, []goparent.FieldError{{Field: "feedingType", Message: "must be one of bottle, breast, solid"}}, apiErr.Fields)
+	}
+
+	otherServer, _ := newTestServer(&api.Handler{ChildService: &mock.ChildService{Kid: &goparent.Child{ID: "2", FamilyID: "2"}}})
+	defer otherServer.Close()
+	_, err = New(otherServer.URL, WithCredentials("testuser", "secret")).Child(ctx, "2")
+	assert.True(t, IsCode(err, api.CodeChildNotFound))
+}
+
+func TestEndpoints(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	child := &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}
+	server, _ := newTestServer(&api.Handler{
+		FamilyService:         &mock.FamilyService{Kids: []*goparent.Child{child}},
+		UserInvitationService: &mock.UserInvitationService{},
+		ChildService:          &mock.ChildService{Kid: child, Deleted: 1},
+		FeedingService: &mock.FeedingService{
+			Feedings: []*goparent.Feeding{{ID: "1", ChildID: "1", Type: "bottle", Amount: 120, Unit: goparent.UnitMilliliter}},
+			Stat:     &goparent.FeedingSummary{},
+			Graph:    &goparent.FeedingChartData{Granularity: goparent.GranularityHour},
+		},
+		SleepService: &mock.SleepService{
+			Stat:      &goparent.SleepSummary{Total: 3600},
+			GetStatus: true,
+			GetSleep:  &goparent.Sleep{ID: "1", ChildID: "1", Start: now.Add(-time.Hour)},
+		},
+		WasteService: &mock.WasteService{
+			Wastes: []*goparent.Waste{{ID: "1", ChildID: "1", Type: 1}, {ID: "2", ChildID: "2", Type: 1}},
+			Stat:   &goparent.WasteSummary{},
+			Graph:  &goparent.WasteChartData{Granularity: goparent.GranularityDay},
+		},
+	})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	info, err := c.Info(ctx)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, info.Version)
+
+	me, err := c.Me(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "testuser", me.UserData.Username)
+	assert.Equal(t, "1", me.FamilyData.ID)
+
+	err = c.Refresh(ctx)
+	assert.Nil(t, err)
+
+	family, err := c.Family(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", family.ID)
+
+	family.Units = goparent.UnitSystemMetric
+	family, err = c.UpdateFamily(ctx, family)
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.UnitSystemMetric, family.Units)
+
+	_, err = c.Invites(ctx)
+	assert.Nil(t, err)
+	invite, err := c.Invite(ctx, "other@test.com", goparent.RoleCaregiver)
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.RoleCaregiver, invite.Role)
+
+	created, err := c.CreateChild(ctx, &goparent.Child{Name: "Sally", Birthday: now.AddDate(-1, 0, 0)})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", created.FamilyID)
+
+	got, err := c.Child(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Billy", got.Name)
+
+	got.Name = "William"
+	updated, err := c.UpdateChild(ctx, got)
+	assert.Nil(t, err)
+	assert.Equal(t, "William", updated.Name)
+
+	summary, err := c.ChildSummary(ctx, "1", &Query{Days: 3, Timezone: "America/Los_Angeles"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3600), summary.Sleep.Total)
+
+	feedings, err := c.Feedings(ctx, "1", &Query{Units: goparent.UnitSystemImperial})
+	assert.Nil(t, err)
+	if assert.Len(t, feedings, 1) {
+		assert.Equal(t, goparent.UnitFluidOunce, feedings[0].Unit)
+	}
+
+	feeding, err := c.CreateFeeding(ctx, &goparent.Feeding{ChildID: "1", Type: "bottle", Amount: 4, TimeStamp: now})
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.UnitFluidOunce, feeding.Unit)
+
+	feedingGraph, err := c.FeedingGraph(ctx, "1", &Query{Granularity: goparent.GranularityHour})
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.GranularityHour, feedingGraph.Granularity)
+
+	status, err := c.SleepStatus(ctx, "1")
+	assert.Nil(t, err)
+	assert.True(t, status.Active)
+	assert.Equal(t, "1", status.Sleep.ID)
+
+	status, err = c.EndSleep(ctx, "1")
+	assert.Nil(t, err)
+	assert.False(t, status.Active)
+
+	sleep, err := c.CreateSleep(ctx, &goparent.Sleep{ChildID: "1", Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", sleep.ChildID)
+
+	wastes, err := c.Wastes(ctx, "1", nil)
+	assert.Nil(t, err)
+	assert.Len(t, wastes, 1)
+
+	waste, err := c.CreateWaste(ctx, &goparent.Waste{ChildID: "1", Type: 2, TimeStamp: now})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, waste.Type)
+
+	wasteGraph, err := c.WasteGraph(ctx, "1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.GranularityDay, wasteGraph.Granularity)
+
+	assert.Nil(t, c.DeleteChild(ctx, "1"))
+}
+
+func TestEvents(t *testing.T) {
+	server, _ := newTestServer(&api.Handler{})
+	defer server.Close()
+	_, err := New(server.URL, WithCredentials("testuser", "secret")).Events(context.Background())
+	assert.True(t, IsCode(err, api.CodeUnavailable))
+
+	bus := goparent.NewEventBus()
+	server, _ = newTestServer(&api.Handler{Env: &goparent.Env{Events: bus}})
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := New(server.URL, WithCredentials("testuser", "secret")).Events(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus.Publish(goparent.Event{Type: goparent.EventCreated, Resource: goparent.ResourceFeeding, FamilyID: "1", ChildID: "1", Data: &goparent.Feeding{ID: "1"}})
+	event, ok := <-events
+	if assert.True(t, ok) {
+		assert.Equal(t, "feeding.created", event.Name())
+		assert.Equal(t, "1", event.ChildID)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	received := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(goparent.WebhookEventHeader)
+	}))
+	defer receiver.Close()
+
+	webhooks := &mock.WebhookService{WebhookID: "1"}
+	server, _ := newTestServer(&api.Handler{WebhookService: webhooks})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	webhook, err := c.CreateWebhook(ctx, &goparent.Webhook{URL: receiver.URL, Events: []string{"sleep.*"}})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, webhook.Secret)
+	webhooks.GetWebhook = webhook
+	webhooks.GetWebhooks = []*goparent.Webhook{webhook}
+
+	list, err := c.Webhooks(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Empty(t, list[0].Secret)
+	}
+
+	delivery, err := c.TestWebhook(ctx, "1")
+	assert.Nil(t, err)
+	assert.True(t, delivery.Succeeded())
+	assert.Equal(t, "webhook.ping", <-received)
+
+	webhooks.GetDeliveries = webhooks.Saved
+	deliveries, err := c.WebhookDeliveries(ctx, "1")
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1)
+
+	disabled, err := c.DisableWebhook(ctx, "1")
+	assert.Nil(t, err)
+	assert.True(t, disabled.Disabled)
+
+	webhooks.GetWebhook = nil
+	_, err = c.DisableWebhook(ctx, "2")
+	assert.True(t, IsCode(err, api.CodeWebhookNotFound))
+}
+
+func TestReminders(t *testing.T) {
+	ctx := context.Background()
+	reminders := &mock.ReminderService{RuleID: "1"}
+	server, _ := newTestServer(&api.Handler{
+		ReminderService: reminders,
+		ChildService:    &mock.ChildService{Kid: &goparent.Child{ID: "1", FamilyID: "1"}},
+	})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	rule, err := c.CreateReminder(ctx, &goparent.ReminderRule{ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 180})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", rule.ID)
+	reminders.GetRule = rule
+	reminders.GetRules = []*goparent.ReminderRule{rule}
+
+	list, err := c.Reminders(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	rule.Interval = 120
+	rule, err = c.UpdateReminder(ctx, rule)
+	assert.Nil(t, err)
+	assert.Equal(t, 120, rule.Interval)
+
+	assert.Nil(t, c.DeleteReminder(ctx, "1"))
+	assert.Len(t, reminders.Deleted, 1)
+
+	reminders.GetNotification = &goparent.Notification{ID: "1", FamilyID: "1", Status: goparent.NotificationDelivered}
+	reminders.GetNotifications = []*goparent.Notification{reminders.GetNotification}
+	inbox, err := c.Notifications(ctx, true)
+	assert.Nil(t, err)
+	assert.Len(t, inbox, 1)
+
+	snoozed, err := c.SnoozeNotification(ctx, "1", 30)
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.NotificationSnoozed, snoozed.Status)
+
+	acknowledged, err := c.AcknowledgeNotification(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.NotificationAcknowledged, acknowledged.Status)
+
+	reminders.GetNotification = nil
+	_, err = c.AcknowledgeNotification(ctx, "2")
+	assert.True(t, IsCode(err, api.CodeNotificationNotFound))
+}
+
+func TestInvites(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	invites := &mock.UserInvitationService{
+		GetInvite: &goparent.UserInvitation{ID: "2", UserID: "1", InviteEmail: "new@test.com", Role: goparent.RoleMember, Status: goparent.InvitationPending, Timestamp: now, ExpiresAt: now.Add(time.Hour)},
+	}
+	server, _ := newTestServer(&api.Handler{UserInvitationService: invites})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	resent, err := c.ResendInvite(ctx, "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "new@test.com", resent.InviteEmail)
+	assert.Nil(t, c.DeclineInvite(ctx, "2"))
+
+	invites.DeclineErr = goparent.ErrInvitationExpired
+	err = c.DeclineInvite(ctx, "2")
+	assert.True(t, IsCode(err, api.CodeInvitationExpired))
+
+	anon := New(server.URL)
+	preview, err := anon.InvitePreview(ctx, "code")
+	assert.Nil(t, err)
+	assert.Equal(t, "test user", preview.From)
+	assert.Equal(t, goparent.InvitationPending, preview.Status)
+
+	user, err := anon.SignUpWithInvite(ctx, "code", "New Parent", "newparent", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "new@test.com", user.Email)
+	assert.Equal(t, "newparent", anon.Username)
+	assert.NotEmpty(t, anon.token)
+}
+
+func TestFamilies(t *testing.T) {
+	ctx := context.Background()
+	server, users := newTestServer(&api.Handler{})
+	defer server.Close()
+	users.UserService.Families = []*goparent.Family{
+		{ID: "1", Admin: "1", Members: []string{"1"}},
+		{ID: "2", Admin: "2", Members: []string{"2", "1"}},
+	}
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	families, err := c.Families(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, families.FamilyData, 2)
+
+	switched, err := c.SwitchFamily(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", switched.UserData.CurrentFamily)
+	assert.Equal(t, "1", c.User().CurrentFamily)
+
+	//a family the user isn't in is turned down
+	users.UserService.Family = &goparent.Family{ID: "3", Admin: "3", Members: []string{"3"}}
+	other := New(server.URL, WithCredentials("testuser", "secret"), WithFamily("3"))
+	_, err = other.Family(ctx)
+	assert.True(t, IsCode(err, api.CodeForbidden))
+}
+
+func TestFamilyMembership(t *testing.T) {
+	ctx := context.Background()
+	families := &mock.FamilyService{Kids: []*goparent.Child{{ID: "1"}}}
+	server, users := newTestServer(&api.Handler{FamilyService: families})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	//the admin has to hand the family over before leaving
+	err := c.LeaveFamily(ctx)
+	assert.True(t, IsCode(err, api.CodeFamilyAdmin))
+	err = c.RemoveMember(ctx, "2")
+	assert.True(t, IsCode(err, api.CodeNotFamilyMember))
+
+	users.UserService.Family = &goparent.Family{ID: "1", Admin: "1", Members: []string{"1", "2"}}
+	family, err := c.TransferFamily(ctx, "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", family.Admin)
+	err = c.LeaveFamily(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, families.Removed, 1)
+
+	users.UserService.Family = &goparent.Family{ID: "1", Admin: "1", Members: []string{"1"}}
+	err = c.DeleteFamily(ctx, "garbage")
+	assert.True(t, IsCode(err, api.CodeConfirmationRequired))
+	confirm, err := c.DeleteFamilyConfirmation(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, confirm.Children)
+	err = c.DeleteFamily(ctx, confirm.Confirm)
+	assert.Nil(t, err)
+	assert.Len(t, families.Deleted, 1)
+}
+
+func TestAccount(t *testing.T) {
+	ctx := context.Background()
+	mailer := &mock.Mailer{}
+	server, users := newTestServer(&api.Handler{Env: &goparent.Env{Mailer: mailer}})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	updated, err := c.UpdateProfile(ctx, "new name", "testuser")
+	assert.Nil(t, err)
+	assert.Equal(t, "new name", updated.UserData.Name)
+	_, err = c.UpdateProfile(ctx, "", "testuser")
+	assert.True(t, IsCode(err, api.CodeValidation))
+
+	err = c.ChangeEmail(ctx, "new@test.com", "wrong")
+	assert.True(t, IsCode(err, api.CodeInvalidLogin))
+	err = c.ChangeEmail(ctx, "new@test.com", "secret")
+	assert.Nil(t, err)
+	if assert.Len(t, mailer.Sent, 1) {
+		assert.Equal(t, []string{"new@test.com"}, mailer.Sent[0].To)
+	}
+
+	_, err = c.ConfirmEmail(ctx, "garbage")
+	assert.True(t, IsCode(err, api.CodeInvalidToken))
+
+	_, err = c.VerifyEmail(ctx, "garbage")
+	assert.True(t, IsCode(err, api.CodeInvalidToken))
+	err = c.ResendVerification(ctx)
+	assert.True(t, IsCode(err, api.CodeConflict))
+	users.user.Unverified = true
+	users.user.VerificationSentAt = time.Now()
+	err = c.ResendVerification(ctx)
+	assert.True(t, IsCode(err, api.CodeTooManyRequests))
+	if apiErr, ok := err.(*Error); assert.True(t, ok) {
+		assert.Equal(t, 5*time.Minute, apiErr.RetryAfter)
+	}
+	users.user.Unverified = false
+
+	err = c.DeleteAccount(ctx, "wrong")
+	assert.True(t, IsCode(err, api.CodeInvalidLogin))
+	err = c.DeleteAccount(ctx, "secret")
+	assert.Nil(t, err)
+	assert.Len(t, users.UserService.Deleted, 1)
+}
+
+func TestTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	server, users := newTestServer(&api.Handler{})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	enroll, err := c.EnrollTwoFactor(ctx, "secret")
+	assert.Nil(t, err)
+	code, _ := goparent.TOTPCode(enroll.Secret, time.Now())
+	recovery, err := c.ConfirmTwoFactor(ctx, code)
+	assert.Nil(t, err)
+	assert.Len(t, recovery, goparent.RecoveryCodeCount)
+	assert.True(t, users.user.TOTPEnabled)
+
+	//logging in needs a code now
+	_, err = New(server.URL, WithCredentials("testuser", "secret")).Login(ctx)
+	assert.Equal(t, ErrTwoFactorRequired, err)
+	wrong := New(server.URL, WithCredentials("testuser", "secret"), WithTwoFactor(func(context.Context) (string, error) {
+		return "000000", nil
+	}))
+	_, err = wrong.Login(ctx)
+	assert.True(t, IsCode(err, api.CodeInvalidTwoFactorCode))
+	withCode := New(server.URL, WithCredentials("testuser", "secret"), WithTwoFactor(func(context.Context) (string, error) {
+		return recovery[0], nil
+	}))
+	user, err := withCode.Login(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", user.ID)
+
+	err = withCode.DisableTwoFactor(ctx, "secret", recovery[1])
+	assert.Nil(t, err)
+	assert.False(t, users.user.TOTPEnabled)
+}
+
+func TestChildShares(t *testing.T) {
+	ctx := context.Background()
+	child := &goparent.Child{ID: "1", Name: "Billy", FamilyID: "1"}
+	server, _ := newTestServer(&api.Handler{
+		ChildService:  &mock.ChildService{Kid: child},
+		FamilyService: &mock.FamilyService{GetFamily: &goparent.Family{ID: "2", Admin: "2", Members: []string{"2"}}},
+	})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	_, err := c.ShareChild(ctx, "1", "2", "edit")
+	assert.True(t, IsCode(err, api.CodeValidation))
+	shared, err := c.ShareChild(ctx, "1", "2", goparent.ShareView)
+	assert.Nil(t, err)
+	assert.Len(t, shared.Shares, 1)
+
+	shares, err := c.ChildShares(ctx, "1")
+	assert.Nil(t, err)
+	if assert.Len(t, shares, 1) {
+		assert.Equal(t, goparent.ShareView, shares[0].Permission)
+		assert.True(t, shares[0].Pending, "the user isn't in the other family so it has to accept")
+	}
+	_, err = c.AcceptShare(ctx, "1", "2")
+	assert.True(t, IsCode(err, api.CodeForbidden), "only the other family can accept")
+
+	err = c.UnshareChild(ctx, "1", "2")
+	assert.Nil(t, err)
+	err = c.UnshareChild(ctx, "1", "2")
+	assert.True(t, IsCode(err, api.CodeShareNotFound))
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := &mock.APIKeyService{KeyID: "key-1"}
+	server, _ := newTestServer(&api.Handler{
+		APIKeyService: keys,
+		FamilyService: &mock.FamilyService{Kids: []*goparent.Child{{ID: "1", Name: "Billy"}}},
+	})
+	defer server.Close()
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	key, secret, err := c.CreateAPIKey(ctx, "kitchen tablet", goparent.ScopeChildrenRead)
+	assert.Nil(t, err)
+	assert.Equal(t, "key-1", key.ID)
+	assert.True(t, goparent.IsAPIKey(secret))
+	keys.GetKey = keys.Saved[0]
+	keys.GetKeys = keys.Saved
+
+	list, err := c.APIKeys(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	keyClient := New(server.URL, WithAPIKey(secret))
+	kids, err := keyClient.Children(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, kids, 1)
+	_, err = keyClient.APIKeys(ctx)
+	assert.True(t, IsCode(err, api.CodeForbidden), "keys can't manage keys")
+
+	err = c.RevokeAPIKey(ctx, "key-1")
+	assert.Nil(t, err)
+	assert.Len(t, keys.Deleted, 1)
+}
+
+func TestIdentities(t *testing.T) {
+	ctx := context.Background()
+	identities := &mock.IdentityService{}
+	identities.Save(ctx, &goparent.ExternalIdentity{UserID: "1", Provider: "stub", Issuer: "https://idp.test", Subject: "a"})
+	server, users := newTestServer(&api.Handler{
+		Env:             &goparent.Env{OIDC: map[string]*goparent.OIDCProvider{"stub": {Name: "stub"}}},
+		IdentityService: identities,
+	})
+	defer server.Close()
+	users.user.Password = "secret"
+	c := New(server.URL, WithCredentials("testuser", "secret"))
+
+	providers, err := c.OIDCProviders(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stub"}, providers)
+
+	linked, err := c.Identities(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, linked, 1) {
+		assert.Equal(t, "stub", linked[0].Provider)
+		err = c.UnlinkIdentity(ctx, linked[0].ID)
+		assert.Nil(t, err)
+	}
+	err = c.UnlinkIdentity(ctx, "nope")
+	assert.True(t, IsCode(err, api.CodeIdentityNotFound))
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+//Error - an error response from the service.  Code is one of the api.Code constants,
+// switch on it rather than the message.  RetryAfter is how long the service asked the
+// client to wait, when it did.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Fields     []goparent.FieldError
+	RequestID  string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("goparent: %d %s: %s (request %s)", e.StatusCode, e.Code, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("goparent: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+//IsCode - whether err is an error response with the code
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+//newError - read the error body of a response.  responses that don't have one, from a
+// proxy in front of the service say, get a code from their status.
+func newError(resp *http.Response) error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Code:       statusCode(resp.StatusCode),
+		Message:    http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return apiErr
+	}
+	var errBody api.ErrService
+	if json.Unmarshal(body, &errBody) == nil && errBody.ErrMessage.Code != "" {
+		apiErr.Code = errBody.ErrMessage.Code
+		apiErr.Message = errBody.ErrMessage.Message
+		apiErr.Fields = errBody.ErrMessage.Fields
+		if errBody.ErrMessage.RequestID != "" {
+			apiErr.RequestID = errBody.ErrMessage.RequestID
+		}
+	}
+	return apiErr
+}
+
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return api.CodeBadRequest
+	case http.StatusUnauthorized:
+		return api.CodeUnauthorized
+	case http.StatusForbidden:
+		return api.CodeForbidden
+	case http.StatusNotFound:
+		return api.CodeNotFound
+	case http.StatusConflict:
+		return api.CodeConflict
+	case http.StatusTooManyRequests:
+		return api.CodeTooManyRequests
+	case http.StatusNotImplemented:
+		return api.CodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return api.CodeUnavailable
+	}
+	return api.CodeInternal
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sasimpson/goparent"
+)
+
+//Events - watch the events in the user's family.  the channel is closed when ctx is done
+// or the service ends the stream, an HTTPClient with a Timeout will end it early.
+func (c *Client) Events(ctx context.Context) (<-chan goparent.Event, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+
+	events := make(chan goparent.Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		readEvents(ctx, bufio.NewScanner(resp.Body), events)
+	}()
+	return events, nil
+}
+
+//readEvents - send each server sent event's data on events.  comments, ids and event
+// names are skipped, the event name is in the data as well.
+func readEvents(ctx context.Context, scanner *bufio.Scanner, events chan<- goparent.Event) {
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var event goparent.Event
+		err := json.Unmarshal([]byte(data.String()), &event)
+		data.Reset()
+		if err != nil {
+			continue
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
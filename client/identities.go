@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+//OIDCProviders - the identity providers users can log in with.  logging in with one has to
+// happen in a browser, starting at /api/user/oidc/{provider}/login.
+func (c *Client) OIDCProviders(ctx context.Context) ([]string, error) {
+	var providers api.OIDCProvidersResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/oidc", public: true}, &providers)
+	if err != nil {
+		return nil, err
+	}
+	return providers.Providers, nil
+}
+
+//Identities - the identity providers linked to the user
+func (c *Client) Identities(ctx context.Context) ([]*goparent.ExternalIdentity, error) {
+	var identities api.IdentitiesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/identities"}, &identities)
+	if err != nil {
+		return nil, err
+	}
+	return identities.Identities, nil
+}
+
+//UnlinkIdentity - unlink an identity provider from the user
+func (c *Client) UnlinkIdentity(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/user/identities/" + url.PathEscape(id)}, nil)
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+func reminderPath(id string) string {
+	return "/reminders/" + url.PathEscape(id)
+}
+
+func notificationPath(id string, action string) string {
+	return "/notifications/" + url.PathEscape(id) + "/" + action
+}
+
+//Reminders - the family's reminder rules
+func (c *Client) Reminders(ctx context.Context) ([]*goparent.ReminderRule, error) {
+	var reminders api.RemindersResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/reminders"}, &reminders)
+	if err != nil {
+		return nil, err
+	}
+	return reminders.Reminders, nil
+}
+
+//CreateReminder - add a reminder rule for one of the family's children
+func (c *Client) CreateReminder(ctx context.Context, rule *goparent.ReminderRule) (*goparent.ReminderRule, error) {
+	var created api.ReminderResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/reminders", body: api.ReminderRequest{ReminderData: *rule}}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return created.ReminderData, nil
+}
+
+//UpdateReminder - replace a reminder rule
+func (c *Client) UpdateReminder(ctx context.Context, rule *goparent.ReminderRule) (*goparent.ReminderRule, error) {
+	var updated api.ReminderResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: reminderPath(rule.ID), body: api.ReminderRequest{ReminderData: *rule}}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated.ReminderData, nil
+}
+
+//DeleteReminder - delete a reminder rule
+func (c *Client) DeleteReminder(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: reminderPath(id)}, nil)
+}
+
+//Notifications - the family's inbox, newest first.  acknowledged notifications are only
+// included when all is set.
+func (c *Client) Notifications(ctx context.Context, all bool) ([]*goparent.Notification, error) {
+	var query url.Values
+	if all {
+		query = url.Values{"all": {"true"}}
+	}
+	var notifications api.NotificationsResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/notifications", query: query}, &notifications)
+	if err != nil {
+		return nil, err
+	}
+	return notifications.Notifications, nil
+}
+
+//SnoozeNotification - deliver a notification again after minutes, 0 uses the service's default
+func (c *Client) SnoozeNotification(ctx context.Context, id string, minutes int) (*goparent.Notification, error) {
+	var snoozed api.NotificationResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: notificationPath(id, "snooze"), body: api.SnoozeRequest{Minutes: minutes}}, &snoozed)
+	if err != nil {
+		return nil, err
+	}
+	return snoozed.NotificationData, nil
+}
+
+//AcknowledgeNotification - mark a notification as dealt with
+func (c *Client) AcknowledgeNotification(ctx context.Context, id string) (*goparent.Notification, error) {
+	var acknowledged api.NotificationResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: notificationPath(id, "ack")}, &acknowledged)
+	if err != nil {
+		return nil, err
+	}
+	return acknowledged.NotificationData, nil
+}
@@ -0,0 +1,336 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+//Info - the service version and host, it doesn't need a login
+func (c *Client) Info(ctx context.Context) (*api.ServiceInfo, error) {
+	var info api.ServiceInfo
+	err := c.do(ctx, request{method: http.MethodGet, path: "/info", public: true}, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+//Login - log in with the client's credentials, the token is used for the calls after it
+func (c *Client) Login(ctx context.Context) (*goparent.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Username == "" {
+		return nil, ErrNoCredentials
+	}
+	err := c.loginLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.user, nil
+}
+
+//Refresh - swap the current token for a long lived one
+func (c *Client) Refresh(ctx context.Context) error {
+	_, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshLocked(ctx)
+}
+
+//SignUp - create a new user, log in with the username and password afterwards
+func (c *Client) SignUp(ctx context.Context, name string, email string, username string, password string) (*goparent.User, error) {
+	var newUser api.NewUserRequest
+	newUser.UserData.Name = name
+	newUser.UserData.Email = email
+	newUser.UserData.Username = username
+	newUser.UserData.Password = password
+
+	var user goparent.User
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/", body: newUser, public: true}, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+//Me - the logged in user and their current family
+func (c *Client) Me(ctx context.Context) (*api.UserResponse, error) {
+	var me api.UserResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/"}, &me)
+	if err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+//UpdateProfile - change the logged in user's name and username
+func (c *Client) UpdateProfile(ctx context.Context, name string, username string) (*api.UserResponse, error) {
+	var updated api.UserResponse
+	body := api.UserRequest{UserData: &goparent.User{Name: name, Username: username}}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/user/", body: body}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.user = updated.UserData
+	c.mu.Unlock()
+	return &updated, nil
+}
+
+//ChangeEmail - mail a token to the new address, the email changes once it's confirmed
+func (c *Client) ChangeEmail(ctx context.Context, email string, password string) error {
+	form := url.Values{"email": {email}, "password": {password}}
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/email", form: form}, nil)
+}
+
+//ConfirmEmail - change a user's email with the token mailed to the new address
+func (c *Client) ConfirmEmail(ctx context.Context, token string) (*goparent.User, error) {
+	form := url.Values{"token": {token}}
+	var confirmed api.UserResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/email/confirm", form: form, public: true}, &confirmed)
+	if err != nil {
+		return nil, err
+	}
+	return confirmed.UserData, nil
+}
+
+//VerifyEmail - verify a new user's email with the token mailed to it
+func (c *Client) VerifyEmail(ctx context.Context, token string) (*goparent.User, error) {
+	form := url.Values{"token": {token}}
+	var verified api.UserResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/verify", form: form, public: true}, &verified)
+	if err != nil {
+		return nil, err
+	}
+	return verified.UserData, nil
+}
+
+//ResendVerification - mail the logged in user another verification token
+func (c *Client) ResendVerification(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/verify/resend"}, nil)
+}
+
+//EnrollTwoFactor - start turning on two factor, set up an authenticator with the secret or
+// uri and confirm it with ConfirmTwoFactor
+func (c *Client) EnrollTwoFactor(ctx context.Context, password string) (*api.TwoFactorEnrollResponse, error) {
+	form := url.Values{"password": {password}}
+	var enroll api.TwoFactorEnrollResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/2fa", form: form}, &enroll)
+	if err != nil {
+		return nil, err
+	}
+	return &enroll, nil
+}
+
+//ConfirmTwoFactor - turn on two factor with a code from the new authenticator, the
+// recovery codes returned aren't shown again
+func (c *Client) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	form := url.Values{"code": {code}}
+	var recovery api.RecoveryCodesResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/2fa/confirm", form: form}, &recovery)
+	if err != nil {
+		return nil, err
+	}
+	return recovery.RecoveryCodes, nil
+}
+
+//DisableTwoFactor - turn off two factor
+func (c *Client) DisableTwoFactor(ctx context.Context, password string, code string) error {
+	form := url.Values{"password": {password}, "code": {code}}
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/2fa/disable", form: form}, nil)
+}
+
+//NewRecoveryCodes - replace the user's recovery codes
+func (c *Client) NewRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	form := url.Values{"code": {code}}
+	var recovery api.RecoveryCodesResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/2fa/recovery", form: form}, &recovery)
+	if err != nil {
+		return nil, err
+	}
+	return recovery.RecoveryCodes, nil
+}
+
+//DeleteAccount - delete the logged in user's account
+func (c *Client) DeleteAccount(ctx context.Context, password string) error {
+	form := url.Values{"password": {password}}
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/delete", form: form}, nil)
+}
+
+//RequestPasswordReset - email a reset code to the user with this address
+func (c *Client) RequestPasswordReset(ctx context.Context, email string) error {
+	form := url.Values{"email": {email}}
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/resetpassword", form: form, public: true}, nil)
+}
+
+//ResetPassword - set a new password with a reset code
+func (c *Client) ResetPassword(ctx context.Context, code string, password string) error {
+	form := url.Values{"password": {password}}
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/resetpassword/" + url.PathEscape(code), form: form, public: true}, nil)
+}
+
+//Invites - invites the user has sent, and ones waiting for them to accept
+func (c *Client) Invites(ctx context.Context) (*api.InvitesResponse, error) {
+	var invites api.InvitesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/invite"}, &invites)
+	if err != nil {
+		return nil, err
+	}
+	return &invites, nil
+}
+
+//Invite - invite another parent to the user's family, role is member or caregiver and
+// defaults to member
+func (c *Client) Invite(ctx context.Context, email string, role string) (*goparent.UserInvitation, error) {
+	form := url.Values{"email": {email}}
+	if role != "" {
+		form.Set("role", role)
+	}
+	var invite api.InviteResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/invite", form: form}, &invite)
+	if err != nil {
+		return nil, err
+	}
+	return invite.InviteData, nil
+}
+
+//ResendInvite - mail an invite again, the old code stops working
+func (c *Client) ResendInvite(ctx context.Context, id string) (*goparent.UserInvitation, error) {
+	var invite api.InviteResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/invite/" + url.PathEscape(id) + "/resend"}, &invite)
+	if err != nil {
+		return nil, err
+	}
+	return invite.InviteData, nil
+}
+
+//AcceptInvite - join the family of the parent that sent the invite
+func (c *Client) AcceptInvite(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/invite/accept/" + url.PathEscape(id)}, nil)
+}
+
+//DeclineInvite - turn down an invite
+func (c *Client) DeclineInvite(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/user/invite/decline/" + url.PathEscape(id)}, nil)
+}
+
+//InvitePreview - who sent the invite a code was mailed for, it doesn't need a login
+func (c *Client) InvitePreview(ctx context.Context, code string) (*api.InvitePreviewResponse, error) {
+	var preview api.InvitePreviewResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/invite/token/" + url.PathEscape(code), public: true}, &preview)
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+//SignUpWithInvite - create a user with the email an invite code was mailed to and join
+// the inviting family.  the client is logged in as the new user afterwards.
+func (c *Client) SignUpWithInvite(ctx context.Context, code string, name string, username string, password string) (*goparent.User, error) {
+	signup := api.InviteSignupRequest{Token: code, Name: name, Username: username, Password: password}
+	var auth api.UserAuthResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user/signup/invite", body: signup, public: true}, &auth)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Username = username
+	c.Password = password
+	c.setToken(auth.Token, auth.UserData)
+	return auth.UserData, nil
+}
+
+//DeleteInvite - take back an invite
+func (c *Client) DeleteInvite(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/user/invite/" + url.PathEscape(id)}, nil)
+}
+
+//Family - the user's current family
+func (c *Client) Family(ctx context.Context) (*goparent.Family, error) {
+	var family api.FamilyResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/family"}, &family)
+	if err != nil {
+		return nil, err
+	}
+	return family.FamilyData, nil
+}
+
+//Families - every family the user is a member of, and the id of their current one
+func (c *Client) Families(ctx context.Context) (*api.FamiliesResponse, error) {
+	var families api.FamiliesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/families"}, &families)
+	if err != nil {
+		return nil, err
+	}
+	return &families, nil
+}
+
+//SwitchFamily - make another of the user's families their current family
+func (c *Client) SwitchFamily(ctx context.Context, familyID string) (*api.UserResponse, error) {
+	form := url.Values{"familyID": {familyID}}
+	var switched api.UserResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: "/user/family", form: form}, &switched)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.user = switched.UserData
+	c.mu.Unlock()
+	return &switched, nil
+}
+
+//UpdateFamily - change the family's time zone and units, only the admin can do this
+func (c *Client) UpdateFamily(ctx context.Context, family *goparent.Family) (*goparent.Family, error) {
+	var updated api.FamilyResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: "/family", body: api.FamilyRequest{FamilyData: api.FamilySettings{Timezone: &family.Timezone, Units: &family.Units}}}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated.FamilyData, nil
+}
+
+//RemoveMember - take someone out of the family, only the admin can do this
+func (c *Client) RemoveMember(ctx context.Context, userID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/family/members/" + url.PathEscape(userID)}, nil)
+}
+
+//LeaveFamily - leave the family, the admin has to transfer it to someone else first
+func (c *Client) LeaveFamily(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/family/leave"}, nil)
+}
+
+//TransferFamily - make another member the family admin
+func (c *Client) TransferFamily(ctx context.Context, userID string) (*goparent.Family, error) {
+	form := url.Values{"userID": {userID}}
+	var transferred api.FamilyResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: "/family/admin", form: form}, &transferred)
+	if err != nil {
+		return nil, err
+	}
+	return transferred.FamilyData, nil
+}
+
+//DeleteFamilyConfirmation - what deleting the family will remove, with the confirmation
+// DeleteFamily needs
+func (c *Client) DeleteFamilyConfirmation(ctx context.Context) (*api.FamilyDeleteResponse, error) {
+	var confirm api.FamilyDeleteResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/family/delete"}, &confirm)
+	if err != nil {
+		return nil, err
+	}
+	return &confirm, nil
+}
+
+//DeleteFamily - delete the family and everything in it, confirm is from
+// DeleteFamilyConfirmation
+func (c *Client) DeleteFamily(ctx context.Context, confirm string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/family", query: url.Values{"confirm": {confirm}}}, nil)
+}
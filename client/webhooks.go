@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/api"
+)
+
+func webhookPath(id string, action string) string {
+	return "/webhooks/" + url.PathEscape(id) + "/" + action
+}
+
+//Webhooks - the family's webhooks, secrets aren't included
+func (c *Client) Webhooks(ctx context.Context) ([]*goparent.Webhook, error) {
+	var webhooks api.WebhooksResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/webhooks"}, &webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks.Webhooks, nil
+}
+
+//CreateWebhook - register a webhook, only the family admin can.  keep the returned secret
+// to check the signatures on deliveries, it isn't shown again.
+func (c *Client) CreateWebhook(ctx context.Context, webhook *goparent.Webhook) (*goparent.Webhook, error) {
+	var created api.WebhookResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/webhooks", body: api.WebhookRequest{WebhookData: *webhook}}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return created.WebhookData, nil
+}
+
+//TestWebhook - send a webhook.ping event once and get back how the receiver answered
+func (c *Client) TestWebhook(ctx context.Context, id string) (*goparent.WebhookDelivery, error) {
+	var delivery api.WebhookDeliveryResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: webhookPath(id, "test")}, &delivery)
+	if err != nil {
+		return nil, err
+	}
+	return delivery.Delivery, nil
+}
+
+//DisableWebhook - stop sending events to a webhook
+func (c *Client) DisableWebhook(ctx context.Context, id string) (*goparent.Webhook, error) {
+	var disabled api.WebhookResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: webhookPath(id, "disable")}, &disabled)
+	if err != nil {
+		return nil, err
+	}
+	return disabled.WebhookData, nil
+}
+
+//WebhookDeliveries - recent delivery attempts for a webhook, newest first
+func (c *Client) WebhookDeliveries(ctx context.Context, id string) ([]*goparent.WebhookDelivery, error) {
+	var deliveries api.WebhookDeliveriesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: webhookPath(id, "deliveries")}, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries.Deliveries, nil
+}
@@ -1,20 +1,14 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"flag"
 	"fmt"
-	"io"
 	"math/rand"
-	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
 	"github.com/sasimpson/goparent"
-	"github.com/sasimpson/goparent/api"
+	"github.com/sasimpson/goparent/client"
 )
 
 var (
@@ -31,6 +25,7 @@ var (
 	mode      string
 	userData  *goparent.User
 	loc       time.Location
+	apiClient *client.Client
 )
 
 const dateParseString = "2006-01-02"
@@ -70,6 +65,15 @@ func main() {
 
 	fmt.Println(startDate)
 
+	var options []client.Option
+	if user != "" {
+		options = append(options, client.WithCredentials(user, password))
+	}
+	if token != "" {
+		options = append(options, client.WithToken(token))
+	}
+	apiClient = client.New(fmt.Sprintf("http://%s:%d", host, port), options...)
+
 	//make sure service is up:
 	health := healthCheck()
 	if health != nil {
@@ -206,21 +210,16 @@ func generateRandomDiaper(child *goparent.Child, genDate time.Time) error {
 	genDate = time.Date(genDate.Year(), genDate.Month(), genDate.Day(), 0, 0, 0, 0, loc)
 	for x := 0; x < numberOfEntries; x++ {
 		randomTime := time.Unix(genDate.Unix()+r.Int63n(86400), 0)
+		//the service rejects entries in the future
+		if randomTime.After(time.Now()) {
+			continue
+		}
 		diaper := goparent.Waste{
 			TimeStamp: randomTime,
 			ChildID:   child.ID,
 			Type:      r.Intn(3) + 1,
 		}
-		wasteRequest := api.WasteRequest{
-			WasteData: diaper,
-		}
-
-		js, err := json.Marshal(&wasteRequest)
-		if err != nil {
-			return newError("generateRandomDiaper()", err)
-		}
-		var wasteResponse api.WasteRequest
-		err = makeRequest(http.MethodPost, "waste", bytes.NewReader(js), false, &wasteResponse)
+		_, err := apiClient.CreateWaste(context.Background(), &diaper)
 		if err != nil {
 			return newError("generateRandomDiaper()", err)
 		}
@@ -258,6 +257,7 @@ func generateRandomFeeding(child *goparent.Child, genDate time.Time) error {
 				Type:      feedingType,
 				Side:      "right",
 				Amount:    float32(r.Intn(29) + 1),
+				Unit:      goparent.UnitMinute,
 				ChildID:   child.ID,
 			}
 			feeding2 := feeding
@@ -269,21 +269,17 @@ func generateRandomFeeding(child *goparent.Child, genDate time.Time) error {
 				TimeStamp: time.Unix(startDate.Unix()+randomInterval, 0),
 				Type:      feedingType,
 				Amount:    float32(r.Intn(7) + 1),
+				Unit:      goparent.UnitFluidOunce,
 				ChildID:   child.ID,
 			}
 			feedings = append(feedings, feeding)
 		}
-		//save the feeding to the service
-		feedingRequest := api.FeedingRequest{
-			FeedingData: feeding,
-		}
-		js, err := json.Marshal(&feedingRequest)
-		if err != nil {
-			return newError("generateRandomFeeding()", err)
+		//the service rejects entries in the future
+		if feeding.TimeStamp.After(time.Now()) {
+			break
 		}
-
-		var feedingResponse api.FeedingRequest
-		err = makeRequest(http.MethodPost, "feeding", bytes.NewReader(js), false, &feedingResponse)
+		//save the feeding to the service
+		_, err := apiClient.CreateFeeding(context.Background(), &feeding)
 		if err != nil {
 			return newError("generateRandomFeeding()", err)
 		}
@@ -307,15 +303,11 @@ func generateRandomSleeps(child *goparent.Child, genDate time.Time) error {
 			Start:   time.Unix(startDate.Unix()+randomInterval, 0),
 			End:     time.Unix(startDate.Unix()+randomInterval+(5400+r.Int63n(1800)), 0),
 		}
-		sleepRequest := api.SleepRequest{
-			SleepData: sleep,
-		}
-		js, err := json.Marshal(&sleepRequest)
-		if err != nil {
-			return newError("generateRandomSleeps()", err)
+		//the service rejects entries in the future
+		if sleep.End.After(time.Now()) {
+			break
 		}
-		var sleepResponse api.SleepRequest
-		err = makeRequest(http.MethodPost, "sleep", bytes.NewReader(js), false, &sleepResponse)
+		_, err := apiClient.CreateSleep(context.Background(), &sleep)
 		if err != nil {
 			return newError("generateRandomSleeps()", err)
 		}
@@ -324,7 +316,7 @@ func generateRandomSleeps(child *goparent.Child, genDate time.Time) error {
 }
 
 func healthCheck() error {
-	err := makeRequest(http.MethodGet, "info", nil, false, nil)
+	_, err := apiClient.Info(context.Background())
 	if err != nil {
 		return newError("healthCheck()", err)
 	}
@@ -332,22 +324,20 @@ func healthCheck() error {
 }
 
 func getToken() error {
-	formData := url.Values{}
-	formData.Add("username", user)
-	formData.Add("password", password)
-	var authData api.UserAuthResponse
-	err := makeRequest(http.MethodPost, "user/login", strings.NewReader(formData.Encode()), true, &authData)
+	var err error
+	userData, err = apiClient.Login(context.Background())
+	if err != nil {
+		return newError("getToken()", err)
+	}
+	token, err = apiClient.Token(context.Background())
 	if err != nil {
 		return newError("getToken()", err)
 	}
-	token = authData.Token
-	userData = authData.UserData
 	return nil
 }
 
 func validateToken() error {
-	var userResponse api.UserResponse
-	err := makeRequest(http.MethodGet, "user/", nil, false, &userResponse)
+	userResponse, err := apiClient.Me(context.Background())
 	if err != nil {
 		return newError("validateToken()", err)
 	}
@@ -356,47 +346,17 @@ func validateToken() error {
 }
 
 func getChildren() ([]*goparent.Child, error) {
-	var childrenResp api.ChildrenResponse
-	err := makeRequest(http.MethodGet, "children", nil, false, &childrenResp)
+	children, err := apiClient.Children(context.Background())
 	if err != nil {
 		return nil, newError("getChildren()", err)
 	}
-	return childrenResp.Children, nil
+	return children, nil
 }
 
 func getChild(id string) (*goparent.Child, error) {
-	var child goparent.Child
-	err := makeRequest(http.MethodGet, fmt.Sprintf("children/%s", id), nil, false, &child)
+	child, err := apiClient.Child(context.Background(), id)
 	if err != nil {
 		return nil, newError("getChild()", err)
 	}
-	return &child, nil
-}
-
-func makeRequest(method string, path string, body io.Reader, form bool, thing interface{}) error {
-	client := &http.Client{}
-	req, err := http.NewRequest(method, fmt.Sprintf("http://%s:%d/api/%s", host, port, path), body)
-	if err != nil {
-		return newError("makeRequest()", err)
-	}
-	if form {
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	} else {
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return newError("makeRequest()", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode/200 != 1 {
-		return newError("makeRequest()", errors.New(resp.Status))
-	}
-	if thing != nil {
-		err = json.NewDecoder(resp.Body).Decode(thing)
-		if err != nil {
-			return newError("makeRequest()", err)
-		}
-	}
-	return nil
+	return child, nil
 }
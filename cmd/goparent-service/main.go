@@ -1,35 +1,88 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" //family time zones shouldn't depend on the container having zoneinfo
 
 	"github.com/gorilla/handlers"
 	"github.com/sasimpson/goparent"
 	"github.com/sasimpson/goparent/api"
+	"github.com/sasimpson/goparent/metrics"
 	"github.com/sasimpson/goparent/rethinkdb"
 )
 
 func main() {
 	env, dbenv := rethinkdb.InitRethinkDBConfig()
-	runService(env, dbenv)
+	//kubernetes sends SIGTERM when it wants the pod gone
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := runService(ctx, env, dbenv)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-//RunService - Runs service interfaces for app
-func runService(env *goparent.Env, dbenv *rethinkdb.DBEnv) {
+//RunService - Runs service interfaces for app until ctx is done, then drains the requests in
+// flight and closes the database session
+func runService(ctx context.Context, env *goparent.Env, dbenv *rethinkdb.DBEnv) error {
 	log.SetOutput(os.Stdout)
+	//the health checks go through env.DB, it has to be the session that's closed below
+	env.DB = dbenv
+	defer func() {
+		err := dbenv.Close()
+		if err != nil {
+			log.Printf("closing the database session: %s", err)
+		}
+	}()
+	env.Events = goparent.NewEventBus()
+	feed := &rethinkdb.ChangeFeed{DB: dbenv, Bus: env.Events}
+	go feed.Run(ctx)
+	m := metrics.New(dbenv)
+	webhookService := m.WebhookService(&rethinkdb.WebhookService{Env: env, DB: dbenv})
+	webhookDispatcher := &goparent.WebhookDispatcher{
+		Service: webhookService,
+		Leases:  m.LeaseStore(&rethinkdb.LeaseStore{DB: dbenv}),
+	}
+	go webhookDispatcher.Run(ctx, env.Events)
+
 	serviceHandler := api.Handler{
-		UserService:           &rethinkdb.UserService{Env: env, DB: dbenv},
-		UserInvitationService: &rethinkdb.UserInviteService{Env: env, DB: dbenv},
-		FamilyService:         &rethinkdb.FamilyService{Env: env, DB: dbenv},
-		ChildService:          &rethinkdb.ChildService{Env: env, DB: dbenv},
-		FeedingService:        &rethinkdb.FeedingService{Env: env, DB: dbenv},
-		SleepService:          &rethinkdb.SleepService{Env: env, DB: dbenv},
-		WasteService:          &rethinkdb.WasteService{Env: env, DB: dbenv},
+		UserService:           m.UserService(&rethinkdb.UserService{Env: env, DB: dbenv}),
+		UserInvitationService: m.UserInvitationService(&rethinkdb.UserInviteService{Env: env, DB: dbenv}),
+		FamilyService:         m.FamilyService(&rethinkdb.FamilyService{Env: env, DB: dbenv}),
+		ChildService:          m.ChildService(&rethinkdb.ChildService{Env: env, DB: dbenv}),
+		FeedingService:        m.FeedingService(&rethinkdb.FeedingService{Env: env, DB: dbenv}),
+		SleepService:          m.SleepService(&rethinkdb.SleepService{Env: env, DB: dbenv}),
+		WasteService:          m.WasteService(&rethinkdb.WasteService{Env: env, DB: dbenv}),
+		WebhookService:        webhookService,
+		WebhookDispatcher:     webhookDispatcher,
+		ReminderService:       m.ReminderService(&rethinkdb.ReminderService{Env: env, DB: dbenv}),
+		APIKeyService:         m.APIKeyService(&rethinkdb.APIKeyService{Env: env, DB: dbenv}),
+		IdentityService:       m.IdentityService(&rethinkdb.IdentityService{Env: env, DB: dbenv}),
+		RateLimits:            m.RateLimitStore(&rethinkdb.RateLimitStore{DB: dbenv}),
+		Metrics:               m,
+		EventSource:           feed,
 		Env:                   env,
 	}
 
+	scheduler := &goparent.ReminderScheduler{
+		Reminders: serviceHandler.ReminderService,
+		Children:  serviceHandler.ChildService,
+		Feedings:  serviceHandler.FeedingService,
+		Wastes:    serviceHandler.WasteService,
+		Sleeps:    serviceHandler.SleepService,
+		Channels: map[string]goparent.NotificationChannel{
+			goparent.ChannelWebhook: goparent.WebhookChannel(webhookDispatcher),
+			goparent.ChannelEmail:   goparent.EmailChannel(env.Mailer, serviceHandler.FamilyService, serviceHandler.UserService),
+		},
+	}
+	go scheduler.Run(ctx)
+
 	r := api.BuildAPIRouting(&serviceHandler)
 	// setup cors, this should end up in Env.Service config, which this should receive.
 	headersOk := handlers.AllowedHeaders([]string{"X-Requested-With", "Accept", "Content-Type", "Authorization", "Origin"})
@@ -38,8 +91,18 @@ func runService(env *goparent.Env, dbenv *rethinkdb.DBEnv) {
 
 	r.Use(simpleRequestLog)
 
-	log.Printf("starting service on 8000")
-	http.ListenAndServe(":8000", handlers.CORS(originsOk, headersOk, methodsOk)(r))
+	srv, err := newServer(env.Service, handlers.CORS(originsOk, headersOk, methodsOk)(r))
+	if err != nil {
+		return err
+	}
+	//event streams only end when the bus closes, they'd hold up the shutdown otherwise
+	srv.RegisterOnShutdown(env.Events.Close)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("starting service on %s, tls %t", ln.Addr(), env.Service.TLS.Enabled())
+	return serve(ctx, srv, env.Service, ln)
 }
 
 func simpleRequestLog(next http.Handler) http.Handler {
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/sasimpson/goparent"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+//newServer - the http server for the service config
+func newServer(service goparent.Service, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              service.Addr(),
+		Handler:           handler,
+		ReadTimeout:       service.ReadTimeout,
+		ReadHeaderTimeout: service.ReadHeaderTimeout,
+		WriteTimeout:      service.WriteTimeout,
+		IdleTimeout:       service.IdleTimeout,
+	}
+
+	tls := service.TLS
+	switch {
+	case tls.CertFile != "" || tls.KeyFile != "":
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			return nil, fmt.Errorf("service.tls needs both a certfile and a keyfile")
+		}
+	case len(tls.AutocertHosts) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tls.AutocertHosts...),
+			Cache:      autocert.DirCache(tls.AutocertDir),
+			Email:      tls.AutocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+	}
+	return srv, nil
+}
+
+//serve - serve on ln until ctx is done, then stop taking connections and give the requests
+// in flight ShutdownTimeout to finish
+func serve(ctx context.Context, srv *http.Server, service goparent.Service, ln net.Listener) error {
+	errs := make(chan error, 1)
+	go func() {
+		var err error
+		switch {
+		case service.TLS.CertFile != "":
+			err = srv.ServeTLS(ln, service.TLS.CertFile, service.TLS.KeyFile)
+		case srv.TLSConfig != nil:
+			err = srv.ServeTLS(ln, "", "")
+		default:
+			err = srv.Serve(ln)
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %s for requests to finish", service.ShutdownTimeout)
+	shutdownCtx := context.Background()
+	if service.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, service.ShutdownTimeout)
+		defer cancel()
+	}
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		//the requests that are left are cut off
+		srv.Close()
+	}
+	serveErr := <-errs
+	if err != nil {
+		return err
+	}
+	return serveErr
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServer(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		service goparent.Service
+		addr    string
+		tls     bool
+		err     bool
+	}{
+		{
+			desc:    "every interface",
+			service: goparent.Service{Port: 8000, ReadTimeout: time.Second, WriteTimeout: 2 * time.Second},
+			addr:    ":8000",
+		},
+		{
+			desc:    "host and port",
+			service: goparent.Service{Host: "127.0.0.1", Port: 9000},
+			addr:    "127.0.0.1:9000",
+		},
+		{
+			desc:    "cert without a key",
+			service: goparent.Service{Port: 443, TLS: goparent.ServiceTLS{CertFile: "cert.pem"}},
+			err:     true,
+		},
+		{
+			desc:    "autocert",
+			service: goparent.Service{Port: 443, TLS: goparent.ServiceTLS{AutocertHosts: []string{"goparent.test"}, AutocertDir: t.TempDir()}},
+			addr:    ":443",
+			tls:     true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			srv, err := newServer(tC.service, http.NotFoundHandler())
+			if tC.err {
+				assert.NotNil(t, err)
+				return
+			}
+			if assert.Nil(t, err) {
+				assert.Equal(t, tC.addr, srv.Addr)
+				assert.Equal(t, tC.service.ReadTimeout, srv.ReadTimeout)
+				assert.Equal(t, tC.service.WriteTimeout, srv.WriteTimeout)
+				assert.Equal(t, tC.tls, srv.TLSConfig != nil)
+			}
+		})
+	}
+}
+
+func TestServeDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv, _ := newServer(goparent.Service{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, goparent.Service{ShutdownTimeout: 5 * time.Second}, ln)
+	}()
+
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-served:
+		t.Fatal("stopped before the request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = http.Get("http://" + ln.Addr().String())
+	assert.NotNil(t, err, "new connections aren't taken while draining")
+
+	close(release)
+	assert.Equal(t, "done", <-responses)
+	assert.Nil(t, <-served)
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	srv, _ := newServer(goparent.Service{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, goparent.Service{ShutdownTimeout: 50 * time.Millisecond}, ln)
+	}()
+	go http.Get("http://" + ln.Addr().String())
+
+	<-started
+	cancel()
+	select {
+	case err := <-served:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stuck request held up the shutdown")
+	}
+}
@@ -0,0 +1,109 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sasimpson/goparent"
+	"google.golang.org/appengine/datastore"
+)
+
+//APIKeyService -
+type APIKeyService struct {
+	Env *goparent.Env
+}
+
+//APIKeyKind - the kind for api keys in gcp datastore
+const APIKeyKind = "APIKey"
+
+//Save creates or updates an api key, keys are children of their user
+func (s *APIKeyService) Save(ctx context.Context, key *goparent.APIKey) error {
+	if key.ID == "" {
+		key.ID = uuid.New().String()
+	}
+
+	_, err := datastore.Put(ctx, s.key(ctx, key), key)
+	if err != nil {
+		return NewError("APIKeyService.Save", err)
+	}
+	return nil
+}
+
+//APIKey returns the api key with the id
+func (s *APIKeyService) APIKey(ctx context.Context, id string) (*goparent.APIKey, error) {
+	key, err := s.first(ctx, datastore.NewQuery(APIKeyKind).Filter("ID =", id))
+	if err != nil {
+		return nil, NewError("APIKeyService.APIKey", err)
+	}
+	return key, nil
+}
+
+//APIKeyByHash returns the api key with the hash, for authenticating with it
+func (s *APIKeyService) APIKeyByHash(ctx context.Context, hash string) (*goparent.APIKey, error) {
+	key, err := s.first(ctx, datastore.NewQuery(APIKeyKind).Filter("Hash =", hash))
+	if err != nil {
+		return nil, NewError("APIKeyService.APIKeyByHash", err)
+	}
+	return key, nil
+}
+
+//APIKeys returns all of a user's api keys, oldest first
+func (s *APIKeyService) APIKeys(ctx context.Context, user *goparent.User) ([]*goparent.APIKey, error) {
+	var keys []*goparent.APIKey
+	userKey := datastore.NewKey(ctx, UserKind, user.ID, 0, nil)
+	q := datastore.NewQuery(APIKeyKind).Ancestor(userKey).Order("CreatedAt")
+	_, err := q.GetAll(ctx, &keys)
+	if err != nil {
+		return nil, NewError("APIKeyService.APIKeys", err)
+	}
+	return keys, nil
+}
+
+//Used records when the key was last used, a key that's been deleted is left deleted
+func (s *APIKeyService) Used(ctx context.Context, key *goparent.APIKey, at time.Time) error {
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		var saved goparent.APIKey
+		err := datastore.Get(tc, s.key(tc, key), &saved)
+		if err != nil {
+			return err
+		}
+		saved.LastUsed = at
+		_, err = datastore.Put(tc, s.key(tc, key), &saved)
+		return err
+	}, nil)
+	if err == datastore.ErrNoSuchEntity {
+		return nil
+	}
+	if err != nil {
+		return NewError("APIKeyService.Used", err)
+	}
+	key.LastUsed = at
+	return nil
+}
+
+//Delete revokes an api key
+func (s *APIKeyService) Delete(ctx context.Context, key *goparent.APIKey) error {
+	err := datastore.Delete(ctx, s.key(ctx, key))
+	if err != nil {
+		return NewError("APIKeyService.Delete", err)
+	}
+	return nil
+}
+
+func (s *APIKeyService) key(ctx context.Context, key *goparent.APIKey) *datastore.Key {
+	userKey := datastore.NewKey(ctx, UserKind, key.UserID, 0, nil)
+	return datastore.NewKey(ctx, APIKeyKind, key.ID, 0, userKey)
+}
+
+func (s *APIKeyService) first(ctx context.Context, q *datastore.Query) (*goparent.APIKey, error) {
+	var key goparent.APIKey
+	_, err := q.Run(ctx).Next(&key)
+	if err == datastore.Done {
+		return nil, goparent.ErrNoAPIKeyFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,51 @@
+package datastore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/datastore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestDatastoreAPIKey(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	defer done()
+	if err != nil {
+		t.Error("error", err)
+	}
+
+	keyService := datastore.APIKeyService{}
+	user := &goparent.User{ID: "user-1"}
+
+	nilKey, err := keyService.APIKeyByHash(ctx, "hash")
+	assert.Nil(t, nilKey)
+	assert.NotNil(t, err)
+
+	key, secret, err := goparent.NewAPIKey(user, "button", []string{goparent.ScopeWasteWrite}, time.Now())
+	assert.Nil(t, err)
+	err = keyService.Save(ctx, key)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, key.ID)
+
+	found, err := keyService.APIKeyByHash(ctx, goparent.HashAPIKey(secret))
+	assert.Nil(t, err)
+	assert.Equal(t, key.ID, found.ID)
+
+	at := time.Now().Truncate(time.Second)
+	err = keyService.Used(ctx, found, at)
+	assert.Nil(t, err)
+
+	keys, err := keyService.APIKeys(ctx, user)
+	assert.Nil(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.True(t, at.Equal(keys[0].LastUsed))
+	}
+
+	err = keyService.Delete(ctx, key)
+	assert.Nil(t, err)
+	_, err = keyService.APIKey(ctx, key.ID)
+	assert.NotNil(t, err)
+}
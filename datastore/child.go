@@ -22,7 +22,9 @@ func (s *ChildService) Save(ctx context.Context, child *goparent.Child) error {
 	var childKey *datastore.Key
 	//TODO: family id nil error here
 	familyKey := datastore.NewKey(ctx, FamilyKind, child.FamilyID, 0, nil)
+	eventType := goparent.EventUpdated
 	if child.ID == "" {
+		eventType = goparent.EventCreated
 		u := uuid.New()
 		childKey = datastore.NewKey(ctx, ChildKind, u.String(), 0, familyKey)
 		child.CreatedAt = time.Now()
@@ -35,6 +37,7 @@ func (s *ChildService) Save(ctx context.Context, child *goparent.Child) error {
 	if err != nil {
 		return NewError("ChildService.Save", err)
 	}
+	s.Env.Publish(goparent.Event{Type: eventType, Resource: goparent.ResourceChild, FamilyID: child.FamilyID, ChildID: child.ID, Data: child, SharedWith: shareFamilies(child)})
 
 	return nil
 }
@@ -51,6 +54,9 @@ func (s *ChildService) Child(ctx context.Context, id string) (*goparent.Child, e
 	// childKey := datastore.NewKey(ctx, ChildKind, id, 0, nil)
 	// err := datastore.Get(ctx, childKey, &child)
 
+	if err == datastore.Done {
+		return nil, NewError("ChildService.Child", goparent.ErrNoChildFound)
+	}
 	if err != nil {
 		return nil, NewError("ChildService.Child", err)
 	}
@@ -64,5 +70,47 @@ func (s *ChildService) Delete(ctx context.Context, child *goparent.Child) (int,
 	if err != nil {
 		return 0, err
 	}
+	s.Env.Publish(goparent.Event{Type: goparent.EventDeleted, Resource: goparent.ResourceChild, FamilyID: child.FamilyID, ChildID: child.ID, Data: child, SharedWith: shareFamilies(child)})
 	return 1, nil
 }
+
+//childFamilies - the families that see the records logged for a child, nil when there's
+// nothing to publish to or the child can't be found
+func childFamilies(ctx context.Context, env *goparent.Env, childID string) []string {
+	if env == nil || env.Events == nil {
+		return nil
+	}
+	cs := ChildService{Env: env}
+	child, err := cs.Child(ctx, childID)
+	if err != nil {
+		return nil
+	}
+	return child.Families()
+}
+
+//shareFamilies - every family the child is shared with, pending shares included so the
+// family a child is shared into hears about it and can accept
+func shareFamilies(child *goparent.Child) []string {
+	var families []string
+	for _, share := range child.Shares {
+		families = append(families, share.FamilyID)
+	}
+	return families
+}
+
+//sharedChildren - the children the family can see that are shared between families.
+// they're the only children other households can log records for.
+func sharedChildren(ctx context.Context, env *goparent.Env, family *goparent.Family) ([]*goparent.Child, error) {
+	fs := FamilyService{Env: env}
+	children, err := fs.Children(ctx, family)
+	if err != nil {
+		return nil, err
+	}
+	var shared []*goparent.Child
+	for _, child := range children {
+		if len(child.Shares) > 0 {
+			shared = append(shared, child)
+		}
+	}
+	return shared, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/sasimpson/goparent"
 	"google.golang.org/appengine"
 )
 
@@ -13,9 +14,10 @@ import (
 type DBEnv struct {
 }
 
-//GetConnection -
+//GetConnection - appengine connects for each request through its context, there is
+//nothing to hold open here
 func (db *DBEnv) GetConnection() error {
-	panic("not implemented")
+	return nil
 }
 
 //GetContext - appengine requires a context from the request, but we don't
@@ -42,6 +44,11 @@ func (e Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Origin, e.Message)
 }
 
+//Unwrap returns the original error so callers can use errors.Is and errors.As
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
 //NewError is the creator of the new errors
 func NewError(origin string, err error) error {
 	return Error{
@@ -51,9 +58,10 @@ func NewError(origin string, err error) error {
 	}
 }
 
-//RoundToDay helps round up or down to the nearest day.  pass true to round up, false to round down
-func RoundToDay(t time.Time, up bool) time.Time {
-	roundedTime := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+//RoundToDay helps round up or down to the nearest day in the passed location, so day
+//boundaries are local midnight.  pass true to round up, false to round down
+func RoundToDay(t time.Time, loc *time.Location, up bool) time.Time {
+	roundedTime := goparent.StartOfDay(t, loc)
 	//if we round up, add a day, then subtract a second to get the very end of the day.
 	if up == true {
 		roundedTime = roundedTime.AddDate(0, 0, 1)
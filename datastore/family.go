@@ -29,7 +29,9 @@ var (
 func (s *FamilyService) Save(ctx context.Context, family *goparent.Family) error {
 	var familyKey *datastore.Key
 	//if the family id is blank then we are creating a new family
+	eventType := goparent.EventUpdated
 	if family.ID == "" {
+		eventType = goparent.EventCreated
 		u := uuid.New()
 		familyKey = datastore.NewKey(ctx, FamilyKind, u.String(), 0, nil)
 		family.CreatedAt = time.Now()
@@ -44,6 +46,7 @@ func (s *FamilyService) Save(ctx context.Context, family *goparent.Family) error
 	if err != nil {
 		return NewError("FamilyService.Save", err)
 	}
+	s.Env.Publish(goparent.Event{Type: eventType, Resource: goparent.ResourceFamily, FamilyID: family.ID, Data: family})
 
 	return nil
 }
@@ -60,7 +63,7 @@ func (s *FamilyService) Family(ctx context.Context, id string) (*goparent.Family
 	return &family, nil
 }
 
-//Children -
+//Children - the family's children and the children other families have shared with it
 func (s *FamilyService) Children(ctx context.Context, family *goparent.Family) ([]*goparent.Child, error) {
 	var children []*goparent.Child
 	familyKey := datastore.NewKey(ctx, FamilyKind, family.ID, 0, nil)
@@ -80,6 +83,17 @@ func (s *FamilyService) Children(ctx context.Context, family *goparent.Family) (
 
 	}
 
+	var shared []*goparent.Child
+	_, err := datastore.NewQuery(ChildKind).Filter("Shares.FamilyID =", family.ID).GetAll(ctx, &shared)
+	if err != nil {
+		return nil, NewError("datastore.FamilyService.Children", err)
+	}
+	//shares the family hasn't accepted yet don't show it the child
+	for _, child := range shared {
+		if child.CanView(family.ID) {
+			children = append(children, child)
+		}
+	}
 	return children, nil
 }
 
@@ -100,6 +114,105 @@ func (s *FamilyService) AddMember(ctx context.Context, family *goparent.Family,
 	return nil
 }
 
+//RemoveMember - take a member out of the family, withdraw every invite they sent to it so
+// none can be resent and move them to another family if it was their current one
+func (s *FamilyService) RemoveMember(ctx context.Context, family *goparent.Family, member *goparent.User) error {
+	err := family.RemoveMember(member.ID)
+	if err != nil {
+		return err
+	}
+	err = s.Save(ctx, family)
+	if err != nil {
+		return NewError("datastore.FamilyService.RemoveMember", err)
+	}
+
+	userKey := datastore.NewKey(ctx, UserKind, member.ID, 0, nil)
+	keys, err := datastore.NewQuery(InviteKind).Ancestor(userKey).Filter("FamilyID =", family.ID).KeysOnly().GetAll(ctx, nil)
+	if err != nil {
+		return NewError("datastore.FamilyService.RemoveMember", err)
+	}
+	err = datastore.DeleteMulti(ctx, keys)
+	if err != nil {
+		return NewError("datastore.FamilyService.RemoveMember", err)
+	}
+
+	if member.CurrentFamily != family.ID {
+		return nil
+	}
+	return s.moveFrom(ctx, member, family.ID)
+}
+
+//deleteBatch - the most keys datastore deletes in one call
+const deleteBatch = 500
+
+//Delete - delete the family with everything recorded under it and the invites to it.
+// members whose current family it was are moved to another of theirs.
+func (s *FamilyService) Delete(ctx context.Context, family *goparent.Family) error {
+	familyKey := datastore.NewKey(ctx, FamilyKind, family.ID, 0, nil)
+	//an ancestor query finds the family itself as well as its children, records,
+	// webhooks and reminders
+	keys, err := datastore.NewQuery("").Ancestor(familyKey).KeysOnly().GetAll(ctx, nil)
+	if err != nil {
+		return NewError("datastore.FamilyService.Delete", err)
+	}
+	inviteKeys, err := datastore.NewQuery(InviteKind).Filter("FamilyID =", family.ID).KeysOnly().GetAll(ctx, nil)
+	if err != nil {
+		return NewError("datastore.FamilyService.Delete", err)
+	}
+	keys = append(keys, inviteKeys...)
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > deleteBatch {
+			n = deleteBatch
+		}
+		err = datastore.DeleteMulti(ctx, keys[:n])
+		if err != nil {
+			return NewError("datastore.FamilyService.Delete", err)
+		}
+		keys = keys[n:]
+	}
+
+	//children other families shared with this one are taken back out of it
+	var shared []*goparent.Child
+	sharedKeys, err := datastore.NewQuery(ChildKind).Filter("Shares.FamilyID =", family.ID).GetAll(ctx, &shared)
+	if err != nil {
+		return NewError("datastore.FamilyService.Delete", err)
+	}
+	for _, child := range shared {
+		child.Unshare(family.ID)
+	}
+	_, err = datastore.PutMulti(ctx, sharedKeys, shared)
+	if err != nil {
+		return NewError("datastore.FamilyService.Delete", err)
+	}
+	s.Env.Publish(goparent.Event{Type: goparent.EventDeleted, Resource: goparent.ResourceFamily, FamilyID: family.ID, Data: family})
+
+	var users []*goparent.User
+	_, err = datastore.NewQuery(UserKind).Filter("CurrentFamily =", family.ID).GetAll(ctx, &users)
+	if err != nil {
+		return NewError("datastore.FamilyService.Delete", err)
+	}
+	for _, user := range users {
+		err = s.moveFrom(ctx, user, family.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//moveFrom - switch the user to another family they belong to, saving a user without one
+// gives them a family of their own
+func (s *FamilyService) moveFrom(ctx context.Context, user *goparent.User, familyID string) error {
+	us := UserService{Env: s.Env}
+	families, err := us.GetAllFamily(ctx, user)
+	if err != nil {
+		return NewError("datastore.FamilyService.moveFrom", err)
+	}
+	user.CurrentFamily = goparent.NextFamily(user, families, familyID)
+	return us.Save(ctx, user)
+}
+
 //GetAdminFamily -
 func (s *FamilyService) GetAdminFamily(ctx context.Context, user *goparent.User) (*goparent.Family, error) {
 	var families []goparent.Family
@@ -2,6 +2,7 @@ package datastore
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"google.golang.org/appengine/datastore"
@@ -23,7 +24,11 @@ func (s *FeedingService) Save(ctx context.Context, feeding *goparent.Feeding) er
 	var feedKey *datastore.Key
 	familyKey := datastore.NewKey(ctx, FamilyKind, feeding.FamilyID, 0, nil)
 	childKey := datastore.NewKey(ctx, ChildKind, feeding.ChildID, 0, familyKey)
+	//always store the unit with the amount
+	feeding.Unit = feeding.AmountUnit()
+	eventType := goparent.EventUpdated
 	if feeding.ID == "" {
+		eventType = goparent.EventCreated
 		u := uuid.New()
 		feedKey = datastore.NewKey(ctx, FeedingKind, u.String(), 0, childKey)
 		feeding.CreatedAt = time.Now()
@@ -38,6 +43,7 @@ func (s *FeedingService) Save(ctx context.Context, feeding *goparent.Feeding) er
 	if err != nil {
 		return NewError("FeedingService.Save", err)
 	}
+	s.Env.Publish(goparent.Event{Type: eventType, Resource: goparent.ResourceFeeding, FamilyID: feeding.FamilyID, ChildID: feeding.ChildID, Data: feeding, SharedWith: childFamilies(ctx, s.Env, feeding.ChildID)})
 	return nil
 }
 
@@ -62,61 +68,50 @@ func (s *FeedingService) Feeding(ctx context.Context, family *goparent.Family, d
 		}
 		feedings = append(feedings, &feeding)
 	}
-	return feedings, nil
-}
-
-//Stats -
-func (s *FeedingService) Stats(ctx context.Context, child *goparent.Child) (*goparent.FeedingSummary, error) {
-	var feedings []goparent.Feeding
-	end := time.Now()
-	start := end.AddDate(0, 0, -1)
 
-	familyKey := datastore.NewKey(ctx, FamilyKind, child.FamilyID, 0, nil)
-	childKey := datastore.NewKey(ctx, ChildKind, child.ID, 0, familyKey)
-
-	q := datastore.NewQuery(FeedingKind).Filter("ChildID = ", childKey).Filter("TimeStamp >=", start).Order("-TimeStamp")
-	itx := q.Run(ctx)
-	for {
-		var feeding goparent.Feeding
-		_, err := itx.Next(&feeding)
-		if err == datastore.Done {
-			break
-		}
+	//feedings other households logged for children shared with or by the family
+	children, err := sharedChildren(ctx, s.Env, family)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		var logged []*goparent.Feeding
+		_, err = datastore.NewQuery(FeedingKind).Filter("ChildID =", child.ID).Filter("TimeStamp >", start).GetAll(ctx, &logged)
 		if err != nil {
 			return nil, err
 		}
-		feedings = append(feedings, feeding)
+		for _, feeding := range logged {
+			if feeding.FamilyID != family.ID {
+				feedings = append(feedings, feeding)
+			}
+		}
 	}
+	sort.Slice(feedings, func(i, j int) bool { return feedings[i].TimeStamp.After(feedings[j].TimeStamp) })
+	return feedings, nil
+}
 
-	summary := &goparent.FeedingSummary{
-		Data:  feedings,
-		Total: make(map[string]float32),
-		Mean:  make(map[string]float32),
-		Range: make(map[string]int),
+//Stats -
+func (s *FeedingService) Stats(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.FeedingSummary, error) {
+	feedings, err := s.childFeedings(ctx, child, query)
+	if err != nil {
+		return nil, NewError("FeedingService.Stats", err)
 	}
+	return goparent.NewFeedingSummary(feedings), nil
+}
 
-	for _, x := range feedings {
-		if _, ok := summary.Total[x.Type]; !ok {
-			summary.Total[x.Type] = 0.0
-		}
-		summary.Total[x.Type] += x.Amount
-		summary.Range[x.Type]++
-	}
-	for k := range summary.Total {
-		summary.Mean[k] = summary.Total[k] / float32(summary.Range[k])
+//GraphData -
+func (s *FeedingService) GraphData(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.FeedingChartData, error) {
+	feedings, err := s.childFeedings(ctx, child, query)
+	if err != nil {
+		return nil, NewError("FeedingService.GraphData", err)
 	}
-	return summary, nil
+	return goparent.NewFeedingChartData(query, feedings), nil
 }
 
-//GraphData -
-func (s *FeedingService) GraphData(ctx context.Context, child *goparent.Child) (*goparent.FeedingChartData, error) {
+//childFeedings returns the feedings for a child inside of the query window, newest first.
+func (s *FeedingService) childFeedings(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) ([]goparent.Feeding, error) {
 	var feedings []goparent.Feeding
-	feedingCounts := make(map[time.Time][]goparent.Feeding)
-
-	end := time.Now()
-	start := end.AddDate(0, 0, -7)
-	q := datastore.NewQuery(FeedingKind).Filter("ChildID =", child.ID).Filter("TimeStamp >", start).Filter("TimeStamp <=", end).Order("-TimeStamp")
-	//get each item from the query organize them by day.
+	q := datastore.NewQuery(FeedingKind).Filter("ChildID =", child.ID).Filter("TimeStamp >=", query.Start).Filter("TimeStamp <", query.End).Order("-TimeStamp")
 	itx := q.Run(ctx)
 	for {
 		var feeding goparent.Feeding
@@ -127,32 +122,7 @@ func (s *FeedingService) GraphData(ctx context.Context, child *goparent.Child) (
 		if err != nil {
 			return nil, err
 		}
-
-		roundedDate := RoundToDay(feeding.TimeStamp, false)
-		feedingCounts[roundedDate] = append(feedingCounts[roundedDate], feeding)
 		feedings = append(feedings, feeding)
 	}
-
-	chartData := &goparent.FeedingChartData{
-		Start:   start,
-		End:     end,
-		Dataset: make([]goparent.FeedingChartDataset, 1),
-	}
-	for day, feedings := range feedingCounts {
-		counts := make(map[string]int)
-		sums := make(map[string]float32)
-		for _, t := range feedings {
-			counts[t.Type]++
-			sums[t.Type] += t.Amount
-		}
-		for feedingType, count := range counts {
-			chartData.Dataset = append(chartData.Dataset, goparent.FeedingChartDataset{
-				Date:  day,
-				Type:  feedingType,
-				Count: count,
-				Sum:   sums[feedingType],
-			})
-		}
-	}
-	return chartData, nil
+	return feedings, nil
 }
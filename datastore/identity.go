@@ -0,0 +1,89 @@
+package datastore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sasimpson/goparent"
+	"google.golang.org/appengine/datastore"
+)
+
+//IdentityService -
+type IdentityService struct {
+	Env *goparent.Env
+}
+
+//IdentityKind - the kind for linked identities in gcp datastore
+const IdentityKind = "Identity"
+
+//Save links an identity or updates a linked one.  identities are keyed by their IdentityID,
+// linking one that's already linked to another user fails instead of replacing it.
+func (s *IdentityService) Save(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	id := identity.ID
+	if id == "" {
+		id = goparent.IdentityID(identity.Issuer, identity.Subject)
+	}
+	key := datastore.NewKey(ctx, IdentityKind, id, 0, nil)
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		var existing goparent.ExternalIdentity
+		err := datastore.Get(tc, key, &existing)
+		if err == nil && existing.UserID != identity.UserID {
+			return goparent.ErrIdentityLinked
+		}
+		if err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		saved := *identity
+		saved.ID = id
+		_, err = datastore.Put(tc, key, &saved)
+		return err
+	}, nil)
+	if err == goparent.ErrIdentityLinked {
+		return err
+	}
+	if err != nil {
+		return NewError("IdentityService.Save", err)
+	}
+	identity.ID = id
+	return nil
+}
+
+//Identity returns the linked identity with the id
+func (s *IdentityService) Identity(ctx context.Context, id string) (*goparent.ExternalIdentity, error) {
+	var identity goparent.ExternalIdentity
+	err := datastore.Get(ctx, datastore.NewKey(ctx, IdentityKind, id, 0, nil), &identity)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, goparent.ErrNoIdentityFound
+	}
+	if err != nil {
+		return nil, NewError("IdentityService.Identity", err)
+	}
+	return &identity, nil
+}
+
+//IdentityBySubject returns the identity linked for a provider's issuer and subject
+func (s *IdentityService) IdentityBySubject(ctx context.Context, issuer string, subject string) (*goparent.ExternalIdentity, error) {
+	return s.Identity(ctx, goparent.IdentityID(issuer, subject))
+}
+
+//Identities returns all of the identities linked to a user, oldest first
+func (s *IdentityService) Identities(ctx context.Context, user *goparent.User) ([]*goparent.ExternalIdentity, error) {
+	var identities []*goparent.ExternalIdentity
+	_, err := datastore.NewQuery(IdentityKind).Filter("UserID =", user.ID).GetAll(ctx, &identities)
+	if err != nil {
+		return nil, NewError("IdentityService.Identities", err)
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+	return identities, nil
+}
+
+//Delete unlinks an identity
+func (s *IdentityService) Delete(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	err := datastore.Delete(ctx, datastore.NewKey(ctx, IdentityKind, identity.ID, 0, nil))
+	if err != nil {
+		return NewError("IdentityService.Delete", err)
+	}
+	return nil
+}
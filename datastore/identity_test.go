@@ -0,0 +1,47 @@
+package datastore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/datastore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestDatastoreIdentity(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	defer done()
+	if err != nil {
+		t.Error("error", err)
+	}
+
+	identityService := datastore.IdentityService{}
+	user := &goparent.User{ID: "user-1"}
+
+	_, err = identityService.IdentityBySubject(ctx, "https://idp.test", "sub-1")
+	assert.Equal(t, goparent.ErrNoIdentityFound, err)
+
+	identity := &goparent.ExternalIdentity{UserID: user.ID, Issuer: "https://idp.test", Subject: "sub-1", CreatedAt: time.Now()}
+	err = identityService.Save(ctx, identity)
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.IdentityID("https://idp.test", "sub-1"), identity.ID)
+
+	other := &goparent.ExternalIdentity{UserID: "user-2", Issuer: "https://idp.test", Subject: "sub-1"}
+	err = identityService.Save(ctx, other)
+	assert.Equal(t, goparent.ErrIdentityLinked, err)
+
+	found, err := identityService.IdentityBySubject(ctx, "https://idp.test", "sub-1")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, found.UserID)
+
+	identities, err := identityService.Identities(ctx, user)
+	assert.Nil(t, err)
+	assert.Len(t, identities, 1)
+
+	err = identityService.Delete(ctx, identity)
+	assert.Nil(t, err)
+	_, err = identityService.Identity(ctx, identity.ID)
+	assert.Equal(t, goparent.ErrNoIdentityFound, err)
+}
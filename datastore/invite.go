@@ -2,8 +2,11 @@ package datastore
 
 import (
 	"context"
+	"errors"
+	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/sasimpson/goparent"
 	"google.golang.org/appengine/datastore"
 )
@@ -16,33 +19,63 @@ type UserInviteService struct {
 //InviteKind is the datastore kind representation
 const InviteKind = "Invite"
 
-//InviteParent adds an invitation for another parent to join in on a family's data
-func (s *UserInviteService) InviteParent(ctx context.Context, user *goparent.User, inviteEmail string, timestamp time.Time) error {
-	//get existing invites by the invitee's email and make sure they don't already have one.
-	q := datastore.NewQuery(InviteKind).Filter("InviteEmail = ", inviteEmail).KeysOnly()
-	keys, err := q.GetAll(ctx, nil)
-	if len(keys) > 0 {
-		return NewError("UserInviteService.InviteParent", err)
+//InviteParent adds an invitation for another parent to join the user's current family, the invite
+// is given a token and expiry and mailed to the invitee
+func (s *UserInviteService) InviteParent(ctx context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
+	us := UserService{Env: s.Env}
+	family, err := us.GetFamily(ctx, user)
+	if err != nil {
+		return err
 	}
 
-	//if not, add an invite for the invitee
-	userKey := datastore.NewKey(ctx, UserKind, user.ID, 0, nil)
-	//set the user as the parent so we can lookup all invites sent by a user by ancestry.
-	inviteKey := datastore.NewIncompleteKey(ctx, InviteKind, userKey)
-	inviteUser := goparent.UserInvitation{
-		UserID:      user.ID,
-		InviteEmail: inviteEmail,
-		Timestamp:   timestamp,
+	//get existing invites by the invitee's email and make sure they don't already have an open one to this family.
+	var existing []*goparent.UserInvitation
+	q := datastore.NewQuery(InviteKind).Filter("InviteEmail =", invite.InviteEmail).Filter("FamilyID =", family.ID)
+	_, err = q.GetAll(ctx, &existing)
+	if err != nil {
+		return NewError("UserInviteService.InviteParent", err)
+	}
+	for _, e := range existing {
+		if e.CurrentStatus(invite.Timestamp) == goparent.InvitationPending {
+			return goparent.ErrExistingInvitation
+		}
 	}
 
-	_, err = datastore.Put(ctx, inviteKey, &inviteUser)
+	invite.ID = uuid.New().String()
+	invite.UserID = user.ID
+	invite.FamilyID = family.ID
+	token, err := invite.Issue(invite.Timestamp)
+	if err != nil {
+		return err
+	}
+	err = s.save(ctx, invite)
 	if err != nil {
 		return err
 	}
 
+	err = s.Env.MailInvitation(ctx, user, invite, token)
+	if err != nil {
+		log.Printf("error sending mail: %#v", err)
+	}
+
 	return nil
 }
 
+//save puts the invite, invites are children of the user that sent them so we can look up all invites
+// sent by a user by ancestry.
+func (s *UserInviteService) save(ctx context.Context, invite *goparent.UserInvitation) error {
+	_, err := datastore.Put(ctx, s.inviteKey(ctx, invite), invite)
+	if err != nil {
+		return NewError("UserInviteService.save", err)
+	}
+	return nil
+}
+
+func (s *UserInviteService) inviteKey(ctx context.Context, invite *goparent.UserInvitation) *datastore.Key {
+	userKey := datastore.NewKey(ctx, UserKind, invite.UserID, 0, nil)
+	return datastore.NewKey(ctx, InviteKind, invite.ID, 0, userKey)
+}
+
 //SentInvites gets the invites that a user has sent out to other people
 func (s *UserInviteService) SentInvites(ctx context.Context, user *goparent.User) ([]*goparent.UserInvitation, error) {
 	var userInvites []*goparent.UserInvitation
@@ -69,20 +102,27 @@ func (s *UserInviteService) SentInvites(ctx context.Context, user *goparent.User
 
 //Invite gets an invite by its ID.
 func (s *UserInviteService) Invite(ctx context.Context, id string) (*goparent.UserInvitation, error) {
-	q := datastore.NewQuery(InviteKind).Filter("ID =", id)
+	return s.first(ctx, datastore.NewQuery(InviteKind).Filter("ID =", id))
+}
+
+//InviteByToken gets the invite a token was mailed for
+func (s *UserInviteService) InviteByToken(ctx context.Context, token string) (*goparent.UserInvitation, error) {
+	return s.first(ctx, datastore.NewQuery(InviteKind).Filter("TokenHash =", goparent.HashInviteToken(token)))
+}
+
+func (s *UserInviteService) first(ctx context.Context, q *datastore.Query) (*goparent.UserInvitation, error) {
 	itx := q.Run(ctx)
 
 	var invite goparent.UserInvitation
 	_, err := itx.Next(&invite)
 	if err == datastore.Done {
-		return nil, nil
+		return nil, goparent.ErrNoInvitationFound
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &invite, nil
-
 }
 
 //Invites returns the invites that have been issued to a user based on their email
@@ -105,47 +145,111 @@ func (s *UserInviteService) Invites(ctx context.Context, user *goparent.User) ([
 	return invites, nil
 }
 
-//Accept an invite from an existing user, add them to the family and set their current family.
+//Accept an invite sent to the user's email, add them to the family with the invite's role and set their current family.
 func (s *UserInviteService) Accept(ctx context.Context, user *goparent.User, id string) error {
-	//get invite by id and invited user
 	invite, err := s.Invite(ctx, id)
 	if err != nil {
 		return err
 	}
+	now := time.Now()
+	err = invite.Answerable(user, now)
+	if err != nil {
+		return err
+	}
 
-	//get the user and family that is doing the inviting
 	us := UserService{Env: s.Env}
-	invitingUser, err := us.User(ctx, invite.UserID)
+	fs := FamilyService{Env: s.Env}
+	family, err := s.inviteFamily(ctx, invite)
+	if err != nil {
+		return err
+	}
+
+	//add the user to the family with their role
+	family.SetRole(user.ID, invite.Role)
+	err = fs.AddMember(ctx, family, user)
+	if errors.Is(err, ErrAlreadyInFamily) {
+		err = fs.Save(ctx, family)
+	}
 	if err != nil {
 		return err
 	}
 
-	family, err := us.GetFamily(ctx, invitingUser)
+	user.CurrentFamily = family.ID
+	err = us.Save(ctx, user)
 	if err != nil {
 		return err
 	}
-	//add the user to the family of the inviting user
+
+	invite.Answer(goparent.InvitationAccepted, now)
+	return s.save(ctx, invite)
+}
+
+//inviteFamily - the family the invite is to
+func (s *UserInviteService) inviteFamily(ctx context.Context, invite *goparent.UserInvitation) (*goparent.Family, error) {
+	us := UserService{Env: s.Env}
 	fs := FamilyService{Env: s.Env}
-	err = fs.AddMember(ctx, family, user)
+	if invite.FamilyID != "" {
+		return fs.Family(ctx, invite.FamilyID)
+	}
+	//invites from before they had a family are for the inviting user's family
+	invitingUser, err := us.User(ctx, invite.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return us.GetFamily(ctx, invitingUser)
+}
+
+//Decline an invite sent to the user's email
+func (s *UserInviteService) Decline(ctx context.Context, user *goparent.User, id string) error {
+	invite, err := s.Invite(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = invite.Answerable(user, now)
 	if err != nil {
 		return err
 	}
 
-	//remove the invite from the system
-	err = s.Delete(ctx, invite)
+	invite.Answer(goparent.InvitationDeclined, now)
+	return s.save(ctx, invite)
+}
+
+//Resend mails a pending or expired invite again with a new token and expiry
+func (s *UserInviteService) Resend(ctx context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
+	now := time.Now()
+	status := invite.CurrentStatus(now)
+	if status != goparent.InvitationPending && status != goparent.InvitationExpired {
+		return goparent.ErrInvitationAnswered
+	}
+	//someone who has left or been removed can't keep inviting people to the family
+	family, err := s.inviteFamily(ctx, invite)
 	if err != nil {
 		return err
 	}
+	if !family.IsMember(user.ID) {
+		return goparent.ErrNotFamilyMember
+	}
 
+	token, err := invite.Issue(now)
+	if err != nil {
+		return err
+	}
+	err = s.save(ctx, invite)
+	if err != nil {
+		return err
+	}
+
+	err = s.Env.MailInvitation(ctx, user, invite, token)
+	if err != nil {
+		log.Printf("error sending mail: %#v", err)
+	}
 	return nil
 }
 
 //Delete removes an invite
 func (s *UserInviteService) Delete(ctx context.Context, invite *goparent.UserInvitation) error {
-	userKey := datastore.NewKey(ctx, UserKind, invite.UserID, 0, nil)
-	inviteKey := datastore.NewKey(ctx, InviteKind, invite.ID, 0, userKey)
-
-	err := datastore.Delete(ctx, inviteKey)
+	err := datastore.Delete(ctx, s.inviteKey(ctx, invite))
 
 	return err
 }
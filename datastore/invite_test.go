@@ -36,9 +36,13 @@ func TestUserInvite(t *testing.T) {
 	assert.Len(t, userInvites, 0)
 
 	//invite someone
-	err = inviteService.InviteParent(ctx, user, "mrstest@test.com", time.Now())
+	err = inviteService.InviteParent(ctx, user, &goparent.UserInvitation{InviteEmail: "mrstest@test.com", Timestamp: time.Now()})
 	assert.Nil(t, err)
 
+	//an open invite can't be sent twice
+	err = inviteService.InviteParent(ctx, user, &goparent.UserInvitation{InviteEmail: "mrstest@test.com", Timestamp: time.Now()})
+	assert.Equal(t, goparent.ErrExistingInvitation, err)
+
 	//test sent invite
 	userInvites, err = inviteService.SentInvites(ctx, user)
 	assert.Nil(t, err)
@@ -64,11 +68,19 @@ func TestUserInvite(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Len(t, invites, 1)
 
+	//someone else can't accept it
+	err = inviteService.Accept(ctx, user, invites[0].ID)
+	assert.Equal(t, goparent.ErrNoInvitationFound, err)
+
 	//test accepting that invite
 	err = inviteService.Accept(ctx, mrsUser, invites[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, user.CurrentFamily, mrsUser.CurrentFamily)
 
-	//test invite was deleted
-	// deletedInvite, err := inviteService.Invite(ctx, invites[0].ID)
-	// assert.Nil(t, err)
-	// assert.Nil(t, deletedInvite)
+	//the invite can only be answered once
+	acceptedInvite, err := inviteService.Invite(ctx, invites[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.InvitationAccepted, acceptedInvite.Status)
+	err = inviteService.Decline(ctx, mrsUser, invites[0].ID)
+	assert.Equal(t, goparent.ErrInvitationAnswered, err)
 }
@@ -0,0 +1,39 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"google.golang.org/appengine/datastore"
+)
+
+//LeaseStore - leases kept in gcp datastore so every instance sees them
+type LeaseStore struct {
+	Env *goparent.Env
+}
+
+//LeaseKind - the kind for leases, keyed by the lease name
+const LeaseKind = "Lease"
+
+//Hold takes or renews the lease in a transaction so two instances can't both take it
+func (s *LeaseStore) Hold(ctx context.Context, name string, holder string, now time.Time, ttl time.Duration) (goparent.Lease, error) {
+	var lease goparent.Lease
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		key := datastore.NewKey(tc, LeaseKind, name, 0, nil)
+		err := datastore.Get(tc, key, &lease)
+		if err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		if err == nil && lease.Holder != holder && now.Before(lease.Expires) {
+			return nil
+		}
+		lease = goparent.Lease{Name: name, Holder: holder, Expires: now.Add(ttl)}
+		_, err = datastore.Put(tc, key, &lease)
+		return err
+	}, nil)
+	if err != nil {
+		return goparent.Lease{}, NewError("LeaseStore.Hold", err)
+	}
+	return lease, nil
+}
@@ -0,0 +1,40 @@
+package datastore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent/datastore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestDatastoreLease(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	defer done()
+	if err != nil {
+		t.Error("error", err)
+	}
+
+	store := datastore.LeaseStore{}
+	now := time.Now().Truncate(time.Second)
+
+	lease, err := store.Hold(ctx, "webhooks", "instance-1", now, time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, lease.HeldBy("instance-1", now))
+
+	//someone else can't take it while it's held
+	lease, err = store.Hold(ctx, "webhooks", "instance-2", now.Add(time.Second), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, lease.HeldBy("instance-2", now.Add(time.Second)))
+
+	//the holder renews it
+	lease, err = store.Hold(ctx, "webhooks", "instance-1", now.Add(30*time.Second), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, lease.Expires.Equal(now.Add(90*time.Second)))
+
+	//it's up for grabs once it expires
+	lease, err = store.Hold(ctx, "webhooks", "instance-2", now.Add(2*time.Minute), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, lease.HeldBy("instance-2", now.Add(2*time.Minute)))
+}
@@ -0,0 +1,70 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"google.golang.org/appengine/datastore"
+)
+
+//RateLimitStore - rate limit counts kept in gcp datastore so every instance shares them
+type RateLimitStore struct {
+	Env *goparent.Env
+}
+
+//RateLimitKind - the kind for rate limit counts, keyed by the rate limit key
+const RateLimitKind = "RateLimit"
+
+//Hit counts a hit against the key in a transaction so concurrent hits aren't lost
+func (s *RateLimitStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	var count goparent.RateCount
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		var err error
+		count, err = s.count(tc, key, now, window)
+		if err != nil {
+			return err
+		}
+		if count.Count == 0 {
+			count.Start = now
+		}
+		count.Count++
+		count.Last = now
+		_, err = datastore.Put(tc, datastore.NewKey(tc, RateLimitKind, key, 0, nil), &count)
+		return err
+	}, nil)
+	if err != nil {
+		return goparent.RateCount{}, NewError("RateLimitStore.Hit", err)
+	}
+	return count, nil
+}
+
+//Count returns the key's count in its current window
+func (s *RateLimitStore) Count(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	count, err := s.count(ctx, key, now, window)
+	if err != nil {
+		return goparent.RateCount{}, NewError("RateLimitStore.Count", err)
+	}
+	return count, nil
+}
+
+//Reset forgets the key's count
+func (s *RateLimitStore) Reset(ctx context.Context, key string) error {
+	err := datastore.Delete(ctx, datastore.NewKey(ctx, RateLimitKind, key, 0, nil))
+	if err != nil && err != datastore.ErrNoSuchEntity {
+		return NewError("RateLimitStore.Reset", err)
+	}
+	return nil
+}
+
+func (s *RateLimitStore) count(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	var count goparent.RateCount
+	err := datastore.Get(ctx, datastore.NewKey(ctx, RateLimitKind, key, 0, nil), &count)
+	if err == datastore.ErrNoSuchEntity || (err == nil && !now.Before(count.Start.Add(window))) {
+		return goparent.RateCount{Key: key}, nil
+	}
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+	return count, nil
+}
@@ -0,0 +1,42 @@
+package datastore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent/datastore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestDatastoreRateLimit(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	defer done()
+	if err != nil {
+		t.Error("error", err)
+	}
+
+	store := datastore.RateLimitStore{}
+	now := time.Now().Truncate(time.Second)
+
+	count, err := store.Count(ctx, "login:test", now, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count.Count)
+
+	for i := 1; i <= 3; i++ {
+		count, err = store.Hit(ctx, "login:test", now, time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, i, count.Count)
+	}
+
+	//a new window starts once the last one is over
+	count, err = store.Hit(ctx, "login:test", now.Add(2*time.Minute), time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count.Count)
+
+	err = store.Reset(ctx, "login:test")
+	assert.Nil(t, err)
+	count, err = store.Count(ctx, "login:test", now.Add(2*time.Minute), time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count.Count)
+}
@@ -0,0 +1,230 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sasimpson/goparent"
+	"google.golang.org/appengine/datastore"
+)
+
+//ReminderService -
+type ReminderService struct {
+	Env *goparent.Env
+}
+
+//ReminderKind and NotificationKind are the kinds for reminder rules and their notifications in gcp datastore
+const (
+	ReminderKind     = "Reminder"
+	NotificationKind = "Notification"
+)
+
+//SaveRule creates or updates a reminder rule, rules are children of their family
+func (s *ReminderService) SaveRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	rule.LastUpdated = time.Now()
+	if rule.ID == "" {
+		rule.ID = uuid.New().String()
+		rule.CreatedAt = rule.LastUpdated
+	}
+
+	_, err := datastore.Put(ctx, s.ruleKey(ctx, rule), rule)
+	if err != nil {
+		return NewError("ReminderService.SaveRule", err)
+	}
+	return nil
+}
+
+//Rule returns the reminder rule with the id
+func (s *ReminderService) Rule(ctx context.Context, id string) (*goparent.ReminderRule, error) {
+	var rule goparent.ReminderRule
+	q := datastore.NewQuery(ReminderKind).Filter("ID =", id)
+	itx := q.Run(ctx)
+	_, err := itx.Next(&rule)
+	if err == datastore.Done {
+		return nil, NewError("ReminderService.Rule", goparent.ErrNoReminderFound)
+	}
+	if err != nil {
+		return nil, NewError("ReminderService.Rule", err)
+	}
+	return &rule, nil
+}
+
+//Rules returns all of a family's reminder rules, oldest first
+func (s *ReminderService) Rules(ctx context.Context, family *goparent.Family) ([]*goparent.ReminderRule, error) {
+	var rules []*goparent.ReminderRule
+	familyKey := datastore.NewKey(ctx, FamilyKind, family.ID, 0, nil)
+	q := datastore.NewQuery(ReminderKind).Ancestor(familyKey).Order("CreatedAt")
+	_, err := q.GetAll(ctx, &rules)
+	if err != nil {
+		return nil, NewError("ReminderService.Rules", err)
+	}
+	return rules, nil
+}
+
+//ActiveRules returns every family's rules that aren't disabled
+func (s *ReminderService) ActiveRules(ctx context.Context) ([]*goparent.ReminderRule, error) {
+	var rules []*goparent.ReminderRule
+	q := datastore.NewQuery(ReminderKind).Filter("Disabled =", false)
+	_, err := q.GetAll(ctx, &rules)
+	if err != nil {
+		return nil, NewError("ReminderService.ActiveRules", err)
+	}
+	return rules, nil
+}
+
+//DeleteRule deletes a reminder rule, its notifications are kept
+func (s *ReminderService) DeleteRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	err := datastore.Delete(ctx, s.ruleKey(ctx, rule))
+	if err != nil {
+		return NewError("ReminderService.DeleteRule", err)
+	}
+	return nil
+}
+
+func (s *ReminderService) ruleKey(ctx context.Context, rule *goparent.ReminderRule) *datastore.Key {
+	familyKey := datastore.NewKey(ctx, FamilyKind, rule.FamilyID, 0, nil)
+	return datastore.NewKey(ctx, ReminderKind, rule.ID, 0, familyKey)
+}
+
+//SaveNotification creates or updates a notification, notifications are children of their family
+func (s *ReminderService) SaveNotification(ctx context.Context, n *goparent.Notification) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	_, err := datastore.Put(ctx, s.notificationKey(ctx, n), n)
+	if err != nil {
+		return NewError("ReminderService.SaveNotification", err)
+	}
+	return nil
+}
+
+//CreateNotification saves a new notification in a transaction, ErrNotificationExists if its id is taken
+func (s *ReminderService) CreateNotification(ctx context.Context, n *goparent.Notification) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	key := s.notificationKey(ctx, n)
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		var existing goparent.Notification
+		err := datastore.Get(tc, key, &existing)
+		if err == nil {
+			return goparent.ErrNotificationExists
+		}
+		if err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		_, err = datastore.Put(tc, key, n)
+		return err
+	}, nil)
+	if err == goparent.ErrNotificationExists {
+		return err
+	}
+	if err != nil {
+		return NewError("ReminderService.CreateNotification", err)
+	}
+	return nil
+}
+
+//ClaimNotification marks the notification delivered in a transaction if it's still due, false if it isn't
+func (s *ReminderService) ClaimNotification(ctx context.Context, n *goparent.Notification, now time.Time) (bool, error) {
+	key := s.notificationKey(ctx, n)
+	claimed := false
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		claimed = false
+		var existing goparent.Notification
+		err := datastore.Get(tc, key, &existing)
+		if err != nil || !existing.Due(now) {
+			return err
+		}
+		existing.Status = goparent.NotificationDelivered
+		existing.DeliveredAt = now
+		_, err = datastore.Put(tc, key, &existing)
+		claimed = err == nil
+		return err
+	}, nil)
+	if err == datastore.ErrNoSuchEntity {
+		return false, nil
+	}
+	if err != nil {
+		return false, NewError("ReminderService.ClaimNotification", err)
+	}
+	if claimed {
+		n.Status = goparent.NotificationDelivered
+		n.DeliveredAt = now
+	}
+	return claimed, nil
+}
+
+func (s *ReminderService) notificationKey(ctx context.Context, n *goparent.Notification) *datastore.Key {
+	familyKey := datastore.NewKey(ctx, FamilyKind, n.FamilyID, 0, nil)
+	return datastore.NewKey(ctx, NotificationKind, n.ID, 0, familyKey)
+}
+
+//Notification returns the notification with the id
+func (s *ReminderService) Notification(ctx context.Context, id string) (*goparent.Notification, error) {
+	var n goparent.Notification
+	q := datastore.NewQuery(NotificationKind).Filter("ID =", id)
+	itx := q.Run(ctx)
+	_, err := itx.Next(&n)
+	if err == datastore.Done {
+		return nil, NewError("ReminderService.Notification", goparent.ErrNoNotificationFound)
+	}
+	if err != nil {
+		return nil, NewError("ReminderService.Notification", err)
+	}
+	return &n, nil
+}
+
+//Notifications returns a family's inbox newest first, acknowledged notifications are left out unless all is set
+func (s *ReminderService) Notifications(ctx context.Context, family *goparent.Family, all bool) ([]*goparent.Notification, error) {
+	var notifications []*goparent.Notification
+	familyKey := datastore.NewKey(ctx, FamilyKind, family.ID, 0, nil)
+	q := datastore.NewQuery(NotificationKind).Ancestor(familyKey).Order("-DueAt")
+	_, err := q.GetAll(ctx, &notifications)
+	if err != nil {
+		return nil, NewError("ReminderService.Notifications", err)
+	}
+	if all {
+		return notifications, nil
+	}
+
+	inbox := notifications[:0]
+	for _, n := range notifications {
+		if n.Status != goparent.NotificationAcknowledged {
+			inbox = append(inbox, n)
+		}
+	}
+	return inbox, nil
+}
+
+//LatestNotification returns the rule's most recent notification, nil if it hasn't had one
+func (s *ReminderService) LatestNotification(ctx context.Context, rule *goparent.ReminderRule) (*goparent.Notification, error) {
+	var notifications []*goparent.Notification
+	familyKey := datastore.NewKey(ctx, FamilyKind, rule.FamilyID, 0, nil)
+	q := datastore.NewQuery(NotificationKind).Ancestor(familyKey).Filter("RuleID =", rule.ID).Order("-DueAt").Limit(1)
+	_, err := q.GetAll(ctx, &notifications)
+	if err != nil {
+		return nil, NewError("ReminderService.LatestNotification", err)
+	}
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+	return notifications[0], nil
+}
+
+//DueNotifications returns the notifications waiting to be delivered, new ones and ones whose snooze is over
+func (s *ReminderService) DueNotifications(ctx context.Context, now time.Time) ([]*goparent.Notification, error) {
+	var pending, snoozed []*goparent.Notification
+	q := datastore.NewQuery(NotificationKind).Filter("Status =", string(goparent.NotificationPending))
+	_, err := q.GetAll(ctx, &pending)
+	if err != nil {
+		return nil, NewError("ReminderService.DueNotifications", err)
+	}
+	q = datastore.NewQuery(NotificationKind).Filter("Status =", string(goparent.NotificationSnoozed)).Filter("SnoozedUntil <=", now)
+	_, err = q.GetAll(ctx, &snoozed)
+	if err != nil {
+		return nil, NewError("ReminderService.DueNotifications", err)
+	}
+	return append(pending, snoozed...), nil
+}
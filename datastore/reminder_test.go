@@ -0,0 +1,72 @@
+package datastore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/datastore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestDatastoreReminder(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	defer done()
+	if err != nil {
+		t.Error("error", err)
+	}
+
+	reminderService := datastore.ReminderService{}
+	family := &goparent.Family{ID: "1"}
+
+	nilRule, err := reminderService.Rule(ctx, "123")
+	assert.Nil(t, nilRule)
+	assert.NotNil(t, err)
+
+	rule := &goparent.ReminderRule{FamilyID: "1", ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 180}
+	err = reminderService.SaveRule(ctx, rule)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, rule.ID)
+
+	rules, err := reminderService.Rules(ctx, family)
+	assert.Nil(t, err)
+	assert.Len(t, rules, 1)
+
+	latest, err := reminderService.LatestNotification(ctx, rule)
+	assert.Nil(t, err)
+	assert.Nil(t, latest)
+
+	now := time.Now()
+	n := &goparent.Notification{FamilyID: "1", ChildID: "1", RuleID: rule.ID, DueAt: now, Status: goparent.NotificationPending}
+	err = reminderService.SaveNotification(ctx, n)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, n.ID)
+
+	//the scheduler creates and claims notifications, only once each
+	due := &goparent.Notification{ID: goparent.NotificationID(rule, now), FamilyID: "1", ChildID: "1", RuleID: rule.ID, DueAt: now, Status: goparent.NotificationPending}
+	err = reminderService.CreateNotification(ctx, due)
+	assert.Nil(t, err)
+	err = reminderService.CreateNotification(ctx, &goparent.Notification{ID: due.ID, FamilyID: "1", RuleID: rule.ID, DueAt: now, Status: goparent.NotificationPending})
+	assert.Equal(t, goparent.ErrNotificationExists, err)
+	claimed, err := reminderService.ClaimNotification(ctx, due, now)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+	claimed, err = reminderService.ClaimNotification(ctx, due, now)
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+	due.Acknowledge(now, &goparent.User{ID: "1"})
+	err = reminderService.SaveNotification(ctx, due)
+	assert.Nil(t, err)
+
+	n.Acknowledge(now, &goparent.User{ID: "1"})
+	err = reminderService.SaveNotification(ctx, n)
+	assert.Nil(t, err)
+
+	inbox, err := reminderService.Notifications(ctx, family, false)
+	assert.Nil(t, err)
+	assert.Len(t, inbox, 0)
+
+	err = reminderService.DeleteRule(ctx, rule)
+	assert.Nil(t, err)
+}
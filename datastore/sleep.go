@@ -2,6 +2,7 @@ package datastore
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,6 +20,15 @@ const SleepKind = "Sleep"
 
 //Save is the function that will save a record
 func (s *SleepService) Save(ctx context.Context, sleep *goparent.Sleep) error {
+	eventType := goparent.EventUpdated
+	if sleep.ID == "" {
+		eventType = goparent.EventCreated
+	}
+	return s.save(ctx, sleep, eventType)
+}
+
+//save stores the sleep and publishes the event, start and end have their own event types.
+func (s *SleepService) save(ctx context.Context, sleep *goparent.Sleep, eventType goparent.EventType) error {
 	var sleepKey *datastore.Key
 	familyKey := datastore.NewKey(ctx, FamilyKind, sleep.FamilyID, 0, nil)
 	childKey := datastore.NewKey(ctx, ChildKind, sleep.ChildID, 0, familyKey)
@@ -37,6 +47,7 @@ func (s *SleepService) Save(ctx context.Context, sleep *goparent.Sleep) error {
 	if err != nil {
 		return NewError("SleepService.Save", err)
 	}
+	s.Env.Publish(goparent.Event{Type: eventType, Resource: goparent.ResourceSleep, FamilyID: sleep.FamilyID, ChildID: sleep.ChildID, Data: sleep, SharedWith: childFamilies(ctx, s.Env, sleep.ChildID)})
 	return nil
 }
 
@@ -60,6 +71,25 @@ func (s *SleepService) Sleep(ctx context.Context, family *goparent.Family, days
 		}
 		sleeps = append(sleeps, &sleep)
 	}
+
+	//sleeps other households logged for children shared with or by the family
+	children, err := sharedChildren(ctx, s.Env, family)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		var logged []*goparent.Sleep
+		_, err = datastore.NewQuery(SleepKind).Filter("ChildID =", child.ID).Filter("Start >", start).GetAll(ctx, &logged)
+		if err != nil {
+			return nil, err
+		}
+		for _, sleep := range logged {
+			if sleep.FamilyID != family.ID {
+				sleeps = append(sleeps, sleep)
+			}
+		}
+	}
+	sort.Slice(sleeps, func(i, j int) bool { return sleeps[i].Start.After(sleeps[j].Start) })
 	return sleeps, nil
 }
 
@@ -104,7 +134,7 @@ func (s *SleepService) Start(ctx context.Context, family *goparent.Family, child
 		ChildID:  child.ID,
 	}
 
-	err = s.Save(ctx, sleep)
+	err = s.save(ctx, sleep, goparent.EventStarted)
 	if err != nil {
 		return err
 	}
@@ -124,64 +154,35 @@ func (s *SleepService) End(ctx context.Context, family *goparent.Family, child *
 	}
 
 	sleep.End = time.Now()
-	err = s.Save(ctx, sleep)
+	err = s.save(ctx, sleep, goparent.EventEnded)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-//Stats returns sleep stats about a one day period for a child.
-func (s *SleepService) Stats(ctx context.Context, child *goparent.Child) (*goparent.SleepSummary, error) {
-	var sleeps []goparent.Sleep
-	end := time.Now()
-	start := end.AddDate(0, 0, -1)
-
-	familyKey := datastore.NewKey(ctx, FamilyKind, child.FamilyID, 0, nil)
-	childKey := datastore.NewKey(ctx, ChildKind, child.ID, 0, familyKey)
-
-	q := datastore.NewQuery(SleepKind).Filter("ChildID = ", childKey).Filter("TimeStamp >= ", start).Order("-TimeStamp")
-	itx := q.Run(ctx)
-	for {
-		var sleep goparent.Sleep
-		_, err := itx.Next(&sleep)
-		if err == datastore.Done {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		sleeps = append(sleeps, sleep)
-	}
-
-	summary := &goparent.SleepSummary{
-		Data:  sleeps,
-		Total: 0,
-		Mean:  0.0,
-		Range: 0,
+//Stats returns sleep stats about the query window for a child.
+func (s *SleepService) Stats(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.SleepSummary, error) {
+	sleeps, err := s.childSleeps(ctx, child, query)
+	if err != nil {
+		return nil, NewError("SleepService.Stats", err)
 	}
+	return goparent.NewSleepSummary(sleeps), nil
+}
 
-	for _, x := range sleeps {
-		//if the sleep end is before the start then the sleep period hasn't stopped yet.  don't count
-		if x.End.After(x.Start) {
-			summary.Total += int64(x.End.Sub(x.Start).Seconds())
-			summary.Range++
-		}
+//GraphData returns data that a graph can be created from
+func (s *SleepService) GraphData(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.SleepChartData, error) {
+	sleeps, err := s.childSleeps(ctx, child, query)
+	if err != nil {
+		return nil, NewError("SleepService.GraphData", err)
 	}
-	// summary.Mean = float64(summary.Total / int64(summary.Range))
-
-	return summary, nil
+	return goparent.NewSleepChartData(query, sleeps), nil
 }
 
-//GraphData returns data that a graph can be created from
-func (s *SleepService) GraphData(ctx context.Context, child *goparent.Child) (*goparent.SleepChartData, error) {
+//childSleeps returns the sleeps for a child that started inside of the query window, newest first.
+func (s *SleepService) childSleeps(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) ([]goparent.Sleep, error) {
 	var sleeps []goparent.Sleep
-	sleepCounts := make(map[time.Time][]goparent.Sleep)
-
-	end := time.Now()
-	start := end.AddDate(0, 0, -7)
-	q := datastore.NewQuery(SleepKind).Filter("ChildID =", child.ID).Filter("Start >", start).Filter("Start <=", end).Order("-Start")
-	//get each item from the query organize them by day.
+	q := datastore.NewQuery(SleepKind).Filter("ChildID =", child.ID).Filter("Start >=", query.Start).Filter("Start <", query.End).Order("-Start")
 	itx := q.Run(ctx)
 	for {
 		var sleep goparent.Sleep
@@ -192,33 +193,7 @@ func (s *SleepService) GraphData(ctx context.Context, child *goparent.Child) (*g
 		if err != nil {
 			return nil, err
 		}
-
-		roundedDate := RoundToDay(sleep.Start, false)
-		sleepCounts[roundedDate] = append(sleepCounts[roundedDate], sleep)
 		sleeps = append(sleeps, sleep)
 	}
-
-	chartData := &goparent.SleepChartData{
-		Start:   start,
-		End:     end,
-		Dataset: []goparent.SleepChartDataset{},
-	}
-
-	//sleep data needs to be represented as each increment of sleep per day.
-
-	for day, sleeps := range sleepCounts {
-		var sleepTotals []time.Duration
-		for _, sleepEntry := range sleeps {
-			//if the entry hasn't stopped, don't count it, its still active
-			if sleepEntry.End.After(sleepEntry.Start) {
-				sleepTotals = append(sleepTotals, sleepEntry.End.Sub(sleepEntry.Start))
-			}
-		}
-		chartData.Dataset = append(chartData.Dataset, goparent.SleepChartDataset{
-			Date:   day,
-			Totals: sleepTotals,
-		})
-	}
-
-	return chartData, nil
+	return sleeps, nil
 }
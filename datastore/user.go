@@ -3,8 +3,6 @@ package datastore
 import (
 	"context"
 	"crypto/md5"
-	"encoding/base64"
-	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -12,9 +10,9 @@ import (
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 	"github.com/sasimpson/goparent"
 	"google.golang.org/appengine/datastore"
-	"google.golang.org/appengine/mail"
 )
 
 //UserService -
@@ -40,13 +38,11 @@ const ResetKind = "PasswordReset"
 
 var (
 	//ErrNoUserFound is when there is no user returned by the datastore
-	ErrNoUserFound = errors.New("no result for that id")
+	ErrNoUserFound = goparent.ErrNoUserFound
 	//ErrInvalidLogin is when the password/user combo do not match
-	ErrInvalidLogin = errors.New("no result for that username password combo")
-	//ErrInvalidEmail is when a user submits an invalid email for password reset
-	ErrInvalidEmail = errors.New("no result for that email")
+	ErrInvalidLogin = goparent.ErrInvalidLogin
 	//ErrInvalidResetCode is when a user submits a code for resetting password that is invalid
-	ErrInvalidResetCode = errors.New("invalid code for reset")
+	ErrInvalidResetCode = goparent.ErrInvalidResetCode
 )
 
 //User - get a user by the key/id
@@ -68,10 +64,17 @@ func (s *UserService) UserByLogin(ctx context.Context, email string, password st
 	var user goparent.User
 	userKey := datastore.NewKey(ctx, UserKind, md5Email(email), 0, nil)
 	err := datastore.Get(ctx, userKey, &user)
-	if err != nil {
-		if err == datastore.ErrNoSuchEntity {
+	if err == datastore.ErrNoSuchEntity || (err == nil && user.Email != email) {
+		//users who changed their email are still keyed by the one they signed up with
+		found, err := s.userByEmail(ctx, email)
+		if err == ErrNoUserFound {
 			return nil, ErrInvalidLogin
 		}
+		if err != nil {
+			return nil, NewError("datastore.UserService.User", err)
+		}
+		user = *found
+	} else if err != nil {
 		return nil, NewError("datastore.UserService.User", err)
 	}
 
@@ -84,8 +87,10 @@ func (s *UserService) UserByLogin(ctx context.Context, email string, password st
 
 //Save - save a user's current values
 func (s *UserService) Save(ctx context.Context, user *goparent.User) error {
-	userKey := datastore.NewKey(ctx, UserKind, md5Email(user.Email), 0, nil)
-	//we use id as a way to lookup stuff, but don't actually _use_ id since we are using key...
+	userKey, err := s.userKey(ctx, user)
+	if err != nil {
+		return NewError("datastore.UserService.Save", err)
+	}
 
 	var family *goparent.Family
 	fs := &FamilyService{Env: s.Env}
@@ -93,7 +98,7 @@ func (s *UserService) Save(ctx context.Context, user *goparent.User) error {
 	if user.CurrentFamily == "" && user.ID != "" {
 		//get the family for which the user is the admin
 		family, err := fs.GetAdminFamily(ctx, user)
-		if err == ErrNoFamilyFound {
+		if errors.Is(err, ErrNoFamilyFound) {
 			//didn't find a family, creating one, save it
 			family = &goparent.Family{Admin: userKey.StringID(), Members: []string{userKey.StringID()}}
 			err = fs.Save(ctx, family)
@@ -119,7 +124,7 @@ func (s *UserService) Save(ctx context.Context, user *goparent.User) error {
 
 	user.ID = userKey.StringID()
 	//this will save if there is or isn't a record for this user.
-	_, err := datastore.Put(ctx, userKey, user)
+	_, err = datastore.Put(ctx, userKey, user)
 	if err != nil {
 		return NewError("datastore.UserService.Save.3", err)
 	}
@@ -201,41 +206,40 @@ func (s *UserService) GetAllFamily(ctx context.Context, user *goparent.User) ([]
 //RequestResetPassword will setup a password reset token for the email submitted.  that token will then be used
 // to actually change the password
 func (s *UserService) RequestResetPassword(ctx context.Context, email string, ip string) error {
-	q := datastore.NewQuery(UserKind).Filter("Email =", email)
-	t := q.Run(ctx)
-	var user goparent.User
-	_, err := t.Next(&user)
+	user, err := s.userByEmail(ctx, email)
 	if err != nil {
-		return ErrInvalidEmail
+		return err
+	}
+	//a reset would hand the account to whoever owns the email, which hasn't been shown yet
+	if user.Unverified {
+		return goparent.ErrEmailNotVerified
 	}
 
-	// log.Println("password reset requested for user", user.Email)
-
-	// userKey := datastore.NewKey(ctx, UserKind, user.ID, 0, nil)
-	resetKey := datastore.NewIncompleteKey(ctx, ResetKind, nil)
+	//the reset is keyed by the hash of the code, the code itself is only in the mail
+	code, hash, err := goparent.NewResetCode()
+	if err != nil {
+		return err
+	}
+	resetKey := datastore.NewKey(ctx, ResetKind, hash, 0, nil)
 
+	now := time.Now()
 	resetRequest := &goparent.UserReset{
-		Timestamp:   time.Now(),
+		Timestamp:   now,
 		RequestAddr: ip,
 		Email:       email,
+		ExpiresAt:   now.Add(goparent.ResetCodeExpiry),
 	}
 
-	key, err := datastore.Put(ctx, resetKey, resetRequest)
+	_, err = datastore.Put(ctx, resetKey, resetRequest)
 	if err != nil {
 		return err
 	}
-	//this key needs to be emailed to the user.  should eventually make this a jwt reset.
-	code := encodeInt(key.IntID())
-	log.Println("password reset key is", key.IntID(), code)
 
-	resetMessage := mail.Message{
-		Sender:  "noreply@goparent-181120.appspotmail.com",
-		To:      []string{user.Email},
-		Subject: "GoParent password reset",
-		Body:    fmt.Sprintf("your password reset code is: %s", code),
+	resetMessage, err := goparent.NewMail(goparent.MailReset, []string{user.Email}, goparent.ResetMail{Name: user.Name, Code: code})
+	if err != nil {
+		return err
 	}
-
-	err = mail.Send(ctx, &resetMessage)
+	err = s.Env.Mail(ctx, resetMessage)
 	if err != nil {
 		log.Printf("error sending mail: %#v", err)
 	}
@@ -243,58 +247,207 @@ func (s *UserService) RequestResetPassword(ctx context.Context, email string, ip
 	return nil
 }
 
-//ResetPassword will reset the password for the user assuming they meet the requirements
+//ResetPassword will reset the password for the user assuming they meet the requirements.
+// the reset is read and deleted in a transaction so the code only works once.
 func (s *UserService) ResetPassword(ctx context.Context, code string, password string) error {
 	//get code and verify it exists in the datastore
-	resetID := decodeBase64(code)
-	resetKey := datastore.NewKey(ctx, ResetKind, "", resetID, nil)
+	resetKey := datastore.NewKey(ctx, ResetKind, goparent.HashResetCode(code), 0, nil)
 	var resetRequest goparent.UserReset
-	err := datastore.Get(ctx, resetKey, &resetRequest)
+	err := datastore.RunInTransaction(ctx, func(tc context.Context) error {
+		err := datastore.Get(tc, resetKey, &resetRequest)
+		if err != nil {
+			return err
+		}
+		return datastore.Delete(tc, resetKey)
+	}, nil)
 	if err == datastore.ErrNoSuchEntity {
 		return ErrInvalidResetCode
 	}
 	if err != nil {
 		return NewError("datastore.ResetPassword a", err)
 	}
+	if resetRequest.Expired(time.Now()) {
+		return ErrInvalidResetCode
+	}
 
 	//now lookup the user and reset the password to the new one.
-	q := datastore.NewQuery(UserKind).Filter("Email =", resetRequest.Email)
-	t := q.Run(ctx)
-	var user goparent.User
-	_, err = t.Next(&user)
+	user, err := s.userByEmail(ctx, resetRequest.Email)
 	if err != nil {
 		return NewError("datastore.ResetPassword b", err)
 	}
 
-	userKey := datastore.NewKey(ctx, UserKind, md5Email(user.Email), 0, nil)
+	userKey := datastore.NewKey(ctx, UserKind, user.ID, 0, nil)
 	user.Password = password
-	_, err = datastore.Put(ctx, userKey, &user)
+	_, err = datastore.Put(ctx, userKey, user)
 	if err != nil {
 		return NewError("datastore.ResetPassword c", err)
 	}
 
-	err = datastore.Delete(ctx, resetKey)
+	return nil
+}
+
+//ChangeEmail - move the user to a new, confirmed, email address which can't belong to anyone else.
+// the user keeps the key they were saved under, it's their id and every family and record
+// refers to it, so it stays the md5 of the old address.  logins find them by email when the
+// key doesn't match, and userKey gives a later signup with the old address a key of its own.
+func (s *UserService) ChangeEmail(ctx context.Context, user *goparent.User, email string) error {
+	existing, err := s.userByEmail(ctx, email)
+	if err == nil && existing.ID != user.ID {
+		return goparent.ErrExistingUser
+	}
+	if err != nil && err != ErrNoUserFound {
+		return NewError("datastore.UserService.ChangeEmail", err)
+	}
+
+	var stored goparent.User
+	userKey := datastore.NewKey(ctx, UserKind, user.ID, 0, nil)
+	err = datastore.Get(ctx, userKey, &stored)
+	if err != nil {
+		return NewError("datastore.UserService.ChangeEmail", err)
+	}
+	//the change is only made once the new address is confirmed, so it's verified too
+	stored.Email = email
+	stored.Unverified = false
+	_, err = datastore.Put(ctx, userKey, &stored)
+	if err != nil {
+		return NewError("datastore.UserService.ChangeEmail", err)
+	}
+	user.Email = email
+	user.Unverified = false
+	return nil
+}
+
+//userFields - the properties that record which user did something, blanked when the user
+// is deleted so the family keeps the records but not who they were
+var userFields = []struct {
+	kind  string
+	field string
+}{
+	{ChildKind, "ParentID"},
+	{FeedingKind, "UserID"},
+	{SleepKind, "UserID"},
+	{WasteKind, "UserID"},
+	{NotificationKind, "AcknowledgedBy"},
+}
+
+//Delete - delete the user's account.  they leave all their families first, families they
+// run go to another member or are deleted with them if they were the only one.  what they
+// recorded in families that carry on is kept without their name on it.
+func (s *UserService) Delete(ctx context.Context, user *goparent.User) error {
+	families, err := s.GetAllFamily(ctx, user)
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
+	}
+	userKey := datastore.NewKey(ctx, UserKind, user.ID, 0, nil)
+	err = datastore.Delete(ctx, userKey)
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
+	}
+	err = goparent.LeaveFamilies(ctx, &FamilyService{Env: s.Env}, user, families)
+	if err != nil {
+		return err
+	}
+
+	//invites they sent are kept under their key, pending ones to them by email
+	keys, err := datastore.NewQuery(InviteKind).Ancestor(userKey).KeysOnly().GetAll(ctx, nil)
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
+	}
+	var invites []*goparent.UserInvitation
+	inviteKeys, err := datastore.NewQuery(InviteKind).Filter("InviteEmail =", user.Email).GetAll(ctx, &invites)
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
+	}
+	now := time.Now()
+	for i, invite := range invites {
+		if invite.CurrentStatus(now) == goparent.InvitationPending {
+			keys = append(keys, inviteKeys[i])
+		}
+	}
+	resetKeys, err := datastore.NewQuery(ResetKind).Filter("Email =", user.Email).KeysOnly().GetAll(ctx, nil)
 	if err != nil {
-		return NewError("datastore.ResetPassword d", err)
+		return NewError("datastore.UserService.Delete", err)
+	}
+	apiKeys, err := datastore.NewQuery(APIKeyKind).Ancestor(userKey).KeysOnly().GetAll(ctx, nil)
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
+	}
+	identityKeys, err := datastore.NewQuery(IdentityKind).Filter("UserID =", user.ID).KeysOnly().GetAll(ctx, nil)
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
+	}
+	keys = append(append(keys, resetKeys...), apiKeys...)
+	err = datastore.DeleteMulti(ctx, append(keys, identityKeys...))
+	if err != nil {
+		return NewError("datastore.UserService.Delete", err)
 	}
 
+	for _, uf := range userFields {
+		var entities []datastore.PropertyList
+		keys, err := datastore.NewQuery(uf.kind).Filter(uf.field+" =", user.ID).GetAll(ctx, &entities)
+		if err != nil {
+			return NewError("datastore.UserService.Delete", err)
+		}
+		for _, entity := range entities {
+			for i := range entity {
+				if entity[i].Name == uf.field {
+					entity[i].Value = ""
+				}
+			}
+		}
+		_, err = datastore.PutMulti(ctx, keys, entities)
+		if err != nil {
+			return NewError("datastore.UserService.Delete", err)
+		}
+	}
 	return nil
 }
 
+//userKey - the key a user is saved under.  existing users keep the key they have, new
+// ones are keyed by their email unless that key still belongs to someone who has since
+// changed their email.  new users can't take an email someone already has, whatever
+// key that someone is under.
+func (s *UserService) userKey(ctx context.Context, user *goparent.User) (*datastore.Key, error) {
+	if user.ID != "" {
+		return datastore.NewKey(ctx, UserKind, user.ID, 0, nil), nil
+	}
+	_, err := s.userByEmail(ctx, user.Email)
+	if err == nil {
+		return nil, goparent.ErrExistingUser
+	}
+	if err != ErrNoUserFound {
+		return nil, err
+	}
+
+	userKey := datastore.NewKey(ctx, UserKind, md5Email(user.Email), 0, nil)
+	var existing goparent.User
+	err = datastore.Get(ctx, userKey, &existing)
+	if err == datastore.ErrNoSuchEntity {
+		return userKey, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	//the key's user changed their email away from this one
+	return datastore.NewKey(ctx, UserKind, uuid.New().String(), 0, nil), nil
+}
+
+//userByEmail - the user with the email, ErrNoUserFound if there isn't one
+func (s *UserService) userByEmail(ctx context.Context, email string) (*goparent.User, error) {
+	var users []*goparent.User
+	_, err := datastore.NewQuery(UserKind).Filter("Email =", email).Limit(1).GetAll(ctx, &users)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, ErrNoUserFound
+	}
+	return users[0], nil
+}
+
 //util functions
 func md5Email(email string) string {
 	h := md5.New()
 	h.Write([]byte(email))
 	return hex.EncodeToString(h.Sum(nil))
 }
-
-func encodeInt(i int64) string {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, uint64(i))
-	return base64.StdEncoding.EncodeToString(b)
-}
-
-func decodeBase64(encoded string) int64 {
-	decoded, _ := base64.StdEncoding.DecodeString(encoded)
-	return int64(binary.LittleEndian.Uint64(decoded))
-}
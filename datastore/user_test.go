@@ -89,4 +89,39 @@ func TestDatastoreUser(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.EqualError(t, err, "no result for that username password combo")
 
+	//change email, the user keeps their key and logs in with the new address
+	err = us.ChangeEmail(ctx, loggedInUser, "changed@test.com")
+	assert.Nil(t, err)
+	changedUser, err := us.UserByLogin(ctx, "changed@test.com", "testing")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, changedUser.ID)
+	_, err = us.UserByLogin(ctx, "test@test.com", "testing")
+	assert.EqualError(t, err, "no result for that username password combo")
+
+	//nobody can sign up with the new address, even though it isn't the key's
+	taken := goparent.User{Name: "taken", Email: "changed@test.com", Password: "other"}
+	assert.ErrorIs(t, us.Save(ctx, &taken), goparent.ErrExistingUser)
+
+	//someone else can sign up with the old address, the changed user is still under its key
+	// so the new user gets one of their own
+	newUser := goparent.User{Name: "new", Email: "test@test.com", Password: "other"}
+	err = us.Save(ctx, &newUser)
+	assert.Nil(t, err)
+	assert.NotEqual(t, user.ID, newUser.ID)
+	assert.Equal(t, goparent.ErrExistingUser, us.ChangeEmail(ctx, &newUser, "changed@test.com"))
+	oldAddress, err := us.UserByLogin(ctx, "test@test.com", "other")
+	assert.Nil(t, err)
+	assert.Equal(t, newUser.ID, oldAddress.ID)
+	changedUser, err = us.UserByLogin(ctx, "changed@test.com", "testing")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, changedUser.ID)
+	assert.ErrorIs(t, us.Save(ctx, &goparent.User{Name: "again", Email: "test@test.com", Password: "other"}), goparent.ErrExistingUser)
+
+	//delete the account, the family they were alone in goes with them
+	err = us.Delete(ctx, changedUser)
+	assert.Nil(t, err)
+	_, err = us.User(ctx, user.ID)
+	assert.NotNil(t, err)
+	_, err = us.UserByLogin(ctx, "changed@test.com", "testing")
+	assert.NotNil(t, err)
 }
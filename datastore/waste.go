@@ -2,6 +2,7 @@ package datastore
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,9 @@ func (s *WasteService) Save(ctx context.Context, waste *goparent.Waste) error {
 	var wasteKey *datastore.Key
 	familyKey := datastore.NewKey(ctx, FamilyKind, waste.FamilyID, 0, nil)
 	childKey := datastore.NewKey(ctx, ChildKind, waste.ChildID, 0, familyKey)
+	eventType := goparent.EventUpdated
 	if waste.ID == "" {
+		eventType = goparent.EventCreated
 		u := uuid.New()
 		wasteKey = datastore.NewKey(ctx, WasteKind, u.String(), 0, childKey)
 		waste.CreatedAt = time.Now()
@@ -37,6 +40,7 @@ func (s *WasteService) Save(ctx context.Context, waste *goparent.Waste) error {
 	if err != nil {
 		return NewError("WasteService.Save", err)
 	}
+	s.Env.Publish(goparent.Event{Type: eventType, Resource: goparent.ResourceWaste, FamilyID: waste.FamilyID, ChildID: waste.ChildID, Data: waste, SharedWith: childFamilies(ctx, s.Env, waste.ChildID)})
 	return nil
 }
 
@@ -61,54 +65,50 @@ func (s *WasteService) Waste(ctx context.Context, family *goparent.Family, days
 		}
 		wastes = append(wastes, &waste)
 	}
-	return wastes, nil
-}
-
-//Stats returns the stats for a particular child
-func (s *WasteService) Stats(ctx context.Context, child *goparent.Child) (*goparent.WasteSummary, error) {
-	var wastes []goparent.Waste
-	end := time.Now()
-	start := end.AddDate(0, 0, -1)
 
-	familyKey := datastore.NewKey(ctx, FamilyKind, child.FamilyID, 0, nil)
-	childKey := datastore.NewKey(ctx, ChildKind, child.ID, 0, familyKey)
-
-	q := datastore.NewQuery(WasteKind).Filter("ChildID=", childKey).Filter("TimeStamp >=", start).Order("-TimeStamp")
-	itx := q.Run(ctx)
-	for {
-		var waste goparent.Waste
-		_, err := itx.Next(&waste)
-		if err == datastore.Done {
-			break
-		}
+	//wastes other households logged for children shared with or by the family
+	children, err := sharedChildren(ctx, s.Env, family)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		var logged []*goparent.Waste
+		_, err = datastore.NewQuery(WasteKind).Filter("ChildID =", child.ID).Filter("TimeStamp >", start).GetAll(ctx, &logged)
 		if err != nil {
 			return nil, err
 		}
-		wastes = append(wastes, waste)
+		for _, waste := range logged {
+			if waste.FamilyID != family.ID {
+				wastes = append(wastes, waste)
+			}
+		}
 	}
+	sort.Slice(wastes, func(i, j int) bool { return wastes[i].TimeStamp.After(wastes[j].TimeStamp) })
+	return wastes, nil
+}
 
-	summary := goparent.WasteSummary{
-		Data:  wastes,
-		Total: make(map[int]int),
+//Stats returns the stats for a particular child inside of the query window
+func (s *WasteService) Stats(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.WasteSummary, error) {
+	wastes, err := s.childWastes(ctx, child, query)
+	if err != nil {
+		return nil, NewError("WasteService.Stats", err)
 	}
+	return goparent.NewWasteSummary(wastes), nil
+}
 
-	for _, x := range wastes {
-		if _, ok := summary.Total[x.Type]; !ok {
-			summary.Total[x.Type] = 0
-		}
-		summary.Total[x.Type]++
+//GraphData returns the data necessary for graphing information about the child
+func (s *WasteService) GraphData(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.WasteChartData, error) {
+	wastes, err := s.childWastes(ctx, child, query)
+	if err != nil {
+		return nil, NewError("WasteService.GraphData", err)
 	}
-	return &summary, nil
+	return goparent.NewWasteChartData(query, wastes), nil
 }
 
-//GraphData returns the data necessary for graphing information about the child
-func (s *WasteService) GraphData(ctx context.Context, child *goparent.Child) (*goparent.WasteChartData, error) {
+//childWastes returns the wastes for a child inside of the query window, newest first.
+func (s *WasteService) childWastes(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) ([]goparent.Waste, error) {
 	var wastes []goparent.Waste
-	wasteCounts := make(map[time.Time][]goparent.Waste)
-	end := time.Now()
-	start := end.AddDate(0, 0, -7)
-	q := datastore.NewQuery(WasteKind).Filter("ChildID =", child.ID).Filter("TimeStamp >", start).Filter("TimeStamp <=", end).Order("-TimeStamp")
-	//get each item from the query organize them by day.
+	q := datastore.NewQuery(WasteKind).Filter("ChildID =", child.ID).Filter("TimeStamp >=", query.Start).Filter("TimeStamp <", query.End).Order("-TimeStamp")
 	itx := q.Run(ctx)
 	for {
 		var waste goparent.Waste
@@ -119,31 +119,7 @@ func (s *WasteService) GraphData(ctx context.Context, child *goparent.Child) (*g
 		if err != nil {
 			return nil, err
 		}
-		roundedDate := RoundToDay(waste.TimeStamp, false)
-		wasteCounts[roundedDate] = append(wasteCounts[roundedDate], waste)
 		wastes = append(wastes, waste)
 	}
-
-	chartData := &goparent.WasteChartData{
-		Start:   start,
-		End:     end,
-		Dataset: make([]goparent.WasteChartDataset, 1),
-	}
-
-	//now organize each day by the total of each type. setup dataset
-	for day, wastes := range wasteCounts {
-		counts := make(map[int]int)
-		for _, t := range wastes {
-			counts[t.Type]++
-		}
-		for wasteType, count := range counts {
-			chartData.Dataset = append(chartData.Dataset, goparent.WasteChartDataset{
-				Date:  day,
-				Type:  wasteType,
-				Count: count,
-			})
-		}
-	}
-
-	return chartData, nil
+	return wastes, nil
 }
@@ -0,0 +1,92 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sasimpson/goparent"
+	"google.golang.org/appengine/datastore"
+)
+
+//WebhookService -
+type WebhookService struct {
+	Env *goparent.Env
+}
+
+//WebhookKind and WebhookDeliveryKind are the kinds for webhooks and their delivery log in gcp datastore
+const (
+	WebhookKind         = "Webhook"
+	WebhookDeliveryKind = "WebhookDelivery"
+)
+
+//Save creates or updates a webhook, webhooks are children of their family
+func (s *WebhookService) Save(ctx context.Context, webhook *goparent.Webhook) error {
+	familyKey := datastore.NewKey(ctx, FamilyKind, webhook.FamilyID, 0, nil)
+	webhook.LastUpdated = time.Now()
+	if webhook.ID == "" {
+		webhook.ID = uuid.New().String()
+		webhook.CreatedAt = webhook.LastUpdated
+	}
+	webhookKey := datastore.NewKey(ctx, WebhookKind, webhook.ID, 0, familyKey)
+
+	_, err := datastore.Put(ctx, webhookKey, webhook)
+	if err != nil {
+		return NewError("WebhookService.Save", err)
+	}
+	return nil
+}
+
+//Webhook returns the webhook with the id
+func (s *WebhookService) Webhook(ctx context.Context, id string) (*goparent.Webhook, error) {
+	var webhook goparent.Webhook
+	q := datastore.NewQuery(WebhookKind).Filter("ID =", id)
+	itx := q.Run(ctx)
+	_, err := itx.Next(&webhook)
+	if err == datastore.Done {
+		return nil, NewError("WebhookService.Webhook", goparent.ErrNoWebhookFound)
+	}
+	if err != nil {
+		return nil, NewError("WebhookService.Webhook", err)
+	}
+	return &webhook, nil
+}
+
+//Webhooks returns all of a family's webhooks, oldest first
+func (s *WebhookService) Webhooks(ctx context.Context, family *goparent.Family) ([]*goparent.Webhook, error) {
+	var webhooks []*goparent.Webhook
+	familyKey := datastore.NewKey(ctx, FamilyKind, family.ID, 0, nil)
+	q := datastore.NewQuery(WebhookKind).Ancestor(familyKey).Order("CreatedAt")
+	_, err := q.GetAll(ctx, &webhooks)
+	if err != nil {
+		return nil, NewError("WebhookService.Webhooks", err)
+	}
+	return webhooks, nil
+}
+
+//SaveDelivery adds an attempt to the delivery log, deliveries are children of their webhook
+func (s *WebhookService) SaveDelivery(ctx context.Context, delivery *goparent.WebhookDelivery) error {
+	familyKey := datastore.NewKey(ctx, FamilyKind, delivery.FamilyID, 0, nil)
+	webhookKey := datastore.NewKey(ctx, WebhookKind, delivery.WebhookID, 0, familyKey)
+	delivery.ID = uuid.New().String()
+	deliveryKey := datastore.NewKey(ctx, WebhookDeliveryKind, delivery.ID, 0, webhookKey)
+
+	_, err := datastore.Put(ctx, deliveryKey, delivery)
+	if err != nil {
+		return NewError("WebhookService.SaveDelivery", err)
+	}
+	return nil
+}
+
+//Deliveries returns the most recent delivery attempts for a webhook, newest first
+func (s *WebhookService) Deliveries(ctx context.Context, webhook *goparent.Webhook, limit int) ([]*goparent.WebhookDelivery, error) {
+	var deliveries []*goparent.WebhookDelivery
+	familyKey := datastore.NewKey(ctx, FamilyKind, webhook.FamilyID, 0, nil)
+	webhookKey := datastore.NewKey(ctx, WebhookKind, webhook.ID, 0, familyKey)
+	q := datastore.NewQuery(WebhookDeliveryKind).Ancestor(webhookKey).Order("-Timestamp").Limit(limit)
+	_, err := q.GetAll(ctx, &deliveries)
+	if err != nil {
+		return nil, NewError("WebhookService.Deliveries", err)
+	}
+	return deliveries, nil
+}
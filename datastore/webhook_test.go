@@ -0,0 +1,40 @@
+package datastore_test
+
+import (
+	"testing"
+
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/datastore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestDatastoreWebhook(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	defer done()
+	if err != nil {
+		t.Error("error", err)
+	}
+
+	webhookService := datastore.WebhookService{}
+
+	nilWebhook, err := webhookService.Webhook(ctx, "123")
+	assert.Nil(t, nilWebhook)
+	assert.NotNil(t, err)
+
+	webhook := &goparent.Webhook{FamilyID: "1", URL: "http://localhost/hook", Events: []string{"sleep.*"}}
+	err = webhookService.Save(ctx, webhook)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, webhook.ID)
+
+	webhooks, err := webhookService.Webhooks(ctx, &goparent.Family{ID: "1"})
+	assert.Nil(t, err)
+	assert.Len(t, webhooks, 1)
+
+	err = webhookService.SaveDelivery(ctx, &goparent.WebhookDelivery{WebhookID: webhook.ID, FamilyID: "1", Attempt: 1, StatusCode: 200})
+	assert.Nil(t, err)
+
+	deliveries, err := webhookService.Deliveries(ctx, webhook, 10)
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1)
+}
@@ -0,0 +1,180 @@
+package goparent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//EventType - what happened to a resource
+type EventType string
+
+//Event types.  sleeps also have started and ended events when a sleep is started or
+// ended from the sleep toggle rather than saved whole.
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+	EventStarted EventType = "started"
+	EventEnded   EventType = "ended"
+)
+
+//Resources that events are published for
+const (
+	ResourceFamily  = "family"
+	ResourceChild   = "child"
+	ResourceFeeding = "feeding"
+	ResourceSleep   = "sleep"
+	ResourceWaste   = "waste"
+)
+
+//EventBufferSize - how many events a subscriber can fall behind by before it misses some
+const EventBufferSize = 64
+
+//Event - something that happened in a family.  Data is the resource after the change,
+// or before it for deletes.  SharedWith is the other families that can see the child the
+// event is about, e.g. the owning family when a sharing household logs a feeding.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       EventType   `json:"type"`
+	Resource   string      `json:"resource"`
+	FamilyID   string      `json:"familyID"`
+	ChildID    string      `json:"childID,omitempty"`
+	Time       time.Time   `json:"time"`
+	Data       interface{} `json:"data,omitempty"`
+	SharedWith []string    `json:"-"`
+}
+
+//Name - the resource and type, e.g. feeding.created
+func (e Event) Name() string {
+	return e.Resource + "." + string(e.Type)
+}
+
+//Families - every family the event is for, its own family first and no family twice
+func (e Event) Families() []string {
+	families := []string{e.FamilyID}
+	for _, familyID := range e.SharedWith {
+		if familyID != "" && !contains(families, familyID) {
+			families = append(families, familyID)
+		}
+	}
+	return families
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//EventBus - delivers events to everyone watching a family.  Close ends every subscription,
+// it's for shutting down so streams don't hold the service open.
+type EventBus interface {
+	Publish(Event)
+	Subscribe(familyID string) (<-chan Event, func())
+	Close()
+}
+
+//EventSource - what publishes events on the bus.  Err is why it isn't publishing, nil while
+// it is, so the service can report it as not ready.
+type EventSource interface {
+	Err() error
+}
+
+//NewEventBus - an in memory bus for one service instance.  publishing never blocks,
+// subscribers that fall more than EventBufferSize events behind miss events.
+func NewEventBus() EventBus {
+	return &memoryBus{subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+type memoryBus struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+	closed      bool
+}
+
+//Publish - send the event to the subscribers of each of its families, the id and time
+// are filled in if they're blank.
+func (b *memoryBus) Publish(e Event) {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, familyID := range e.Families() {
+		b.send(b.subscribers[familyID], e)
+	}
+	if e.FamilyID != "" {
+		b.send(b.subscribers[""], e)
+	}
+}
+
+func (b *memoryBus) send(subscribers map[chan Event]struct{}, e Event) {
+	for ch := range subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+//Subscribe - get the events for a family until cancel is called, cancel closes the channel.
+// a blank family id gets every family's events.  once the bus is closed the channel comes
+// back closed.
+func (b *memoryBus) Subscribe(familyID string) (<-chan Event, func()) {
+	ch := make(chan Event, EventBufferSize)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[familyID] == nil {
+		b.subscribers[familyID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[familyID][ch] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		//the channel is already closed if the bus was
+		if _, ok := b.subscribers[familyID][ch]; !ok {
+			return
+		}
+		delete(b.subscribers[familyID], ch)
+		if len(b.subscribers[familyID]) == 0 {
+			delete(b.subscribers, familyID)
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+//Close - close every subscriber's channel
+func (b *memoryBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	b.subscribers = make(map[string]map[chan Event]struct{})
+}
+
+//Publish - publish an event on the env's bus, if it has one
+func (env *Env) Publish(e Event) {
+	if env == nil || env.Events == nil {
+		return
+	}
+	env.Events.Publish(e)
+}
@@ -0,0 +1,82 @@
+package goparent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	family, cancelFamily := bus.Subscribe("1")
+	other, cancelOther := bus.Subscribe("2")
+	defer cancelOther()
+
+	bus.Publish(Event{Type: EventCreated, Resource: ResourceFeeding, FamilyID: "1", ChildID: "1"})
+	event := <-family
+	assert.Equal(t, "feeding.created", event.Name())
+	assert.NotEmpty(t, event.ID)
+	assert.False(t, event.Time.IsZero())
+	assert.Len(t, other, 0)
+
+	//a subscriber that falls behind misses events rather than holding up the publisher
+	for i := 0; i < EventBufferSize+10; i++ {
+		bus.Publish(Event{Type: EventUpdated, Resource: ResourceChild, FamilyID: "1"})
+	}
+	assert.Len(t, family, EventBufferSize)
+
+	cancelFamily()
+	cancelFamily()
+	for range family {
+	}
+	bus.Publish(Event{Type: EventDeleted, Resource: ResourceChild, FamilyID: "1"})
+}
+
+func TestEventBusSharedChild(t *testing.T) {
+	bus := NewEventBus()
+	owner, cancelOwner := bus.Subscribe("1")
+	defer cancelOwner()
+	sharing, cancelSharing := bus.Subscribe("2")
+	defer cancelSharing()
+	all, cancelAll := bus.Subscribe("")
+	defer cancelAll()
+
+	//the other household logs a feeding for the child the first family shared with them
+	bus.Publish(Event{Type: EventCreated, Resource: ResourceFeeding, FamilyID: "2", ChildID: "1", SharedWith: []string{"1", "2"}})
+	assert.Equal(t, "2", (<-owner).FamilyID)
+	assert.Equal(t, "2", (<-sharing).FamilyID)
+	assert.Len(t, sharing, 0, "a family gets an event once")
+	<-all
+	assert.Len(t, all, 0)
+}
+
+func TestEventBusClose(t *testing.T) {
+	bus := NewEventBus()
+	family, cancel := bus.Subscribe("1")
+	all, _ := bus.Subscribe("")
+
+	bus.Close()
+	_, ok := <-family
+	assert.False(t, ok)
+	_, ok = <-all
+	assert.False(t, ok)
+	cancel()
+
+	late, cancelLate := bus.Subscribe("1")
+	_, ok = <-late
+	assert.False(t, ok, "subscriptions after the bus is closed end straight away")
+	cancelLate()
+	bus.Publish(Event{Type: EventCreated, Resource: ResourceFeeding, FamilyID: "1"})
+}
+
+func TestEnvPublish(t *testing.T) {
+	var env *Env
+	env.Publish(Event{FamilyID: "1"})
+	(&Env{}).Publish(Event{FamilyID: "1"})
+
+	bus := NewEventBus()
+	events, cancel := bus.Subscribe("1")
+	defer cancel()
+	(&Env{Events: bus}).Publish(Event{Type: EventEnded, Resource: ResourceSleep, FamilyID: "1"})
+	assert.Equal(t, "sleep.ended", (<-events).Name())
+}
@@ -0,0 +1,100 @@
+package goparent
+
+import (
+	"context"
+	"errors"
+)
+
+//ErrFamilyAdmin - the admin has to hand the family to someone else before they can leave it
+var ErrFamilyAdmin = errors.New("the family admin can't leave the family, transfer ownership first")
+
+//ErrNotFamilyMember - the user isn't a member of the family
+var ErrNotFamilyMember = errors.New("user is not a member of the family")
+
+//RemoveMember - take a member and their role out of the family.  the admin can't be removed.
+func (family *Family) RemoveMember(userID string) error {
+	if userID == family.Admin {
+		return ErrFamilyAdmin
+	}
+	if !family.IsMember(userID) {
+		return ErrNotFamilyMember
+	}
+	members := make([]string, 0, len(family.Members))
+	for _, member := range family.Members {
+		if member != userID {
+			members = append(members, member)
+		}
+	}
+	family.Members = members
+	family.SetRole(userID, RoleMember)
+	return nil
+}
+
+//TransferAdmin - make another member the admin, the old admin stays on as a member
+func (family *Family) TransferAdmin(userID string) error {
+	if !family.IsMember(userID) {
+		return ErrNotFamilyMember
+	}
+	family.SetRole(userID, RoleMember)
+	family.Admin = userID
+	return nil
+}
+
+//NextFamily - the family a user should switch to when they leave or lose the family
+// with gone's id, blank if they don't belong to any other.
+func NextFamily(user *User, families []*Family, gone string) string {
+	for _, family := range families {
+		if family.ID != gone && family.IsMember(user.ID) {
+			return family.ID
+		}
+	}
+	return ""
+}
+
+//Successor - the member who should take the family over when the admin goes, members
+// before caregivers.  blank if there is nobody else in the family.
+func (family *Family) Successor() string {
+	successor := ""
+	for _, member := range family.Members {
+		if member == family.Admin {
+			continue
+		}
+		if family.Role(member) == RoleMember {
+			return member
+		}
+		if successor == "" {
+			successor = member
+		}
+	}
+	return successor
+}
+
+//LeaveFamilies - take a user out of every family they belong to, for when their account
+// is deleted.  families they run are handed to their successor, or deleted along with
+// their children and records when there's nobody left to hand them to.
+func LeaveFamilies(ctx context.Context, fs FamilyService, user *User, families []*Family) error {
+	//the account is going away, so there's no current family to move them out of
+	leaving := *user
+	leaving.CurrentFamily = ""
+	for _, family := range families {
+		if family.Admin == user.ID {
+			successor := family.Successor()
+			if successor == "" {
+				err := fs.Delete(ctx, family)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err := family.TransferAdmin(successor)
+			if err != nil {
+				return err
+			}
+		}
+		err := fs.RemoveMember(ctx, family, &leaving)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package goparent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFamilyRemoveMember(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		userID  string
+		err     error
+		members []string
+	}{
+		{desc: "remove member", userID: "2", members: []string{"1", "3"}},
+		{desc: "admin", userID: "1", err: ErrFamilyAdmin, members: []string{"1", "2", "3"}},
+		{desc: "not a member", userID: "4", err: ErrNotFamilyMember, members: []string{"1", "2", "3"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			family := &Family{ID: "1", Admin: "1", Members: []string{"1", "2", "3"}, Roles: []MemberRole{{UserID: "2", Role: RoleCaregiver}}}
+			err := family.RemoveMember(tC.userID)
+			assert.Equal(t, tC.err, err)
+			assert.Equal(t, tC.members, family.Members)
+			if err == nil {
+				assert.Empty(t, family.Roles)
+			}
+		})
+	}
+}
+
+func TestFamilyTransferAdmin(t *testing.T) {
+	family := &Family{ID: "1", Admin: "1", Members: []string{"1", "2"}, Roles: []MemberRole{{UserID: "2", Role: RoleCaregiver}}}
+	assert.Equal(t, ErrNotFamilyMember, family.TransferAdmin("3"))
+	assert.Nil(t, family.TransferAdmin("2"))
+	assert.Equal(t, "2", family.Admin)
+	assert.Equal(t, RoleAdmin, family.Role("2"))
+	assert.Equal(t, RoleMember, family.Role("1"))
+	assert.True(t, family.IsMember("1"))
+}
+
+func TestNextFamily(t *testing.T) {
+	user := &User{ID: "1"}
+	families := []*Family{
+		{ID: "1", Admin: "1", Members: []string{"1"}},
+		{ID: "2", Admin: "2", Members: []string{"2"}},
+		{ID: "3", Admin: "2", Members: []string{"2", "1"}},
+	}
+	assert.Equal(t, "3", NextFamily(user, families, "1"))
+	assert.Equal(t, "1", NextFamily(user, families, "3"))
+	assert.Equal(t, "", NextFamily(user, families[:2], "1"))
+}
+
+func TestFamilySuccessor(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		family    *Family
+		successor string
+	}{
+		{desc: "member first", family: &Family{Admin: "1", Members: []string{"1", "2", "3"}, Roles: []MemberRole{{UserID: "2", Role: RoleCaregiver}}}, successor: "3"},
+		{desc: "caregiver", family: &Family{Admin: "1", Members: []string{"1", "2"}, Roles: []MemberRole{{UserID: "2", Role: RoleCaregiver}}}, successor: "2"},
+		{desc: "alone", family: &Family{Admin: "1", Members: []string{"1"}}, successor: ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.successor, tC.family.Successor())
+		})
+	}
+}
+
+//leaveFamilyService - records what LeaveFamilies does to each family
+type leaveFamilyService struct {
+	FamilyService
+	deleted []string
+	removed []string
+}
+
+func (fs *leaveFamilyService) Delete(ctx context.Context, family *Family) error {
+	fs.deleted = append(fs.deleted, family.ID)
+	return nil
+}
+
+func (fs *leaveFamilyService) RemoveMember(ctx context.Context, family *Family, user *User) error {
+	if user.CurrentFamily != "" {
+		return ErrFamilyAdmin
+	}
+	err := family.RemoveMember(user.ID)
+	if err != nil {
+		return err
+	}
+	fs.removed = append(fs.removed, family.ID)
+	return nil
+}
+
+func TestLeaveFamilies(t *testing.T) {
+	user := &User{ID: "1", CurrentFamily: "1"}
+	families := []*Family{
+		{ID: "1", Admin: "1", Members: []string{"1"}},
+		{ID: "2", Admin: "1", Members: []string{"1", "2"}},
+		{ID: "3", Admin: "3", Members: []string{"3", "1"}},
+	}
+	fs := &leaveFamilyService{}
+	err := LeaveFamilies(context.Background(), fs, user, families)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, fs.deleted)
+	assert.Equal(t, []string{"2", "3"}, fs.removed)
+	assert.Equal(t, "2", families[1].Admin)
+	assert.Equal(t, []string{"2"}, families[1].Members)
+	assert.Equal(t, []string{"3"}, families[2].Members)
+	assert.Equal(t, "1", user.CurrentFamily)
+}
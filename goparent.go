@@ -2,12 +2,26 @@ package goparent
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/spf13/viper"
+)
+
+//Version and Commit - the build of the service, set at build time with
+// -ldflags "-X github.com/sasimpson/goparent.Version=v1.2.3 -X github.com/sasimpson/goparent.Commit=abc123"
+var (
+	Version = "dev"
+	Commit  = "unknown"
 )
 
 //Env - container for all environment configuraitons
@@ -15,12 +29,74 @@ type Env struct {
 	Service Service
 	DB      Datastore
 	Auth    Authentication
+	Events  EventBus
+	Mailer  Mailer
+	//OIDC - the openid connect providers users can log in with, by name
+	OIDC map[string]*OIDCProvider
 }
 
-//Service - structure for service configurations
+//Service - structure for service configurations.  a blank Host listens on every interface,
+// timeouts of zero are left off.
 type Service struct {
-	Host string
-	Port int
+	Host              string
+	Port              int
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	//ShutdownTimeout - how long in-flight requests get to finish when the service is stopped
+	ShutdownTimeout time.Duration
+	TLS             ServiceTLS
+}
+
+//ServiceTLS - how the service serves https.  CertFile and KeyFile are used if they're set,
+// otherwise certificates are fetched from let's encrypt for AutocertHosts if there are any.
+// autocert answers the acme tls-alpn challenge, so the service has to be reachable on 443.
+type ServiceTLS struct {
+	CertFile      string
+	KeyFile       string
+	AutocertHosts []string
+	AutocertDir   string
+	AutocertEmail string
+}
+
+//Addr - the address the service listens on
+func (s Service) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+//InitServiceConfig - read the service section of the config
+func InitServiceConfig() Service {
+	viper.SetDefault("service.host", "")
+	viper.SetDefault("service.port", 8000)
+	viper.SetDefault("service.readtimeout", "15s")
+	viper.SetDefault("service.readheadertimeout", "5s")
+	viper.SetDefault("service.writetimeout", "30s")
+	viper.SetDefault("service.idletimeout", "2m")
+	viper.SetDefault("service.shutdowntimeout", "20s")
+	viper.SetDefault("service.tls.autocertdir", "autocert")
+
+	return Service{
+		Host:              viper.GetString("service.host"),
+		Port:              viper.GetInt("service.port"),
+		ReadTimeout:       viper.GetDuration("service.readtimeout"),
+		ReadHeaderTimeout: viper.GetDuration("service.readheadertimeout"),
+		WriteTimeout:      viper.GetDuration("service.writetimeout"),
+		IdleTimeout:       viper.GetDuration("service.idletimeout"),
+		ShutdownTimeout:   viper.GetDuration("service.shutdowntimeout"),
+		TLS: ServiceTLS{
+			CertFile:      viper.GetString("service.tls.certfile"),
+			KeyFile:       viper.GetString("service.tls.keyfile"),
+			AutocertHosts: viper.GetStringSlice("service.tls.autocerthosts"),
+			AutocertDir:   viper.GetString("service.tls.autocertdir"),
+			AutocertEmail: viper.GetString("service.tls.autocertemail"),
+		},
+	}
+}
+
+//Enabled - whether the service serves https
+func (t ServiceTLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || len(t.AutocertHosts) > 0
 }
 
 //Authentication - structure for authentication configurations
@@ -34,6 +110,24 @@ type Datastore interface {
 	GetContext(*http.Request) context.Context
 }
 
+//ErrConnectionTimeout - the backend didn't answer in time
+var ErrConnectionTimeout = errors.New("timed out connecting to the backend")
+
+//CheckConnection - GetConnection, giving up after timeout so a hung backend doesn't hang
+// the caller too
+func CheckConnection(db Datastore, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- db.GetConnection()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return ErrConnectionTimeout
+	}
+}
+
 //ErrExistingInvitation -
 var ErrExistingInvitation = errors.New("invitation already exists for that parent")
 
@@ -43,14 +137,39 @@ var ErrExistingStart = errors.New("already have a start record")
 //ErrNoExistingSession - don't have a sleep record to end.
 var ErrNoExistingSession = errors.New("no existing sleep session to end")
 
-//User -
+//ErrNoUserFound - there is no user with that id
+var ErrNoUserFound = errors.New("no result for that id")
+
+//ErrInvalidLogin - the username and password combo do not match
+var ErrInvalidLogin = errors.New("no result for that username password combo")
+
+//ErrExistingUser - there is already a user with that email
+var ErrExistingUser = errors.New("a user with that email already exists")
+
+//ErrInvalidResetCode - the password reset code doesn't exist or has expired
+var ErrInvalidResetCode = errors.New("invalid code for reset")
+
+//ErrEmailNotVerified - the user hasn't confirmed they own their email yet
+var ErrEmailNotVerified = errors.New("the account's email hasn't been verified")
+
+//User - Unverified is set for users who signed up and haven't confirmed their email yet.
+// accounts from before verification, and ones made from an invite, don't need to.
+// TOTPSecret is set once two factor enrollment starts, it's only used for logins once
+// TOTPEnabled is set by confirming it.  TOTPLastStep is the time step of the last
+// authenticator code used, so a code can't be used twice.
 type User struct {
-	ID            string `json:"id" gorethink:"id,omitempty"`
-	Name          string `json:"name" gorethink:"name"`
-	Email         string `json:"email" gorethink:"email"`
-	Username      string `json:"username" gorethink:"username"`
-	Password      string `json:"-" gorethink:"password"`
-	CurrentFamily string `json:"currentFamily" gorethink:"currentFamily"`
+	ID                 string    `json:"id" gorethink:"id,omitempty"`
+	Name               string    `json:"name" gorethink:"name"`
+	Email              string    `json:"email" gorethink:"email"`
+	Username           string    `json:"username" gorethink:"username"`
+	Password           string    `json:"-" gorethink:"password"`
+	CurrentFamily      string    `json:"currentFamily" gorethink:"currentFamily"`
+	Unverified         bool      `json:"unverified" gorethink:"unverified"`
+	VerificationSentAt time.Time `json:"-" gorethink:"verificationSentAt"`
+	TOTPEnabled        bool      `json:"totpEnabled" gorethink:"totpEnabled"`
+	TOTPSecret         string    `json:"-" gorethink:"totpSecret"`
+	TOTPLastStep       int64     `json:"-" gorethink:"totpLastStep"`
+	RecoveryCodes      []string  `json:"-" gorethink:"recoveryCodes"`
 }
 
 func (user User) String() string {
@@ -67,10 +186,40 @@ type UserClaims struct {
 	jwt.StandardClaims
 }
 
+//UserReset - a password reset request.  only a hash of the code that was mailed is kept,
+// it's the reset's id, so the code can't be read back out of the database.
 type UserReset struct {
+	ID          string    `json:"-" gorethink:"id,omitempty" datastore:"-"`
 	Timestamp   time.Time `json:"timestamp"`
 	RequestAddr string    `json:"request_addr"`
 	Email       string    `json:"email"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+//ResetCodeExpiry - how long a password reset code can be used for
+const ResetCodeExpiry = time.Hour
+
+//NewResetCode - a random code to mail for a password reset and the hash to store it under
+func NewResetCode() (string, string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+	code := hex.EncodeToString(b)
+	return code, HashResetCode(code), nil
+}
+
+//HashResetCode - the hash a reset code is stored under
+func HashResetCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+//Expired - whether the reset code can no longer be used.  resets from before codes
+// expired don't have an expiry and are treated as expired.
+func (reset *UserReset) Expired(now time.Time) bool {
+	return !now.Before(reset.ExpiresAt)
 }
 
 //UserService -
@@ -84,33 +233,57 @@ type UserService interface {
 	GetAllFamily(context.Context, *User) ([]*Family, error)
 	ResetPassword(context.Context, string, string) error
 	RequestResetPassword(context.Context, string, string) error
+	ChangeEmail(context.Context, *User, string) error
+	Delete(context.Context, *User) error
 }
 
-//UserInvitation - structure for storing invitations
+//UserInvitation - structure for storing invitations.  the token that is mailed to the
+// invitee is only stored hashed, and is cleared once the invite is answered.
 type UserInvitation struct {
-	ID          string    `json:"id" gorethink:"id,omitempty"`
-	UserID      string    `json:"userID" gorethink:"userID"`
-	InviteEmail string    `json:"inviteEmail" gorethink:"inviteEmail"`
-	Timestamp   time.Time `json:"timestamp" gorethink:"timestamp"`
+	ID          string           `json:"id" gorethink:"id,omitempty"`
+	UserID      string           `json:"userID" gorethink:"userID"`
+	FamilyID    string           `json:"familyID" gorethink:"familyID"`
+	InviteEmail string           `json:"inviteEmail" gorethink:"inviteEmail"`
+	Role        string           `json:"role" gorethink:"role"`
+	Status      InvitationStatus `json:"status" gorethink:"status"`
+	TokenHash   string           `json:"-" gorethink:"tokenHash"`
+	Timestamp   time.Time        `json:"timestamp" gorethink:"timestamp"`
+	ExpiresAt   time.Time        `json:"expiresAt" gorethink:"expiresAt"`
+	AnsweredAt  time.Time        `json:"answeredAt" gorethink:"answeredAt"`
 }
 
 //UserInvitationService -
 type UserInvitationService interface {
-	InviteParent(context.Context, *User, string, time.Time) error
+	InviteParent(context.Context, *User, *UserInvitation) error
 	SentInvites(context.Context, *User) ([]*UserInvitation, error)
 	Invite(context.Context, string) (*UserInvitation, error)
+	InviteByToken(context.Context, string) (*UserInvitation, error)
 	Invites(context.Context, *User) ([]*UserInvitation, error)
 	Accept(context.Context, *User, string) error
+	Decline(context.Context, *User, string) error
+	Resend(context.Context, *User, *UserInvitation) error
 	Delete(context.Context, *UserInvitation) error
 }
 
 //Family -
 type Family struct {
-	ID          string    `json:"id" gorethink:"id,omitempty"`
-	Admin       string    `json:"admin" gorethink:"admin"`
-	Members     []string  `json:"members" gorethink:"members"`
-	CreatedAt   time.Time `json:"created_at" gorethink:"created_at"`
-	LastUpdated time.Time `json:"last_updated" gorethink:"last_updated"`
+	ID          string       `json:"id" gorethink:"id,omitempty"`
+	Admin       string       `json:"admin" gorethink:"admin"`
+	Members     []string     `json:"members" gorethink:"members"`
+	Roles       []MemberRole `json:"roles,omitempty" gorethink:"roles"`
+	Timezone    string       `json:"timezone" gorethink:"timezone"`
+	Units       UnitSystem   `json:"units" gorethink:"units"`
+	CreatedAt   time.Time    `json:"created_at" gorethink:"created_at"`
+	LastUpdated time.Time    `json:"last_updated" gorethink:"last_updated"`
+}
+
+//Location - the family's time zone, day boundaries for summaries and graphs are
+// local midnight in this zone.  families without a time zone use UTC.
+func (family Family) Location() (*time.Location, error) {
+	if family.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(family.Timezone)
 }
 
 //FamilyService -
@@ -119,19 +292,21 @@ type FamilyService interface {
 	Family(context.Context, string) (*Family, error)
 	Children(context.Context, *Family) ([]*Child, error)
 	AddMember(context.Context, *Family, *User) error
+	RemoveMember(context.Context, *Family, *User) error
 	GetAdminFamily(context.Context, *User) (*Family, error)
-	// Delete(*Family) (int, error)
+	Delete(context.Context, *Family) error
 }
 
 //Child -
 type Child struct {
-	ID          string    `json:"id" gorethink:"id,omitempty"`
-	Name        string    `json:"name" gorethink:"name"`
-	ParentID    string    `json:"parentID" gorethink:"parentID"`
-	FamilyID    string    `json:"familyID" gorethink:"familyID"`
-	Birthday    time.Time `json:"birthday" gorethink:"birthday"`
-	CreatedAt   time.Time `json:"created_at" gorethink:"created_at"`
-	LastUpdated time.Time `json:"last_updated" gorethink:"last_updated"`
+	ID          string       `json:"id" gorethink:"id,omitempty"`
+	Name        string       `json:"name" gorethink:"name"`
+	ParentID    string       `json:"parentID" gorethink:"parentID"`
+	FamilyID    string       `json:"familyID" gorethink:"familyID"`
+	Birthday    time.Time    `json:"birthday" gorethink:"birthday"`
+	Shares      []ChildShare `json:"shares,omitempty" gorethink:"shares"`
+	CreatedAt   time.Time    `json:"created_at" gorethink:"created_at"`
+	LastUpdated time.Time    `json:"last_updated" gorethink:"last_updated"`
 }
 
 func (child Child) String() string {
@@ -150,6 +325,7 @@ type Feeding struct {
 	ID          string    `json:"id" gorethink:"id,omitempty"`
 	Type        string    `json:"feedingType" gorethink:"feedingType"`
 	Amount      float32   `json:"feedingAmount" gorethink:"feedingAmount"`
+	Unit        Unit      `json:"feedingUnit" gorethink:"feedingUnit"`
 	Side        string    `json:"feedingSide" gorethink:"feedingSide,omitempty"`
 	UserID      string    `json:"userid" gorethink:"userID"`
 	FamilyID    string    `json:"familyid" gorethink:"familyID"`
@@ -159,19 +335,22 @@ type Feeding struct {
 	LastUpdated time.Time `json:"lastUpdated" gorethink:"lastUpdated"`
 }
 
-//FeedingSummary - represents feeding summary data
+//FeedingSummary - represents feeding summary data.  totals and means are keyed by
+// feeding type and are in the unit for that key.
 type FeedingSummary struct {
 	Data  []Feeding          `json:"data"`
 	Total map[string]float32 `json:"total"`
 	Mean  map[string]float32 `json:"mean"`
 	Range map[string]int     `json:"range"`
+	Unit  map[string]Unit    `json:"unit"`
 }
 
 //FeedingChartData -
 type FeedingChartData struct {
-	Start   time.Time             `json:"start"`
-	End     time.Time             `json:"end"`
-	Dataset []FeedingChartDataset `json:"dataset"`
+	Start       time.Time             `json:"start"`
+	End         time.Time             `json:"end"`
+	Granularity Granularity           `json:"granularity"`
+	Dataset     []FeedingChartDataset `json:"dataset"`
 }
 
 //FeedingChartDataset -
@@ -180,14 +359,15 @@ type FeedingChartDataset struct {
 	Type  string    `json:"type"`
 	Count int       `json:"count"`
 	Sum   float32   `json:"sum"`
+	Unit  Unit      `json:"unit"`
 }
 
 //FeedingService -
 type FeedingService interface {
 	Save(context.Context, *Feeding) error
 	Feeding(context.Context, *Family, uint64) ([]*Feeding, error)
-	Stats(context.Context, *Child) (*FeedingSummary, error)
-	GraphData(context.Context, *Child) (*FeedingChartData, error)
+	Stats(context.Context, *Child, *DataQuery) (*FeedingSummary, error)
+	GraphData(context.Context, *Child, *DataQuery) (*FeedingChartData, error)
 }
 
 //Sleep - tracks the baby's sleep start and end.
@@ -212,9 +392,10 @@ type SleepSummary struct {
 
 //SleepChartData -
 type SleepChartData struct {
-	Start   time.Time           `json:"start"`
-	End     time.Time           `json:"end"`
-	Dataset []SleepChartDataset `json:"dataset"`
+	Start       time.Time           `json:"start"`
+	End         time.Time           `json:"end"`
+	Granularity Granularity         `json:"granularity"`
+	Dataset     []SleepChartDataset `json:"dataset"`
 }
 
 //SleepChartDataset -
@@ -227,11 +408,11 @@ type SleepChartDataset struct {
 type SleepService interface {
 	Save(context.Context, *Sleep) error
 	Sleep(context.Context, *Family, uint64) ([]*Sleep, error)
-	Stats(context.Context, *Child) (*SleepSummary, error)
+	Stats(context.Context, *Child, *DataQuery) (*SleepSummary, error)
 	Status(context.Context, *Family, *Child) (*Sleep, bool, error)
 	Start(context.Context, *Family, *Child) error
 	End(context.Context, *Family, *Child) error
-	GraphData(context.Context, *Child) (*SleepChartData, error)
+	GraphData(context.Context, *Child, *DataQuery) (*SleepChartData, error)
 }
 
 //Waste - structure for holding waste data such as diapers
@@ -269,15 +450,16 @@ type WasteType struct {
 type WasteService interface {
 	Save(context.Context, *Waste) error
 	Waste(context.Context, *Family, uint64) ([]*Waste, error)
-	Stats(context.Context, *Child) (*WasteSummary, error)
-	GraphData(context.Context, *Child) (*WasteChartData, error)
+	Stats(context.Context, *Child, *DataQuery) (*WasteSummary, error)
+	GraphData(context.Context, *Child, *DataQuery) (*WasteChartData, error)
 }
 
 //wasteChartData -
 type WasteChartData struct {
-	Start   time.Time           `json:"start"`
-	End     time.Time           `json:"end"`
-	Dataset []WasteChartDataset `json:"dataset"`
+	Start       time.Time           `json:"start"`
+	End         time.Time           `json:"end"`
+	Granularity Granularity         `json:"granularity"`
+	Dataset     []WasteChartDataset `json:"dataset"`
 }
 
 //WasteChartDataset -
@@ -0,0 +1,190 @@
+package goparent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+//InvitationStatus - where an invitation is in its life
+type InvitationStatus string
+
+//Invitation statuses.  expired isn't saved, it's what a pending invite reads as once
+// ExpiresAt has passed.
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationExpired  InvitationStatus = "expired"
+)
+
+//Family roles.  the family's Admin is always admin, other members are member unless
+// they were invited with another role.
+const (
+	RoleAdmin     = "admin"
+	RoleMember    = "member"
+	RoleCaregiver = "caregiver"
+)
+
+//InviteRoles - the roles someone can be invited with
+var InviteRoles = []string{RoleMember, RoleCaregiver}
+
+//InvitationLifetime - how long an invite can be accepted for after it's sent
+const InvitationLifetime = 7 * 24 * time.Hour
+
+//ErrNoInvitationFound - there is no invite with that id or token
+var ErrNoInvitationFound = errors.New("no invitation found")
+
+//ErrInvitationExpired - the invite wasn't answered in time, it has to be resent
+var ErrInvitationExpired = errors.New("invitation has expired")
+
+//ErrInvitationAnswered - the invite has already been accepted or declined
+var ErrInvitationAnswered = errors.New("invitation has already been answered")
+
+//MemberRole - a family member's role
+type MemberRole struct {
+	UserID string `json:"userID" gorethink:"userID"`
+	Role   string `json:"role" gorethink:"role"`
+}
+
+//IsMember - whether the user belongs to the family
+func (family *Family) IsMember(userID string) bool {
+	for _, member := range family.Members {
+		if member == userID {
+			return true
+		}
+	}
+	return false
+}
+
+//Role - the member's role in the family
+func (family *Family) Role(userID string) string {
+	if userID == family.Admin {
+		return RoleAdmin
+	}
+	for _, role := range family.Roles {
+		if role.UserID == userID {
+			return role.Role
+		}
+	}
+	return RoleMember
+}
+
+//SetRole - record a member's role, members are members unless set otherwise
+func (family *Family) SetRole(userID string, role string) {
+	roles := family.Roles[:0]
+	for _, r := range family.Roles {
+		if r.UserID != userID {
+			roles = append(roles, r)
+		}
+	}
+	if role != "" && role != RoleMember {
+		roles = append(roles, MemberRole{UserID: userID, Role: role})
+	}
+	family.Roles = roles
+}
+
+//Validate - check an invite before it's sent
+func (invite UserInvitation) Validate(now time.Time) error {
+	rules := []rule{inviteEmail("email", invite.InviteEmail)}
+	if invite.Role != "" {
+		rules = append(rules, oneOf("role", invite.Role, InviteRoles))
+	}
+	return validate(rules...)
+}
+
+func inviteEmail(field string, value string) rule {
+	return func() *FieldError {
+		at := strings.LastIndex(value, "@")
+		if at < 1 || at == len(value)-1 || strings.ContainsAny(value, " <>") {
+			return &FieldError{field, "must be an email address"}
+		}
+		return nil
+	}
+}
+
+//Issue - give the invite a new token and expiry, any token it had before stops working.
+// the token is returned to be mailed, only its hash is kept.
+func (invite *UserInvitation) Issue(now time.Time) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	invite.TokenHash = HashInviteToken(token)
+	invite.Status = InvitationPending
+	invite.ExpiresAt = now.Add(InvitationLifetime)
+	if invite.Role == "" {
+		invite.Role = RoleMember
+	}
+	return token, nil
+}
+
+//HashInviteToken - the hash invites are looked up by
+func HashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+//CurrentStatus - the invite's status, pending invites past their expiry are expired.
+// invites from before invites had a status or expiry are pending for InvitationLifetime.
+func (invite *UserInvitation) CurrentStatus(now time.Time) InvitationStatus {
+	status := invite.Status
+	if status == "" {
+		status = InvitationPending
+	}
+	expires := invite.ExpiresAt
+	if expires.IsZero() {
+		expires = invite.Timestamp.Add(InvitationLifetime)
+	}
+	if status == InvitationPending && now.After(expires) {
+		return InvitationExpired
+	}
+	return status
+}
+
+//For - whether the invite was sent to the user's email
+func (invite *UserInvitation) For(user *User) bool {
+	return strings.EqualFold(strings.TrimSpace(invite.InviteEmail), strings.TrimSpace(user.Email))
+}
+
+//Answerable - why the user can't accept or decline the invite, nil if they can.  invites
+// for someone else aren't found.
+func (invite *UserInvitation) Answerable(user *User, now time.Time) error {
+	if !invite.For(user) {
+		return ErrNoInvitationFound
+	}
+	switch invite.CurrentStatus(now) {
+	case InvitationPending:
+		return nil
+	case InvitationExpired:
+		return ErrInvitationExpired
+	}
+	return ErrInvitationAnswered
+}
+
+//Answer - mark the invite accepted or declined, the token can't be used again
+func (invite *UserInvitation) Answer(status InvitationStatus, now time.Time) {
+	invite.Status = status
+	invite.AnsweredAt = now
+	invite.TokenHash = ""
+}
+
+//MailInvitation - mail the invite's token to the invitee
+func (env *Env) MailInvitation(ctx context.Context, from *User, invite *UserInvitation, token string) error {
+	msg, err := NewMail(MailInvitation, []string{invite.InviteEmail}, InvitationMail{
+		From:      from.Name,
+		Email:     invite.InviteEmail,
+		Token:     token,
+		ExpiresAt: invite.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	return env.Mail(ctx, msg)
+}
@@ -0,0 +1,131 @@
+package goparent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitationIssue(t *testing.T) {
+	now := time.Now()
+	invite := &UserInvitation{InviteEmail: "sam@test.com", Timestamp: now}
+	token, err := invite.Issue(now)
+	assert.Nil(t, err)
+	assert.Len(t, token, 64)
+	assert.Equal(t, HashInviteToken(token), invite.TokenHash)
+	assert.Equal(t, InvitationPending, invite.Status)
+	assert.Equal(t, RoleMember, invite.Role)
+	assert.Equal(t, now.Add(InvitationLifetime), invite.ExpiresAt)
+
+	//issuing again replaces the token
+	again, err := invite.Issue(now)
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, again)
+	assert.Equal(t, HashInviteToken(again), invite.TokenHash)
+}
+
+func TestInvitationValidate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		invite UserInvitation
+		valid  bool
+	}{
+		{desc: "email only", invite: UserInvitation{InviteEmail: "sam@test.com"}, valid: true},
+		{desc: "caregiver", invite: UserInvitation{InviteEmail: "sam@test.com", Role: RoleCaregiver}, valid: true},
+		{desc: "not an email", invite: UserInvitation{InviteEmail: "sam"}},
+		{desc: "admin role", invite: UserInvitation{InviteEmail: "sam@test.com", Role: RoleAdmin}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.invite.Validate(time.Now())
+			assert.Equal(t, tC.valid, err == nil)
+		})
+	}
+}
+
+func TestInvitationAnswerable(t *testing.T) {
+	now := time.Now()
+	user := &User{ID: "2", Email: "Sam@Test.com"}
+	testCases := []struct {
+		desc   string
+		invite UserInvitation
+		user   *User
+		status InvitationStatus
+		err    error
+	}{
+		{
+			desc:   "pending",
+			invite: UserInvitation{InviteEmail: "sam@test.com", Status: InvitationPending, ExpiresAt: now.Add(time.Hour)},
+			user:   user,
+			status: InvitationPending,
+		},
+		{
+			desc:   "someone else's",
+			invite: UserInvitation{InviteEmail: "pat@test.com", Status: InvitationPending, ExpiresAt: now.Add(time.Hour)},
+			user:   user,
+			status: InvitationPending,
+			err:    ErrNoInvitationFound,
+		},
+		{
+			desc:   "expired",
+			invite: UserInvitation{InviteEmail: "sam@test.com", Status: InvitationPending, ExpiresAt: now.Add(-time.Hour)},
+			user:   user,
+			status: InvitationExpired,
+			err:    ErrInvitationExpired,
+		},
+		{
+			desc:   "declined",
+			invite: UserInvitation{InviteEmail: "sam@test.com", Status: InvitationDeclined, ExpiresAt: now.Add(-time.Hour)},
+			user:   user,
+			status: InvitationDeclined,
+			err:    ErrInvitationAnswered,
+		},
+		{
+			desc:   "from before invites expired",
+			invite: UserInvitation{InviteEmail: "sam@test.com", Timestamp: now.Add(-time.Hour)},
+			user:   user,
+			status: InvitationPending,
+		},
+		{
+			desc:   "old invite from before invites expired",
+			invite: UserInvitation{InviteEmail: "sam@test.com", Timestamp: now.Add(-InvitationLifetime - time.Hour)},
+			user:   user,
+			status: InvitationExpired,
+			err:    ErrInvitationExpired,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.status, tC.invite.CurrentStatus(now))
+			assert.Equal(t, tC.err, tC.invite.Answerable(tC.user, now))
+		})
+	}
+}
+
+func TestInvitationAnswer(t *testing.T) {
+	now := time.Now()
+	invite := &UserInvitation{InviteEmail: "sam@test.com"}
+	invite.Issue(now)
+	invite.Answer(InvitationAccepted, now)
+	assert.Equal(t, InvitationAccepted, invite.CurrentStatus(now.Add(2*InvitationLifetime)))
+	assert.Equal(t, now, invite.AnsweredAt)
+	assert.Empty(t, invite.TokenHash)
+}
+
+func TestFamilyRoles(t *testing.T) {
+	family := &Family{Admin: "1", Members: []string{"1", "2", "3"}}
+	assert.Equal(t, RoleAdmin, family.Role("1"))
+	assert.Equal(t, RoleMember, family.Role("2"))
+
+	family.SetRole("2", RoleCaregiver)
+	family.SetRole("3", RoleCaregiver)
+	assert.Equal(t, RoleCaregiver, family.Role("2"))
+	family.SetRole("2", RoleCaregiver)
+	assert.Len(t, family.Roles, 2)
+
+	family.SetRole("2", RoleMember)
+	assert.Equal(t, RoleMember, family.Role("2"))
+	assert.Equal(t, RoleCaregiver, family.Role("3"))
+	assert.Len(t, family.Roles, 1)
+}
@@ -0,0 +1,26 @@
+package goparent
+
+import (
+	"context"
+	"time"
+)
+
+//Lease - a named lease and who holds it until when
+type Lease struct {
+	Name    string    `json:"id" gorethink:"id"`
+	Holder  string    `json:"holder" gorethink:"holder"`
+	Expires time.Time `json:"expires" gorethink:"expires"`
+}
+
+//LeaseStore - leases let one instance of the service at a time do something every instance
+// could, like delivering webhooks for the events they all see
+type LeaseStore interface {
+	//Hold - take the lease for holder until now plus ttl if it's free or has expired, or renew
+	// it if holder has it already.  the lease is returned as it is afterwards, whoever has it.
+	Hold(ctx context.Context, name string, holder string, now time.Time, ttl time.Duration) (Lease, error)
+}
+
+//HeldBy - whether holder has the lease at now
+func (lease Lease) HeldBy(holder string, now time.Time) bool {
+	return lease.Holder == holder && now.Before(lease.Expires)
+}
@@ -0,0 +1,331 @@
+package goparent
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+//Mail templates.  each has a text and an html version in mail/, the text one also
+// defines the subject.
+const (
+	MailReset        = "reset"
+	MailInvitation   = "invitation"
+	MailDigest       = "digest"
+	MailEmailChange  = "email-change"
+	MailEmailChanged = "email-changed"
+	MailVerify       = "verify"
+)
+
+//DefaultMailFrom - the sender when the config doesn't set one
+const DefaultMailFrom = "GoParent <noreply@goparent.local>"
+
+//go:embed mail
+var mailFiles embed.FS
+
+var (
+	textTemplates = template.Must(template.ParseFS(mailFiles, "mail/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(mailFiles, "mail/*.html"))
+)
+
+//Message - an email with a text and html body
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+//Mailer - sends email
+type Mailer interface {
+	Send(context.Context, *Message) error
+}
+
+//ResetMail - data for the password reset email
+type ResetMail struct {
+	Name string
+	Code string
+}
+
+//InvitationMail - data for the invitation email, Token lets someone without an account
+// sign up and join in one step
+type InvitationMail struct {
+	From      string
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+//EmailChangeMail - data for the email sent to a new address to confirm it
+type EmailChangeMail struct {
+	Name      string
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+//VerifyMail - data for the email that confirms a new user owns their address
+type VerifyMail struct {
+	Name      string
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+//EmailChangedMail - data for the notice sent to the old address once the email is changed
+type EmailChangedMail struct {
+	Name  string
+	Email string
+}
+
+//DigestMail - data for the reminder digest email
+type DigestMail struct {
+	Name          string
+	Notifications []*Notification
+}
+
+//NewMail - render one of the mail templates into a message
+func NewMail(name string, to []string, data interface{}) (*Message, error) {
+	var subject, text, html bytes.Buffer
+	err := textTemplates.ExecuteTemplate(&subject, name+".subject", data)
+	if err != nil {
+		return nil, err
+	}
+	err = textTemplates.ExecuteTemplate(&text, name+".txt", data)
+	if err != nil {
+		return nil, err
+	}
+	err = htmlTemplates.ExecuteTemplate(&html, name+".html", data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+//Bytes - the message as a multipart/alternative email
+func (m *Message) Bytes(now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@goparent>\r\n", uuid.New().String())
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		_, err = io.WriteString(qp, part.content)
+		if err != nil {
+			return nil, err
+		}
+		qp.Close()
+	}
+	err := body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//SMTPMailer - sends mail through an smtp server, with plain auth if there's a username
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+//Send - send the message
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	msg = withFrom(msg, m.From)
+	body, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+strconv.Itoa(m.Port), auth, address(msg.From), msg.To, body)
+}
+
+//FileMailer - writes each message to an .eml file in Dir, for development
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+//Send - write the message to a new file
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	body, err := withFrom(msg, m.From).Bytes(now)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(m.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(m.Dir, name), body, 0o644)
+}
+
+//LogMailer - logs the text version of each message instead of sending it
+type LogMailer struct {
+	From string
+}
+
+//Send - log the message
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	msg = withFrom(msg, m.From)
+	log.Printf("mail from %s to %s: %s\n%s", msg.From, strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+	return nil
+}
+
+//withFrom - the message with the mailer's sender if it doesn't have one
+func withFrom(msg *Message, from string) *Message {
+	if msg.From != "" {
+		return msg
+	}
+	withFrom := *msg
+	withFrom.From = from
+	if withFrom.From == "" {
+		withFrom.From = DefaultMailFrom
+	}
+	return &withFrom
+}
+
+//address - the bare address from a From header like Name <address>
+func address(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(from[i+1:], ">")
+	}
+	return from
+}
+
+//MailConfig - how mail is sent.  Driver is smtp, file or log.
+type MailConfig struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Dir      string
+}
+
+//InitMailConfig - read the mail section of the config, mail is logged unless it says otherwise
+func InitMailConfig() MailConfig {
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.from", DefaultMailFrom)
+	viper.SetDefault("mail.port", 587)
+	viper.SetDefault("mail.dir", "mail")
+
+	return MailConfig{
+		Driver:   viper.GetString("mail.driver"),
+		From:     viper.GetString("mail.from"),
+		Host:     viper.GetString("mail.host"),
+		Port:     viper.GetInt("mail.port"),
+		Username: viper.GetString("mail.username"),
+		Password: viper.GetString("mail.password"),
+		Dir:      viper.GetString("mail.dir"),
+	}
+}
+
+//NewMailer - the mailer for a config
+func NewMailer(config MailConfig) (Mailer, error) {
+	switch config.Driver {
+	case "smtp":
+		if config.Host == "" {
+			return nil, fmt.Errorf("mail.host is required for the smtp mail driver")
+		}
+		return &SMTPMailer{Host: config.Host, Port: config.Port, Username: config.Username, Password: config.Password, From: config.From}, nil
+	case "file":
+		return &FileMailer{Dir: config.Dir, From: config.From}, nil
+	case "log", "":
+		return &LogMailer{From: config.From}, nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+}
+
+//Mail - send a message with the env's mailer, mail is logged if there isn't one
+func (env *Env) Mail(ctx context.Context, msg *Message) error {
+	if env == nil || env.Mailer == nil {
+		return (&LogMailer{}).Send(ctx, msg)
+	}
+	return env.Mailer.Send(ctx, msg)
+}
+
+//MailEmailChange - mail the token that confirms a new email address to that address
+func (env *Env) MailEmailChange(ctx context.Context, user *User, email string, token string, expires time.Time) error {
+	msg, err := NewMail(MailEmailChange, []string{email}, EmailChangeMail{
+		Name:      user.Name,
+		Email:     email,
+		Token:     token,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return err
+	}
+	return env.Mail(ctx, msg)
+}
+
+//MailEmailChanged - let the old address know the account's email was changed
+func (env *Env) MailEmailChanged(ctx context.Context, user *User, oldEmail string) error {
+	msg, err := NewMail(MailEmailChanged, []string{oldEmail}, EmailChangedMail{Name: user.Name, Email: user.Email})
+	if err != nil {
+		return err
+	}
+	return env.Mail(ctx, msg)
+}
+
+//MailVerification - mail the token that verifies the user's email to them
+func (env *Env) MailVerification(ctx context.Context, user *User, token string, expires time.Time) error {
+	msg, err := NewMail(MailVerify, []string{user.Email}, VerifyMail{
+		Name:      user.Name,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return err
+	}
+	return env.Mail(ctx, msg)
+}
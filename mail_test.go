@@ -0,0 +1,259 @@
+package goparent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMail(t *testing.T) {
+	due := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc    string
+		name    string
+		data    interface{}
+		subject string
+		text    string
+		html    string
+	}{
+		{
+			desc:    "reset",
+			name:    MailReset,
+			data:    ResetMail{Name: "Pat", Code: "abc123"},
+			subject: "GoParent password reset",
+			text:    "abc123",
+			html:    "<strong>abc123</strong>",
+		},
+		{
+			desc:    "invitation escapes html",
+			name:    MailInvitation,
+			data:    InvitationMail{From: "Pat <script>", Email: "sam@test.com"},
+			subject: "Pat <script> invited you to GoParent",
+			text:    "log in as sam@test.com",
+			html:    "Pat &lt;script&gt;",
+		},
+		{
+			desc:    "digest of one",
+			name:    MailDigest,
+			data:    DigestMail{Notifications: []*Notification{{Message: "Sam hasn't been fed in 3h0m0s", DueAt: due}}},
+			subject: "Sam hasn't been fed in 3h0m0s",
+			text:    "* Sam hasn't been fed in 3h0m0s (due Sun 12:00 PM UTC)",
+			html:    "<li>Sam hasn&#39;t been fed in 3h0m0s",
+		},
+		{
+			desc:    "digest of several",
+			name:    MailDigest,
+			data:    DigestMail{Name: "Pat", Notifications: []*Notification{{Message: "one", DueAt: due}, {Message: "two", DueAt: due}}},
+			subject: "2 GoParent reminders",
+			text:    "Hi Pat,",
+			html:    "<li>two",
+		},
+		{
+			desc:    "email change",
+			name:    MailEmailChange,
+			data:    EmailChangeMail{Name: "Pat", Email: "pat@new.com", Token: "abc123"},
+			subject: "Confirm your new GoParent email",
+			text:    "abc123",
+			html:    "pat@new.com",
+		},
+		{
+			desc:    "verify",
+			name:    MailVerify,
+			data:    VerifyMail{Name: "Pat", Email: "pat@test.com", Token: "abc123"},
+			subject: "Verify your GoParent email",
+			text:    "abc123",
+			html:    "<strong>pat@test.com</strong>",
+		},
+		{
+			desc:    "email changed",
+			name:    MailEmailChanged,
+			data:    EmailChangedMail{Name: "Pat", Email: "pat@new.com"},
+			subject: "Your GoParent email was changed",
+			text:    "changed to pat@new.com",
+			html:    "pat@new.com",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			msg, err := NewMail(tC.name, []string{"sam@test.com"}, tC.data)
+			assert.Nil(t, err)
+			assert.Equal(t, tC.subject, msg.Subject)
+			assert.Contains(t, msg.Text, tC.text)
+			assert.NotContains(t, msg.Text, "subject")
+			assert.Contains(t, msg.HTML, tC.html)
+		})
+	}
+
+	_, err := NewMail("welcome", nil, nil)
+	assert.NotNil(t, err)
+}
+
+//parseMail - check a rendered message and return its text and html parts
+func parseMail(t *testing.T, raw []byte) (*mail.Message, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+	return msg, parts
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{From: DefaultMailFrom, To: []string{"a@test.com", "b@test.com"}, Subject: "Sam's bottle ☺", Text: "plain", HTML: "<p>html</p>"}
+	raw, err := msg.Bytes(time.Now())
+	assert.Nil(t, err)
+
+	parsed, parts := parseMail(t, raw)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.Nil(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	assert.Equal(t, "a@test.com, b@test.com", parsed.Header.Get("To"))
+	assert.Equal(t, "plain", parts["text/plain"])
+	assert.Equal(t, "<p>html</p>", parts["text/html"])
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := &FileMailer{Dir: dir}
+	err := mailer.Send(context.Background(), &Message{To: []string{"a@test.com"}, Subject: "hi", Text: "text", HTML: "html"})
+	assert.Nil(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	if assert.Len(t, files, 1) {
+		raw, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+		assert.Nil(t, err)
+		parsed, _ := parseMail(t, raw)
+		assert.Equal(t, DefaultMailFrom, parsed.Header.Get("From"))
+	}
+}
+
+//smtpServer - accepts one message and sends what it got on received
+func smtpServer(t *testing.T, received chan<- string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost")
+		var transcript strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			transcript.WriteString(line)
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					transcript.WriteString(line)
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("502 unknown")
+			}
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestSMTPMailer(t *testing.T) {
+	received := make(chan string, 1)
+	host, port, _ := net.SplitHostPort(smtpServer(t, received))
+	portNumber, _ := strconv.Atoi(port)
+	mailer, err := NewMailer(MailConfig{Driver: "smtp", Host: host, Port: portNumber, From: "GoParent <noreply@test.com>"})
+	assert.Nil(t, err)
+
+	msg, _ := NewMail(MailReset, []string{"pat@test.com"}, ResetMail{Code: "abc123"})
+	err = mailer.Send(context.Background(), msg)
+	assert.Nil(t, err)
+
+	transcript := <-received
+	assert.Contains(t, transcript, "MAIL FROM:<noreply@test.com>")
+	assert.Contains(t, transcript, "RCPT TO:<pat@test.com>")
+	assert.Contains(t, transcript, "abc123")
+}
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer(MailConfig{})
+	assert.Nil(t, err)
+	assert.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = NewMailer(MailConfig{Driver: "file", Dir: "mail"})
+	assert.Nil(t, err)
+	assert.IsType(t, &FileMailer{}, mailer)
+
+	_, err = NewMailer(MailConfig{Driver: "smtp"})
+	assert.NotNil(t, err)
+
+	_, err = NewMailer(MailConfig{Driver: "pigeon"})
+	assert.NotNil(t, err)
+}
+
+func TestEnvMail(t *testing.T) {
+	var env *Env
+	assert.Nil(t, env.Mail(context.Background(), &Message{To: []string{"a@test.com"}}))
+
+	mailer := &testMailer{err: errors.New("test error")}
+	env = &Env{Mailer: mailer}
+	assert.Equal(t, mailer.err, env.Mail(context.Background(), &Message{}))
+}
+
+type testMailer struct {
+	sent []*Message
+	err  error
+}
+
+func (m *testMailer) Send(ctx context.Context, msg *Message) error {
+	m.sent = append(m.sent, msg)
+	return m.err
+}
@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sasimpson/goparent"
+)
+
+const namespace = "goparent"
+
+//connectionCheckTimeout - how long a scrape waits on the backend before reporting it down
+const connectionCheckTimeout = 2 * time.Second
+
+//Metrics - the service's prometheus metrics.  requests are counted by Middleware, backend
+// queries and domain events by the services Metrics wraps.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	queryDuration   *prometheus.HistogramVec
+	queryErrors     *prometheus.CounterVec
+
+	feedingsLogged prometheus.Counter
+	sleepsStarted  prometheus.Counter
+	wastesLogged   prometheus.Counter
+	signups        prometheus.Counter
+}
+
+//New - metrics registered with a registry of their own, with the go runtime and process
+// metrics and whether db is connected
+func New(db goparent.Datastore) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route name, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "How long HTTP requests took by route name and method, event streams are open for as long as they're connected.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "backend_query_duration_seconds",
+			Help:      "How long backend calls took by service and method.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"service", "method"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backend_query_errors_total",
+			Help:      "Backend calls that returned an error by service and method, not found included.",
+		}, []string{"service", "method"}),
+		feedingsLogged: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "feedings_logged_total",
+			Help:      "New feedings saved.",
+		}),
+		sleepsStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sleeps_started_total",
+			Help:      "Sleeps started or saved new.",
+		}),
+		wastesLogged: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "wastes_logged_total",
+			Help:      "New diapers saved.",
+		}),
+		signups: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_signed_up_total",
+			Help:      "New users saved.",
+		}),
+	}
+	backendUp := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backend_up",
+		Help:      "1 if there's a connection to the backend, checked on each scrape.",
+	}, func() float64 {
+		if goparent.CheckConnection(db, connectionCheckTimeout) != nil {
+			return 0
+		}
+		return 1
+	})
+	build := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "build_info",
+		Help:        "Always 1, labeled with the version and commit the service was built from.",
+		ConstLabels: prometheus.Labels{"version": goparent.Version, "commit": goparent.Commit},
+	})
+	build.Set(1)
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.inFlight,
+		m.queryDuration, m.queryErrors, backendUp, build,
+		m.feedingsLogged, m.sleepsStarted, m.wastesLogged, m.signups,
+	)
+	return m
+}
+
+//Handler - serves the metrics for prometheus to scrape
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+//Middleware - count and time requests by the name of the route they matched, it has to be
+// used on the mux router so the route is known
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unnamed"
+		if current := mux.CurrentRoute(r); current != nil && current.GetName() != "" {
+			route = current.GetName()
+		}
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		sw := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(sw, r)
+		m.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.code())).Inc()
+	})
+}
+
+//observe - record a backend call that started at start
+func (m *Metrics) observe(service string, method string, start time.Time, err error) {
+	m.queryDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.queryErrors.WithLabelValues(service, method).Inc()
+	}
+}
+
+//statusWriter - remembers the status code written.  it unwraps for http.ResponseController,
+// flushes and hijacks so event streams and websockets still work through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+//Hijack - websocket upgrades take the connection over, the handshake they write on it
+// is a 101
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+//code - the status code, handlers that never write are a 200
+func (w *statusWriter) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sasimpson/goparent"
+	"github.com/sasimpson/goparent/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New(&mock.DBEnv{})
+	r := mux.NewRouter()
+	r.Use(m.Middleware)
+	r.HandleFunc("/feeding", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods("POST").Name("FeedingNew")
+	r.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok, "event streams have to be able to flush")
+		w.Write([]byte("data: {}\n\n"))
+	}).Methods("GET").Name("EventStream")
+	r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Hijacker)
+		assert.True(t, ok, "websockets have to be able to take the connection over")
+	}).Methods("GET").Name("EventSocket")
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", "/feeding", nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req, _ := http.NewRequest("GET", "/events", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("GET", "/ws", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.requests.WithLabelValues("FeedingNew", "POST", "201")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("EventStream", "GET", "200")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requestDuration))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.inFlight))
+}
+
+func TestServices(t *testing.T) {
+	m := New(&mock.DBEnv{})
+	ctx := context.Background()
+
+	feedings := &mock.FeedingService{}
+	fs := m.FeedingService(feedings)
+	fs.Save(ctx, &goparent.Feeding{})
+	fs.Save(ctx, &goparent.Feeding{ID: "1"})
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.feedingsLogged), "updates aren't new feedings")
+	feedings.GetErr = errors.New("connection refused")
+	fs.Save(ctx, &goparent.Feeding{})
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.feedingsLogged), "failed saves aren't counted")
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.queryErrors.WithLabelValues("FeedingService", "Save")))
+
+	sleeps := &mock.SleepService{}
+	ss := m.SleepService(sleeps)
+	ss.Start(ctx, &goparent.Family{}, &goparent.Child{})
+	ss.Save(ctx, &goparent.Sleep{})
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.sleepsStarted))
+
+	assert.Equal(t, 3, testutil.CollectAndCount(m.queryDuration), "each service method is timed on its own")
+}
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		connErr error
+		up      string
+	}{
+		{desc: "connected", up: "goparent_backend_up 1"},
+		{desc: "backend down", connErr: errors.New("connection refused"), up: "goparent_backend_up 0"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m := New(&mock.DBEnv{ConnectionErr: tC.connErr})
+			req, _ := http.NewRequest("GET", "/metrics", nil)
+			rr := httptest.NewRecorder()
+			m.Handler().ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Contains(t, rr.Body.String(), tC.up)
+			assert.Contains(t, rr.Body.String(), `goparent_build_info{commit="unknown",version="dev"} 1`)
+			assert.Contains(t, rr.Body.String(), "go_goroutines")
+		})
+	}
+}
@@ -0,0 +1,495 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+//call - time a backend call that only returns an error
+func call(m *Metrics, service string, method string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	m.observe(service, method, start, err)
+	return err
+}
+
+//query - time a backend call that returns a value
+func query[T any](m *Metrics, service string, method string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+	v, err := fn()
+	m.observe(service, method, start, err)
+	return v, err
+}
+
+//UserService - next, with its calls recorded and new users counted
+func (m *Metrics) UserService(next goparent.UserService) goparent.UserService {
+	return &userService{next: next, m: m}
+}
+
+type userService struct {
+	next goparent.UserService
+	m    *Metrics
+}
+
+func (s *userService) User(ctx context.Context, id string) (*goparent.User, error) {
+	return query(s.m, "UserService", "User", func() (*goparent.User, error) { return s.next.User(ctx, id) })
+}
+
+func (s *userService) UserByLogin(ctx context.Context, login string, password string) (*goparent.User, error) {
+	return query(s.m, "UserService", "UserByLogin", func() (*goparent.User, error) { return s.next.UserByLogin(ctx, login, password) })
+}
+
+func (s *userService) Save(ctx context.Context, user *goparent.User) error {
+	isNew := user.ID == ""
+	err := call(s.m, "UserService", "Save", func() error { return s.next.Save(ctx, user) })
+	if err == nil && isNew {
+		s.m.signups.Inc()
+	}
+	return err
+}
+
+//GetToken - signs a token, there's no backend call to record
+func (s *userService) GetToken(user *goparent.User, expiration time.Duration) (string, error) {
+	return s.next.GetToken(user, expiration)
+}
+
+func (s *userService) ValidateToken(ctx context.Context, token string) (*goparent.User, bool, error) {
+	start := time.Now()
+	user, ok, err := s.next.ValidateToken(ctx, token)
+	s.m.observe("UserService", "ValidateToken", start, err)
+	return user, ok, err
+}
+
+func (s *userService) GetFamily(ctx context.Context, user *goparent.User) (*goparent.Family, error) {
+	return query(s.m, "UserService", "GetFamily", func() (*goparent.Family, error) { return s.next.GetFamily(ctx, user) })
+}
+
+func (s *userService) GetAllFamily(ctx context.Context, user *goparent.User) ([]*goparent.Family, error) {
+	return query(s.m, "UserService", "GetAllFamily", func() ([]*goparent.Family, error) { return s.next.GetAllFamily(ctx, user) })
+}
+
+func (s *userService) ResetPassword(ctx context.Context, code string, password string) error {
+	return call(s.m, "UserService", "ResetPassword", func() error { return s.next.ResetPassword(ctx, code, password) })
+}
+
+func (s *userService) RequestResetPassword(ctx context.Context, email string, code string) error {
+	return call(s.m, "UserService", "RequestResetPassword", func() error { return s.next.RequestResetPassword(ctx, email, code) })
+}
+
+func (s *userService) ChangeEmail(ctx context.Context, user *goparent.User, email string) error {
+	return call(s.m, "UserService", "ChangeEmail", func() error { return s.next.ChangeEmail(ctx, user, email) })
+}
+
+func (s *userService) Delete(ctx context.Context, user *goparent.User) error {
+	return call(s.m, "UserService", "Delete", func() error { return s.next.Delete(ctx, user) })
+}
+
+//UserInvitationService - next, with its calls recorded
+func (m *Metrics) UserInvitationService(next goparent.UserInvitationService) goparent.UserInvitationService {
+	return &userInvitationService{next: next, m: m}
+}
+
+type userInvitationService struct {
+	next goparent.UserInvitationService
+	m    *Metrics
+}
+
+func (s *userInvitationService) InviteParent(ctx context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
+	return call(s.m, "UserInvitationService", "InviteParent", func() error { return s.next.InviteParent(ctx, user, invite) })
+}
+
+func (s *userInvitationService) SentInvites(ctx context.Context, user *goparent.User) ([]*goparent.UserInvitation, error) {
+	return query(s.m, "UserInvitationService", "SentInvites", func() ([]*goparent.UserInvitation, error) { return s.next.SentInvites(ctx, user) })
+}
+
+func (s *userInvitationService) Invite(ctx context.Context, id string) (*goparent.UserInvitation, error) {
+	return query(s.m, "UserInvitationService", "Invite", func() (*goparent.UserInvitation, error) { return s.next.Invite(ctx, id) })
+}
+
+func (s *userInvitationService) InviteByToken(ctx context.Context, token string) (*goparent.UserInvitation, error) {
+	return query(s.m, "UserInvitationService", "InviteByToken", func() (*goparent.UserInvitation, error) { return s.next.InviteByToken(ctx, token) })
+}
+
+func (s *userInvitationService) Invites(ctx context.Context, user *goparent.User) ([]*goparent.UserInvitation, error) {
+	return query(s.m, "UserInvitationService", "Invites", func() ([]*goparent.UserInvitation, error) { return s.next.Invites(ctx, user) })
+}
+
+func (s *userInvitationService) Accept(ctx context.Context, user *goparent.User, id string) error {
+	return call(s.m, "UserInvitationService", "Accept", func() error { return s.next.Accept(ctx, user, id) })
+}
+
+func (s *userInvitationService) Decline(ctx context.Context, user *goparent.User, id string) error {
+	return call(s.m, "UserInvitationService", "Decline", func() error { return s.next.Decline(ctx, user, id) })
+}
+
+func (s *userInvitationService) Resend(ctx context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
+	return call(s.m, "UserInvitationService", "Resend", func() error { return s.next.Resend(ctx, user, invite) })
+}
+
+func (s *userInvitationService) Delete(ctx context.Context, invite *goparent.UserInvitation) error {
+	return call(s.m, "UserInvitationService", "Delete", func() error { return s.next.Delete(ctx, invite) })
+}
+
+//FamilyService - next, with its calls recorded
+func (m *Metrics) FamilyService(next goparent.FamilyService) goparent.FamilyService {
+	return &familyService{next: next, m: m}
+}
+
+type familyService struct {
+	next goparent.FamilyService
+	m    *Metrics
+}
+
+func (s *familyService) Save(ctx context.Context, family *goparent.Family) error {
+	return call(s.m, "FamilyService", "Save", func() error { return s.next.Save(ctx, family) })
+}
+
+func (s *familyService) Family(ctx context.Context, id string) (*goparent.Family, error) {
+	return query(s.m, "FamilyService", "Family", func() (*goparent.Family, error) { return s.next.Family(ctx, id) })
+}
+
+func (s *familyService) Children(ctx context.Context, family *goparent.Family) ([]*goparent.Child, error) {
+	return query(s.m, "FamilyService", "Children", func() ([]*goparent.Child, error) { return s.next.Children(ctx, family) })
+}
+
+func (s *familyService) AddMember(ctx context.Context, family *goparent.Family, user *goparent.User) error {
+	return call(s.m, "FamilyService", "AddMember", func() error { return s.next.AddMember(ctx, family, user) })
+}
+
+func (s *familyService) RemoveMember(ctx context.Context, family *goparent.Family, user *goparent.User) error {
+	return call(s.m, "FamilyService", "RemoveMember", func() error { return s.next.RemoveMember(ctx, family, user) })
+}
+
+func (s *familyService) GetAdminFamily(ctx context.Context, user *goparent.User) (*goparent.Family, error) {
+	return query(s.m, "FamilyService", "GetAdminFamily", func() (*goparent.Family, error) { return s.next.GetAdminFamily(ctx, user) })
+}
+
+func (s *familyService) Delete(ctx context.Context, family *goparent.Family) error {
+	return call(s.m, "FamilyService", "Delete", func() error { return s.next.Delete(ctx, family) })
+}
+
+//ChildService - next, with its calls recorded
+func (m *Metrics) ChildService(next goparent.ChildService) goparent.ChildService {
+	return &childService{next: next, m: m}
+}
+
+type childService struct {
+	next goparent.ChildService
+	m    *Metrics
+}
+
+func (s *childService) Save(ctx context.Context, child *goparent.Child) error {
+	return call(s.m, "ChildService", "Save", func() error { return s.next.Save(ctx, child) })
+}
+
+func (s *childService) Child(ctx context.Context, id string) (*goparent.Child, error) {
+	return query(s.m, "ChildService", "Child", func() (*goparent.Child, error) { return s.next.Child(ctx, id) })
+}
+
+func (s *childService) Delete(ctx context.Context, child *goparent.Child) (int, error) {
+	return query(s.m, "ChildService", "Delete", func() (int, error) { return s.next.Delete(ctx, child) })
+}
+
+//FeedingService - next, with its calls recorded and new feedings counted
+func (m *Metrics) FeedingService(next goparent.FeedingService) goparent.FeedingService {
+	return &feedingService{next: next, m: m}
+}
+
+type feedingService struct {
+	next goparent.FeedingService
+	m    *Metrics
+}
+
+func (s *feedingService) Save(ctx context.Context, feeding *goparent.Feeding) error {
+	isNew := feeding.ID == ""
+	err := call(s.m, "FeedingService", "Save", func() error { return s.next.Save(ctx, feeding) })
+	if err == nil && isNew {
+		s.m.feedingsLogged.Inc()
+	}
+	return err
+}
+
+func (s *feedingService) Feeding(ctx context.Context, family *goparent.Family, days uint64) ([]*goparent.Feeding, error) {
+	return query(s.m, "FeedingService", "Feeding", func() ([]*goparent.Feeding, error) { return s.next.Feeding(ctx, family, days) })
+}
+
+func (s *feedingService) Stats(ctx context.Context, child *goparent.Child, dq *goparent.DataQuery) (*goparent.FeedingSummary, error) {
+	return query(s.m, "FeedingService", "Stats", func() (*goparent.FeedingSummary, error) { return s.next.Stats(ctx, child, dq) })
+}
+
+func (s *feedingService) GraphData(ctx context.Context, child *goparent.Child, dq *goparent.DataQuery) (*goparent.FeedingChartData, error) {
+	return query(s.m, "FeedingService", "GraphData", func() (*goparent.FeedingChartData, error) { return s.next.GraphData(ctx, child, dq) })
+}
+
+//SleepService - next, with its calls recorded and sleeps started counted
+func (m *Metrics) SleepService(next goparent.SleepService) goparent.SleepService {
+	return &sleepService{next: next, m: m}
+}
+
+type sleepService struct {
+	next goparent.SleepService
+	m    *Metrics
+}
+
+func (s *sleepService) Save(ctx context.Context, sleep *goparent.Sleep) error {
+	isNew := sleep.ID == ""
+	err := call(s.m, "SleepService", "Save", func() error { return s.next.Save(ctx, sleep) })
+	if err == nil && isNew {
+		s.m.sleepsStarted.Inc()
+	}
+	return err
+}
+
+func (s *sleepService) Sleep(ctx context.Context, family *goparent.Family, days uint64) ([]*goparent.Sleep, error) {
+	return query(s.m, "SleepService", "Sleep", func() ([]*goparent.Sleep, error) { return s.next.Sleep(ctx, family, days) })
+}
+
+func (s *sleepService) Stats(ctx context.Context, child *goparent.Child, dq *goparent.DataQuery) (*goparent.SleepSummary, error) {
+	return query(s.m, "SleepService", "Stats", func() (*goparent.SleepSummary, error) { return s.next.Stats(ctx, child, dq) })
+}
+
+func (s *sleepService) Status(ctx context.Context, family *goparent.Family, child *goparent.Child) (*goparent.Sleep, bool, error) {
+	start := time.Now()
+	sleep, ok, err := s.next.Status(ctx, family, child)
+	s.m.observe("SleepService", "Status", start, err)
+	return sleep, ok, err
+}
+
+func (s *sleepService) Start(ctx context.Context, family *goparent.Family, child *goparent.Child) error {
+	err := call(s.m, "SleepService", "Start", func() error { return s.next.Start(ctx, family, child) })
+	if err == nil {
+		s.m.sleepsStarted.Inc()
+	}
+	return err
+}
+
+func (s *sleepService) End(ctx context.Context, family *goparent.Family, child *goparent.Child) error {
+	return call(s.m, "SleepService", "End", func() error { return s.next.End(ctx, family, child) })
+}
+
+func (s *sleepService) GraphData(ctx context.Context, child *goparent.Child, dq *goparent.DataQuery) (*goparent.SleepChartData, error) {
+	return query(s.m, "SleepService", "GraphData", func() (*goparent.SleepChartData, error) { return s.next.GraphData(ctx, child, dq) })
+}
+
+//WasteService - next, with its calls recorded and new diapers counted
+func (m *Metrics) WasteService(next goparent.WasteService) goparent.WasteService {
+	return &wasteService{next: next, m: m}
+}
+
+type wasteService struct {
+	next goparent.WasteService
+	m    *Metrics
+}
+
+func (s *wasteService) Save(ctx context.Context, waste *goparent.Waste) error {
+	isNew := waste.ID == ""
+	err := call(s.m, "WasteService", "Save", func() error { return s.next.Save(ctx, waste) })
+	if err == nil && isNew {
+		s.m.wastesLogged.Inc()
+	}
+	return err
+}
+
+func (s *wasteService) Waste(ctx context.Context, family *goparent.Family, days uint64) ([]*goparent.Waste, error) {
+	return query(s.m, "WasteService", "Waste", func() ([]*goparent.Waste, error) { return s.next.Waste(ctx, family, days) })
+}
+
+func (s *wasteService) Stats(ctx context.Context, child *goparent.Child, dq *goparent.DataQuery) (*goparent.WasteSummary, error) {
+	return query(s.m, "WasteService", "Stats", func() (*goparent.WasteSummary, error) { return s.next.Stats(ctx, child, dq) })
+}
+
+func (s *wasteService) GraphData(ctx context.Context, child *goparent.Child, dq *goparent.DataQuery) (*goparent.WasteChartData, error) {
+	return query(s.m, "WasteService", "GraphData", func() (*goparent.WasteChartData, error) { return s.next.GraphData(ctx, child, dq) })
+}
+
+//WebhookService - next, with its calls recorded
+func (m *Metrics) WebhookService(next goparent.WebhookService) goparent.WebhookService {
+	return &webhookService{next: next, m: m}
+}
+
+type webhookService struct {
+	next goparent.WebhookService
+	m    *Metrics
+}
+
+func (s *webhookService) Save(ctx context.Context, webhook *goparent.Webhook) error {
+	return call(s.m, "WebhookService", "Save", func() error { return s.next.Save(ctx, webhook) })
+}
+
+func (s *webhookService) Webhook(ctx context.Context, id string) (*goparent.Webhook, error) {
+	return query(s.m, "WebhookService", "Webhook", func() (*goparent.Webhook, error) { return s.next.Webhook(ctx, id) })
+}
+
+func (s *webhookService) Webhooks(ctx context.Context, family *goparent.Family) ([]*goparent.Webhook, error) {
+	return query(s.m, "WebhookService", "Webhooks", func() ([]*goparent.Webhook, error) { return s.next.Webhooks(ctx, family) })
+}
+
+func (s *webhookService) SaveDelivery(ctx context.Context, delivery *goparent.WebhookDelivery) error {
+	return call(s.m, "WebhookService", "SaveDelivery", func() error { return s.next.SaveDelivery(ctx, delivery) })
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, webhook *goparent.Webhook, limit int) ([]*goparent.WebhookDelivery, error) {
+	return query(s.m, "WebhookService", "Deliveries", func() ([]*goparent.WebhookDelivery, error) { return s.next.Deliveries(ctx, webhook, limit) })
+}
+
+//ReminderService - next, with its calls recorded
+func (m *Metrics) ReminderService(next goparent.ReminderService) goparent.ReminderService {
+	return &reminderService{next: next, m: m}
+}
+
+type reminderService struct {
+	next goparent.ReminderService
+	m    *Metrics
+}
+
+func (s *reminderService) SaveRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	return call(s.m, "ReminderService", "SaveRule", func() error { return s.next.SaveRule(ctx, rule) })
+}
+
+func (s *reminderService) Rule(ctx context.Context, id string) (*goparent.ReminderRule, error) {
+	return query(s.m, "ReminderService", "Rule", func() (*goparent.ReminderRule, error) { return s.next.Rule(ctx, id) })
+}
+
+func (s *reminderService) Rules(ctx context.Context, family *goparent.Family) ([]*goparent.ReminderRule, error) {
+	return query(s.m, "ReminderService", "Rules", func() ([]*goparent.ReminderRule, error) { return s.next.Rules(ctx, family) })
+}
+
+func (s *reminderService) ActiveRules(ctx context.Context) ([]*goparent.ReminderRule, error) {
+	return query(s.m, "ReminderService", "ActiveRules", func() ([]*goparent.ReminderRule, error) { return s.next.ActiveRules(ctx) })
+}
+
+func (s *reminderService) DeleteRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	return call(s.m, "ReminderService", "DeleteRule", func() error { return s.next.DeleteRule(ctx, rule) })
+}
+
+func (s *reminderService) SaveNotification(ctx context.Context, notification *goparent.Notification) error {
+	return call(s.m, "ReminderService", "SaveNotification", func() error { return s.next.SaveNotification(ctx, notification) })
+}
+
+func (s *reminderService) CreateNotification(ctx context.Context, notification *goparent.Notification) error {
+	return call(s.m, "ReminderService", "CreateNotification", func() error { return s.next.CreateNotification(ctx, notification) })
+}
+
+func (s *reminderService) ClaimNotification(ctx context.Context, notification *goparent.Notification, now time.Time) (bool, error) {
+	return query(s.m, "ReminderService", "ClaimNotification", func() (bool, error) { return s.next.ClaimNotification(ctx, notification, now) })
+}
+
+func (s *reminderService) Notification(ctx context.Context, id string) (*goparent.Notification, error) {
+	return query(s.m, "ReminderService", "Notification", func() (*goparent.Notification, error) { return s.next.Notification(ctx, id) })
+}
+
+func (s *reminderService) Notifications(ctx context.Context, family *goparent.Family, all bool) ([]*goparent.Notification, error) {
+	return query(s.m, "ReminderService", "Notifications", func() ([]*goparent.Notification, error) { return s.next.Notifications(ctx, family, all) })
+}
+
+func (s *reminderService) LatestNotification(ctx context.Context, rule *goparent.ReminderRule) (*goparent.Notification, error) {
+	return query(s.m, "ReminderService", "LatestNotification", func() (*goparent.Notification, error) { return s.next.LatestNotification(ctx, rule) })
+}
+
+func (s *reminderService) DueNotifications(ctx context.Context, now time.Time) ([]*goparent.Notification, error) {
+	return query(s.m, "ReminderService", "DueNotifications", func() ([]*goparent.Notification, error) { return s.next.DueNotifications(ctx, now) })
+}
+
+//APIKeyService - next, with its calls recorded
+func (m *Metrics) APIKeyService(next goparent.APIKeyService) goparent.APIKeyService {
+	return &apiKeyService{next: next, m: m}
+}
+
+type apiKeyService struct {
+	next goparent.APIKeyService
+	m    *Metrics
+}
+
+func (s *apiKeyService) Save(ctx context.Context, key *goparent.APIKey) error {
+	return call(s.m, "APIKeyService", "Save", func() error { return s.next.Save(ctx, key) })
+}
+
+func (s *apiKeyService) APIKey(ctx context.Context, id string) (*goparent.APIKey, error) {
+	return query(s.m, "APIKeyService", "APIKey", func() (*goparent.APIKey, error) { return s.next.APIKey(ctx, id) })
+}
+
+func (s *apiKeyService) APIKeyByHash(ctx context.Context, hash string) (*goparent.APIKey, error) {
+	return query(s.m, "APIKeyService", "APIKeyByHash", func() (*goparent.APIKey, error) { return s.next.APIKeyByHash(ctx, hash) })
+}
+
+func (s *apiKeyService) APIKeys(ctx context.Context, user *goparent.User) ([]*goparent.APIKey, error) {
+	return query(s.m, "APIKeyService", "APIKeys", func() ([]*goparent.APIKey, error) { return s.next.APIKeys(ctx, user) })
+}
+
+func (s *apiKeyService) Used(ctx context.Context, key *goparent.APIKey, now time.Time) error {
+	return call(s.m, "APIKeyService", "Used", func() error { return s.next.Used(ctx, key, now) })
+}
+
+func (s *apiKeyService) Delete(ctx context.Context, key *goparent.APIKey) error {
+	return call(s.m, "APIKeyService", "Delete", func() error { return s.next.Delete(ctx, key) })
+}
+
+//IdentityService - next, with its calls recorded
+func (m *Metrics) IdentityService(next goparent.ExternalIdentityService) goparent.ExternalIdentityService {
+	return &identityService{next: next, m: m}
+}
+
+type identityService struct {
+	next goparent.ExternalIdentityService
+	m    *Metrics
+}
+
+func (s *identityService) Save(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	return call(s.m, "IdentityService", "Save", func() error { return s.next.Save(ctx, identity) })
+}
+
+func (s *identityService) Identity(ctx context.Context, id string) (*goparent.ExternalIdentity, error) {
+	return query(s.m, "IdentityService", "Identity", func() (*goparent.ExternalIdentity, error) { return s.next.Identity(ctx, id) })
+}
+
+func (s *identityService) IdentityBySubject(ctx context.Context, issuer string, subject string) (*goparent.ExternalIdentity, error) {
+	return query(s.m, "IdentityService", "IdentityBySubject", func() (*goparent.ExternalIdentity, error) {
+		return s.next.IdentityBySubject(ctx, issuer, subject)
+	})
+}
+
+func (s *identityService) Identities(ctx context.Context, user *goparent.User) ([]*goparent.ExternalIdentity, error) {
+	return query(s.m, "IdentityService", "Identities", func() ([]*goparent.ExternalIdentity, error) { return s.next.Identities(ctx, user) })
+}
+
+func (s *identityService) Delete(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	return call(s.m, "IdentityService", "Delete", func() error { return s.next.Delete(ctx, identity) })
+}
+
+//RateLimitStore - next, with its calls recorded
+func (m *Metrics) RateLimitStore(next goparent.RateLimitStore) goparent.RateLimitStore {
+	return &rateLimitStore{next: next, m: m}
+}
+
+type rateLimitStore struct {
+	next goparent.RateLimitStore
+	m    *Metrics
+}
+
+func (s *rateLimitStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	return query(s.m, "RateLimitStore", "Hit", func() (goparent.RateCount, error) { return s.next.Hit(ctx, key, now, window) })
+}
+
+func (s *rateLimitStore) Count(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	return query(s.m, "RateLimitStore", "Count", func() (goparent.RateCount, error) { return s.next.Count(ctx, key, now, window) })
+}
+
+func (s *rateLimitStore) Reset(ctx context.Context, key string) error {
+	return call(s.m, "RateLimitStore", "Reset", func() error { return s.next.Reset(ctx, key) })
+}
+
+//LeaseStore - next, with its calls recorded
+func (m *Metrics) LeaseStore(next goparent.LeaseStore) goparent.LeaseStore {
+	return &leaseStore{next: next, m: m}
+}
+
+type leaseStore struct {
+	next goparent.LeaseStore
+	m    *Metrics
+}
+
+func (s *leaseStore) Hold(ctx context.Context, name string, holder string, now time.Time, ttl time.Duration) (goparent.Lease, error) {
+	return query(s.m, "LeaseStore", "Hold", func() (goparent.Lease, error) { return s.next.Hold(ctx, name, holder, now, ttl) })
+}
@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+//APIKeyService -
+type APIKeyService struct {
+	GetKey    *goparent.APIKey
+	GetKeys   []*goparent.APIKey
+	KeyID     string
+	SaveErr   error
+	KeyErr    error
+	KeysErr   error
+	UsedErr   error
+	DeleteErr error
+	Saved     []*goparent.APIKey
+	UsedAt    []time.Time
+	Deleted   []*goparent.APIKey
+}
+
+//Save -
+func (m *APIKeyService) Save(ctx context.Context, key *goparent.APIKey) error {
+	if m.SaveErr != nil {
+		return m.SaveErr
+	}
+	if key.ID == "" {
+		key.ID = m.KeyID
+	}
+	m.Saved = append(m.Saved, key)
+	return nil
+}
+
+//APIKey -
+func (m *APIKeyService) APIKey(context.Context, string) (*goparent.APIKey, error) {
+	return m.key()
+}
+
+//APIKeyByHash - the key if the hash matches GetKey's
+func (m *APIKeyService) APIKeyByHash(ctx context.Context, hash string) (*goparent.APIKey, error) {
+	key, err := m.key()
+	if err != nil || key.Hash != hash {
+		return nil, goparent.ErrNoAPIKeyFound
+	}
+	return key, nil
+}
+
+func (m *APIKeyService) key() (*goparent.APIKey, error) {
+	if m.KeyErr != nil {
+		return nil, m.KeyErr
+	}
+	if m.GetKey != nil {
+		return m.GetKey, nil
+	}
+	return nil, goparent.ErrNoAPIKeyFound
+}
+
+//APIKeys -
+func (m *APIKeyService) APIKeys(context.Context, *goparent.User) ([]*goparent.APIKey, error) {
+	if m.KeysErr != nil {
+		return nil, m.KeysErr
+	}
+	return m.GetKeys, nil
+}
+
+//Used - records the time in UsedAt
+func (m *APIKeyService) Used(ctx context.Context, key *goparent.APIKey, at time.Time) error {
+	if m.UsedErr != nil {
+		return m.UsedErr
+	}
+	key.LastUsed = at
+	m.UsedAt = append(m.UsedAt, at)
+	return nil
+}
+
+//Delete - records the key in Deleted
+func (m *APIKeyService) Delete(ctx context.Context, key *goparent.APIKey) error {
+	if m.DeleteErr != nil {
+		return m.DeleteErr
+	}
+	m.Deleted = append(m.Deleted, key)
+	return nil
+}
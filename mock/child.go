@@ -13,13 +13,15 @@ type ChildService struct {
 	Deleted   int
 	GetErr    error
 	DeleteErr error
+	Saved     []*goparent.Child
 }
 
 //Save -
-func (mcs *ChildService) Save(context.Context, *goparent.Child) error {
+func (mcs *ChildService) Save(_ context.Context, child *goparent.Child) error {
 	if mcs.GetErr != nil {
 		return mcs.GetErr
 	}
+	mcs.Saved = append(mcs.Saved, child)
 	return nil
 }
 
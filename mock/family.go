@@ -13,11 +13,21 @@ type FamilyService struct {
 	GetFamily    *goparent.Family
 	GetErr       error
 	GetFamilyErr error
+	SaveErr      error
+	RemoveErr    error
+	DeleteErr    error
+	Saved        []*goparent.Family
+	Removed      []*goparent.User
+	Deleted      []*goparent.Family
 }
 
 //Save -
-func (mfs *FamilyService) Save(context.Context, *goparent.Family) error {
-	panic("not implemented")
+func (mfs *FamilyService) Save(_ context.Context, family *goparent.Family) error {
+	if mfs.SaveErr != nil {
+		return mfs.SaveErr
+	}
+	mfs.Saved = append(mfs.Saved, family)
+	return nil
 }
 
 //Family -
@@ -41,6 +51,28 @@ func (mfs *FamilyService) AddMember(context.Context, *goparent.Family, *goparent
 	panic("not implemented")
 }
 
+//RemoveMember -
+func (mfs *FamilyService) RemoveMember(_ context.Context, family *goparent.Family, member *goparent.User) error {
+	if mfs.RemoveErr != nil {
+		return mfs.RemoveErr
+	}
+	err := family.RemoveMember(member.ID)
+	if err != nil {
+		return err
+	}
+	mfs.Removed = append(mfs.Removed, member)
+	return nil
+}
+
+//Delete -
+func (mfs *FamilyService) Delete(_ context.Context, family *goparent.Family) error {
+	if mfs.DeleteErr != nil {
+		return mfs.DeleteErr
+	}
+	mfs.Deleted = append(mfs.Deleted, family)
+	return nil
+}
+
 //GetAdminFamily -
 func (mfs *FamilyService) GetAdminFamily(context.Context, *goparent.User) (*goparent.Family, error) {
 	panic("not implemented")
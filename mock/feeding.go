@@ -38,7 +38,7 @@ func (m *FeedingService) Feeding(context.Context, *goparent.Family, uint64) ([]*
 }
 
 //Stats -
-func (m *FeedingService) Stats(context.Context, *goparent.Child) (*goparent.FeedingSummary, error) {
+func (m *FeedingService) Stats(context.Context, *goparent.Child, *goparent.DataQuery) (*goparent.FeedingSummary, error) {
 	if m.StatErr != nil {
 		return nil, m.StatErr
 	}
@@ -50,7 +50,7 @@ func (m *FeedingService) Stats(context.Context, *goparent.Child) (*goparent.Feed
 }
 
 //GraphData -
-func (m *FeedingService) GraphData(context.Context, *goparent.Child) (*goparent.FeedingChartData, error) {
+func (m *FeedingService) GraphData(context.Context, *goparent.Child, *goparent.DataQuery) (*goparent.FeedingChartData, error) {
 	if m.GraphErr != nil {
 		return nil, m.GraphErr
 	}
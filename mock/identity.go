@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/sasimpson/goparent"
+)
+
+//IdentityService - keeps identities in memory, by IdentityID
+type IdentityService struct {
+	Linked    map[string]*goparent.ExternalIdentity
+	SaveErr   error
+	DeleteErr error
+	Deleted   []*goparent.ExternalIdentity
+}
+
+//Save - fails with ErrIdentityLinked like the real ones if another user has the identity
+func (m *IdentityService) Save(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	if m.SaveErr != nil {
+		return m.SaveErr
+	}
+	id := goparent.IdentityID(identity.Issuer, identity.Subject)
+	if existing, ok := m.Linked[id]; ok && existing.UserID != identity.UserID {
+		return goparent.ErrIdentityLinked
+	}
+	if m.Linked == nil {
+		m.Linked = map[string]*goparent.ExternalIdentity{}
+	}
+	identity.ID = id
+	m.Linked[id] = identity
+	return nil
+}
+
+//Identity -
+func (m *IdentityService) Identity(ctx context.Context, id string) (*goparent.ExternalIdentity, error) {
+	if identity, ok := m.Linked[id]; ok {
+		return identity, nil
+	}
+	return nil, goparent.ErrNoIdentityFound
+}
+
+//IdentityBySubject -
+func (m *IdentityService) IdentityBySubject(ctx context.Context, issuer string, subject string) (*goparent.ExternalIdentity, error) {
+	return m.Identity(ctx, goparent.IdentityID(issuer, subject))
+}
+
+//Identities -
+func (m *IdentityService) Identities(ctx context.Context, user *goparent.User) ([]*goparent.ExternalIdentity, error) {
+	var identities []*goparent.ExternalIdentity
+	for _, identity := range m.Linked {
+		if identity.UserID == user.ID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+//Delete - records the identity in Deleted
+func (m *IdentityService) Delete(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	if m.DeleteErr != nil {
+		return m.DeleteErr
+	}
+	delete(m.Linked, identity.ID)
+	m.Deleted = append(m.Deleted, identity)
+	return nil
+}
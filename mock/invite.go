@@ -2,7 +2,6 @@ package mock
 
 import (
 	"context"
-	"time"
 
 	"github.com/sasimpson/goparent"
 )
@@ -12,19 +11,27 @@ type UserInvitationService struct {
 	GetSentInvites  []*goparent.UserInvitation
 	GetInvites      []*goparent.UserInvitation
 	GetInvite       *goparent.UserInvitation
+	Invited         []*goparent.UserInvitation
 	InviteParentErr error
 	SentInvitesErr  error
 	InvitesErr      error
 	InviteErr       error
 	AcceptErr       error
+	DeclineErr      error
+	ResendErr       error
 	DeleteErr       error
 }
 
 //InviteParent -
-func (m *UserInvitationService) InviteParent(context.Context, *goparent.User, string, time.Time) error {
+func (m *UserInvitationService) InviteParent(_ context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
 	if m.InviteParentErr != nil {
 		return m.InviteParentErr
 	}
+	invite.ID = "1"
+	invite.UserID = user.ID
+	invite.FamilyID = user.CurrentFamily
+	invite.Issue(invite.Timestamp)
+	m.Invited = append(m.Invited, invite)
 	return nil
 }
 
@@ -33,6 +40,9 @@ func (m *UserInvitationService) SentInvites(context.Context, *goparent.User) ([]
 	if m.SentInvitesErr != nil {
 		return nil, m.SentInvitesErr
 	}
+	if m.GetSentInvites != nil {
+		return m.GetSentInvites, nil
+	}
 	return nil, nil
 }
 
@@ -44,7 +54,12 @@ func (m *UserInvitationService) Invite(context.Context, string) (*goparent.UserI
 	if m.GetInvite != nil {
 		return m.GetInvite, nil
 	}
-	return nil, nil
+	return nil, goparent.ErrNoInvitationFound
+}
+
+//InviteByToken -
+func (m *UserInvitationService) InviteByToken(context.Context, string) (*goparent.UserInvitation, error) {
+	return m.Invite(nil, "")
 }
 
 //Invites -
@@ -68,6 +83,22 @@ func (m *UserInvitationService) Accept(context.Context, *goparent.User, string)
 	return nil
 }
 
+//Decline -
+func (m *UserInvitationService) Decline(context.Context, *goparent.User, string) error {
+	if m.DeclineErr != nil {
+		return m.DeclineErr
+	}
+	return nil
+}
+
+//Resend -
+func (m *UserInvitationService) Resend(context.Context, *goparent.User, *goparent.UserInvitation) error {
+	if m.ResendErr != nil {
+		return m.ResendErr
+	}
+	return nil
+}
+
 //Delete -
 func (m *UserInvitationService) Delete(context.Context, *goparent.UserInvitation) error {
 	if m.DeleteErr != nil {
@@ -0,0 +1,22 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/sasimpson/goparent"
+)
+
+//Mailer - records the messages it's asked to send
+type Mailer struct {
+	Sent    []*goparent.Message
+	SendErr error
+}
+
+//Send - records the message in Sent
+func (m *Mailer) Send(ctx context.Context, msg *goparent.Message) error {
+	if m.SendErr != nil {
+		return m.SendErr
+	}
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
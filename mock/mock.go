@@ -6,11 +6,13 @@ import (
 )
 
 //DBEnv mock
-type DBEnv struct{}
+type DBEnv struct {
+	ConnectionErr error
+}
 
 //GetConnection interface impl mock
 func (db *DBEnv) GetConnection() error {
-	panic("not implemented")
+	return db.ConnectionErr
 }
 
 //GetContext interface impl mock
@@ -0,0 +1,159 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+)
+
+//ReminderService -
+type ReminderService struct {
+	GetRule               *goparent.ReminderRule
+	GetRules              []*goparent.ReminderRule
+	GetNotification       *goparent.Notification
+	GetNotifications      []*goparent.Notification
+	GetLatest             *goparent.Notification
+	GetDue                []*goparent.Notification
+	SavedRules            []*goparent.ReminderRule
+	SavedNotifications    []*goparent.Notification
+	Deleted               []*goparent.ReminderRule
+	RuleID                string
+	SaveRuleErr           error
+	RuleErr               error
+	RulesErr              error
+	DeleteRuleErr         error
+	SaveNotificationErr   error
+	CreateNotificationErr error
+	ClaimNotificationErr  error
+	NotificationErr       error
+	NotificationsErr      error
+	LatestNotificationErr error
+	DueNotificationsErr   error
+}
+
+//SaveRule - records the rule in SavedRules
+func (m *ReminderService) SaveRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	if m.SaveRuleErr != nil {
+		return m.SaveRuleErr
+	}
+	if rule.ID == "" {
+		rule.ID = m.RuleID
+	}
+	m.SavedRules = append(m.SavedRules, rule)
+	return nil
+}
+
+//Rule -
+func (m *ReminderService) Rule(context.Context, string) (*goparent.ReminderRule, error) {
+	if m.RuleErr != nil {
+		return nil, m.RuleErr
+	}
+	if m.GetRule != nil {
+		return m.GetRule, nil
+	}
+	return nil, goparent.ErrNoReminderFound
+}
+
+//Rules -
+func (m *ReminderService) Rules(context.Context, *goparent.Family) ([]*goparent.ReminderRule, error) {
+	if m.RulesErr != nil {
+		return nil, m.RulesErr
+	}
+	return m.GetRules, nil
+}
+
+//ActiveRules - GetRules without the disabled ones
+func (m *ReminderService) ActiveRules(context.Context) ([]*goparent.ReminderRule, error) {
+	if m.RulesErr != nil {
+		return nil, m.RulesErr
+	}
+	var rules []*goparent.ReminderRule
+	for _, rule := range m.GetRules {
+		if !rule.Disabled {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+//DeleteRule - records the rule in Deleted
+func (m *ReminderService) DeleteRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	if m.DeleteRuleErr != nil {
+		return m.DeleteRuleErr
+	}
+	m.Deleted = append(m.Deleted, rule)
+	return nil
+}
+
+//SaveNotification - records the notification in SavedNotifications
+func (m *ReminderService) SaveNotification(ctx context.Context, n *goparent.Notification) error {
+	if m.SaveNotificationErr != nil {
+		return m.SaveNotificationErr
+	}
+	m.SavedNotifications = append(m.SavedNotifications, n)
+	return nil
+}
+
+//CreateNotification - records the notification in SavedNotifications, ErrNotificationExists
+// if one with its id is there already
+func (m *ReminderService) CreateNotification(ctx context.Context, n *goparent.Notification) error {
+	if m.CreateNotificationErr != nil {
+		return m.CreateNotificationErr
+	}
+	for _, saved := range m.SavedNotifications {
+		if saved.ID == n.ID {
+			return goparent.ErrNotificationExists
+		}
+	}
+	m.SavedNotifications = append(m.SavedNotifications, n)
+	return nil
+}
+
+//ClaimNotification - marks the notification delivered if it's due
+func (m *ReminderService) ClaimNotification(ctx context.Context, n *goparent.Notification, now time.Time) (bool, error) {
+	if m.ClaimNotificationErr != nil {
+		return false, m.ClaimNotificationErr
+	}
+	if !n.Due(now) {
+		return false, nil
+	}
+	n.Status = goparent.NotificationDelivered
+	n.DeliveredAt = now
+	return true, nil
+}
+
+//Notification -
+func (m *ReminderService) Notification(context.Context, string) (*goparent.Notification, error) {
+	if m.NotificationErr != nil {
+		return nil, m.NotificationErr
+	}
+	if m.GetNotification != nil {
+		return m.GetNotification, nil
+	}
+	return nil, goparent.ErrNoNotificationFound
+}
+
+//Notifications -
+func (m *ReminderService) Notifications(context.Context, *goparent.Family, bool) ([]*goparent.Notification, error) {
+	if m.NotificationsErr != nil {
+		return nil, m.NotificationsErr
+	}
+	return m.GetNotifications, nil
+}
+
+//LatestNotification -
+func (m *ReminderService) LatestNotification(context.Context, *goparent.ReminderRule) (*goparent.Notification, error) {
+	if m.LatestNotificationErr != nil {
+		return nil, m.LatestNotificationErr
+	}
+	return m.GetLatest, nil
+}
+
+//DueNotifications -
+func (m *ReminderService) DueNotifications(context.Context, time.Time) ([]*goparent.Notification, error) {
+	if m.DueNotificationsErr != nil {
+		return nil, m.DueNotificationsErr
+	}
+	return m.GetDue, nil
+}
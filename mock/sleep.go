@@ -11,6 +11,7 @@ type SleepService struct {
 	Env       *goparent.Env
 	Sleeps    []*goparent.Sleep
 	Stat      *goparent.SleepSummary
+	Graph     *goparent.SleepChartData
 	GetStatus bool
 	GetSleep  *goparent.Sleep
 	GetErr    error
@@ -18,6 +19,7 @@ type SleepService struct {
 	StatusErr error
 	StartErr  error
 	EndErr    error
+	GraphErr  error
 }
 
 //Save -
@@ -40,7 +42,7 @@ func (m *SleepService) Sleep(context.Context, *goparent.Family, uint64) ([]*gopa
 }
 
 //Stats -
-func (m *SleepService) Stats(context.Context, *goparent.Child) (*goparent.SleepSummary, error) {
+func (m *SleepService) Stats(context.Context, *goparent.Child, *goparent.DataQuery) (*goparent.SleepSummary, error) {
 	if m.StatErr != nil {
 		return nil, m.StatErr
 	}
@@ -76,6 +78,12 @@ func (m *SleepService) End(context.Context, *goparent.Family, *goparent.Child) e
 }
 
 //GraphData -
-func (m *SleepService) GraphData(context.Context, *goparent.Child) (*goparent.SleepChartData, error) {
-	panic("not implemented")
+func (m *SleepService) GraphData(context.Context, *goparent.Child, *goparent.DataQuery) (*goparent.SleepChartData, error) {
+	if m.GraphErr != nil {
+		return nil, m.GraphErr
+	}
+	if m.Graph != nil {
+		return m.Graph, nil
+	}
+	return nil, nil
 }
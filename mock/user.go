@@ -11,6 +11,7 @@ import (
 type UserService struct {
 	Env          *goparent.Env
 	Family       *goparent.Family
+	Families     []*goparent.Family
 	ReturnedUser *goparent.User
 	Token        string
 	UserID       string
@@ -18,11 +19,19 @@ type UserService struct {
 	TokenErr     error
 	FamilyErr    error
 	SaveErr      error
+	EmailErr     error
+	DeleteErr    error
+	ResetErr     error
+	Saved        []*goparent.User
+	Deleted      []*goparent.User
 }
 
 //User -
 func (m *UserService) User(context.Context, string) (*goparent.User, error) {
-	panic("not implemented")
+	if m.ReturnedUser != nil {
+		return m.ReturnedUser, nil
+	}
+	return nil, goparent.ErrNoUserFound
 }
 
 //UserByLogin -
@@ -44,6 +53,7 @@ func (m *UserService) Save(ctx context.Context, user *goparent.User) error {
 	if m.UserID != "" {
 		user.ID = m.UserID
 	}
+	m.Saved = append(m.Saved, user)
 	return nil
 }
 
@@ -73,15 +83,37 @@ func (m *UserService) GetFamily(context.Context, *goparent.User) (*goparent.Fami
 
 //GetAllFamily -
 func (m *UserService) GetAllFamily(context.Context, *goparent.User) ([]*goparent.Family, error) {
-	panic("not implemented")
+	if m.FamilyErr != nil {
+		return nil, m.FamilyErr
+	}
+	return m.Families, nil
 }
 
 //RequestResetPassword -
 func (m *UserService) RequestResetPassword(context.Context, string, string) error {
-	panic("not implemented")
+	return m.ResetErr
 }
 
 //ResetPassword -
 func (m *UserService) ResetPassword(context.Context, string, string) error {
 	panic("not implemented")
 }
+
+//ChangeEmail -
+func (m *UserService) ChangeEmail(ctx context.Context, user *goparent.User, email string) error {
+	if m.EmailErr != nil {
+		return m.EmailErr
+	}
+	user.Email = email
+	m.Saved = append(m.Saved, user)
+	return nil
+}
+
+//Delete -
+func (m *UserService) Delete(ctx context.Context, user *goparent.User) error {
+	if m.DeleteErr != nil {
+		return m.DeleteErr
+	}
+	m.Deleted = append(m.Deleted, user)
+	return nil
+}
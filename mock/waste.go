@@ -37,7 +37,7 @@ func (m *WasteService) Waste(context.Context, *goparent.Family, uint64) ([]*gopa
 }
 
 //Stats -
-func (m *WasteService) Stats(context.Context, *goparent.Child) (*goparent.WasteSummary, error) {
+func (m *WasteService) Stats(context.Context, *goparent.Child, *goparent.DataQuery) (*goparent.WasteSummary, error) {
 	if m.StatErr != nil {
 		return nil, m.StatErr
 	}
@@ -49,7 +49,7 @@ func (m *WasteService) Stats(context.Context, *goparent.Child) (*goparent.WasteS
 }
 
 //GraphData -
-func (m *WasteService) GraphData(context.Context, *goparent.Child) (*goparent.WasteChartData, error) {
+func (m *WasteService) GraphData(context.Context, *goparent.Child, *goparent.DataQuery) (*goparent.WasteChartData, error) {
 	if m.GraphErr != nil {
 		return nil, m.GraphErr
 	}
@@ -0,0 +1,68 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/sasimpson/goparent"
+)
+
+//WebhookService -
+type WebhookService struct {
+	GetWebhook      *goparent.Webhook
+	GetWebhooks     []*goparent.Webhook
+	GetDeliveries   []*goparent.WebhookDelivery
+	Saved           []*goparent.WebhookDelivery
+	WebhookID       string
+	SaveErr         error
+	WebhookErr      error
+	WebhooksErr     error
+	SaveDeliveryErr error
+	DeliveriesErr   error
+}
+
+//Save -
+func (m *WebhookService) Save(ctx context.Context, webhook *goparent.Webhook) error {
+	if m.SaveErr != nil {
+		return m.SaveErr
+	}
+	if webhook.ID == "" {
+		webhook.ID = m.WebhookID
+	}
+	return nil
+}
+
+//Webhook -
+func (m *WebhookService) Webhook(context.Context, string) (*goparent.Webhook, error) {
+	if m.WebhookErr != nil {
+		return nil, m.WebhookErr
+	}
+	if m.GetWebhook != nil {
+		return m.GetWebhook, nil
+	}
+	return nil, goparent.ErrNoWebhookFound
+}
+
+//Webhooks -
+func (m *WebhookService) Webhooks(context.Context, *goparent.Family) ([]*goparent.Webhook, error) {
+	if m.WebhooksErr != nil {
+		return nil, m.WebhooksErr
+	}
+	return m.GetWebhooks, nil
+}
+
+//SaveDelivery - records the delivery in Saved
+func (m *WebhookService) SaveDelivery(ctx context.Context, delivery *goparent.WebhookDelivery) error {
+	if m.SaveDeliveryErr != nil {
+		return m.SaveDeliveryErr
+	}
+	m.Saved = append(m.Saved, delivery)
+	return nil
+}
+
+//Deliveries -
+func (m *WebhookService) Deliveries(context.Context, *goparent.Webhook, int) ([]*goparent.WebhookDelivery, error) {
+	if m.DeliveriesErr != nil {
+		return nil, m.DeliveriesErr
+	}
+	return m.GetDeliveries, nil
+}
@@ -0,0 +1,385 @@
+package goparent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/spf13/viper"
+)
+
+//ErrNoIdentityFound - there is no linked identity with that id or subject
+var ErrNoIdentityFound = errors.New("no linked identity found")
+
+//ErrIdentityLinked - the external account is already linked to another user
+var ErrIdentityLinked = errors.New("that account is already linked to another user")
+
+//ErrInvalidIDToken - the provider's id token didn't verify
+var ErrInvalidIDToken = errors.New("the identity provider's id token is invalid")
+
+//oidcKeyRefreshInterval - how often a provider's signing keys can be fetched again for a
+// key id we don't know, so bad tokens can't make us hammer the provider
+const oidcKeyRefreshInterval = time.Minute
+
+//ExternalIdentity - a user's account with an openid connect provider, the provider's issuer
+// and subject are what the user is found by when they log in with it
+type ExternalIdentity struct {
+	ID        string    `json:"id" gorethink:"id,omitempty"`
+	UserID    string    `json:"userID" gorethink:"userID"`
+	Provider  string    `json:"provider" gorethink:"provider"`
+	Issuer    string    `json:"issuer" gorethink:"issuer"`
+	Subject   string    `json:"subject" gorethink:"subject"`
+	Email     string    `json:"email" gorethink:"email"`
+	CreatedAt time.Time `json:"createdAt" gorethink:"createdAt"`
+	LastLogin time.Time `json:"lastLogin" gorethink:"lastLogin"`
+}
+
+//ExternalIdentityService - storage for linked identities.  an issuer and subject can only be
+// linked to one user.
+type ExternalIdentityService interface {
+	Save(context.Context, *ExternalIdentity) error
+	Identity(context.Context, string) (*ExternalIdentity, error)
+	IdentityBySubject(context.Context, string, string) (*ExternalIdentity, error)
+	Identities(context.Context, *User) ([]*ExternalIdentity, error)
+	Delete(context.Context, *ExternalIdentity) error
+}
+
+//IdentityID - the id an identity is stored under, there's only one for each issuer and
+// subject so they can't be linked to two users
+func IdentityID(issuer string, subject string) string {
+	issuer = strings.TrimSuffix(issuer, "/")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s", len(issuer), issuer, subject)))
+	return hex.EncodeToString(sum[:])
+}
+
+//OIDCClaims - who the provider says logged in
+type OIDCClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	//AuthTime - when the user last logged in to the provider, zero if it didn't say
+	AuthTime time.Time
+}
+
+//OIDCProvider - an openid connect provider users can log in with, google, apple or our own
+// keycloak.  the discovery document and signing keys are fetched when they're first needed.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcJWKS struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+//NewOIDCLogin - the random state, nonce and pkce verifier for one login with a provider
+func NewOIDCLogin() (state string, nonce string, verifier string, err error) {
+	values := make([]string, 3)
+	for i := range values {
+		b := make([]byte, 32)
+		_, err = rand.Read(b)
+		if err != nil {
+			return "", "", "", err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return values[0], values[1], values[2], nil
+}
+
+func (p *OIDCProvider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+//endpoints - the provider's discovery document, it's only fetched once
+func (p *OIDCProvider) endpoints(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	err := p.getJSON(ctx, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, fmt.Errorf("oidc provider %s: %s", p.Name, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, fmt.Errorf("oidc provider %s: discovery is for issuer %q", p.Name, discovery.Issuer)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+//AuthURL - where to send the user to log in with the provider
+func (p *OIDCProvider) AuthURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	discovery, err := p.endpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := append([]string{"openid"}, p.Scopes...)
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+//ReauthURL - AuthURL for a user confirming it's them, the provider is asked to log them in
+// again even if they still have a session with it
+func (p *OIDCProvider) ReauthURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	authURL, err := p.AuthURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+	return authURL + "&" + url.Values{"prompt": {"login"}, "max_age": {"0"}}.Encode(), nil
+}
+
+//Exchange - trade the code the provider redirected back with for its id token, and verify it
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*OIDCClaims, error) {
+	discovery, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token oidcTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, fmt.Errorf("oidc provider %s: token response: %s", p.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("oidc provider %s: token exchange failed: %s %s", p.Name, token.Error, token.ErrorDescription)
+	}
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+//Verify - check an id token was signed by the provider for us and this login, and return
+// who it's for
+func (p *OIDCProvider) Verify(ctx context.Context, idToken string, nonce string) (*OIDCClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+	tokenNonce, _ := claims["nonce"].(string)
+	_, expires := claims["exp"]
+	if strings.TrimSuffix(issuer, "/") != strings.TrimSuffix(p.Issuer, "/") || subject == "" || !expires ||
+		!oidcAudience(claims["aud"], p.ClientID) || tokenNonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+
+	oidcClaims := &OIDCClaims{Issuer: p.Issuer, Subject: subject}
+	oidcClaims.Email, _ = claims["email"].(string)
+	oidcClaims.Name, _ = claims["name"].(string)
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		oidcClaims.AuthTime = time.Unix(int64(authTime), 0)
+	}
+	//some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		oidcClaims.EmailVerified = verified
+	case string:
+		oidcClaims.EmailVerified = verified == "true"
+	}
+	return oidcClaims, nil
+}
+
+func oidcAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//key - the provider's signing key with the id.  the keys are fetched again for ids we don't
+// know, providers rotate them.
+func (p *OIDCProvider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	discovery, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok := p.findKey(kid)
+	if ok || time.Since(p.keysFetched) < oidcKeyRefreshInterval {
+		if !ok {
+			return nil, fmt.Errorf("oidc provider %s: no signing key %q", p.Name, kid)
+		}
+		return key, nil
+	}
+
+	var jwks oidcJWKS
+	err = p.getJSON(ctx, discovery.JWKSURI, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("oidc provider %s: %s", p.Name, err)
+	}
+	p.keys = map[string]*rsa.PublicKey{}
+	p.keysFetched = time.Now()
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	key, ok = p.findKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("oidc provider %s: no signing key %q", p.Name, kid)
+	}
+	return key, nil
+}
+
+//findKey - tokens without a key id can only be checked if the provider has one key
+func (p *OIDCProvider) findKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+//InitOIDCConfig - read the openid connect providers from the config, under
+// oidc.providers.<name> with issuer, clientid, clientsecret, redirecturl and scopes
+func InitOIDCConfig() (map[string]*OIDCProvider, error) {
+	providers := map[string]*OIDCProvider{}
+	names := make([]string, 0)
+	for name := range viper.GetStringMap("oidc.providers") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := "oidc.providers." + name + "."
+		provider := &OIDCProvider{
+			Name:         name,
+			Issuer:       viper.GetString(key + "issuer"),
+			ClientID:     viper.GetString(key + "clientid"),
+			ClientSecret: viper.GetString(key + "clientsecret"),
+			RedirectURL:  viper.GetString(key + "redirecturl"),
+			Scopes:       viper.GetStringSlice(key + "scopes"),
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"email", "profile"}
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc provider %s needs an issuer, clientid and redirecturl", name)
+		}
+		providers[name] = provider
+	}
+	return providers, nil
+}
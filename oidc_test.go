@@ -0,0 +1,144 @@
+package goparent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCVerify(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var issuer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/jwks"})
+		case "/jwks":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		}
+	}))
+	defer server.Close()
+	issuer = server.URL
+	provider := &OIDCProvider{Name: "test", Issuer: issuer, ClientID: "goparent", HTTPClient: server.Client()}
+
+	sign := func(signingKey *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+		base := jwt.MapClaims{"iss": issuer, "sub": "sub-1", "aud": "goparent", "nonce": "n1", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range claims {
+			base[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+		token.Header["kid"] = kid
+		signed, _ := token.SignedString(signingKey)
+		return signed
+	}
+
+	testCases := []struct {
+		desc     string
+		token    string
+		valid    bool
+		verified bool
+		authTime bool
+	}{
+		{
+			desc:     "valid",
+			token:    sign(key, "k1", jwt.MapClaims{"email": "a@test.com", "email_verified": true}),
+			valid:    true,
+			verified: true,
+		},
+		{
+			desc:     "audience list and email_verified as a string",
+			token:    sign(key, "k1", jwt.MapClaims{"aud": []string{"other", "goparent"}, "email_verified": "true"}),
+			valid:    true,
+			verified: true,
+		},
+		{
+			desc:     "auth time",
+			token:    sign(key, "k1", jwt.MapClaims{"email_verified": true, "auth_time": time.Now().Unix()}),
+			valid:    true,
+			verified: true,
+			authTime: true,
+		},
+		{
+			desc:  "unverified email",
+			token: sign(key, "k1", jwt.MapClaims{"email_verified": false}),
+			valid: true,
+		},
+		{desc: "another client", token: sign(key, "k1", jwt.MapClaims{"aud": "other"})},
+		{desc: "another issuer", token: sign(key, "k1", jwt.MapClaims{"iss": "https://evil.test"})},
+		{desc: "another login's nonce", token: sign(key, "k1", jwt.MapClaims{"nonce": "n2"})},
+		{desc: "expired", token: sign(key, "k1", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})},
+		{desc: "no subject", token: sign(key, "k1", jwt.MapClaims{"sub": ""})},
+		{desc: "signed by someone else", token: sign(otherKey, "k1", nil)},
+		{desc: "unknown key", token: sign(otherKey, "k2", nil)},
+		{
+			desc: "not rsa",
+			token: func() string {
+				s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": issuer, "sub": "sub-1", "aud": "goparent", "nonce": "n1"}).SignedString([]byte("secret"))
+				return s
+			}(),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			claims, err := provider.Verify(context.Background(), tC.token, "n1")
+			if !tC.valid {
+				assert.Equal(t, ErrInvalidIDToken, err)
+				return
+			}
+			if assert.Nil(t, err) {
+				assert.Equal(t, "sub-1", claims.Subject)
+				assert.Equal(t, tC.verified, claims.EmailVerified)
+				assert.Equal(t, tC.authTime, !claims.AuthTime.IsZero())
+			}
+		})
+	}
+}
+
+func TestOIDCAuthURL(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	provider := &OIDCProvider{Name: "test", Issuer: server.URL, ClientID: "goparent", RedirectURL: "https://app.test/callback", Scopes: []string{"email"}}
+	provider.discovery = &oidcDiscovery{Issuer: server.URL, AuthorizationEndpoint: server.URL + "/authorize?prompt=login"}
+
+	u, err := provider.AuthURL(context.Background(), "state", "nonce", "verifier")
+	assert.Nil(t, err)
+	assert.Contains(t, u, server.URL+"/authorize?prompt=login&")
+	assert.Contains(t, u, "scope=openid+email")
+	assert.Contains(t, u, "code_challenge_method=S256")
+	assert.NotContains(t, u, "verifier")
+
+	u, err = provider.ReauthURL(context.Background(), "state", "nonce", "verifier")
+	assert.Nil(t, err)
+	assert.Contains(t, u, "max_age=0")
+	assert.Contains(t, u, "code_challenge_method=S256")
+}
+
+func TestNewOIDCLogin(t *testing.T) {
+	state, nonce, verifier, err := NewOIDCLogin()
+	assert.Nil(t, err)
+	assert.Len(t, verifier, 43)
+	assert.NotEqual(t, state, nonce)
+	assert.NotEqual(t, nonce, verifier)
+}
+
+func TestIdentityID(t *testing.T) {
+	assert.Equal(t, IdentityID("https://idp.test", "sub-1"), IdentityID("https://idp.test/", "sub-1"))
+	assert.NotEqual(t, IdentityID("https://idp.test", "sub-1"), IdentityID("https://idp.test", "sub-2"))
+	assert.NotEqual(t, IdentityID("https://idp.test", "a b"), IdentityID("https://idp.test a", "b"))
+}
@@ -0,0 +1,287 @@
+package goparent
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+//Granularity - the size of the buckets that graph data is grouped into
+type Granularity string
+
+const (
+	//GranularityHour - group data by hour
+	GranularityHour Granularity = "hour"
+	//GranularityDay - group data by day
+	GranularityDay Granularity = "day"
+	//GranularityWeek - group data by week, weeks start on monday
+	GranularityWeek Granularity = "week"
+	//GranularityMonth - group data by month
+	GranularityMonth Granularity = "month"
+)
+
+//ErrInvalidGranularity - the requested bucket size isn't one we support
+var ErrInvalidGranularity = errors.New("invalid granularity, must be one of hour, day, week or month")
+
+//ErrInvalidDateRange - the end of a query window is not after the start
+var ErrInvalidDateRange = errors.New("invalid date range, end must be after start")
+
+//ErrDateRangeTooLong - the window would have more buckets than MaxDataQueryBuckets
+var ErrDateRangeTooLong = errors.New("invalid date range, too long for the granularity")
+
+//MaxDataQueryBuckets - the most buckets a query can ask for, about 41 days by the hour or
+// 2.7 years by the day
+const MaxDataQueryBuckets = 1000
+
+//DataQuery - the window and bucket size used when asking for stats and graph data.
+// Start is inclusive and End is exclusive.  Buckets are aligned to the wall clock
+// in Location, a nil Location is UTC.
+type DataQuery struct {
+	Start       time.Time
+	End         time.Time
+	Granularity Granularity
+	Location    *time.Location
+}
+
+//NewStatsQuery - the default window for summaries, the current day from local midnight
+// to the next local midnight, which is 23 or 25 hours long on DST transition days.
+func NewStatsQuery(now time.Time, loc *time.Location) *DataQuery {
+	start := StartOfDay(now, loc)
+	return &DataQuery{
+		Start:       start,
+		End:         start.AddDate(0, 0, 1),
+		Granularity: GranularityDay,
+		Location:    loc,
+	}
+}
+
+//NewGraphQuery - the default window for graphs, the last 7 local days including today bucketed by day.
+func NewGraphQuery(now time.Time, loc *time.Location) *DataQuery {
+	end := StartOfDay(now, loc).AddDate(0, 0, 1)
+	return &DataQuery{
+		Start:       end.AddDate(0, 0, -7),
+		End:         end,
+		Granularity: GranularityDay,
+		Location:    loc,
+	}
+}
+
+//StartOfDay - local midnight of the day t falls on in loc, nil is UTC.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+//Validate - make sure the query has a sane window and a known granularity
+func (q *DataQuery) Validate() error {
+	switch q.Granularity {
+	case GranularityHour, GranularityDay, GranularityWeek, GranularityMonth:
+	default:
+		return ErrInvalidGranularity
+	}
+	if !q.End.After(q.Start) {
+		return ErrInvalidDateRange
+	}
+	if q.End.Sub(q.Start)/q.Granularity.minSize() > MaxDataQueryBuckets {
+		return ErrDateRangeTooLong
+	}
+	return nil
+}
+
+//minSize - the shortest a bucket can be, so window / minSize is the most buckets it can have
+func (g Granularity) minSize() time.Duration {
+	switch g {
+	case GranularityHour:
+		return time.Hour
+	case GranularityWeek:
+		return 7 * 24 * time.Hour
+	case GranularityMonth:
+		return 28 * 24 * time.Hour
+	default:
+		//days are 23 hours when the clocks go forward
+		return 23 * time.Hour
+	}
+}
+
+//Contains - is the time inside of the query window
+func (q *DataQuery) Contains(t time.Time) bool {
+	return !t.Before(q.Start) && t.Before(q.End)
+}
+
+//Bucket - returns the start of the bucket that t falls into, in the query's location
+func (q *DataQuery) Bucket(t time.Time) time.Time {
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	switch q.Granularity {
+	case GranularityHour:
+		//strip the minutes off rather than rebuilding the wall clock so the
+		// repeated hour when clocks fall back stays two different buckets.
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case GranularityWeek:
+		//go weeks start on sunday, shift so monday is day 0
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+//NewFeedingSummary - build the summary for a set of feedings
+func NewFeedingSummary(feedings []Feeding) *FeedingSummary {
+	summary := &FeedingSummary{
+		Data:  feedings,
+		Total: make(map[string]float32),
+		Mean:  make(map[string]float32),
+		Range: make(map[string]int),
+		Unit:  make(map[string]Unit),
+	}
+
+	for _, x := range feedings {
+		key, unit := feedingTotalKey(x)
+		amount, err := Convert(x.Amount, x.AmountUnit(), unit)
+		if err != nil {
+			//an unknown unit can't be added to anything, leave it out of the totals
+			continue
+		}
+		summary.Unit[key] = unit
+		summary.Total[key] += amount
+		summary.Range[key]++
+	}
+	for k := range summary.Total {
+		summary.Mean[k] = summary.Total[k] / float32(summary.Range[k])
+	}
+	return summary
+}
+
+//NewFeedingChartData - group feedings into the buckets of the query, by type.  types
+// are split the same way as the summary so sums never mix units.
+func NewFeedingChartData(q *DataQuery, feedings []Feeding) *FeedingChartData {
+	type bucketKey struct {
+		date        time.Time
+		feedingType string
+	}
+	buckets := make(map[bucketKey]*FeedingChartDataset)
+	for _, feeding := range feedings {
+		feedingType, unit := feedingTotalKey(feeding)
+		amount, err := Convert(feeding.Amount, feeding.AmountUnit(), unit)
+		if err != nil {
+			continue
+		}
+		key := bucketKey{q.Bucket(feeding.TimeStamp), feedingType}
+		if _, ok := buckets[key]; !ok {
+			buckets[key] = &FeedingChartDataset{Date: key.date, Type: key.feedingType, Unit: unit}
+		}
+		buckets[key].Count++
+		buckets[key].Sum += amount
+	}
+
+	chartData := &FeedingChartData{
+		Start:       q.Start,
+		End:         q.End,
+		Granularity: q.Granularity,
+		Dataset:     []FeedingChartDataset{},
+	}
+	for _, dataset := range buckets {
+		chartData.Dataset = append(chartData.Dataset, *dataset)
+	}
+	sort.Slice(chartData.Dataset, func(i, j int) bool {
+		a, b := chartData.Dataset[i], chartData.Dataset[j]
+		if a.Date.Equal(b.Date) {
+			return a.Type < b.Type
+		}
+		return a.Date.Before(b.Date)
+	})
+	return chartData
+}
+
+//NewSleepSummary - build the summary for a set of sleeps, sleeps that haven't ended are not counted.
+func NewSleepSummary(sleeps []Sleep) *SleepSummary {
+	summary := &SleepSummary{Data: sleeps}
+	for _, x := range sleeps {
+		if x.End.After(x.Start) {
+			summary.Total += int64(x.End.Sub(x.Start).Seconds())
+			summary.Range++
+		}
+	}
+	if summary.Range > 0 {
+		summary.Mean = float64(summary.Total) / float64(summary.Range)
+	}
+	return summary
+}
+
+//NewSleepChartData - group completed sleeps into the buckets of the query by their start
+func NewSleepChartData(q *DataQuery, sleeps []Sleep) *SleepChartData {
+	buckets := make(map[time.Time][]time.Duration)
+	for _, sleep := range sleeps {
+		//if the entry hasn't stopped, don't count it, its still active
+		if !sleep.End.After(sleep.Start) {
+			continue
+		}
+		date := q.Bucket(sleep.Start)
+		buckets[date] = append(buckets[date], sleep.End.Sub(sleep.Start))
+	}
+
+	chartData := &SleepChartData{
+		Start:       q.Start,
+		End:         q.End,
+		Granularity: q.Granularity,
+		Dataset:     []SleepChartDataset{},
+	}
+	for date, totals := range buckets {
+		chartData.Dataset = append(chartData.Dataset, SleepChartDataset{Date: date, Totals: totals})
+	}
+	sort.Slice(chartData.Dataset, func(i, j int) bool {
+		return chartData.Dataset[i].Date.Before(chartData.Dataset[j].Date)
+	})
+	return chartData
+}
+
+//NewWasteSummary - build the summary for a set of wastes
+func NewWasteSummary(wastes []Waste) *WasteSummary {
+	summary := &WasteSummary{
+		Data:  wastes,
+		Total: make(map[int]int),
+	}
+	for _, x := range wastes {
+		summary.Total[x.Type]++
+	}
+	return summary
+}
+
+//NewWasteChartData - group wastes into the buckets of the query, by type
+func NewWasteChartData(q *DataQuery, wastes []Waste) *WasteChartData {
+	type bucketKey struct {
+		date      time.Time
+		wasteType int
+	}
+	counts := make(map[bucketKey]int)
+	for _, waste := range wastes {
+		counts[bucketKey{q.Bucket(waste.TimeStamp), waste.Type}]++
+	}
+
+	chartData := &WasteChartData{
+		Start:       q.Start,
+		End:         q.End,
+		Granularity: q.Granularity,
+		Dataset:     []WasteChartDataset{},
+	}
+	for key, count := range counts {
+		chartData.Dataset = append(chartData.Dataset, WasteChartDataset{Date: key.date, Type: key.wasteType, Count: count})
+	}
+	sort.Slice(chartData.Dataset, func(i, j int) bool {
+		a, b := chartData.Dataset[i], chartData.Dataset[j]
+		if a.Date.Equal(b.Date) {
+			return a.Type < b.Type
+		}
+		return a.Date.Before(b.Date)
+	})
+	return chartData
+}
@@ -0,0 +1,79 @@
+package goparent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataQueryBucket(t *testing.T) {
+	//a sunday evening
+	ts := time.Date(2018, 7, 15, 21, 30, 10, 0, time.UTC)
+	testCases := []struct {
+		desc        string
+		granularity Granularity
+		bucket      time.Time
+	}{
+		{desc: "hour", granularity: GranularityHour, bucket: time.Date(2018, 7, 15, 21, 0, 0, 0, time.UTC)},
+		{desc: "day", granularity: GranularityDay, bucket: time.Date(2018, 7, 15, 0, 0, 0, 0, time.UTC)},
+		{desc: "week starts monday", granularity: GranularityWeek, bucket: time.Date(2018, 7, 9, 0, 0, 0, 0, time.UTC)},
+		{desc: "month", granularity: GranularityMonth, bucket: time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			q := &DataQuery{Granularity: tC.granularity}
+			assert.True(t, tC.bucket.Equal(q.Bucket(ts)), "got %s", q.Bucket(ts))
+		})
+	}
+}
+
+func TestDataQueryValidate(t *testing.T) {
+	now := time.Now()
+	assert.Nil(t, NewStatsQuery(now, nil).Validate())
+	assert.Nil(t, NewGraphQuery(now, time.UTC).Validate())
+	assert.Equal(t, ErrInvalidGranularity, (&DataQuery{Start: now, End: now.Add(time.Hour), Granularity: "fortnight"}).Validate())
+	assert.Equal(t, ErrInvalidDateRange, (&DataQuery{Start: now, End: now, Granularity: GranularityDay}).Validate())
+	assert.Nil(t, (&DataQuery{Start: now.AddDate(0, 0, -31), End: now, Granularity: GranularityHour}).Validate())
+	assert.Equal(t, ErrDateRangeTooLong, (&DataQuery{Start: now.AddDate(0, 0, -60), End: now, Granularity: GranularityHour}).Validate())
+	assert.Nil(t, (&DataQuery{Start: now.AddDate(-10, 0, 0), End: now, Granularity: GranularityMonth}).Validate())
+}
+
+func TestDataQueryLocalDays(t *testing.T) {
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//an evening feed in california is still today, not tomorrow in UTC
+	evening := time.Date(2018, 7, 15, 3, 30, 0, 0, time.UTC)
+	q := NewGraphQuery(evening, pacific)
+	assert.True(t, time.Date(2018, 7, 14, 0, 0, 0, 0, pacific).Equal(q.Bucket(evening)))
+	assert.True(t, time.Date(2018, 7, 15, 0, 0, 0, 0, pacific).Equal(q.End))
+
+	testCases := []struct {
+		desc   string
+		now    time.Time
+		length time.Duration
+	}{
+		{desc: "normal day", now: time.Date(2018, 7, 15, 12, 0, 0, 0, pacific), length: 24 * time.Hour},
+		{desc: "spring forward", now: time.Date(2018, 3, 11, 12, 0, 0, 0, pacific), length: 23 * time.Hour},
+		{desc: "fall back", now: time.Date(2018, 11, 4, 12, 0, 0, 0, pacific), length: 25 * time.Hour},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			q := NewStatsQuery(tC.now, pacific)
+			assert.Equal(t, tC.length, q.End.Sub(q.Start))
+			assert.Equal(t, 0, q.Start.In(pacific).Hour())
+			assert.Equal(t, 0, q.End.In(pacific).Hour())
+		})
+	}
+
+	//the repeated hour when clocks fall back gets its own bucket
+	hourly := &DataQuery{Granularity: GranularityHour, Location: pacific}
+	first := time.Date(2018, 11, 4, 8, 30, 0, 0, time.UTC)
+	second := time.Date(2018, 11, 4, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, 1, hourly.Bucket(first).Hour())
+	assert.Equal(t, 1, hourly.Bucket(second).Hour())
+	assert.False(t, hourly.Bucket(first).Equal(hourly.Bucket(second)))
+}
@@ -0,0 +1,136 @@
+package goparent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//RateCount - the hits against a rate limit key in its current window
+type RateCount struct {
+	Key   string    `json:"id" gorethink:"id"`
+	Count int       `json:"count" gorethink:"count"`
+	Start time.Time `json:"start" gorethink:"start"`
+	Last  time.Time `json:"last" gorethink:"last"`
+}
+
+//RateLimitStore - where rate limit and lockout counts are kept.  in memory is fine for a
+// single instance, running more than one needs them kept in the backend so they're shared.
+type RateLimitStore interface {
+	//Hit - count a hit against the key, starting a new window if the last one is over
+	Hit(ctx context.Context, key string, now time.Time, window time.Duration) (RateCount, error)
+	//Count - the key's count in its current window, without adding to it
+	Count(ctx context.Context, key string, now time.Time, window time.Duration) (RateCount, error)
+	//Reset - forget the key's count
+	Reset(ctx context.Context, key string) error
+}
+
+//RateLimit - at most Limit hits per Window
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+//Wait - how long until another hit is allowed, zero if one is allowed now
+func (limit RateLimit) Wait(count RateCount, now time.Time) time.Duration {
+	if count.Count <= limit.Limit {
+		return 0
+	}
+	return count.Start.Add(limit.Window).Sub(now)
+}
+
+//Lockout - locks an account out after Threshold failures within Window, for Base to
+// begin with and twice as long for every failure after that, up to Max
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+//Wait - how long the account is locked out for, zero if it isn't
+func (lockout Lockout) Wait(count RateCount, now time.Time) time.Duration {
+	if count.Count < lockout.Threshold {
+		return 0
+	}
+	wait := lockout.Max
+	if shift := uint(count.Count - lockout.Threshold); shift < 32 && lockout.Base<<shift < lockout.Max {
+		wait = lockout.Base << shift
+	}
+	until := count.Last.Add(wait)
+	if !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+//memory store sizes, once it has more keys than memoryRateLimitSweep any that haven't
+// been hit for memoryRateLimitMaxAge are dropped, at most once every memoryRateLimitSweepEvery
+// so a store full of recent keys isn't scanned on every hit
+const (
+	memoryRateLimitSweep      = 10000
+	memoryRateLimitMaxAge     = 24 * time.Hour
+	memoryRateLimitSweepEvery = time.Minute
+)
+
+//MemoryRateLimitStore - rate limit counts kept in memory, they're lost on restart and
+// aren't shared between instances
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	counts    map[string]RateCount
+	lastSweep time.Time
+}
+
+//NewMemoryRateLimitStore - an empty in memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counts: make(map[string]RateCount)}
+}
+
+//Hit - count a hit against the key
+func (s *MemoryRateLimitStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (RateCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.current(key, now, window)
+	if count.Count == 0 {
+		count.Start = now
+	}
+	count.Count++
+	count.Last = now
+	s.counts[key] = count
+	return count, nil
+}
+
+//Count - the key's count in its current window
+func (s *MemoryRateLimitStore) Count(ctx context.Context, key string, now time.Time, window time.Duration) (RateCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current(key, now, window), nil
+}
+
+//Reset - forget the key's count
+func (s *MemoryRateLimitStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counts, key)
+	return nil
+}
+
+//current - the key's count, dropping it if its window is over.  old counts for other
+// keys are swept out now and then so the map doesn't keep growing.
+func (s *MemoryRateLimitStore) current(key string, now time.Time, window time.Duration) RateCount {
+	count, ok := s.counts[key]
+	if ok && !now.Before(count.Start.Add(window)) {
+		delete(s.counts, key)
+		count = RateCount{}
+	}
+	if len(s.counts) > memoryRateLimitSweep && now.Sub(s.lastSweep) >= memoryRateLimitSweepEvery {
+		s.lastSweep = now
+		for k, c := range s.counts {
+			if now.Sub(c.Last) > memoryRateLimitMaxAge {
+				delete(s.counts, k)
+			}
+		}
+	}
+	count.Key = key
+	return count
+}
@@ -0,0 +1,121 @@
+package goparent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1500000000, 0)
+	store := NewMemoryRateLimitStore()
+
+	for i := 1; i <= 3; i++ {
+		count, err := store.Hit(ctx, "key", now.Add(time.Duration(i)*time.Second), time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, i, count.Count)
+		assert.Equal(t, now.Add(time.Second), count.Start)
+	}
+	count, err := store.Count(ctx, "key", now.Add(10*time.Second), time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count.Count)
+	assert.Equal(t, now.Add(3*time.Second), count.Last)
+
+	count, _ = store.Count(ctx, "other", now, time.Minute)
+	assert.Equal(t, 0, count.Count)
+
+	count, _ = store.Hit(ctx, "key", now.Add(2*time.Minute), time.Minute)
+	assert.Equal(t, 1, count.Count, "a new window starts once the last is over")
+	assert.Equal(t, now.Add(2*time.Minute), count.Start)
+
+	assert.Nil(t, store.Reset(ctx, "key"))
+	count, _ = store.Count(ctx, "key", now.Add(2*time.Minute), time.Minute)
+	assert.Equal(t, 0, count.Count)
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1500000000, 0)
+	store := NewMemoryRateLimitStore()
+	fill := func(n int, last time.Time) {
+		for i := 0; i < n; i++ {
+			store.counts[fmt.Sprintf("%d-%d", last.Unix(), i)] = RateCount{Start: last, Last: last}
+		}
+	}
+
+	fill(memoryRateLimitSweep, now.Add(-2*memoryRateLimitMaxAge))
+	store.Hit(ctx, "key", now, time.Minute)
+	assert.Len(t, store.counts, memoryRateLimitSweep+1, "there aren't enough keys to sweep yet")
+	store.Hit(ctx, "key", now, time.Minute)
+	assert.Len(t, store.counts, 1, "old keys are swept once there are too many")
+
+	//recent keys stay, and the store isn't scanned again on every hit
+	fill(memoryRateLimitSweep, now)
+	fill(10, now.Add(-2*memoryRateLimitMaxAge))
+	store.Hit(ctx, "key", now.Add(time.Second), time.Minute)
+	assert.Len(t, store.counts, memoryRateLimitSweep+11)
+	store.Hit(ctx, "key", now.Add(memoryRateLimitSweepEvery), time.Minute)
+	assert.Len(t, store.counts, memoryRateLimitSweep+1)
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	limit := RateLimit{Limit: 3, Window: time.Minute}
+	testCases := []struct {
+		desc  string
+		count RateCount
+		wait  time.Duration
+	}{
+		{desc: "under", count: RateCount{Count: 2, Start: now.Add(-10 * time.Second)}},
+		{desc: "at the limit", count: RateCount{Count: 3, Start: now.Add(-10 * time.Second)}},
+		{desc: "over", count: RateCount{Count: 4, Start: now.Add(-10 * time.Second)}, wait: 50 * time.Second},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.wait, limit.Wait(tC.count, now))
+		})
+	}
+}
+
+func TestLockoutWait(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	lockout := Lockout{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute, Window: time.Hour}
+	testCases := []struct {
+		desc  string
+		count RateCount
+		wait  time.Duration
+	}{
+		{desc: "under the threshold", count: RateCount{Count: 2, Last: now}},
+		{desc: "at the threshold", count: RateCount{Count: 3, Last: now}, wait: time.Minute},
+		{desc: "doubles", count: RateCount{Count: 5, Last: now}, wait: 4 * time.Minute},
+		{desc: "capped", count: RateCount{Count: 8, Last: now}, wait: 10 * time.Minute},
+		{desc: "capped for huge counts", count: RateCount{Count: 500, Last: now}, wait: 10 * time.Minute},
+		{desc: "counts from the last failure", count: RateCount{Count: 3, Last: now.Add(-40 * time.Second)}, wait: 20 * time.Second},
+		{desc: "over", count: RateCount{Count: 3, Last: now.Add(-2 * time.Minute)}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.wait, lockout.Wait(tC.count, now))
+		})
+	}
+}
+
+func TestResetCode(t *testing.T) {
+	code, hash, err := NewResetCode()
+	assert.Nil(t, err)
+	assert.Len(t, code, 64)
+	assert.Equal(t, hash, HashResetCode(code))
+	assert.NotEqual(t, code, hash)
+
+	other, _, _ := NewResetCode()
+	assert.NotEqual(t, code, other)
+
+	now := time.Now()
+	assert.False(t, (&UserReset{ExpiresAt: now.Add(time.Minute)}).Expired(now))
+	assert.True(t, (&UserReset{ExpiresAt: now}).Expired(now))
+	assert.True(t, (&UserReset{}).Expired(now))
+}
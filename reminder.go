@@ -0,0 +1,424 @@
+package goparent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+//ReminderKind - what a reminder rule watches
+type ReminderKind string
+
+//Reminder kinds.  the data kinds are due Interval after the child's latest feeding, wet
+// diaper or sleep, medication is due every Interval from Start.
+const (
+	ReminderFeedingDue   ReminderKind = "feeding_due"
+	ReminderNoWetDiaper  ReminderKind = "no_wet_diaper"
+	ReminderAwakeTooLong ReminderKind = "awake_too_long"
+	ReminderMedication   ReminderKind = "medication"
+)
+
+//ReminderKinds - the kinds of rules we evaluate
+var ReminderKinds = []string{string(ReminderFeedingDue), string(ReminderNoWetDiaper), string(ReminderAwakeTooLong), string(ReminderMedication)}
+
+//Notification channels.  every notification goes to the inbox, rules can add the others.
+const (
+	ChannelInbox   = "inbox"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+//NotificationChannels - the channels a rule can deliver through
+var NotificationChannels = []string{ChannelInbox, ChannelWebhook, ChannelEmail}
+
+//NotificationStatus - where a notification is in its life
+type NotificationStatus string
+
+//Notification statuses.  pending notifications haven't been delivered yet, snoozed ones
+// are delivered again once SnoozedUntil passes.
+const (
+	NotificationPending      NotificationStatus = "pending"
+	NotificationDelivered    NotificationStatus = "delivered"
+	NotificationSnoozed      NotificationStatus = "snoozed"
+	NotificationAcknowledged NotificationStatus = "acknowledged"
+)
+
+//ResourceReminder - the resource reminder events are published as, with type due
+const ResourceReminder = "reminder"
+
+//EventDue - a reminder came due
+const EventDue EventType = "due"
+
+//ErrNoReminderFound - there is no reminder rule with that id
+var ErrNoReminderFound = errors.New("no reminder found with that id")
+
+//ErrNoNotificationFound - there is no notification with that id
+var ErrNoNotificationFound = errors.New("no notification found with that id")
+
+//ErrNotificationExists - a notification with that id has been created already
+var ErrNotificationExists = errors.New("notification already exists")
+
+//ReminderRule - a reminder for one child.  Interval is in minutes.
+type ReminderRule struct {
+	ID          string       `json:"id" gorethink:"id,omitempty"`
+	FamilyID    string       `json:"familyID" gorethink:"familyID"`
+	ChildID     string       `json:"childID" gorethink:"childID"`
+	Kind        ReminderKind `json:"kind" gorethink:"kind"`
+	Name        string       `json:"name" gorethink:"name"`
+	Interval    int          `json:"intervalMinutes" gorethink:"intervalMinutes"`
+	Start       time.Time    `json:"start" gorethink:"start"`
+	Channels    []string     `json:"channels" gorethink:"channels"`
+	Disabled    bool         `json:"disabled" gorethink:"disabled"`
+	CreatedAt   time.Time    `json:"createdAt" gorethink:"createdAt"`
+	LastUpdated time.Time    `json:"lastUpdated" gorethink:"lastUpdated"`
+}
+
+//Notification - a reminder that came due.  there's at most one per rule and due time, so
+// restarting the scheduler doesn't repeat them.
+type Notification struct {
+	ID             string             `json:"id" gorethink:"id,omitempty"`
+	FamilyID       string             `json:"familyID" gorethink:"familyID"`
+	ChildID        string             `json:"childID" gorethink:"childID"`
+	RuleID         string             `json:"ruleID" gorethink:"ruleID"`
+	Kind           ReminderKind       `json:"kind" gorethink:"kind"`
+	Message        string             `json:"message" gorethink:"message"`
+	DueAt          time.Time          `json:"dueAt" gorethink:"dueAt"`
+	Status         NotificationStatus `json:"status" gorethink:"status"`
+	SnoozedUntil   time.Time          `json:"snoozedUntil" gorethink:"snoozedUntil"`
+	DeliveredAt    time.Time          `json:"deliveredAt" gorethink:"deliveredAt"`
+	AcknowledgedAt time.Time          `json:"acknowledgedAt" gorethink:"acknowledgedAt"`
+	AcknowledgedBy string             `json:"acknowledgedBy" gorethink:"acknowledgedBy"`
+	CreatedAt      time.Time          `json:"createdAt" gorethink:"createdAt"`
+}
+
+//ReminderService -
+type ReminderService interface {
+	SaveRule(context.Context, *ReminderRule) error
+	Rule(context.Context, string) (*ReminderRule, error)
+	Rules(context.Context, *Family) ([]*ReminderRule, error)
+	ActiveRules(context.Context) ([]*ReminderRule, error)
+	DeleteRule(context.Context, *ReminderRule) error
+	SaveNotification(context.Context, *Notification) error
+	CreateNotification(context.Context, *Notification) error
+	ClaimNotification(context.Context, *Notification, time.Time) (bool, error)
+	Notification(context.Context, string) (*Notification, error)
+	Notifications(context.Context, *Family, bool) ([]*Notification, error)
+	LatestNotification(context.Context, *ReminderRule) (*Notification, error)
+	DueNotifications(context.Context, time.Time) ([]*Notification, error)
+}
+
+//NotificationID - the id of the rule's notification for a due time.  every scheduler comes
+// up with the same one, so only the first to create it gets to.
+func NotificationID(rule *ReminderRule, due time.Time) string {
+	return fmt.Sprintf("%s-%d", rule.ID, due.Unix())
+}
+
+//Duration - the rule's interval
+func (rule *ReminderRule) Duration() time.Duration {
+	return time.Duration(rule.Interval) * time.Minute
+}
+
+//Validate - check a reminder rule before it's saved
+func (reminder ReminderRule) Validate(now time.Time) error {
+	rules := []rule{
+		required("childID", reminder.ChildID),
+		oneOf("kind", string(reminder.Kind), ReminderKinds),
+		between("intervalMinutes", reminder.Interval, 1, 7*24*60),
+	}
+	if reminder.Kind == ReminderMedication {
+		rules = append(rules, requiredTime("start", reminder.Start))
+	}
+	for _, channel := range reminder.Channels {
+		rules = append(rules, oneOf("channels", channel, NotificationChannels))
+	}
+	return validate(rules...)
+}
+
+//Due - whether the notification is waiting to be delivered at now
+func (n *Notification) Due(now time.Time) bool {
+	return n.Status == NotificationPending || (n.Status == NotificationSnoozed && !n.SnoozedUntil.After(now))
+}
+
+//Snooze - deliver the notification again after d
+func (n *Notification) Snooze(now time.Time, d time.Duration) {
+	n.Status = NotificationSnoozed
+	n.SnoozedUntil = now.Add(d)
+}
+
+//Acknowledge - the notification has been dealt with, it won't be delivered again
+func (n *Notification) Acknowledge(now time.Time, user *User) {
+	n.Status = NotificationAcknowledged
+	n.AcknowledgedAt = now
+	n.AcknowledgedBy = user.ID
+}
+
+//NotificationChannel - a way of telling the family about a notification
+type NotificationChannel interface {
+	Notify(context.Context, *Notification) error
+}
+
+//NotificationChannelFunc - a function that is a NotificationChannel
+type NotificationChannelFunc func(context.Context, *Notification) error
+
+//Notify - call f
+func (f NotificationChannelFunc) Notify(ctx context.Context, n *Notification) error {
+	return f(ctx, n)
+}
+
+//NotificationEvent - the reminder.due event for a notification.  it isn't published on the
+// bus, webhooks only get it when the rule asks for the webhook channel.
+func NotificationEvent(n *Notification) Event {
+	return Event{
+		ID:       n.ID,
+		Type:     EventDue,
+		Resource: ResourceReminder,
+		FamilyID: n.FamilyID,
+		ChildID:  n.ChildID,
+		Data:     n,
+	}
+}
+
+//WebhookChannel - deliver notifications as reminder.due events to the family's webhooks
+func WebhookChannel(d *WebhookDispatcher) NotificationChannel {
+	return NotificationChannelFunc(func(ctx context.Context, n *Notification) error {
+		event := NotificationEvent(n)
+		event.Time = time.Now()
+		d.Dispatch(ctx, event)
+		return nil
+	})
+}
+
+//EmailChannel - mail notifications to every member of the family
+func EmailChannel(mailer Mailer, families FamilyService, users UserService) NotificationChannel {
+	return NotificationChannelFunc(func(ctx context.Context, n *Notification) error {
+		family, err := families.Family(ctx, n.FamilyID)
+		if err != nil {
+			return err
+		}
+		var to []string
+		for _, id := range family.Members {
+			user, err := users.User(ctx, id)
+			if err != nil {
+				return err
+			}
+			to = append(to, user.Email)
+		}
+		if len(to) == 0 {
+			return nil
+		}
+
+		msg, err := NewMail(MailDigest, to, DigestMail{Notifications: []*Notification{n}})
+		if err != nil {
+			return err
+		}
+		return mailer.Send(ctx, msg)
+	})
+}
+
+//ReminderScheduler - evaluates reminder rules and delivers the notifications that come due.
+// notifications are saved before they're delivered, so ones that were due while the
+// scheduler was down go out when it starts again.  every instance of the service runs one,
+// creating and claiming notifications is atomic so each is only sent once.
+type ReminderScheduler struct {
+	Reminders ReminderService
+	Children  ChildService
+	Feedings  FeedingService
+	Wastes    WasteService
+	Sleeps    SleepService
+	//Channels - delivery channels by name, the inbox doesn't need one
+	Channels map[string]NotificationChannel
+	Interval time.Duration
+	//Lookback - how far back to look for a child's latest data, rules for children with
+	// nothing recorded in that time are skipped
+	Lookback time.Duration
+}
+
+//Run - evaluate rules every Interval until the context is done
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := s.Tick(ctx, time.Now())
+		if err != nil {
+			log.Printf("reminder scheduler: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//Tick - create notifications for the rules that are due and deliver what is waiting
+func (s *ReminderScheduler) Tick(ctx context.Context, now time.Time) error {
+	rules, err := s.Reminders.ActiveRules(ctx)
+	if err != nil {
+		return err
+	}
+	channels := make(map[string][]string)
+	for _, rule := range rules {
+		channels[rule.ID] = rule.Channels
+		err = s.evaluate(ctx, rule, now)
+		if err != nil {
+			log.Printf("reminder %s: %s", rule.ID, err)
+		}
+	}
+
+	notifications, err := s.Reminders.DueNotifications(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, n := range notifications {
+		s.deliver(ctx, n, channels[n.RuleID], now)
+	}
+	return nil
+}
+
+//evaluate - create a pending notification if the rule is due and it hasn't been already
+func (s *ReminderScheduler) evaluate(ctx context.Context, rule *ReminderRule, now time.Time) error {
+	due, ok, err := s.dueAt(ctx, rule, now)
+	if err != nil || !ok || due.After(now) {
+		return err
+	}
+	latest, err := s.Reminders.LatestNotification(ctx, rule)
+	if err != nil {
+		return err
+	}
+	if latest != nil && !latest.DueAt.Before(due) {
+		return nil
+	}
+
+	n := &Notification{
+		ID:        NotificationID(rule, due),
+		FamilyID:  rule.FamilyID,
+		ChildID:   rule.ChildID,
+		RuleID:    rule.ID,
+		Kind:      rule.Kind,
+		Message:   s.message(ctx, rule, due, now),
+		DueAt:     due,
+		Status:    NotificationPending,
+		CreatedAt: now,
+	}
+	err = s.Reminders.CreateNotification(ctx, n)
+	if err == ErrNotificationExists {
+		return nil
+	}
+	return err
+}
+
+//dueAt - when the rule is next due, ok is false when there isn't enough data to say
+func (s *ReminderScheduler) dueAt(ctx context.Context, rule *ReminderRule, now time.Time) (time.Time, bool, error) {
+	interval := rule.Duration()
+	if rule.Kind == ReminderMedication {
+		if now.Before(rule.Start) {
+			return rule.Start, true, nil
+		}
+		return rule.Start.Add(now.Sub(rule.Start) / interval * interval), true, nil
+	}
+
+	lookback := s.Lookback
+	if lookback <= 0 {
+		lookback = 7 * 24 * time.Hour
+	}
+	child := &Child{ID: rule.ChildID, FamilyID: rule.FamilyID}
+	query := &DataQuery{Start: now.Add(-lookback), End: now.Add(time.Second), Granularity: GranularityDay}
+
+	var last time.Time
+	switch rule.Kind {
+	case ReminderFeedingDue:
+		summary, err := s.Feedings.Stats(ctx, child, query)
+		if err != nil {
+			return last, false, err
+		}
+		for _, feeding := range summary.Data {
+			if feeding.TimeStamp.After(last) {
+				last = feeding.TimeStamp
+			}
+		}
+	case ReminderNoWetDiaper:
+		summary, err := s.Wastes.Stats(ctx, child, query)
+		if err != nil {
+			return last, false, err
+		}
+		for _, waste := range summary.Data {
+			//2 is solid only, the others are wet
+			if waste.Type != 2 && waste.TimeStamp.After(last) {
+				last = waste.TimeStamp
+			}
+		}
+	case ReminderAwakeTooLong:
+		_, asleep, err := s.Sleeps.Status(ctx, &Family{ID: rule.FamilyID}, child)
+		if err != nil || asleep {
+			return last, false, err
+		}
+		summary, err := s.Sleeps.Stats(ctx, child, query)
+		if err != nil {
+			return last, false, err
+		}
+		for _, sleep := range summary.Data {
+			if sleep.End.After(last) {
+				last = sleep.End
+			}
+		}
+	default:
+		return last, false, fmt.Errorf("unknown reminder kind %q", rule.Kind)
+	}
+	if last.IsZero() {
+		return last, false, nil
+	}
+	return last.Add(interval), true, nil
+}
+
+func (s *ReminderScheduler) message(ctx context.Context, rule *ReminderRule, due time.Time, now time.Time) string {
+	name := "your child"
+	if s.Children != nil {
+		child, err := s.Children.Child(ctx, rule.ChildID)
+		if err == nil {
+			name = child.Name
+		}
+	}
+	since := rule.Duration()
+	switch rule.Kind {
+	case ReminderFeedingDue:
+		return fmt.Sprintf("%s hasn't been fed in %s", name, since)
+	case ReminderNoWetDiaper:
+		return fmt.Sprintf("%s hasn't had a wet diaper in %s", name, since)
+	case ReminderAwakeTooLong:
+		return fmt.Sprintf("%s has been awake for %s", name, since)
+	}
+	if rule.Name != "" {
+		return fmt.Sprintf("%s is due for %s", name, rule.Name)
+	}
+	return fmt.Sprintf("%s is due for medication", name)
+}
+
+//deliver - claim the notification and send it through its rule's channels.  claiming marks
+// it delivered, so another scheduler doesn't send it too.  channel failures are logged, the
+// notification is in the inbox either way.
+func (s *ReminderScheduler) deliver(ctx context.Context, n *Notification, channels []string, now time.Time) {
+	claimed, err := s.Reminders.ClaimNotification(ctx, n, now)
+	if err != nil {
+		log.Printf("claiming notification %s: %s", n.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	for _, name := range channels {
+		channel, ok := s.Channels[name]
+		if !ok {
+			continue
+		}
+		err := channel.Notify(ctx, n)
+		if err != nil {
+			log.Printf("notification %s to %s: %s", n.ID, name, err)
+		}
+	}
+}
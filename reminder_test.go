@@ -0,0 +1,358 @@
+package goparent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testReminderService - keeps rules and notifications in memory
+type testReminderService struct {
+	ReminderService
+	mu            sync.Mutex
+	rules         []*ReminderRule
+	notifications []*Notification
+}
+
+func (s *testReminderService) ActiveRules(ctx context.Context) ([]*ReminderRule, error) {
+	return s.rules, nil
+}
+
+func (s *testReminderService) CreateNotification(ctx context.Context, n *Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, saved := range s.notifications {
+		if saved.ID == n.ID {
+			return ErrNotificationExists
+		}
+	}
+	saved := *n
+	s.notifications = append(s.notifications, &saved)
+	return nil
+}
+
+func (s *testReminderService) ClaimNotification(ctx context.Context, n *Notification, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, saved := range s.notifications {
+		if saved.ID == n.ID && saved.Due(now) {
+			saved.Status = NotificationDelivered
+			saved.DeliveredAt = now
+			n.Status, n.DeliveredAt = saved.Status, saved.DeliveredAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *testReminderService) LatestNotification(ctx context.Context, rule *ReminderRule) (*Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latest *Notification
+	for _, n := range s.notifications {
+		if n.RuleID == rule.ID && (latest == nil || n.DueAt.After(latest.DueAt)) {
+			latest = n
+		}
+	}
+	return latest, nil
+}
+
+func (s *testReminderService) DueNotifications(ctx context.Context, now time.Time) ([]*Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*Notification
+	for _, n := range s.notifications {
+		if n.Due(now) {
+			//a copy, like a row read from the database
+			copied := *n
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+type testFeedingService struct {
+	FeedingService
+	feedings []Feeding
+}
+
+func (s *testFeedingService) Stats(ctx context.Context, child *Child, query *DataQuery) (*FeedingSummary, error) {
+	return &FeedingSummary{Data: s.feedings}, nil
+}
+
+type testWasteService struct {
+	WasteService
+	wastes []Waste
+}
+
+func (s *testWasteService) Stats(ctx context.Context, child *Child, query *DataQuery) (*WasteSummary, error) {
+	return &WasteSummary{Data: s.wastes}, nil
+}
+
+type testSleepService struct {
+	SleepService
+	sleeps []Sleep
+	asleep bool
+}
+
+func (s *testSleepService) Status(ctx context.Context, family *Family, child *Child) (*Sleep, bool, error) {
+	return nil, s.asleep, nil
+}
+
+func (s *testSleepService) Stats(ctx context.Context, child *Child, query *DataQuery) (*SleepSummary, error) {
+	return &SleepSummary{Data: s.sleeps}, nil
+}
+
+type testChildService struct {
+	ChildService
+}
+
+func (s *testChildService) Child(ctx context.Context, id string) (*Child, error) {
+	return &Child{ID: id, Name: "Sam"}, nil
+}
+
+func TestReminderRuleValidate(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		desc   string
+		rule   ReminderRule
+		fields []string
+	}{
+		{
+			desc: "valid feeding rule",
+			rule: ReminderRule{ChildID: "1", Kind: ReminderFeedingDue, Interval: 180, Channels: []string{ChannelWebhook}},
+		},
+		{
+			desc:   "missing everything",
+			rule:   ReminderRule{},
+			fields: []string{"childID", "kind", "intervalMinutes"},
+		},
+		{
+			desc:   "medication needs a start",
+			rule:   ReminderRule{ChildID: "1", Kind: ReminderMedication, Interval: 360},
+			fields: []string{"start"},
+		},
+		{
+			desc:   "unknown channel",
+			rule:   ReminderRule{ChildID: "1", Kind: ReminderAwakeTooLong, Interval: 120, Channels: []string{"sms"}},
+			fields: []string{"channels"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.rule.Validate(now)
+			if len(tC.fields) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			var verr *ValidationError
+			if assert.True(t, errors.As(err, &verr)) {
+				var fields []string
+				for _, f := range verr.Fields {
+					fields = append(fields, f.Field)
+				}
+				assert.Equal(t, tC.fields, fields)
+			}
+		})
+	}
+}
+
+func TestReminderDueAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	scheduler := &ReminderScheduler{
+		Feedings: &testFeedingService{feedings: []Feeding{{TimeStamp: now.Add(-5 * time.Hour)}, {TimeStamp: now.Add(-2 * time.Hour)}}},
+		Wastes:   &testWasteService{wastes: []Waste{{Type: 1, TimeStamp: now.Add(-6 * time.Hour)}, {Type: 2, TimeStamp: now.Add(-time.Hour)}}},
+		Sleeps:   &testSleepService{sleeps: []Sleep{{Start: now.Add(-4 * time.Hour), End: now.Add(-3 * time.Hour)}}},
+	}
+	testCases := []struct {
+		desc string
+		rule *ReminderRule
+		due  time.Time
+		ok   bool
+	}{
+		{
+			desc: "feeding after the latest feeding",
+			rule: &ReminderRule{Kind: ReminderFeedingDue, Interval: 180},
+			due:  now.Add(time.Hour),
+			ok:   true,
+		},
+		{
+			desc: "solid only diapers don't count",
+			rule: &ReminderRule{Kind: ReminderNoWetDiaper, Interval: 240},
+			due:  now.Add(-2 * time.Hour),
+			ok:   true,
+		},
+		{
+			desc: "awake since the last sleep ended",
+			rule: &ReminderRule{Kind: ReminderAwakeTooLong, Interval: 120},
+			due:  now.Add(-time.Hour),
+			ok:   true,
+		},
+		{
+			desc: "medication on its schedule",
+			rule: &ReminderRule{Kind: ReminderMedication, Interval: 360, Start: now.Add(-13 * time.Hour)},
+			due:  now.Add(-time.Hour),
+			ok:   true,
+		},
+		{
+			desc: "medication not started",
+			rule: &ReminderRule{Kind: ReminderMedication, Interval: 360, Start: now.Add(time.Hour)},
+			due:  now.Add(time.Hour),
+			ok:   true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			due, ok, err := scheduler.dueAt(context.Background(), tC.rule, now)
+			assert.Nil(t, err)
+			assert.Equal(t, tC.ok, ok)
+			assert.True(t, tC.due.Equal(due), "due %s, want %s", due, tC.due)
+		})
+	}
+
+	asleep := &ReminderScheduler{Sleeps: &testSleepService{asleep: true}}
+	_, ok, err := asleep.dueAt(context.Background(), &ReminderRule{Kind: ReminderAwakeTooLong, Interval: 120}, now)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	noData := &ReminderScheduler{Feedings: &testFeedingService{}}
+	_, ok, err = noData.dueAt(context.Background(), &ReminderRule{Kind: ReminderFeedingDue, Interval: 120}, now)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestReminderSchedulerTick(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	reminders := &testReminderService{rules: []*ReminderRule{
+		{ID: "1", FamilyID: "1", ChildID: "1", Kind: ReminderFeedingDue, Interval: 60, Channels: []string{ChannelWebhook, ChannelEmail}},
+		{ID: "2", FamilyID: "1", ChildID: "1", Kind: ReminderMedication, Interval: 60, Start: now.Add(time.Hour)},
+	}}
+	var notified []string
+	scheduler := &ReminderScheduler{
+		Reminders: reminders,
+		Children:  &testChildService{},
+		Feedings:  &testFeedingService{feedings: []Feeding{{TimeStamp: now.Add(-2 * time.Hour)}}},
+		Channels: map[string]NotificationChannel{
+			ChannelWebhook: NotificationChannelFunc(func(ctx context.Context, n *Notification) error {
+				notified = append(notified, ChannelWebhook+":"+n.RuleID)
+				return nil
+			}),
+			ChannelEmail: NotificationChannelFunc(func(ctx context.Context, n *Notification) error {
+				return errors.New("mail server down")
+			}),
+		},
+	}
+
+	assert.Nil(t, scheduler.Tick(ctx, now))
+	if assert.Len(t, reminders.notifications, 1) {
+		n := reminders.notifications[0]
+		assert.Equal(t, "Sam hasn't been fed in 1h0m0s", n.Message)
+		assert.True(t, now.Add(-time.Hour).Equal(n.DueAt))
+		assert.Equal(t, NotificationDelivered, n.Status)
+		assert.True(t, now.Equal(n.DeliveredAt))
+	}
+	assert.Equal(t, []string{"webhook:1"}, notified)
+
+	//a restart evaluates the same data again, it isn't notified twice
+	assert.Nil(t, scheduler.Tick(ctx, now.Add(time.Minute)))
+	assert.Len(t, reminders.notifications, 1)
+	assert.Len(t, notified, 1)
+
+	//snoozed notifications are delivered again once the snooze is over
+	reminders.notifications[0].Snooze(now, 15*time.Minute)
+	assert.Nil(t, scheduler.Tick(ctx, now.Add(10*time.Minute)))
+	assert.Len(t, notified, 1)
+	assert.Nil(t, scheduler.Tick(ctx, now.Add(15*time.Minute)))
+	assert.Len(t, notified, 2)
+	assert.Equal(t, NotificationDelivered, reminders.notifications[0].Status)
+
+	//the medication comes due when its start passes
+	assert.Nil(t, scheduler.Tick(ctx, now.Add(90*time.Minute)))
+	if assert.Len(t, reminders.notifications, 2) {
+		assert.Equal(t, "Sam is due for medication", reminders.notifications[1].Message)
+		assert.True(t, now.Add(time.Hour).Equal(reminders.notifications[1].DueAt))
+	}
+}
+
+func TestReminderSchedulerReplicas(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	reminders := &testReminderService{rules: []*ReminderRule{
+		{ID: "1", FamilyID: "1", ChildID: "1", Kind: ReminderFeedingDue, Interval: 60, Channels: []string{ChannelWebhook}},
+		{ID: "2", FamilyID: "1", ChildID: "1", Kind: ReminderMedication, Interval: 60, Start: now.Add(-time.Minute), Channels: []string{ChannelWebhook}},
+	}}
+	var mu sync.Mutex
+	notified := make(map[string]int)
+	channels := map[string]NotificationChannel{
+		ChannelWebhook: NotificationChannelFunc(func(ctx context.Context, n *Notification) error {
+			mu.Lock()
+			defer mu.Unlock()
+			notified[n.ID]++
+			return nil
+		}),
+	}
+
+	//one scheduler per instance of the service, all ticking against the same database
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		scheduler := &ReminderScheduler{
+			Reminders: reminders,
+			Feedings:  &testFeedingService{feedings: []Feeding{{TimeStamp: now.Add(-2 * time.Hour)}}},
+			Channels:  channels,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, scheduler.Tick(ctx, now))
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, reminders.notifications, 2)
+	assert.Equal(t, map[string]int{
+		NotificationID(reminders.rules[0], now.Add(-time.Hour)):   1,
+		NotificationID(reminders.rules[1], now.Add(-time.Minute)): 1,
+	}, notified)
+}
+
+func TestNotificationAcknowledge(t *testing.T) {
+	now := time.Now()
+	n := &Notification{Status: NotificationSnoozed}
+	n.Acknowledge(now, &User{ID: "2"})
+	assert.Equal(t, NotificationAcknowledged, n.Status)
+	assert.Equal(t, "2", n.AcknowledgedBy)
+	assert.Equal(t, now, n.AcknowledgedAt)
+}
+
+type testFamilyService struct {
+	FamilyService
+	family *Family
+}
+
+func (s *testFamilyService) Family(ctx context.Context, id string) (*Family, error) {
+	return s.family, nil
+}
+
+type testUserService struct {
+	UserService
+}
+
+func (s *testUserService) User(ctx context.Context, id string) (*User, error) {
+	return &User{ID: id, Email: "parent" + id + "@test.com"}, nil
+}
+
+func TestEmailChannel(t *testing.T) {
+	mailer := &testMailer{}
+	channel := EmailChannel(mailer, &testFamilyService{family: &Family{ID: "1", Members: []string{"1", "2"}}}, &testUserService{})
+	err := channel.Notify(context.Background(), &Notification{FamilyID: "1", Message: "Sam is due for medication", DueAt: time.Now()})
+	assert.Nil(t, err)
+	if assert.Len(t, mailer.sent, 1) {
+		assert.Equal(t, []string{"parent1@test.com", "parent2@test.com"}, mailer.sent[0].To)
+		assert.Equal(t, "Sam is due for medication", mailer.sent[0].Subject)
+	}
+}
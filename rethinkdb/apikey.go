@@ -0,0 +1,125 @@
+package rethinkdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	gorethink "gopkg.in/gorethink/gorethink.v3"
+)
+
+//APIKeyService - service for implementing the interface
+type APIKeyService struct {
+	Env *goparent.Env
+	DB  *DBEnv
+}
+
+//Save - create or update an api key
+func (ks *APIKeyService) Save(ctx context.Context, key *goparent.APIKey) error {
+	err := ks.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	res, err := gorethink.Table("apikeys").Insert(key, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(ks.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted > 0 && len(res.GeneratedKeys) > 0 {
+		key.ID = res.GeneratedKeys[0]
+	}
+	return nil
+}
+
+//APIKey - return an api key for an id
+func (ks *APIKeyService) APIKey(ctx context.Context, id string) (*goparent.APIKey, error) {
+	err := ks.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("apikeys").Get(id).Run(ks.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	return oneAPIKey(res)
+}
+
+//APIKeyByHash - return the api key with the hash, for authenticating with it
+func (ks *APIKeyService) APIKeyByHash(ctx context.Context, hash string) (*goparent.APIKey, error) {
+	err := ks.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("apikeys").Filter(map[string]interface{}{"hash": hash}).Run(ks.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	return oneAPIKey(res)
+}
+
+func oneAPIKey(res *gorethink.Cursor) (*goparent.APIKey, error) {
+	defer res.Close()
+	var key goparent.APIKey
+	err := res.One(&key)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoAPIKeyFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+//APIKeys - all of a user's api keys, oldest first
+func (ks *APIKeyService) APIKeys(ctx context.Context, user *goparent.User) ([]*goparent.APIKey, error) {
+	err := ks.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("apikeys").
+		Filter(map[string]interface{}{
+			"userID": user.ID,
+		}).
+		OrderBy("createdAt").
+		Run(ks.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows []*goparent.APIKey
+	err = res.All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//Used - record when the key was last used
+func (ks *APIKeyService) Used(ctx context.Context, key *goparent.APIKey, at time.Time) error {
+	err := ks.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("apikeys").Get(key.ID).Update(map[string]interface{}{"lastUsed": at}).RunWrite(ks.DB.Session)
+	if err != nil {
+		return err
+	}
+	key.LastUsed = at
+	return nil
+}
+
+//Delete - revoke an api key
+func (ks *APIKeyService) Delete(ctx context.Context, key *goparent.APIKey) error {
+	err := ks.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("apikeys").Get(key.ID).Delete().RunWrite(ks.DB.Session)
+	return err
+}
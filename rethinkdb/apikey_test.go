@@ -0,0 +1,81 @@
+package rethinkdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestAPIKeySave(t *testing.T) {
+	rmock := r.NewMock()
+	rmock.On(r.Table("apikeys").MockAnything()).Return(r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"key-1"}}, nil)
+	ks := APIKeyService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+	key := &goparent.APIKey{UserID: "user-1", Name: "button", Hash: "hash", Scopes: []string{goparent.ScopeWasteWrite}}
+	err := ks.Save(context.Background(), key)
+	assert.Nil(t, err)
+	assert.Equal(t, "key-1", key.ID)
+}
+
+func TestAPIKeyByHash(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		rows        []interface{}
+		returnError error
+	}{
+		{
+			desc: "found",
+			rows: []interface{}{map[string]interface{}{"id": "key-1", "userID": "user-1", "hash": "hash", "scopes": []interface{}{"waste:write"}}},
+		},
+		{
+			desc:        "not found",
+			rows:        []interface{}{},
+			returnError: goparent.ErrNoAPIKeyFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rmock := r.NewMock()
+			rmock.On(r.Table("apikeys").Filter(map[string]interface{}{"hash": "hash"})).Return(tC.rows, nil)
+			ks := APIKeyService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+			key, err := ks.APIKeyByHash(context.Background(), "hash")
+			assert.Equal(t, tC.returnError, err)
+			if tC.returnError == nil {
+				assert.Equal(t, "user-1", key.UserID)
+				assert.Equal(t, []string{goparent.ScopeWasteWrite}, key.Scopes)
+			}
+		})
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	rmock := r.NewMock()
+	rmock.On(r.Table("apikeys").Filter(map[string]interface{}{"userID": "user-1"}).OrderBy("createdAt")).Return([]interface{}{
+		map[string]interface{}{"id": "key-1", "userID": "user-1", "name": "button"},
+		map[string]interface{}{"id": "key-2", "userID": "user-1", "name": "phone"},
+	}, nil)
+	ks := APIKeyService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+	keys, err := ks.APIKeys(context.Background(), &goparent.User{ID: "user-1"})
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+}
+
+func TestAPIKeyUsedAndDelete(t *testing.T) {
+	at := time.Unix(1500000000, 0)
+	rmock := r.NewMock()
+	rmock.On(r.Table("apikeys").Get("key-1").Update(map[string]interface{}{"lastUsed": at})).Return(r.WriteResponse{Replaced: 1}, nil)
+	rmock.On(r.Table("apikeys").Get("key-1").Delete()).Return(r.WriteResponse{Deleted: 1}, nil)
+	ks := APIKeyService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+	key := &goparent.APIKey{ID: "key-1"}
+	assert.Nil(t, ks.Used(context.Background(), key, at))
+	assert.Equal(t, at, key.LastUsed)
+	assert.Nil(t, ks.Delete(context.Background(), key))
+	rmock.AssertExpectations(t)
+}
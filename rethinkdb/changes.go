@@ -0,0 +1,276 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"gopkg.in/gorethink/gorethink.v3"
+	"gopkg.in/gorethink/gorethink.v3/encoding"
+)
+
+//watchedTables - the tables with family data and the resource their changes are published as
+var watchedTables = map[string]string{
+	"family":   goparent.ResourceFamily,
+	"children": goparent.ResourceChild,
+	"feeding":  goparent.ResourceFeeding,
+	"sleep":    goparent.ResourceSleep,
+	"waste":    goparent.ResourceWaste,
+}
+
+//change - a document from a changefeed, old is nil for inserts and new is nil for deletes
+type change struct {
+	OldVal map[string]interface{} `gorethink:"old_val"`
+	NewVal map[string]interface{} `gorethink:"new_val"`
+}
+
+//WatchChanges - publish the changes to family data on the bus until the context is done.
+// changefeeds see writes from every instance of the service, so nothing in this package
+// publishes on its own.
+func WatchChanges(ctx context.Context, dbenv *DBEnv, bus goparent.EventBus) error {
+	err := dbenv.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(watchedTables))
+	for table, resource := range watchedTables {
+		go func(table string, resource string) {
+			errs <- watchTable(ctx, dbenv, bus, table, resource)
+		}(table, resource)
+	}
+
+	//the first feed to fail takes the rest down with it
+	for range watchedTables {
+		feedErr := <-errs
+		if feedErr != nil && err == nil {
+			err = feedErr
+			cancel()
+		}
+	}
+	return err
+}
+
+//ErrFeedNotStarted - the change feed hasn't been started yet
+var ErrFeedNotStarted = errors.New("the change feed hasn't started")
+
+//ErrFeedEnded - a change feed closed without an error, rethinkdb does that when it drops one
+var ErrFeedEnded = errors.New("the change feed ended")
+
+//changeFeedBackoff - the first wait before restarting a feed that failed, it doubles each
+// failure in a row up to changeFeedMaxBackoff
+const (
+	changeFeedBackoff    = time.Second
+	changeFeedMaxBackoff = time.Minute
+)
+
+//ChangeFeed - keeps WatchChanges running until the context is done, restarting it when it
+// fails, whether that's at startup or when the feed drops later.  it's the bus's
+// goparent.EventSource, Err is why the feed is down.
+type ChangeFeed struct {
+	DB  *DBEnv
+	Bus goparent.EventBus
+	//Backoff - the first wait before a restart, changeFeedBackoff if it isn't set
+	Backoff time.Duration
+
+	mu      sync.Mutex
+	err     error
+	started bool
+}
+
+//Run - watch the changes until ctx is done
+func (f *ChangeFeed) Run(ctx context.Context) {
+	wait := f.Backoff
+	if wait <= 0 {
+		wait = changeFeedBackoff
+	}
+	backoff := wait
+	for {
+		f.setErr(nil)
+		start := time.Now()
+		err := WatchChanges(ctx, f.DB, f.Bus)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = ErrFeedEnded
+		}
+		f.setErr(err)
+		//a feed that ran for a while dropped, it didn't fail to start, so start over
+		if time.Since(start) > changeFeedMaxBackoff {
+			backoff = wait
+		}
+		log.Printf("event stream stopped, restarting in %s: %s", backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > changeFeedMaxBackoff {
+			backoff = changeFeedMaxBackoff
+		}
+	}
+}
+
+//Err - why the feed is down, nil while it's running
+func (f *ChangeFeed) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.started {
+		return ErrFeedNotStarted
+	}
+	return f.err
+}
+
+func (f *ChangeFeed) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = true
+	f.err = err
+}
+
+func watchTable(ctx context.Context, dbenv *DBEnv, bus goparent.EventBus, table string, resource string) error {
+	res, err := gorethink.Table(table).Changes().Run(dbenv.Session)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		res.Close()
+	}()
+
+	children := &ChildService{DB: dbenv}
+	var c change
+	for res.Next(&c) {
+		event, err := changeEvent(resource, c.OldVal, c.NewVal)
+		if err != nil {
+			return err
+		}
+		if event.ChildID != "" && resource != goparent.ResourceChild {
+			event.SharedWith, err = childFamilies(ctx, children, event.ChildID)
+			if err != nil {
+				log.Printf("families for child %s: %s", event.ChildID, err)
+			}
+		}
+		bus.Publish(event)
+		c = change{}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return res.Err()
+}
+
+//changeEvent - turn the before and after of a document into an event.  sleeps that are
+// opened or closed get started and ended events instead of created and updated.
+func changeEvent(resource string, oldVal map[string]interface{}, newVal map[string]interface{}) (goparent.Event, error) {
+	event := goparent.Event{Resource: resource}
+	switch {
+	case oldVal == nil:
+		event.Type = goparent.EventCreated
+	case newVal == nil:
+		event.Type = goparent.EventDeleted
+	default:
+		event.Type = goparent.EventUpdated
+	}
+
+	val := newVal
+	if val == nil {
+		val = oldVal
+	}
+	var err error
+	event.Data, err = decodeRecord(resource, val)
+	if err != nil {
+		return event, err
+	}
+
+	switch data := event.Data.(type) {
+	case *goparent.Family:
+		event.FamilyID = data.ID
+	case *goparent.Child:
+		event.FamilyID = data.FamilyID
+		event.ChildID = data.ID
+		event.SharedWith = shareFamilies(data)
+		//a family the child was just unshared from still hears about it
+		if event.Type == goparent.EventUpdated {
+			var old goparent.Child
+			err = encoding.Decode(&old, oldVal)
+			if err != nil {
+				return event, err
+			}
+			event.SharedWith = append(event.SharedWith, shareFamilies(&old)...)
+		}
+	case *goparent.Feeding:
+		event.FamilyID = data.FamilyID
+		event.ChildID = data.ChildID
+	case *goparent.Waste:
+		event.FamilyID = data.FamilyID
+		event.ChildID = data.ChildID
+	case *goparent.Sleep:
+		event.FamilyID = data.FamilyID
+		event.ChildID = data.ChildID
+		if event.Type == goparent.EventCreated && data.End.IsZero() {
+			event.Type = goparent.EventStarted
+		}
+		if event.Type == goparent.EventUpdated && !data.End.IsZero() {
+			var old goparent.Sleep
+			err = encoding.Decode(&old, oldVal)
+			if err != nil {
+				return event, err
+			}
+			if old.End.IsZero() {
+				event.Type = goparent.EventEnded
+			}
+		}
+	}
+	return event, nil
+}
+
+//childFamilies - the families that can see a child's records, records for a shared child
+// can be logged by a family other than the one it belongs to
+func childFamilies(ctx context.Context, children *ChildService, childID string) ([]string, error) {
+	child, err := children.Child(ctx, childID)
+	if err != nil {
+		return nil, err
+	}
+	return child.Families(), nil
+}
+
+//shareFamilies - every family the child is shared with, pending shares included so the
+// family a child is shared into hears about it and can accept
+func shareFamilies(child *goparent.Child) []string {
+	var families []string
+	for _, share := range child.Shares {
+		families = append(families, share.FamilyID)
+	}
+	return families
+}
+
+func decodeRecord(resource string, val map[string]interface{}) (interface{}, error) {
+	var record interface{}
+	switch resource {
+	case goparent.ResourceFamily:
+		record = &goparent.Family{}
+	case goparent.ResourceChild:
+		record = &goparent.Child{}
+	case goparent.ResourceFeeding:
+		record = &goparent.Feeding{}
+	case goparent.ResourceSleep:
+		record = &goparent.Sleep{}
+	case goparent.ResourceWaste:
+		record = &goparent.Waste{}
+	default:
+		return val, nil
+	}
+	err := encoding.Decode(record, val)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
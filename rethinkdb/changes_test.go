@@ -0,0 +1,246 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestChangeEvent(t *testing.T) {
+	open := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2018, 1, 1, 1, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	sleep := func(end time.Time) map[string]interface{} {
+		return map[string]interface{}{"id": "1", "familyID": "1", "childID": "2", "start": start, "end": end}
+	}
+	testCases := []struct {
+		desc       string
+		resource   string
+		oldVal     map[string]interface{}
+		newVal     map[string]interface{}
+		eventType  goparent.EventType
+		familyID   string
+		childID    string
+		sharedWith []string
+	}{
+		{
+			desc:      "feeding created",
+			resource:  goparent.ResourceFeeding,
+			newVal:    map[string]interface{}{"id": "1", "familyID": "1", "childID": "2", "feedingType": "bottle"},
+			eventType: goparent.EventCreated,
+			familyID:  "1",
+			childID:   "2",
+		},
+		{
+			desc:      "waste updated",
+			resource:  goparent.ResourceWaste,
+			oldVal:    map[string]interface{}{"id": "1", "familyID": "1", "childID": "2", "wasteType": 1},
+			newVal:    map[string]interface{}{"id": "1", "familyID": "1", "childID": "2", "wasteType": 2},
+			eventType: goparent.EventUpdated,
+			familyID:  "1",
+			childID:   "2",
+		},
+		{
+			desc:      "child deleted",
+			resource:  goparent.ResourceChild,
+			oldVal:    map[string]interface{}{"id": "2", "familyID": "1", "name": "Billy"},
+			eventType: goparent.EventDeleted,
+			familyID:  "1",
+			childID:   "2",
+		},
+		{
+			desc:       "child unshared",
+			resource:   goparent.ResourceChild,
+			oldVal:     map[string]interface{}{"id": "2", "familyID": "1", "shares": []interface{}{map[string]interface{}{"familyID": "3"}, map[string]interface{}{"familyID": "4"}}},
+			newVal:     map[string]interface{}{"id": "2", "familyID": "1", "shares": []interface{}{map[string]interface{}{"familyID": "3"}}},
+			eventType:  goparent.EventUpdated,
+			familyID:   "1",
+			childID:    "2",
+			sharedWith: []string{"3", "3", "4"},
+		},
+		{
+			desc:      "family updated",
+			resource:  goparent.ResourceFamily,
+			oldVal:    map[string]interface{}{"id": "1", "units": "metric"},
+			newVal:    map[string]interface{}{"id": "1", "units": "imperial"},
+			eventType: goparent.EventUpdated,
+			familyID:  "1",
+		},
+		{
+			desc:      "sleep started",
+			resource:  goparent.ResourceSleep,
+			newVal:    sleep(open),
+			eventType: goparent.EventStarted,
+			familyID:  "1",
+			childID:   "2",
+		},
+		{
+			desc:      "finished sleep created",
+			resource:  goparent.ResourceSleep,
+			newVal:    sleep(end),
+			eventType: goparent.EventCreated,
+			familyID:  "1",
+			childID:   "2",
+		},
+		{
+			desc:      "sleep ended",
+			resource:  goparent.ResourceSleep,
+			oldVal:    sleep(open),
+			newVal:    sleep(end),
+			eventType: goparent.EventEnded,
+			familyID:  "1",
+			childID:   "2",
+		},
+		{
+			desc:      "finished sleep updated",
+			resource:  goparent.ResourceSleep,
+			oldVal:    sleep(end),
+			newVal:    sleep(end.Add(time.Minute)),
+			eventType: goparent.EventUpdated,
+			familyID:  "1",
+			childID:   "2",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			event, err := changeEvent(tC.resource, tC.oldVal, tC.newVal)
+			assert.Nil(t, err)
+			assert.Equal(t, tC.eventType, event.Type)
+			assert.Equal(t, tC.resource, event.Resource)
+			assert.Equal(t, tC.familyID, event.FamilyID)
+			assert.Equal(t, tC.childID, event.ChildID)
+			assert.Equal(t, tC.sharedWith, event.SharedWith)
+			assert.NotNil(t, event.Data)
+		})
+	}
+}
+
+func TestWatchChanges(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		feedErr     error
+		childError  error
+		events      []string
+		returnError error
+	}{
+		{
+			desc:   "publishes changes",
+			events: []string{"feeding.created", "sleep.started"},
+		},
+		{
+			desc:       "child lookup fails",
+			childError: errors.New("test error"),
+			events:     []string{"sleep.started"},
+		},
+		{
+			desc:        "feed error",
+			feedErr:     errors.New("test error"),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			//the feeding was logged by a household the child is shared with
+			mock.On(r.Table("children").Get("1")).Return(map[string]interface{}{
+				"id": "1", "familyID": "1", "shares": []interface{}{map[string]interface{}{"familyID": "3", "permission": "log"}},
+			}, tC.childError)
+			for table := range watchedTables {
+				var changes []interface{}
+				switch table {
+				case "feeding":
+					changes = []interface{}{map[string]interface{}{"new_val": map[string]interface{}{"id": "1", "familyID": "3", "childID": "1"}}}
+				case "sleep":
+					changes = []interface{}{map[string]interface{}{"new_val": map[string]interface{}{"id": "1", "familyID": "1", "childID": "1", "end": time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)}}}
+				case "waste":
+					if tC.feedErr != nil {
+						mock.On(r.Table(table).Changes()).Return(nil, tC.feedErr)
+						continue
+					}
+				}
+				mock.On(r.Table(table).Changes()).Return(changes, nil)
+			}
+
+			bus := goparent.NewEventBus()
+			events, cancel := bus.Subscribe("1")
+			defer cancel()
+
+			err := WatchChanges(context.Background(), &DBEnv{Session: mock}, bus)
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+				return
+			}
+			assert.Nil(t, err)
+			var names []string
+			for len(events) > 0 {
+				names = append(names, (<-events).Name())
+			}
+			assert.ElementsMatch(t, tC.events, names)
+		})
+	}
+}
+
+func TestChangeFeed(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		feedErr error
+		err     error
+	}{
+		{desc: "restarts a feed that ends", err: ErrFeedEnded},
+		{desc: "restarts a feed that fails", feedErr: errors.New("test error"), err: errors.New("test error")},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			for table := range watchedTables {
+				var changes []interface{}
+				if table == "feeding" {
+					changes = []interface{}{map[string]interface{}{"new_val": map[string]interface{}{"id": "1", "familyID": "1"}}}
+				}
+				if table == "waste" && tC.feedErr != nil {
+					mock.On(r.Table(table).Changes()).Return(nil, tC.feedErr)
+					continue
+				}
+				mock.On(r.Table(table).Changes()).Return(changes, nil)
+			}
+			bus := goparent.NewEventBus()
+			events, unsubscribe := bus.Subscribe("1")
+			defer unsubscribe()
+
+			feed := &ChangeFeed{DB: &DBEnv{Session: mock}, Bus: bus, Backoff: time.Millisecond}
+			assert.Equal(t, ErrFeedNotStarted, feed.Err())
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				feed.Run(ctx)
+				close(done)
+			}()
+
+			assert.Eventually(t, func() bool {
+				err := feed.Err()
+				return err != nil && err.Error() == tC.err.Error()
+			}, time.Second, time.Millisecond)
+			if tC.feedErr == nil {
+				//the feed was started again and published the change again
+				for i := 0; i < 2; i++ {
+					select {
+					case <-events:
+					case <-time.After(time.Second):
+						t.Fatal("the feed wasn't restarted")
+					}
+				}
+			}
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("the feed didn't stop with its context")
+			}
+		})
+	}
+}
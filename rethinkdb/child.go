@@ -46,6 +46,9 @@ func (cs *ChildService) Child(ctx context.Context, id string) (*goparent.Child,
 	defer res.Close()
 	var child goparent.Child
 	err = res.One(&child)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoChildFound
+	}
 	if err != nil {
 		return nil, err
 	}
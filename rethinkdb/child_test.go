@@ -106,6 +106,15 @@ func TestChild(t *testing.T) {
 			),
 			returnError: errors.New("test error"),
 		},
+		{
+			desc: "get child not found",
+			env:  &goparent.Env{},
+			id:   "child-2",
+			query: (&r.Mock{}).On(
+				r.Table("children").Get("child-2"),
+			).Return(nil, nil),
+			returnError: goparent.ErrNoChildFound,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -58,14 +58,15 @@ func (fs *FamilyService) Family(ctx context.Context, id string) (*goparent.Famil
 	return &family, nil
 }
 
-//Children - returns all the children for a family
+//Children - returns all the children for a family, including children other families
+// have shared with it
 func (fs *FamilyService) Children(ctx context.Context, family *goparent.Family) ([]*goparent.Child, error) {
 	err := fs.DB.GetConnection()
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := gorethink.Table("children").Filter(map[string]interface{}{"familyID": family.ID}).OrderBy(gorethink.Desc("birthday")).Run(fs.DB.Session)
+	res, err := gorethink.Table("children").Filter(visibleTo(family)).OrderBy(gorethink.Desc("birthday")).Run(fs.DB.Session)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +100,98 @@ func (fs *FamilyService) AddMember(ctx context.Context, family *goparent.Family,
 	return nil
 }
 
+//RemoveMember - take a member out of the family.  the invites they sent to the family are
+// withdrawn, whatever their status, so none can be resent, and if it was their current
+// family they are moved to another of theirs.
+func (fs *FamilyService) RemoveMember(ctx context.Context, family *goparent.Family, member *goparent.User) error {
+	err := family.RemoveMember(member.ID)
+	if err != nil {
+		return err
+	}
+	err = fs.Save(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("invites").Filter(map[string]interface{}{
+		"familyID": family.ID,
+		"userID":   member.ID,
+	}).Delete().RunWrite(fs.DB.Session)
+	if err != nil {
+		return err
+	}
+
+	if member.CurrentFamily != family.ID {
+		return nil
+	}
+	return fs.moveFrom(ctx, member, family.ID)
+}
+
+//familyTables - the tables with records that belong to a family, by familyID
+var familyTables = []string{"children", "feeding", "sleep", "waste", "invites", "webhooks", "webhook_deliveries", "reminders", "notifications"}
+
+//Delete - delete the family and everything recorded for it.  members whose current family
+// it was are moved to another of theirs.
+func (fs *FamilyService) Delete(ctx context.Context, family *goparent.Family) error {
+	err := fs.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("family").Get(family.ID).Delete().RunWrite(fs.DB.Session)
+	if err != nil {
+		return err
+	}
+	for _, table := range familyTables {
+		_, err = gorethink.Table(table).Filter(map[string]interface{}{"familyID": family.ID}).Delete().RunWrite(fs.DB.Session)
+		if err != nil {
+			return err
+		}
+	}
+
+	//children other families shared with this one are taken back out of it
+	_, err = gorethink.Table("children").Filter(sharedWith(family)).Update(func(child gorethink.Term) interface{} {
+		return map[string]interface{}{
+			"shares": child.Field("shares").Filter(func(share gorethink.Term) gorethink.Term {
+				return share.Field("familyID").Ne(family.ID)
+			}),
+		}
+	}).RunWrite(fs.DB.Session)
+	if err != nil {
+		return err
+	}
+
+	res, err := gorethink.Table("users").Filter(map[string]interface{}{"currentFamily": family.ID}).Run(fs.DB.Session)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	var users []*goparent.User
+	err = res.All(&users)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		err = fs.moveFrom(ctx, user, family.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//moveFrom - switch the user to another family they belong to.  users with no other
+// family are saved without one, and saving gives them a family of their own.
+func (fs *FamilyService) moveFrom(ctx context.Context, user *goparent.User, familyID string) error {
+	us := UserService{Env: fs.Env, DB: fs.DB}
+	families, err := us.GetAllFamily(ctx, user)
+	if err != nil {
+		return err
+	}
+	user.CurrentFamily = goparent.NextFamily(user, families, familyID)
+	return us.Save(ctx, user)
+}
+
 //GetAdminFamily - returns the family for which the user is the admin.
 func (fs *FamilyService) GetAdminFamily(ctx context.Context, user *goparent.User) (*goparent.Family, error) {
 	err := fs.DB.GetConnection()
@@ -121,3 +214,31 @@ func (fs *FamilyService) GetAdminFamily(ctx context.Context, user *goparent.User
 
 	return &family, nil
 }
+
+//sharedWith - filters children to the ones another family has shared with the family
+func sharedWith(family *goparent.Family) func(gorethink.Term) gorethink.Term {
+	return func(child gorethink.Term) gorethink.Term {
+		return child.Field("shares").Default([]interface{}{}).Contains(func(share gorethink.Term) gorethink.Term {
+			return share.Field("familyID").Eq(family.ID)
+		})
+	}
+}
+
+//visibleTo - filters children to the ones the family can see, its own and the shared ones
+// it accepted
+func visibleTo(family *goparent.Family) func(gorethink.Term) gorethink.Term {
+	return func(child gorethink.Term) gorethink.Term {
+		return child.Field("familyID").Eq(family.ID).Or(child.Field("shares").Default([]interface{}{}).Contains(func(share gorethink.Term) gorethink.Term {
+			return share.Field("familyID").Eq(family.ID).And(share.Field("pending").Default(false).Not())
+		}))
+	}
+}
+
+//familyRecords - filters feedings, sleeps and wastes to the ones the family sees: the
+// ones it logged and the ones other households logged for children it can see.
+func familyRecords(family *goparent.Family) func(gorethink.Term) gorethink.Term {
+	children := gorethink.Table("children").Filter(visibleTo(family)).Field("id").CoerceTo("array")
+	return func(record gorethink.Term) gorethink.Term {
+		return record.Field("familyID").Eq(family.ID).Or(children.Contains(record.Field("childID")))
+	}
+}
@@ -182,10 +182,7 @@ func TestChildren(t *testing.T) {
 				LastUpdated: timestamp,
 			},
 			query: (&r.Mock{}).On(
-				r.Table("children").Filter(
-					map[string]interface{}{
-						"familyID": "family-1",
-					}).OrderBy(r.Desc("birthday")),
+				r.Table("children").Filter(visibleTo(&goparent.Family{ID: "family-1"})).OrderBy(r.Desc("birthday")),
 			).Return([]map[string]interface{}{
 				{
 					"id":       "child-1",
@@ -215,10 +212,7 @@ func TestChildren(t *testing.T) {
 				LastUpdated: timestamp,
 			},
 			query: (&r.Mock{}).On(
-				r.Table("children").Filter(
-					map[string]interface{}{
-						"familyID": "family-1",
-					}).OrderBy(r.Desc("birthday")),
+				r.Table("children").Filter(visibleTo(&goparent.Family{ID: "family-1"})).OrderBy(r.Desc("birthday")),
 			).Return(nil, errors.New("test error")),
 			returnError: errors.New("test error"),
 		},
@@ -393,3 +387,153 @@ func TestGetAdminFamily(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveMember(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		family        *goparent.Family
+		member        *goparent.User
+		queries       []*r.MockQuery
+		currentFamily string
+		returnError   error
+	}{
+		{
+			desc:   "remove member",
+			family: &goparent.Family{ID: "family-1", Admin: "user-1", Members: []string{"user-1", "user-2"}},
+			member: &goparent.User{ID: "user-2", CurrentFamily: "family-2"},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+				//every invite they sent, not only the pending ones
+				(&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
+					"familyID": "family-1",
+					"userID":   "user-2",
+				}).Delete()).Once().Return(r.WriteResponse{Deleted: 2}, nil),
+			},
+			currentFamily: "family-2",
+		},
+		{
+			desc:   "moved to their other family",
+			family: &goparent.Family{ID: "family-1", Admin: "user-1", Members: []string{"user-1", "user-2"}},
+			member: &goparent.User{ID: "user-2", Email: "user2@test.com", CurrentFamily: "family-1"},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+				(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(r.WriteResponse{Deleted: 0}, nil),
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return([]interface{}{
+					map[string]interface{}{"id": "family-2", "admin": "user-2", "members": []interface{}{"user-2"}},
+				}, nil),
+				(&r.Mock{}).On(r.Table("users").MockAnything()).Once().Return([]interface{}{
+					map[string]interface{}{"id": "user-2", "email": "user2@test.com"},
+				}, nil),
+				(&r.Mock{}).On(r.Table("users").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+			},
+			currentFamily: "family-2",
+		},
+		{
+			desc:          "admin",
+			family:        &goparent.Family{ID: "family-1", Admin: "user-1", Members: []string{"user-1", "user-2"}},
+			member:        &goparent.User{ID: "user-1", CurrentFamily: "family-1"},
+			currentFamily: "family-1",
+			returnError:   goparent.ErrFamilyAdmin,
+		},
+		{
+			desc:   "invites error",
+			family: &goparent.Family{ID: "family-1", Admin: "user-1", Members: []string{"user-1", "user-2"}},
+			member: &goparent.User{ID: "user-2", CurrentFamily: "family-1"},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+				(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(nil, errors.New("test error")),
+			},
+			currentFamily: "family-1",
+			returnError:   errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.queries...)
+			fs := FamilyService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			err := fs.RemoveMember(ctx, tC.family, tC.member)
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.False(t, tC.family.IsMember(tC.member.ID))
+			}
+			assert.Equal(t, tC.currentFamily, tC.member.CurrentFamily)
+		})
+	}
+}
+
+func TestRemovedMemberResend(t *testing.T) {
+	family := &goparent.Family{ID: "family-1", Admin: "user-1", Members: []string{"user-1", "user-2"}}
+	member := &goparent.User{ID: "user-2", CurrentFamily: "family-2"}
+	rmock := r.NewMock()
+	rmock.On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil)
+	rmock.On(r.Table("invites").MockAnything()).Once().Return(r.WriteResponse{Deleted: 1}, nil)
+	fs := FamilyService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+	err := fs.RemoveMember(context.Background(), family, member)
+	assert.Nil(t, err)
+
+	//an expired invite they sent before they were removed
+	rmock.On(r.Table("family").Get("family-1")).Return(map[string]interface{}{"id": family.ID, "admin": family.Admin, "members": family.Members}, nil)
+	mailer := &mock.Mailer{}
+	uis := UserInviteService{Env: &goparent.Env{DB: &mock.DBEnv{}, Mailer: mailer}, DB: &DBEnv{Session: rmock}}
+	invite := &goparent.UserInvitation{ID: "invite-1", UserID: "user-2", FamilyID: "family-1", InviteEmail: "invitedUser@test.com", Status: goparent.InvitationPending, TokenHash: "old", ExpiresAt: time.Now().Add(-time.Hour)}
+	err = uis.Resend(context.Background(), member, invite)
+	assert.Equal(t, goparent.ErrNotFamilyMember, err)
+	assert.Equal(t, "old", invite.TokenHash)
+	assert.Len(t, mailer.Sent, 0)
+	rmock.AssertExpectations(t)
+}
+
+func TestDeleteFamily(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		queries     []*r.MockQuery
+		returnError error
+	}{
+		{
+			desc: "delete family",
+			queries: func() []*r.MockQuery {
+				queries := []*r.MockQuery{
+					(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Deleted: 1}, nil),
+				}
+				for _, table := range familyTables {
+					queries = append(queries, (&r.Mock{}).On(r.Table(table).MockAnything()).Once().Return(r.WriteResponse{Deleted: 1}, nil))
+				}
+				queries = append(queries, (&r.Mock{}).On(r.Table("children").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil))
+				return append(queries, (&r.Mock{}).On(r.Table("users").MockAnything()).Once().Return([]interface{}{}, nil))
+			}(),
+		},
+		{
+			desc: "delete error",
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(nil, errors.New("test error")),
+			},
+			returnError: errors.New("test error"),
+		},
+		{
+			desc: "records error",
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Deleted: 1}, nil),
+				(&r.Mock{}).On(r.Table("children").MockAnything()).Once().Return(nil, errors.New("test error")),
+			},
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.queries...)
+			fs := FamilyService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			err := fs.Delete(ctx, &goparent.Family{ID: "family-1", Admin: "user-1", Members: []string{"user-1"}})
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package rethinkdb
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/sasimpson/goparent"
@@ -15,13 +14,6 @@ type FeedingService struct {
 	DB  *DBEnv
 }
 
-type feedingReductionData struct {
-	Group     []interface{} `gorethink:"group"`
-	Reduction []struct {
-		FeedingAmount float32 `gorethink:"feedingAmount"`
-	} `gorethink:"reduction"`
-}
-
 //Save - save the structure to the datastore
 func (fs *FeedingService) Save(ctx context.Context, feeding *goparent.Feeding) error {
 	err := fs.DB.GetConnection()
@@ -29,6 +21,9 @@ func (fs *FeedingService) Save(ctx context.Context, feeding *goparent.Feeding) e
 		return err
 	}
 
+	//always store the unit with the amount
+	feeding.Unit = feeding.AmountUnit()
+
 	res, err := gorethink.Table("feeding").Insert(feeding, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(fs.DB.Session)
 	if err != nil {
 		return err
@@ -50,9 +45,7 @@ func (fs *FeedingService) Feeding(ctx context.Context, family *goparent.Family,
 	daysBack := int(0 - days)
 
 	res, err := gorethink.Table("feeding").
-		Filter(map[string]interface{}{
-			"familyID": family.ID,
-		}).
+		Filter(familyRecords(family)).
 		Filter(gorethink.Row.Field("timestamp").During(time.Now().AddDate(0, 0, daysBack), time.Now())).
 		OrderBy(gorethink.Desc("timestamp")).
 		Run(fs.DB.Session)
@@ -70,110 +63,49 @@ func (fs *FeedingService) Feeding(ctx context.Context, family *goparent.Family,
 	return rows, nil
 }
 
-//Stats - get feeding stats for one child for the last 24 hours.
-func (fs *FeedingService) Stats(ctx context.Context, child *goparent.Child) (*goparent.FeedingSummary, error) {
-	err := fs.DB.GetConnection()
-	if err != nil {
-		return nil, err
-	}
-
-	end := time.Now()
-	start := end.AddDate(0, 0, -1)
-
-	res, err := gorethink.Table("feeding").
-		Filter(map[string]interface{}{
-			"childID": child.ID,
-		}).
-		Filter(
-			gorethink.Row.Field("timestamp").During(start, end),
-		).
-		OrderBy(gorethink.Desc("timestamp")).
-		Run(fs.DB.Session)
+//Stats - get feeding stats for one child for the query window.
+func (fs *FeedingService) Stats(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.FeedingSummary, error) {
+	rows, err := fs.childFeedings(child, query)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Close()
+	return goparent.NewFeedingSummary(rows), nil
+}
 
-	var rows []goparent.Feeding
-	err = res.All(&rows)
+//GraphData - get feeding data for one child grouped into the query's buckets.
+func (fs *FeedingService) GraphData(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.FeedingChartData, error) {
+	rows, err := fs.childFeedings(child, query)
 	if err != nil {
 		return nil, err
 	}
-	//build summary output
-	summary := &goparent.FeedingSummary{
-		Data:  rows,
-		Total: make(map[string]float32),
-		Mean:  make(map[string]float32),
-		Range: make(map[string]int),
-	}
-
-	for _, x := range rows {
-		if _, ok := summary.Total[x.Type]; !ok {
-			summary.Total[x.Type] = 0.0
-		}
-		summary.Total[x.Type] += x.Amount
-		summary.Range[x.Type]++
-	}
-	for k := range summary.Total {
-		summary.Mean[k] = summary.Total[k] / float32(summary.Range[k])
-	}
-	return summary, nil
+	return goparent.NewFeedingChartData(query, rows), nil
 }
 
-//GraphData -
-func (fs *FeedingService) GraphData(ctx context.Context, child *goparent.Child) (*goparent.FeedingChartData, error) {
+//childFeedings - all the feedings for a child inside of the query window, newest first.
+func (fs *FeedingService) childFeedings(child *goparent.Child, query *goparent.DataQuery) ([]goparent.Feeding, error) {
 	err := fs.DB.GetConnection()
 	if err != nil {
 		return nil, err
 	}
 
-	end := time.Now()
-	start := end.AddDate(0, 0, -7)
-	/*
-		r.db("goparent")
-		.table("feeding")
-		.filter(r.row("timestamp")
-			.during(r.time(2018,7,13,"Z"),r.now()))
-		.group([r.row("timestamp").year(), r.row("timestamp").month(), r.row("timestamp").day(), r.row("feedingType")])
-		.pluck( "feedingAmount")
-	*/
 	res, err := gorethink.Table("feeding").
-		Filter(gorethink.Row.Field("timestamp").During(start, end)).OrderBy("timestamp").
-		Group(
-			gorethink.Row.Field("timestamp").Year(),
-			gorethink.Row.Field("timestamp").Month(),
-			gorethink.Row.Field("timestamp").Day(),
-			gorethink.Row.Field("feedingType"),
+		Filter(map[string]interface{}{
+			"childID": child.ID,
+		}).
+		Filter(
+			gorethink.Row.Field("timestamp").During(query.Start, query.End),
 		).
-		Pluck("feedingAmount").
+		OrderBy(gorethink.Desc("timestamp")).
 		Run(fs.DB.Session)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Close()
 
-	var data []feedingReductionData
-	err = res.All(&data)
+	var rows []goparent.Feeding
+	err = res.All(&rows)
 	if err != nil {
 		return nil, err
 	}
-
-	chartData := &goparent.FeedingChartData{Start: start, End: end}
-	// graph.Data = goparent.ChartData{Datasets: []goparent.ChartDataset{}}
-	for _, line := range data {
-		gdDate, err := time.Parse("2006-01-02", fmt.Sprintf("%.0f-%02.0f-%02.0f", line.Group[0], line.Group[1], line.Group[2]))
-		if err != nil {
-			return nil, err
-		}
-
-		var rC int
-		var rS float32
-		for _, reduction := range line.Reduction {
-			rC++
-			rS += reduction.FeedingAmount
-		}
-		dataset := goparent.FeedingChartDataset{Date: gdDate, Type: line.Group[3].(string), Count: rC, Sum: rS}
-		chartData.Dataset = append(chartData.Dataset, dataset)
-	}
-	return chartData, nil
+	return rows, nil
 }
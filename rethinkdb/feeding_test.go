@@ -107,6 +107,7 @@ func TestFeedingSave(t *testing.T) {
 						"timestamp":     timestamp.Add(time.Hour),
 						"feedingType":   "bottle",
 						"feedingAmount": 3.5,
+						"feedingUnit":   "floz",
 						"createdAt":     timestamp.Add(time.Hour),
 						"lastUpdated":   timestamp.Add(time.Hour),
 					}, r.InsertOpts{Conflict: "replace"},
@@ -142,6 +143,7 @@ func TestFeedingSave(t *testing.T) {
 						"timestamp":     timestamp.Add(time.Hour),
 						"feedingType":   "bottle",
 						"feedingAmount": 3.5,
+						"feedingUnit":   "floz",
 						"createdAt":     timestamp.Add(time.Hour),
 						"lastUpdated":   timestamp.Add(time.Hour),
 					}, r.InsertOpts{Conflict: "replace"},
@@ -173,8 +175,122 @@ func TestFeedingSave(t *testing.T) {
 			} else {
 				assert.Nil(t, err)
 				assert.Equal(t, tC.id, tC.data.ID)
+				assert.Equal(t, goparent.UnitFluidOunce, tC.data.Unit)
 			}
 
 		})
 	}
 }
+
+func TestFeedingStats(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		env         *goparent.Env
+		query       *r.MockQuery
+		child       *goparent.Child
+		total       map[string]float32
+		returnError error
+	}{
+		{
+			desc: "get stats",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("feeding").MockAnything(),
+			).Return([]map[string]interface{}{
+				{"id": "1", "feedingType": "bottle", "feedingAmount": 3.5},
+				{"id": "2", "feedingType": "bottle", "feedingAmount": 4.5},
+				{"id": "3", "feedingType": "breast", "feedingAmount": 10},
+			}, nil),
+			child: &goparent.Child{ID: "1"},
+			total: map[string]float32{"bottle": 8, "breast": 10},
+		},
+		{
+			desc: "get stats error",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("feeding").MockAnything(),
+			).Return(nil, errors.New("test error")),
+			child:       &goparent.Child{ID: "1"},
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			fs := FeedingService{Env: tC.env, DB: &DBEnv{Session: mock}}
+			summary, err := fs.Stats(ctx, tC.child, goparent.NewStatsQuery(time.Now(), time.UTC))
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tC.total, summary.Total)
+			}
+		})
+	}
+}
+
+func TestFeedingGraphData(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		env         *goparent.Env
+		query       *r.MockQuery
+		child       *goparent.Child
+		dataQuery   *goparent.DataQuery
+		datasets    []goparent.FeedingChartDataset
+		returnError error
+	}{
+		{
+			desc: "get graph data by hour",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("feeding").MockAnything(),
+			).Return([]map[string]interface{}{
+				{"id": "1", "feedingType": "bottle", "feedingAmount": 3, "timestamp": time.Date(2018, 1, 1, 1, 45, 0, 0, time.UTC)},
+				{"id": "2", "feedingType": "bottle", "feedingAmount": 2, "timestamp": time.Date(2018, 1, 1, 1, 15, 0, 0, time.UTC)},
+				{"id": "3", "feedingType": "bottle", "feedingAmount": 4, "timestamp": time.Date(2018, 1, 1, 0, 30, 0, 0, time.UTC)},
+			}, nil),
+			child: &goparent.Child{ID: "1"},
+			dataQuery: &goparent.DataQuery{
+				Start:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC),
+				Granularity: goparent.GranularityHour,
+			},
+			datasets: []goparent.FeedingChartDataset{
+				{Date: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Type: "bottle", Count: 1, Sum: 4},
+				{Date: time.Date(2018, 1, 1, 1, 0, 0, 0, time.UTC), Type: "bottle", Count: 2, Sum: 5},
+			},
+		},
+		{
+			desc: "get graph data error",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("feeding").MockAnything(),
+			).Return(nil, errors.New("test error")),
+			child:       &goparent.Child{ID: "1"},
+			dataQuery:   goparent.NewGraphQuery(time.Now(), time.UTC),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			fs := FeedingService{Env: tC.env, DB: &DBEnv{Session: mock}}
+			chartData, err := fs.GraphData(ctx, tC.child, tC.dataQuery)
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, len(tC.datasets), len(chartData.Dataset))
+				for i := range tC.datasets {
+					assert.True(t, tC.datasets[i].Date.Equal(chartData.Dataset[i].Date))
+					assert.Equal(t, tC.datasets[i].Count, chartData.Dataset[i].Count)
+					assert.Equal(t, tC.datasets[i].Sum, chartData.Dataset[i].Sum)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package rethinkdb
+
+import (
+	"context"
+
+	"github.com/sasimpson/goparent"
+	gorethink "gopkg.in/gorethink/gorethink.v3"
+)
+
+//IdentityService - service for implementing the interface
+type IdentityService struct {
+	Env *goparent.Env
+	DB  *DBEnv
+}
+
+//Save - link an identity, or update a linked one.  identities are stored under their
+// IdentityID, so linking one that's already linked to another user fails instead of
+// replacing it.
+func (is *IdentityService) Save(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	err := is.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	if identity.ID == "" {
+		identity.ID = goparent.IdentityID(identity.Issuer, identity.Subject)
+		//the insert only errors if there's already an identity with the id
+		res, err := gorethink.Table("identities").Insert(identity).RunWrite(is.DB.Session)
+		if res.Errors == 0 {
+			return err
+		}
+		existing, err := is.Identity(ctx, identity.ID)
+		if err != nil {
+			return err
+		}
+		if existing.UserID != identity.UserID {
+			identity.ID = ""
+			return goparent.ErrIdentityLinked
+		}
+	}
+
+	_, err = gorethink.Table("identities").Insert(identity, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(is.DB.Session)
+	return err
+}
+
+//Identity - return a linked identity for an id
+func (is *IdentityService) Identity(ctx context.Context, id string) (*goparent.ExternalIdentity, error) {
+	err := is.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("identities").Get(id).Run(is.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var identity goparent.ExternalIdentity
+	err = res.One(&identity)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoIdentityFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+//IdentityBySubject - the identity linked for a provider's issuer and subject
+func (is *IdentityService) IdentityBySubject(ctx context.Context, issuer string, subject string) (*goparent.ExternalIdentity, error) {
+	return is.Identity(ctx, goparent.IdentityID(issuer, subject))
+}
+
+//Identities - all of the identities linked to a user, oldest first
+func (is *IdentityService) Identities(ctx context.Context, user *goparent.User) ([]*goparent.ExternalIdentity, error) {
+	err := is.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("identities").
+		Filter(map[string]interface{}{
+			"userID": user.ID,
+		}).
+		OrderBy("createdAt").
+		Run(is.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows []*goparent.ExternalIdentity
+	err = res.All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//Delete - unlink an identity
+func (is *IdentityService) Delete(ctx context.Context, identity *goparent.ExternalIdentity) error {
+	err := is.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("identities").Get(identity.ID).Delete().RunWrite(is.DB.Session)
+	return err
+}
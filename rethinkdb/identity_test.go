@@ -0,0 +1,73 @@
+package rethinkdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestIdentitySave(t *testing.T) {
+	id := goparent.IdentityID("https://idp.test", "sub-1")
+	testCases := []struct {
+		desc        string
+		insert      r.WriteResponse
+		existing    []interface{}
+		replaced    bool
+		returnError error
+	}{
+		{
+			desc:   "new identity",
+			insert: r.WriteResponse{Inserted: 1},
+		},
+		{
+			desc:     "already linked to the user",
+			insert:   r.WriteResponse{Errors: 1},
+			existing: []interface{}{map[string]interface{}{"id": id, "userID": "user-1"}},
+			replaced: true,
+		},
+		{
+			desc:        "linked to someone else",
+			insert:      r.WriteResponse{Errors: 1},
+			existing:    []interface{}{map[string]interface{}{"id": id, "userID": "user-2"}},
+			returnError: goparent.ErrIdentityLinked,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			identity := &goparent.ExternalIdentity{UserID: "user-1", Issuer: "https://idp.test", Subject: "sub-1"}
+			stored := *identity
+			stored.ID = id
+			rmock := r.NewMock()
+			rmock.On(r.Table("identities").Insert(stored)).Once().Return(tC.insert, nil)
+			rmock.On(r.Table("identities").Get(id)).Return(tC.existing, nil)
+			replace := rmock.On(r.Table("identities").Insert(stored, r.InsertOpts{Conflict: "replace"})).Return(r.WriteResponse{Replaced: 1}, nil)
+			is := IdentityService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+			err := is.Save(context.Background(), identity)
+			assert.Equal(t, tC.returnError, err)
+			if tC.returnError == nil {
+				assert.Equal(t, id, identity.ID)
+			}
+			if !tC.replaced {
+				rmock.AssertNotExecuted(t, replace)
+			}
+		})
+	}
+}
+
+func TestIdentityBySubject(t *testing.T) {
+	id := goparent.IdentityID("https://idp.test/", "sub-1")
+	rmock := r.NewMock()
+	rmock.On(r.Table("identities").Get(id)).Return([]interface{}{map[string]interface{}{"id": id, "userID": "user-1"}}, nil)
+	rmock.On(r.Table("identities").Get("nope")).Return([]interface{}{}, nil)
+	is := IdentityService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+	identity, err := is.IdentityBySubject(context.Background(), "https://idp.test", "sub-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "user-1", identity.UserID)
+	_, err = is.Identity(context.Background(), "nope")
+	assert.Equal(t, goparent.ErrNoIdentityFound, err)
+}
@@ -3,51 +3,82 @@ package rethinkdb
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/sasimpson/goparent"
 	gorethink "gopkg.in/gorethink/gorethink.v3"
 )
 
-//ErrExistingInvitation - the user already has an invitation
-const ErrExistingInvitation string = "existing invitation for that user"
-
 //UserInviteService - struct for implementing the interface
 type UserInviteService struct {
 	Env *goparent.Env
 	DB  *DBEnv
 }
 
-//InviteParent - add an invitation for another parent to join in on user's data.
-func (uis *UserInviteService) InviteParent(ctx context.Context, user *goparent.User, inviteEmail string, timestamp time.Time) error {
+//InviteParent - invite another parent to join the user's current family.  the invite is
+// given a token and expiry and mailed to the invitee.
+func (uis *UserInviteService) InviteParent(ctx context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
 	err := uis.DB.GetConnection()
 	if err != nil {
 		return err
 	}
 
+	us := UserService{Env: uis.Env, DB: uis.DB}
+	family, err := us.GetFamily(ctx, user)
+	if err != nil {
+		return err
+	}
+
 	res, err := gorethink.Table("invites").Filter(map[string]interface{}{
-		"inviteEmail": inviteEmail,
+		"inviteEmail": invite.InviteEmail,
+		"familyID":    family.ID,
 	}).Run(uis.DB.Session)
 	if err != nil {
 		return err
 	}
 	defer res.Close()
 
-	//if there is already an invite for that user, return error.
-	if !res.IsNil() {
-		return errors.New(ErrExistingInvitation)
+	//if there is already an open invite to the family for that email, return error.
+	var existing []*goparent.UserInvitation
+	err = res.All(&existing)
+	if err != nil {
+		return err
+	}
+	for _, e := range existing {
+		if e != nil && e.CurrentStatus(invite.Timestamp) == goparent.InvitationPending {
+			return goparent.ErrExistingInvitation
+		}
 	}
 
-	inviteUser := goparent.UserInvitation{
-		UserID:      user.ID,
-		InviteEmail: inviteEmail,
-		Timestamp:   timestamp,
+	invite.ID = ""
+	invite.UserID = user.ID
+	invite.FamilyID = family.ID
+	token, err := invite.Issue(invite.Timestamp)
+	if err != nil {
+		return err
 	}
-	_, err = gorethink.Table("invites").Insert(inviteUser).Run(uis.DB.Session)
+	err = uis.save(invite)
 	if err != nil {
 		return err
 	}
 
+	err = uis.Env.MailInvitation(ctx, user, invite, token)
+	if err != nil {
+		log.Printf("error sending mail: %s", err)
+	}
+	return nil
+}
+
+//save - insert or replace the invite
+func (uis *UserInviteService) save(invite *goparent.UserInvitation) error {
+	res, err := gorethink.Table("invites").Insert(invite, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(uis.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted > 0 && len(res.GeneratedKeys) > 0 {
+		invite.ID = res.GeneratedKeys[0]
+	}
 	return nil
 }
 
@@ -93,6 +124,36 @@ func (uis *UserInviteService) Invite(ctx context.Context, id string) (*goparent.
 
 	var invite goparent.UserInvitation
 	err = res.One(&invite)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoInvitationFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+//InviteByToken - return the invite a token was mailed for
+func (uis *UserInviteService) InviteByToken(ctx context.Context, token string) (*goparent.UserInvitation, error) {
+	err := uis.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("invites").Filter(map[string]interface{}{
+		"tokenHash": goparent.HashInviteToken(token),
+	}).Run(uis.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var invite goparent.UserInvitation
+	err = res.One(&invite)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoInvitationFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -127,58 +188,110 @@ func (uis *UserInviteService) Invites(ctx context.Context, user *goparent.User)
 	return rows, nil
 }
 
-//Accept - user can accept an invite, this will set their
-// CurrentFamily and add them as a member to that family.
+//Accept - user can accept an invite sent to their email, this will add them as a member
+// to the family with the invite's role and make it their CurrentFamily.
 func (uis *UserInviteService) Accept(ctx context.Context, user *goparent.User, id string) error {
-	err := uis.DB.GetConnection()
+	invite, err := uis.Invite(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = invite.Answerable(user, now)
 	if err != nil {
 		return err
 	}
 
-	//get invite by id and invited user (current):
-	res, err := gorethink.Table("invites").
-		Filter(map[string]interface{}{
-			"id":          id,
-			"inviteEmail": user.Email,
-		}).
-		OrderBy(gorethink.Desc("timestamp")).
-		Run(uis.DB.Session)
+	us := UserService{Env: uis.Env, DB: uis.DB}
+	fs := FamilyService{Env: uis.Env, DB: uis.DB}
+	family, err := uis.inviteFamily(ctx, invite)
 	if err != nil {
 		return err
 	}
-	defer res.Close()
 
-	var invite goparent.UserInvitation
-	err = res.One(&invite)
+	family.SetRole(user.ID, invite.Role)
+	if family.IsMember(user.ID) {
+		err = fs.Save(ctx, family)
+	} else {
+		err = fs.AddMember(ctx, family, user)
+	}
 	if err != nil {
 		return err
 	}
 
-	//get the user and family that is doing the inviting
+	user.CurrentFamily = family.ID
+	err = us.Save(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	invite.Answer(goparent.InvitationAccepted, now)
+	return uis.save(invite)
+}
+
+//inviteFamily - the family the invite is to
+func (uis *UserInviteService) inviteFamily(ctx context.Context, invite *goparent.UserInvitation) (*goparent.Family, error) {
 	us := UserService{Env: uis.Env, DB: uis.DB}
+	fs := FamilyService{Env: uis.Env, DB: uis.DB}
+	if invite.FamilyID != "" {
+		return fs.Family(ctx, invite.FamilyID)
+	}
+	//invites from before they had a family are for the inviting user's family
 	invitingUser, err := us.User(ctx, invite.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return us.GetFamily(ctx, invitingUser)
+}
+
+//Decline - user can turn down an invite sent to their email
+func (uis *UserInviteService) Decline(ctx context.Context, user *goparent.User, id string) error {
+	invite, err := uis.Invite(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = invite.Answerable(user, now)
 	if err != nil {
 		return err
 	}
 
-	//NOTE: this would need to be set in the invite if we allowed family switching
-	family, err := us.GetFamily(ctx, invitingUser)
+	invite.Answer(goparent.InvitationDeclined, now)
+	return uis.save(invite)
+}
+
+//Resend - mail a pending or expired invite again with a new token and expiry
+func (uis *UserInviteService) Resend(ctx context.Context, user *goparent.User, invite *goparent.UserInvitation) error {
+	err := uis.DB.GetConnection()
 	if err != nil {
 		return err
 	}
-	fs := FamilyService{Env: uis.Env, DB: uis.DB}
-	//add the user to the family of the inviting user
-	err = fs.AddMember(ctx, family, user)
+	now := time.Now()
+	status := invite.CurrentStatus(now)
+	if status != goparent.InvitationPending && status != goparent.InvitationExpired {
+		return goparent.ErrInvitationAnswered
+	}
+	//someone who has left or been removed can't keep inviting people to the family
+	family, err := uis.inviteFamily(ctx, invite)
 	if err != nil {
 		return err
 	}
+	if !family.IsMember(user.ID) {
+		return goparent.ErrNotFamilyMember
+	}
 
-	//remove invite from system
-	err = uis.Delete(ctx, &invite)
+	token, err := invite.Issue(now)
+	if err != nil {
+		return err
+	}
+	err = uis.save(invite)
 	if err != nil {
 		return err
 	}
 
+	err = uis.Env.MailInvitation(ctx, user, invite, token)
+	if err != nil {
+		log.Printf("error sending mail: %s", err)
+	}
 	return nil
 }
 
@@ -3,6 +3,7 @@ package rethinkdb
 import (
 	"context"
 	"errors"
+	"regexp"
 	"testing"
 	"time"
 
@@ -14,89 +15,100 @@ import (
 
 func TestInviteParent(t *testing.T) {
 	timestamp := time.Now()
+	family := map[string]interface{}{"id": "family-1", "admin": "1", "members": []string{"1"}}
 	testCases := []struct {
 		desc        string
 		env         *goparent.Env
-		query1      *r.MockQuery
-		query2      *r.MockQuery
-		inviteEmail string
+		queries     []*r.MockQuery
 		user        *goparent.User
 		returnError error
 	}{
 		{
 			desc: "invite parent",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
-			query1: (&r.Mock{}).On(
-				r.Table("invites").Filter(
-					map[string]interface{}{
-						"inviteEmail": "invitedUser@test.com",
-					}),
-			).Return(nil, nil),
-			query2: (&r.Mock{}).
-				On(
-					r.Table("invites").Insert(
-						map[string]interface{}{
-							"userID":      "1",
-							"inviteEmail": "invitedUser@test.com",
-							"timestamp":   timestamp,
-						}),
-				).Return(nil, nil),
-			inviteEmail: "invitedUser@test.com",
-			user:        &goparent.User{ID: "1"},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Return(family, nil),
+				(&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
+					"inviteEmail": "invitedUser@test.com",
+					"familyID":    "family-1",
+				})).Return(nil, nil),
+				(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(
+					r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"invite-1"}}, nil),
+			},
+			user: &goparent.User{ID: "1", CurrentFamily: "family-1"},
 		},
 		{
 			desc: "invite parent existing invite",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
-			query1: (&r.Mock{}).On(
-				r.Table("invites").Filter(
-					map[string]interface{}{
-						"inviteEmail": "invitedUser@test.com",
-					}),
-			).Return(map[string]interface{}{
-				"id":          "1",
-				"userID":      "1",
-				"inviteEmail": "invitedUser@test.com",
-				"timestamp":   timestamp,
-			}, nil),
-			query2:      nil,
-			inviteEmail: "invitedUser@test.com",
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Return(family, nil),
+				(&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
+					"inviteEmail": "invitedUser@test.com",
+					"familyID":    "family-1",
+				})).Return(map[string]interface{}{
+					"id":          "1",
+					"userID":      "1",
+					"inviteEmail": "invitedUser@test.com",
+					"status":      "pending",
+					"timestamp":   timestamp,
+					"expiresAt":   timestamp.Add(time.Hour),
+				}, nil),
+			},
+			user:        &goparent.User{ID: "1", CurrentFamily: "family-1"},
+			returnError: goparent.ErrExistingInvitation,
+		},
+		{
+			desc: "invite parent expired invite",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Return(family, nil),
+				(&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
+					"inviteEmail": "invitedUser@test.com",
+					"familyID":    "family-1",
+				})).Return(map[string]interface{}{
+					"id":          "1",
+					"userID":      "1",
+					"inviteEmail": "invitedUser@test.com",
+					"status":      "pending",
+					"timestamp":   timestamp.Add(-8 * 24 * time.Hour),
+					"expiresAt":   timestamp.Add(-24 * time.Hour),
+				}, nil),
+				(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(
+					r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"invite-2"}}, nil),
+			},
+			user: &goparent.User{ID: "1", CurrentFamily: "family-1"},
+		},
+		{
+			desc:        "invite parent no family",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
 			user:        &goparent.User{ID: "1"},
-			returnError: errors.New(ErrExistingInvitation),
+			returnError: errors.New("user has no current family"),
 		},
 		{
 			desc: "invite parent check error",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
-			query1: (&r.Mock{}).On(
-				r.Table("invites").Filter(
-					map[string]interface{}{
-						"inviteEmail": "invitedUser@test.com",
-					}),
-			).Return(nil, errors.New("test error")),
-			query2:      nil,
-			inviteEmail: "invitedUser@test.com",
-			user:        &goparent.User{ID: "1"},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Return(family, nil),
+				(&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
+					"inviteEmail": "invitedUser@test.com",
+					"familyID":    "family-1",
+				})).Return(nil, errors.New("test error")),
+			},
+			user:        &goparent.User{ID: "1", CurrentFamily: "family-1"},
 			returnError: errors.New("test error"),
 		},
 		{
 			desc: "invite parent insert error",
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
-			query1: (&r.Mock{}).On(
-				r.Table("invites").Filter(
-					map[string]interface{}{
-						"inviteEmail": "invitedUser@test.com",
-					}),
-			).Return(nil, nil),
-			query2: (&r.Mock{}).
-				On(
-					r.Table("invites").Insert(
-						map[string]interface{}{
-							"userID":      "1",
-							"inviteEmail": "invitedUser@test.com",
-							"timestamp":   timestamp,
-						}),
-				).Return(nil, errors.New("test error")),
-			inviteEmail: "invitedUser@test.com",
-			user:        &goparent.User{ID: "1"},
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Return(family, nil),
+				(&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
+					"inviteEmail": "invitedUser@test.com",
+					"familyID":    "family-1",
+				})).Return(nil, nil),
+				(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(nil, errors.New("test error")),
+			},
+			user:        &goparent.User{ID: "1", CurrentFamily: "family-1"},
 			returnError: errors.New("test error"),
 		},
 	}
@@ -104,22 +116,48 @@ func TestInviteParent(t *testing.T) {
 		t.Run(tC.desc, func(t *testing.T) {
 			ctx := context.Background()
 			mock := r.NewMock()
-			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query1)
-			if tC.query2 != nil {
-				mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query2)
-			}
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.queries...)
 			uis := UserInviteService{Env: tC.env, DB: &DBEnv{Session: mock}}
-			err := uis.InviteParent(ctx, tC.user, tC.inviteEmail, timestamp)
+			invite := &goparent.UserInvitation{InviteEmail: "invitedUser@test.com", Timestamp: timestamp}
+			err := uis.InviteParent(ctx, tC.user, invite)
 			if tC.returnError != nil {
 				assert.EqualError(t, tC.returnError, err.Error())
 			} else {
 				assert.Nil(t, err)
+				assert.NotEmpty(t, invite.ID)
+				assert.Equal(t, "family-1", invite.FamilyID)
+				assert.Equal(t, goparent.InvitationPending, invite.Status)
+				assert.Equal(t, goparent.RoleMember, invite.Role)
+				assert.Equal(t, timestamp.Add(goparent.InvitationLifetime), invite.ExpiresAt)
+				assert.NotEmpty(t, invite.TokenHash)
 			}
 			mock.AssertExpectations(t)
 		})
 	}
 }
 
+func TestInviteParentMail(t *testing.T) {
+	timestamp := time.Now()
+	mailer := &mock.Mailer{}
+	rmock := r.NewMock()
+	rmock.On(r.Table("family").Get("family-1")).Return(map[string]interface{}{"id": "family-1"}, nil)
+	rmock.On(r.Table("invites").Filter(map[string]interface{}{"inviteEmail": "invitedUser@test.com", "familyID": "family-1"})).Return(nil, nil)
+	rmock.On(r.Table("invites").MockAnything()).Return(r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"invite-1"}}, nil)
+	uis := UserInviteService{Env: &goparent.Env{DB: &mock.DBEnv{}, Mailer: mailer}, DB: &DBEnv{Session: rmock}}
+
+	invite := &goparent.UserInvitation{InviteEmail: "invitedUser@test.com", Timestamp: timestamp}
+	err := uis.InviteParent(context.Background(), &goparent.User{ID: "1", Name: "Pat", CurrentFamily: "family-1"}, invite)
+	assert.Nil(t, err)
+	if assert.Len(t, mailer.Sent, 1) {
+		assert.Equal(t, []string{"invitedUser@test.com"}, mailer.Sent[0].To)
+		assert.Equal(t, "Pat invited you to GoParent", mailer.Sent[0].Subject)
+		//the mail carries the token, only its hash is saved
+		token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(mailer.Sent[0].Text)
+		assert.Equal(t, invite.TokenHash, goparent.HashInviteToken(token))
+		assert.Contains(t, mailer.Sent[0].Text, "The invitation expires")
+	}
+}
+
 func TestSentInvites(t *testing.T) {
 	testCases := []struct {
 		desc         string
@@ -212,7 +250,7 @@ func TestInvite(t *testing.T) {
 				r.Table("invites").Get("1"),
 			).Return(nil, nil),
 			id:          "1",
-			returnError: r.ErrEmptyResult,
+			returnError: goparent.ErrNoInvitationFound,
 		},
 		{
 			desc: "invite returned",
@@ -340,81 +378,172 @@ func TestInvites(t *testing.T) {
 }
 
 func TestAcceptInvite(t *testing.T) {
-	//TODO: add error conditions
 	timestamp := time.Now()
+	invite := func(status string, expires time.Time) *r.MockQuery {
+		return (&r.Mock{}).On(r.Table("invites").Get("invite-1")).Once().Return(map[string]interface{}{
+			"id":          "invite-1",
+			"inviteEmail": "invitedUser@test.com",
+			"userID":      "user-1",
+			"familyID":    "family-1",
+			"role":        "caregiver",
+			"status":      status,
+			"timestamp":   timestamp,
+			"expiresAt":   expires,
+		}, nil)
+	}
 	testCases := []struct {
-		desc           string
-		env            *goparent.Env
-		id             string
-		invitedUser    *goparent.User
-		inviteQuery    *r.MockQuery
-		returnError    error
-		userQuery      *r.MockQuery
-		familyQuery    *r.MockQuery
-		addMemberQuery *r.MockQuery
-		deleteQuery    *r.MockQuery
+		desc        string
+		env         *goparent.Env
+		invitedUser *goparent.User
+		queries     []*r.MockQuery
+		returnError error
 	}{
 		{
-			desc: "invite accepted, no errors",
-			env:  &goparent.Env{DB: &mock.DBEnv{}},
-			id:   "invite-1",
-			invitedUser: &goparent.User{
-				ID: "user-2", Email: "invitedUser@test.com",
-			},
-			inviteQuery: (&r.Mock{}).On(
-				r.Table("invites").Filter(
-					map[string]interface{}{
-						"id":          "invite-1",
-						"inviteEmail": "invitedUser@test.com",
-					},
-				).OrderBy(r.Desc("timestamp")),
-			).Return(map[string]interface{}{
-				"id":          "invite-1",
-				"inviteEmail": "invitedUser@test.com",
-				"userID":      "user-1",
-				"timestamp":   time.Now(),
-			}, nil),
-			userQuery: (&r.Mock{}).On(r.Table("users").MockAnything()).Once().Return(
-				map[string]interface{}{
-					"id":            "user-1",
-					"currentFamily": "family-1",
-				}, nil),
-			familyQuery: (&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(
-				map[string]interface{}{
-					"id":           "family-1",
-					"created_at":   timestamp,
-					"last_updated": timestamp,
-				}, nil),
-			addMemberQuery: (&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(
-				r.WriteResponse{
-					Updated: 1,
-					Errors:  0,
-				}, nil),
-			deleteQuery: (&r.Mock{}).On(r.Table("invites").Filter(map[string]interface{}{
-				"id": "invite-1",
-			}).Delete()).Return(
-				r.WriteResponse{
-					Deleted: 1,
-					Errors:  0,
+			desc:        "invite accepted, no errors",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
+			invitedUser: &goparent.User{ID: "user-2", Email: "InvitedUser@test.com", CurrentFamily: "family-2"},
+			queries: []*r.MockQuery{
+				invite("pending", timestamp.Add(time.Hour)),
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Once().Return(map[string]interface{}{
+					"id":      "family-1",
+					"admin":   "user-1",
+					"members": []string{"user-1"},
 				}, nil),
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+				(&r.Mock{}).On(r.Table("users").MockAnything()).Once().Return(map[string]interface{}{"id": "user-2"}, nil),
+				(&r.Mock{}).On(r.Table("users").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+				(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+			},
+		},
+		{
+			desc:        "invite for someone else",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
+			invitedUser: &goparent.User{ID: "user-3", Email: "someoneElse@test.com"},
+			queries:     []*r.MockQuery{invite("pending", timestamp.Add(time.Hour))},
+			returnError: goparent.ErrNoInvitationFound,
+		},
+		{
+			desc:        "invite expired",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
+			invitedUser: &goparent.User{ID: "user-2", Email: "invitedUser@test.com"},
+			queries:     []*r.MockQuery{invite("pending", timestamp.Add(-time.Hour))},
+			returnError: goparent.ErrInvitationExpired,
+		},
+		{
+			desc:        "invite already accepted",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
+			invitedUser: &goparent.User{ID: "user-2", Email: "invitedUser@test.com"},
+			queries:     []*r.MockQuery{invite("accepted", timestamp.Add(time.Hour))},
+			returnError: goparent.ErrInvitationAnswered,
+		},
+		{
+			desc:        "family error",
+			env:         &goparent.Env{DB: &mock.DBEnv{}},
+			invitedUser: &goparent.User{ID: "user-2", Email: "invitedUser@test.com"},
+			queries: []*r.MockQuery{
+				invite("pending", timestamp.Add(time.Hour)),
+				(&r.Mock{}).On(r.Table("family").Get("family-1")).Once().Return(nil, errors.New("test error")),
+			},
+			returnError: errors.New("test error"),
 		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			ctx := context.Background()
 			mock := r.NewMock()
-			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.inviteQuery)
-			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.userQuery)
-			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.familyQuery)
-			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.addMemberQuery)
-			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.deleteQuery)
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.queries...)
 
 			uis := UserInviteService{Env: tC.env, DB: &DBEnv{Session: mock}}
-			err := uis.Accept(ctx, tC.invitedUser, tC.id)
+			err := uis.Accept(ctx, tC.invitedUser, "invite-1")
+			if tC.returnError != nil {
+				assert.EqualError(t, tC.returnError, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, "family-1", tC.invitedUser.CurrentFamily)
+			}
+			mock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeclineInvite(t *testing.T) {
+	timestamp := time.Now()
+	rmock := r.NewMock()
+	rmock.On(r.Table("invites").Get("invite-1")).Return(map[string]interface{}{
+		"id":          "invite-1",
+		"inviteEmail": "invitedUser@test.com",
+		"userID":      "user-1",
+		"status":      "pending",
+		"tokenHash":   "hash",
+		"timestamp":   timestamp,
+		"expiresAt":   timestamp.Add(time.Hour),
+	}, nil)
+	rmock.On(r.Table("invites").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil)
+	uis := UserInviteService{Env: &goparent.Env{DB: &mock.DBEnv{}}, DB: &DBEnv{Session: rmock}}
+
+	err := uis.Decline(context.Background(), &goparent.User{ID: "user-3", Email: "someoneElse@test.com"}, "invite-1")
+	assert.Equal(t, goparent.ErrNoInvitationFound, err)
+	err = uis.Decline(context.Background(), &goparent.User{ID: "user-2", Email: "invitedUser@test.com"}, "invite-1")
+	assert.Nil(t, err)
+	rmock.AssertExpectations(t)
+}
+
+func TestResendInvite(t *testing.T) {
+	timestamp := time.Now()
+	testCases := []struct {
+		desc        string
+		invite      *goparent.UserInvitation
+		family      map[string]interface{}
+		saveErr     error
+		returnError error
+	}{
+		{
+			desc:   "resend expired invite",
+			invite: &goparent.UserInvitation{ID: "invite-1", FamilyID: "family-1", InviteEmail: "invitedUser@test.com", Status: goparent.InvitationPending, TokenHash: "old", Timestamp: timestamp.Add(-8 * 24 * time.Hour), ExpiresAt: timestamp.Add(-24 * time.Hour)},
+			family: map[string]interface{}{"id": "family-1", "admin": "user-1", "members": []string{"user-1"}},
+		},
+		{
+			desc:        "resend accepted invite",
+			invite:      &goparent.UserInvitation{ID: "invite-1", FamilyID: "family-1", InviteEmail: "invitedUser@test.com", Status: goparent.InvitationAccepted, Timestamp: timestamp},
+			returnError: goparent.ErrInvitationAnswered,
+		},
+		{
+			desc:        "resend after leaving the family",
+			invite:      &goparent.UserInvitation{ID: "invite-1", FamilyID: "family-1", InviteEmail: "invitedUser@test.com", Status: goparent.InvitationPending, Timestamp: timestamp.Add(-8 * 24 * time.Hour), ExpiresAt: timestamp.Add(-24 * time.Hour)},
+			family:      map[string]interface{}{"id": "family-1", "admin": "user-2", "members": []string{"user-2"}},
+			returnError: goparent.ErrNotFamilyMember,
+		},
+		{
+			desc:        "save error",
+			invite:      &goparent.UserInvitation{ID: "invite-1", FamilyID: "family-1", InviteEmail: "invitedUser@test.com", Status: goparent.InvitationPending, Timestamp: timestamp},
+			family:      map[string]interface{}{"id": "family-1", "admin": "user-1", "members": []string{"user-1"}},
+			saveErr:     errors.New("test error"),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mailer := &mock.Mailer{}
+			rmock := r.NewMock()
+			if tC.family != nil {
+				rmock.On(r.Table("family").Get("family-1")).Return(tC.family, nil)
+			}
+			if tC.saveErr != nil {
+				rmock.On(r.Table("invites").MockAnything()).Return(nil, tC.saveErr)
+			} else {
+				rmock.On(r.Table("invites").MockAnything()).Return(r.WriteResponse{Replaced: 1}, nil)
+			}
+			uis := UserInviteService{Env: &goparent.Env{DB: &mock.DBEnv{}, Mailer: mailer}, DB: &DBEnv{Session: rmock}}
+
+			err := uis.Resend(context.Background(), &goparent.User{ID: "user-1", Name: "Pat"}, tC.invite)
 			if tC.returnError != nil {
 				assert.EqualError(t, tC.returnError, err.Error())
+				assert.Len(t, mailer.Sent, 0)
 			} else {
 				assert.Nil(t, err)
+				assert.NotEqual(t, "old", tC.invite.TokenHash)
+				assert.Equal(t, goparent.InvitationPending, tC.invite.CurrentStatus(time.Now()))
+				assert.Len(t, mailer.Sent, 1)
 			}
 		})
 	}
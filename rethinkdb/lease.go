@@ -0,0 +1,52 @@
+package rethinkdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"gopkg.in/gorethink/gorethink.v3"
+)
+
+//LeaseStore - leases kept in the leases table so every instance of the service sees them
+type LeaseStore struct {
+	DB *DBEnv
+}
+
+//Hold - take or renew the lease.  who gets it is decided in the insert's conflict function
+// so two instances can't both take it.
+func (ls *LeaseStore) Hold(ctx context.Context, name string, holder string, now time.Time, ttl time.Duration) (goparent.Lease, error) {
+	err := ls.DB.GetConnection()
+	if err != nil {
+		return goparent.Lease{}, err
+	}
+
+	lease := goparent.Lease{Name: name, Holder: holder, Expires: now.Add(ttl)}
+	res, err := gorethink.Table("leases").Insert(lease, gorethink.InsertOpts{
+		ReturnChanges: "always",
+		Conflict: func(id, oldDoc, newDoc gorethink.Term) interface{} {
+			return gorethink.Branch(
+				oldDoc.Field("holder").Eq(holder).Or(oldDoc.Field("expires").Le(now)),
+				newDoc,
+				oldDoc,
+			)
+		},
+	}).Run(ls.DB.Session)
+	if err != nil {
+		return goparent.Lease{}, err
+	}
+	defer res.Close()
+	var written struct {
+		Changes []struct {
+			NewValue goparent.Lease `gorethink:"new_val"`
+		} `gorethink:"changes"`
+	}
+	err = res.One(&written)
+	if err != nil {
+		return goparent.Lease{}, err
+	}
+	if len(written.Changes) == 0 {
+		return lease, nil
+	}
+	return written.Changes[0].NewValue, nil
+}
@@ -0,0 +1,39 @@
+package rethinkdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestLeaseHold(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	testCases := []struct {
+		desc   string
+		holder string
+		held   bool
+	}{
+		{desc: "taken", holder: "instance-1", held: true},
+		{desc: "held by another instance", holder: "instance-2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rmock := r.NewMock()
+			rmock.On(r.Table("leases").MockAnything()).Return(map[string]interface{}{
+				"unchanged": 1,
+				"changes": []interface{}{map[string]interface{}{
+					"new_val": map[string]interface{}{"id": "webhooks", "holder": "instance-1", "expires": now.Add(time.Minute)},
+				}},
+			}, nil)
+			ls := LeaseStore{DB: &DBEnv{Session: rmock}}
+
+			lease, err := ls.Hold(context.Background(), "webhooks", tC.holder, now, time.Minute)
+			assert.Nil(t, err)
+			assert.Equal(t, "webhooks", lease.Name)
+			assert.Equal(t, tC.held, lease.HeldBy(tC.holder, now))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package rethinkdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"gopkg.in/gorethink/gorethink.v3"
+)
+
+//RateLimitStore - rate limit counts kept in the ratelimits table so every instance of the
+// service shares them
+type RateLimitStore struct {
+	DB *DBEnv
+}
+
+//Hit - count a hit against the key.  the count is updated in the insert's conflict
+// function so concurrent hits don't lose each other.
+func (rs *RateLimitStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+
+	count := goparent.RateCount{Key: key, Count: 1, Start: now, Last: now}
+	res, err := gorethink.Table("ratelimits").Insert(count, gorethink.InsertOpts{
+		ReturnChanges: "always",
+		Conflict: func(id, oldDoc, newDoc gorethink.Term) interface{} {
+			return gorethink.Branch(
+				oldDoc.Field("start").Le(now.Add(-window)),
+				newDoc,
+				oldDoc.Merge(map[string]interface{}{"count": oldDoc.Field("count").Add(1), "last": now}),
+			)
+		},
+	}).Run(rs.DB.Session)
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+	defer res.Close()
+	var written struct {
+		Changes []struct {
+			NewValue goparent.RateCount `gorethink:"new_val"`
+		} `gorethink:"changes"`
+	}
+	err = res.One(&written)
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+	if len(written.Changes) == 0 {
+		return count, nil
+	}
+	return written.Changes[0].NewValue, nil
+}
+
+//Count - the key's count in its current window
+func (rs *RateLimitStore) Count(ctx context.Context, key string, now time.Time, window time.Duration) (goparent.RateCount, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+
+	res, err := gorethink.Table("ratelimits").Get(key).Run(rs.DB.Session)
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+	defer res.Close()
+	var count goparent.RateCount
+	err = res.One(&count)
+	if err == gorethink.ErrEmptyResult || (err == nil && !now.Before(count.Start.Add(window))) {
+		return goparent.RateCount{Key: key}, nil
+	}
+	if err != nil {
+		return goparent.RateCount{}, err
+	}
+	return count, nil
+}
+
+//Reset - forget the key's count
+func (rs *RateLimitStore) Reset(ctx context.Context, key string) error {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("ratelimits").Get(key).Delete().RunWrite(rs.DB.Session)
+	return err
+}
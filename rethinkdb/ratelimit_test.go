@@ -0,0 +1,72 @@
+package rethinkdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestRateLimitHit(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	rmock := r.NewMock()
+	rmock.On(r.Table("ratelimits").MockAnything()).Return(map[string]interface{}{
+		"replaced": 1,
+		"changes": []interface{}{map[string]interface{}{
+			"new_val": map[string]interface{}{"id": "login:1", "count": 3, "start": now.Add(-time.Minute), "last": now},
+		}},
+	}, nil)
+	rs := RateLimitStore{DB: &DBEnv{Session: rmock}}
+
+	count, err := rs.Hit(context.Background(), "login:1", now, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count.Count)
+	assert.Equal(t, "login:1", count.Key)
+	assert.True(t, now.Add(-time.Minute).Equal(count.Start))
+}
+
+func TestRateLimitCount(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	testCases := []struct {
+		desc  string
+		saved interface{}
+		count int
+	}{
+		{
+			desc:  "in the window",
+			saved: map[string]interface{}{"id": "login:1", "count": 3, "start": now.Add(-time.Minute), "last": now},
+			count: 3,
+		},
+		{
+			desc:  "window over",
+			saved: map[string]interface{}{"id": "login:1", "count": 3, "start": now.Add(-2 * time.Hour), "last": now.Add(-2 * time.Hour)},
+		},
+		{
+			desc:  "no hits",
+			saved: nil,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rmock := r.NewMock()
+			rmock.On(r.Table("ratelimits").Get("login:1")).Return(tC.saved, nil)
+			rs := RateLimitStore{DB: &DBEnv{Session: rmock}}
+
+			count, err := rs.Count(context.Background(), "login:1", now, time.Hour)
+			assert.Nil(t, err)
+			assert.Equal(t, tC.count, count.Count)
+		})
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	rmock := r.NewMock()
+	rmock.On(r.Table("ratelimits").Get("login:1").Delete()).Return(r.WriteResponse{Deleted: 1}, nil)
+	var rs goparent.RateLimitStore = &RateLimitStore{DB: &DBEnv{Session: rmock}}
+
+	assert.Nil(t, rs.Reset(context.Background(), "login:1"))
+	rmock.AssertExpectations(t)
+}
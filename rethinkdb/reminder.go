@@ -0,0 +1,250 @@
+package rethinkdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	gorethink "gopkg.in/gorethink/gorethink.v3"
+)
+
+//ReminderService - service for implementing the interface
+type ReminderService struct {
+	Env *goparent.Env
+	DB  *DBEnv
+}
+
+//SaveRule - create or update a reminder rule
+func (rs *ReminderService) SaveRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	rule.LastUpdated = time.Now()
+	if rule.ID == "" {
+		rule.CreatedAt = rule.LastUpdated
+	}
+	res, err := gorethink.Table("reminders").Insert(rule, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(rs.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted > 0 && len(res.GeneratedKeys) > 0 {
+		rule.ID = res.GeneratedKeys[0]
+	}
+	return nil
+}
+
+//Rule - return a reminder rule for an id
+func (rs *ReminderService) Rule(ctx context.Context, id string) (*goparent.ReminderRule, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("reminders").Get(id).Run(rs.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var rule goparent.ReminderRule
+	err = res.One(&rule)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoReminderFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+//Rules - all of a family's reminder rules, oldest first
+func (rs *ReminderService) Rules(ctx context.Context, family *goparent.Family) ([]*goparent.ReminderRule, error) {
+	return rs.rules(gorethink.Table("reminders").
+		Filter(map[string]interface{}{
+			"familyID": family.ID,
+		}).
+		OrderBy("createdAt"))
+}
+
+//ActiveRules - every family's rules that aren't disabled, for the scheduler
+func (rs *ReminderService) ActiveRules(ctx context.Context) ([]*goparent.ReminderRule, error) {
+	return rs.rules(gorethink.Table("reminders").
+		Filter(map[string]interface{}{
+			"disabled": false,
+		}))
+}
+
+func (rs *ReminderService) rules(query gorethink.Term) ([]*goparent.ReminderRule, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := query.Run(rs.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows []*goparent.ReminderRule
+	err = res.All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//DeleteRule - delete a reminder rule, its notifications are kept
+func (rs *ReminderService) DeleteRule(ctx context.Context, rule *goparent.ReminderRule) error {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("reminders").Get(rule.ID).Delete().RunWrite(rs.DB.Session)
+	return err
+}
+
+//SaveNotification - create or update a notification
+func (rs *ReminderService) SaveNotification(ctx context.Context, n *goparent.Notification) error {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	res, err := gorethink.Table("notifications").Insert(n, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(rs.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted > 0 && len(res.GeneratedKeys) > 0 {
+		n.ID = res.GeneratedKeys[0]
+	}
+	return nil
+}
+
+//CreateNotification - insert a notification, ErrNotificationExists if its id is taken.  the
+// existing one is left as it is.
+func (rs *ReminderService) CreateNotification(ctx context.Context, n *goparent.Notification) error {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	res, err := gorethink.Table("notifications").Insert(n, gorethink.InsertOpts{
+		Conflict: func(id, oldDoc, newDoc gorethink.Term) interface{} {
+			return oldDoc
+		},
+	}).RunWrite(rs.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted == 0 {
+		return goparent.ErrNotificationExists
+	}
+	if len(res.GeneratedKeys) > 0 {
+		n.ID = res.GeneratedKeys[0]
+	}
+	return nil
+}
+
+//ClaimNotification - mark the notification delivered if it's still due, in one update so
+// only one scheduler claims it.  false if it isn't due anymore.
+func (rs *ReminderService) ClaimNotification(ctx context.Context, n *goparent.Notification, now time.Time) (bool, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := gorethink.Table("notifications").Get(n.ID).Update(func(doc gorethink.Term) interface{} {
+		return gorethink.Branch(
+			doc.Field("status").Eq(goparent.NotificationPending).Or(
+				doc.Field("status").Eq(goparent.NotificationSnoozed).And(doc.Field("snoozedUntil").Le(now)),
+			),
+			map[string]interface{}{"status": goparent.NotificationDelivered, "deliveredAt": now},
+			map[string]interface{}{},
+		)
+	}).RunWrite(rs.DB.Session)
+	if err != nil {
+		return false, err
+	}
+	if res.Replaced == 0 {
+		return false, nil
+	}
+	n.Status = goparent.NotificationDelivered
+	n.DeliveredAt = now
+	return true, nil
+}
+
+//Notification - return a notification for an id
+func (rs *ReminderService) Notification(ctx context.Context, id string) (*goparent.Notification, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("notifications").Get(id).Run(rs.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var n goparent.Notification
+	err = res.One(&n)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoNotificationFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+//Notifications - a family's inbox, newest first.  acknowledged notifications are left out
+// unless all is set.
+func (rs *ReminderService) Notifications(ctx context.Context, family *goparent.Family, all bool) ([]*goparent.Notification, error) {
+	query := gorethink.Table("notifications").Filter(map[string]interface{}{"familyID": family.ID})
+	if !all {
+		query = query.Filter(gorethink.Row.Field("status").Ne(goparent.NotificationAcknowledged))
+	}
+	return rs.notifications(query.OrderBy(gorethink.Desc("dueAt")))
+}
+
+//LatestNotification - the rule's most recent notification, nil if it hasn't had one
+func (rs *ReminderService) LatestNotification(ctx context.Context, rule *goparent.ReminderRule) (*goparent.Notification, error) {
+	rows, err := rs.notifications(gorethink.Table("notifications").
+		Filter(map[string]interface{}{"ruleID": rule.ID}).
+		OrderBy(gorethink.Desc("dueAt")).
+		Limit(1))
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+//DueNotifications - notifications waiting to be delivered, new ones and ones whose snooze is over
+func (rs *ReminderService) DueNotifications(ctx context.Context, now time.Time) ([]*goparent.Notification, error) {
+	return rs.notifications(gorethink.Table("notifications").
+		Filter(gorethink.Row.Field("status").Eq(goparent.NotificationPending).Or(
+			gorethink.Row.Field("status").Eq(goparent.NotificationSnoozed).And(gorethink.Row.Field("snoozedUntil").Le(now)),
+		)))
+}
+
+func (rs *ReminderService) notifications(query gorethink.Term) ([]*goparent.Notification, error) {
+	err := rs.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := query.Run(rs.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows []*goparent.Notification
+	err = res.All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
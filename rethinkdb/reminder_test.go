@@ -0,0 +1,252 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestReminderSaveRule(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		rule        *goparent.ReminderRule
+		query       *r.MockQuery
+		id          string
+		returnError error
+	}{
+		{
+			desc: "save new rule",
+			rule: &goparent.ReminderRule{FamilyID: "1", ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 180},
+			query: (&r.Mock{}).On(r.Table("reminders").MockAnything()).Once().Return(
+				r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"1"}}, nil),
+			id: "1",
+		},
+		{
+			desc: "update rule",
+			rule: &goparent.ReminderRule{ID: "2", FamilyID: "1", ChildID: "1", Kind: goparent.ReminderFeedingDue, Interval: 180},
+			query: (&r.Mock{}).On(r.Table("reminders").MockAnything()).Once().Return(
+				r.WriteResponse{Replaced: 1}, nil),
+			id: "2",
+		},
+		{
+			desc: "error saving rule",
+			rule: &goparent.ReminderRule{FamilyID: "1"},
+			query: (&r.Mock{}).On(r.Table("reminders").MockAnything()).Once().Return(
+				r.WriteResponse{Errors: 1}, errors.New("test error")),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			rs := ReminderService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			err := rs.SaveRule(context.Background(), tC.rule)
+			if tC.returnError != nil {
+				assert.Equal(t, tC.returnError, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tC.id, tC.rule.ID)
+			assert.False(t, tC.rule.LastUpdated.IsZero())
+		})
+	}
+}
+
+func TestReminderRule(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		query       *r.MockQuery
+		returnError error
+	}{
+		{
+			desc: "get rule",
+			query: (&r.Mock{}).On(r.Table("reminders").Get("1")).Return(
+				map[string]interface{}{"id": "1", "familyID": "1", "childID": "1", "kind": "feeding_due", "intervalMinutes": 180}, nil),
+		},
+		{
+			desc:        "no rule",
+			query:       (&r.Mock{}).On(r.Table("reminders").Get("1")).Return(nil, nil),
+			returnError: goparent.ErrNoReminderFound,
+		},
+		{
+			desc:        "error getting rule",
+			query:       (&r.Mock{}).On(r.Table("reminders").Get("1")).Return(nil, errors.New("test error")),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			rs := ReminderService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			rule, err := rs.Rule(context.Background(), "1")
+			if tC.returnError != nil {
+				assert.Equal(t, tC.returnError, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, 3*time.Hour, rule.Duration())
+		})
+	}
+}
+
+func TestReminderRules(t *testing.T) {
+	mock := r.NewMock()
+	mock.On(r.Table("reminders").Filter(map[string]interface{}{"familyID": "1"}).OrderBy("createdAt")).Return(
+		[]interface{}{
+			map[string]interface{}{"id": "1", "familyID": "1"},
+			map[string]interface{}{"id": "2", "familyID": "1", "disabled": true},
+		}, nil)
+	mock.On(r.Table("reminders").Filter(map[string]interface{}{"disabled": false})).Return(
+		[]interface{}{
+			map[string]interface{}{"id": "1", "familyID": "1"},
+		}, nil)
+	mock.On(r.Table("reminders").Get("2").Delete()).Return(r.WriteResponse{Deleted: 1}, nil)
+	rs := ReminderService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+	ctx := context.Background()
+
+	rules, err := rs.Rules(ctx, &goparent.Family{ID: "1"})
+	assert.Nil(t, err)
+	assert.Len(t, rules, 2)
+
+	rules, err = rs.ActiveRules(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, rules, 1)
+
+	err = rs.DeleteRule(ctx, &goparent.ReminderRule{ID: "2"})
+	assert.Nil(t, err)
+}
+
+func TestReminderNotifications(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock := r.NewMock()
+	mock.On(r.Table("notifications").MockAnything()).Once().Return(
+		r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"10"}}, nil)
+	mock.On(r.Table("notifications").Get("10")).Return(
+		map[string]interface{}{"id": "10", "familyID": "1", "status": "pending"}, nil)
+	mock.On(r.Table("notifications").Get("11")).Return(nil, nil)
+	mock.On(r.Table("notifications").
+		Filter(map[string]interface{}{"familyID": "1"}).
+		Filter(r.Row.Field("status").Ne(goparent.NotificationAcknowledged)).
+		OrderBy(r.Desc("dueAt"))).Return(
+		[]interface{}{
+			map[string]interface{}{"id": "10", "familyID": "1", "status": "pending"},
+		}, nil)
+	mock.On(r.Table("notifications").
+		Filter(map[string]interface{}{"familyID": "1"}).
+		OrderBy(r.Desc("dueAt"))).Return(
+		[]interface{}{
+			map[string]interface{}{"id": "10", "familyID": "1", "status": "pending"},
+			map[string]interface{}{"id": "9", "familyID": "1", "status": "acknowledged"},
+		}, nil)
+	mock.On(r.Table("notifications").
+		Filter(map[string]interface{}{"ruleID": "1"}).
+		OrderBy(r.Desc("dueAt")).
+		Limit(1)).Return(
+		[]interface{}{
+			map[string]interface{}{"id": "10", "ruleID": "1", "dueAt": now},
+		}, nil)
+	mock.On(r.Table("notifications").
+		Filter(map[string]interface{}{"ruleID": "2"}).
+		OrderBy(r.Desc("dueAt")).
+		Limit(1)).Return([]interface{}{}, nil)
+	mock.On(r.Table("notifications").
+		Filter(r.Row.Field("status").Eq(goparent.NotificationPending).Or(
+			r.Row.Field("status").Eq(goparent.NotificationSnoozed).And(r.Row.Field("snoozedUntil").Le(now)),
+		))).Return(
+		[]interface{}{
+			map[string]interface{}{"id": "10", "status": "pending"},
+			map[string]interface{}{"id": "8", "status": "snoozed"},
+		}, nil)
+	rs := ReminderService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+	ctx := context.Background()
+	family := &goparent.Family{ID: "1"}
+
+	n := &goparent.Notification{FamilyID: "1", RuleID: "1", Status: goparent.NotificationPending}
+	err := rs.SaveNotification(ctx, n)
+	assert.Nil(t, err)
+	assert.Equal(t, "10", n.ID)
+
+	n, err = rs.Notification(ctx, "10")
+	assert.Nil(t, err)
+	assert.Equal(t, goparent.NotificationPending, n.Status)
+
+	_, err = rs.Notification(ctx, "11")
+	assert.Equal(t, goparent.ErrNoNotificationFound, err)
+
+	inbox, err := rs.Notifications(ctx, family, false)
+	assert.Nil(t, err)
+	assert.Len(t, inbox, 1)
+
+	inbox, err = rs.Notifications(ctx, family, true)
+	assert.Nil(t, err)
+	assert.Len(t, inbox, 2)
+
+	latest, err := rs.LatestNotification(ctx, &goparent.ReminderRule{ID: "1"})
+	assert.Nil(t, err)
+	if assert.NotNil(t, latest) {
+		assert.True(t, now.Equal(latest.DueAt))
+	}
+
+	latest, err = rs.LatestNotification(ctx, &goparent.ReminderRule{ID: "2"})
+	assert.Nil(t, err)
+	assert.Nil(t, latest)
+
+	due, err := rs.DueNotifications(ctx, now)
+	assert.Nil(t, err)
+	assert.Len(t, due, 2)
+}
+
+func TestReminderCreateNotification(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		response r.WriteResponse
+		err      error
+	}{
+		{desc: "created", response: r.WriteResponse{Inserted: 1}},
+		{desc: "already created", response: r.WriteResponse{Unchanged: 1}, err: goparent.ErrNotificationExists},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.On(r.Table("notifications").MockAnything()).Return(tC.response, nil)
+			rs := ReminderService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+
+			n := &goparent.Notification{ID: "1-1767268800", FamilyID: "1", RuleID: "1", Status: goparent.NotificationPending}
+			err := rs.CreateNotification(context.Background(), n)
+			assert.Equal(t, tC.err, err)
+			assert.Equal(t, "1-1767268800", n.ID)
+		})
+	}
+}
+
+func TestReminderClaimNotification(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc     string
+		response r.WriteResponse
+		claimed  bool
+	}{
+		{desc: "claimed", response: r.WriteResponse{Replaced: 1}, claimed: true},
+		{desc: "claimed by another scheduler", response: r.WriteResponse{Unchanged: 1}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.On(r.Table("notifications").Get("10").MockAnything()).Return(tC.response, nil)
+			rs := ReminderService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+
+			n := &goparent.Notification{ID: "10", Status: goparent.NotificationPending}
+			claimed, err := rs.ClaimNotification(context.Background(), n, now)
+			assert.Nil(t, err)
+			assert.Equal(t, tC.claimed, claimed)
+			assert.Equal(t, tC.claimed, n.Status == goparent.NotificationDelivered)
+		})
+	}
+}
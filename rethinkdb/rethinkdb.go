@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/sasimpson/goparent"
 	"github.com/spf13/viper"
 	"gopkg.in/gorethink/gorethink.v3"
 )
 
-//DBEnv - stores the connection parameters for the rethinkdb instance
+//DBEnv - stores the connection parameters for the rethinkdb instance.  one DBEnv is shared by
+// the services and the health checks, mu keeps them from connecting over each other.
 type DBEnv struct {
 	Host     string
 	Port     int
@@ -19,10 +21,13 @@ type DBEnv struct {
 	Username string
 	Password string
 	Session  gorethink.QueryExecutor
+	mu       sync.Mutex
 }
 
 //GetConnection - get a connection to the db
 func (dbenv *DBEnv) GetConnection() error {
+	dbenv.mu.Lock()
+	defer dbenv.mu.Unlock()
 	if dbenv.Session != nil && dbenv.Session.IsConnected() {
 		return nil
 	}
@@ -41,6 +46,17 @@ func (dbenv *DBEnv) GetConnection() error {
 	return nil
 }
 
+//Close - close the session, if there is one
+func (dbenv *DBEnv) Close() error {
+	dbenv.mu.Lock()
+	defer dbenv.mu.Unlock()
+	session, ok := dbenv.Session.(*gorethink.Session)
+	if !ok || session == nil {
+		return nil
+	}
+	return session.Close()
+}
+
 //GetContext returns the request context to satisfy the interface needs
 func (dbenv *DBEnv) GetContext(r *http.Request) context.Context {
 	return r.Context()
@@ -61,13 +77,21 @@ func CreateTables(dbenv *DBEnv) {
 	gorethink.DB("goparent").TableCreate("children").Run(dbenv.Session)
 	gorethink.DB("goparent").TableCreate("invites").Run(dbenv.Session)
 	gorethink.DB("goparent").TableCreate("family").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("webhooks").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("webhook_deliveries").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("reminders").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("notifications").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("resets").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("ratelimits").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("apikeys").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("identities").Run(dbenv.Session)
+	gorethink.DB("goparent").TableCreate("leases").Run(dbenv.Session)
 }
 
-//InitRethinkDBConfig - setup and read configuration for the service
+//InitRethinkDBConfig - setup and read configuration for the service.  the DBEnv returned is
+// also the Env's DB, so the services, health checks and shutdown all share one session.
 func InitRethinkDBConfig() (*goparent.Env, *DBEnv) {
 	//set defaults
-	viper.SetDefault("service.host", "localhost")
-	viper.SetDefault("service.port", "8000")
 	viper.SetDefault("rethinkdb.host", "localhost")
 	viper.SetDefault("rethinkdb.port", 28015)
 	viper.SetDefault("rethinkdb.name", "goparent")
@@ -85,22 +109,27 @@ func InitRethinkDBConfig() (*goparent.Env, *DBEnv) {
 	}
 	log.Println("config used:", viper.ConfigFileUsed())
 
+	mailer, err := goparent.NewMailer(goparent.InitMailConfig())
+	if err != nil {
+		panic(fmt.Errorf("mail config: %s", err))
+	}
+	providers, err := goparent.InitOIDCConfig()
+	if err != nil {
+		panic(fmt.Errorf("oidc config: %s", err))
+	}
+
+	dbenv := &DBEnv{
+		Host:     viper.GetString("rethinkdb.host"),
+		Port:     viper.GetInt("rethinkdb.port"),
+		Database: viper.GetString("rethinkdb.name"),
+		Username: viper.GetString("rethinkdb.username"),
+		Password: viper.GetString("rethinkdb.password")}
 	return &goparent.Env{
-			Service: goparent.Service{
-				Host: viper.GetString("service.host"),
-				Port: viper.GetInt("service.port")},
-			DB: &DBEnv{
-				Host:     viper.GetString("rethinkdb.host"),
-				Port:     viper.GetInt("rethinkdb.port"),
-				Database: viper.GetString("rethinkdb.name"),
-				Username: viper.GetString("rethinkdb.username"),
-				Password: viper.GetString("rethinkdb.password")},
-			Auth: goparent.Authentication{
-				SigningKey: []byte(viper.GetString("auth.signingkey"))},
-		}, &DBEnv{
-			Host:     viper.GetString("rethinkdb.host"),
-			Port:     viper.GetInt("rethinkdb.port"),
-			Database: viper.GetString("rethinkdb.name"),
-			Username: viper.GetString("rethinkdb.username"),
-			Password: viper.GetString("rethinkdb.password")}
+		Service: goparent.InitServiceConfig(),
+		DB:      dbenv,
+		Auth: goparent.Authentication{
+			SigningKey: []byte(viper.GetString("auth.signingkey"))},
+		Mailer: mailer,
+		OIDC:   providers,
+	}, dbenv
 }
@@ -21,11 +21,11 @@ func (ss *SleepService) Status(ctx context.Context, family *goparent.Family, chi
 		return nil, false, err
 	}
 
-	//check to see if we already have an open sleep session
+	//check to see if we already have an open sleep session.  any household the child is
+	// shared with can end a sleep another started.
 	res, err := gorethink.Table("sleep").Filter(map[string]interface{}{
-		"end":      time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
-		"familyID": family.ID,
-		"childID":  child.ID,
+		"end":     time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		"childID": child.ID,
 	}).Run(ss.DB.Session)
 	if err != nil {
 		if err == gorethink.ErrEmptyResult {
@@ -53,28 +53,31 @@ func (ss *SleepService) Start(ctx context.Context, family *goparent.Family, chil
 	if err != nil {
 		return err
 	}
-
-	if !ok {
-		//this is broken until someone fixes it
-		// sleep.Start = time.Now()
-		return nil
+	if ok {
+		return goparent.ErrExistingStart
 	}
-	return goparent.ErrExistingStart
+
+	//an open sleep has a zero end, that's what status looks for
+	return ss.Save(ctx, &goparent.Sleep{
+		Start:    time.Now(),
+		End:      time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		FamilyID: family.ID,
+		ChildID:  child.ID,
+	})
 }
 
 //End - record end of sleep
 func (ss *SleepService) End(ctx context.Context, family *goparent.Family, child *goparent.Child) error {
-	_, ok, err := ss.Status(ctx, family, child)
+	sleep, ok, err := ss.Status(ctx, family, child)
 	if err != nil {
 		return err
 	}
-	if ok {
-		//this is broken until someone fixes it
-		// sleep.End = time.Now()
-		return nil
+	if !ok {
+		return goparent.ErrNoExistingSession
 	}
-	return goparent.ErrNoExistingSession
 
+	sleep.End = time.Now()
+	return ss.Save(ctx, sleep)
 }
 
 //Save - creates/saves the record.  saves if there is an id filled in.
@@ -104,9 +107,7 @@ func (ss *SleepService) Sleep(ctx context.Context, family *goparent.Family, days
 
 	daysBack := int(0 - days)
 	res, err := gorethink.Table("sleep").
-		Filter(map[string]interface{}{
-			"familyID": family.ID,
-		}).
+		Filter(familyRecords(family)).
 		Filter(gorethink.Row.Field("timestamp").During(time.Now().AddDate(0, 0, daysBack), time.Now())).
 		OrderBy(gorethink.Desc("end")).
 		Run(ss.DB.Session)
@@ -123,21 +124,36 @@ func (ss *SleepService) Sleep(ctx context.Context, family *goparent.Family, days
 	return rows, nil
 }
 
-//Stats - get sleep stats for one child for the last 24 hours.
-func (ss *SleepService) Stats(ctx context.Context, child *goparent.Child) (*goparent.SleepSummary, error) {
-	err := ss.DB.GetConnection()
+//Stats - get sleep stats for one child for the query window.
+func (ss *SleepService) Stats(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.SleepSummary, error) {
+	rows, err := ss.childSleeps(child, query)
+	if err != nil {
+		return nil, err
+	}
+	return goparent.NewSleepSummary(rows), nil
+}
+
+//GraphData - get sleep data for one child grouped into the query's buckets.
+func (ss *SleepService) GraphData(ctx context.Context, child *goparent.Child, query *goparent.DataQuery) (*goparent.SleepChartData, error) {
+	rows, err := ss.childSleeps(child, query)
 	if err != nil {
 		return nil, err
 	}
+	return goparent.NewSleepChartData(query, rows), nil
+}
 
-	end := time.Now()
-	start := end.AddDate(0, 0, -1)
+//childSleeps - all the sleeps for a child that started inside of the query window, newest first.
+func (ss *SleepService) childSleeps(child *goparent.Child, query *goparent.DataQuery) ([]goparent.Sleep, error) {
+	err := ss.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
 
 	res, err := gorethink.Table("sleep").
 		Filter(map[string]interface{}{
 			"childID": child.ID,
 		}).
-		Filter(gorethink.Row.Field("start").During(start, end)).
+		Filter(gorethink.Row.Field("start").During(query.Start, query.End)).
 		OrderBy(gorethink.Desc("start")).
 		Run(ss.DB.Session)
 	if err != nil {
@@ -150,35 +166,5 @@ func (ss *SleepService) Stats(ctx context.Context, child *goparent.Child) (*gopa
 	if err != nil {
 		return nil, err
 	}
-	//build summary output
-	summary := goparent.SleepSummary{
-		Data: rows,
-	}
-
-	for _, x := range rows {
-		summary.Total += x.End.Unix() - x.Start.Unix()
-	}
-	summary.Range = len(rows)
-	summary.Mean = float64(summary.Total) / float64(summary.Range)
-	return &summary, nil
-}
-
-//GraphData -
-func (ss *SleepService) GraphData(ctx context.Context, child *goparent.Child) (*goparent.SleepChartData, error) {
-	// err := ss.DB.GetConnection()
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	// end := time.Now()
-	// start := end.AddDate(0, 0, -7)
-
-	// res, err := gorethink.Table("sleep").
-	// 	Filter(gorethink.Row.Field("timestamp").During(start, end)).OrderBy("timestamp").
-	// 	Group(
-	// 		gorethink.Row.Field("timestamp").Year(),
-	// 		gorethink.Row.Field("timestamp").Month(),
-	// 		gorethink.Row.Field("timestamp").Day(),
-	// 	).Run(ss.DB.Session)
-	return nil, nil
+	return rows, nil
 }
@@ -190,9 +190,8 @@ func TestStatus(t *testing.T) {
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
 			query: (&r.Mock{}).On(
 				r.Table("sleep").Filter(map[string]interface{}{
-					"end":      time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
-					"familyID": "1",
-					"childID":  "1",
+					"end":     time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+					"childID": "1",
 				}),
 			).Return(map[string]interface{}{
 				"id": "1",
@@ -207,9 +206,8 @@ func TestStatus(t *testing.T) {
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
 			query: (&r.Mock{}).On(
 				r.Table("sleep").Filter(map[string]interface{}{
-					"end":      time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
-					"familyID": "1",
-					"childID":  "1",
+					"end":     time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+					"childID": "1",
 				}),
 			).Return(nil, nil),
 			family: &goparent.Family{ID: "1"},
@@ -222,9 +220,8 @@ func TestStatus(t *testing.T) {
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
 			query: (&r.Mock{}).On(
 				r.Table("sleep").Filter(map[string]interface{}{
-					"end":      time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
-					"familyID": "1",
-					"childID":  "1",
+					"end":     time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+					"childID": "1",
 				}),
 			).Return(map[string]interface{}{}, r.ErrEmptyResult),
 			family:      &goparent.Family{ID: "1"},
@@ -238,9 +235,8 @@ func TestStatus(t *testing.T) {
 			env:  &goparent.Env{DB: &mock.DBEnv{}},
 			query: (&r.Mock{}).On(
 				r.Table("sleep").Filter(map[string]interface{}{
-					"end":      time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
-					"familyID": "1",
-					"childID":  "1",
+					"end":     time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+					"childID": "1",
 				}),
 			).Return(nil, errors.New("test error")),
 			family:      &goparent.Family{ID: "1"},
@@ -255,6 +251,9 @@ func TestStatus(t *testing.T) {
 			ctx := context.Background()
 			mock := r.NewMock()
 			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			mock.On(
+				r.Table("sleep").Insert(r.MockAnything(), r.InsertOpts{Conflict: "replace"}),
+			).Return(r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"2"}}, nil)
 
 			ss := SleepService{Env: tC.env, DB: &DBEnv{Session: mock}}
 			_, status, err := ss.Status(ctx, tC.family, tC.child)
@@ -283,3 +282,130 @@ func TestStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestSleepStats(t *testing.T) {
+	start := time.Date(2018, 1, 1, 1, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc        string
+		env         *goparent.Env
+		query       *r.MockQuery
+		child       *goparent.Child
+		total       int64
+		mean        float64
+		rangeCount  int
+		returnError error
+	}{
+		{
+			desc: "get stats, skip active sleep",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("sleep").MockAnything(),
+			).Return([]map[string]interface{}{
+				{"id": "1", "start": start, "end": start.Add(time.Hour)},
+				{"id": "2", "start": start.Add(2 * time.Hour), "end": start.Add(4 * time.Hour)},
+				{"id": "3", "start": start.Add(5 * time.Hour), "end": time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)},
+			}, nil),
+			child:      &goparent.Child{ID: "1"},
+			total:      3 * 3600,
+			mean:       1.5 * 3600,
+			rangeCount: 2,
+		},
+		{
+			desc: "get stats, no sleeps",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("sleep").MockAnything(),
+			).Return([]map[string]interface{}{}, nil),
+			child: &goparent.Child{ID: "1"},
+		},
+		{
+			desc: "get stats error",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("sleep").MockAnything(),
+			).Return(nil, errors.New("test error")),
+			child:       &goparent.Child{ID: "1"},
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			ss := SleepService{Env: tC.env, DB: &DBEnv{Session: mock}}
+			summary, err := ss.Stats(ctx, tC.child, goparent.NewStatsQuery(time.Now(), time.UTC))
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tC.total, summary.Total)
+				assert.Equal(t, tC.mean, summary.Mean)
+				assert.Equal(t, tC.rangeCount, summary.Range)
+			}
+		})
+	}
+}
+
+func TestSleepGraphData(t *testing.T) {
+	start := time.Date(2018, 1, 31, 22, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc        string
+		env         *goparent.Env
+		query       *r.MockQuery
+		child       *goparent.Child
+		dataQuery   *goparent.DataQuery
+		datasets    []goparent.SleepChartDataset
+		returnError error
+	}{
+		{
+			desc: "get graph data by month",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("sleep").MockAnything(),
+			).Return([]map[string]interface{}{
+				{"id": "1", "start": start, "end": start.Add(time.Hour)},
+				{"id": "2", "start": start.Add(3 * time.Hour), "end": start.Add(5 * time.Hour)},
+			}, nil),
+			child: &goparent.Child{ID: "1"},
+			dataQuery: &goparent.DataQuery{
+				Start:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+				Granularity: goparent.GranularityMonth,
+			},
+			datasets: []goparent.SleepChartDataset{
+				{Date: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Totals: []time.Duration{time.Hour}},
+				{Date: time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC), Totals: []time.Duration{2 * time.Hour}},
+			},
+		},
+		{
+			desc: "get graph data error",
+			env:  &goparent.Env{},
+			query: (&r.Mock{}).On(
+				r.Table("sleep").MockAnything(),
+			).Return(nil, errors.New("test error")),
+			child:       &goparent.Child{ID: "1"},
+			dataQuery:   goparent.NewGraphQuery(time.Now(), time.UTC),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			ss := SleepService{Env: tC.env, DB: &DBEnv{Session: mock}}
+			chartData, err := ss.GraphData(ctx, tC.child, tC.dataQuery)
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, len(tC.datasets), len(chartData.Dataset))
+				for i := range tC.datasets {
+					assert.True(t, tC.datasets[i].Date.Equal(chartData.Dataset[i].Date))
+					assert.Equal(t, tC.datasets[i].Totals, chartData.Dataset[i].Totals)
+				}
+			}
+		})
+	}
+}
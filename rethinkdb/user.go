@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
@@ -40,7 +41,7 @@ func (us *UserService) User(ctx context.Context, id string) (*goparent.User, err
 
 	defer res.Close()
 	if res.IsNil() {
-		return nil, errors.New("no result for that id")
+		return nil, goparent.ErrNoUserFound
 	}
 
 	var user goparent.User
@@ -65,7 +66,7 @@ func (us *UserService) UserByLogin(ctx context.Context, username string, passwor
 
 	defer res.Close()
 	if res.IsNil() {
-		return nil, errors.New("no result for that username password combo")
+		return nil, goparent.ErrInvalidLogin
 	}
 
 	var user goparent.User
@@ -134,7 +135,8 @@ func (us *UserService) Save(ctx context.Context, user *goparent.User) error {
 		}
 		return nil
 	}
-	return errors.New("there needs to be an ID in the user if one with that email exists")
+	//there needs to be an ID in the user if one with that email exists
+	return goparent.ErrExistingUser
 }
 
 //GetToken - gets the user token
@@ -222,12 +224,185 @@ func (us *UserService) GetAllFamily(ctx context.Context, user *goparent.User) ([
 	return family, nil
 }
 
-//RequestResetPassword - needs implementation
-func (us *UserService) RequestResetPassword(context.Context, string, string) error {
+//RequestResetPassword - save a password reset request for the email and mail the user the
+// code for it.  the request is keyed by the hash of the code, the code itself is only in the mail.
+func (us *UserService) RequestResetPassword(ctx context.Context, email string, ip string) error {
+	err := us.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	user, err := us.userByEmail(email)
+	if err != nil {
+		return err
+	}
+	//a reset would hand the account to whoever owns the email, which hasn't been shown yet
+	if user.Unverified {
+		return goparent.ErrEmailNotVerified
+	}
+
+	code, hash, err := goparent.NewResetCode()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	resetRequest := goparent.UserReset{
+		ID:          hash,
+		Timestamp:   now,
+		RequestAddr: ip,
+		Email:       email,
+		ExpiresAt:   now.Add(goparent.ResetCodeExpiry),
+	}
+	_, err = gorethink.Table("resets").Insert(resetRequest).RunWrite(us.DB.Session)
+	if err != nil {
+		return err
+	}
+
+	resetMessage, err := goparent.NewMail(goparent.MailReset, []string{user.Email}, goparent.ResetMail{Name: user.Name, Code: code})
+	if err != nil {
+		return err
+	}
+	err = us.Env.Mail(ctx, resetMessage)
+	if err != nil {
+		log.Printf("error sending mail: %s", err)
+	}
 	return nil
 }
 
-//ResetPassword - needs implementation
-func (us *UserService) ResetPassword(context.Context, string, string) error {
+//ResetPassword - set a new password for the user the reset code was sent to.  the reset
+// is deleted as it's read so the code can only be used once, even by requests racing each other.
+func (us *UserService) ResetPassword(ctx context.Context, code string, password string) error {
+	err := us.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	res, err := gorethink.Table("resets").Get(goparent.HashResetCode(code)).Delete(gorethink.DeleteOpts{ReturnChanges: true}).Run(us.DB.Session)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	var deleted struct {
+		Changes []struct {
+			OldValue goparent.UserReset `gorethink:"old_val"`
+		} `gorethink:"changes"`
+	}
+	err = res.One(&deleted)
+	if err != nil && err != gorethink.ErrEmptyResult {
+		return err
+	}
+	if len(deleted.Changes) == 0 || deleted.Changes[0].OldValue.Expired(time.Now()) {
+		return goparent.ErrInvalidResetCode
+	}
+
+	user, err := us.userByEmail(deleted.Changes[0].OldValue.Email)
+	if err != nil {
+		return err
+	}
+	_, err = gorethink.Table("users").Get(user.ID).Update(map[string]interface{}{"password": password}).RunWrite(us.DB.Session)
+	return err
+}
+
+//ChangeEmail - move the user to a new, confirmed, email address which can't belong to anyone else.
+// the user's id doesn't change so everything they've recorded stays theirs.
+func (us *UserService) ChangeEmail(ctx context.Context, user *goparent.User, email string) error {
+	err := us.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	existing, err := us.userByEmail(email)
+	if err == nil && existing.ID != user.ID {
+		return goparent.ErrExistingUser
+	}
+	if err != nil && err != goparent.ErrNoUserFound {
+		return err
+	}
+
+	//the change is only made once the new address is confirmed, so it's verified too
+	_, err = gorethink.Table("users").Get(user.ID).Update(map[string]interface{}{"email": email, "unverified": false}).RunWrite(us.DB.Session)
+	if err != nil {
+		return err
+	}
+	user.Email = email
+	user.Unverified = false
 	return nil
 }
+
+//userFields - the fields that record which user did something, blanked when the user is
+// deleted so the family keeps the records but not who they were
+var userFields = []struct {
+	table string
+	field string
+}{
+	{"children", "parentID"},
+	{"feeding", "userID"},
+	{"sleep", "userID"},
+	{"waste", "userID"},
+	{"notifications", "acknowledgedBy"},
+}
+
+//Delete - delete the user's account.  they leave all their families first, families they
+// run go to another member or are deleted with them if they were the only one.  what they
+// recorded in families that carry on is kept without their name on it.
+func (us *UserService) Delete(ctx context.Context, user *goparent.User) error {
+	err := us.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	families, err := us.GetAllFamily(ctx, user)
+	if err != nil {
+		return err
+	}
+	_, err = gorethink.Table("users").Get(user.ID).Delete().RunWrite(us.DB.Session)
+	if err != nil {
+		return err
+	}
+	err = goparent.LeaveFamilies(ctx, &FamilyService{Env: us.Env, DB: us.DB}, user, families)
+	if err != nil {
+		return err
+	}
+
+	_, err = gorethink.Table("invites").Filter(func(invite gorethink.Term) gorethink.Term {
+		return invite.Field("userID").Eq(user.ID).Or(invite.Field("inviteEmail").Eq(user.Email).And(invite.Field("status").Eq(goparent.InvitationPending)))
+	}).Delete().RunWrite(us.DB.Session)
+	if err != nil {
+		return err
+	}
+	for _, uf := range userFields {
+		_, err = gorethink.Table(uf.table).Filter(map[string]interface{}{uf.field: user.ID}).Update(map[string]interface{}{uf.field: ""}).RunWrite(us.DB.Session)
+		if err != nil {
+			return err
+		}
+	}
+	for _, table := range []string{"apikeys", "identities"} {
+		_, err = gorethink.Table(table).Filter(map[string]interface{}{"userID": user.ID}).Delete().RunWrite(us.DB.Session)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = gorethink.Table("resets").Filter(map[string]interface{}{"email": user.Email}).Delete().RunWrite(us.DB.Session)
+	return err
+}
+
+//userByEmail - the user with the email, ErrNoUserFound if there isn't one
+func (us *UserService) userByEmail(email string) (*goparent.User, error) {
+	res, err := gorethink.Table("users").Filter(map[string]interface{}{
+		"email": email,
+	}).Run(us.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var user goparent.User
+	err = res.One(&user)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoUserFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
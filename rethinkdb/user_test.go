@@ -2,6 +2,8 @@ package rethinkdb
 
 import (
 	"context"
+	"errors"
+	"regexp"
 	"testing"
 	"time"
 
@@ -110,11 +112,17 @@ func TestNewUserSave(t *testing.T) {
 		On(
 			r.Table("users").Insert(
 				map[string]interface{}{
-					"name":          "test user",
-					"email":         "testuser@test.com",
-					"username":      "testuser",
-					"password":      "testpassword",
-					"currentFamily": "1",
+					"name":               "test user",
+					"email":              "testuser@test.com",
+					"username":           "testuser",
+					"password":           "testpassword",
+					"currentFamily":      "1",
+					"unverified":         false,
+					"verificationSentAt": time.Time{},
+					"totpEnabled":        false,
+					"totpSecret":         "",
+					"totpLastStep":       int64(0),
+					"recoveryCodes":      []string(nil),
 				}, r.InsertOpts{Conflict: "replace"},
 			),
 		).
@@ -169,12 +177,18 @@ func TestUserSave(t *testing.T) {
 		On(
 			r.Table("users").Insert(
 				map[string]interface{}{
-					"name":          "test user",
-					"email":         "testuser@test.com",
-					"username":      "testuser",
-					"password":      "testpassword",
-					"currentFamily": "1",
-					"id":            "1",
+					"name":               "test user",
+					"email":              "testuser@test.com",
+					"username":           "testuser",
+					"password":           "testpassword",
+					"currentFamily":      "1",
+					"unverified":         false,
+					"verificationSentAt": time.Time{},
+					"totpEnabled":        false,
+					"totpSecret":         "",
+					"totpLastStep":       int64(0),
+					"recoveryCodes":      []string(nil),
+					"id":                 "1",
 				}, r.InsertOpts{Conflict: "replace"},
 			),
 		).
@@ -296,3 +310,189 @@ func TestGetAllFamily(t *testing.T) {
 // 	assert.Nil(t, err)
 // 	assert.Equal(t, "1", user.ID)
 // }
+
+func TestRequestResetPassword(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		users       []interface{}
+		mailed      int
+		returnError error
+	}{
+		{
+			desc:   "mails the reset code",
+			users:  []interface{}{map[string]interface{}{"id": "1", "name": "test user", "email": "testuser@test.com"}},
+			mailed: 1,
+		},
+		{
+			desc:        "unknown email",
+			users:       []interface{}{},
+			returnError: goparent.ErrNoUserFound,
+		},
+		{
+			desc:        "unverified email",
+			users:       []interface{}{map[string]interface{}{"id": "1", "email": "testuser@test.com", "unverified": true}},
+			returnError: goparent.ErrEmailNotVerified,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mailer := &mock.Mailer{}
+			rmock := r.NewMock()
+			rmock.On(r.Table("users").Filter(map[string]interface{}{"email": "testuser@test.com"})).Return(tC.users, nil)
+			rmock.On(r.Table("resets").MockAnything()).Return(r.WriteResponse{Inserted: 1}, nil)
+			us := UserService{Env: &goparent.Env{Mailer: mailer}, DB: &DBEnv{Session: rmock}}
+
+			err := us.RequestResetPassword(context.Background(), "testuser@test.com", "127.0.0.1")
+			assert.Equal(t, tC.returnError, err)
+			if assert.Len(t, mailer.Sent, tC.mailed) && tC.mailed > 0 {
+				assert.Equal(t, []string{"testuser@test.com"}, mailer.Sent[0].To)
+				code := regexp.MustCompile(`[0-9a-f]{64}`).FindString(mailer.Sent[0].Text)
+				assert.NotEmpty(t, code, "the mail has the code")
+				inserted := rmock.Queries[len(rmock.Queries)-1].Query.Term.String()
+				assert.Contains(t, inserted, goparent.HashResetCode(code), "the reset is stored under the code's hash")
+				assert.NotContains(t, inserted, code, "the code itself isn't stored")
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	deleted := func(reset map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"deleted": 1, "changes": []interface{}{map[string]interface{}{"old_val": reset}}}
+	}
+	testCases := []struct {
+		desc        string
+		deleted     interface{}
+		returnError error
+	}{
+		{
+			desc:    "resets the password",
+			deleted: deleted(map[string]interface{}{"email": "testuser@test.com", "expiresAt": time.Now().Add(time.Minute)}),
+		},
+		{
+			desc:        "unknown or used code",
+			deleted:     map[string]interface{}{"skipped": 1},
+			returnError: goparent.ErrInvalidResetCode,
+		},
+		{
+			desc:        "expired code",
+			deleted:     deleted(map[string]interface{}{"email": "testuser@test.com", "expiresAt": time.Now().Add(-time.Minute)}),
+			returnError: goparent.ErrInvalidResetCode,
+		},
+		{
+			desc:        "code from before expiry",
+			deleted:     deleted(map[string]interface{}{"email": "testuser@test.com"}),
+			returnError: goparent.ErrInvalidResetCode,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rmock := r.NewMock()
+			rmock.On(r.Table("resets").Get(goparent.HashResetCode("reset-code")).Delete(r.DeleteOpts{ReturnChanges: true})).Return(tC.deleted, nil)
+			rmock.On(r.Table("users").Filter(map[string]interface{}{"email": "testuser@test.com"})).Return(
+				[]interface{}{map[string]interface{}{"id": "1", "email": "testuser@test.com"}}, nil)
+			update := rmock.On(r.Table("users").Get("1").Update(map[string]interface{}{"password": "newpassword"})).Return(r.WriteResponse{Replaced: 1}, nil)
+			us := UserService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+			err := us.ResetPassword(context.Background(), "reset-code", "newpassword")
+			assert.Equal(t, tC.returnError, err)
+			if tC.returnError == nil {
+				rmock.AssertExpectations(t)
+			} else {
+				rmock.AssertNotExecuted(t, update)
+			}
+		})
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		existing    []interface{}
+		email       string
+		returnError error
+	}{
+		{
+			desc:     "changes the email",
+			existing: []interface{}{},
+			email:    "newuser@test.com",
+		},
+		{
+			desc:        "email belongs to someone else",
+			existing:    []interface{}{map[string]interface{}{"id": "2", "email": "newuser@test.com"}},
+			email:       "testuser@test.com",
+			returnError: goparent.ErrExistingUser,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rmock := r.NewMock()
+			rmock.On(r.Table("users").Filter(map[string]interface{}{"email": "newuser@test.com"})).Return(tC.existing, nil)
+			rmock.On(r.Table("users").Get("1").Update(map[string]interface{}{"email": "newuser@test.com", "unverified": false})).Return(r.WriteResponse{Replaced: 1}, nil)
+			us := UserService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+			user := &goparent.User{ID: "1", Email: "testuser@test.com"}
+			err := us.ChangeEmail(context.Background(), user, "newuser@test.com")
+			assert.Equal(t, tC.returnError, err)
+			assert.Equal(t, tC.email, user.Email)
+			if tC.returnError == nil {
+				rmock.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		queries     []*r.MockQuery
+		returnError error
+	}{
+		{
+			desc: "hands the family over and anonymizes records",
+			queries: func() []*r.MockQuery {
+				queries := []*r.MockQuery{
+					(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return([]interface{}{
+						map[string]interface{}{"id": "family-1", "admin": "user-1", "members": []interface{}{"user-1", "user-2"}},
+					}, nil),
+					(&r.Mock{}).On(r.Table("users").Get("user-1").Delete()).Once().Return(r.WriteResponse{Deleted: 1}, nil),
+					(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(r.WriteResponse{Replaced: 1}, nil),
+					(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(r.WriteResponse{Deleted: 0}, nil),
+					(&r.Mock{}).On(r.Table("invites").MockAnything()).Once().Return(r.WriteResponse{Deleted: 1}, nil),
+				}
+				for _, uf := range userFields {
+					queries = append(queries, (&r.Mock{}).On(
+						r.Table(uf.table).Filter(map[string]interface{}{uf.field: "user-1"}).Update(map[string]interface{}{uf.field: ""}),
+					).Once().Return(r.WriteResponse{Replaced: 1}, nil))
+				}
+				return append(queries,
+					(&r.Mock{}).On(r.Table("apikeys").Filter(map[string]interface{}{"userID": "user-1"}).Delete()).Once().Return(r.WriteResponse{Deleted: 1}, nil),
+					(&r.Mock{}).On(r.Table("identities").Filter(map[string]interface{}{"userID": "user-1"}).Delete()).Once().Return(r.WriteResponse{Deleted: 1}, nil),
+					(&r.Mock{}).On(r.Table("resets").MockAnything()).Once().Return(r.WriteResponse{Deleted: 0}, nil),
+				)
+			}(),
+		},
+		{
+			desc: "families error",
+			queries: []*r.MockQuery{
+				(&r.Mock{}).On(r.Table("family").MockAnything()).Once().Return(nil, errors.New("test error")),
+			},
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rmock := r.NewMock()
+			rmock.ExpectedQueries = append(rmock.ExpectedQueries, tC.queries...)
+			us := UserService{Env: &goparent.Env{}, DB: &DBEnv{Session: rmock}}
+
+			err := us.Delete(context.Background(), &goparent.User{ID: "user-1", Email: "testuser@test.com", CurrentFamily: "family-1"})
+			if tC.returnError != nil {
+				assert.EqualError(t, err, tC.returnError.Error())
+			} else {
+				assert.Nil(t, err)
+				rmock.AssertExpectations(t)
+			}
+		})
+	}
+}
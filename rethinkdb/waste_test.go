@@ -180,12 +180,21 @@ func TestStats(t *testing.T) {
 			query: (&r.Mock{}).On(
 				r.Table("waste").MockAnything(),
 			).Return([]map[string]interface{}{
-				{"id": "1"},
-				{"id": "2"},
+				{"id": "1", "wasteType": 1},
+				{"id": "2", "wasteType": 2},
 			}, nil),
 			child:       &goparent.Child{ID: "1"},
 			returnError: nil,
 		},
+		{
+			desc: "get stats error",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			query: (&r.Mock{}).On(
+				r.Table("waste").MockAnything(),
+			).Return(nil, errors.New("test error")),
+			child:       &goparent.Child{ID: "1"},
+			returnError: errors.New("test error"),
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -193,12 +202,13 @@ func TestStats(t *testing.T) {
 			mock := r.NewMock()
 			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
 			fs := WasteService{Env: tC.env, DB: &DBEnv{Session: mock}}
-			statsData, err := fs.Stats(ctx, tC.child)
+			statsData, err := fs.Stats(ctx, tC.child, goparent.NewStatsQuery(time.Now(), time.UTC))
 			if tC.returnError != nil {
 				assert.EqualError(t, tC.returnError, err.Error())
 			} else {
 				assert.Nil(t, err)
 				assert.NotNil(t, statsData)
+				assert.Equal(t, map[int]int{1: 1, 2: 1}, statsData.Total)
 			}
 		})
 	}
@@ -210,6 +220,8 @@ func TestWasteGraph(t *testing.T) {
 		env         *goparent.Env
 		query       *r.MockQuery
 		child       *goparent.Child
+		dataQuery   *goparent.DataQuery
+		datasets    []goparent.WasteChartDataset
 		returnError *error
 	}{
 		{
@@ -218,10 +230,41 @@ func TestWasteGraph(t *testing.T) {
 			query: (&r.Mock{}).On(
 				r.Table("waste").MockAnything(),
 			).Return([]map[string]interface{}{
-				{"group": []int{2018, 1, 1, 1}, "reduction": 1},
-				{"group": []int{2018, 1, 1, 2}, "reduction": 2},
+				{"id": "1", "wasteType": 1, "timestamp": time.Date(2018, 1, 2, 10, 0, 0, 0, time.UTC)},
+				{"id": "2", "wasteType": 2, "timestamp": time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)},
+				{"id": "3", "wasteType": 2, "timestamp": time.Date(2018, 1, 1, 11, 0, 0, 0, time.UTC)},
+			}, nil),
+			child: &goparent.Child{ID: "1", Name: "Billy"},
+			dataQuery: &goparent.DataQuery{
+				Start:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC),
+				Granularity: goparent.GranularityDay,
+			},
+			datasets: []goparent.WasteChartDataset{
+				{Date: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Type: 2, Count: 2},
+				{Date: time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC), Type: 1, Count: 1},
+			},
+		},
+		{
+			desc: "get graph data by week",
+			env:  &goparent.Env{DB: &mock.DBEnv{}},
+			query: (&r.Mock{}).On(
+				r.Table("waste").MockAnything(),
+			).Return([]map[string]interface{}{
+				{"id": "1", "wasteType": 1, "timestamp": time.Date(2018, 1, 2, 10, 0, 0, 0, time.UTC)},
+				{"id": "2", "wasteType": 1, "timestamp": time.Date(2018, 1, 7, 12, 0, 0, 0, time.UTC)},
+				{"id": "3", "wasteType": 1, "timestamp": time.Date(2018, 1, 8, 11, 0, 0, 0, time.UTC)},
 			}, nil),
 			child: &goparent.Child{ID: "1", Name: "Billy"},
+			dataQuery: &goparent.DataQuery{
+				Start:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC),
+				Granularity: goparent.GranularityWeek,
+			},
+			datasets: []goparent.WasteChartDataset{
+				{Date: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Type: 1, Count: 2},
+				{Date: time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC), Type: 1, Count: 1},
+			},
 		},
 	}
 	for _, tC := range testCases {
@@ -230,12 +273,19 @@ func TestWasteGraph(t *testing.T) {
 			mock := r.NewMock()
 			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
 			fs := WasteService{Env: tC.env, DB: &DBEnv{Session: mock}}
-			chartData, err := fs.GraphData(ctx, tC.child)
+			chartData, err := fs.GraphData(ctx, tC.child, tC.dataQuery)
 			if tC.returnError != nil {
 				assert.EqualError(t, *tC.returnError, err.Error())
 			} else {
 				assert.Nil(t, err)
 				assert.NotNil(t, chartData)
+				assert.Equal(t, tC.dataQuery.Granularity, chartData.Granularity)
+				assert.Equal(t, len(tC.datasets), len(chartData.Dataset))
+				for i := range tC.datasets {
+					assert.True(t, tC.datasets[i].Date.Equal(chartData.Dataset[i].Date))
+					assert.Equal(t, tC.datasets[i].Type, chartData.Dataset[i].Type)
+					assert.Equal(t, tC.datasets[i].Count, chartData.Dataset[i].Count)
+				}
 			}
 		})
 	}
@@ -0,0 +1,129 @@
+package rethinkdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasimpson/goparent"
+	gorethink "gopkg.in/gorethink/gorethink.v3"
+)
+
+//WebhookService - service for implementing the interface
+type WebhookService struct {
+	Env *goparent.Env
+	DB  *DBEnv
+}
+
+//Save - create or update a webhook
+func (ws *WebhookService) Save(ctx context.Context, webhook *goparent.Webhook) error {
+	err := ws.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	webhook.LastUpdated = time.Now()
+	if webhook.ID == "" {
+		webhook.CreatedAt = webhook.LastUpdated
+	}
+	res, err := gorethink.Table("webhooks").Insert(webhook, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(ws.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted > 0 && len(res.GeneratedKeys) > 0 {
+		webhook.ID = res.GeneratedKeys[0]
+	}
+	return nil
+}
+
+//Webhook - return a webhook for an id
+func (ws *WebhookService) Webhook(ctx context.Context, id string) (*goparent.Webhook, error) {
+	err := ws.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("webhooks").Get(id).Run(ws.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var webhook goparent.Webhook
+	err = res.One(&webhook)
+	if err == gorethink.ErrEmptyResult {
+		return nil, goparent.ErrNoWebhookFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+//Webhooks - all of a family's webhooks, oldest first
+func (ws *WebhookService) Webhooks(ctx context.Context, family *goparent.Family) ([]*goparent.Webhook, error) {
+	err := ws.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("webhooks").
+		Filter(map[string]interface{}{
+			"familyID": family.ID,
+		}).
+		OrderBy("createdAt").
+		Run(ws.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows []*goparent.Webhook
+	err = res.All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//SaveDelivery - add an attempt to the delivery log
+func (ws *WebhookService) SaveDelivery(ctx context.Context, delivery *goparent.WebhookDelivery) error {
+	err := ws.DB.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	res, err := gorethink.Table("webhook_deliveries").Insert(delivery).RunWrite(ws.DB.Session)
+	if err != nil {
+		return err
+	}
+	if res.Inserted > 0 && len(res.GeneratedKeys) > 0 {
+		delivery.ID = res.GeneratedKeys[0]
+	}
+	return nil
+}
+
+//Deliveries - the most recent delivery attempts for a webhook, newest first
+func (ws *WebhookService) Deliveries(ctx context.Context, webhook *goparent.Webhook, limit int) ([]*goparent.WebhookDelivery, error) {
+	err := ws.DB.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gorethink.Table("webhook_deliveries").
+		Filter(map[string]interface{}{
+			"webhookID": webhook.ID,
+		}).
+		OrderBy(gorethink.Desc("timestamp")).
+		Limit(limit).
+		Run(ws.DB.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows []*goparent.WebhookDelivery
+	err = res.All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
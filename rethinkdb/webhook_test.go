@@ -0,0 +1,159 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sasimpson/goparent"
+	"github.com/stretchr/testify/assert"
+	r "gopkg.in/gorethink/gorethink.v3"
+)
+
+func TestWebhookSave(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		webhook     *goparent.Webhook
+		query       *r.MockQuery
+		id          string
+		returnError error
+	}{
+		{
+			desc:    "save new webhook",
+			webhook: &goparent.Webhook{FamilyID: "1", URL: "http://localhost/hook"},
+			query: (&r.Mock{}).On(r.Table("webhooks").MockAnything()).Once().Return(
+				r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"1"}}, nil),
+			id: "1",
+		},
+		{
+			desc:    "update webhook",
+			webhook: &goparent.Webhook{ID: "2", FamilyID: "1", URL: "http://localhost/hook", Disabled: true},
+			query: (&r.Mock{}).On(r.Table("webhooks").MockAnything()).Once().Return(
+				r.WriteResponse{Replaced: 1}, nil),
+			id: "2",
+		},
+		{
+			desc:    "error saving webhook",
+			webhook: &goparent.Webhook{FamilyID: "1", URL: "http://localhost/hook"},
+			query: (&r.Mock{}).On(r.Table("webhooks").MockAnything()).Once().Return(
+				r.WriteResponse{Errors: 1}, errors.New("test error")),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			ws := WebhookService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			err := ws.Save(context.Background(), tC.webhook)
+			if tC.returnError != nil {
+				assert.Equal(t, tC.returnError, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tC.id, tC.webhook.ID)
+			assert.False(t, tC.webhook.LastUpdated.IsZero())
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		query       *r.MockQuery
+		returnError error
+	}{
+		{
+			desc: "get webhook",
+			query: (&r.Mock{}).On(r.Table("webhooks").Get("1")).Return(
+				map[string]interface{}{"id": "1", "familyID": "1", "url": "http://localhost/hook", "events": []interface{}{"sleep.*"}}, nil),
+		},
+		{
+			desc:        "no webhook",
+			query:       (&r.Mock{}).On(r.Table("webhooks").Get("1")).Return(nil, nil),
+			returnError: goparent.ErrNoWebhookFound,
+		},
+		{
+			desc:        "error getting webhook",
+			query:       (&r.Mock{}).On(r.Table("webhooks").Get("1")).Return(nil, errors.New("test error")),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			ws := WebhookService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			webhook, err := ws.Webhook(context.Background(), "1")
+			if tC.returnError != nil {
+				assert.Equal(t, tC.returnError, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"sleep.*"}, webhook.Events)
+		})
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		query       *r.MockQuery
+		count       int
+		returnError error
+	}{
+		{
+			desc: "list webhooks",
+			query: (&r.Mock{}).On(r.Table("webhooks").Filter(map[string]interface{}{"familyID": "1"}).OrderBy("createdAt")).Return(
+				[]interface{}{
+					map[string]interface{}{"id": "1", "familyID": "1"},
+					map[string]interface{}{"id": "2", "familyID": "1", "disabled": true},
+				}, nil),
+			count: 2,
+		},
+		{
+			desc:        "error listing webhooks",
+			query:       (&r.Mock{}).On(r.Table("webhooks").Filter(map[string]interface{}{"familyID": "1"}).OrderBy("createdAt")).Return(nil, errors.New("test error")),
+			returnError: errors.New("test error"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			mock := r.NewMock()
+			mock.ExpectedQueries = append(mock.ExpectedQueries, tC.query)
+			ws := WebhookService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+			webhooks, err := ws.Webhooks(context.Background(), &goparent.Family{ID: "1"})
+			if tC.returnError != nil {
+				assert.Equal(t, tC.returnError, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Len(t, webhooks, tC.count)
+		})
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	mock := r.NewMock()
+	mock.On(r.Table("webhook_deliveries").MockAnything()).Once().Return(
+		r.WriteResponse{Inserted: 1, GeneratedKeys: []string{"10"}}, nil)
+	mock.On(
+		r.Table("webhook_deliveries").Filter(map[string]interface{}{"webhookID": "1"}).OrderBy(r.Desc("timestamp")).Limit(20),
+	).Return([]interface{}{
+		map[string]interface{}{"id": "10", "webhookID": "1", "attempt": 2, "statusCode": 200},
+		map[string]interface{}{"id": "9", "webhookID": "1", "attempt": 1, "statusCode": 503},
+	}, nil)
+	ws := WebhookService{Env: &goparent.Env{}, DB: &DBEnv{Session: mock}}
+	ctx := context.Background()
+
+	delivery := &goparent.WebhookDelivery{WebhookID: "1", Attempt: 2, StatusCode: 200}
+	err := ws.SaveDelivery(ctx, delivery)
+	assert.Nil(t, err)
+	assert.Equal(t, "10", delivery.ID)
+
+	deliveries, err := ws.Deliveries(ctx, &goparent.Webhook{ID: "1"}, 20)
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, 503, deliveries[1].StatusCode)
+	}
+}
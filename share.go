@@ -0,0 +1,140 @@
+package goparent
+
+import (
+	"errors"
+	"time"
+)
+
+//Child share permissions.  a family a child is shared with can always see the child and
+// its records, log lets them log feedings, sleeps and wastes for it too.
+const (
+	ShareView = "view"
+	ShareLog  = "log"
+)
+
+//SharePermissions - the permissions a child can be shared with
+var SharePermissions = []string{ShareView, ShareLog}
+
+//ErrNoShareFound - the child isn't shared with that family
+var ErrNoShareFound = errors.New("the child isn't shared with that family")
+
+//ErrChildViewOnly - the child is shared with the family, but not for logging
+var ErrChildViewOnly = errors.New("the child is shared with this family to view only")
+
+//ErrNotChildOwner - only the child's own family can change, delete or share it
+var ErrNotChildOwner = errors.New("the child belongs to another family")
+
+//ErrShareNotPending - the share was already accepted
+var ErrShareNotPending = errors.New("the child's share with this family was already accepted")
+
+//ChildShare - another family the child is linked into, e.g. the other parent's household
+// when parents are separated.  records either family logs show up for both.  a share is
+// pending until the other family's admin accepts it, pending shares don't give any access.
+type ChildShare struct {
+	FamilyID   string    `json:"familyID" gorethink:"familyID"`
+	Permission string    `json:"permission" gorethink:"permission"`
+	SharedBy   string    `json:"sharedBy" gorethink:"sharedBy"`
+	SharedAt   time.Time `json:"sharedAt" gorethink:"sharedAt"`
+	Pending    bool      `json:"pending,omitempty" gorethink:"pending,omitempty"`
+	AcceptedBy string    `json:"acceptedBy,omitempty" gorethink:"acceptedBy,omitempty"`
+}
+
+//Validate - check a share before it's added to a child
+func (share ChildShare) Validate(now time.Time) error {
+	return validate(
+		required("familyID", share.FamilyID),
+		oneOf("permission", share.Permission, SharePermissions),
+	)
+}
+
+//SharedWith - the child's share with the family, pending or not, nil if it isn't shared with it
+func (child *Child) SharedWith(familyID string) *ChildShare {
+	for i := range child.Shares {
+		if child.Shares[i].FamilyID == familyID {
+			return &child.Shares[i]
+		}
+	}
+	return nil
+}
+
+//CanView - whether the family can see the child, its own family always can
+func (child *Child) CanView(familyID string) bool {
+	if child.FamilyID == familyID {
+		return true
+	}
+	share := child.SharedWith(familyID)
+	return share != nil && !share.Pending
+}
+
+//CanLog - whether the family can log records for the child
+func (child *Child) CanLog(familyID string) bool {
+	if child.FamilyID == familyID {
+		return true
+	}
+	share := child.SharedWith(familyID)
+	return share != nil && !share.Pending && share.Permission == ShareLog
+}
+
+//Families - the child's own family and the families that accepted a share of it, the
+// families that see the records logged for it
+func (child *Child) Families() []string {
+	families := []string{child.FamilyID}
+	for _, share := range child.Shares {
+		if !share.Pending {
+			families = append(families, share.FamilyID)
+		}
+	}
+	return families
+}
+
+//Share - link the child into another family, sharing again changes the permission.  a
+// share that was accepted stays accepted.
+func (child *Child) Share(share ChildShare) error {
+	if share.FamilyID == child.FamilyID {
+		return NewValidationError("familyID", "the child already belongs to that family")
+	}
+	err := share.Validate(share.SharedAt)
+	if err != nil {
+		return err
+	}
+	existing := child.SharedWith(share.FamilyID)
+	if existing != nil {
+		if !existing.Pending {
+			share.Pending = false
+			share.AcceptedBy = existing.AcceptedBy
+		}
+		*existing = share
+		return nil
+	}
+	child.Shares = append(child.Shares, share)
+	return nil
+}
+
+//Accept - the family takes the child's pending share
+func (child *Child) Accept(familyID string, acceptedBy string) error {
+	share := child.SharedWith(familyID)
+	if share == nil {
+		return ErrNoShareFound
+	}
+	if !share.Pending {
+		return ErrShareNotPending
+	}
+	share.Pending = false
+	share.AcceptedBy = acceptedBy
+	return nil
+}
+
+//Unshare - take the child back out of another family
+func (child *Child) Unshare(familyID string) error {
+	shares := make([]ChildShare, 0, len(child.Shares))
+	for _, share := range child.Shares {
+		if share.FamilyID != familyID {
+			shares = append(shares, share)
+		}
+	}
+	if len(shares) == len(child.Shares) {
+		return ErrNoShareFound
+	}
+	child.Shares = shares
+	return nil
+}
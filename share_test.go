@@ -0,0 +1,68 @@
+package goparent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChildAccess(t *testing.T) {
+	child := &Child{ID: "1", FamilyID: "1", Shares: []ChildShare{
+		{FamilyID: "2", Permission: ShareLog},
+		{FamilyID: "3", Permission: ShareView},
+		{FamilyID: "5", Permission: ShareLog, Pending: true},
+	}}
+	testCases := []struct {
+		desc     string
+		familyID string
+		canView  bool
+		canLog   bool
+	}{
+		{desc: "own family", familyID: "1", canView: true, canLog: true},
+		{desc: "shared to log", familyID: "2", canView: true, canLog: true},
+		{desc: "shared to view", familyID: "3", canView: true},
+		{desc: "not shared", familyID: "4"},
+		{desc: "share not accepted", familyID: "5"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.canView, child.CanView(tC.familyID))
+			assert.Equal(t, tC.canLog, child.CanLog(tC.familyID))
+		})
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, child.Families())
+}
+
+func TestChildShare(t *testing.T) {
+	now := time.Now()
+	child := &Child{ID: "1", FamilyID: "1"}
+
+	err := child.Share(ChildShare{FamilyID: "1", Permission: ShareLog, SharedAt: now})
+	assert.IsType(t, &ValidationError{}, err)
+	err = child.Share(ChildShare{FamilyID: "2", Permission: "edit", SharedAt: now})
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Empty(t, child.Shares)
+
+	assert.Nil(t, child.Share(ChildShare{FamilyID: "2", Permission: ShareView, SharedAt: now}))
+	assert.Nil(t, child.Share(ChildShare{FamilyID: "3", Permission: ShareLog, SharedAt: now}))
+	//sharing again changes the permission
+	assert.Nil(t, child.Share(ChildShare{FamilyID: "2", Permission: ShareLog, SharedAt: now}))
+	assert.Len(t, child.Shares, 2)
+	assert.Equal(t, ShareLog, child.SharedWith("2").Permission)
+
+	assert.Equal(t, ErrNoShareFound, child.Accept("4", "4"))
+	assert.Nil(t, child.Share(ChildShare{FamilyID: "4", Permission: ShareView, SharedAt: now, Pending: true}))
+	assert.False(t, child.CanView("4"))
+	assert.Nil(t, child.Accept("4", "4"))
+	assert.Equal(t, ErrShareNotPending, child.Accept("4", "4"))
+	//sharing an accepted share again doesn't make the family accept it again
+	assert.Nil(t, child.Share(ChildShare{FamilyID: "4", Permission: ShareLog, SharedAt: now, Pending: true}))
+	assert.True(t, child.CanLog("4"))
+	assert.Equal(t, "4", child.SharedWith("4").AcceptedBy)
+
+	assert.Nil(t, child.Unshare("2"))
+	assert.Nil(t, child.SharedWith("2"))
+	assert.Equal(t, ErrNoShareFound, child.Unshare("2"))
+	assert.Len(t, child.Shares, 2)
+}
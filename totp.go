@@ -0,0 +1,165 @@
+package goparent
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//TOTP settings, the defaults authenticator apps expect (RFC 6238)
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+	//TOTPSkew - how many periods either side of now a code is still accepted for, to allow
+	// for clocks that are a little out
+	TOTPSkew = 1
+	//TOTPIssuer - the name authenticator apps show the account under
+	TOTPIssuer = "GoParent"
+	//RecoveryCodeCount - how many recovery codes a user gets when they turn on two factor
+	RecoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+//NewTOTPSecret - a random secret for a user's authenticator, base32 encoded
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+//TOTPCode - the code for the secret at time t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return totpCode(key, uint64(t.Unix())/uint64(TOTPPeriod.Seconds())), nil
+}
+
+func totpCode(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
+
+//ValidTOTP - whether the code is right for the secret at time t, give or take TOTPSkew periods
+func ValidTOTP(secret string, code string, t time.Time) bool {
+	_, valid := TOTPStep(secret, code, t)
+	return valid
+}
+
+//TOTPStep - the time step the code is for, if it's right for the secret at time t give or
+// take TOTPSkew periods
+func TOTPStep(secret string, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != TOTPDigits {
+		return 0, false
+	}
+	counter := int64(t.Unix()) / int64(TOTPPeriod.Seconds())
+	var step int64
+	valid := 0
+	for i := -TOTPSkew; i <= TOTPSkew; i++ {
+		match := subtle.ConstantTimeCompare([]byte(totpCode(key, uint64(counter+int64(i)))), []byte(code))
+		if match == 1 {
+			step = counter + int64(i)
+		}
+		valid |= match
+	}
+	return step, valid == 1
+}
+
+//TOTPURI - the otpauth:// uri authenticator apps are set up with, usually shown as a qr code
+func TOTPURI(secret string, account string) string {
+	label := url.PathEscape(TOTPIssuer + ":" + account)
+	params := url.Values{
+		"secret":    {secret},
+		"issuer":    {TOTPIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(TOTPDigits)},
+		"period":    {fmt.Sprint(int(TOTPPeriod.Seconds()))},
+	}
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+//NewRecoveryCodes - one time codes that get the user in when they don't have their
+// authenticator.  the codes are returned to be shown once, only their hashes are kept.
+func NewRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+//HashRecoveryCode - the hash a recovery code is kept as.  dashes, spaces and case are
+// ignored so codes can be typed however they were written down.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+//UseRecoveryCode - check a recovery code and use it up, each works once
+func (user *User) UseRecoveryCode(code string) bool {
+	hash := HashRecoveryCode(code)
+	for i, recoveryCode := range user.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			user.RecoveryCodes = append(user.RecoveryCodes[:i:i], user.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//UseTOTP - check an authenticator code and use it up.  a code is good for a few periods so
+// the step it was for is kept, and codes for that step or earlier don't work again.
+func (user *User) UseTOTP(code string, now time.Time) bool {
+	step, valid := TOTPStep(user.TOTPSecret, code, now)
+	if !valid || step <= user.TOTPLastStep {
+		return false
+	}
+	user.TOTPLastStep = step
+	return true
+}
+
+//CheckTwoFactor - whether code is an unused authenticator code or an unused recovery code.
+// either is used up, the user needs saving afterwards if the code was valid.
+func (user *User) CheckTwoFactor(code string, now time.Time) (valid bool, recovery bool) {
+	if user.UseTOTP(code, now) {
+		return true, false
+	}
+	if user.UseRecoveryCode(code) {
+		return true, true
+	}
+	return false, false
+}
@@ -0,0 +1,97 @@
+package goparent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//rfcSecret - the sha1 secret from the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	testCases := []struct {
+		desc string
+		at   int64
+		code string
+	}{
+		{desc: "59", at: 59, code: "287082"},
+		{desc: "1111111109", at: 1111111109, code: "081804"},
+		{desc: "1234567890", at: 1234567890, code: "005924"},
+		{desc: "2000000000", at: 2000000000, code: "279037"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			code, err := TOTPCode(rfcSecret, time.Unix(tC.at, 0))
+			assert.Nil(t, err)
+			assert.Equal(t, tC.code, code)
+		})
+	}
+}
+
+func TestValidTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	testCases := []struct {
+		desc  string
+		code  string
+		at    time.Time
+		valid bool
+	}{
+		{desc: "current", code: "005924", at: now, valid: true},
+		{desc: "a period late", code: "005924", at: now.Add(TOTPPeriod), valid: true},
+		{desc: "too late", code: "005924", at: now.Add(3 * TOTPPeriod)},
+		{desc: "wrong", code: "123456", at: now},
+		{desc: "too short", code: "5924", at: now},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.valid, ValidTOTP(rfcSecret, tC.code, tC.at))
+		})
+	}
+	assert.False(t, ValidTOTP("not base32!", "005924", now))
+}
+
+func TestUseTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	user := &User{TOTPSecret: rfcSecret}
+	assert.True(t, user.UseTOTP("005924", now))
+	assert.Equal(t, now.Unix()/30, user.TOTPLastStep)
+	assert.False(t, user.UseTOTP("005924", now), "a code only works once")
+	assert.False(t, user.UseTOTP("005924", now.Add(TOTPPeriod)), "not even in the next period")
+
+	//a code from the period before the last one used is no good either
+	earlier, _ := TOTPCode(rfcSecret, now.Add(-TOTPPeriod))
+	assert.False(t, user.UseTOTP(earlier, now))
+	next, _ := TOTPCode(rfcSecret, now.Add(TOTPPeriod))
+	assert.True(t, user.UseTOTP(next, now.Add(TOTPPeriod)))
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("ABC", "pat@test.com")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/GoParent:pat@test.com?"))
+	assert.Contains(t, uri, "secret=ABC")
+	assert.Contains(t, uri, "issuer=GoParent")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	assert.Nil(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+	assert.NotContains(t, hashes, codes[0])
+
+	user := &User{TOTPSecret: rfcSecret, RecoveryCodes: hashes}
+	valid, recovery := user.CheckTwoFactor(strings.ToUpper(strings.Replace(codes[3], "-", " ", 1)), time.Now())
+	assert.True(t, valid)
+	assert.True(t, recovery)
+	assert.Len(t, user.RecoveryCodes, RecoveryCodeCount-1)
+	assert.Equal(t, hashes[:3], user.RecoveryCodes[:3])
+
+	valid, _ = user.CheckTwoFactor(codes[3], time.Now())
+	assert.False(t, valid, "recovery codes only work once")
+
+	valid, recovery = user.CheckTwoFactor("005924", time.Unix(1234567890, 0))
+	assert.True(t, valid)
+	assert.False(t, recovery)
+}
@@ -0,0 +1,237 @@
+package goparent
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Unit - the unit a quantity was recorded in
+type Unit string
+
+const (
+	//UnitMilliliter - volume in milliliters
+	UnitMilliliter Unit = "ml"
+	//UnitFluidOunce - volume in US fluid ounces
+	UnitFluidOunce Unit = "floz"
+	//UnitMinute - duration in minutes, used for breast feedings
+	UnitMinute Unit = "min"
+	//UnitGram - mass in grams
+	UnitGram Unit = "g"
+	//UnitKilogram - mass in kilograms
+	UnitKilogram Unit = "kg"
+	//UnitPound - mass in pounds
+	UnitPound Unit = "lb"
+	//UnitCentimeter - length in centimeters
+	UnitCentimeter Unit = "cm"
+	//UnitInch - length in inches
+	UnitInch Unit = "in"
+)
+
+//Dimension - what a unit measures, only units of the same dimension can be converted or added.
+type Dimension string
+
+const (
+	//DimensionVolume - ml, floz
+	DimensionVolume Dimension = "volume"
+	//DimensionDuration - min
+	DimensionDuration Dimension = "duration"
+	//DimensionMass - g, kg, lb
+	DimensionMass Dimension = "mass"
+	//DimensionLength - cm, in
+	DimensionLength Dimension = "length"
+)
+
+//UnitSystem - a family's preference for the units quantities are displayed in
+type UnitSystem string
+
+const (
+	//UnitSystemRecorded - show quantities in the units they were recorded in
+	UnitSystemRecorded UnitSystem = ""
+	//UnitSystemMetric - ml, g and cm
+	UnitSystemMetric UnitSystem = "metric"
+	//UnitSystemImperial - floz, lb and in
+	UnitSystemImperial UnitSystem = "imperial"
+)
+
+//ErrInvalidUnit - a unit we don't know about
+var ErrInvalidUnit = errors.New("invalid unit")
+
+//ErrIncompatibleUnits - trying to convert between units of different dimensions, ie minutes to ounces
+var ErrIncompatibleUnits = errors.New("incompatible units")
+
+//ErrInvalidUnitSystem - a unit system we don't know about
+var ErrInvalidUnitSystem = errors.New("invalid unit system, must be metric or imperial")
+
+//unitDefinitions - the dimension of each unit and how many of the dimension's base unit it is.
+var unitDefinitions = map[Unit]struct {
+	dimension Dimension
+	base      float64
+}{
+	UnitMilliliter: {DimensionVolume, 1},
+	UnitFluidOunce: {DimensionVolume, 29.5735295625},
+	UnitMinute:     {DimensionDuration, 1},
+	UnitGram:       {DimensionMass, 1},
+	UnitKilogram:   {DimensionMass, 1000},
+	UnitPound:      {DimensionMass, 453.59237},
+	UnitCentimeter: {DimensionLength, 1},
+	UnitInch:       {DimensionLength, 2.54},
+}
+
+//Valid - is this a unit we know about
+func (u Unit) Valid() bool {
+	_, ok := unitDefinitions[u]
+	return ok
+}
+
+//Dimension - the dimension the unit measures, blank for unknown units
+func (u Unit) Dimension() Dimension {
+	return unitDefinitions[u].dimension
+}
+
+//BaseUnit - the unit totals are kept in when a dimension has no better default
+func (d Dimension) BaseUnit() Unit {
+	switch d {
+	case DimensionVolume:
+		return UnitMilliliter
+	case DimensionDuration:
+		return UnitMinute
+	case DimensionMass:
+		return UnitGram
+	case DimensionLength:
+		return UnitCentimeter
+	}
+	return ""
+}
+
+//Convert - convert an amount from one unit to another of the same dimension
+func Convert(amount float32, from Unit, to Unit) (float32, error) {
+	if from == to {
+		return amount, nil
+	}
+	f, ok := unitDefinitions[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUnit, from)
+	}
+	t, ok := unitDefinitions[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUnit, to)
+	}
+	if f.dimension != t.dimension {
+		return 0, fmt.Errorf("%w: %s to %s", ErrIncompatibleUnits, from, to)
+	}
+	return float32(float64(amount) * f.base / t.base), nil
+}
+
+//Valid - is this a unit system we know about, the recorded system is valid
+func (s UnitSystem) Valid() bool {
+	switch s {
+	case UnitSystemRecorded, UnitSystemMetric, UnitSystemImperial:
+		return true
+	}
+	return false
+}
+
+//Unit - the unit this system displays a dimension in.  durations are always minutes.
+// the recorded system returns the unit passed in.
+func (s UnitSystem) Unit(recorded Unit) Unit {
+	d := recorded.Dimension()
+	switch {
+	case s == UnitSystemMetric && d == DimensionVolume:
+		return UnitMilliliter
+	case s == UnitSystemMetric && d == DimensionMass:
+		return UnitGram
+	case s == UnitSystemMetric && d == DimensionLength:
+		return UnitCentimeter
+	case s == UnitSystemImperial && d == DimensionVolume:
+		return UnitFluidOunce
+	case s == UnitSystemImperial && d == DimensionMass:
+		return UnitPound
+	case s == UnitSystemImperial && d == DimensionLength:
+		return UnitInch
+	}
+	return recorded
+}
+
+//DefaultFeedingUnit - the unit a feeding type is recorded in when none is given.  this
+// matches how feedings were recorded before units were stored: breast feedings in minutes,
+// solids in grams and everything else in ounces.
+func DefaultFeedingUnit(feedingType string) Unit {
+	switch feedingType {
+	case "breast":
+		return UnitMinute
+	case "solid":
+		return UnitGram
+	}
+	return UnitFluidOunce
+}
+
+//AmountUnit - the unit the feeding amount is in, older feedings without a unit use the type's default.
+func (feeding Feeding) AmountUnit() Unit {
+	if feeding.Unit != "" {
+		return feeding.Unit
+	}
+	return DefaultFeedingUnit(feeding.Type)
+}
+
+//In - the feeding with its amount converted to the unit system
+func (feeding Feeding) In(system UnitSystem) Feeding {
+	from := feeding.AmountUnit()
+	to := system.Unit(from)
+	amount, err := Convert(feeding.Amount, from, to)
+	if err != nil {
+		//unknown units are shown as they were recorded
+		return feeding
+	}
+	feeding.Amount = amount
+	feeding.Unit = to
+	return feeding
+}
+
+//feedingTotalKey - the summary key and unit a feeding is totaled under.  feedings are totaled
+// by type, and anything recorded in a different dimension than the type's default is totaled
+// separately under "type:dimension" so minutes are never added to ounces.
+func feedingTotalKey(feeding Feeding) (string, Unit) {
+	unit := feeding.AmountUnit()
+	keyUnit := DefaultFeedingUnit(feeding.Type)
+	if unit.Dimension() == keyUnit.Dimension() {
+		return feeding.Type, keyUnit
+	}
+	return fmt.Sprintf("%s:%s", feeding.Type, unit.Dimension()), unit.Dimension().BaseUnit()
+}
+
+//In - the summary with its totals, means and data converted to the unit system
+func (summary *FeedingSummary) In(system UnitSystem) *FeedingSummary {
+	converted := &FeedingSummary{
+		Data:  make([]Feeding, len(summary.Data)),
+		Total: make(map[string]float32),
+		Mean:  make(map[string]float32),
+		Range: summary.Range,
+		Unit:  make(map[string]Unit),
+	}
+	for i, feeding := range summary.Data {
+		converted.Data[i] = feeding.In(system)
+	}
+	for key, unit := range summary.Unit {
+		to := system.Unit(unit)
+		converted.Unit[key] = to
+		converted.Total[key], _ = Convert(summary.Total[key], unit, to)
+		converted.Mean[key], _ = Convert(summary.Mean[key], unit, to)
+	}
+	return converted
+}
+
+//In - the chart data with its sums converted to the unit system
+func (chartData *FeedingChartData) In(system UnitSystem) *FeedingChartData {
+	converted := *chartData
+	converted.Dataset = make([]FeedingChartDataset, len(chartData.Dataset))
+	for i, dataset := range chartData.Dataset {
+		to := system.Unit(dataset.Unit)
+		sum, err := Convert(dataset.Sum, dataset.Unit, to)
+		if err == nil {
+			dataset.Sum = sum
+			dataset.Unit = to
+		}
+		converted.Dataset[i] = dataset
+	}
+	return &converted
+}
@@ -0,0 +1,103 @@
+package goparent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		amount float32
+		from   Unit
+		to     Unit
+		result float32
+		err    error
+	}{
+		{desc: "ounces to ml", amount: 4, from: UnitFluidOunce, to: UnitMilliliter, result: 118.29412},
+		{desc: "ml to ounces", amount: 120, from: UnitMilliliter, to: UnitFluidOunce, result: 4.057682},
+		{desc: "kg to lb", amount: 3.5, from: UnitKilogram, to: UnitPound, result: 7.716179},
+		{desc: "in to cm", amount: 20, from: UnitInch, to: UnitCentimeter, result: 50.8},
+		{desc: "same unit", amount: 15, from: UnitMinute, to: UnitMinute, result: 15},
+		{desc: "minutes to ounces", amount: 15, from: UnitMinute, to: UnitFluidOunce, err: ErrIncompatibleUnits},
+		{desc: "unknown unit", amount: 1, from: "cups", to: UnitMilliliter, err: ErrInvalidUnit},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result, err := Convert(tC.amount, tC.from, tC.to)
+			if tC.err != nil {
+				assert.ErrorIs(t, err, tC.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.InDelta(t, tC.result, result, 0.0001)
+		})
+	}
+}
+
+func TestUnitSystemUnit(t *testing.T) {
+	assert.Equal(t, UnitMilliliter, UnitSystemMetric.Unit(UnitFluidOunce))
+	assert.Equal(t, UnitFluidOunce, UnitSystemImperial.Unit(UnitMilliliter))
+	assert.Equal(t, UnitPound, UnitSystemImperial.Unit(UnitGram))
+	assert.Equal(t, UnitMinute, UnitSystemMetric.Unit(UnitMinute))
+	assert.Equal(t, UnitKilogram, UnitSystemRecorded.Unit(UnitKilogram))
+	assert.False(t, UnitSystem("cubits").Valid())
+}
+
+func TestFeedingSummaryUnits(t *testing.T) {
+	now := time.Now()
+	feedings := []Feeding{
+		{Type: "bottle", Amount: 4, Unit: UnitFluidOunce, TimeStamp: now},
+		{Type: "bottle", Amount: 60, Unit: UnitMilliliter, TimeStamp: now},
+		//older feedings without a unit use the type's default
+		{Type: "breast", Amount: 10, TimeStamp: now},
+		{Type: "breast", Amount: 20, Unit: UnitMinute, TimeStamp: now},
+		//a pumped breast feeding recorded as a volume is never added to the minutes
+		{Type: "breast", Amount: 2, Unit: UnitFluidOunce, TimeStamp: now},
+	}
+
+	summary := NewFeedingSummary(feedings)
+	assert.InDelta(t, 6.028841, summary.Total["bottle"], 0.0001)
+	assert.Equal(t, UnitFluidOunce, summary.Unit["bottle"])
+	assert.Equal(t, 2, summary.Range["bottle"])
+	assert.InDelta(t, 30, summary.Total["breast"], 0.0001)
+	assert.InDelta(t, 15, summary.Mean["breast"], 0.0001)
+	assert.Equal(t, UnitMinute, summary.Unit["breast"])
+	assert.InDelta(t, 59.147059, summary.Total["breast:volume"], 0.0001)
+	assert.Equal(t, UnitMilliliter, summary.Unit["breast:volume"])
+
+	metric := summary.In(UnitSystemMetric)
+	assert.InDelta(t, 178.29412, metric.Total["bottle"], 0.001)
+	assert.Equal(t, UnitMilliliter, metric.Unit["bottle"])
+	assert.InDelta(t, 30, metric.Total["breast"], 0.0001)
+	assert.Equal(t, UnitMinute, metric.Unit["breast"])
+	assert.Equal(t, UnitMilliliter, metric.Data[0].Unit)
+	assert.Equal(t, UnitMinute, metric.Data[2].Unit)
+	//converting doesn't touch the original
+	assert.Equal(t, UnitFluidOunce, summary.Data[0].Unit)
+}
+
+func TestFeedingChartDataUnits(t *testing.T) {
+	q := &DataQuery{
+		Start:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC),
+		Granularity: GranularityDay,
+	}
+	feedings := []Feeding{
+		{Type: "bottle", Amount: 4, Unit: UnitFluidOunce, TimeStamp: time.Date(2018, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{Type: "bottle", Amount: 120, Unit: UnitMilliliter, TimeStamp: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{Type: "breast", Amount: 15, TimeStamp: time.Date(2018, 1, 1, 14, 0, 0, 0, time.UTC)},
+	}
+
+	chartData := NewFeedingChartData(q, feedings).In(UnitSystemMetric)
+	assert.Len(t, chartData.Dataset, 2)
+	assert.Equal(t, "bottle", chartData.Dataset[0].Type)
+	assert.InDelta(t, 238.29412, chartData.Dataset[0].Sum, 0.001)
+	assert.Equal(t, UnitMilliliter, chartData.Dataset[0].Unit)
+	assert.Equal(t, 2, chartData.Dataset[0].Count)
+	assert.Equal(t, "breast", chartData.Dataset[1].Type)
+	assert.InDelta(t, 15, chartData.Dataset[1].Sum, 0.0001)
+	assert.Equal(t, UnitMinute, chartData.Dataset[1].Unit)
+}
@@ -0,0 +1,210 @@
+package goparent
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+//FeedingTypes - the kinds of feedings we track
+var FeedingTypes = []string{"bottle", "breast", "solid"}
+
+//FeedingSides - the side of a breast feeding, blank for other types
+var FeedingSides = []string{"", "left", "right"}
+
+//MinWasteType and MaxWasteType - the range of waste types, 1 is wet, 2 is solid and 3 is both
+const (
+	MinWasteType = 1
+	MaxWasteType = 3
+)
+
+//AllowedClockSkew - how far in the future a timestamp can be before it's rejected, clocks on
+// phones and the server are never quite in sync.
+const AllowedClockSkew = 5 * time.Minute
+
+//ErrNoChildFound - there is no child with that id
+var ErrNoChildFound = errors.New("no child found with that id")
+
+//FieldError - a problem with one field of a request, Field is the json name of the field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//ValidationError - all of the problems found with an entity
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = fmt.Sprintf("%s %s", field.Field, field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+//NewValidationError - a validation error for a single field
+func NewValidationError(field string, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+//Validator - an entity that can check itself before it's saved.  now is passed in
+// so timestamps can be checked against the request time.
+type Validator interface {
+	Validate(now time.Time) error
+}
+
+//rule - a single check on a field, nil if the field is fine
+type rule func() *FieldError
+
+//validate - run all of the rules and collect the failures
+func validate(rules ...rule) error {
+	var fields []FieldError
+	for _, r := range rules {
+		if fieldErr := r(); fieldErr != nil {
+			fields = append(fields, *fieldErr)
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func required(field string, value string) rule {
+	return func() *FieldError {
+		if strings.TrimSpace(value) == "" {
+			return &FieldError{field, "is required"}
+		}
+		return nil
+	}
+}
+
+func emailAddress(field string, value string) rule {
+	return func() *FieldError {
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return &FieldError{field, "must be an email address"}
+		}
+		return nil
+	}
+}
+
+func oneOf(field string, value string, allowed []string) rule {
+	return func() *FieldError {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return &FieldError{field, fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))}
+	}
+}
+
+func between(field string, value int, min int, max int) rule {
+	return func() *FieldError {
+		if value < min || value > max {
+			return &FieldError{field, fmt.Sprintf("must be between %d and %d", min, max)}
+		}
+		return nil
+	}
+}
+
+func notNegative(field string, value float32) rule {
+	return func() *FieldError {
+		if value < 0 {
+			return &FieldError{field, "must not be negative"}
+		}
+		return nil
+	}
+}
+
+func validUnit(field string, unit Unit) rule {
+	return func() *FieldError {
+		if unit != "" && !unit.Valid() {
+			return &FieldError{field, ErrInvalidUnit.Error()}
+		}
+		return nil
+	}
+}
+
+func requiredTime(field string, t time.Time) rule {
+	return func() *FieldError {
+		if t.IsZero() {
+			return &FieldError{field, "is required"}
+		}
+		return nil
+	}
+}
+
+func notFuture(field string, t time.Time, now time.Time) rule {
+	return func() *FieldError {
+		if t.After(now.Add(AllowedClockSkew)) {
+			return &FieldError{field, "must not be in the future"}
+		}
+		return nil
+	}
+}
+
+//after - t must be after other, a zero t is skipped so optional times can use it
+func after(field string, t time.Time, otherField string, other time.Time) rule {
+	return func() *FieldError {
+		if !t.IsZero() && !t.After(other) {
+			return &FieldError{field, fmt.Sprintf("must be after %s", otherField)}
+		}
+		return nil
+	}
+}
+
+//Validate - check a feeding before it's saved
+func (feeding Feeding) Validate(now time.Time) error {
+	return validate(
+		required("childID", feeding.ChildID),
+		oneOf("feedingType", feeding.Type, FeedingTypes),
+		notNegative("feedingAmount", feeding.Amount),
+		validUnit("feedingUnit", feeding.Unit),
+		oneOf("feedingSide", feeding.Side, FeedingSides),
+		requiredTime("timestamp", feeding.TimeStamp),
+		notFuture("timestamp", feeding.TimeStamp, now),
+	)
+}
+
+//Validate - check a sleep before it's saved, sleeps that are still going have no end
+func (sleep Sleep) Validate(now time.Time) error {
+	return validate(
+		required("childID", sleep.ChildID),
+		requiredTime("start", sleep.Start),
+		notFuture("start", sleep.Start, now),
+		after("end", sleep.End, "start", sleep.Start),
+		notFuture("end", sleep.End, now),
+	)
+}
+
+//Validate - check a waste before it's saved
+func (waste Waste) Validate(now time.Time) error {
+	return validate(
+		required("childid", waste.ChildID),
+		between("wasteType", waste.Type, MinWasteType, MaxWasteType),
+		requiredTime("timestamp", waste.TimeStamp),
+		notFuture("timestamp", waste.TimeStamp, now),
+	)
+}
+
+//Validate - check a child before it's saved
+func (child Child) Validate(now time.Time) error {
+	return validate(
+		required("name", child.Name),
+		notFuture("birthday", child.Birthday, now),
+	)
+}
+
+//Validate - check a user's profile before it's saved
+func (user User) Validate(now time.Time) error {
+	return validate(
+		required("name", user.Name),
+		emailAddress("email", user.Email),
+	)
+}
@@ -0,0 +1,103 @@
+package goparent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2018, 7, 15, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc   string
+		entity Validator
+		fields []string
+	}{
+		{
+			desc:   "valid feeding",
+			entity: Feeding{ChildID: "1", Type: "bottle", Amount: 4, Unit: UnitFluidOunce, TimeStamp: now},
+		},
+		{
+			desc:   "valid breast feeding a minute ahead of the server clock",
+			entity: Feeding{ChildID: "1", Type: "breast", Side: "left", Amount: 15, TimeStamp: now.Add(time.Minute)},
+		},
+		{
+			desc:   "invalid feeding",
+			entity: Feeding{Type: "soda", Amount: -1, Unit: "cups", Side: "middle", TimeStamp: now.Add(time.Hour)},
+			fields: []string{"childID", "feedingType", "feedingAmount", "feedingUnit", "feedingSide", "timestamp"},
+		},
+		{
+			desc:   "feeding without a timestamp",
+			entity: Feeding{ChildID: "1", Type: "bottle", Amount: 4},
+			fields: []string{"timestamp"},
+		},
+		{
+			desc:   "valid sleep",
+			entity: Sleep{ChildID: "1", Start: now.Add(-time.Hour), End: now},
+		},
+		{
+			desc:   "active sleep",
+			entity: Sleep{ChildID: "1", Start: now.Add(-time.Hour)},
+		},
+		{
+			desc:   "sleep ending before it starts",
+			entity: Sleep{ChildID: "1", Start: now.Add(-time.Hour), End: now.Add(-2 * time.Hour)},
+			fields: []string{"end"},
+		},
+		{
+			desc:   "sleep in the future",
+			entity: Sleep{ChildID: "1", Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+			fields: []string{"start", "end"},
+		},
+		{
+			desc:   "valid waste",
+			entity: Waste{ChildID: "1", Type: 3, TimeStamp: now},
+		},
+		{
+			desc:   "invalid waste",
+			entity: Waste{Type: 4, TimeStamp: now},
+			fields: []string{"childid", "wasteType"},
+		},
+		{
+			desc:   "valid child",
+			entity: Child{Name: "Billy", Birthday: now.AddDate(-1, 0, 0)},
+		},
+		{
+			desc:   "invalid child",
+			entity: Child{Name: "  ", Birthday: now.AddDate(1, 0, 0)},
+			fields: []string{"name", "birthday"},
+		},
+		{
+			desc:   "valid user",
+			entity: User{Name: "test user", Email: "testuser@test.com"},
+		},
+		{
+			desc:   "invalid user",
+			entity: User{Email: "Test User <testuser@test.com>"},
+			fields: []string{"name", "email"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.entity.Validate(now)
+			if tC.fields == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			var fields []string
+			for _, field := range validationErr.Fields {
+				fields = append(fields, field.Field)
+			}
+			assert.Equal(t, tC.fields, fields)
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := Waste{Type: 9, TimeStamp: time.Now()}.Validate(time.Now())
+	assert.EqualError(t, err, "validation failed: childid is required, wasteType must be between 1 and 3")
+}
@@ -0,0 +1,327 @@
+package goparent
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//Webhook headers sent with every delivery.  the signature is the hex HMAC-SHA256 of the
+// body with the webhook's secret, prefixed with sha256=.
+const (
+	WebhookEventHeader     = "X-Goparent-Event"
+	WebhookDeliveryHeader  = "X-Goparent-Delivery"
+	WebhookSignatureHeader = "X-Goparent-Signature"
+)
+
+//ErrNoWebhookFound - there is no webhook with that id
+var ErrNoWebhookFound = errors.New("no webhook found with that id")
+
+//Webhook - a url the family's events are posted to.  Events filters which events are sent,
+// e.g. sleep.started or sleep.* for all sleep events, and an empty filter sends everything.
+type Webhook struct {
+	ID          string    `json:"id" gorethink:"id,omitempty"`
+	FamilyID    string    `json:"familyID" gorethink:"familyID"`
+	URL         string    `json:"url" gorethink:"url"`
+	Secret      string    `json:"secret,omitempty" gorethink:"secret"`
+	Events      []string  `json:"events" gorethink:"events"`
+	Disabled    bool      `json:"disabled" gorethink:"disabled"`
+	CreatedAt   time.Time `json:"createdAt" gorethink:"createdAt"`
+	LastUpdated time.Time `json:"lastUpdated" gorethink:"lastUpdated"`
+}
+
+//WebhookDelivery - one attempt to post an event to a webhook.  StatusCode is 0 when the
+// receiver couldn't be reached, Error says why.
+type WebhookDelivery struct {
+	ID         string    `json:"id" gorethink:"id,omitempty"`
+	WebhookID  string    `json:"webhookID" gorethink:"webhookID"`
+	FamilyID   string    `json:"familyID" gorethink:"familyID"`
+	EventID    string    `json:"eventID" gorethink:"eventID"`
+	Event      string    `json:"event" gorethink:"event"`
+	Attempt    int       `json:"attempt" gorethink:"attempt"`
+	StatusCode int       `json:"statusCode" gorethink:"statusCode"`
+	Error      string    `json:"error,omitempty" gorethink:"error"`
+	Duration   int64     `json:"duration" gorethink:"duration"`
+	Timestamp  time.Time `json:"timestamp" gorethink:"timestamp"`
+}
+
+//Succeeded - whether the receiver took the event
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+//retryable - whether trying again later might work.  other client errors mean the
+// receiver turned the event down.
+func (d *WebhookDelivery) retryable() bool {
+	return d.StatusCode == 0 || d.StatusCode == http.StatusTooManyRequests || d.StatusCode >= 500
+}
+
+//WebhookService -
+type WebhookService interface {
+	Save(context.Context, *Webhook) error
+	Webhook(context.Context, string) (*Webhook, error)
+	Webhooks(context.Context, *Family) ([]*Webhook, error)
+	SaveDelivery(context.Context, *WebhookDelivery) error
+	Deliveries(context.Context, *Webhook, int) ([]*WebhookDelivery, error)
+}
+
+//Matches - whether the event passes the webhook's filter
+func (webhook *Webhook) Matches(event Event) bool {
+	if webhook.Disabled || !contains(event.Families(), webhook.FamilyID) {
+		return false
+	}
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, filter := range webhook.Events {
+		if filter == "*" || filter == event.Name() || filter == event.Resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+//WebhookPing - the webhook.ping event sent when a webhook is tested
+func WebhookPing(webhook *Webhook) Event {
+	return Event{
+		ID:       uuid.New().String(),
+		Type:     "ping",
+		Resource: "webhook",
+		FamilyID: webhook.FamilyID,
+		Time:     time.Now(),
+		Data:     map[string]string{"webhookID": webhook.ID, "url": webhook.URL},
+	}
+}
+
+//NewWebhookSecret - a random secret for signing deliveries
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//SignWebhook - the signature header value for a body
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//VerifyWebhook - check a signature header against the body, for receivers
+func VerifyWebhook(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, body)), []byte(signature))
+}
+
+//Validate - check a webhook before it's saved
+func (webhook Webhook) Validate(now time.Time) error {
+	return validate(
+		webhookURL("url", webhook.URL),
+		eventFilters("events", webhook.Events),
+	)
+}
+
+func webhookURL(field string, value string) rule {
+	return func() *FieldError {
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &FieldError{field, "must be an http or https url"}
+		}
+		return nil
+	}
+}
+
+var eventResources = []string{ResourceFamily, ResourceChild, ResourceFeeding, ResourceSleep, ResourceWaste, ResourceReminder}
+
+var eventTypes = []string{string(EventCreated), string(EventUpdated), string(EventDeleted), string(EventStarted), string(EventEnded), string(EventDue), "*"}
+
+func eventFilters(field string, filters []string) rule {
+	return func() *FieldError {
+		for _, filter := range filters {
+			if filter == "*" {
+				continue
+			}
+			parts := strings.Split(filter, ".")
+			if len(parts) != 2 || oneOf(field, parts[0], eventResources)() != nil || oneOf(field, parts[1], eventTypes)() != nil {
+				return &FieldError{field, "must be resource.type filters like sleep.started or sleep.*"}
+			}
+		}
+		return nil
+	}
+}
+
+//WebhookLease - the lease the dispatcher that delivers events holds, and how long it's held
+// for between renewals
+const (
+	WebhookLease    = "webhooks"
+	WebhookLeaseTTL = 30 * time.Second
+)
+
+//WebhookDispatcher - posts events to the webhooks that want them.  failed deliveries are
+// retried with exponential backoff and every attempt is saved to the delivery log.
+type WebhookDispatcher struct {
+	Service WebhookService
+	//Leases - every instance of the service sees every event, only the one holding
+	// WebhookLease delivers them.  all of them deliver if it isn't set.
+	Leases LeaseStore
+	//Holder - who the dispatcher holds the lease as, a random id if it isn't set
+	Holder      string
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+//Run - deliver events from the bus until the context is done
+func (d *WebhookDispatcher) Run(ctx context.Context, bus EventBus) {
+	events, cancel := bus.Subscribe("")
+	defer cancel()
+	holder := d.Holder
+	if holder == "" {
+		holder = uuid.New().String()
+	}
+	delivering := d.holdLease(ctx, holder)
+	var renew <-chan time.Time
+	if d.Leases != nil {
+		ticker := time.NewTicker(WebhookLeaseTTL / 3)
+		defer ticker.Stop()
+		renew = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-renew:
+			delivering = d.holdLease(ctx, holder)
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if delivering {
+				d.Dispatch(ctx, event)
+			}
+		}
+	}
+}
+
+//holdLease - whether the dispatcher delivers events, taking or renewing the lease if
+// there is one.  it doesn't deliver when the lease can't be checked, another one might.
+func (d *WebhookDispatcher) holdLease(ctx context.Context, holder string) bool {
+	if d.Leases == nil {
+		return true
+	}
+	now := time.Now()
+	lease, err := d.Leases.Hold(ctx, WebhookLease, holder, now, WebhookLeaseTTL)
+	if err != nil {
+		log.Printf("holding the webhook lease: %s", err)
+		return false
+	}
+	return lease.HeldBy(holder, now)
+}
+
+//Dispatch - deliver an event to the matching webhooks of each of its families in the background
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event Event) {
+	for _, familyID := range event.Families() {
+		webhooks, err := d.Service.Webhooks(ctx, &Family{ID: familyID})
+		if err != nil {
+			log.Printf("webhooks for family %s: %s", familyID, err)
+			continue
+		}
+		for _, webhook := range webhooks {
+			if webhook.Matches(event) {
+				go d.Deliver(ctx, webhook, event)
+			}
+		}
+	}
+}
+
+//Deliver - post the event until the receiver takes it, turns it down or MaxAttempts is used up
+func (d *WebhookDispatcher) Deliver(ctx context.Context, webhook *Webhook, event Event) *WebhookDelivery {
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var delivery *WebhookDelivery
+	for attempt := 1; ; attempt++ {
+		delivery = d.send(ctx, webhook, event, attempt)
+		if delivery.Succeeded() || !delivery.retryable() || attempt >= maxAttempts {
+			return delivery
+		}
+		select {
+		case <-ctx.Done():
+			return delivery
+		case <-time.After(backoff << uint(attempt-1)):
+		}
+	}
+}
+
+//Send - post the event once, for testing a webhook
+func (d *WebhookDispatcher) Send(ctx context.Context, webhook *Webhook, event Event) *WebhookDelivery {
+	return d.send(ctx, webhook, event, 1)
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *Webhook, event Event, attempt int) *WebhookDelivery {
+	delivery := &WebhookDelivery{
+		WebhookID: webhook.ID,
+		FamilyID:  webhook.FamilyID,
+		EventID:   event.ID,
+		Event:     event.Name(),
+		Attempt:   attempt,
+		Timestamp: time.Now(),
+	}
+	delivery.StatusCode, delivery.Error = d.post(ctx, webhook, event)
+	delivery.Duration = int64(time.Since(delivery.Timestamp) / time.Millisecond)
+
+	err := d.Service.SaveDelivery(ctx, delivery)
+	if err != nil {
+		log.Printf("saving delivery of %s to webhook %s: %s", delivery.Event, webhook.ID, err)
+	}
+	return delivery
+}
+
+//post - the status the receiver answered with, or why it couldn't be reached
+func (d *WebhookDispatcher) post(ctx context.Context, webhook *Webhook, event Event) (int, string) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err.Error()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event.Name())
+	req.Header.Set(WebhookDeliveryHeader, uuid.New().String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, body))
+
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	return resp.StatusCode, ""
+}
@@ -0,0 +1,288 @@
+package goparent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//testWebhookService - keeps the delivery log in memory
+type testWebhookService struct {
+	WebhookService
+	mu         sync.Mutex
+	webhooks   []*Webhook
+	deliveries []*WebhookDelivery
+}
+
+func (s *testWebhookService) Webhooks(ctx context.Context, family *Family) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.FamilyID == family.ID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (s *testWebhookService) SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *testWebhookService) attempts() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var codes []int
+	for _, d := range s.deliveries {
+		codes = append(codes, d.StatusCode)
+	}
+	return codes
+}
+
+//testLeaseStore - keeps the leases in memory, each Hold is sent on held if it's set
+type testLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]Lease
+	held   chan string
+}
+
+func (s *testLeaseStore) Hold(ctx context.Context, name string, holder string, now time.Time, ttl time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leases == nil {
+		s.leases = make(map[string]Lease)
+	}
+	lease, ok := s.leases[name]
+	if !ok || lease.HeldBy(holder, now) || !now.Before(lease.Expires) {
+		lease = Lease{Name: name, Holder: holder, Expires: now.Add(ttl)}
+		s.leases[name] = lease
+	}
+	if s.held != nil {
+		s.held <- holder
+	}
+	return lease, nil
+}
+
+func TestWebhookMatches(t *testing.T) {
+	started := Event{Type: EventStarted, Resource: ResourceSleep, FamilyID: "1"}
+	testCases := []struct {
+		desc    string
+		webhook Webhook
+		event   Event
+		matches bool
+	}{
+		{desc: "no filter", webhook: Webhook{FamilyID: "1"}, event: started, matches: true},
+		{desc: "exact", webhook: Webhook{FamilyID: "1", Events: []string{"feeding.created", "sleep.started"}}, event: started, matches: true},
+		{desc: "resource wildcard", webhook: Webhook{FamilyID: "1", Events: []string{"sleep.*"}}, event: started, matches: true},
+		{desc: "everything", webhook: Webhook{FamilyID: "1", Events: []string{"*"}}, event: started, matches: true},
+		{desc: "filtered out", webhook: Webhook{FamilyID: "1", Events: []string{"sleep.ended"}}, event: started},
+		{desc: "disabled", webhook: Webhook{FamilyID: "1", Disabled: true}, event: started},
+		{desc: "other family", webhook: Webhook{FamilyID: "2"}, event: started},
+		{desc: "family the child is shared with", webhook: Webhook{FamilyID: "2"}, event: Event{Type: EventStarted, Resource: ResourceSleep, FamilyID: "1", SharedWith: []string{"2"}}, matches: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.matches, tC.webhook.Matches(tC.event))
+		})
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		webhook Webhook
+		fields  []string
+	}{
+		{desc: "valid", webhook: Webhook{URL: "https://example.com/hook", Events: []string{"sleep.*", "feeding.created", "*"}}},
+		{desc: "no url", webhook: Webhook{}, fields: []string{"url"}},
+		{desc: "not http", webhook: Webhook{URL: "ftp://example.com"}, fields: []string{"url"}},
+		{desc: "bad filter", webhook: Webhook{URL: "http://localhost:8080", Events: []string{"nap.started"}}, fields: []string{"events"}},
+		{desc: "bad type", webhook: Webhook{URL: "http://localhost:8080", Events: []string{"sleep"}}, fields: []string{"events"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.webhook.Validate(time.Now())
+			if tC.fields == nil {
+				assert.Nil(t, err)
+				return
+			}
+			if validationErr, ok := err.(*ValidationError); assert.True(t, ok) {
+				var fields []string
+				for _, f := range validationErr.Fields {
+					fields = append(fields, f.Field)
+				}
+				assert.Equal(t, tC.fields, fields)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		responses []int
+		attempts  []int
+		succeeded bool
+	}{
+		{desc: "delivered", responses: []int{200}, attempts: []int{200}, succeeded: true},
+		{desc: "retried until delivered", responses: []int{503, 500, 204}, attempts: []int{503, 500, 204}, succeeded: true},
+		{desc: "turned down", responses: []int{400}, attempts: []int{400}},
+		{desc: "gives up", responses: []int{503, 503, 503, 503}, attempts: []int{503, 503, 503}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			secret := "test secret"
+			var mu sync.Mutex
+			calls := 0
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				assert.True(t, VerifyWebhook(secret, body, r.Header.Get(WebhookSignatureHeader)))
+				assert.Equal(t, "sleep.started", r.Header.Get(WebhookEventHeader))
+				var event Event
+				assert.Nil(t, json.Unmarshal(body, &event))
+				assert.Equal(t, "1", event.ID)
+
+				mu.Lock()
+				defer mu.Unlock()
+				w.WriteHeader(tC.responses[calls])
+				calls++
+			}))
+			defer receiver.Close()
+
+			service := &testWebhookService{}
+			d := &WebhookDispatcher{Service: service, MaxAttempts: 3, Backoff: time.Millisecond}
+			webhook := &Webhook{ID: "1", FamilyID: "1", URL: receiver.URL, Secret: secret}
+			delivery := d.Deliver(context.Background(), webhook, Event{ID: "1", Type: EventStarted, Resource: ResourceSleep, FamilyID: "1"})
+			assert.Equal(t, tC.succeeded, delivery.Succeeded())
+			assert.Equal(t, len(tC.attempts), delivery.Attempt)
+			assert.Equal(t, tC.attempts, service.attempts())
+		})
+	}
+}
+
+func TestWebhookUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiver.Close()
+
+	service := &testWebhookService{}
+	d := &WebhookDispatcher{Service: service, MaxAttempts: 2, Backoff: time.Millisecond}
+	delivery := d.Deliver(context.Background(), &Webhook{ID: "1", URL: receiver.URL}, Event{ID: "1"})
+	assert.False(t, delivery.Succeeded())
+	assert.Equal(t, 0, delivery.StatusCode)
+	assert.NotEmpty(t, delivery.Error)
+	assert.Equal(t, []int{0, 0}, service.attempts())
+}
+
+func TestWebhookDispatcherRun(t *testing.T) {
+	received := make(chan string, 100)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(WebhookEventHeader)
+	}))
+	defer receiver.Close()
+
+	service := &testWebhookService{webhooks: []*Webhook{
+		{ID: "1", FamilyID: "1", URL: receiver.URL, Events: []string{"sleep.*"}},
+		{ID: "2", FamilyID: "1", URL: receiver.URL, Disabled: true},
+		{ID: "3", FamilyID: "2", URL: receiver.URL, Events: []string{"waste.*"}},
+	}}
+	bus := NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go (&WebhookDispatcher{Service: service}).Run(ctx, bus)
+
+	//events published before Run subscribes are missed, so keep publishing until one arrives
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for len(received) == 0 {
+		select {
+		case <-ticker.C:
+			bus.Publish(Event{Type: EventCreated, Resource: ResourceFeeding, FamilyID: "1"})
+			bus.Publish(Event{Type: EventStarted, Resource: ResourceSleep, FamilyID: "1"})
+		case <-timeout:
+			t.Fatal("no delivery")
+		}
+	}
+	assert.Equal(t, "sleep.started", <-received)
+
+	//a diaper the child's own family logs reaches the household it's shared with, sleeps
+	// from the loop above can still be arriving
+	bus.Publish(Event{Type: EventCreated, Resource: ResourceWaste, FamilyID: "1", SharedWith: []string{"2"}})
+	for {
+		select {
+		case name := <-received:
+			if name == "waste.created" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("no delivery to the family the child is shared with")
+		}
+	}
+}
+
+func TestWebhookDispatcherLease(t *testing.T) {
+	received := make(chan string, 100)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(WebhookEventHeader)
+	}))
+	defer receiver.Close()
+
+	service := &testWebhookService{webhooks: []*Webhook{
+		{ID: "1", FamilyID: "1", URL: receiver.URL},
+	}}
+	leases := &testLeaseStore{held: make(chan string, 10)}
+	bus := NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//two instances of the service, both seeing every event
+	go (&WebhookDispatcher{Service: service, Leases: leases, Holder: "instance-1"}).Run(ctx, bus)
+	go (&WebhookDispatcher{Service: service, Leases: leases, Holder: "instance-2"}).Run(ctx, bus)
+	//they've both subscribed once they've asked for the lease
+	timeout := time.After(5 * time.Second)
+	for held := 0; held < 2; held++ {
+		select {
+		case <-leases.held:
+		case <-timeout:
+			t.Fatal("lease not asked for")
+		}
+	}
+
+	events := 5
+	for i := 0; i < events; i++ {
+		bus.Publish(Event{Type: EventCreated, Resource: ResourceFeeding, FamilyID: "1"})
+	}
+	for i := 0; i < events; i++ {
+		select {
+		case <-received:
+		case <-timeout:
+			t.Fatal("no delivery")
+		}
+	}
+	//give a second delivery time to arrive
+	select {
+	case <-received:
+		t.Fatal("event delivered twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	delivered := make(map[string]int)
+	service.mu.Lock()
+	for _, delivery := range service.deliveries {
+		delivered[delivery.EventID]++
+	}
+	service.mu.Unlock()
+	assert.Len(t, delivered, events)
+	for id, n := range delivered {
+		assert.Equal(t, 1, n, id)
+	}
+}